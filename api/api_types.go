@@ -260,6 +260,10 @@ type SectorInfo struct {
 	Retries      uint64
 	ToUpgrade    bool
 
+	// Recovery playbook attempts by failure state
+	RecoveryAttempts  map[SectorState]uint64
+	RecoveryEscalated bool
+
 	LastErr string
 
 	Log []SectorLog
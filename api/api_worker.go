@@ -24,6 +24,14 @@ type WorkerAPI interface {
 
 	TaskNumbers(context.Context) (string, error)
 
+	// TaskLimits returns the number of tasks of each type allowed to run at the same time
+	TaskLimits(context.Context) (map[types.TaskType]int64, error)
+	// SetTaskLimit changes the limit of a task type, limit <= 0 removes the limit
+	SetTaskLimit(ctx context.Context, tt types.TaskType, limit int64) error
+
+	BindP1P2(context.Context) (bool, error)
+	SetBindP1P2(ctx context.Context, bind bool) error
+
 	SectorExists(context.Context, types.TaskType, storage.SectorRef) (bool, error)
 
 	storiface.WorkerCalls
@@ -0,0 +1,220 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/venus-sealer/types"
+)
+
+// MaxAuditParamsLen limits the size of recorded call arguments
+var MaxAuditParamsLen = 4096
+
+// JSON fields containing any of these are redacted in audit records
+var auditSensitiveFields = []string{"token", "secret", "password", "passwd", "privatekey", "authorization"}
+
+const auditRedacted = "<redacted>"
+
+type AuditRecorder interface {
+	RecordAudit(entry *types.AuditEntry) error
+}
+
+type auditCaller struct {
+	token  string
+	remote string
+}
+
+type auditCallerKey struct{}
+
+// AuditHandler adds the identity of the caller to the request context, it
+// doesn't verify the token, that's left to the auth handler
+func AuditHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := r.Header.Get("Authorization")
+		if token == "" {
+			token = r.FormValue("token")
+		} else {
+			token = strings.TrimPrefix(token, "Bearer ")
+		}
+
+		ctx := context.WithValue(r.Context(), auditCallerKey{}, auditCaller{
+			token:  tokenIdentity(token),
+			remote: r.RemoteAddr,
+		})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// tokenIdentity is the ID of scoped tokens, and a fingerprint of tokens without ID
+func tokenIdentity(token string) string {
+	if token == "" {
+		return "anonymous"
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) == 3 {
+		var payload types.JWTPayload
+		if raw, err := base64.RawURLEncoding.DecodeString(parts[1]); err == nil && json.Unmarshal(raw, &payload) == nil && payload.JWTID != "" {
+			return "id:" + payload.JWTID
+		}
+	}
+
+	sum := sha256.Sum256([]byte(token))
+	return "sha256:" + hex.EncodeToString(sum[:8])
+}
+
+func auditCallerFromContext(ctx context.Context) auditCaller {
+	c, ok := ctx.Value(auditCallerKey{}).(auditCaller)
+	if !ok {
+		return auditCaller{token: "local"}
+	}
+	return c
+}
+
+// audited returns whether calls of a method are recorded, calls of all methods
+// which don't only read are, except the ones tagged with audit:"skip", which
+// workers and the sector index call all the time
+func audited(field reflect.StructField) bool {
+	if field.Tag.Get("audit") == "skip" {
+		return false
+	}
+
+	perm := field.Tag.Get("perm")
+	return perm != string(PermRead) && !strings.HasSuffix(perm, ":read")
+}
+
+// AuditedStorMinerAPI records calls of the mutating methods of the api, with
+// the outcome of the call. Methods tagged with audit:"redact" don't have
+// their arguments recorded. The recorder shouldn't block, it's called before
+// the call returns.
+func AuditedStorMinerAPI(a StorageMiner, rec AuditRecorder) StorageMiner {
+	var out StorageMinerStruct
+	auditProxy(a, rec, &out.Internal)
+	auditProxy(a, rec, &out.CommonStruct.Internal)
+	return &out
+}
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+func auditProxy(in interface{}, rec AuditRecorder, out interface{}) {
+	rint := reflect.ValueOf(out).Elem()
+	ra := reflect.ValueOf(in)
+
+	for f := 0; f < rint.NumField(); f++ {
+		field := rint.Type().Field(f)
+		fn := ra.MethodByName(field.Name)
+		if !fn.IsValid() {
+			continue
+		}
+
+		nout := field.Type.NumOut()
+		if !audited(field) || nout == 0 || field.Type.Out(nout-1) != errorType {
+			rint.Field(f).Set(fn)
+			continue
+		}
+
+		method := field.Name
+		redact := field.Tag.Get("audit") == "redact"
+
+		rint.Field(f).Set(reflect.MakeFunc(field.Type, func(args []reflect.Value) []reflect.Value {
+			start := time.Now()
+			res := fn.Call(args)
+
+			ctx, _ := args[0].Interface().(context.Context)
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			caller := auditCallerFromContext(ctx)
+
+			entry := &types.AuditEntry{
+				Time:     start,
+				Caller:   caller.token,
+				Remote:   caller.remote,
+				Method:   method,
+				Params:   auditParams(args[1:], redact),
+				Duration: time.Since(start),
+			}
+			if errv := res[len(res)-1]; !errv.IsNil() {
+				entry.Error = errv.Interface().(error).Error()
+			}
+
+			if err := rec.RecordAudit(entry); err != nil {
+				log.Errorw("recording audit entry", "method", method, "error", err)
+				if res[len(res)-1].IsNil() {
+					// the call went through, but it must not look like an unrecorded success
+					res[len(res)-1] = reflect.ValueOf(xerrors.Errorf("recording audit entry of %s: %w", method, err)).Convert(errorType)
+				}
+			}
+
+			return res
+		}))
+	}
+}
+
+func auditParams(args []reflect.Value, redact bool) string {
+	if redact {
+		return auditRedacted
+	}
+
+	params := make([]interface{}, 0, len(args))
+	for _, arg := range args {
+		b, err := json.Marshal(arg.Interface())
+		if err != nil {
+			params = append(params, "<"+arg.Type().String()+">")
+			continue
+		}
+
+		var v interface{}
+		if err := json.Unmarshal(b, &v); err != nil {
+			params = append(params, "<"+arg.Type().String()+">")
+			continue
+		}
+		params = append(params, redactSensitive(v))
+	}
+
+	b, err := json.Marshal(params)
+	if err != nil {
+		return ""
+	}
+	if len(b) > MaxAuditParamsLen {
+		return string(b[:MaxAuditParamsLen]) + "..."
+	}
+	return string(b)
+}
+
+func redactSensitive(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, val := range v {
+			if sensitiveField(k) {
+				v[k] = auditRedacted
+				continue
+			}
+			v[k] = redactSensitive(val)
+		}
+	case []interface{}:
+		for i, val := range v {
+			v[i] = redactSensitive(val)
+		}
+	}
+	return v
+}
+
+func sensitiveField(name string) bool {
+	name = strings.ToLower(name)
+	for _, s := range auditSensitiveFields {
+		if strings.Contains(name, s) {
+			return true
+		}
+	}
+	return false
+}
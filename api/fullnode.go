@@ -13,10 +13,6 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/ipfs/go-cid"
-	metrics "github.com/libp2p/go-libp2p-core/metrics"
-	"github.com/libp2p/go-libp2p-core/network"
-	"github.com/libp2p/go-libp2p-core/peer"
-	protocol "github.com/libp2p/go-libp2p-core/protocol"
 
 	"github.com/filecoin-project/venus/app/submodule/apitypes"
 	"github.com/filecoin-project/venus/pkg/chain"
@@ -474,23 +470,6 @@ type CommonStruct struct {
 		AuthNew    func(ctx context.Context, perms []auth.Permission) ([]byte, error) `perm:"admin"`
 		Token      func(ctx context.Context) ([]byte, error)                          `perm:"admin"`
 
-		NetConnectedness            func(context.Context, peer.ID) (network.Connectedness, error)    `perm:"read"`
-		NetPeers                    func(context.Context) ([]peer.AddrInfo, error)                   `perm:"read"`
-		NetConnect                  func(context.Context, peer.AddrInfo) error                       `perm:"write"`
-		NetAddrsListen              func(context.Context) (peer.AddrInfo, error)                     `perm:"read"`
-		NetDisconnect               func(context.Context, peer.ID) error                             `perm:"write"`
-		NetFindPeer                 func(context.Context, peer.ID) (peer.AddrInfo, error)            `perm:"read"`
-		NetPubsubScores             func(context.Context) ([]PubsubScore, error)                     `perm:"read"`
-		NetAutoNatStatus            func(context.Context) (NatInfo, error)                           `perm:"read"`
-		NetBandwidthStats           func(ctx context.Context) (metrics.Stats, error)                 `perm:"read"`
-		NetBandwidthStatsByPeer     func(ctx context.Context) (map[string]metrics.Stats, error)      `perm:"read"`
-		NetBandwidthStatsByProtocol func(ctx context.Context) (map[protocol.ID]metrics.Stats, error) `perm:"read"`
-		NetAgentVersion             func(ctx context.Context, p peer.ID) (string, error)             `perm:"read"`
-		NetBlockAdd                 func(ctx context.Context, acl NetBlockList) error                `perm:"admin"`
-		NetBlockRemove              func(ctx context.Context, acl NetBlockList) error                `perm:"admin"`
-		NetBlockList                func(ctx context.Context) (NetBlockList, error)                  `perm:"read"`
-
-		ID      func(context.Context) (peer.ID, error) `perm:"read"`
 		Version func(context.Context) (Version, error) `perm:"read"`
 
 		LogList     func(context.Context) ([]string, error)     `perm:"write"`
@@ -674,71 +653,6 @@ func (c *CommonStruct) Token(ctx context.Context) ([]byte, error) {
 	return c.Internal.Token(ctx)
 }
 
-func (c *CommonStruct) NetPubsubScores(ctx context.Context) ([]PubsubScore, error) {
-	return c.Internal.NetPubsubScores(ctx)
-}
-
-func (c *CommonStruct) NetConnectedness(ctx context.Context, pid peer.ID) (network.Connectedness, error) {
-	return c.Internal.NetConnectedness(ctx, pid)
-}
-
-func (c *CommonStruct) NetPeers(ctx context.Context) ([]peer.AddrInfo, error) {
-	return c.Internal.NetPeers(ctx)
-}
-
-func (c *CommonStruct) NetConnect(ctx context.Context, p peer.AddrInfo) error {
-	return c.Internal.NetConnect(ctx, p)
-}
-
-func (c *CommonStruct) NetAddrsListen(ctx context.Context) (peer.AddrInfo, error) {
-	return c.Internal.NetAddrsListen(ctx)
-}
-
-func (c *CommonStruct) NetDisconnect(ctx context.Context, p peer.ID) error {
-	return c.Internal.NetDisconnect(ctx, p)
-}
-
-func (c *CommonStruct) NetFindPeer(ctx context.Context, p peer.ID) (peer.AddrInfo, error) {
-	return c.Internal.NetFindPeer(ctx, p)
-}
-
-func (c *CommonStruct) NetAutoNatStatus(ctx context.Context) (NatInfo, error) {
-	return c.Internal.NetAutoNatStatus(ctx)
-}
-
-func (c *CommonStruct) NetBandwidthStats(ctx context.Context) (metrics.Stats, error) {
-	return c.Internal.NetBandwidthStats(ctx)
-}
-
-func (c *CommonStruct) NetBandwidthStatsByPeer(ctx context.Context) (map[string]metrics.Stats, error) {
-	return c.Internal.NetBandwidthStatsByPeer(ctx)
-}
-
-func (c *CommonStruct) NetBandwidthStatsByProtocol(ctx context.Context) (map[protocol.ID]metrics.Stats, error) {
-	return c.Internal.NetBandwidthStatsByProtocol(ctx)
-}
-
-func (c *CommonStruct) NetBlockAdd(ctx context.Context, acl NetBlockList) error {
-	return c.Internal.NetBlockAdd(ctx, acl)
-}
-
-func (c *CommonStruct) NetBlockRemove(ctx context.Context, acl NetBlockList) error {
-	return c.Internal.NetBlockRemove(ctx, acl)
-}
-
-func (c *CommonStruct) NetBlockList(ctx context.Context) (NetBlockList, error) {
-	return c.Internal.NetBlockList(ctx)
-}
-
-func (c *CommonStruct) NetAgentVersion(ctx context.Context, p peer.ID) (string, error) {
-	return c.Internal.NetAgentVersion(ctx, p)
-}
-
-// ID implements ID
-func (c *CommonStruct) ID(ctx context.Context) (peer.ID, error) {
-	return c.Internal.ID(ctx)
-}
-
 // Version implements Version
 func (c *CommonStruct) Version(ctx context.Context) (Version, error) {
 	return c.Internal.Version(ctx)
@@ -2,6 +2,7 @@ package impl
 
 import (
 	"context"
+	"time"
 
 	"github.com/filecoin-project/go-jsonrpc/auth"
 	"github.com/gbrlsnchs/jwt/v3"
@@ -13,6 +14,7 @@ import (
 	"github.com/filecoin-project/venus-sealer/api"
 	"github.com/filecoin-project/venus-sealer/config"
 	"github.com/filecoin-project/venus-sealer/constants"
+	"github.com/filecoin-project/venus-sealer/service"
 	"github.com/filecoin-project/venus-sealer/types"
 )
 
@@ -25,29 +27,57 @@ type CommonAPI struct {
 	ShutdownChan  types.ShutdownChan
 	NetworkParams *config.NetParamsConfig
 	APIToken      types.APIToken
-}
-
-type jwtPayload struct {
-	Allow []auth.Permission
+	TokenService  *service.TokenService
 }
 
 func (a *CommonAPI) AuthVerify(ctx context.Context, token string) ([]auth.Permission, error) {
-	var payload jwtPayload
+	var payload types.JWTPayload
 	if _, err := jwt.Verify([]byte(token), (*jwt.HMACSHA)(a.APISecret), &payload); err != nil {
 		return nil, xerrors.Errorf("JWT Verification failed: %w", err)
 	}
 
-	return payload.Allow, nil
+	if payload.ExpirationTime != nil && time.Now().After(payload.ExpirationTime.Time) {
+		return nil, xerrors.Errorf("token expired at %s", payload.ExpirationTime.Time.Format(time.RFC3339))
+	}
+	if payload.JWTID != "" && a.TokenService.IsRevoked(payload.JWTID) {
+		return nil, xerrors.Errorf("token %s has been revoked", payload.JWTID)
+	}
+
+	return api.ExpandPerms(payload.Allow), nil
 }
 
 func (a *CommonAPI) AuthNew(ctx context.Context, perms []auth.Permission) ([]byte, error) {
-	p := jwtPayload{
+	p := types.JWTPayload{
 		Allow: perms, // TODO: consider checking validity
 	}
 
 	return jwt.Sign(&p, (*jwt.HMACSHA)(a.APISecret))
 }
 
+// AuthRevoke revokes a scoped token, given either the token or its ID
+func (a *CommonAPI) AuthRevoke(ctx context.Context, token string) error {
+	id := token
+	var expires time.Time
+
+	var payload types.JWTPayload
+	if _, err := jwt.Verify([]byte(token), (*jwt.HMACSHA)(a.APISecret), &payload); err == nil {
+		if payload.JWTID == "" {
+			return xerrors.Errorf("token has no ID, only scoped tokens can be revoked")
+		}
+
+		id = payload.JWTID
+		if payload.ExpirationTime != nil {
+			expires = payload.ExpirationTime.Time
+		}
+	}
+
+	return a.TokenService.Revoke(id, expires)
+}
+
+func (a *CommonAPI) AuthRevokedList(ctx context.Context) ([]*types.RevokedToken, error) {
+	return a.TokenService.List()
+}
+
 func (a *CommonAPI) Version(context.Context) (api.Version, error) {
 	return api.Version{
 		Version:    constants.MinerVersion.String(),
@@ -3,9 +3,11 @@ package impl
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	api2 "github.com/filecoin-project/venus-market/api"
 	"github.com/filecoin-project/venus-market/piece"
 	"net/http"
+	"os"
 	"strconv"
 	"time"
 
@@ -49,6 +51,8 @@ type StorageMinerAPI struct {
 	CommonAPI
 	Prover       storage.WinningPoStProver
 	SectorBlocks *sectorblocks.SectorBlocks
+	PieceHandler *sectorblocks.PieceHandler
+	Integrity    *storage.IntegrityAuditor
 	Miner        *storage.Miner
 	Full         api.FullNode
 	Messager     api.IMessager
@@ -63,7 +67,10 @@ type StorageMinerAPI struct {
 
 	MarketClient         api2.MarketFullNode
 	LogService           *service.LogService
+	SectorInfoService    *service.SectorInfoService
+	AuditService         *service.AuditService
 	NetParams            *config.NetParamsConfig
+	Live                 *config.Live
 	SetSealingConfigFunc types2.SetSealingConfigFunc
 	GetSealingConfigFunc types2.GetSealingConfigFunc
 }
@@ -84,6 +91,10 @@ func (sm *StorageMinerAPI) UpdateDealStatus(ctx context.Context, dealId abi.Deal
 	return sm.MarketClient.UpdateDealStatus(ctx, addr, dealId, status)
 }
 
+func (sm *StorageMinerAPI) AuditList(ctx context.Context, filter types2.AuditFilter) ([]*types2.AuditEntry, error) {
+	return sm.AuditService.List(filter)
+}
+
 func (sm *StorageMinerAPI) IsUnsealed(ctx context.Context, sector sto.SectorRef, offset storiface.UnpaddedByteIndex, size abi.UnpaddedPieceSize) (bool, error) {
 	return sm.Stor.CheckIsUnsealed(ctx, sector, abi.PaddedPieceSize(offset.Padded()), abi.PaddedPieceSize(offset.Padded()))
 }
@@ -114,6 +125,24 @@ func (sm *StorageMinerAPI) WorkerJobs(ctx context.Context) (map[uuid.UUID][]stor
 	return sm.StorageMgr.WorkerJobs(), nil
 }
 
+func (sm *StorageMinerAPI) WorkerDrain(ctx context.Context, id uuid.UUID) error {
+	return sm.StorageMgr.DrainWorker(ctx, id, func(ctx context.Context, sid abi.SectorID) (abi.RegisteredSealProof, error) {
+		info, err := sm.Miner.GetSectorInfo(sid.Number)
+		if err != nil {
+			return 0, err
+		}
+		return info.SectorType, nil
+	})
+}
+
+func (sm *StorageMinerAPI) WorkerDrainCancel(ctx context.Context, id uuid.UUID) error {
+	return sm.StorageMgr.CancelDrain(ctx, id)
+}
+
+func (sm *StorageMinerAPI) WorkerDrainStatus(ctx context.Context) (map[uuid.UUID]storiface.WorkerDrain, error) {
+	return sm.StorageMgr.DrainStatus(), nil
+}
+
 func (sm *StorageMinerAPI) ActorAddress(context.Context) (address.Address, error) {
 	return sm.Miner.Address(), nil
 }
@@ -160,7 +189,30 @@ func (sm *StorageMinerAPI) DealSector(ctx context.Context) ([]types2.DealAssign,
 	return sm.Miner.DealSector(ctx)
 }
 
+func (sm *StorageMinerAPI) DealIngestStatus(ctx context.Context) (types2.DealIngestStatus, error) {
+	return sm.Miner.DealIngestStatus(), nil
+}
+
+func (sm *StorageMinerAPI) DealPackingPlan(ctx context.Context) (types2.PackingPlan, error) {
+	return sm.Miner.DealPackingPlan(ctx)
+}
+
 func (sm *StorageMinerAPI) RedoSector(ctx context.Context, rsi storiface.SectorRedoParams) error  {
+	// paths which aren't initialized as storage use the flat layout
+	for _, p := range []struct {
+		path   string
+		layout *storiface.SectorLayout
+	}{{rsi.SealPath, &rsi.SealLayout}, {rsi.StorePath, &rsi.StoreLayout}} {
+		meta, err := stores.ReadStorageMeta(p.path)
+		switch {
+		case err == nil:
+			*p.layout = meta.Layout
+		case xerrors.Is(err, os.ErrNotExist):
+		default:
+			return err
+		}
+	}
+
 	return sm.Miner.RedoSector(ctx, rsi)
 }
 
@@ -212,6 +264,8 @@ func (sm *StorageMinerAPI) SectorsStatus(ctx context.Context, sid abi.SectorNumb
 		Retries:      info.InvalidProofs,
 		ToUpgrade:    sm.Miner.IsMarkedForUpgrade(sid),
 
+		RecoveryEscalated: info.RecoveryEscalated,
+
 		LastErr: info.LastErr,
 		Log:     log,
 		// on chain info
@@ -225,6 +279,13 @@ func (sm *StorageMinerAPI) SectorsStatus(ctx context.Context, sid abi.SectorNumb
 		Early:              0,
 	}
 
+	for state, attempts := range info.RecoveryAttempts {
+		if sInfo.RecoveryAttempts == nil {
+			sInfo.RecoveryAttempts = map[api.SectorState]uint64{}
+		}
+		sInfo.RecoveryAttempts[api.SectorState(state)] = attempts
+	}
+
 	if !showOnChainInfo {
 		return sInfo, nil
 	}
@@ -476,6 +537,10 @@ func (sm *StorageMinerAPI) StorageStat(ctx context.Context, id stores.ID) (fsuti
 	return sm.StorageMgr.FsStat(ctx, id)
 }
 
+func (sm *StorageMinerAPI) StorageUnsealedCache(ctx context.Context) ([]storiface.UnsealedPath, error) {
+	return sm.StorageMgr.UnsealedCacheStatus(), nil
+}
+
 func (sm *StorageMinerAPI) SectorStartSealing(ctx context.Context, number abi.SectorNumber) error {
 	return sm.Miner.StartPackingSector(number)
 }
@@ -500,13 +565,17 @@ func (sm *StorageMinerAPI) SectorGetSealDelay(ctx context.Context) (time.Duratio
 }
 
 func (sm *StorageMinerAPI) SectorSetExpectedSealDuration(ctx context.Context, delay time.Duration) error {
-	//return sm.SetExpectedSealDurationFunc(delay)
-	panic("not impl")
+	return sm.mutateDealmaking(func(cfg *config.DealmakingConfig) {
+		cfg.ExpectedSealDuration = config.Duration(delay)
+	})
 }
 
 func (sm *StorageMinerAPI) SectorGetExpectedSealDuration(ctx context.Context) (time.Duration, error) {
-	//return sm.GetExpectedSealDurationFunc()
-	panic("not impl")
+	var out time.Duration
+	sm.Live.Read(func(cfg *config.StorageMiner) {
+		out = time.Duration(cfg.Dealmaking.ExpectedSealDuration)
+	})
+	return out, nil
 }
 
 func (sm *StorageMinerAPI) SectorsUpdate(ctx context.Context, id abi.SectorNumber, state api.SectorState) error {
@@ -549,6 +618,107 @@ func (sm *StorageMinerAPI) SectorCommitPending(ctx context.Context) ([]abi.Secto
 	return sm.Miner.CommitPending(ctx)
 }
 
+func (sm *StorageMinerAPI) SectorBatchQueues(ctx context.Context) (types2.BatchQueues, error) {
+	return sm.Miner.BatchQueues(ctx)
+}
+
+func (sm *StorageMinerAPI) SectorBatchExplain(ctx context.Context) (types2.BatchExplains, error) {
+	return sm.Miner.BatchExplain(ctx)
+}
+
+func (sm *StorageMinerAPI) SectorsHistory(ctx context.Context, sid abi.SectorNumber) (types2.SectorHistory, error) {
+	return sm.Miner.SectorHistory(sid)
+}
+
+func (sm *StorageMinerAPI) SectorsVerify(ctx context.Context, sectors []abi.SectorNumber, rounds int) ([]types2.SectorIntegrity, error) {
+	return sm.Integrity.Verify(ctx, sectors, rounds)
+}
+
+func (sm *StorageMinerAPI) SectorsVerifyBackground(ctx context.Context, sectors []abi.SectorNumber) (int, error) {
+	return sm.Integrity.Enqueue(ctx, sectors)
+}
+
+func (sm *StorageMinerAPI) SectorsIntegrity(ctx context.Context) ([]*types2.SectorIntegrity, error) {
+	return sm.Integrity.Results()
+}
+
+func (sm *StorageMinerAPI) SectorsExport(ctx context.Context, sid abi.SectorNumber, dir string) (*storiface.SectorExport, error) {
+	info, err := sm.Miner.GetSectorInfo(sid)
+	if err != nil {
+		return nil, xerrors.Errorf("getting sector info: %w", err)
+	}
+	if info.CommR == nil {
+		return nil, xerrors.Errorf("sector %d isn't sealed", sid)
+	}
+
+	mid, err := address.IDFromAddress(sm.Miner.Address())
+	if err != nil {
+		return nil, err
+	}
+
+	meta, err := json.Marshal(info)
+	if err != nil {
+		return nil, xerrors.Errorf("encoding sector info: %w", err)
+	}
+
+	return sm.StorageMgr.ExportSector(ctx, sto.SectorRef{
+		ID: abi.SectorID{
+			Miner:  abi.ActorID(mid),
+			Number: sid,
+		},
+		ProofType: info.SectorType,
+	}, *info.CommR, meta, dir)
+}
+
+func (sm *StorageMinerAPI) SectorsImport(ctx context.Context, from string) (*storiface.SectorExport, error) {
+	exp, err := sectorstorage.ReadSectorExport(ctx, from)
+	if err != nil {
+		return nil, err
+	}
+
+	maddr := sm.Miner.Address()
+	mid, err := address.IDFromAddress(maddr)
+	if err != nil {
+		return nil, err
+	}
+	if exp.Sector.Miner != abi.ActorID(mid) {
+		return nil, xerrors.Errorf("sector was exported from miner t0%d, not %s", exp.Sector.Miner, maddr)
+	}
+
+	onChain, err := sm.Full.StateSectorGetInfo(ctx, maddr, exp.Sector.Number, types.EmptyTSK)
+	if err != nil {
+		return nil, xerrors.Errorf("getting on-chain sector info: %w", err)
+	}
+	if onChain != nil && !onChain.SealedCID.Equals(exp.CommR) {
+		return nil, xerrors.Errorf("exported CommR %s doesn't match the on-chain CommR %s", exp.CommR, onChain.SealedCID)
+	}
+
+	exp, err = sm.StorageMgr.ImportSector(ctx, from)
+	if err != nil {
+		return nil, err
+	}
+
+	// restore the metadata when the sealer lost it
+	has, err := sm.SectorInfoService.Has(uint64(exp.Sector.Number))
+	if err != nil {
+		return nil, xerrors.Errorf("checking sector info: %w", err)
+	}
+	if !has && len(exp.Info) > 0 {
+		var info types2.SectorInfo
+		if err := json.Unmarshal(exp.Info, &info); err != nil {
+			return nil, xerrors.Errorf("decoding exported sector info: %w", err)
+		}
+		if err := sm.SectorInfoService.Begin(uint64(exp.Sector.Number), &info); err != nil {
+			return nil, xerrors.Errorf("restoring sector info: %w", err)
+		}
+		if err := sm.Miner.RestoreSector(ctx, exp.Sector.Number); err != nil {
+			return nil, xerrors.Errorf("restarting restored sector: %w", err)
+		}
+	}
+
+	return exp, nil
+}
+
 func (sm *StorageMinerAPI) WorkerConnect(ctx context.Context, url string) error {
 	w, err := connectRemoteWorker(ctx, sm, url)
 	if err != nil {
@@ -568,6 +738,14 @@ func (sm *StorageMinerAPI) SealingAbort(ctx context.Context, call types2.CallID)
 	return sm.StorageMgr.Abort(ctx, call)
 }
 
+func (sm *StorageMinerAPI) SealingUnsealQueue(ctx context.Context) ([]storiface.UnsealJob, error) {
+	return sm.StorageMgr.UnsealQueue(), nil
+}
+
+func (sm *StorageMinerAPI) SealingUnsealBoost(ctx context.Context, sector abi.SectorID, priority int) error {
+	return sm.StorageMgr.BoostUnseal(ctx, sector, priority)
+}
+
 func (sm *StorageMinerAPI) MarketImportDealData(ctx context.Context, propCid cid.Cid, path string) error {
 	/*	fi, err := os.Open(path)
 		if err != nil {
@@ -611,73 +789,121 @@ func (sm *StorageMinerAPI) RetrievalDealsList(ctx context.Context) (map[retrieva
 }
 
 func (sm *StorageMinerAPI) DealsConsiderOnlineStorageDeals(ctx context.Context) (bool, error) {
-	//return sm.ConsiderOnlineStorageDealsConfigFunc()
-	panic("not impl")
+	var out bool
+	sm.Live.Read(func(cfg *config.StorageMiner) {
+		out = cfg.Dealmaking.ConsiderOnlineStorageDeals
+	})
+	return out, nil
 }
 
 func (sm *StorageMinerAPI) DealsSetConsiderOnlineStorageDeals(ctx context.Context, b bool) error {
-	//return sm.SetConsiderOnlineStorageDealsConfigFunc(b)
-	panic("not impl")
+	return sm.mutateDealmaking(func(cfg *config.DealmakingConfig) {
+		cfg.ConsiderOnlineStorageDeals = b
+	})
 }
 
 func (sm *StorageMinerAPI) DealsConsiderOnlineRetrievalDeals(ctx context.Context) (bool, error) {
-	//return sm.ConsiderOnlineRetrievalDealsConfigFunc()
-	panic("not impl")
+	var out bool
+	sm.Live.Read(func(cfg *config.StorageMiner) {
+		out = cfg.Dealmaking.ConsiderOnlineRetrievalDeals
+	})
+	return out, nil
 }
 
 func (sm *StorageMinerAPI) DealsSetConsiderOnlineRetrievalDeals(ctx context.Context, b bool) error {
-	//return sm.SetConsiderOnlineRetrievalDealsConfigFunc(b)
-	panic("not impl")
+	return sm.mutateDealmaking(func(cfg *config.DealmakingConfig) {
+		cfg.ConsiderOnlineRetrievalDeals = b
+	})
 }
 
 func (sm *StorageMinerAPI) DealsConsiderOfflineStorageDeals(ctx context.Context) (bool, error) {
-	//return sm.ConsiderOfflineStorageDealsConfigFunc()
-	panic("not impl")
+	var out bool
+	sm.Live.Read(func(cfg *config.StorageMiner) {
+		out = cfg.Dealmaking.ConsiderOfflineStorageDeals
+	})
+	return out, nil
 }
 
 func (sm *StorageMinerAPI) DealsSetConsiderOfflineStorageDeals(ctx context.Context, b bool) error {
-	//return sm.SetConsiderOfflineStorageDealsConfigFunc(b)
-	panic("not impl")
+	return sm.mutateDealmaking(func(cfg *config.DealmakingConfig) {
+		cfg.ConsiderOfflineStorageDeals = b
+	})
 }
 
 func (sm *StorageMinerAPI) DealsConsiderOfflineRetrievalDeals(ctx context.Context) (bool, error) {
-	//return sm.ConsiderOfflineRetrievalDealsConfigFunc()
-	panic("not impl")
+	var out bool
+	sm.Live.Read(func(cfg *config.StorageMiner) {
+		out = cfg.Dealmaking.ConsiderOfflineRetrievalDeals
+	})
+	return out, nil
 }
 
 func (sm *StorageMinerAPI) DealsSetConsiderOfflineRetrievalDeals(ctx context.Context, b bool) error {
-	//return sm.SetConsiderOfflineRetrievalDealsConfigFunc(b)
-	panic("not impl")
+	return sm.mutateDealmaking(func(cfg *config.DealmakingConfig) {
+		cfg.ConsiderOfflineRetrievalDeals = b
+	})
 }
 
 func (sm *StorageMinerAPI) DealsConsiderVerifiedStorageDeals(ctx context.Context) (bool, error) {
-	//return sm.ConsiderVerifiedStorageDealsConfigFunc()
-	panic("not impl")
+	var out bool
+	sm.Live.Read(func(cfg *config.StorageMiner) {
+		out = cfg.Dealmaking.ConsiderVerifiedStorageDeals
+	})
+	return out, nil
 }
 
 func (sm *StorageMinerAPI) DealsSetConsiderVerifiedStorageDeals(ctx context.Context, b bool) error {
-	//return sm.SetConsiderVerifiedStorageDealsConfigFunc(b)
-	panic("not impl")
+	return sm.mutateDealmaking(func(cfg *config.DealmakingConfig) {
+		cfg.ConsiderVerifiedStorageDeals = b
+	})
 }
 
 func (sm *StorageMinerAPI) DealsConsiderUnverifiedStorageDeals(ctx context.Context) (bool, error) {
-	//return sm.ConsiderUnverifiedStorageDealsConfigFunc()
-	panic("not impl")
+	var out bool
+	sm.Live.Read(func(cfg *config.StorageMiner) {
+		out = cfg.Dealmaking.ConsiderUnverifiedStorageDeals
+	})
+	return out, nil
 }
 
 func (sm *StorageMinerAPI) DealsSetConsiderUnverifiedStorageDeals(ctx context.Context, b bool) error {
-	//return sm.SetConsiderUnverifiedStorageDealsConfigFunc(b)
-	panic("not impl")
+	return sm.mutateDealmaking(func(cfg *config.DealmakingConfig) {
+		cfg.ConsiderUnverifiedStorageDeals = b
+	})
 }
 
 func (sm *StorageMinerAPI) DealsGetExpectedSealDurationFunc(ctx context.Context) (time.Duration, error) {
-	//return sm.GetExpectedSealDurationFunc()
-	panic("not impl")
+	var out time.Duration
+	sm.Live.Read(func(cfg *config.StorageMiner) {
+		out = time.Duration(cfg.Dealmaking.ExpectedSealDuration)
+	})
+	return out, nil
 }
 
 func (sm *StorageMinerAPI) DealsSetExpectedSealDurationFunc(ctx context.Context, d time.Duration) error {
-	//return sm.SetExpectedSealDurationFunc(d)
-	panic("not impl")
+	return sm.mutateDealmaking(func(cfg *config.DealmakingConfig) {
+		cfg.ExpectedSealDuration = config.Duration(d)
+	})
+}
+
+func (sm *StorageMinerAPI) mutateDealmaking(mutator func(cfg *config.DealmakingConfig)) error {
+	_, err := sm.Live.Mutate(func(cfg *config.StorageMiner) error {
+		mutator(&cfg.Dealmaking)
+		return nil
+	})
+	return err
+}
+
+func (sm *StorageMinerAPI) ConfigReload(ctx context.Context) ([]string, error) {
+	return sm.Live.Reload()
+}
+
+func (sm *StorageMinerAPI) ConfigGet(ctx context.Context, key string) (string, error) {
+	return sm.Live.GetKey(key)
+}
+
+func (sm *StorageMinerAPI) ConfigSet(ctx context.Context, key string, value string) ([]string, error) {
+	return sm.Live.Set(key, value)
 }
 
 func (sm *StorageMinerAPI) DealsImportData(ctx context.Context, deal cid.Cid, fname string) error {
@@ -692,13 +918,17 @@ func (sm *StorageMinerAPI) DealsImportData(ctx context.Context, deal cid.Cid, fn
 }
 
 func (sm *StorageMinerAPI) DealsPieceCidBlocklist(ctx context.Context) ([]cid.Cid, error) {
-	//return sm.StorageDealPieceCidBlocklistConfigFunc()
-	panic("not impl")
+	var out []cid.Cid
+	sm.Live.Read(func(cfg *config.StorageMiner) {
+		out = append(out, cfg.Dealmaking.PieceCidBlocklist...)
+	})
+	return out, nil
 }
 
 func (sm *StorageMinerAPI) DealsSetPieceCidBlocklist(ctx context.Context, cids []cid.Cid) error {
-	//return sm.SetStorageDealPieceCidBlocklistConfigFunc(cids)
-	panic("not impl")
+	return sm.mutateDealmaking(func(cfg *config.DealmakingConfig) {
+		cfg.PieceCidBlocklist = cids
+	})
 }
 
 func (sm *StorageMinerAPI) StorageAddLocal(ctx context.Context, path string) error {
@@ -709,6 +939,22 @@ func (sm *StorageMinerAPI) StorageAddLocal(ctx context.Context, path string) err
 	return sm.StorageMgr.AddLocalStorage(ctx, path)
 }
 
+func (sm *StorageMinerAPI) StorageRelayout(ctx context.Context, id stores.ID, layout storiface.SectorLayout) (stores.RelayoutResult, error) {
+	if sm.StorageMgr == nil {
+		return stores.RelayoutResult{}, xerrors.Errorf("no storage manager")
+	}
+
+	return sm.StorageMgr.RelayoutLocalStorage(ctx, id, layout)
+}
+
+func (sm *StorageMinerAPI) StorageVerifyManifest(ctx context.Context, id stores.ID) (stores.ManifestReport, error) {
+	if sm.StorageMgr == nil {
+		return stores.ManifestReport{}, xerrors.Errorf("no storage manager")
+	}
+
+	return sm.StorageMgr.VerifyLocalManifest(ctx, id)
+}
+
 func (sm *StorageMinerAPI) PiecesListPieces(ctx context.Context) ([]cid.Cid, error) {
 	//return sm.PieceStore.ListPieceInfoKeys()
 	panic("not impl")
@@ -774,11 +1020,31 @@ func (sm *StorageMinerAPI) CheckProvable(ctx context.Context, pp abi.RegisteredP
 		out[sid.Number] = err
 	}
 
+	// replicas which didn't match their CommR when last checked
+	results, err := sm.Integrity.Results()
+	if err != nil {
+		return nil, xerrors.Errorf("getting integrity results: %w", err)
+	}
+	failed := make(map[abi.SectorNumber]*types2.SectorIntegrity, len(results))
+	for _, res := range results {
+		if !res.OK() {
+			failed[res.SectorNumber] = res
+		}
+	}
+	for _, sector := range sectors {
+		if _, ok := out[sector.ID.Number]; ok {
+			continue
+		}
+		if res, ok := failed[sector.ID.Number]; ok {
+			out[sector.ID.Number] = fmt.Sprintf("integrity check failed at %s: %s", res.Checked.Format(time.RFC3339), res.Error)
+		}
+	}
+
 	return out, nil
 }
 
 func (sm *StorageMinerAPI) ActorAddressConfig(ctx context.Context) (api.AddressConfig, error) {
-	return sm.AddrSel.AddressConfig, nil
+	return sm.AddrSel.Config(), nil
 }
 
 func (sm *StorageMinerAPI) NetParamsConfig(ctx context.Context) (*config.NetParamsConfig, error) {
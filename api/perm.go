@@ -1,6 +1,11 @@
 package api
 
-import "github.com/filecoin-project/go-jsonrpc/auth"
+import (
+	"strings"
+
+	"github.com/filecoin-project/go-jsonrpc/auth"
+	"golang.org/x/xerrors"
+)
 
 const (
 	// When changing these, update docs/API.md too
@@ -11,5 +16,72 @@ const (
 	PermAdmin auth.Permission = "admin" // Manage permissions
 )
 
-var AllPermissions = []auth.Permission{PermRead, PermWrite, PermSign, PermAdmin}
+// Scoped permissions grant access to one area of the API, tokens with admin
+// permission have all of them
+const (
+	PermSectorsRead   auth.Permission = "sectors:read"
+	PermSectorsManage auth.Permission = "sectors:manage"
+	PermWorkersRead   auth.Permission = "workers:read"
+	PermWorkersManage auth.Permission = "workers:manage"
+	PermStorageRead   auth.Permission = "storage:read"
+	PermStorageAttach auth.Permission = "storage:attach"
+	PermProvingRead   auth.Permission = "proving:read"
+	PermDealsWrite    auth.Permission = "deals:write"
+	PermPiecesRead    auth.Permission = "pieces:read"
+)
+
+var Scopes = []auth.Permission{
+	PermSectorsRead, PermSectorsManage,
+	PermWorkersRead, PermWorkersManage,
+	PermStorageRead, PermStorageAttach,
+	PermProvingRead,
+	PermDealsWrite,
+	PermPiecesRead,
+}
+
+var AllPermissions = append([]auth.Permission{PermRead, PermWrite, PermSign, PermAdmin}, Scopes...)
 var DefaultPerms = []auth.Permission{PermRead}
+
+// ExpandPerms adds the scoped permissions implied by admin, tokens issued
+// before scopes existed only carry the coarse permissions
+func ExpandPerms(perms []auth.Permission) []auth.Permission {
+	has := map[auth.Permission]bool{}
+	for _, p := range perms {
+		has[p] = true
+	}
+	if !has[PermAdmin] {
+		return perms
+	}
+
+	out := append([]auth.Permission{}, perms...)
+	for _, s := range Scopes {
+		if !has[s] {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// ParseScopes parses a comma separated list of scoped permissions
+func ParseScopes(s string) ([]auth.Permission, error) {
+	valid := map[auth.Permission]bool{}
+	for _, p := range Scopes {
+		valid[p] = true
+	}
+
+	var out []auth.Permission
+	for _, part := range strings.Split(s, ",") {
+		p := auth.Permission(strings.TrimSpace(part))
+		if p == "" {
+			continue
+		}
+		if !valid[p] {
+			return nil, xerrors.Errorf("unknown scope %q", p)
+		}
+		out = append(out, p)
+	}
+	if len(out) == 0 {
+		return nil, xerrors.Errorf("no scopes given")
+	}
+	return out, nil
+}
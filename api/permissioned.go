@@ -0,0 +1,14 @@
+package api
+
+import (
+	"github.com/filecoin-project/go-jsonrpc/auth"
+)
+
+// PermissionedStorMinerAPI checks the permissions of the caller against the
+// perm tags of StorageMinerStruct before calling the api
+func PermissionedStorMinerAPI(a StorageMiner) StorageMiner {
+	var out StorageMinerStruct
+	auth.PermissionedProxy(AllPermissions, DefaultPerms, a, &out.Internal)
+	auth.PermissionedProxy(AllPermissions, DefaultPerms, a, &out.CommonStruct.Internal)
+	return &out
+}
@@ -32,6 +32,23 @@ import (
 type StorageMiner interface {
 	Common
 
+	// AuthRevoke revokes a scoped token, given either the token or its ID
+	AuthRevoke(ctx context.Context, token string) error
+	AuthRevokedList(ctx context.Context) ([]*types.RevokedToken, error)
+
+	// AuditList returns recorded calls of mutating api methods, newest first
+	AuditList(ctx context.Context, filter types.AuditFilter) ([]*types.AuditEntry, error)
+
+	// ConfigReload applies the config file to the running sealer, it returns
+	// the changed sections which only take effect after a restart
+	ConfigReload(ctx context.Context) ([]string, error)
+	// ConfigGet returns the TOML value of a dotted key, eg. Sealing.MaxSealingSectors,
+	// or the whole config for an empty key
+	ConfigGet(ctx context.Context, key string) (string, error)
+	// ConfigSet applies and persists a TOML value for a dotted key, it returns
+	// the changed sections which only take effect after a restart
+	ConfigSet(ctx context.Context, key string, value string) ([]string, error)
+
 	ComputeProof(context.Context, []proof2.SectorInfo, abi.PoStRandomness) ([]proof2.PoStProof, error)
 
 	NetParamsConfig(ctx context.Context) (*config.NetParamsConfig, error)
@@ -94,27 +111,61 @@ type StorageMiner interface {
 	SectorMarkForUpgrade(ctx context.Context, id abi.SectorNumber) error
 	// SectorPreCommitFlush immediately sends a PreCommit message with sectors batched for PreCommit.
 	// Returns null if message wasn't sent
-	SectorPreCommitFlush(ctx context.Context) ([]sealiface.PreCommitBatchRes, error) //perm:admin
+	SectorPreCommitFlush(ctx context.Context) ([]sealiface.PreCommitBatchRes, error) //perm:sectors:manage
 	// SectorPreCommitPending returns a list of pending PreCommit sectors to be sent in the next batch message
-	SectorPreCommitPending(ctx context.Context) ([]abi.SectorID, error) //perm:admin
+	SectorPreCommitPending(ctx context.Context) ([]abi.SectorID, error) //perm:sectors:read
 	// SectorCommitFlush immediately sends a Commit message with sectors aggregated for Commit.
 	// Returns null if message wasn't sent
-	SectorCommitFlush(ctx context.Context) ([]sealiface.CommitBatchRes, error) //perm:admin
+	SectorCommitFlush(ctx context.Context) ([]sealiface.CommitBatchRes, error) //perm:sectors:manage
 	// SectorCommitPending returns a list of pending Commit sectors to be sent in the next aggregate message
-	SectorCommitPending(ctx context.Context) ([]abi.SectorID, error) //perm:admin
+	SectorCommitPending(ctx context.Context) ([]abi.SectorID, error) //perm:sectors:read
+	// SectorBatchQueues returns the sectors waiting in the PreCommit, Commit and Terminate batchers with their cutoffs
+	SectorBatchQueues(ctx context.Context) (types.BatchQueues, error) //perm:read
+	// SectorBatchExplain returns the last and the next decision of the batch cost model
+	SectorBatchExplain(ctx context.Context) (types.BatchExplains, error) //perm:read
+	// SectorsHistory returns the states the sector went through and the time spent in each
+	SectorsHistory(ctx context.Context, sid abi.SectorNumber) (types.SectorHistory, error) //perm:read
+	// SectorsVerify checks the sealed replicas against the CommR they were precommitted with, all
+	// active sectors if none are given. Failed checks show up in CheckProvable.
+	SectorsVerify(ctx context.Context, sectors []abi.SectorNumber, rounds int) ([]types.SectorIntegrity, error) //perm:sectors:manage
+	// SectorsVerifyBackground queues the sectors for the rate-limited background checks, all active
+	// sectors if none are given. It returns how many sectors weren't queued yet.
+	SectorsVerifyBackground(ctx context.Context, sectors []abi.SectorNumber) (int, error) //perm:sectors:manage
+	// SectorsIntegrity returns the last integrity check of every checked sector
+	SectorsIntegrity(ctx context.Context) ([]*types.SectorIntegrity, error) //perm:sectors:read
+	// SectorsExport copies the sealed and cache files of the sector with its metadata and their
+	// checksums to a new directory in dir, on the sealer host, or under a prefix in an object
+	// store when dir is a s3://bucket/prefix url
+	SectorsExport(ctx context.Context, sid abi.SectorNumber, dir string) (*storiface.SectorExport, error) //perm:admin
+	// SectorsImport restores a sector exported to the directory, or s3://bucket/prefix url, from
+	// into a local storage path, the sector is only kept if it is provable. Restored sector
+	// metadata is handed to the sealing state machine.
+	SectorsImport(ctx context.Context, from string) (*storiface.SectorExport, error) //perm:admin
 
 	StorageLocal(ctx context.Context) (map[stores.ID]string, error)
 	StorageStat(ctx context.Context, id stores.ID) (fsutil.FsStat, error)
+	// StorageUnsealedCache returns the tracked unsealed copies by storage path, in eviction order
+	StorageUnsealedCache(ctx context.Context) ([]storiface.UnsealedPath, error)
 
 	// WorkerConnect tells the node to connect to workers RPC
 	WorkerConnect(context.Context, string) error
 	WorkerStats(context.Context) (map[uuid.UUID]storiface.WorkerStats, error)
 	WorkerJobs(context.Context) (map[uuid.UUID][]storiface.WorkerJob, error)
+	// WorkerDrain disables the worker, waits for its tasks and moves sector files
+	// only stored on it to other workers
+	WorkerDrain(ctx context.Context, id uuid.UUID) error
+	WorkerDrainCancel(ctx context.Context, id uuid.UUID) error
+	WorkerDrainStatus(ctx context.Context) (map[uuid.UUID]storiface.WorkerDrain, error)
 	storiface.WorkerReturn
 
 	// SealingSchedDiag dumps internal sealing scheduler state
 	SealingSchedDiag(ctx context.Context, doSched bool) (interface{}, error)
 	SealingAbort(ctx context.Context, call types.CallID) error
+	// SealingUnsealQueue lists the unseals for retrievals, those waiting for a worker first
+	SealingUnsealQueue(ctx context.Context) ([]storiface.UnsealJob, error)
+	// SealingUnsealBoost raises the scheduler priority of the unseal of the sector
+	// while it waits for a worker
+	SealingUnsealBoost(ctx context.Context, sector abi.SectorID, priority int) error
 
 	stores.SectorIndex
 
@@ -136,6 +187,12 @@ type StorageMiner interface {
 	DealsSetConsiderUnverifiedStorageDeals(context.Context, bool) error
 
 	StorageAddLocal(ctx context.Context, path string) error
+	// StorageRelayout switches a local storage path to the sector file layout and moves
+	// the files into it, files of locked sectors are skipped
+	StorageRelayout(ctx context.Context, id stores.ID, layout storiface.SectorLayout) (stores.RelayoutResult, error) //perm:admin
+	// StorageVerifyManifest compares the manifest of a local storage path with the sector
+	// files in it, and updates the manifest and the index to match the files
+	StorageVerifyManifest(ctx context.Context, id stores.ID) (stores.ManifestReport, error) //perm:admin
 
 	PiecesListPieces(ctx context.Context) ([]cid.Cid, error)
 	PiecesListCidInfos(ctx context.Context) ([]cid.Cid, error)
@@ -161,6 +218,10 @@ type StorageMiner interface {
 	UpdateDealStatus(ctx context.Context, dealId abi.DealID, status string) error
 
 	DealSector(ctx context.Context) ([]types.DealAssign, error)
+	// DealIngestStatus reports the automatic deal ingestion from venus-market
+	DealIngestStatus(ctx context.Context) (types.DealIngestStatus, error)
+	// DealPackingPlan shows how the packing planner would assign the deals waiting for sectors, without assigning them
+	DealPackingPlan(ctx context.Context) (types.PackingPlan, error)
 	IsUnsealed(ctx context.Context, sector storage.SectorRef, offset storiface.UnpaddedByteIndex, size abi.UnpaddedPieceSize) (bool, error)
 
 	// SectorsUnsealPiece will Unseal a Sealed sector file for the given sector.
@@ -171,6 +232,15 @@ type StorageMiner interface {
 type StorageMinerStruct struct {
 	CommonStruct
 	Internal struct {
+		AuthRevoke      func(ctx context.Context, token string) error            `perm:"admin" audit:"redact"`
+		AuthRevokedList func(ctx context.Context) ([]*types.RevokedToken, error) `perm:"admin"`
+
+		AuditList func(ctx context.Context, filter types.AuditFilter) ([]*types.AuditEntry, error) `perm:"admin"`
+
+		ConfigReload func(ctx context.Context) ([]string, error)                           `perm:"admin"`
+		ConfigGet    func(ctx context.Context, key string) (string, error)                 `perm:"admin"`
+		ConfigSet    func(ctx context.Context, key string, value string) ([]string, error) `perm:"admin" audit:"redact"`
+
 		ComputeProof func(context.Context, []proof2.SectorInfo, abi.PoStRandomness) ([]proof2.PoStProof, error) `perm:"read"`
 
 		ActorAddress       func(context.Context) (address.Address, error)                 `perm:"read"`
@@ -184,77 +254,95 @@ type StorageMinerStruct struct {
 
 		RedoSector func(ctx context.Context, rsi storiface.SectorRedoParams) error `perm:"write"`
 
-		SectorsList                   func(context.Context) ([]abi.SectorNumber, error)                                                `perm:"read"`
-		SectorsListInStates           func(context.Context, []SectorState) ([]abi.SectorNumber, error)                                 `perm:"read"`
-		SectorsInfoListInStates       func(ctx context.Context, ss []SectorState, showOnChainInfo, skipLog bool) ([]SectorInfo, error) `perm:"read"`
-		SectorsSummary                func(ctx context.Context) (map[SectorState]int, error)                                           `perm:"read"`
-		SectorsRefs                   func(context.Context) (map[string][]types.SealedRef, error)                                      `perm:"read"`
-		SectorStartSealing            func(context.Context, abi.SectorNumber) error                                                    `perm:"write"`
-		SectorSetSealDelay            func(context.Context, time.Duration) error                                                       `perm:"write"`
-		SectorGetSealDelay            func(context.Context) (time.Duration, error)                                                     `perm:"read"`
-		SectorSetExpectedSealDuration func(context.Context, time.Duration) error                                                       `perm:"write"`
-		SectorGetExpectedSealDuration func(context.Context) (time.Duration, error)                                                     `perm:"read"`
-		SectorsUpdate                 func(context.Context, abi.SectorNumber, SectorState) error                                       `perm:"admin"`
-		SectorRemove                  func(context.Context, abi.SectorNumber) error                                                    `perm:"admin"`
-		SectorTerminate               func(context.Context, abi.SectorNumber) error                                                    `perm:"admin"`
-		SectorTerminateFlush          func(ctx context.Context) (string, error)                                                        `perm:"admin"`
-		SectorTerminatePending        func(ctx context.Context) ([]abi.SectorID, error)                                                `perm:"admin"`
-		SectorMarkForUpgrade          func(ctx context.Context, id abi.SectorNumber) error                                             `perm:"admin"`
-		SectorPreCommitFlush          func(ctx context.Context) ([]sealiface.PreCommitBatchRes, error)                                 `perm:"admin"`
-		SectorPreCommitPending        func(ctx context.Context) ([]abi.SectorID, error)                                                `perm:"admin"`
-		SectorCommitFlush             func(ctx context.Context) ([]sealiface.CommitBatchRes, error)                                    `perm:"admin"`
-		SectorCommitPending           func(ctx context.Context) ([]abi.SectorID, error)                                                `perm:"admin"`
-
-		WorkerConnect func(context.Context, string) error                                `perm:"admin" retry:"true"` // TODO: worker perm
-		WorkerStats   func(context.Context) (map[uuid.UUID]storiface.WorkerStats, error) `perm:"admin"`
-		WorkerJobs    func(context.Context) (map[uuid.UUID][]storiface.WorkerJob, error) `perm:"admin"`
-
-		ReturnAddPiece        func(ctx context.Context, callID types.CallID, pi abi.PieceInfo, err *storiface.CallError) error          `perm:"admin" retry:"true"`
-		ReturnSealPreCommit1  func(ctx context.Context, callID types.CallID, p1o storage.PreCommit1Out, err *storiface.CallError) error `perm:"admin" retry:"true"`
-		ReturnSealPreCommit2  func(ctx context.Context, callID types.CallID, sealed storage.SectorCids, err *storiface.CallError) error `perm:"admin" retry:"true"`
-		ReturnSealCommit1     func(ctx context.Context, callID types.CallID, out storage.Commit1Out, err *storiface.CallError) error    `perm:"admin" retry:"true"`
-		ReturnSealCommit2     func(ctx context.Context, callID types.CallID, proof storage.Proof, err *storiface.CallError) error       `perm:"admin" retry:"true"`
-		ReturnFinalizeSector  func(ctx context.Context, callID types.CallID, err *storiface.CallError) error                            `perm:"admin" retry:"true"`
-		ReturnReleaseUnsealed func(ctx context.Context, callID types.CallID, err *storiface.CallError) error                            `perm:"admin" retry:"true"`
-		ReturnMoveStorage     func(ctx context.Context, callID types.CallID, err *storiface.CallError) error                            `perm:"admin" retry:"true"`
-		ReturnUnsealPiece     func(ctx context.Context, callID types.CallID, err *storiface.CallError) error                            `perm:"admin" retry:"true"`
-		ReturnReadPiece       func(ctx context.Context, callID types.CallID, ok bool, err *storiface.CallError) error                   `perm:"admin" retry:"true"`
-		ReturnFetch           func(ctx context.Context, callID types.CallID, err *storiface.CallError) error                            `perm:"admin" retry:"true"`
-
-		SealingSchedDiag func(context.Context, bool) (interface{}, error)   `perm:"admin"`
-		SealingAbort     func(ctx context.Context, call types.CallID) error `perm:"admin"`
-
-		StorageList          func(context.Context) (map[stores.ID][]stores.Decl, error)                                                                                   `perm:"admin"`
-		StorageLocal         func(context.Context) (map[stores.ID]string, error)                                                                                          `perm:"admin"`
-		StorageStat          func(context.Context, stores.ID) (fsutil.FsStat, error)                                                                                      `perm:"admin"`
-		StorageAttach        func(context.Context, stores.StorageInfo, fsutil.FsStat) error                                                                               `perm:"admin"`
-		StorageDeclareSector func(context.Context, stores.ID, abi.SectorID, storiface.SectorFileType, bool) error                                                         `perm:"admin"`
-		StorageDropSector    func(context.Context, stores.ID, abi.SectorID, storiface.SectorFileType) error                                                               `perm:"admin"`
-		StorageFindSector    func(context.Context, abi.SectorID, storiface.SectorFileType, abi.SectorSize, bool) ([]stores.SectorStorageInfo, error)                      `perm:"admin"`
-		StorageInfo          func(context.Context, stores.ID) (stores.StorageInfo, error)                                                                                 `perm:"admin"`
-		StorageBestAlloc     func(ctx context.Context, allocate storiface.SectorFileType, ssize abi.SectorSize, sealing storiface.PathType) ([]stores.StorageInfo, error) `perm:"admin"`
-		StorageReportHealth  func(ctx context.Context, id stores.ID, report stores.HealthReport) error                                                                    `perm:"admin"`
-		StorageLock          func(ctx context.Context, sector abi.SectorID, read storiface.SectorFileType, write storiface.SectorFileType) error                          `perm:"admin"`
-		StorageTryLock       func(ctx context.Context, sector abi.SectorID, read storiface.SectorFileType, write storiface.SectorFileType) (bool, error)                  `perm:"admin"`
+		SectorsList                   func(context.Context) ([]abi.SectorNumber, error)                                                  `perm:"read"`
+		SectorsListInStates           func(context.Context, []SectorState) ([]abi.SectorNumber, error)                                   `perm:"read"`
+		SectorsInfoListInStates       func(ctx context.Context, ss []SectorState, showOnChainInfo, skipLog bool) ([]SectorInfo, error)   `perm:"read"`
+		SectorsSummary                func(ctx context.Context) (map[SectorState]int, error)                                             `perm:"read"`
+		SectorsRefs                   func(context.Context) (map[string][]types.SealedRef, error)                                        `perm:"read"`
+		SectorStartSealing            func(context.Context, abi.SectorNumber) error                                                      `perm:"write"`
+		SectorSetSealDelay            func(context.Context, time.Duration) error                                                         `perm:"write"`
+		SectorGetSealDelay            func(context.Context) (time.Duration, error)                                                       `perm:"read"`
+		SectorSetExpectedSealDuration func(context.Context, time.Duration) error                                                         `perm:"write"`
+		SectorGetExpectedSealDuration func(context.Context) (time.Duration, error)                                                       `perm:"read"`
+		SectorsUpdate                 func(context.Context, abi.SectorNumber, SectorState) error                                         `perm:"sectors:manage"`
+		SectorRemove                  func(context.Context, abi.SectorNumber) error                                                      `perm:"sectors:manage"`
+		SectorTerminate               func(context.Context, abi.SectorNumber) error                                                      `perm:"sectors:manage"`
+		SectorTerminateFlush          func(ctx context.Context) (string, error)                                                          `perm:"sectors:manage"`
+		SectorTerminatePending        func(ctx context.Context) ([]abi.SectorID, error)                                                  `perm:"sectors:read"`
+		SectorMarkForUpgrade          func(ctx context.Context, id abi.SectorNumber) error                                               `perm:"sectors:manage"`
+		SectorPreCommitFlush          func(ctx context.Context) ([]sealiface.PreCommitBatchRes, error)                                   `perm:"sectors:manage"`
+		SectorPreCommitPending        func(ctx context.Context) ([]abi.SectorID, error)                                                  `perm:"sectors:read"`
+		SectorCommitFlush             func(ctx context.Context) ([]sealiface.CommitBatchRes, error)                                      `perm:"sectors:manage"`
+		SectorCommitPending           func(ctx context.Context) ([]abi.SectorID, error)                                                  `perm:"sectors:read"`
+		SectorBatchQueues             func(ctx context.Context) (types.BatchQueues, error)                                               `perm:"read"`
+		SectorBatchExplain            func(ctx context.Context) (types.BatchExplains, error)                                             `perm:"read"`
+		SectorsHistory                func(ctx context.Context, sid abi.SectorNumber) (types.SectorHistory, error)                       `perm:"read"`
+		SectorsVerify                 func(ctx context.Context, sectors []abi.SectorNumber, rounds int) ([]types.SectorIntegrity, error) `perm:"sectors:manage"`
+		SectorsVerifyBackground       func(ctx context.Context, sectors []abi.SectorNumber) (int, error)                                 `perm:"sectors:manage"`
+		SectorsExport                 func(ctx context.Context, sid abi.SectorNumber, dir string) (*storiface.SectorExport, error)       `perm:"admin"`
+		SectorsImport                 func(ctx context.Context, from string) (*storiface.SectorExport, error)                            `perm:"admin"`
+		SectorsIntegrity              func(ctx context.Context) ([]*types.SectorIntegrity, error)                                        `perm:"sectors:read"`
+
+		WorkerConnect func(context.Context, string) error                                `perm:"admin" retry:"true" audit:"skip"` // TODO: worker perm
+		WorkerStats   func(context.Context) (map[uuid.UUID]storiface.WorkerStats, error) `perm:"workers:read"`
+		WorkerJobs    func(context.Context) (map[uuid.UUID][]storiface.WorkerJob, error) `perm:"workers:read"`
+
+		WorkerDrain       func(ctx context.Context, id uuid.UUID) error                          `perm:"workers:manage"`
+		WorkerDrainCancel func(ctx context.Context, id uuid.UUID) error                          `perm:"workers:manage"`
+		WorkerDrainStatus func(ctx context.Context) (map[uuid.UUID]storiface.WorkerDrain, error) `perm:"workers:read"`
+
+		ReturnAddPiece        func(ctx context.Context, callID types.CallID, pi abi.PieceInfo, err *storiface.CallError) error          `perm:"admin" retry:"true" audit:"skip"`
+		ReturnSealPreCommit1  func(ctx context.Context, callID types.CallID, p1o storage.PreCommit1Out, err *storiface.CallError) error `perm:"admin" retry:"true" audit:"skip"`
+		ReturnSealPreCommit2  func(ctx context.Context, callID types.CallID, sealed storage.SectorCids, err *storiface.CallError) error `perm:"admin" retry:"true" audit:"skip"`
+		ReturnSealCommit1     func(ctx context.Context, callID types.CallID, out storage.Commit1Out, err *storiface.CallError) error    `perm:"admin" retry:"true" audit:"skip"`
+		ReturnSealCommit2     func(ctx context.Context, callID types.CallID, proof storage.Proof, err *storiface.CallError) error       `perm:"admin" retry:"true" audit:"skip"`
+		ReturnFinalizeSector  func(ctx context.Context, callID types.CallID, err *storiface.CallError) error                            `perm:"admin" retry:"true" audit:"skip"`
+		ReturnReleaseUnsealed func(ctx context.Context, callID types.CallID, err *storiface.CallError) error                            `perm:"admin" retry:"true" audit:"skip"`
+		ReturnMoveStorage     func(ctx context.Context, callID types.CallID, err *storiface.CallError) error                            `perm:"admin" retry:"true" audit:"skip"`
+		ReturnUnsealPiece     func(ctx context.Context, callID types.CallID, err *storiface.CallError) error                            `perm:"admin" retry:"true" audit:"skip"`
+		ReturnReadPiece       func(ctx context.Context, callID types.CallID, ok bool, err *storiface.CallError) error                   `perm:"admin" retry:"true" audit:"skip"`
+		ReturnFetch           func(ctx context.Context, callID types.CallID, err *storiface.CallError) error                            `perm:"admin" retry:"true" audit:"skip"`
+
+		SealingSchedDiag   func(context.Context, bool) (interface{}, error)                   `perm:"workers:read"`
+		SealingAbort       func(ctx context.Context, call types.CallID) error                 `perm:"workers:manage"`
+		SealingUnsealQueue func(ctx context.Context) ([]storiface.UnsealJob, error)           `perm:"workers:read"`
+		SealingUnsealBoost func(ctx context.Context, sector abi.SectorID, priority int) error `perm:"workers:manage"`
+
+		StorageList          func(context.Context) (map[stores.ID][]stores.Decl, error)                                                                                   `perm:"storage:read"`
+		StorageLocal         func(context.Context) (map[stores.ID]string, error)                                                                                          `perm:"storage:read"`
+		StorageStat          func(context.Context, stores.ID) (fsutil.FsStat, error)                                                                                      `perm:"storage:read"`
+		StorageUnsealedCache func(context.Context) ([]storiface.UnsealedPath, error)                                                                                      `perm:"storage:read"`
+		StorageAttach        func(context.Context, stores.StorageInfo, fsutil.FsStat) error                                                                               `perm:"storage:attach"`
+		StorageDeclareSector func(context.Context, stores.ID, abi.SectorID, storiface.SectorFileType, bool) error                                                         `perm:"admin" audit:"skip"`
+		StorageDropSector    func(context.Context, stores.ID, abi.SectorID, storiface.SectorFileType) error                                                               `perm:"admin" audit:"skip"`
+		StorageFindSector    func(context.Context, abi.SectorID, storiface.SectorFileType, abi.SectorSize, bool) ([]stores.SectorStorageInfo, error)                      `perm:"storage:read"`
+		StorageInfo          func(context.Context, stores.ID) (stores.StorageInfo, error)                                                                                 `perm:"storage:read"`
+		StorageBestAlloc     func(ctx context.Context, allocate storiface.SectorFileType, ssize abi.SectorSize, sealing storiface.PathType) ([]stores.StorageInfo, error) `perm:"admin" audit:"skip"`
+		StorageReportHealth  func(ctx context.Context, id stores.ID, report stores.HealthReport) error                                                                    `perm:"admin" audit:"skip"`
+		StorageHealth        func(ctx context.Context, id stores.ID) (stores.PathHealth, error)                                                                           `perm:"storage:read"`
+		StorageLock          func(ctx context.Context, sector abi.SectorID, read storiface.SectorFileType, write storiface.SectorFileType) error                          `perm:"admin" audit:"skip"`
+		StorageTryLock       func(ctx context.Context, sector abi.SectorID, read storiface.SectorFileType, write storiface.SectorFileType) (bool, error)                  `perm:"admin" audit:"skip"`
 
 		DealsImportData                        func(ctx context.Context, dealPropCid cid.Cid, file string) error `perm:"write"`
 		DealsList                              func(ctx context.Context) ([]apitypes.MarketDeal, error)          `perm:"read"`
 		DealsConsiderOnlineStorageDeals        func(context.Context) (bool, error)                               `perm:"read"`
-		DealsSetConsiderOnlineStorageDeals     func(context.Context, bool) error                                 `perm:"admin"`
+		DealsSetConsiderOnlineStorageDeals     func(context.Context, bool) error                                 `perm:"deals:write"`
 		DealsConsiderOnlineRetrievalDeals      func(context.Context) (bool, error)                               `perm:"read"`
-		DealsSetConsiderOnlineRetrievalDeals   func(context.Context, bool) error                                 `perm:"admin"`
+		DealsSetConsiderOnlineRetrievalDeals   func(context.Context, bool) error                                 `perm:"deals:write"`
 		DealsConsiderOfflineStorageDeals       func(context.Context) (bool, error)                               `perm:"read"`
-		DealsSetConsiderOfflineStorageDeals    func(context.Context, bool) error                                 `perm:"admin"`
+		DealsSetConsiderOfflineStorageDeals    func(context.Context, bool) error                                 `perm:"deals:write"`
 		DealsConsiderOfflineRetrievalDeals     func(context.Context) (bool, error)                               `perm:"read"`
-		DealsSetConsiderOfflineRetrievalDeals  func(context.Context, bool) error                                 `perm:"admin"`
+		DealsSetConsiderOfflineRetrievalDeals  func(context.Context, bool) error                                 `perm:"deals:write"`
 		DealsConsiderVerifiedStorageDeals      func(context.Context) (bool, error)                               `perm:"read"`
-		DealsSetConsiderVerifiedStorageDeals   func(context.Context, bool) error                                 `perm:"admin"`
+		DealsSetConsiderVerifiedStorageDeals   func(context.Context, bool) error                                 `perm:"deals:write"`
 		DealsConsiderUnverifiedStorageDeals    func(context.Context) (bool, error)                               `perm:"read"`
-		DealsSetConsiderUnverifiedStorageDeals func(context.Context, bool) error                                 `perm:"admin"`
+		DealsSetConsiderUnverifiedStorageDeals func(context.Context, bool) error                                 `perm:"deals:write"`
 		DealsPieceCidBlocklist                 func(context.Context) ([]cid.Cid, error)                          `perm:"read"`
-		DealsSetPieceCidBlocklist              func(context.Context, []cid.Cid) error                            `perm:"admin"`
+		DealsSetPieceCidBlocklist              func(context.Context, []cid.Cid) error                            `perm:"deals:write"`
 
-		StorageAddLocal func(ctx context.Context, path string) error `perm:"admin"`
+		StorageAddLocal       func(ctx context.Context, path string) error                                                          `perm:"storage:attach"`
+		StorageRelayout       func(ctx context.Context, id stores.ID, layout storiface.SectorLayout) (stores.RelayoutResult, error) `perm:"admin"`
+		StorageVerifyManifest func(ctx context.Context, id stores.ID) (stores.ManifestReport, error)                                `perm:"admin"`
 
 		PiecesListPieces   func(ctx context.Context) ([]cid.Cid, error)                               `perm:"read"`
 		PiecesListCidInfos func(ctx context.Context) ([]cid.Cid, error)                               `perm:"read"`
@@ -263,7 +351,7 @@ type StorageMinerStruct struct {
 
 		CreateBackup func(ctx context.Context, fpath string) error `perm:"admin"`
 
-		CheckProvable func(ctx context.Context, pp abi.RegisteredPoStProof, sectors []storage.SectorRef, expensive bool) (map[abi.SectorNumber]string, error) `perm:"admin"`
+		CheckProvable func(ctx context.Context, pp abi.RegisteredPoStProof, sectors []storage.SectorRef, expensive bool) (map[abi.SectorNumber]string, error) `perm:"proving:read"`
 
 		MessagerWaitMessage func(ctx context.Context, uuid string, confidence uint64) (*chain.MsgLookup, error)  `perm:"read"`
 		MessagerPushMessage func(ctx context.Context, msg *types2.Message, meta *types3.MsgMeta) (string, error) `perm:"sign"`
@@ -274,14 +362,40 @@ type StorageMinerStruct struct {
 		// SectorsUnsealPiece will Unseal a Sealed sector file for the given sector.
 		SectorsUnsealPiece func(ctx context.Context, sector storage.SectorRef, offset storiface.UnpaddedByteIndex, size abi.UnpaddedPieceSize, randomness abi.SealRandomness, commd *cid.Cid) error `perm:"write"`
 
-		DealSector func(ctx context.Context) ([]types.DealAssign, error) `perm:"admin"`
+		DealSector       func(ctx context.Context) ([]types.DealAssign, error)     `perm:"deals:write"`
+		DealIngestStatus func(ctx context.Context) (types.DealIngestStatus, error) `perm:"read"`
+		DealPackingPlan  func(ctx context.Context) (types.PackingPlan, error)      `perm:"read"`
 
 		GetDeals           func(ctx context.Context, pageIndex, pageSize int) ([]*piece.DealInfo, error) `perm:"admin"`
-		MarkDealsAsPacking func(ctx context.Context, deals []abi.DealID) error                           `perm:"admin"`
-		UpdateDealStatus   func(ctx context.Context, dealId abi.DealID, status string) error             `perm:"admin"`
+		MarkDealsAsPacking func(ctx context.Context, deals []abi.DealID) error                           `perm:"deals:write"`
+		UpdateDealStatus   func(ctx context.Context, dealId abi.DealID, status string) error             `perm:"deals:write"`
 	}
 }
 
+func (c *StorageMinerStruct) AuthRevoke(ctx context.Context, token string) error {
+	return c.Internal.AuthRevoke(ctx, token)
+}
+
+func (c *StorageMinerStruct) AuthRevokedList(ctx context.Context) ([]*types.RevokedToken, error) {
+	return c.Internal.AuthRevokedList(ctx)
+}
+
+func (c *StorageMinerStruct) AuditList(ctx context.Context, filter types.AuditFilter) ([]*types.AuditEntry, error) {
+	return c.Internal.AuditList(ctx, filter)
+}
+
+func (c *StorageMinerStruct) ConfigReload(ctx context.Context) ([]string, error) {
+	return c.Internal.ConfigReload(ctx)
+}
+
+func (c *StorageMinerStruct) ConfigGet(ctx context.Context, key string) (string, error) {
+	return c.Internal.ConfigGet(ctx, key)
+}
+
+func (c *StorageMinerStruct) ConfigSet(ctx context.Context, key string, value string) ([]string, error) {
+	return c.Internal.ConfigSet(ctx, key, value)
+}
+
 func (c *StorageMinerStruct) IsUnsealed(ctx context.Context, sector storage.SectorRef, offset storiface.UnpaddedByteIndex, size abi.UnpaddedPieceSize) (bool, error) {
 	return c.Internal.IsUnsealed(ctx, sector, offset, size)
 }
@@ -406,6 +520,38 @@ func (c *StorageMinerStruct) SectorCommitPending(ctx context.Context) ([]abi.Sec
 	return c.Internal.SectorCommitPending(ctx)
 }
 
+func (c *StorageMinerStruct) SectorBatchQueues(ctx context.Context) (types.BatchQueues, error) {
+	return c.Internal.SectorBatchQueues(ctx)
+}
+
+func (c *StorageMinerStruct) SectorBatchExplain(ctx context.Context) (types.BatchExplains, error) {
+	return c.Internal.SectorBatchExplain(ctx)
+}
+
+func (c *StorageMinerStruct) SectorsHistory(ctx context.Context, sid abi.SectorNumber) (types.SectorHistory, error) {
+	return c.Internal.SectorsHistory(ctx, sid)
+}
+
+func (c *StorageMinerStruct) SectorsVerify(ctx context.Context, sectors []abi.SectorNumber, rounds int) ([]types.SectorIntegrity, error) {
+	return c.Internal.SectorsVerify(ctx, sectors, rounds)
+}
+
+func (c *StorageMinerStruct) SectorsVerifyBackground(ctx context.Context, sectors []abi.SectorNumber) (int, error) {
+	return c.Internal.SectorsVerifyBackground(ctx, sectors)
+}
+
+func (c *StorageMinerStruct) SectorsIntegrity(ctx context.Context) ([]*types.SectorIntegrity, error) {
+	return c.Internal.SectorsIntegrity(ctx)
+}
+
+func (c *StorageMinerStruct) SectorsExport(ctx context.Context, sid abi.SectorNumber, dir string) (*storiface.SectorExport, error) {
+	return c.Internal.SectorsExport(ctx, sid, dir)
+}
+
+func (c *StorageMinerStruct) SectorsImport(ctx context.Context, from string) (*storiface.SectorExport, error) {
+	return c.Internal.SectorsImport(ctx, from)
+}
+
 func (c *StorageMinerStruct) WorkerConnect(ctx context.Context, url string) error {
 	return c.Internal.WorkerConnect(ctx, url)
 }
@@ -418,6 +564,18 @@ func (c *StorageMinerStruct) WorkerJobs(ctx context.Context) (map[uuid.UUID][]st
 	return c.Internal.WorkerJobs(ctx)
 }
 
+func (c *StorageMinerStruct) WorkerDrain(ctx context.Context, id uuid.UUID) error {
+	return c.Internal.WorkerDrain(ctx, id)
+}
+
+func (c *StorageMinerStruct) WorkerDrainCancel(ctx context.Context, id uuid.UUID) error {
+	return c.Internal.WorkerDrainCancel(ctx, id)
+}
+
+func (c *StorageMinerStruct) WorkerDrainStatus(ctx context.Context) (map[uuid.UUID]storiface.WorkerDrain, error) {
+	return c.Internal.WorkerDrainStatus(ctx)
+}
+
 func (c *StorageMinerStruct) ReturnAddPiece(ctx context.Context, callID types.CallID, pi abi.PieceInfo, err *storiface.CallError) error {
 	return c.Internal.ReturnAddPiece(ctx, callID, pi, err)
 }
@@ -470,6 +628,14 @@ func (c *StorageMinerStruct) SealingAbort(ctx context.Context, call types.CallID
 	return c.Internal.SealingAbort(ctx, call)
 }
 
+func (c *StorageMinerStruct) SealingUnsealQueue(ctx context.Context) ([]storiface.UnsealJob, error) {
+	return c.Internal.SealingUnsealQueue(ctx)
+}
+
+func (c *StorageMinerStruct) SealingUnsealBoost(ctx context.Context, sector abi.SectorID, priority int) error {
+	return c.Internal.SealingUnsealBoost(ctx, sector, priority)
+}
+
 func (c *StorageMinerStruct) StorageAttach(ctx context.Context, si stores.StorageInfo, st fsutil.FsStat) error {
 	return c.Internal.StorageAttach(ctx, si, st)
 }
@@ -498,6 +664,10 @@ func (c *StorageMinerStruct) StorageStat(ctx context.Context, id stores.ID) (fsu
 	return c.Internal.StorageStat(ctx, id)
 }
 
+func (c *StorageMinerStruct) StorageUnsealedCache(ctx context.Context) ([]storiface.UnsealedPath, error) {
+	return c.Internal.StorageUnsealedCache(ctx)
+}
+
 func (c *StorageMinerStruct) StorageInfo(ctx context.Context, id stores.ID) (stores.StorageInfo, error) {
 	return c.Internal.StorageInfo(ctx, id)
 }
@@ -510,6 +680,10 @@ func (c *StorageMinerStruct) StorageReportHealth(ctx context.Context, id stores.
 	return c.Internal.StorageReportHealth(ctx, id, report)
 }
 
+func (c *StorageMinerStruct) StorageHealth(ctx context.Context, id stores.ID) (stores.PathHealth, error) {
+	return c.Internal.StorageHealth(ctx, id)
+}
+
 func (c *StorageMinerStruct) StorageLock(ctx context.Context, sector abi.SectorID, read storiface.SectorFileType, write storiface.SectorFileType) error {
 	return c.Internal.StorageLock(ctx, sector, read, write)
 }
@@ -586,6 +760,14 @@ func (c *StorageMinerStruct) StorageAddLocal(ctx context.Context, path string) e
 	return c.Internal.StorageAddLocal(ctx, path)
 }
 
+func (c *StorageMinerStruct) StorageRelayout(ctx context.Context, id stores.ID, layout storiface.SectorLayout) (stores.RelayoutResult, error) {
+	return c.Internal.StorageRelayout(ctx, id, layout)
+}
+
+func (c *StorageMinerStruct) StorageVerifyManifest(ctx context.Context, id stores.ID) (stores.ManifestReport, error) {
+	return c.Internal.StorageVerifyManifest(ctx, id)
+}
+
 func (c *StorageMinerStruct) PiecesListPieces(ctx context.Context) ([]cid.Cid, error) {
 	return c.Internal.PiecesListPieces(ctx)
 }
@@ -630,6 +812,14 @@ func (c *StorageMinerStruct) DealSector(ctx context.Context) ([]types.DealAssign
 	return c.Internal.DealSector(ctx)
 }
 
+func (c *StorageMinerStruct) DealIngestStatus(ctx context.Context) (types.DealIngestStatus, error) {
+	return c.Internal.DealIngestStatus(ctx)
+}
+
+func (c *StorageMinerStruct) DealPackingPlan(ctx context.Context) (types.PackingPlan, error) {
+	return c.Internal.DealPackingPlan(ctx)
+}
+
 func (c *StorageMinerStruct) GetDeals(ctx context.Context, pageIndex, pageSize int) ([]*piece.DealInfo, error) {
 	return c.Internal.GetDeals(ctx, pageIndex, pageSize)
 }
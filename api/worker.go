@@ -42,6 +42,10 @@ type WorkerStruct struct {
 		TaskEnable  func(ctx context.Context, tt types.TaskType) error `perm:"admin"`
 
 		TaskNumbers  func(context.Context) (string, error)                                  `perm:"admin"`
+		TaskLimits   func(context.Context) (map[types.TaskType]int64, error)                `perm:"admin"`
+		SetTaskLimit func(ctx context.Context, tt types.TaskType, limit int64) error        `perm:"admin"`
+		BindP1P2     func(context.Context) (bool, error)                                    `perm:"admin"`
+		SetBindP1P2  func(ctx context.Context, bind bool) error                             `perm:"admin"`
 		SectorExists func(context.Context, types.TaskType, storage.SectorRef) (bool, error) `perm:"admin"`
 
 		Remove          func(ctx context.Context, sector abi.SectorID) error `perm:"admin"`
@@ -131,6 +135,22 @@ func (w *WorkerStruct) TaskNumbers(ctx context.Context) (string, error) {
 	return w.Internal.TaskNumbers(ctx)
 }
 
+func (w *WorkerStruct) TaskLimits(ctx context.Context) (map[types.TaskType]int64, error) {
+	return w.Internal.TaskLimits(ctx)
+}
+
+func (w *WorkerStruct) SetTaskLimit(ctx context.Context, tt types.TaskType, limit int64) error {
+	return w.Internal.SetTaskLimit(ctx, tt, limit)
+}
+
+func (w *WorkerStruct) BindP1P2(ctx context.Context) (bool, error) {
+	return w.Internal.BindP1P2(ctx)
+}
+
+func (w *WorkerStruct) SetBindP1P2(ctx context.Context, bind bool) error {
+	return w.Internal.SetBindP1P2(ctx, bind)
+}
+
 func (w *WorkerStruct) SectorExists(ctx context.Context, task types.TaskType, sector storage.SectorRef) (bool, error) {
 	return w.Internal.SectorExists(ctx, task, sector)
 }
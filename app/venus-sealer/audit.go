@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/filecoin-project/venus-sealer/api"
+	"github.com/filecoin-project/venus-sealer/lib/tablewriter"
+	"github.com/filecoin-project/venus-sealer/types"
+)
+
+var auditCmd = &cli.Command{
+	Name:  "audit",
+	Usage: "Inspect calls of mutating api methods",
+	Subcommands: []*cli.Command{
+		auditListCmd,
+	},
+}
+
+var auditListCmd = &cli.Command{
+	Name:  "list",
+	Usage: "List recorded api calls, newest first",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "method",
+			Usage: "only show calls of the method, eg. SectorRemove",
+		},
+		&cli.StringFlag{
+			Name:  "caller",
+			Usage: "only show calls made with the token, eg. id:<token id>",
+		},
+		&cli.DurationFlag{
+			Name:  "since",
+			Usage: "only show calls made within the duration",
+		},
+		&cli.BoolFlag{
+			Name:  "failed",
+			Usage: "only show calls which returned an error",
+		},
+		&cli.IntFlag{
+			Name:  "limit",
+			Usage: "maximum number of calls to show, 0 for all",
+			Value: 100,
+		},
+		&cli.BoolFlag{
+			Name:  "json",
+			Usage: "output in json format",
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		storageAPI, closer, err := api.GetStorageMinerAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		filter := types.AuditFilter{
+			Method:     cctx.String("method"),
+			Caller:     cctx.String("caller"),
+			FailedOnly: cctx.Bool("failed"),
+			Limit:      cctx.Int("limit"),
+		}
+		if cctx.IsSet("since") {
+			filter.Since = time.Now().Add(-cctx.Duration("since"))
+		}
+
+		entries, err := storageAPI.AuditList(api.ReqContext(cctx), filter)
+		if err != nil {
+			return err
+		}
+
+		if cctx.Bool("json") {
+			out, err := json.MarshalIndent(entries, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(out))
+			return nil
+		}
+
+		tw := tablewriter.New(
+			tablewriter.Col("Time"),
+			tablewriter.Col("Method"),
+			tablewriter.Col("Caller"),
+			tablewriter.Col("Remote"),
+			tablewriter.Col("Took"),
+			tablewriter.Col("Params"),
+			tablewriter.NewLineCol("Error"),
+		)
+		for _, e := range entries {
+			row := map[string]interface{}{
+				"Time":   e.Time.Format(time.RFC3339),
+				"Method": e.Method,
+				"Caller": e.Caller,
+				"Remote": e.Remote,
+				"Took":   e.Duration.Truncate(time.Millisecond),
+				"Params": e.Params,
+			}
+			if e.Error != "" {
+				row["Error"] = e.Error
+			}
+			tw.Write(row)
+		}
+		return tw.Flush(os.Stdout)
+	},
+}
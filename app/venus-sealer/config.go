@@ -0,0 +1,308 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ipfs-force-community/venus-common-utils/apiinfo"
+	"github.com/urfave/cli/v2"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/venus-sealer/api"
+	"github.com/filecoin-project/venus-sealer/config"
+)
+
+var configCmd = &cli.Command{
+	Name:  "config",
+	Usage: "Inspect and change the config of the running sealer",
+	Description: `Changes of the Sealing, Dealmaking, Fees, Addresses and IntegrityAudit sections,
+and of the Affinity, SectorLabels, UnsealedCache, UnsealPriority and VerifyUnsealedPieces
+options of the Storage section take effect immediately, changes of other
+sections are saved and take effect after a restart.`,
+	Subcommands: []*cli.Command{
+		configReloadCmd,
+		configGetCmd,
+		configSetCmd,
+		configCheckCmd,
+		configDiffCmd,
+	},
+}
+
+var configReloadCmd = &cli.Command{
+	Name:  "reload",
+	Usage: "Apply the config file to the running sealer",
+	Action: func(cctx *cli.Context) error {
+		storageAPI, closer, err := api.GetStorageMinerAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		restart, err := storageAPI.ConfigReload(api.ReqContext(cctx))
+		if err != nil {
+			return err
+		}
+
+		fmt.Println("config reloaded")
+		printRestartRequired(restart)
+		return nil
+	},
+}
+
+var configGetCmd = &cli.Command{
+	Name:      "get",
+	Usage:     "Print the current config, or the value of a key",
+	ArgsUsage: "[key, eg. Sealing.MaxSealingSectors]",
+	Action: func(cctx *cli.Context) error {
+		if cctx.Args().Len() > 1 {
+			return xerrors.Errorf("expected at most one key")
+		}
+
+		storageAPI, closer, err := api.GetStorageMinerAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		val, err := storageAPI.ConfigGet(api.ReqContext(cctx), cctx.Args().First())
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(strings.TrimSpace(val))
+		return nil
+	},
+}
+
+var configSetCmd = &cli.Command{
+	Name:      "set",
+	Usage:     "Change a config value and save it to the config file",
+	ArgsUsage: "<key> <value>",
+	Description: `The value is given in TOML, strings don't need to be quoted, eg.
+   venus-sealer config set Sealing.MaxSealingSectors 10
+   venus-sealer config set Sealing.WaitDealsDelay 2h0m0s
+   venus-sealer config set Addresses.CommitControl '["f3..."]'`,
+	Action: func(cctx *cli.Context) error {
+		if cctx.Args().Len() != 2 {
+			return xerrors.Errorf("expected a key and a value")
+		}
+
+		storageAPI, closer, err := api.GetStorageMinerAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		restart, err := storageAPI.ConfigSet(api.ReqContext(cctx), cctx.Args().Get(0), cctx.Args().Get(1))
+		if err != nil {
+			return err
+		}
+
+		printRestartRequired(restart)
+		return nil
+	},
+}
+
+func printRestartRequired(sections []string) {
+	if len(sections) == 0 {
+		return
+	}
+	fmt.Printf("changes of %s take effect after a restart\n", strings.Join(sections, ", "))
+}
+
+var configCheckCmd = &cli.Command{
+	Name:  "check",
+	Usage: "Validate the config file",
+	Description: `Checks every section of the config file, limits depending on the network
+are computed with NetParams. Unless --offline is set the configured services are
+dialed and the network of the node is compared with NetParams.`,
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "offline",
+			Usage: "don't contact the node and the other configured services",
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		cfg, err := loadSealerConfig(cctx)
+		if err != nil {
+			return xerrors.Errorf("reading venus-sealer config: %w", err)
+		}
+
+		problems := config.Check(cfg)
+		if !cctx.Bool("offline") {
+			problems = append(problems, checkConfigServices(cctx.Context, cfg, true)...)
+			if err := config.FatalProblems(problems); err == nil {
+				nodeApi, closer, err := api.GetFullNodeAPIV2(cctx)
+				if err != nil {
+					return xerrors.Errorf("getting full node api: %w", err)
+				}
+				defer closer()
+
+				problems = append(problems, checkConfigNetwork(cctx.Context, cfg, nodeApi)...)
+			}
+		}
+
+		for _, p := range problems {
+			fmt.Println(p)
+		}
+		if err := config.FatalProblems(problems); err != nil {
+			return err
+		}
+
+		fmt.Println("config ok")
+		return nil
+	},
+}
+
+var configDiffCmd = &cli.Command{
+	Name:  "diff",
+	Usage: "Print the values of the config file which differ from the defaults",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "network",
+			Usage: fmt.Sprintf("network to compare with the defaults of, one of %v, guessed from NetParams by default", config.Networks),
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		cfg, err := loadSealerConfig(cctx)
+		if err != nil {
+			return xerrors.Errorf("reading venus-sealer config: %w", err)
+		}
+
+		network := cctx.String("network")
+		if network == "" {
+			network = config.GuessNetwork(cfg)
+		}
+		def, err := config.GetDefaultStorageConfig(network)
+		if err != nil {
+			return err
+		}
+
+		diff, err := config.Diff(cfg, def)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("# compared with the %s defaults\n", network)
+		for _, e := range diff {
+			fmt.Printf("%s = %s (default: %s)\n", e.Key, e.Value, e.Default)
+		}
+		return nil
+	},
+}
+
+// venus network names of the networks with a default config
+var networkNames = map[string]string{
+	"mainnet":        "mainnet",
+	"testnetnet":     "mainnet",
+	"calibrationnet": "calibration",
+	"2k":             "2k",
+	"2knet":          "2k",
+	"forcenet":       "force",
+}
+
+const configDialTimeout = 5 * time.Second
+
+type serviceURL struct {
+	key string
+	url string
+}
+
+// loadSealerConfig reads the config file `run` starts the sealer with
+func loadSealerConfig(cctx *cli.Context) (*config.StorageMiner, error) {
+	repoPath := cctx.String("repo")
+	cfgPath := config.FsConfig(repoPath)
+	cfg, err := config.MinerFromFile(cfgPath)
+	if err != nil {
+		return nil, err
+	}
+	cfg.DataDir = repoPath
+	cfg.ConfigPath = cfgPath
+
+	return cfg, nil
+}
+
+// checkConfigServices checks that the configured services can be reached,
+// unreachable services are only reported as warnings unless fatal is set
+func checkConfigServices(ctx context.Context, cfg *config.StorageMiner, fatal bool) []config.Problem {
+	urls := []serviceURL{
+		{"Node.Url", cfg.Node.Url},
+		{"Messager.Url", cfg.Messager.Url},
+		{"Market.Url", cfg.Market.Url},
+	}
+	for i, u := range cfg.RegisterProof.Urls {
+		urls = append(urls, serviceURL{fmt.Sprintf("RegisterProof.Urls[%d]", i), u})
+	}
+	for i, u := range cfg.RegisterMarket.Urls {
+		urls = append(urls, serviceURL{fmt.Sprintf("RegisterMarket.Urls[%d]", i), u})
+	}
+
+	var problems []config.Problem
+	for _, u := range urls {
+		if u.url == "" {
+			continue
+		}
+		if err := dialService(ctx, u.url); err != nil {
+			problems = append(problems, config.Problem{Key: u.key, Message: fmt.Sprintf("unreachable: %s", err), Fatal: fatal})
+		}
+	}
+	return problems
+}
+
+func dialService(ctx context.Context, addr string) error {
+	ai := apiinfo.APIInfo{Addr: addr}
+	dialArgs, err := ai.DialArgs("v0")
+	if err != nil {
+		return err
+	}
+
+	u, err := url.Parse(dialArgs)
+	if err != nil {
+		return err
+	}
+
+	host := u.Host
+	if u.Port() == "" {
+		port := "80"
+		if u.Scheme == "https" || u.Scheme == "wss" {
+			port = "443"
+		}
+		host = net.JoinHostPort(u.Hostname(), port)
+	}
+
+	d := net.Dialer{Timeout: configDialTimeout}
+	conn, err := d.DialContext(ctx, "tcp", host)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// checkConfigNetwork compares NetParams with the defaults of the network the node is on
+func checkConfigNetwork(ctx context.Context, cfg *config.StorageMiner, nodeApi api.FullNode) []config.Problem {
+	name, err := nodeApi.StateNetworkName(ctx)
+	if err != nil {
+		return []config.Problem{{Key: "Node", Message: fmt.Sprintf("getting network name: %s", err), Fatal: true}}
+	}
+
+	network, ok := networkNames[string(name)]
+	if !ok {
+		return nil
+	}
+	def, err := config.GetDefaultStorageConfig(network)
+	if err != nil {
+		return nil
+	}
+
+	if def.NetParams != cfg.NetParams {
+		return []config.Problem{{
+			Key:     "NetParams",
+			Message: fmt.Sprintf("the node is on %s, whose defaults are %+v, configured %+v", name, def.NetParams, cfg.NetParams),
+		}}
+	}
+	return nil
+}
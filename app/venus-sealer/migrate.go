@@ -0,0 +1,568 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+
+	"github.com/fatih/color"
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/namespace"
+	"github.com/ipfs/go-datastore/query"
+	levelds "github.com/ipfs/go-ds-leveldb"
+	"github.com/mitchellh/go-homedir"
+	ldbopts "github.com/syndtr/goleveldb/leveldb/opt"
+	"github.com/urfave/cli/v2"
+	"github.com/zbiljic/go-filelock"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-address"
+	cborutil "github.com/filecoin-project/go-cbor-util"
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/venus-sealer/config"
+	"github.com/filecoin-project/venus-sealer/models"
+	"github.com/filecoin-project/venus-sealer/models/repo"
+	sectorstorage "github.com/filecoin-project/venus-sealer/sector-storage"
+	"github.com/filecoin-project/venus-sealer/sector-storage/stores"
+	"github.com/filecoin-project/venus-sealer/sector-storage/storiface"
+	"github.com/filecoin-project/venus-sealer/service"
+	lotustypes "github.com/filecoin-project/venus-sealer/tool/convert-with-lotus/types"
+	types2 "github.com/filecoin-project/venus-sealer/types"
+)
+
+const (
+	lotusMetadataDs        = "datastore/metadata"
+	lotusSectorStorePrefix = "/sectors"
+	lotusStorageCounterKey = "/storage/nextid"
+	lotusMinerAddressKey   = "/miner-address"
+	lotusStorageConfig     = "storage.json"
+)
+
+// sectors in these states have messages in flight which were sent by lotus-miner; the
+// message cids can't be tracked by venus-messager and have to be checked by the operator
+var lotusInflightStates = map[types2.SectorState]struct{}{
+	types2.PreCommitWait:       {},
+	types2.PreCommitBatchWait:  {},
+	types2.CommitWait:          {},
+	types2.CommitAggregateWait: {},
+	types2.TerminateWait:       {},
+}
+
+var migrateCmd = &cli.Command{
+	Name:  "migrate",
+	Usage: "Migrate data from other miner implementations",
+	Subcommands: []*cli.Command{
+		migrateFromLotusCmd,
+	},
+}
+
+var migrateFromLotusCmd = &cli.Command{
+	Name:  "from-lotus",
+	Usage: "Import sectors, storage paths and the sector counter from a lotus-miner repo",
+	Description: `The venus-sealer repo must already be initialized for the same miner actor, and
+both venus-sealer and lotus-miner must be stopped.
+
+The migration is safe to re-run: sectors which already exist in the venus-sealer
+database are skipped, storage paths are only added once, and the sector counter is
+never moved backwards. An interrupted migration can be resumed by running the
+command again.`,
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:     "lotus-miner-repo",
+			Usage:    "path to the lotus-miner repo",
+			Required: true,
+		},
+		&cli.BoolFlag{
+			Name:  "skip-storage",
+			Usage: "don't import storage paths from the lotus-miner storage.json",
+		},
+		&cli.BoolFlag{
+			Name:  "skip-verify",
+			Usage: "don't check sealed and cache files of proving sectors",
+		},
+		&cli.Uint64Flag{
+			Name:  "sector-counter",
+			Usage: "override the sector counter (default: max of the lotus counter and the largest sector number)",
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		lmRepo, err := homedir.Expand(cctx.String("lotus-miner-repo"))
+		if err != nil {
+			return xerrors.Errorf("expanding lotus-miner repo path: %w", err)
+		}
+
+		repoPath := cctx.String("repo")
+		cfg, err := config.MinerFromFile(config.FsConfig(repoPath))
+		if err != nil {
+			return xerrors.Errorf("reading venus-sealer config (is the repo initialized?): %w", err)
+		}
+		cfg.DataDir = repoPath
+		cfg.ConfigPath = config.FsConfig(repoPath)
+
+		dataDir, err := homedir.Expand(cfg.DataDir)
+		if err != nil {
+			return err
+		}
+		fl, err := filelock.New(path.Join(dataDir, "repo.lock"))
+		if err != nil {
+			return err
+		}
+		locked, err := fl.TryLock()
+		if err != nil {
+			return xerrors.Errorf("locking venus-sealer repo: %w", err)
+		}
+		if !locked {
+			return xerrors.Errorf("venus-sealer repo is locked, stop venus-sealer first")
+		}
+		defer fl.Unlock() //nolint:errcheck
+
+		lds, err := levelds.NewDatastore(filepath.Join(lmRepo, lotusMetadataDs), &levelds.Options{
+			Compression: ldbopts.NoCompression,
+			Strict:      ldbopts.StrictAll,
+			ReadOnly:    true,
+		})
+		if err != nil {
+			return xerrors.Errorf("opening lotus-miner metadata (is lotus-miner running?): %w", err)
+		}
+		defer lds.Close() //nolint:errcheck
+
+		dbRepo, err := models.SetDataBase(config.HomeDir(dataDir), &cfg.DB)
+		if err != nil {
+			return err
+		}
+		defer dbRepo.DbClose() //nolint:errcheck
+		if err := dbRepo.AutoMigrate(); err != nil {
+			return err
+		}
+
+		metadataService := service.NewMetadataService(dbRepo)
+		maddr, err := metadataService.GetMinerAddress()
+		if err != nil {
+			return xerrors.Errorf("getting miner address (is the repo initialized?): %w", err)
+		}
+		mid, err := address.IDFromAddress(maddr)
+		if err != nil {
+			return err
+		}
+
+		m := &lotusMigration{
+			lotusRepo: lmRepo,
+			lotusDs:   lds,
+			miner:     abi.ActorID(mid),
+			repo:      dbRepo,
+			meta:      metadataService,
+			ls:        cfg.LocalStorage(),
+		}
+
+		if err := m.checkMiner(); err != nil {
+			return err
+		}
+
+		if err := m.importSectors(); err != nil {
+			return xerrors.Errorf("importing sectors: %w", err)
+		}
+
+		if !cctx.Bool("skip-storage") {
+			if err := m.importStorage(); err != nil {
+				return xerrors.Errorf("importing storage paths: %w", err)
+			}
+		}
+
+		if err := m.importCounter(cctx.Uint64("sector-counter")); err != nil {
+			return xerrors.Errorf("importing sector counter: %w", err)
+		}
+
+		if !cctx.Bool("skip-verify") {
+			if err := m.verifySectorFiles(); err != nil {
+				return xerrors.Errorf("verifying sector files: %w", err)
+			}
+		}
+
+		m.printReport(cctx.Bool("skip-verify"))
+		return nil
+	},
+}
+
+type lotusMigration struct {
+	lotusRepo string
+	lotusDs   datastore.Batching
+	miner     abi.ActorID
+	repo      repo.Repo
+	meta      *service.MetadataService
+	ls        *config.LocalStorage
+
+	// report
+	imported     []abi.SectorNumber
+	skipped      []abi.SectorNumber
+	decodeErrs   map[string]string
+	inflight     []abi.SectorNumber
+	proving      []*types2.SectorInfo
+	maxSector    abi.SectorNumber
+	counterOld   abi.SectorNumber
+	counterNew   abi.SectorNumber
+	pathsAdded   []string
+	pathsExisted []string
+	pathsBad     map[string]string
+	storePaths   []string
+	verifiedOk   int
+	verifyBad    map[abi.SectorNumber]string
+}
+
+func (m *lotusMigration) importSectors() error {
+	ds := namespace.Wrap(m.lotusDs, datastore.NewKey(lotusSectorStorePrefix))
+	res, err := ds.Query(query.Query{})
+	if err != nil {
+		return err
+	}
+	defer res.Close() //nolint:errcheck
+
+	m.decodeErrs = map[string]string{}
+	sectorRepo := m.repo.SectorInfoRepo()
+	logRepo := m.repo.LogRepo()
+
+	for r := range res.Next() {
+		if r.Error != nil {
+			return r.Error
+		}
+
+		var lsector lotustypes.SectorInfo
+		if err := cborutil.ReadCborRPC(bytes.NewReader(r.Value), &lsector); err != nil {
+			m.decodeErrs[r.Key] = err.Error()
+			continue
+		}
+
+		sector, err := fromLotusSectorInfo(&lsector)
+		if err != nil {
+			m.decodeErrs[r.Key] = err.Error()
+			continue
+		}
+
+		if sector.SectorNumber > m.maxSector {
+			m.maxSector = sector.SectorNumber
+		}
+		if sector.State == types2.Proving {
+			m.proving = append(m.proving, sector)
+		}
+
+		has, err := sectorRepo.HasSectorInfo(uint64(sector.SectorNumber))
+		if err != nil {
+			return xerrors.Errorf("checking sector %d: %w", sector.SectorNumber, err)
+		}
+		if has {
+			m.skipped = append(m.skipped, sector.SectorNumber)
+			continue
+		}
+
+		if _, ok := lotusInflightStates[sector.State]; ok {
+			m.inflight = append(m.inflight, sector.SectorNumber)
+		}
+
+		// logs are written first, a sector is only considered imported once its info is saved,
+		// so a resumed migration starts over with a clean log
+		if err := logRepo.DelLogs(uint64(sector.SectorNumber)); err != nil {
+			return xerrors.Errorf("cleaning logs of sector %d: %w", sector.SectorNumber, err)
+		}
+		for _, l := range lsector.Log {
+			if err := logRepo.Append(&types2.Log{
+				SectorNumber: sector.SectorNumber,
+				Timestamp:    l.Timestamp,
+				Trace:        l.Trace,
+				Message:      l.Message,
+				Kind:         l.Kind,
+			}); err != nil {
+				return xerrors.Errorf("saving logs of sector %d: %w", sector.SectorNumber, err)
+			}
+		}
+
+		if err := sectorRepo.Save(sector); err != nil {
+			return xerrors.Errorf("saving sector %d: %w", sector.SectorNumber, err)
+		}
+		m.imported = append(m.imported, sector.SectorNumber)
+	}
+
+	return nil
+}
+
+func (m *lotusMigration) importStorage() error {
+	lsc, err := config.StorageFromFile(filepath.Join(m.lotusRepo, lotusStorageConfig), &stores.StorageConfig{})
+	if err != nil {
+		return xerrors.Errorf("reading lotus-miner storage config: %w", err)
+	}
+
+	sc, err := m.ls.GetStorage()
+	if err != nil {
+		sc = stores.StorageConfig{}
+	}
+	existing := map[string]struct{}{}
+	for _, p := range sc.StoragePaths {
+		existing[filepath.Clean(p.Path)] = struct{}{}
+	}
+
+	m.pathsBad = map[string]string{}
+	var toAdd []stores.LocalPath
+	for _, lp := range lsc.StoragePaths {
+		p, err := homedir.Expand(lp.Path)
+		if err != nil {
+			m.pathsBad[lp.Path] = err.Error()
+			continue
+		}
+		p = filepath.Clean(p)
+
+		// the index is built from sectorstore.json when the path is opened, make sure it's usable
+		mb, err := ioutil.ReadFile(filepath.Join(p, metaFile))
+		if err != nil {
+			m.pathsBad[p] = err.Error()
+			continue
+		}
+		var meta stores.LocalStorageMeta
+		if err := json.Unmarshal(mb, &meta); err != nil {
+			m.pathsBad[p] = xerrors.Errorf("unmarshalling %s: %w", metaFile, err).Error()
+			continue
+		}
+
+		m.storePaths = append(m.storePaths, p)
+		if _, ok := existing[p]; ok {
+			m.pathsExisted = append(m.pathsExisted, p)
+			continue
+		}
+		existing[p] = struct{}{}
+		toAdd = append(toAdd, stores.LocalPath{Path: p})
+		m.pathsAdded = append(m.pathsAdded, p)
+	}
+
+	if len(toAdd) == 0 {
+		return nil
+	}
+
+	return m.ls.SetStorage(func(sc *stores.StorageConfig) {
+		sc.StoragePaths = append(sc.StoragePaths, toAdd...)
+	})
+}
+
+// checkMiner refuses to import a lotus-miner repo that belongs to another miner
+func (m *lotusMigration) checkMiner() error {
+	b, err := m.lotusDs.Get(datastore.NewKey(lotusMinerAddressKey))
+	if err != nil {
+		return xerrors.Errorf("reading lotus-miner address: %w", err)
+	}
+	laddr, err := address.NewFromBytes(b)
+	if err != nil {
+		return xerrors.Errorf("decoding lotus-miner address: %w", err)
+	}
+	lid, err := address.IDFromAddress(laddr)
+	if err != nil {
+		return xerrors.Errorf("lotus-miner address %s: %w", laddr, err)
+	}
+	if abi.ActorID(lid) != m.miner {
+		maddr, _ := address.NewIDAddress(uint64(m.miner))
+		return xerrors.Errorf("lotus-miner repo is for miner %s, but the venus-sealer repo is for %s", laddr, maddr)
+	}
+	return nil
+}
+
+func (m *lotusMigration) importCounter(override uint64) error {
+	cur, err := m.meta.GetStorageCounter()
+	if err != nil {
+		return err
+	}
+	m.counterOld = cur
+
+	next := m.maxSector
+	has, err := m.lotusDs.Has(datastore.NewKey(lotusStorageCounterKey))
+	if err != nil {
+		return err
+	}
+	if has {
+		b, err := m.lotusDs.Get(datastore.NewKey(lotusStorageCounterKey))
+		if err != nil {
+			return err
+		}
+		lc, n := binary.Uvarint(b)
+		if n <= 0 {
+			return xerrors.Errorf("decoding lotus-miner sector counter")
+		}
+		if abi.SectorNumber(lc) > next {
+			next = abi.SectorNumber(lc)
+		}
+	}
+
+	if override > 0 {
+		if abi.SectorNumber(override) < m.maxSector {
+			return xerrors.Errorf("sector counter %d is lower than the largest sector number %d", override, m.maxSector)
+		}
+		next = abi.SectorNumber(override)
+	}
+
+	// never hand out a sector number twice
+	if next < cur {
+		next = cur
+	}
+	m.counterNew = next
+
+	return m.meta.SetStorageCounter(uint64(next))
+}
+
+func (m *lotusMigration) verifySectorFiles() error {
+	if len(m.storePaths) == 0 {
+		sc, err := m.ls.GetStorage()
+		if err != nil {
+			return err
+		}
+		for _, p := range sc.StoragePaths {
+			m.storePaths = append(m.storePaths, p.Path)
+		}
+	}
+
+	m.verifyBad = map[abi.SectorNumber]string{}
+	for _, sector := range m.proving {
+		ssize, err := sector.SectorType.SectorSize()
+		if err != nil {
+			return err
+		}
+
+		sid := abi.SectorID{Miner: m.miner, Number: sector.SectorNumber}
+		sealed, cache := findSectorFiles(m.storePaths, sid)
+		if sealed == "" || cache == "" {
+			m.verifyBad[sector.SectorNumber] = fmt.Sprintf("cache and/or sealed paths not found, cache %q, sealed %q", cache, sealed)
+			continue
+		}
+
+		if err := sectorstorage.CheckSectorFiles(sealed, cache, ssize); err != nil {
+			m.verifyBad[sector.SectorNumber] = err.Error()
+			continue
+		}
+		m.verifiedOk++
+	}
+
+	return nil
+}
+
+func findSectorFiles(paths []string, sid abi.SectorID) (sealed string, cache string) {
+	for _, p := range paths {
+		if sealed == "" {
+			sp := filepath.Join(p, storiface.FTSealed.String(), storiface.SectorName(sid))
+			if _, err := os.Stat(sp); err == nil {
+				sealed = sp
+			}
+		}
+		if cache == "" {
+			cp := filepath.Join(p, storiface.FTCache.String(), storiface.SectorName(sid))
+			if _, err := os.Stat(cp); err == nil {
+				cache = cp
+			}
+		}
+	}
+	return sealed, cache
+}
+
+func (m *lotusMigration) printReport(skipVerify bool) {
+	fmt.Println("Sectors:")
+	fmt.Printf("\tImported: %d\n", len(m.imported))
+	fmt.Printf("\tAlready present (skipped): %d\n", len(m.skipped))
+	if len(m.decodeErrs) > 0 {
+		fmt.Printf("\t%s: %d\n", color.RedString("Failed to decode"), len(m.decodeErrs))
+		for k, e := range m.decodeErrs {
+			fmt.Printf("\t\t%s: %s\n", k, e)
+		}
+	}
+	if len(m.inflight) > 0 {
+		sortSectorNumbers(m.inflight)
+		fmt.Printf("\t%s: %v\n", color.YellowString("Waiting for lotus-miner messages (check on chain)"), m.inflight)
+	}
+
+	fmt.Println("Sector counter:")
+	fmt.Printf("\tLargest sector number: %d\n", m.maxSector)
+	fmt.Printf("\tCounter: %d -> %d\n", m.counterOld, m.counterNew)
+
+	if m.pathsBad != nil {
+		fmt.Println("Storage paths:")
+		for _, p := range m.pathsAdded {
+			fmt.Printf("\t%s: %s\n", color.GreenString("Added"), p)
+		}
+		for _, p := range m.pathsExisted {
+			fmt.Printf("\tAlready present: %s\n", p)
+		}
+		for p, e := range m.pathsBad {
+			fmt.Printf("\t%s: %s: %s\n", color.RedString("Skipped"), p, e)
+		}
+	}
+
+	if skipVerify {
+		return
+	}
+
+	fmt.Println("Proving sector files:")
+	fmt.Printf("\tOK: %d/%d\n", m.verifiedOk, len(m.proving))
+	if len(m.verifyBad) > 0 {
+		var bad []abi.SectorNumber
+		for sn := range m.verifyBad {
+			bad = append(bad, sn)
+		}
+		sortSectorNumbers(bad)
+		fmt.Printf("\t%s: %d\n", color.RedString("Bad"), len(bad))
+		for _, sn := range bad {
+			fmt.Printf("\t\t%d: %s\n", sn, m.verifyBad[sn])
+		}
+	}
+}
+
+func sortSectorNumbers(s []abi.SectorNumber) {
+	sort.Slice(s, func(i, j int) bool {
+		return s[i] < s[j]
+	})
+}
+
+func fromLotusSectorInfo(ls *lotustypes.SectorInfo) (*types2.SectorInfo, error) {
+	sector := &types2.SectorInfo{
+		State:            types2.SectorState(ls.State),
+		SectorNumber:     ls.SectorNumber,
+		SectorType:       ls.SectorType,
+		CreationTime:     ls.CreationTime,
+		TicketValue:      ls.TicketValue,
+		TicketEpoch:      ls.TicketEpoch,
+		PreCommit1Out:    ls.PreCommit1Out,
+		CommD:            ls.CommD,
+		CommR:            ls.CommR,
+		Proof:            ls.Proof,
+		PreCommitInfo:    ls.PreCommitInfo,
+		PreCommitDeposit: ls.PreCommitDeposit,
+		PreCommitTipSet:  types2.TipSetToken(ls.PreCommitTipSet),
+		PreCommit2Fails:  ls.PreCommit2Fails,
+		SeedValue:        ls.SeedValue,
+		SeedEpoch:        ls.SeedEpoch,
+		InvalidProofs:    ls.InvalidProofs,
+		Return:           types2.ReturnState(ls.Return),
+		TerminatedAt:     ls.TerminatedAt,
+		LastErr:          ls.LastErr,
+	}
+
+	if _, ok := types2.ExistSectorStateList[sector.State]; !ok {
+		return nil, xerrors.Errorf("sector %d has unknown state %s", ls.SectorNumber, ls.State)
+	}
+
+	for _, p := range ls.Pieces {
+		piece := types2.Piece{Piece: p.Piece}
+		if p.DealInfo != nil {
+			piece.DealInfo = &types2.PieceDealInfo{
+				PublishCid:   p.DealInfo.PublishCid,
+				DealID:       p.DealInfo.DealID,
+				DealProposal: p.DealInfo.DealProposal,
+				DealSchedule: types2.DealSchedule{
+					StartEpoch: p.DealInfo.DealSchedule.StartEpoch,
+					EndEpoch:   p.DealInfo.DealSchedule.EndEpoch,
+				},
+				KeepUnsealed: p.DealInfo.KeepUnsealed,
+			}
+		}
+		sector.Pieces = append(sector.Pieces, piece)
+	}
+
+	return sector, nil
+}
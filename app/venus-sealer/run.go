@@ -61,13 +61,23 @@ var runCmd = &cli.Command{
 		}
 
 		//read config
-		repoPath := cctx.String("repo")
-		cfgPath := config.FsConfig(repoPath)
-		cfg, err := config.MinerFromFile(cfgPath)
+		cfg, err := loadSealerConfig(cctx)
 		if err != nil {
 			return err
 		}
-		cfg.DataDir = repoPath
+
+		// services may be restarting, only `config check` fails on unreachable ones
+		problems := config.Check(cfg)
+		problems = append(problems, checkConfigServices(cctx.Context, cfg, false)...)
+		for _, p := range problems {
+			if !p.Fatal {
+				log.Warnf("config %s", p)
+			}
+		}
+		if err := config.FatalProblems(problems); err != nil {
+			return xerrors.Errorf("%w\nrun `venus-sealer config check` after fixing the config", err)
+		}
+		logConfigDiff(cfg)
 
 		//lock repo
 		dataDir, err := homedir.Expand(cfg.DataDir)
@@ -100,6 +110,10 @@ var runCmd = &cli.Command{
 			return err
 		}
 
+		for _, p := range checkConfigNetwork(ctx, cfg, nodeApi) {
+			log.Warnf("config %s", p)
+		}
+
 		log.Info("Checking full node sync status")
 		if !cctx.Bool("nosync") {
 			if err := api.SyncWait(ctx, nodeApi, cfg.NetParams.BlockDelaySecs, false); err != nil {
@@ -156,6 +170,23 @@ var runCmd = &cli.Command{
 	},
 }
 
+func logConfigDiff(cfg *config.StorageMiner) {
+	network := config.GuessNetwork(cfg)
+	def, err := config.GetDefaultStorageConfig(network)
+	if err != nil {
+		return
+	}
+
+	diff, err := config.Diff(cfg, def)
+	if err != nil {
+		log.Warnf("comparing config with the defaults: %s", err)
+		return
+	}
+	for _, e := range diff {
+		log.Infow("non-default config", "network", network, "key", e.Key, "value", e.Value, "default", e.Default)
+	}
+}
+
 func checkV1ApiSupport(nodeApi api.FullNode) error {
 	v, err := nodeApi.Version(context.Background())
 
@@ -7,6 +7,7 @@ import (
 	"github.com/filecoin-project/venus-sealer/api"
 	"os"
 	"sort"
+	"strconv"
 	"strings"
 	"text/tabwriter"
 	"time"
@@ -16,7 +17,11 @@ import (
 	"github.com/urfave/cli/v2"
 	"golang.org/x/xerrors"
 
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+
 	"github.com/filecoin-project/venus-sealer/sector-storage/storiface"
+	types2 "github.com/filecoin-project/venus-sealer/types"
 
 	"github.com/filecoin-project/venus/pkg/types"
 )
@@ -29,6 +34,7 @@ var sealingCmd = &cli.Command{
 		sealingWorkersCmd,
 		sealingSchedDiagCmd,
 		sealingAbortCmd,
+		sealingUnsealsCmd,
 	},
 }
 
@@ -38,6 +44,9 @@ var sealingWorkersCmd = &cli.Command{
 	Flags: []cli.Flag{
 		&cli.BoolFlag{Name: "color"},
 	},
+	Subcommands: []*cli.Command{
+		sealingWorkersDrainCmd,
+	},
 	Action: func(cctx *cli.Context) error {
 		color.NoColor = !cctx.Bool("color")
 
@@ -83,6 +92,10 @@ var sealingWorkersCmd = &cli.Command{
 
 			fmt.Printf("Worker %s, host %s%s\n", stat.id, color.MagentaString(stat.Info.Hostname), disabled)
 
+			if len(stat.Info.Labels) > 0 {
+				fmt.Printf("\tLABELS: %s\n", workerLabels(stat.Info.Labels))
+			}
+
 			var barCols = uint64(64)
 			cpuBars := int(stat.CpuUse * barCols / stat.Info.Resources.CPUs)
 			cpuBar := strings.Repeat("|", cpuBars) + strings.Repeat(" ", int(barCols)-cpuBars)
@@ -117,12 +130,178 @@ var sealingWorkersCmd = &cli.Command{
 			for _, gpu := range stat.Info.Resources.GPUs {
 				fmt.Printf("\tGPU: %s\n", color.New(gpuCol).Sprintf("%s, %sused", gpu, gpuUse))
 			}
+
+			if tasks := workerTaskUsage(stat.WorkerStats); tasks != "" {
+				fmt.Printf("\tTASK: %s\n", tasks)
+			}
 		}
 
 		return nil
 	},
 }
 
+var sealingWorkersDrainCmd = &cli.Command{
+	Name:      "drain",
+	Usage:     "stop scheduling tasks on a worker and move sector files only stored on it to other workers",
+	ArgsUsage: "[worker id]",
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "cancel",
+			Usage: "stop draining the worker and enable it again",
+		},
+		&cli.BoolFlag{
+			Name:  "no-wait",
+			Usage: "don't wait for the drain to finish",
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		nodeApi, closer, err := api.GetStorageMinerAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		ctx := api.ReqContext(cctx)
+
+		if !cctx.Args().Present() {
+			drains, err := nodeApi.WorkerDrainStatus(ctx)
+			if err != nil {
+				return err
+			}
+			for wid, st := range drains {
+				printWorkerDrain(wid, st)
+			}
+			return nil
+		}
+
+		wid, err := uuid.Parse(cctx.Args().First())
+		if err != nil {
+			return xerrors.Errorf("parsing worker id: %w", err)
+		}
+
+		if cctx.Bool("cancel") {
+			if err := nodeApi.WorkerDrainCancel(ctx, wid); err != nil {
+				return err
+			}
+			fmt.Printf("Worker %s enabled\n", wid)
+			return nil
+		}
+
+		if err := nodeApi.WorkerDrain(ctx, wid); err != nil {
+			return err
+		}
+
+		for {
+			drains, err := nodeApi.WorkerDrainStatus(ctx)
+			if err != nil {
+				return err
+			}
+
+			st, ok := drains[wid]
+			if !ok {
+				return xerrors.Errorf("worker %s is not draining", wid)
+			}
+			printWorkerDrain(wid, st)
+
+			switch st.State {
+			case storiface.DrainDone:
+				if !st.SafeToPowerOff() {
+					return xerrors.Errorf("%d sector(s) couldn't be moved off the worker", len(st.Failed))
+				}
+				return nil
+			case storiface.DrainFailed, storiface.DrainCancelled:
+				return xerrors.Errorf("drain %s", st.State)
+			}
+
+			if cctx.Bool("no-wait") {
+				return nil
+			}
+
+			select {
+			case <-time.After(5 * time.Second):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	},
+}
+
+func printWorkerDrain(wid uuid.UUID, st storiface.WorkerDrain) {
+	fmt.Printf("Worker %s, host %s: %s (%s)\n", wid, st.Hostname, st.State, time.Since(st.Started).Truncate(time.Second))
+
+	switch st.State {
+	case storiface.DrainWaiting:
+		fmt.Printf("\twaiting for %d task(s) to finish\n", st.RunningTasks)
+	case storiface.DrainEvacuating, storiface.DrainDone:
+		fmt.Printf("\tsectors moved: %d/%d, failed: %d\n", st.Evacuated, st.Sectors, len(st.Failed))
+	}
+
+	var failed []abi.SectorNumber
+	for n := range st.Failed {
+		failed = append(failed, n)
+	}
+	sort.Slice(failed, func(i, j int) bool {
+		return failed[i] < failed[j]
+	})
+	for _, n := range failed {
+		fmt.Printf("\tsector %d: %s\n", n, st.Failed[n])
+	}
+
+	if st.Error != "" {
+		fmt.Printf("\terror: %s\n", st.Error)
+	}
+
+	if st.SafeToPowerOff() {
+		fmt.Printf("\tsafe to power off\n")
+	}
+}
+
+func workerLabels(labels map[string]string) string {
+	out := make([]string, 0, len(labels))
+	for k, v := range labels {
+		out = append(out, k+"="+v)
+	}
+	sort.Strings(out)
+	return strings.Join(out, ", ")
+}
+
+// workerTaskUsage formats running task counts, with the limit of task types which have one
+func workerTaskUsage(stat storiface.WorkerStats) string {
+	var tts []types2.TaskType
+	seen := map[types2.TaskType]struct{}{}
+	for _, m := range []map[types2.TaskType]int64{stat.TaskCounts, stat.Info.TaskLimits} {
+		for tt := range m {
+			if _, ok := seen[tt]; !ok {
+				seen[tt] = struct{}{}
+				tts = append(tts, tt)
+			}
+		}
+	}
+	sort.Slice(tts, func(i, j int) bool {
+		return tts[i].Less(tts[j])
+	})
+
+	var out []string
+	for _, tt := range tts {
+		running := stat.TaskCounts[tt]
+		limit, ok := stat.Info.TaskLimits[tt]
+		if !ok {
+			if running > 0 {
+				out = append(out, fmt.Sprintf("%s %d", tt.Short(), running))
+			}
+			continue
+		}
+
+		s := fmt.Sprintf("%s %d/%d", tt.Short(), running, limit)
+		if running >= limit {
+			s = color.YellowString(s)
+		}
+		out = append(out, s)
+	}
+
+	return strings.Join(out, "; ")
+}
+
 var sealingJobsCmd = &cli.Command{
 	Name:  "jobs",
 	Usage: "list running jobs",
@@ -305,3 +484,87 @@ var sealingAbortCmd = &cli.Command{
 		return nodeApi.SealingAbort(ctx, job.ID)
 	},
 }
+
+var sealingUnsealsCmd = &cli.Command{
+	Name:  "unseals",
+	Usage: "list unseals for retrievals",
+	Subcommands: []*cli.Command{
+		sealingUnsealsBoostCmd,
+	},
+	Action: func(cctx *cli.Context) error {
+		nodeApi, closer, err := api.GetStorageMinerAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		ctx := api.ReqContext(cctx)
+
+		jobs, err := nodeApi.SealingUnsealQueue(ctx)
+		if err != nil {
+			return xerrors.Errorf("getting unseal queue: %w", err)
+		}
+
+		tw := tabwriter.NewWriter(os.Stdout, 2, 4, 2, ' ', 0)
+		_, _ = fmt.Fprintf(tw, "Sector\tPriority\tWaiters\tState\tTime\n")
+
+		for _, job := range jobs {
+			state := "queued"
+			since := job.Queued
+			if !job.Started.IsZero() {
+				state = "running"
+				since = job.Started
+			}
+
+			_, _ = fmt.Fprintf(tw, "%d\t%d\t%d\t%s\t%s\n",
+				job.Sector.Number,
+				job.Priority,
+				job.Waiters,
+				state,
+				time.Since(since).Truncate(time.Millisecond*100))
+		}
+
+		return tw.Flush()
+	},
+}
+
+var sealingUnsealsBoostCmd = &cli.Command{
+	Name:      "boost",
+	Usage:     "Raise the priority of a queued unseal",
+	ArgsUsage: "[sector number] [priority]",
+	Action: func(cctx *cli.Context) error {
+		if cctx.Args().Len() != 2 {
+			return xerrors.Errorf("expected 2 arguments")
+		}
+
+		snum, err := strconv.ParseUint(cctx.Args().Get(0), 10, 64)
+		if err != nil {
+			return xerrors.Errorf("parsing sector number: %w", err)
+		}
+
+		prio, err := strconv.Atoi(cctx.Args().Get(1))
+		if err != nil {
+			return xerrors.Errorf("parsing priority: %w", err)
+		}
+
+		nodeApi, closer, err := api.GetStorageMinerAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		ctx := api.ReqContext(cctx)
+
+		ma, err := nodeApi.ActorAddress(ctx)
+		if err != nil {
+			return err
+		}
+
+		mid, err := address.IDFromAddress(ma)
+		if err != nil {
+			return err
+		}
+
+		return nodeApi.SealingUnsealBoost(ctx, abi.SectorID{Miner: abi.ActorID(mid), Number: abi.SectorNumber(snum)}, prio)
+	},
+}
@@ -60,6 +60,10 @@ var sectorsCmd = &cli.Command{
 		sectorsCapacityCollateralCmd,
 		sectorsBatching,
 		sectorsRedoCmd,
+		sectorsFSMCmd,
+		sectorsVerifyCmd,
+		sectorsExportCmd,
+		sectorsImportCmd,
 	},
 }
 
@@ -156,6 +160,10 @@ var sectorsCurrentIDCmd = &cli.Command{
 var sectorsDealCmd = &cli.Command{
 	Name:  "deal",
 	Usage: "store deal data in a sector",
+	Subcommands: []*cli.Command{
+		sectorsDealStatusCmd,
+		sectorsDealPlanCmd,
+	},
 	Action: func(cctx *cli.Context) error {
 		nodeApi, closer, err := api.GetStorageMinerAPI(cctx)
 		if err != nil {
@@ -177,6 +185,106 @@ var sectorsDealCmd = &cli.Command{
 	},
 }
 
+var sectorsDealStatusCmd = &cli.Command{
+	Name:  "status",
+	Usage: "show automatic deal ingestion status",
+	Action: func(cctx *cli.Context) error {
+		nodeApi, closer, err := api.GetStorageMinerAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+		ctx := api.ReqContext(cctx)
+
+		st, err := nodeApi.DealIngestStatus(ctx)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Auto ingest: %t\n", st.Enabled)
+		if st.Paused != "" {
+			fmt.Printf("Paused: %s\n", st.Paused)
+		}
+		if !st.LastPoll.IsZero() {
+			fmt.Printf("Last poll: %s (%s ago)\n", st.LastPoll.Format(time.RFC3339), time.Since(st.LastPoll).Truncate(time.Second))
+		}
+		if st.LastError != "" {
+			fmt.Printf("Last error: %s\n", st.LastError)
+		}
+		fmt.Printf("Deals added: %d, failed: %d\n", st.Ingested, st.Failed)
+
+		if len(st.Unreported) > 0 {
+			fmt.Printf("Deals not reported to venus-market yet:\n")
+			for _, ud := range st.Unreported {
+				fmt.Printf("\tdeal %d sector %d offset %d, %d attempt(s): %s\n", ud.DealId, ud.SectorId, ud.Offset, ud.Attempts, ud.LastError)
+			}
+		}
+
+		return nil
+	},
+}
+
+var sectorsDealPlanCmd = &cli.Command{
+	Name:  "plan",
+	Usage: "show how deals waiting for sectors would be packed, without assigning them",
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "json",
+			Usage: "print the plan as json",
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		nodeApi, closer, err := api.GetStorageMinerAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+		ctx := api.ReqContext(cctx)
+
+		plan, err := nodeApi.DealPackingPlan(ctx)
+		if err != nil {
+			return err
+		}
+
+		if cctx.Bool("json") {
+			out, err := json.MarshalIndent(plan, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(out))
+			return nil
+		}
+
+		fmt.Printf("Sectors started now are sealed by epoch %d\n", plan.SealedBy)
+
+		for _, s := range plan.Sectors {
+			name := fmt.Sprintf("sector %d", s.Sector)
+			if s.New {
+				name = "new sector"
+			}
+			fmt.Printf("%s: used %s, free %s, padding %s\n", name,
+				types.SizeStr(types.NewInt(uint64(s.Used))), types.SizeStr(types.NewInt(uint64(s.Free))), types.SizeStr(types.NewInt(uint64(s.Padding))))
+
+			for _, p := range s.Pieces {
+				fmt.Printf("\tdeal %d piece %s size %s epochs %d-%d\n", p.DealID, p.PieceCID, types.SizeStr(types.NewInt(uint64(p.Size.Padded()))), p.StartEpoch, p.EndEpoch)
+			}
+		}
+
+		if len(plan.Rejected) > 0 {
+			fmt.Println("Rejected deals:")
+			for _, p := range plan.Rejected {
+				fmt.Printf("\tdeal %d piece %s: %s\n", p.DealID, p.PieceCID, p.Reason)
+			}
+		}
+
+		if len(plan.Sectors) == 0 && len(plan.Rejected) == 0 {
+			fmt.Println("No deals waiting for sectors")
+		}
+
+		return nil
+	},
+}
+
 var sectorsStatusCmd = &cli.Command{
 	Name:      "status",
 	Usage:     "Get the seal status of a sector by its number",
@@ -240,6 +348,17 @@ var sectorsStatusCmd = &cli.Command{
 		}
 		fmt.Printf("Deals:\t\t%v\n", status.Deals)
 		fmt.Printf("Retries:\t%d\n", status.Retries)
+		if len(status.RecoveryAttempts) > 0 {
+			var attempts []string
+			for state, n := range status.RecoveryAttempts {
+				attempts = append(attempts, fmt.Sprintf("%s: %d", state, n))
+			}
+			sort.Strings(attempts)
+			fmt.Printf("Recovery:\t%s\n", strings.Join(attempts, ", "))
+		}
+		if status.RecoveryEscalated {
+			fmt.Printf("\t\trecovery playbook used up, needs manual recovery (sectors update-state)\n")
+		}
 		if status.LastErr != "" {
 			fmt.Printf("Last Error:\t\t%s\n", status.LastErr)
 		}
@@ -2006,6 +2125,10 @@ var sectorsBatchingPendingCommit = &cli.Command{
 			Name:  "publish-now",
 			Usage: "send a batch now",
 		},
+		&cli.BoolFlag{
+			Name:  "explain",
+			Usage: "show how the batch cost model splits the batch",
+		},
 	},
 	Action: func(cctx *cli.Context) error {
 		storageAPI, closer, err := api.GetStorageMinerAPI(cctx)
@@ -2044,15 +2167,23 @@ var sectorsBatchingPendingCommit = &cli.Command{
 			return nil
 		}
 
-		pending, err := storageAPI.SectorCommitPending(ctx)
+		if cctx.Bool("explain") {
+			explain, err := storageAPI.SectorBatchExplain(ctx)
+			if err != nil {
+				return xerrors.Errorf("getting batch decisions: %w", err)
+			}
+
+			printBatchExplain(explain.Commit)
+			return nil
+		}
+
+		queues, err := storageAPI.SectorBatchQueues(ctx)
 		if err != nil {
-			return xerrors.Errorf("getting pending deals: %w", err)
+			return xerrors.Errorf("getting batch queues: %w", err)
 		}
 
-		if len(pending) > 0 {
-			for _, sector := range pending {
-				fmt.Println(sector.Number)
-			}
+		if len(queues.Commit.Sectors) > 0 {
+			printBatchQueue(queues.Commit)
 			return nil
 		}
 
@@ -2069,6 +2200,10 @@ var sectorsBatchingPendingPreCommit = &cli.Command{
 			Name:  "publish-now",
 			Usage: "send a batch now",
 		},
+		&cli.BoolFlag{
+			Name:  "explain",
+			Usage: "show how the batch cost model splits the batch",
+		},
 	},
 	Action: func(cctx *cli.Context) error {
 		storageAPI, closer, err := api.GetStorageMinerAPI(cctx)
@@ -2102,15 +2237,23 @@ var sectorsBatchingPendingPreCommit = &cli.Command{
 			return nil
 		}
 
-		pending, err := storageAPI.SectorPreCommitPending(ctx)
+		if cctx.Bool("explain") {
+			explain, err := storageAPI.SectorBatchExplain(ctx)
+			if err != nil {
+				return xerrors.Errorf("getting batch decisions: %w", err)
+			}
+
+			printBatchExplain(explain.PreCommit)
+			return nil
+		}
+
+		queues, err := storageAPI.SectorBatchQueues(ctx)
 		if err != nil {
-			return xerrors.Errorf("getting pending deals: %w", err)
+			return xerrors.Errorf("getting batch queues: %w", err)
 		}
 
-		if len(pending) > 0 {
-			for _, sector := range pending {
-				fmt.Println(sector.Number)
-			}
+		if len(queues.PreCommit.Sectors) > 0 {
+			printBatchQueue(queues.PreCommit)
 			return nil
 		}
 
@@ -2119,6 +2262,68 @@ var sectorsBatchingPendingPreCommit = &cli.Command{
 	},
 }
 
+func printBatchQueue(q types2.BatchQueue) {
+	if !q.Cutoff.IsZero() {
+		fmt.Printf("Batch cutoff in %s (%s), sent %s before it at the latest\n",
+			batchTimeLeft(q.Cutoff), q.Cutoff.Format(time.RFC3339), q.Slack)
+	}
+
+	tw := tablewriter.New(
+		tablewriter.Col("Sector"),
+		tablewriter.Col("Cutoff"),
+		tablewriter.Col("Note"),
+	)
+	for _, s := range q.Sectors {
+		row := map[string]interface{}{
+			"Sector": s.Sector,
+			"Cutoff": "-",
+		}
+		if !s.Cutoff.IsZero() {
+			row["Cutoff"] = batchTimeLeft(s.Cutoff)
+		}
+		if s.Restored {
+			row["Note"] = "restored, waiting for the sealing state machine"
+		}
+		tw.Write(row)
+	}
+	_ = tw.Flush(os.Stdout)
+}
+
+func printBatchExplain(e types2.BatchExplain) {
+	printDecision := func(name string, d *types2.BatchDecision) {
+		if d == nil {
+			fmt.Printf("%s: none\n", name)
+			return
+		}
+
+		fmt.Printf("%s (%s):\n", name, d.Time.Format(time.RFC3339))
+		fmt.Printf("\tBase fee: %s, sectors: %d\n", types.FIL(d.BaseFee).Short(), d.Sectors)
+		if !d.EarliestCutoff.IsZero() {
+			fmt.Printf("\tEarliest cutoff: %s\n", batchTimeLeft(d.EarliestCutoff))
+		}
+		for _, o := range d.Options {
+			chosen := ""
+			if o.Aggregated == d.Chosen.Aggregated && o.Individual == d.Chosen.Individual {
+				chosen = color.GreenString(" <- chosen")
+			}
+			fmt.Printf("\tbatch %d + individual %d: gas %s + network fee %s = %s%s\n", o.Aggregated, o.Individual,
+				types.FIL(o.GasCost).Short(), types.FIL(o.NetworkFee).Short(), types.FIL(o.Total).Short(), chosen)
+		}
+		fmt.Printf("\t%s\n", d.Reason)
+	}
+
+	printDecision("Last batch", e.Last)
+	printDecision("Next batch", e.Next)
+}
+
+func batchTimeLeft(cutoff time.Time) string {
+	left := time.Until(cutoff).Truncate(time.Second)
+	if left <= 0 {
+		return color.RedString("passed %s ago", -left)
+	}
+	return left.String()
+}
+
 func yesno(b bool) string {
 	if b {
 		return color.GreenString("YES")
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/docker/go-units"
+	"github.com/urfave/cli/v2"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/venus-sealer/api"
+	"github.com/filecoin-project/venus-sealer/sector-storage/stores"
+	"github.com/filecoin-project/venus-sealer/sector-storage/storiface"
+)
+
+var sectorsExportCmd = &cli.Command{
+	Name:      "export",
+	Usage:     "Copy a sealed sector out for backups",
+	ArgsUsage: "<sectorNum>",
+	Description: `Copies the sealed file and the cache files of the sector, with the sector
+metadata and the checksums of the files, to a new directory named after the
+sector in the --to directory. The directory is on the sealer host, and the
+sector files have to be in one of its local storage paths, eg.
+   venus-sealer sectors export 12 --to /mnt/backup
+   venus-sealer sectors import /mnt/backup/s-t01000-12
+
+The --to target can also be a bucket of an S3-compatible object store, the
+files are uploaded under a prefix named after the sector. The sealer reads the
+credentials from its AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY environment
+variables, or from --s3-credentials-file, eg.
+   venus-sealer sectors export 12 --to s3://backup/miner --s3-endpoint=http://127.0.0.1:9000
+   venus-sealer sectors import s3://backup/miner/s-t01000-12 --s3-endpoint=http://127.0.0.1:9000
+
+A failed export removes the files it copied.`,
+	Flags: append([]cli.Flag{
+		&cli.StringFlag{
+			Name:     "to",
+			Usage:    "directory, or s3://bucket/prefix url, to export the sector to",
+			Required: true,
+		},
+	}, objectStoreFlags...),
+	Action: func(cctx *cli.Context) error {
+		if cctx.Args().Len() != 1 {
+			return xerrors.Errorf("must pass sector number")
+		}
+
+		id, err := strconv.ParseUint(cctx.Args().Get(0), 10, 64)
+		if err != nil {
+			return xerrors.Errorf("could not parse sector number: %w", err)
+		}
+
+		to := cctx.String("to")
+		if strings.Contains(to, "://") {
+			to, err = objectStoreURL(cctx, to)
+		} else {
+			to, err = filepath.Abs(to)
+		}
+		if err != nil {
+			return err
+		}
+
+		nodeApi, closer, err := api.GetStorageMinerAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+		ctx := api.ReqContext(cctx)
+
+		exp, err := nodeApi.SectorsExport(ctx, abi.SectorNumber(id), to)
+		if err != nil {
+			return err
+		}
+
+		var size int64
+		for _, f := range exp.Files {
+			size += f.Size
+		}
+		fmt.Printf("exported sector %d (%d files, %s) to %s in %s\n", id, len(exp.Files), units.BytesSize(float64(size)), storiface.SectorName(exp.Sector), cctx.String("to"))
+		return nil
+	},
+}
+
+// objectStoreFlags configure the object store of s3:// export urls
+var objectStoreFlags = []cli.Flag{
+	&cli.StringFlag{
+		Name:  "s3-endpoint",
+		Usage: "url of the S3-compatible API, defaults to the AWS endpoint of the region",
+	},
+	&cli.StringFlag{
+		Name:  "s3-region",
+		Usage: "region of the object store",
+	},
+	&cli.StringFlag{
+		Name:  "s3-credentials-file",
+		Usage: "AWS shared credentials file on the sealer host to read the keys from",
+	},
+	&cli.StringFlag{
+		Name:  "s3-profile",
+		Usage: "profile in the credentials file",
+	},
+}
+
+// objectStoreURL adds the s3 flags to the parameters of the object store url
+func objectStoreURL(cctx *cli.Context, to string) (string, error) {
+	u, err := url.Parse(to)
+	if err != nil {
+		return "", xerrors.Errorf("parsing %s: %w", to, err)
+	}
+
+	q := u.Query()
+	for flag, param := range map[string]string{
+		"s3-endpoint":         "endpoint",
+		"s3-region":           "region",
+		"s3-credentials-file": "credentials",
+		"s3-profile":          "profile",
+	} {
+		if cctx.IsSet(flag) {
+			q.Set(param, cctx.String(flag))
+		}
+	}
+	u.RawQuery = q.Encode()
+
+	if _, err := stores.ParseObjectStoreURL(u.String()); err != nil {
+		return "", err
+	}
+	return u.String(), nil
+}
+
+var sectorsImportCmd = &cli.Command{
+	Name:      "import",
+	Usage:     "Restore a sector exported with 'sectors export'",
+	ArgsUsage: "<exportDir | s3://bucket/prefix/sector>",
+	Description: `Checks the exported files against their checksums while copying them to the
+best local storage path, declares the sector, and removes it again unless
+it is provable against its CommR. The sector metadata is restored if the
+sealer doesn't know the sector, and the sector continues in its saved state.
+
+Sectors exported to an object store are downloaded from the prefix named
+after the sector, eg.
+   venus-sealer sectors import s3://backup/miner/s-t01000-12 --s3-endpoint=http://127.0.0.1:9000`,
+	Flags: objectStoreFlags,
+	Action: func(cctx *cli.Context) error {
+		if cctx.Args().Len() != 1 {
+			return xerrors.Errorf("must pass the export directory")
+		}
+
+		from := cctx.Args().Get(0)
+		var err error
+		if strings.Contains(from, "://") {
+			from, err = objectStoreURL(cctx, from)
+		} else {
+			from, err = filepath.Abs(from)
+		}
+		if err != nil {
+			return err
+		}
+
+		nodeApi, closer, err := api.GetStorageMinerAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+		ctx := api.ReqContext(cctx)
+
+		exp, err := nodeApi.SectorsImport(ctx, from)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("imported sector %d, exported at %s\n", exp.Sector.Number, exp.Exported.Format("2006-01-02 15:04:05"))
+		return nil
+	},
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/urfave/cli/v2"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/venus-sealer/api"
+	sealing "github.com/filecoin-project/venus-sealer/storage-sealing"
+	types2 "github.com/filecoin-project/venus-sealer/types"
+)
+
+var sectorsFSMCmd = &cli.Command{
+	Name:  "fsm",
+	Usage: "Inspect the sector state machine",
+	Subcommands: []*cli.Command{
+		sectorsFSMGraphCmd,
+		sectorsFSMHistoryCmd,
+	},
+}
+
+var sectorsFSMGraphCmd = &cli.Command{
+	Name:  "graph",
+	Usage: "Render the sector state machine",
+	Description: `Prints the transitions of the sector state machine of this build, eg.
+   venus-sealer sectors fsm graph | dot -Tsvg > fsm.svg`,
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "format",
+			Usage: "dot or mermaid",
+			Value: "dot",
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		return sealing.WriteFSMGraph(os.Stdout, sealing.FSMGraph(), cctx.String("format"))
+	},
+}
+
+var sectorsFSMHistoryCmd = &cli.Command{
+	Name:      "history",
+	Usage:     "Export the states sectors went through and the time spent in each",
+	ArgsUsage: "<sectorNum> ...",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "format",
+			Usage: "json or csv",
+			Value: "json",
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		if !cctx.Args().Present() {
+			return xerrors.Errorf("expected at least one sector number")
+		}
+
+		format := cctx.String("format")
+		if format != "json" && format != "csv" {
+			return xerrors.Errorf("unknown format %s, expected json or csv", format)
+		}
+
+		nodeApi, closer, err := api.GetStorageMinerAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+		ctx := api.ReqContext(cctx)
+
+		var histories []types2.SectorHistory
+		for _, arg := range cctx.Args().Slice() {
+			id, err := strconv.ParseUint(arg, 10, 64)
+			if err != nil {
+				return xerrors.Errorf("could not parse sector number: %w", err)
+			}
+
+			h, err := nodeApi.SectorsHistory(ctx, abi.SectorNumber(id))
+			if err != nil {
+				return xerrors.Errorf("getting history of sector %d: %w", id, err)
+			}
+			histories = append(histories, h)
+		}
+
+		if format == "json" {
+			out, err := json.MarshalIndent(histories, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(out))
+			return nil
+		}
+
+		w := csv.NewWriter(os.Stdout)
+		if err := w.Write([]string{"sector", "state", "entered", "left", "seconds", "event", "incomplete"}); err != nil {
+			return err
+		}
+		for _, h := range histories {
+			for _, s := range h.Spans {
+				left := ""
+				if !s.Left.IsZero() {
+					left = s.Left.Format(time.RFC3339)
+				}
+				if err := w.Write([]string{
+					strconv.FormatUint(uint64(h.Sector), 10),
+					string(s.State),
+					s.Entered.Format(time.RFC3339),
+					left,
+					strconv.FormatInt(int64(s.Duration/time.Second), 10),
+					s.Event,
+					strconv.FormatBool(h.Incomplete),
+				}); err != nil {
+					return err
+				}
+			}
+		}
+		w.Flush()
+		return w.Error()
+	},
+}
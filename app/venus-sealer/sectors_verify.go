@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/urfave/cli/v2"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/venus-sealer/api"
+	types2 "github.com/filecoin-project/venus-sealer/types"
+)
+
+var sectorsVerifyCmd = &cli.Command{
+	Name:      "verify",
+	Usage:     "Check sealed replicas against the CommR they were precommitted with",
+	ArgsUsage: "<sectorNum> ...",
+	Description: `Proves random window post challenges from the stored sealed replica and
+cache files, which fails if they don't match the on-chain CommR of the sector.
+Failed checks are recorded and reported by 'venus-sealer proving check'.
+
+With --background the sectors are queued and checked one at a time, see the
+IntegrityAudit config section for the pace and the periodic sweep of all
+active sectors, eg.
+   venus-sealer sectors verify --all --background
+   venus-sealer sectors verify --status`,
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "all",
+			Usage: "check all active sectors",
+		},
+		&cli.BoolFlag{
+			Name:  "background",
+			Usage: "queue the sectors for the background checks and return",
+		},
+		&cli.IntFlag{
+			Name:  "rounds",
+			Usage: "rounds of challenges proven per sector, defaults to IntegrityAudit.Rounds",
+		},
+		&cli.BoolFlag{
+			Name:  "status",
+			Usage: "print the last check of every checked sector",
+		},
+		&cli.BoolFlag{
+			Name:  "only-bad",
+			Usage: "print only failed checks",
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		color.NoColor = !cctx.Bool("color")
+
+		nodeApi, closer, err := api.GetStorageMinerAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+		ctx := api.ReqContext(cctx)
+
+		if cctx.Bool("status") {
+			results, err := nodeApi.SectorsIntegrity(ctx)
+			if err != nil {
+				return err
+			}
+			return printIntegrity(results, cctx.Bool("only-bad"))
+		}
+
+		var sectors []abi.SectorNumber
+		for _, arg := range cctx.Args().Slice() {
+			id, err := strconv.ParseUint(arg, 10, 64)
+			if err != nil {
+				return xerrors.Errorf("could not parse sector number: %w", err)
+			}
+			sectors = append(sectors, abi.SectorNumber(id))
+		}
+		if cctx.Bool("all") == (len(sectors) > 0) {
+			return xerrors.Errorf("expected either sector numbers or --all")
+		}
+
+		if cctx.Bool("background") {
+			queued, err := nodeApi.SectorsVerifyBackground(ctx, sectors)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("queued %d sectors\n", queued)
+			return nil
+		}
+
+		results, err := nodeApi.SectorsVerify(ctx, sectors, cctx.Int("rounds"))
+		if err != nil {
+			return err
+		}
+
+		out := make([]*types2.SectorIntegrity, 0, len(results))
+		for i := range results {
+			out = append(out, &results[i])
+		}
+		return printIntegrity(out, cctx.Bool("only-bad"))
+	},
+}
+
+func printIntegrity(results []*types2.SectorIntegrity, onlyBad bool) error {
+	tw := tabwriter.NewWriter(os.Stdout, 2, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "sector\tcommR\tchecked\tstatus")
+
+	for _, res := range results {
+		if res.OK() {
+			if !onlyBad {
+				_, _ = fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", res.SectorNumber, res.CommR, res.Checked.Format(time.RFC3339), color.GreenString("good"))
+			}
+			continue
+		}
+		_, _ = fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", res.SectorNumber, res.CommR, res.Checked.Format(time.RFC3339), color.RedString("bad")+fmt.Sprintf(" (%s)", res.Error))
+	}
+
+	return tw.Flush()
+}
@@ -13,6 +13,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/docker/go-units"
 	"github.com/fatih/color"
 	"github.com/google/uuid"
 	"github.com/mitchellh/go-homedir"
@@ -44,6 +45,9 @@ stored while moving through the sealing pipeline (references as 'seal').`,
 		storageListCmd,
 		storageFindCmd,
 		storageCleanupCmd,
+		storageUnsealedCmd,
+		storageRelayoutCmd,
+		storageVerifyManifestCmd,
 	},
 }
 
@@ -68,6 +72,33 @@ Data for the sealing process will be stored here
 Store
 Finalized sectors that will be moved here for long term storage and be proven
 over time
+
+Health
+Paths are probed every minute, with timed reads of sealed files, timed writes
+on sealing paths and, when a block device is given with '--smart-device', the
+SMART data of the device read with smartctl. Paths are ok, degraded or
+failing, see 'storage list'. New sector files are put in degraded paths only
+when no healthy path has space, and never in failing paths. Sectors only stored
+in failing paths are reported faulty before proving.
+
+Layout
+Sector files are kept flat in one directory per file type by default. The
+sharded layout groups them in subdirectories of 1000 sector numbers, which
+keeps directories small on paths with many sectors. The layout of a path can
+be changed later with 'storage relayout'
+
+Object storage
+Store paths can keep the sealed files, and optionally the cache files, in a
+bucket of an S3-compatible object store, eg. AWS S3 or MinIO. The files are
+uploaded once sectors are moved into the path, and proofs read only the
+challenged parts of the sealed files. The path itself keeps the unsealed files
+and the cache files. Credentials are read from the AWS_ACCESS_KEY_ID and
+AWS_SECRET_ACCESS_KEY environment variables of the sealer, from the variables
+named with --s3-access-key-env and --s3-secret-key-env, or from an AWS
+credentials file with --s3-credentials-file. Only the names are stored in the
+path metadata, never the keys, eg.
+   venus-sealer storage attach --init --store --max-storage=1PiB \
+      --s3-endpoint=http://127.0.0.1:9000 --s3-bucket=sectors /data/cold
    `,
 	Flags: []cli.Flag{
 		&cli.BoolFlag{
@@ -87,6 +118,59 @@ over time
 			Name:  "store",
 			Usage: "(for init) use path for long-term storage",
 		},
+		&cli.StringFlag{
+			Name:  "max-storage",
+			Usage: "(for init) limit storage space for sectors (expensive for very large paths!)",
+		},
+		&cli.StringFlag{
+			Name:  "layout",
+			Usage: "(for init) sector file layout, flat or sharded",
+			Value: "flat",
+		},
+		&cli.StringFlag{
+			Name:  "smart-device",
+			Usage: "(for init) block device of the path to check the SMART data of with smartctl, eg. /dev/sda",
+		},
+		&cli.StringFlag{
+			Name:  "s3-endpoint",
+			Usage: "(for init) keep stored files in the S3-compatible object store at this url",
+		},
+		&cli.StringFlag{
+			Name:  "s3-region",
+			Usage: "(for init) region of the object store",
+			Value: "us-east-1",
+		},
+		&cli.StringFlag{
+			Name:  "s3-bucket",
+			Usage: "(for init) bucket for the stored files",
+		},
+		&cli.StringFlag{
+			Name:  "s3-prefix",
+			Usage: "(for init) prefix of the object keys, to share a bucket between paths",
+		},
+		&cli.BoolFlag{
+			Name:  "s3-upload-cache",
+			Usage: "(for init) upload the cache files too instead of keeping them in the path",
+		},
+		&cli.StringFlag{
+			Name:  "s3-credentials-file",
+			Usage: "(for init) AWS shared credentials file the sealer reads the keys from",
+		},
+		&cli.StringFlag{
+			Name:  "s3-profile",
+			Usage: "(for init) profile in the credentials file",
+			Value: "default",
+		},
+		&cli.StringFlag{
+			Name:  "s3-access-key-env",
+			Usage: "(for init) environment variable of the sealer holding the access key",
+			Value: "AWS_ACCESS_KEY_ID",
+		},
+		&cli.StringFlag{
+			Name:  "s3-secret-key-env",
+			Usage: "(for init) environment variable of the sealer holding the secret key",
+			Value: "AWS_SECRET_ACCESS_KEY",
+		},
 	},
 	Action: func(cctx *cli.Context) error {
 		storageAPI, closer, err := api.GetStorageMinerAPI(cctx)
@@ -121,16 +205,53 @@ over time
 			}
 
 			cfg := &stores.LocalStorageMeta{
-				ID:       stores.ID(uuid.New().String()),
-				Weight:   cctx.Uint64("weight"),
-				CanSeal:  cctx.Bool("seal"),
-				CanStore: cctx.Bool("store"),
+				ID:          stores.ID(uuid.New().String()),
+				Weight:      cctx.Uint64("weight"),
+				CanSeal:     cctx.Bool("seal"),
+				CanStore:    cctx.Bool("store"),
+				SmartDevice: cctx.String("smart-device"),
 			}
 
 			if !(cfg.CanStore || cfg.CanSeal) {
 				return xerrors.Errorf("must specify at least one of --store or --seal")
 			}
 
+			if cctx.IsSet("max-storage") {
+				maxStor, err := units.RAMInBytes(cctx.String("max-storage"))
+				if err != nil {
+					return xerrors.Errorf("parsing max-storage: %w", err)
+				}
+				cfg.MaxStorage = uint64(maxStor)
+			}
+
+			cfg.Layout, err = storiface.ParseSectorLayout(cctx.String("layout"))
+			if err != nil {
+				return err
+			}
+
+			if cctx.IsSet("s3-endpoint") {
+				if cfg.CanSeal || cfg.MaxStorage == 0 {
+					return xerrors.Errorf("object store paths can only be used with --store and need --max-storage")
+				}
+
+				cfg.Backend = &stores.ObjectStoreConfig{
+					Type:        stores.BackendS3,
+					Endpoint:    cctx.String("s3-endpoint"),
+					Region:      cctx.String("s3-region"),
+					Bucket:      cctx.String("s3-bucket"),
+					Prefix:      cctx.String("s3-prefix"),
+					UploadCache: cctx.Bool("s3-upload-cache"),
+
+					CredentialsFile: cctx.String("s3-credentials-file"),
+					Profile:         cctx.String("s3-profile"),
+					AccessKeyEnv:    cctx.String("s3-access-key-env"),
+					SecretKeyEnv:    cctx.String("s3-secret-key-env"),
+				}
+				if _, err := stores.NewObjectStore(*cfg.Backend); err != nil {
+					return err
+				}
+			}
+
 			b, err := json.MarshalIndent(cfg, "", "  ")
 			if err != nil {
 				return xerrors.Errorf("marshaling storage config: %w", err)
@@ -264,9 +385,46 @@ var storageListCmd = &cli.Command{
 				fmt.Print(color.HiYellowString("Use: ReadOnly"))
 			}
 
+			health, err := storageAPI.StorageHealth(ctx, s.ID)
+			if err != nil {
+				return err
+			}
+			healthCol := color.FgGreen
+			switch health.Status {
+			case stores.HealthDegraded:
+				healthCol = color.FgYellow
+			case stores.HealthFailing:
+				healthCol = color.FgRed
+			}
+			fmt.Printf("\tHealth: %s", color.New(healthCol).Sprint(health.Status))
+			if health.FailingProbes > 0 {
+				fmt.Printf(" (%d probes)", health.FailingProbes)
+			}
+			if health.ReadLatency > 0 {
+				fmt.Printf("; Read: %s", health.ReadLatency.Truncate(time.Microsecond*100))
+			}
+			if health.WriteLatency > 0 {
+				fmt.Printf("; Write: %s", health.WriteLatency.Truncate(time.Microsecond*100))
+			}
+			fmt.Printf("; I/O errors: %d\n", health.IOErrors)
+			if sm := health.SMART; sm != nil {
+				res := color.GreenString("passed")
+				if !sm.Passed {
+					res = color.RedString("failed")
+				}
+				fmt.Printf("\tSMART (%s): %s; %dC; Reallocated: %d; Pending: %d; Media errors: %d\n",
+					sm.Device, res, sm.Temperature, sm.ReallocatedSectors, sm.PendingSectors, sm.MediaErrors)
+			}
+			for _, r := range health.Reasons {
+				fmt.Printf("\t\t%s\n", color.New(healthCol).Sprint(r))
+			}
+
 			if localPath, ok := local[s.ID]; ok {
 				fmt.Printf("\tLocal: %s\n", color.GreenString(localPath))
 			}
+			if si.Backend != "" {
+				fmt.Printf("\tBackend: %s\n", color.CyanString(si.Backend))
+			}
 			for i, l := range si.URLs {
 				var rtt string
 				if _, ok := local[s.ID]; !ok && i == 0 {
@@ -568,6 +726,170 @@ var storageListSectorsCmd = &cli.Command{
 	},
 }
 
+var storageUnsealedCmd = &cli.Command{
+	Name:  "unsealed",
+	Usage: "list unsealed copies tracked by the unsealed cache, in eviction order",
+	Description: `Copies are only tracked while a budget is set in Storage.UnsealedCache.
+Releasable is the part of a copy which can be evicted, the rest is pinned by
+deals kept unsealed, or the sector isn't proving yet.`,
+	Action: func(cctx *cli.Context) error {
+		nodeApi, closer, err := api.GetStorageMinerAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+		ctx := api.ReqContext(cctx)
+
+		paths, err := nodeApi.StorageUnsealedCache(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, p := range paths {
+			budget := "none"
+			if p.Budget > 0 {
+				budget = types.SizeStr(types.NewInt(p.Budget))
+			}
+			fmt.Printf("%s:\n", p.ID)
+			fmt.Printf("\tUsed: %s, Budget: %s\n", types.SizeStr(types.NewInt(p.Used)), budget)
+
+			tw := tablewriter.New(
+				tablewriter.Col("Sector"),
+				tablewriter.Col("Allocated"),
+				tablewriter.Col("Releasable"),
+				tablewriter.Col("Reads"),
+				tablewriter.Col("Last Read"),
+			)
+			for _, c := range p.Copies {
+				lastRead := "never"
+				if !c.LastAccess.IsZero() {
+					lastRead = c.LastAccess.Format(time.RFC3339)
+				}
+
+				tw.Write(map[string]interface{}{
+					"Sector":     c.Sector.Number,
+					"Allocated":  types.SizeStr(types.NewInt(c.Allocated)),
+					"Releasable": types.SizeStr(types.NewInt(c.Releasable)),
+					"Reads":      c.Reads,
+					"Last Read":  lastRead,
+				})
+			}
+			if err := tw.Flush(os.Stdout); err != nil {
+				return err
+			}
+			fmt.Println()
+		}
+
+		return nil
+	},
+}
+
+var storageRelayoutCmd = &cli.Command{
+	Name:      "relayout",
+	Usage:     "change the sector file layout of a local storage path",
+	ArgsUsage: "[storage id]",
+	Description: `Moves the sector files of the path into the layout and records it in the
+sectorstore.json of the path. The path stays in use while files are moved.
+Files of sectors which are being worked on are skipped, running the command
+again moves them.`,
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:     "layout",
+			Usage:    "flat or sharded",
+			Required: true,
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		nodeApi, closer, err := api.GetStorageMinerAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+		ctx := api.ReqContext(cctx)
+
+		if cctx.Args().Len() != 1 {
+			return xerrors.Errorf("must specify storage id")
+		}
+
+		layout, err := storiface.ParseSectorLayout(cctx.String("layout"))
+		if err != nil {
+			return err
+		}
+
+		res, err := nodeApi.StorageRelayout(ctx, stores.ID(cctx.Args().First()), layout)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Moved %d sector files to the %s layout\n", res.Moved, layout)
+		if len(res.Busy) > 0 {
+			fmt.Printf("Skipped %d files of busy sectors, run relayout again to move them:\n", len(res.Busy))
+			for _, d := range res.Busy {
+				fmt.Printf("\t%d (%s)\n", d.Number, d.SectorFileType)
+			}
+		}
+
+		return nil
+	},
+}
+
+var storageVerifyManifestCmd = &cli.Command{
+	Name:      "verify-manifest",
+	Usage:     "reconcile the sector manifests of local storage paths with their files",
+	ArgsUsage: "[storage id...]",
+	Description: `Local storage paths keep a manifest of their sector files, which is used to
+declare the sectors when the sealer starts instead of listing all files. The
+manifests are checked in the background after starting, this command checks
+them now, eg. after files were changed by hand. Sector files which were added
+or removed are declared in, or dropped from, the index. Without arguments all
+local paths are checked.`,
+	Action: func(cctx *cli.Context) error {
+		nodeApi, closer, err := api.GetStorageMinerAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+		ctx := api.ReqContext(cctx)
+
+		var ids []stores.ID
+		for _, id := range cctx.Args().Slice() {
+			ids = append(ids, stores.ID(id))
+		}
+		if len(ids) == 0 {
+			local, err := nodeApi.StorageLocal(ctx)
+			if err != nil {
+				return err
+			}
+			for id := range local {
+				ids = append(ids, id)
+			}
+			sort.Slice(ids, func(i, j int) bool {
+				return ids[i] < ids[j]
+			})
+		}
+
+		for _, id := range ids {
+			res, err := nodeApi.StorageVerifyManifest(ctx, id)
+			if err != nil {
+				return xerrors.Errorf("verifying manifest of %s: %w", id, err)
+			}
+
+			fmt.Printf("%s: %d files\n", id, res.Entries)
+			for _, d := range res.Added {
+				fmt.Printf("\tadded %d (%s)\n", d.Number, d.SectorFileType)
+			}
+			for _, d := range res.Removed {
+				fmt.Printf("\tremoved %d (%s)\n", d.Number, d.SectorFileType)
+			}
+			if len(res.Changed) > 0 {
+				fmt.Printf("\t%d files changed size or modification time\n", len(res.Changed))
+			}
+		}
+
+		return nil
+	},
+}
+
 func maybeStr(c bool, col color.Attribute, s string) string {
 	if !c {
 		return ""
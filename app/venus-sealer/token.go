@@ -1,16 +1,32 @@
 package main
 
 import (
+	"encoding/hex"
 	"fmt"
-	"github.com/filecoin-project/venus-sealer/api"
 	_ "net/http/pprof"
+	"os"
+	"time"
 
+	"github.com/filecoin-project/go-jsonrpc/auth"
+	"github.com/gbrlsnchs/jwt/v3"
+	"github.com/google/uuid"
 	"github.com/urfave/cli/v2"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/venus-sealer/api"
+	"github.com/filecoin-project/venus-sealer/config"
+	"github.com/filecoin-project/venus-sealer/lib/tablewriter"
+	"github.com/filecoin-project/venus-sealer/types"
 )
 
 var tokenCmd = &cli.Command{
 	Name:  "token",
 	Usage: "Print venus sealer token",
+	Subcommands: []*cli.Command{
+		tokenCreateCmd,
+		tokenRevokeCmd,
+		tokenRevokedCmd,
+	},
 	Action: func(cctx *cli.Context) error {
 		storageAPI, closer, err := api.GetStorageMinerAPI(cctx)
 		if err != nil {
@@ -25,4 +41,120 @@ var tokenCmd = &cli.Command{
 		fmt.Println("token: ", string(token))
 		return nil
 	},
-}
\ No newline at end of file
+}
+
+var tokenCreateCmd = &cli.Command{
+	Name:  "create",
+	Usage: "Create a token with scoped permissions",
+	Description: fmt.Sprintf(`Scoped tokens can only call read methods and methods of their scopes.
+Available scopes: %v`, api.Scopes),
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:     "scopes",
+			Usage:    "comma separated list of scopes, eg. sectors:read,proving:read",
+			Required: true,
+		},
+		&cli.DurationFlag{
+			Name:  "expires",
+			Usage: "how long the token is valid for, 0 for a token which doesn't expire",
+			Value: 30 * 24 * time.Hour,
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		scopes, err := api.ParseScopes(cctx.String("scopes"))
+		if err != nil {
+			return err
+		}
+
+		cfg, err := config.MinerFromFile(config.FsConfig(cctx.String("repo")))
+		if err != nil {
+			return xerrors.Errorf("reading venus-sealer config: %w", err)
+		}
+
+		sk, err := hex.DecodeString(cfg.JWT.Secret)
+		if err != nil {
+			return xerrors.Errorf("decoding jwt secret: %w", err)
+		}
+		if len(sk) != 32 {
+			return xerrors.Errorf("error private key format")
+		}
+
+		now := time.Now()
+		p := types.JWTPayload{
+			Payload: jwt.Payload{
+				JWTID:    uuid.New().String(),
+				IssuedAt: jwt.NumericDate(now),
+			},
+			Allow: append([]auth.Permission{api.PermRead}, scopes...),
+		}
+		if exp := cctx.Duration("expires"); exp > 0 {
+			p.ExpirationTime = jwt.NumericDate(now.Add(exp))
+		}
+
+		token, err := jwt.Sign(&p, jwt.NewHS256(sk))
+		if err != nil {
+			return xerrors.Errorf("signing token: %w", err)
+		}
+
+		fmt.Println("id:    ", p.JWTID)
+		if p.ExpirationTime != nil {
+			fmt.Println("expires:", p.ExpirationTime.Time.Format(time.RFC3339))
+		}
+		fmt.Println("token: ", string(token))
+		return nil
+	},
+}
+
+var tokenRevokeCmd = &cli.Command{
+	Name:      "revoke",
+	Usage:     "Revoke a scoped token",
+	ArgsUsage: "<token or token id>",
+	Action: func(cctx *cli.Context) error {
+		if cctx.Args().Len() != 1 {
+			return xerrors.Errorf("expected a token or a token id")
+		}
+
+		storageAPI, closer, err := api.GetStorageMinerAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		return storageAPI.AuthRevoke(api.ReqContext(cctx), cctx.Args().First())
+	},
+}
+
+var tokenRevokedCmd = &cli.Command{
+	Name:  "revoked",
+	Usage: "List revoked tokens",
+	Action: func(cctx *cli.Context) error {
+		storageAPI, closer, err := api.GetStorageMinerAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		revoked, err := storageAPI.AuthRevokedList(api.ReqContext(cctx))
+		if err != nil {
+			return err
+		}
+
+		tw := tablewriter.New(
+			tablewriter.Col("ID"),
+			tablewriter.Col("Revoked"),
+			tablewriter.Col("Expires"),
+		)
+		for _, t := range revoked {
+			expires := "never"
+			if !t.Expires.IsZero() {
+				expires = t.Expires.Format(time.RFC3339)
+			}
+			tw.Write(map[string]interface{}{
+				"ID":      t.ID,
+				"Revoked": t.Revoked.Format(time.RFC3339),
+				"Expires": expires,
+			})
+		}
+		return tw.Flush(os.Stdout)
+	},
+}
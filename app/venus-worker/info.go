@@ -5,6 +5,7 @@ import (
 	"github.com/filecoin-project/venus-sealer/api"
 	types2 "github.com/filecoin-project/venus-sealer/types"
 	"sort"
+	"strings"
 
 	"github.com/urfave/cli/v2"
 	"golang.org/x/xerrors"
@@ -67,12 +68,31 @@ var infoCmd = &cli.Command{
 		fmt.Printf("Reserved memory: %s\n", types.SizeStr(types.NewInt(info.Resources.MemReserved)))
 		fmt.Printf("Tasks: %s\n", tasks)
 
+		bindP1P2, err := workerApi.BindP1P2(ctx)
+		if err != nil {
+			return xerrors.Errorf("getting bindP1P2: %w", err)
+		}
+		fmt.Printf("Bind P1P2: %t\n", bindP1P2)
+
 		fmt.Printf("Task types: ")
 		for _, t := range ttList(tt) {
+			if limit, ok := info.TaskLimits[t]; ok {
+				fmt.Printf("%s(%d) ", t.Short(), limit)
+				continue
+			}
 			fmt.Printf("%s ", t.Short())
 		}
 		fmt.Println()
 
+		if len(info.Labels) > 0 {
+			var labels []string
+			for k, v := range info.Labels {
+				labels = append(labels, k+"="+v)
+			}
+			sort.Strings(labels)
+			fmt.Printf("Labels: %s\n", strings.Join(labels, ", "))
+		}
+
 		fmt.Println()
 
 		paths, err := workerApi.Paths(ctx)
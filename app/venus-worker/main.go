@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
@@ -171,6 +172,14 @@ var runCmd = &cli.Command{
 			Usage: "P1 and P2 phase tasks are bound to the same machine",
 			Value: false,
 		},
+		&cli.StringSliceFlag{
+			Name:  "task-limit",
+			Usage: "maximum number of tasks of a type running at the same time, e.g. PC1=14 (can be repeated)",
+		},
+		&cli.StringSliceFlag{
+			Name:  "label",
+			Usage: "label reported to the sealer for routing sectors, e.g. tier=deal (can be repeated)",
+		},
 	},
 	Action: func(cctx *cli.Context) error {
 		log.Info("Starting venus worker")
@@ -318,11 +327,22 @@ var runCmd = &cli.Command{
 			taskTypes = append(taskTypes, types.TTCommit2)
 		}
 
+		taskTypes, err = applyTaskOverrides(taskTypes, cfg.Tasks)
+		if err != nil {
+			return err
+		}
+
+		taskLimits, err := parseTaskLimits(cfg.Tasks.Limits)
+		if err != nil {
+			return err
+		}
+
 		if len(taskTypes) == 0 {
 			return xerrors.Errorf("no task types specified")
 		}
 
 		log.Infof("Acceptable task types: %v", taskTypes)
+		log.Infof("Task limits: %v", cfg.Tasks.Limits)
 
 		localStorage := cfg.LocalStorage()
 		_, err = localStorage.GetStorage()
@@ -418,10 +438,13 @@ var runCmd = &cli.Command{
 				TaskTypes:  taskTypes,
 				NoSwap:     cctx.Bool("no-swap"),
 				TaskTotal:  cctx.Int64("task-total"),
-				IsBindP1P2: cctx.Bool("bindP1P2"),
+				IsBindP1P2: cfg.Tasks.BindP1P2,
+				TaskLimits: taskLimits,
+				Labels:     cfg.Labels,
 			}, remote, localStore, nodeApi, nodeApi, wsts),
 			localStore: localStore,
 			ls:         localStorage,
+			cfg:        cfg,
 		}
 
 		mux := mux.NewRouter()
@@ -598,7 +621,79 @@ func flagData(cfg *config.StorageWorker, cctx *cli.Context) error {
 		cfg.Sealer.Token = cctx.String("miner-token")
 	}
 
+	if cctx.IsSet("bindP1P2") {
+		cfg.Tasks.BindP1P2 = cctx.Bool("bindP1P2")
+	}
+
+	for _, l := range cctx.StringSlice("task-limit") {
+		kv := strings.SplitN(l, "=", 2)
+		if len(kv) != 2 {
+			return xerrors.Errorf("malformed task limit '%s', expected TYPE=LIMIT", l)
+		}
+
+		limit, err := strconv.ParseInt(kv[1], 10, 64)
+		if err != nil {
+			return xerrors.Errorf("parsing task limit '%s': %w", l, err)
+		}
+
+		if cfg.Tasks.Limits == nil {
+			cfg.Tasks.Limits = map[string]int64{}
+		}
+		cfg.Tasks.Limits[kv[0]] = limit
+	}
+
+	for _, l := range cctx.StringSlice("label") {
+		kv := strings.SplitN(l, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return xerrors.Errorf("malformed label '%s', expected KEY=VALUE", l)
+		}
+
+		if cfg.Labels == nil {
+			cfg.Labels = map[string]string{}
+		}
+		cfg.Labels[kv[0]] = kv[1]
+	}
+
 	cfg.DataDir = cctx.String("repo")
 
 	return nil
 }
+
+func applyTaskOverrides(taskTypes []types.TaskType, tc config.WorkerTasksConfig) ([]types.TaskType, error) {
+	accept := map[types.TaskType]struct{}{}
+	for _, tt := range taskTypes {
+		accept[tt] = struct{}{}
+	}
+
+	for _, short := range tc.Enable {
+		tt, ok := types.TaskTypeFromShort(short)
+		if !ok {
+			return nil, xerrors.Errorf("unknown task type '%s' in Tasks.Enable", short)
+		}
+		accept[tt] = struct{}{}
+	}
+
+	for _, short := range tc.Disable {
+		tt, ok := types.TaskTypeFromShort(short)
+		if !ok {
+			return nil, xerrors.Errorf("unknown task type '%s' in Tasks.Disable", short)
+		}
+		delete(accept, tt)
+	}
+
+	return ttList(accept), nil
+}
+
+func parseTaskLimits(limits map[string]int64) (map[types.TaskType]int64, error) {
+	out := map[types.TaskType]int64{}
+	for short, limit := range limits {
+		tt, ok := types.TaskTypeFromShort(short)
+		if !ok {
+			return nil, xerrors.Errorf("unknown task type '%s' in Tasks.Limits", short)
+		}
+		if limit > 0 {
+			out[tt] = limit
+		}
+	}
+	return out, nil
+}
@@ -2,7 +2,10 @@ package main
 
 import (
 	"context"
+	"github.com/filecoin-project/venus-sealer/config"
 	"github.com/filecoin-project/venus-sealer/constants"
+	"github.com/filecoin-project/venus-sealer/types"
+	"sync"
 	"sync/atomic"
 
 	"github.com/google/uuid"
@@ -20,6 +23,9 @@ type worker struct {
 	localStore *stores.Local
 	ls         stores.LocalStorage
 
+	cfgLk sync.Mutex
+	cfg   *config.StorageWorker
+
 	disabled int64
 }
 
@@ -46,6 +52,78 @@ func (w *worker) StorageAddLocal(ctx context.Context, path string) error {
 	return nil
 }
 
+// persistTasks applies the change to the task settings in the repo config, so
+// it survives worker restarts
+func (w *worker) persistTasks(mut func(tc *config.WorkerTasksConfig)) error {
+	w.cfgLk.Lock()
+	defer w.cfgLk.Unlock()
+
+	mut(&w.cfg.Tasks)
+	if err := config.UpdateConfig(w.cfg.ConfigPath, w.cfg); err != nil {
+		return xerrors.Errorf("persisting task settings: %w", err)
+	}
+	return nil
+}
+
+func (w *worker) TaskEnable(ctx context.Context, tt types.TaskType) error {
+	if err := w.LocalWorker.TaskEnable(ctx, tt); err != nil {
+		return err
+	}
+
+	return w.persistTasks(func(tc *config.WorkerTasksConfig) {
+		tc.Disable = removeTaskName(tc.Disable, tt.Short())
+		tc.Enable = append(removeTaskName(tc.Enable, tt.Short()), tt.Short())
+	})
+}
+
+func (w *worker) TaskDisable(ctx context.Context, tt types.TaskType) error {
+	if err := w.LocalWorker.TaskDisable(ctx, tt); err != nil {
+		return err
+	}
+
+	return w.persistTasks(func(tc *config.WorkerTasksConfig) {
+		tc.Enable = removeTaskName(tc.Enable, tt.Short())
+		tc.Disable = append(removeTaskName(tc.Disable, tt.Short()), tt.Short())
+	})
+}
+
+func (w *worker) SetTaskLimit(ctx context.Context, tt types.TaskType, limit int64) error {
+	if err := w.LocalWorker.SetTaskLimit(ctx, tt, limit); err != nil {
+		return err
+	}
+
+	return w.persistTasks(func(tc *config.WorkerTasksConfig) {
+		if tc.Limits == nil {
+			tc.Limits = map[string]int64{}
+		}
+		if limit <= 0 {
+			delete(tc.Limits, tt.Short())
+			return
+		}
+		tc.Limits[tt.Short()] = limit
+	})
+}
+
+func (w *worker) SetBindP1P2(ctx context.Context, bind bool) error {
+	if err := w.LocalWorker.SetBindP1P2(ctx, bind); err != nil {
+		return err
+	}
+
+	return w.persistTasks(func(tc *config.WorkerTasksConfig) {
+		tc.BindP1P2 = bind
+	})
+}
+
+func removeTaskName(names []string, name string) []string {
+	out := names[:0]
+	for _, n := range names {
+		if n != name {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
 func (w *worker) SetEnabled(ctx context.Context, enabled bool) error {
 	disabled := int64(1)
 	if enabled {
@@ -2,7 +2,9 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"github.com/filecoin-project/venus-sealer/types"
+	"strconv"
 	"strings"
 
 	"github.com/urfave/cli/v2"
@@ -17,6 +19,8 @@ var tasksCmd = &cli.Command{
 	Subcommands: []*cli.Command{
 		tasksEnableCmd,
 		tasksDisableCmd,
+		tasksLimitCmd,
+		tasksBindP1P2Cmd,
 	},
 }
 
@@ -79,3 +83,81 @@ func taskAction(tf func(a api.WorkerAPI, ctx context.Context, tt types.TaskType)
 		return tf(workerApi, ctx, tt)
 	}
 }
+
+var tasksLimitCmd = &cli.Command{
+	Name:      "limit",
+	Usage:     "Show or set the number of tasks of a type allowed to run at the same time",
+	ArgsUsage: "[task type] [limit (0 = no limit)]",
+	Action: func(cctx *cli.Context) error {
+		workerApi, closer, err := api.GetWorkerAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		ctx := api.ReqContext(cctx)
+
+		if cctx.NArg() == 0 {
+			limits, err := workerApi.TaskLimits(ctx)
+			if err != nil {
+				return xerrors.Errorf("getting task limits: %w", err)
+			}
+
+			tts := map[types.TaskType]struct{}{}
+			for tt := range limits {
+				tts[tt] = struct{}{}
+			}
+			for _, tt := range ttList(tts) {
+				fmt.Printf("%s: %d\n", tt.Short(), limits[tt])
+			}
+			return nil
+		}
+
+		if cctx.NArg() != 2 {
+			return xerrors.Errorf("expected 0 or 2 arguments")
+		}
+
+		tt, ok := types.TaskTypeFromShort(cctx.Args().First())
+		if !ok {
+			return xerrors.Errorf("unknown task type '%s'", cctx.Args().First())
+		}
+
+		limit, err := strconv.ParseInt(cctx.Args().Get(1), 10, 64)
+		if err != nil {
+			return xerrors.Errorf("parsing limit: %w", err)
+		}
+
+		return workerApi.SetTaskLimit(ctx, tt, limit)
+	},
+}
+
+var tasksBindP1P2Cmd = &cli.Command{
+	Name:      "bind-p1p2",
+	Usage:     "Show or set whether PC2 must run on the worker which has done PC1",
+	ArgsUsage: "[true|false]",
+	Action: func(cctx *cli.Context) error {
+		workerApi, closer, err := api.GetWorkerAPI(cctx)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		ctx := api.ReqContext(cctx)
+
+		if cctx.NArg() == 0 {
+			bind, err := workerApi.BindP1P2(ctx)
+			if err != nil {
+				return xerrors.Errorf("getting bindP1P2: %w", err)
+			}
+			fmt.Println(bind)
+			return nil
+		}
+
+		bind, err := strconv.ParseBool(cctx.Args().First())
+		if err != nil {
+			return xerrors.Errorf("parsing argument: %w", err)
+		}
+
+		return workerApi.SetBindP1P2(ctx, bind)
+	},
+}
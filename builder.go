@@ -145,7 +145,13 @@ func Online(cfg *config.StorageMiner) Option {
 
 		Override(new(types.GetSealingConfigFunc), NewGetSealConfigFunc),
 		Override(new(*sectorblocks.SectorBlocks), sectorblocks.NewSectorBlocks),
-		Override(new(*storage.Miner), StorageMiner(config.DefaultMainnetStorageMiner().Fees)),
+		Override(new(sectorstorage.Unsealer), From(new(*sectorstorage.Manager))),
+		Override(new(sectorstorage.PieceProvider), sectorstorage.NewPieceProvider),
+		Override(new(*sectorblocks.PieceHandler), sectorblocks.NewPieceHandler),
+		Override(new(config.GetMinerFeeConfigFunc), NewGetMinerFeeConfigFunc),
+		Override(new(*storage.Miner), StorageMiner),
+		Override(new(sectorstorage.IntegrityChecker), From(new(*sectorstorage.Manager))),
+		Override(new(*storage.IntegrityAuditor), IntegrityAuditor),
 		// Override(new(*storage.AddressSelector), AddressSelector(nil)), // venus-sealer run: Call Repo before, Online after,will overwrite the original injection(MinerAddressConfig)
 		Override(new(types.NetworkName), StorageNetworkName),
 		Override(GetParamsKey, GetParams),
@@ -168,6 +174,7 @@ func Repo(cfg *config.StorageMiner) Option {
 			Override(new(*storage.AddressSelector), AddressSelector(&cfg.Addresses)),
 			Override(new(*config.DbConfig), &cfg.DB),
 			Override(new(*config.StorageMiner), cfg),
+			Override(new(*config.Live), config.NewLive(cfg)),
 			Override(new(*config.MessagerConfig), &cfg.Messager),
 			Override(new(*config.MarketConfig), &cfg.Market),
 			Override(new(*config.RegisterMarketConfig), &cfg.RegisterMarket),
@@ -182,6 +189,11 @@ func Repo(cfg *config.StorageMiner) Option {
 			Providers(
 				service.NewDealRefServiceService,
 				service.NewLogService,
+				service.NewBatchService,
+				service.NewUnreportedDealService,
+				service.NewTokenService,
+				service.NewAuditService,
+				service.NewIntegrityService,
 				service.NewMetadataService,
 				service.NewSectorInfoService,
 			//	service.NewWorkCallService,
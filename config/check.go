@@ -0,0 +1,290 @@
+package config
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/multiformats/go-multiaddr"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+
+	miner5 "github.com/filecoin-project/specs-actors/v5/actors/builtin/miner"
+
+	"github.com/filecoin-project/venus/pkg/types"
+
+	sectorstorage "github.com/filecoin-project/venus-sealer/sector-storage"
+)
+
+// Problem is an issue found when checking the config
+type Problem struct {
+	Key     string
+	Message string
+	// Fatal problems keep the sealer from starting, others are only reported
+	Fatal bool
+}
+
+func (p Problem) String() string {
+	level := "warning"
+	if p.Fatal {
+		level = "error"
+	}
+	return fmt.Sprintf("%s: %s: %s", level, p.Key, p.Message)
+}
+
+// FatalProblems returns an error listing the fatal problems, or nil if there are none
+func FatalProblems(problems []Problem) error {
+	var msgs []string
+	for _, p := range problems {
+		if p.Fatal {
+			msgs = append(msgs, fmt.Sprintf("%s: %s", p.Key, p.Message))
+		}
+	}
+	if len(msgs) == 0 {
+		return nil
+	}
+	return xerrors.Errorf("invalid config:\n  %s", strings.Join(msgs, "\n  "))
+}
+
+type checker struct {
+	problems []Problem
+}
+
+func (c *checker) fatalf(key, format string, args ...interface{}) {
+	c.problems = append(c.problems, Problem{Key: key, Message: fmt.Sprintf(format, args...), Fatal: true})
+}
+
+func (c *checker) warnf(key, format string, args ...interface{}) {
+	c.problems = append(c.problems, Problem{Key: key, Message: fmt.Sprintf(format, args...)})
+}
+
+// Check validates the config, limits depending on the network are computed
+// with NetParams. It doesn't contact any of the configured services.
+func Check(cfg *StorageMiner) []Problem {
+	c := &checker{}
+
+	c.checkNetParams(cfg.NetParams)
+	c.checkSealing(cfg.Sealing, cfg.NetParams)
+	c.checkFees(cfg.Fees)
+	c.checkAddresses(cfg.Addresses)
+
+	if err := sectorstorage.CheckSealerConfig(cfg.Storage); err != nil {
+		c.fatalf("Storage", "%s", err)
+	}
+	if cfg.Storage.ParallelFetchLimit <= 0 {
+		c.fatalf("Storage.ParallelFetchLimit", "must be positive, got %d", cfg.Storage.ParallelFetchLimit)
+	}
+
+	if cfg.IntegrityAudit.Interval <= 0 {
+		c.fatalf("IntegrityAudit.Interval", "must be positive, got %s", time.Duration(cfg.IntegrityAudit.Interval))
+	}
+	if cfg.IntegrityAudit.Rounds <= 0 {
+		c.fatalf("IntegrityAudit.Rounds", "must be positive, got %d", cfg.IntegrityAudit.Rounds)
+	}
+
+	if _, err := multiaddr.NewMultiaddr(strings.TrimSpace(cfg.API.ListenAddress)); err != nil {
+		c.fatalf("API.ListenAddress", "%s", err)
+	}
+	c.checkURL("Node.Url", cfg.Node.Url, true)
+	c.checkURL("Messager.Url", cfg.Messager.Url, true)
+	c.checkURL("Market.Url", cfg.Market.Url, false)
+	for i, u := range cfg.RegisterProof.Urls {
+		c.checkURL(fmt.Sprintf("RegisterProof.Urls[%d]", i), u, true)
+	}
+	for i, u := range cfg.RegisterMarket.Urls {
+		c.checkURL(fmt.Sprintf("RegisterMarket.Urls[%d]", i), u, true)
+	}
+
+	if sk, err := hex.DecodeString(cfg.JWT.Secret); err != nil || len(sk) != 32 {
+		c.fatalf("JWT.Secret", "must be 32 hex encoded bytes")
+	}
+
+	switch cfg.DB.Type {
+	case "sqlite":
+		if cfg.DB.Sqlite.Path == "" {
+			c.fatalf("DB.Sqlite.Path", "not set")
+		}
+	case "mysql":
+		if cfg.DB.MySql.Addr == "" {
+			c.fatalf("DB.MySql.addr", "not set")
+		}
+	default:
+		c.fatalf("DB.Type", "unknown database type '%s', expected sqlite or mysql", cfg.DB.Type)
+	}
+
+	return c.problems
+}
+
+func (c *checker) checkNetParams(np NetParamsConfig) {
+	if np.BlockDelaySecs == 0 {
+		c.fatalf("NetParams.BlockDelaySecs", "must be positive")
+	}
+	if np.ForkLengthThreshold <= 0 {
+		c.fatalf("NetParams.ForkLengthThreshold", "must be positive")
+	}
+	if np.PreCommitChallengeDelay < 0 {
+		c.fatalf("NetParams.PreCommitChallengeDelay", "can't be negative")
+	}
+}
+
+func (c *checker) checkSealing(s SealingConfig, np NetParamsConfig) {
+	durations := []struct {
+		key string
+		d   Duration
+	}{
+		{"WaitDealsDelay", s.WaitDealsDelay},
+		{"CommittedCapacitySectorLifetime", s.CommittedCapacitySectorLifetime},
+		{"PreCommitBatchWait", s.PreCommitBatchWait},
+		{"PreCommitBatchSlack", s.PreCommitBatchSlack},
+		{"CommitBatchWait", s.CommitBatchWait},
+		{"CommitBatchSlack", s.CommitBatchSlack},
+		{"TerminateBatchWait", s.TerminateBatchWait},
+		{"DealIngestInterval", s.DealIngestInterval},
+	}
+	for _, d := range durations {
+		if d.d < 0 {
+			c.fatalf("Sealing."+d.key, "can't be negative")
+		}
+	}
+
+	if s.MaxPreCommitBatch < 1 || s.MaxPreCommitBatch > miner5.PreCommitSectorBatchMaxSize {
+		c.fatalf("Sealing.MaxPreCommitBatch", "must be between 1 and the network max of %d, got %d", miner5.PreCommitSectorBatchMaxSize, s.MaxPreCommitBatch)
+	}
+
+	if s.MinCommitBatch < miner5.MinAggregatedSectors {
+		c.fatalf("Sealing.MinCommitBatch", "can't be less than the network min of %d, got %d", miner5.MinAggregatedSectors, s.MinCommitBatch)
+	}
+	if s.MaxCommitBatch > miner5.MaxAggregatedSectors {
+		c.fatalf("Sealing.MaxCommitBatch", "can't be greater than the network max of %d, got %d", miner5.MaxAggregatedSectors, s.MaxCommitBatch)
+	}
+	if s.MinCommitBatch > s.MaxCommitBatch {
+		c.fatalf("Sealing.MinCommitBatch", "(%d) is greater than Sealing.MaxCommitBatch (%d)", s.MinCommitBatch, s.MaxCommitBatch)
+	}
+
+	if s.TerminateBatchMin > s.TerminateBatchMax {
+		c.fatalf("Sealing.TerminateBatchMin", "(%d) is greater than Sealing.TerminateBatchMax (%d)", s.TerminateBatchMin, s.TerminateBatchMax)
+	}
+	if s.DealIngestBatch < 0 {
+		c.fatalf("Sealing.DealIngestBatch", "can't be negative")
+	}
+
+	seen := map[string]bool{}
+	for i, p := range s.RecoveryPlaybooks {
+		key := fmt.Sprintf("Sealing.RecoveryPlaybooks[%d]", i)
+		if err := p.Validate(); err != nil {
+			c.fatalf(key, "%s", err)
+			continue
+		}
+		if seen[p.State+"/"+p.Cause] {
+			c.fatalf(key, "duplicate playbook for %s", strings.TrimSuffix(p.State+" "+p.Cause, " "))
+		}
+		seen[p.State+"/"+p.Cause] = true
+	}
+
+	if np.BlockDelaySecs == 0 {
+		return
+	}
+	epochs := func(e abi.ChainEpoch) time.Duration {
+		return time.Duration(e) * time.Duration(np.BlockDelaySecs) * time.Second
+	}
+
+	// the precommit ticket expires after MaxPreCommitRandomnessLookback epochs
+	if ticketExpiry := epochs(miner5.MaxPreCommitRandomnessLookback); s.BatchPreCommits && time.Duration(s.PreCommitBatchWait) >= ticketExpiry {
+		c.warnf("Sealing.PreCommitBatchWait", "%s is not shorter than the precommit ticket expiration of %s, batches will be sent at the slack deadline", time.Duration(s.PreCommitBatchWait), ticketExpiry)
+	}
+
+	proveCommitDuration := miner5.MaxProveCommitDuration[abi.RegisteredSealProof_StackedDrg32GiBV1_1]
+	if maxWait := epochs(proveCommitDuration + np.PreCommitChallengeDelay); s.AggregateCommits && time.Duration(s.CommitBatchWait) >= maxWait {
+		c.warnf("Sealing.CommitBatchWait", "%s is not shorter than the prove commit deadline of %s, batches will be sent at the slack deadline", time.Duration(s.CommitBatchWait), maxWait)
+	}
+}
+
+func (c *checker) checkFees(f MinerFeeConfig) {
+	fees := []struct {
+		key string
+		fee types.FIL
+	}{
+		{"MaxPreCommitGasFee", f.MaxPreCommitGasFee},
+		{"MaxCommitGasFee", f.MaxCommitGasFee},
+		{"MaxPreCommitBatchGasFee.PerSector", f.MaxPreCommitBatchGasFee.PerSector},
+		{"MaxCommitBatchGasFee.PerSector", f.MaxCommitBatchGasFee.PerSector},
+		{"MaxTerminateGasFee", f.MaxTerminateGasFee},
+		{"MaxWindowPoStGasFee", f.MaxWindowPoStGasFee},
+		{"MaxPublishDealsFee", f.MaxPublishDealsFee},
+		{"MaxMarketBalanceAddFee", f.MaxMarketBalanceAddFee},
+	}
+
+	for _, fee := range fees {
+		b := big.Int(fee.fee)
+		if b.Int == nil || b.Sign() <= 0 {
+			c.fatalf("Fees."+fee.key, "must be positive, messages can't be sent with a fee cap of zero")
+		}
+	}
+
+	// batch fee caps only need the per sector part, a zero base is common
+	bases := []struct {
+		key string
+		fee types.FIL
+	}{
+		{"MaxPreCommitBatchGasFee.Base", f.MaxPreCommitBatchGasFee.Base},
+		{"MaxCommitBatchGasFee.Base", f.MaxCommitBatchGasFee.Base},
+	}
+
+	for _, fee := range bases {
+		b := big.Int(fee.fee)
+		if b.Int != nil && b.Sign() < 0 {
+			c.fatalf("Fees."+fee.key, "must not be negative")
+		}
+	}
+}
+
+func (c *checker) checkAddresses(a MinerAddressConfig) {
+	addrs := []struct {
+		key   string
+		addrs []string
+	}{
+		{"PreCommitControl", a.PreCommitControl},
+		{"CommitControl", a.CommitControl},
+		{"TerminateControl", a.TerminateControl},
+	}
+	for _, list := range addrs {
+		for i, s := range list.addrs {
+			if _, err := address.NewFromString(s); err != nil {
+				c.fatalf(fmt.Sprintf("Addresses.%s[%d]", list.key, i), "invalid address '%s': %s", s, err)
+			}
+		}
+	}
+
+	if a.DisableOwnerFallback && a.DisableWorkerFallback && len(a.PreCommitControl)+len(a.CommitControl) == 0 {
+		c.warnf("Addresses", "owner and worker fallbacks are disabled without control addresses")
+	}
+}
+
+// checkURL checks that a service url is either a multiaddr or an http(s)/ws(s) url
+func (c *checker) checkURL(key, u string, required bool) {
+	u = strings.TrimSpace(u)
+	if u == "" {
+		if required {
+			c.fatalf(key, "not set")
+		}
+		return
+	}
+
+	if strings.HasPrefix(u, "/") {
+		if _, err := multiaddr.NewMultiaddr(u); err != nil {
+			c.fatalf(key, "invalid multiaddr '%s': %s", u, err)
+		}
+		return
+	}
+
+	for _, scheme := range []string{"http://", "https://", "ws://", "wss://"} {
+		if strings.HasPrefix(u, scheme) {
+			return
+		}
+	}
+	c.fatalf(key, "'%s' is neither a multiaddr nor an url", u)
+}
@@ -0,0 +1,111 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-state-types/big"
+
+	"github.com/filecoin-project/venus/pkg/types"
+
+	"github.com/filecoin-project/venus-sealer/storage-sealing/sealiface"
+)
+
+func checkedConfig() *StorageMiner {
+	cfg := DefaultMainnetStorageMiner()
+	cfg.Messager.Url = "/ip4/127.0.0.1/tcp/39812"
+	return cfg
+}
+
+func problemKeys(problems []Problem) []string {
+	var keys []string
+	for _, p := range problems {
+		keys = append(keys, p.Key)
+	}
+	return keys
+}
+
+func TestCheckDefaults(t *testing.T) {
+	for _, network := range Networks {
+		cfg, err := GetDefaultStorageConfig(network)
+		require.NoError(t, err)
+		cfg.Messager.Url = "/ip4/127.0.0.1/tcp/39812"
+
+		require.NoError(t, FatalProblems(Check(cfg)), network)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	cfg := checkedConfig()
+	cfg.Sealing.MaxCommitBatch = 1000
+	cfg.Fees.MaxWindowPoStGasFee = types.FIL(big.Zero())
+	cfg.Addresses.CommitControl = []string{"f3notanaddress"}
+	cfg.Node.Url = "localhost:3453"
+	cfg.NetParams.BlockDelaySecs = 0
+	cfg.Sealing.RecoveryPlaybooks = []sealiface.RecoveryPlaybook{
+		{State: "CommitFailed", Cause: "ExpiredTicket", Steps: []sealiface.RecoveryStep{{Action: sealiface.RecoveryRestartGetTicket, Attempts: 1}}},
+		{State: "CommitFailed", Cause: "ExpiredTicket", Steps: []sealiface.RecoveryStep{{Action: sealiface.RecoveryRemove, Attempts: 1}}},
+		{State: "Proving", Steps: []sealiface.RecoveryStep{{Action: sealiface.RecoveryRetry, Attempts: 1}}},
+	}
+
+	problems := Check(cfg)
+	require.ElementsMatch(t, []string{
+		"NetParams.BlockDelaySecs",
+		"Sealing.MaxCommitBatch",
+		"Sealing.RecoveryPlaybooks[1]",
+		"Sealing.RecoveryPlaybooks[2]",
+		"Fees.MaxWindowPoStGasFee",
+		"Addresses.CommitControl[0]",
+		"Node.Url",
+	}, problemKeys(problems))
+
+	err := FatalProblems(problems)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "Fees.MaxWindowPoStGasFee")
+}
+
+func TestCheckBatchFeeBase(t *testing.T) {
+	cfg := checkedConfig()
+	cfg.Fees.MaxPreCommitBatchGasFee.Base = types.FIL(big.Zero())
+	cfg.Fees.MaxCommitBatchGasFee.Base = types.FIL(big.Zero())
+	require.Empty(t, Check(cfg))
+
+	cfg.Fees.MaxCommitBatchGasFee.PerSector = types.FIL(big.Zero())
+	require.Equal(t, []string{"Fees.MaxCommitBatchGasFee.PerSector"}, problemKeys(Check(cfg)))
+}
+
+func TestCheckWarnings(t *testing.T) {
+	cfg := checkedConfig()
+	cfg.Sealing.BatchPreCommits = true
+	cfg.Sealing.PreCommitBatchWait = Duration(48 * time.Hour)
+
+	problems := Check(cfg)
+	require.Equal(t, []string{"Sealing.PreCommitBatchWait"}, problemKeys(problems))
+	require.False(t, problems[0].Fatal)
+	require.NoError(t, FatalProblems(problems))
+}
+
+func TestDiff(t *testing.T) {
+	cfg := checkedConfig()
+	cfg.Sealing.MaxSealingSectors = 10
+	cfg.Node.Token = "secret token"
+	cfg.JWT.Secret = "different"
+
+	diff, err := Diff(cfg, DefaultMainnetStorageMiner())
+	require.NoError(t, err)
+	require.Equal(t, []DiffEntry{
+		{Key: "Messager.Url", Value: `"/ip4/127.0.0.1/tcp/39812"`, Default: `""`},
+		{Key: "Node.Token", Value: "<set>", Default: "<set>"},
+		{Key: "Sealing.MaxSealingSectors", Value: "10", Default: "0"},
+	}, diff)
+}
+
+func TestGuessNetwork(t *testing.T) {
+	for _, network := range []string{"mainnet", "2k", "force"} {
+		cfg, err := GetDefaultStorageConfig(network)
+		require.NoError(t, err)
+		require.Equal(t, network, GuessNetwork(cfg))
+	}
+}
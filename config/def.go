@@ -15,6 +15,7 @@ import (
 	"github.com/filecoin-project/venus/pkg/types"
 
 	sectorstorage "github.com/filecoin-project/venus-sealer/sector-storage"
+	"github.com/filecoin-project/venus-sealer/storage-sealing/sealiface"
 )
 
 const (
@@ -33,6 +34,25 @@ type StorageWorker struct {
 	DataDir    string
 	Sealer     NodeConfig
 	DB         DbConfig
+	Tasks      WorkerTasksConfig
+
+	// Labels are reported to the sealer and matched against the labels
+	// required for sectors, e.g. group = "rackA", tier = "deal"
+	Labels map[string]string
+}
+
+// WorkerTasksConfig keeps the task settings changed through the worker api, task
+// types are referred to by their short names (AP, PC1, PC2, C2, UNS ...)
+type WorkerTasksConfig struct {
+	// Enable and Disable are applied on top of the task types selected by run flags
+	Enable  []string
+	Disable []string
+
+	// Limits caps the number of tasks of a type running at the same time
+	Limits map[string]int64
+
+	// BindP1P2 runs PC2 on the worker which has done PC1 of the sector
+	BindP1P2 bool
 }
 
 func (cfg StorageWorker) LocalStorage() *LocalStorage {
@@ -46,6 +66,7 @@ type StorageMiner struct {
 	Dealmaking     DealmakingConfig
 	Sealing        SealingConfig
 	Storage        sectorstorage.SealerConfig
+	IntegrityAudit IntegrityAuditConfig
 	Fees           MinerFeeConfig
 	Addresses      MinerAddressConfig
 	NetParams      NetParamsConfig
@@ -264,6 +285,10 @@ type SealingConfig struct {
 
 	AggregateAboveBaseFee      types.FIL
 	BatchPreCommitAboveBaseFee types.FIL
+	// Decide between batched and individual messages by estimating their gas and network fees
+	// at the current base fee, instead of the AggregateAboveBaseFee/BatchPreCommitAboveBaseFee thresholds.
+	// Commits too few to aggregate wait for more sectors until CommitBatchSlack before the earliest cutoff
+	BatchCostModel bool
 
 	TerminateBatchMax  uint64
 	TerminateBatchMin  uint64
@@ -275,6 +300,19 @@ type SealingConfig struct {
 	AvailableBalanceBuffer types.FIL
 	// Don't send collateral with messages even if there is no available balance in the miner actor
 	DisableCollateralFallback bool
+
+	// Pull unpacked deals from venus-market into sectors without running `sectors deal`
+	AutoDealIngest bool
+	// how often to poll venus-market for unpacked deals
+	DealIngestInterval Duration
+	// maximum number of deals taken from venus-market per poll
+	DealIngestBatch int
+	// Assign deals to sectors with the packing planner, which minimises filler padding, groups
+	// deals with similar end epochs and rejects deals which can't be sealed before their start
+	// epoch, using Dealmaking.ExpectedSealDuration
+	PlanDealPacking bool
+	// Recovery policies of failure states, replacing their built-in retry logic
+	RecoveryPlaybooks []sealiface.RecoveryPlaybook
 	// Keep this many sectors in sealing pipeline, start CC if needed
 	// todo TargetSealingSectors uint64
 
@@ -304,6 +342,22 @@ type MinerFeeConfig struct {
 	MaxMarketBalanceAddFee types.FIL
 }
 
+// GetMinerFeeConfigFunc returns the current fee config, which can be changed at runtime
+type GetMinerFeeConfigFunc func() MinerFeeConfig
+
+// IntegrityAuditConfig configures the background checks of sealed replicas
+// against their CommR, see `venus-sealer sectors verify`
+type IntegrityAuditConfig struct {
+	// Enable sweeps all active sectors, the least recently checked first.
+	// Sectors queued with `sectors verify --background` are checked either way.
+	Enable bool
+	// Interval is the pause between two sector checks, it limits the reads from
+	// the storage paths
+	Interval Duration
+	// Rounds of window post challenges proven per sector check
+	Rounds int
+}
+
 type MinerAddressConfig struct {
 	PreCommitControl []string
 	CommitControl    []string
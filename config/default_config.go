@@ -32,6 +32,9 @@ func GetDefaultWorkerConfig() *StorageWorker {
 				Path: "worker.db",
 			},
 		},
+		Tasks: WorkerTasksConfig{
+			Limits: map[string]int64{},
+		},
 	}
 }
 func GetDefaultStorageConfig(network string) (*StorageMiner, error) {
@@ -56,7 +59,8 @@ func DefaultMainnetStorageMiner() *StorageMiner {
 			ListenAddress: "/ip4/127.0.0.1/tcp/38491/http",
 			Timeout:       Duration(30 * time.Second),
 		},
-		Sealing: defSealing,
+		Sealing:        defSealing,
+		IntegrityAudit: defIntegrityAudit,
 		Storage: sectorstorage.SealerConfig{
 			AllowAddPiece:   true,
 			AllowPreCommit1: true,
@@ -134,7 +138,8 @@ func DefaultForceNetStorageMiner() *StorageMiner {
 			ListenAddress: "/ip4/127.0.0.1/tcp/38491/http",
 			Timeout:       Duration(30 * time.Second),
 		},
-		Sealing: defSealing,
+		Sealing:        defSealing,
+		IntegrityAudit: defIntegrityAudit,
 		Storage: sectorstorage.SealerConfig{
 			AllowAddPiece:   true,
 			AllowPreCommit1: true,
@@ -213,7 +218,8 @@ func DefaultCalibrationStorageMiner() *StorageMiner {
 			ListenAddress: "/ip4/127.0.0.1/tcp/38491/http",
 			Timeout:       Duration(30 * time.Second),
 		},
-		Sealing: defSealing,
+		Sealing:        defSealing,
+		IntegrityAudit: defIntegrityAudit,
 		Storage: sectorstorage.SealerConfig{
 			AllowAddPiece:   true,
 			AllowPreCommit1: true,
@@ -323,6 +329,7 @@ func Default2kStorageMiner() *StorageMiner {
 		},
 		Sealing: defSealing,
 
+		IntegrityAudit: defIntegrityAudit,
 		Storage: sectorstorage.SealerConfig{
 			AllowAddPiece:   true,
 			AllowPreCommit1: true,
@@ -401,6 +408,12 @@ var defMarket = RegisterMarketConfig{
 	Token: "",
 }
 
+var defIntegrityAudit = IntegrityAuditConfig{
+	Enable:   false,
+	Interval: Duration(10 * time.Minute),
+	Rounds:   3,
+}
+
 var defSealing = SealingConfig{
 	MaxWaitDealsSectors:       2, // 64G with 32G sectors
 	MaxSealingSectors:         0,
@@ -422,6 +435,7 @@ var defSealing = SealingConfig{
 
 	BatchPreCommitAboveBaseFee: types.FIL(types.BigMul(types.PicoFil, types.NewInt(320))), // 0.32 nFIL
 	AggregateAboveBaseFee:      types.FIL(types.BigMul(types.PicoFil, types.NewInt(320))), // 0.32 nFIL
+	BatchCostModel:             false,
 
 	TerminateBatchMin:               1,
 	TerminateBatchMax:               100,
@@ -431,4 +445,10 @@ var defSealing = SealingConfig{
 	CollateralFromMinerBalance: false,
 	AvailableBalanceBuffer:     types.FIL(big.Zero()),
 	DisableCollateralFallback:  false,
+
+	AutoDealIngest:     false,
+	DealIngestInterval: Duration(time.Minute),
+	DealIngestBatch:    50,
+
+	PlanDealPacking: false,
 }
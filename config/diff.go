@@ -0,0 +1,123 @@
+package config
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+)
+
+// diffIgnored are keys which differ on every install
+var diffIgnored = map[string]bool{
+	"JWT.Secret": true,
+}
+
+// DiffEntry is a config value which differs from the default
+type DiffEntry struct {
+	Key     string
+	Value   string
+	Default string
+}
+
+// Networks are the networks with a default config, see GetDefaultStorageConfig
+var Networks = []string{"mainnet", "calibration", "2k", "force"}
+
+// GuessNetwork returns the first network whose default NetParams match the
+// config, falling back to mainnet. Mainnet and calibration can't be told apart.
+func GuessNetwork(cfg *StorageMiner) string {
+	for _, network := range Networks {
+		def, err := GetDefaultStorageConfig(network)
+		if err == nil && def.NetParams == cfg.NetParams {
+			return network
+		}
+	}
+	return "mainnet"
+}
+
+// Diff returns the values of cfg which differ from def sorted by key, values
+// of tokens, secrets and passwords are masked
+func Diff(cfg, def *StorageMiner) ([]DiffEntry, error) {
+	cm, err := configMap(cfg)
+	if err != nil {
+		return nil, err
+	}
+	dm, err := configMap(def)
+	if err != nil {
+		return nil, err
+	}
+
+	values, defaults := map[string]string{}, map[string]string{}
+	flattenConfig("", cm, values)
+	flattenConfig("", dm, defaults)
+
+	keys := map[string]struct{}{}
+	for k := range values {
+		keys[k] = struct{}{}
+	}
+	for k := range defaults {
+		keys[k] = struct{}{}
+	}
+
+	var out []DiffEntry
+	for k := range keys {
+		if diffIgnored[k] || values[k] == defaults[k] {
+			continue
+		}
+
+		e := DiffEntry{Key: k, Value: values[k], Default: defaults[k]}
+		if sensitiveKey(k) {
+			e.Value, e.Default = maskValue(e.Value), maskValue(e.Default)
+		}
+		out = append(out, e)
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Key < out[j].Key
+	})
+	return out, nil
+}
+
+func flattenConfig(prefix string, m map[string]interface{}, out map[string]string) {
+	for k, v := range m {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+
+		if section, ok := v.(map[string]interface{}); ok {
+			flattenConfig(key, section, out)
+			continue
+		}
+		out[key] = valueString(k, v)
+	}
+}
+
+func valueString(name string, v interface{}) string {
+	s, err := encodeTOML(map[string]interface{}{name: v})
+	if err == nil && strings.HasPrefix(s, name+" = ") && strings.Count(strings.TrimSpace(s), "\n") == 0 {
+		return strings.TrimSpace(strings.TrimPrefix(s, name+" = "))
+	}
+
+	// tables, eg. lists of affinity rules
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "<unprintable>"
+	}
+	return string(b)
+}
+
+func sensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, s := range []string{"token", "secret", "pass"} {
+		if strings.Contains(key, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func maskValue(v string) string {
+	if v == "" || v == `""` {
+		return v
+	}
+	return "<set>"
+}
@@ -0,0 +1,472 @@
+package config
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/BurntSushi/toml"
+	"golang.org/x/xerrors"
+
+	sectorstorage "github.com/filecoin-project/venus-sealer/sector-storage"
+)
+
+// ConfigApplier prepares applying the config to a running component. It
+// returns an error if the config can't be applied, and otherwise a function
+// doing the change, which is only called once all appliers accepted the config.
+type ConfigApplier func(cfg *StorageMiner) (commit func(), err error)
+
+// Live is the config of the running sealer. Sections read through Live take
+// effect without a restart, changes are validated, applied to the running
+// components and persisted to ConfigPath.
+type Live struct {
+	lk sync.RWMutex
+	// cfg is the last loaded config. applied is the config the running
+	// components use: sections needing a restart keep the values the sealer
+	// started with, so changes to them are reported until it restarts
+	cfg      *StorageMiner
+	applied  *StorageMiner
+	appliers []ConfigApplier
+
+	// persisting changes reads and rewrites the file
+	fileLk sync.Mutex
+}
+
+// liveSections are the config sections applied without a restart
+var liveSections = map[string]bool{
+	"Sealing":        true,
+	"Dealmaking":     true,
+	"Fees":           true,
+	"Addresses":      true,
+	"IntegrityAudit": true,
+	"Storage":        true, // only the scheduler and unseal options, see restartRequired
+}
+
+func NewLive(cfg *StorageMiner) *Live {
+	applied := *cfg
+	return &Live{cfg: cfg, applied: &applied}
+}
+
+// OnChange registers an applier called for every config change
+func (l *Live) OnChange(a ConfigApplier) {
+	l.lk.Lock()
+	defer l.lk.Unlock()
+
+	l.appliers = append(l.appliers, a)
+}
+
+// Read calls cb with the current config, cb must not keep or modify it
+func (l *Live) Read(cb func(cfg *StorageMiner)) {
+	l.lk.RLock()
+	defer l.lk.RUnlock()
+
+	cb(l.cfg)
+}
+
+// Get returns a copy of the current config
+func (l *Live) Get() (*StorageMiner, error) {
+	l.lk.RLock()
+	defer l.lk.RUnlock()
+
+	return copyConfig(l.cfg)
+}
+
+// GetKey returns the TOML encoding of the value of a dotted key, eg.
+// Sealing.MaxSealingSectors, or of the whole config for an empty key
+func (l *Live) GetKey(key string) (string, error) {
+	cfg, err := l.Get()
+	if err != nil {
+		return "", err
+	}
+
+	m, err := configMap(cfg)
+	if err != nil {
+		return "", err
+	}
+
+	if key == "" {
+		return encodeTOML(m)
+	}
+
+	if err := checkKey(key); err != nil {
+		return "", err
+	}
+
+	var cur interface{} = m
+	for _, part := range strings.Split(key, ".") {
+		cm, ok := cur.(map[string]interface{})
+		if !ok {
+			return "", xerrors.Errorf("%s is not a section", key)
+		}
+		cur, ok = cm[part]
+		if !ok {
+			return "", nil // not set
+		}
+	}
+
+	if section, ok := cur.(map[string]interface{}); ok {
+		return encodeTOML(section)
+	}
+
+	parts := strings.Split(key, ".")
+	s, err := encodeTOML(map[string]interface{}{parts[len(parts)-1]: cur})
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(strings.SplitN(s, "=", 2)[1]), nil
+}
+
+// Mutate applies the change to the running sealer and persists it, it returns
+// the changed sections which only take effect after a restart
+func (l *Live) Mutate(mutator func(cfg *StorageMiner) error) ([]string, error) {
+	l.fileLk.Lock()
+	defer l.fileLk.Unlock()
+
+	cur, err := l.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := mutator(cur); err != nil {
+		return nil, err
+	}
+
+	restart, err := l.apply(cur)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := l.persist(mutator); err != nil {
+		return nil, xerrors.Errorf("applied, but persisting config: %w", err)
+	}
+
+	return restart, nil
+}
+
+// Set sets a dotted key to a TOML value, strings don't need to be quoted
+func (l *Live) Set(key, value string) ([]string, error) {
+	if err := checkKey(key); err != nil {
+		return nil, err
+	}
+
+	return l.Mutate(func(cfg *StorageMiner) error {
+		return setKey(cfg, key, value)
+	})
+}
+
+// Reload reads the config file and applies it
+func (l *Live) Reload() ([]string, error) {
+	l.fileLk.Lock()
+	defer l.fileLk.Unlock()
+
+	cur, err := l.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, err := MinerFromFile(l.configPath(cur))
+	if err != nil {
+		return nil, xerrors.Errorf("reading config: %w", err)
+	}
+	cfg.DataDir = cur.DataDir
+	cfg.ConfigPath = cur.ConfigPath
+
+	return l.apply(cfg)
+}
+
+func (l *Live) apply(cfg *StorageMiner) ([]string, error) {
+	if err := validateLive(cfg); err != nil {
+		return nil, err
+	}
+
+	l.lk.Lock()
+	defer l.lk.Unlock()
+
+	var commits []func()
+	for _, a := range l.appliers {
+		commit, err := a(cfg)
+		if err != nil {
+			return nil, err
+		}
+		commits = append(commits, commit)
+	}
+
+	restart, err := restartRequired(l.applied, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	l.cfg = cfg
+	applyLive(l.applied, cfg)
+	for _, commit := range commits {
+		commit()
+	}
+
+	return restart, nil
+}
+
+func (l *Live) configPath(cfg *StorageMiner) string {
+	if cfg.ConfigPath != "" {
+		return cfg.ConfigPath
+	}
+	return FsConfig(cfg.DataDir)
+}
+
+// persist applies the mutation to the config file, to keep settings which
+// come from env vars out of it
+func (l *Live) persist(mutator func(cfg *StorageMiner) error) error {
+	l.lk.RLock()
+	path := l.configPath(l.cfg)
+	l.lk.RUnlock()
+
+	cfg, err := MinerFromFile(path)
+	if err != nil {
+		return err
+	}
+
+	if err := mutator(cfg); err != nil {
+		return err
+	}
+
+	buf := new(bytes.Buffer)
+	if err := toml.NewEncoder(buf).Encode(cfg); err != nil {
+		return err
+	}
+
+	return writeFileAtomic(path, buf.Bytes())
+}
+
+// writeFileAtomic replaces the file with a synced temp file, a crash never
+// leaves a truncated config behind
+func writeFileAtomic(path string, b []byte) error {
+	f, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp-")
+	if err != nil {
+		return xerrors.Errorf("creating temp config file: %w", err)
+	}
+	tmp := f.Name()
+	defer os.Remove(tmp) //nolint:errcheck
+
+	if _, err := f.Write(b); err != nil {
+		_ = f.Close()
+		return xerrors.Errorf("writing temp config file: %w", err)
+	}
+	if err := f.Chmod(0644); err != nil {
+		_ = f.Close()
+		return xerrors.Errorf("setting temp config file mode: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return xerrors.Errorf("syncing temp config file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return xerrors.Errorf("closing temp config file: %w", err)
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		return xerrors.Errorf("replacing config file: %w", err)
+	}
+	return nil
+}
+
+// validateLive checks the sections applied live, problems of other sections
+// are reported when the sealer starts
+func validateLive(cfg *StorageMiner) error {
+	var problems []Problem
+	for _, p := range Check(cfg) {
+		if liveSections[strings.SplitN(p.Key, ".", 2)[0]] {
+			problems = append(problems, p)
+		}
+	}
+	return FatalProblems(problems)
+}
+
+// applyLive copies the sections applied without a restart to the applied config
+func applyLive(applied, cfg *StorageMiner) {
+	av, nv := reflect.ValueOf(applied).Elem(), reflect.ValueOf(cfg).Elem()
+	for i := 0; i < av.NumField(); i++ {
+		name := av.Type().Field(i).Name
+		if liveSections[name] && name != "Storage" {
+			av.Field(i).Set(nv.Field(i))
+		}
+	}
+
+	applied.Storage = withLiveStorage(applied.Storage, cfg.Storage)
+}
+
+// withLiveStorage returns storage with the options applied live taken from from
+func withLiveStorage(storage, from sectorstorage.SealerConfig) sectorstorage.SealerConfig {
+	storage.Affinity = from.Affinity
+	storage.SectorLabels = from.SectorLabels
+	storage.UnsealedCache = from.UnsealedCache
+	storage.UnsealPriority = from.UnsealPriority
+	storage.VerifyUnsealedPieces = from.VerifyUnsealedPieces
+	return storage
+}
+
+// restartRequired returns the changed sections which aren't applied live
+func restartRequired(old, cfg *StorageMiner) ([]string, error) {
+	var out []string
+
+	ov, nv := reflect.ValueOf(old).Elem(), reflect.ValueOf(cfg).Elem()
+	for i := 0; i < ov.NumField(); i++ {
+		name := ov.Type().Field(i).Name
+		if name == "ConfigPath" || (liveSections[name] && name != "Storage") {
+			continue
+		}
+
+		of, nf := ov.Field(i).Interface(), nv.Field(i).Interface()
+		if name == "Storage" {
+			nf = withLiveStorage(cfg.Storage, old.Storage)
+		}
+
+		same, err := sameTOML(of, nf)
+		if err != nil {
+			return nil, xerrors.Errorf("comparing %s: %w", name, err)
+		}
+		if !same {
+			out = append(out, name)
+		}
+	}
+
+	return out, nil
+}
+
+func sameTOML(a, b interface{}) (bool, error) {
+	wrap := func(v interface{}) (string, error) {
+		return encodeTOML(map[string]interface{}{"v": v})
+	}
+
+	as, err := wrap(a)
+	if err != nil {
+		return false, err
+	}
+	bs, err := wrap(b)
+	if err != nil {
+		return false, err
+	}
+	return as == bs, nil
+}
+
+func copyConfig(cfg *StorageMiner) (*StorageMiner, error) {
+	buf := new(bytes.Buffer)
+	if err := toml.NewEncoder(buf).Encode(cfg); err != nil {
+		return nil, xerrors.Errorf("encoding config: %w", err)
+	}
+
+	out := new(StorageMiner)
+	if _, err := toml.Decode(buf.String(), out); err != nil {
+		return nil, xerrors.Errorf("decoding config: %w", err)
+	}
+	out.ConfigPath = cfg.ConfigPath
+
+	return out, nil
+}
+
+func configMap(cfg *StorageMiner) (map[string]interface{}, error) {
+	buf := new(bytes.Buffer)
+	if err := toml.NewEncoder(buf).Encode(cfg); err != nil {
+		return nil, xerrors.Errorf("encoding config: %w", err)
+	}
+
+	m := map[string]interface{}{}
+	if _, err := toml.Decode(buf.String(), &m); err != nil {
+		return nil, xerrors.Errorf("decoding config: %w", err)
+	}
+	return m, nil
+}
+
+func encodeTOML(v interface{}) (string, error) {
+	buf := new(bytes.Buffer)
+	if err := toml.NewEncoder(buf).Encode(v); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// checkKey checks that a dotted key names a config field, keys of maps aren't checked
+func checkKey(key string) error {
+	t := reflect.TypeOf(StorageMiner{})
+
+	for _, part := range strings.Split(key, ".") {
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+
+		switch t.Kind() {
+		case reflect.Map:
+			return nil
+		case reflect.Struct:
+		default:
+			return xerrors.Errorf("unknown config key %s: %s is not a section", key, part)
+		}
+
+		f, ok := fieldByKey(t, part)
+		if !ok {
+			return xerrors.Errorf("unknown config key %s", key)
+		}
+		t = f.Type
+	}
+
+	return nil
+}
+
+func fieldByKey(t reflect.Type, key string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := f.Name
+		if tag := strings.Split(f.Tag.Get("toml"), ",")[0]; tag != "" {
+			if tag == "-" {
+				continue
+			}
+			name = tag
+		}
+		if name == key {
+			return f, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+func setKey(cfg *StorageMiner, key, value string) error {
+	m, err := configMap(cfg)
+	if err != nil {
+		return err
+	}
+
+	// values which aren't valid TOML are taken as strings
+	var val interface{} = value
+	parsed := map[string]interface{}{}
+	if _, err := toml.Decode("v = "+value, &parsed); err == nil {
+		val = parsed["v"]
+	}
+
+	parts := strings.Split(key, ".")
+	cur := m
+	for _, part := range parts[:len(parts)-1] {
+		next, ok := cur[part].(map[string]interface{})
+		if !ok {
+			next = map[string]interface{}{}
+			cur[part] = next
+		}
+		cur = next
+	}
+	cur[parts[len(parts)-1]] = val
+
+	enc, err := encodeTOML(m)
+	if err != nil {
+		return xerrors.Errorf("encoding config: %w", err)
+	}
+
+	out := new(StorageMiner)
+	if _, err := toml.Decode(enc, out); err != nil {
+		return xerrors.Errorf("setting %s to %s: %w", key, value, err)
+	}
+	out.ConfigPath = cfg.ConfigPath
+
+	*cfg = *out
+	return nil
+}
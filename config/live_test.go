@@ -0,0 +1,142 @@
+package config
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/xerrors"
+)
+
+func testLive(t *testing.T) (*Live, string) {
+	cfg := DefaultMainnetStorageMiner()
+	cfg.ConfigPath = filepath.Join(t.TempDir(), "config.toml")
+
+	buf := new(bytes.Buffer)
+	require.NoError(t, toml.NewEncoder(buf).Encode(cfg))
+	require.NoError(t, ioutil.WriteFile(cfg.ConfigPath, buf.Bytes(), 0644))
+
+	return NewLive(cfg), cfg.ConfigPath
+}
+
+func TestLiveSet(t *testing.T) {
+	l, path := testLive(t)
+
+	var applied *StorageMiner
+	l.OnChange(func(cfg *StorageMiner) (func(), error) {
+		return func() {
+			applied = cfg
+		}, nil
+	})
+
+	restart, err := l.Set("Sealing.MaxSealingSectors", "12")
+	require.NoError(t, err)
+	require.Empty(t, restart)
+	require.Equal(t, uint64(12), applied.Sealing.MaxSealingSectors)
+
+	_, err = l.Set("Sealing.WaitDealsDelay", "2h0m0s")
+	require.NoError(t, err)
+
+	val, err := l.GetKey("Sealing.WaitDealsDelay")
+	require.NoError(t, err)
+	require.Equal(t, `"2h0m0s"`, val)
+
+	// persisted
+	onDisk, err := MinerFromFile(path)
+	require.NoError(t, err)
+	require.Equal(t, uint64(12), onDisk.Sealing.MaxSealingSectors)
+	require.Equal(t, Duration(2*time.Hour), onDisk.Sealing.WaitDealsDelay)
+
+	// replaced through a temp file which doesn't stay around
+	files, err := ioutil.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	restart, err = l.Set("API.ListenAddress", "/ip4/127.0.0.1/tcp/2346")
+	require.NoError(t, err)
+	require.Equal(t, []string{"API"}, restart)
+}
+
+func TestLiveSetInvalid(t *testing.T) {
+	l, _ := testLive(t)
+
+	_, err := l.Set("Sealing.NoSuchKey", "1")
+	require.Error(t, err)
+
+	_, err = l.Set("Sealing.MaxSealingSectors", "many")
+	require.Error(t, err)
+
+	_, err = l.Set("Sealing.MinCommitBatch", "10000")
+	require.Error(t, err)
+
+	l.OnChange(func(cfg *StorageMiner) (func(), error) {
+		return nil, xerrors.New("rejected")
+	})
+	_, err = l.Set("Sealing.MaxSealingSectors", "3")
+	require.Error(t, err)
+
+	cfg, err := l.Get()
+	require.NoError(t, err)
+	require.Equal(t, DefaultMainnetStorageMiner().Sealing.MaxSealingSectors, cfg.Sealing.MaxSealingSectors)
+}
+
+func TestLiveReload(t *testing.T) {
+	l, path := testLive(t)
+
+	onDisk, err := MinerFromFile(path)
+	require.NoError(t, err)
+	onDisk.Dealmaking.ConsiderOfflineStorageDeals = !onDisk.Dealmaking.ConsiderOfflineStorageDeals
+	buf := new(bytes.Buffer)
+	require.NoError(t, toml.NewEncoder(buf).Encode(onDisk))
+	require.NoError(t, ioutil.WriteFile(path, buf.Bytes(), 0644))
+
+	restart, err := l.Reload()
+	require.NoError(t, err)
+	require.Empty(t, restart)
+
+	l.Read(func(cfg *StorageMiner) {
+		require.Equal(t, onDisk.Dealmaking.ConsiderOfflineStorageDeals, cfg.Dealmaking.ConsiderOfflineStorageDeals)
+	})
+
+	// sections needing a restart are reported on every reload until the sealer restarts
+	onDisk.API.ListenAddress = "/ip4/127.0.0.1/tcp/2346"
+	buf.Reset()
+	require.NoError(t, toml.NewEncoder(buf).Encode(onDisk))
+	require.NoError(t, ioutil.WriteFile(path, buf.Bytes(), 0644))
+
+	for i := 0; i < 2; i++ {
+		restart, err = l.Reload()
+		require.NoError(t, err)
+		require.Equal(t, []string{"API"}, restart)
+	}
+}
+
+func TestLiveSetStorage(t *testing.T) {
+	l, _ := testLive(t)
+
+	// applied to the running manager
+	restart, err := l.Set("Storage.UnsealPriority", "5")
+	require.NoError(t, err)
+	require.Empty(t, restart)
+
+	restart, err = l.Set("Storage.VerifyUnsealedPieces", "true")
+	require.NoError(t, err)
+	require.Empty(t, restart)
+
+	restart, err = l.Set("Storage.UnsealedCache.Budget", "2TiB")
+	require.NoError(t, err)
+	require.Empty(t, restart)
+
+	restart, err = l.Set("Storage.ParallelFetchLimit", "3")
+	require.NoError(t, err)
+	require.Equal(t, []string{"Storage"}, restart)
+
+	// still needs a restart after later changes
+	restart, err = l.Set("Storage.UnsealPriority", "6")
+	require.NoError(t, err)
+	require.Equal(t, []string{"Storage"}, restart)
+}
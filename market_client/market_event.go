@@ -128,6 +128,20 @@ func (e *MarketEvent) processSectorUnsealed(ctx context.Context, reqId uuid.UUID
 		return
 	}
 
+	if e.storageMgr.VerifyingUnsealedPieces() {
+		pi, ok := sectorInfo.PieceAt(abi.PaddedPieceSize(req.Offset))
+		if !ok || pi.Size != req.Size {
+			e.error(ctx, reqId, xerrors.Errorf("no piece of size %d at offset %d in sector %d to verify", req.Size, req.Offset, req.Sector.ID.Number))
+			return
+		}
+
+		err = e.storageMgr.VerifyUnsealedPiece(ctx, req.Sector, storiface.UnpaddedByteIndex(abi.PaddedPieceSize(req.Offset).Unpadded()), req.Size.Unpadded(), sectorInfo.TicketValue, sectorInfo.CommD, pi.PieceCID)
+		if err != nil {
+			e.error(ctx, reqId, xerrors.Errorf("verifying unsealed piece: %w", err))
+			return
+		}
+	}
+
 	if err := e.index.StorageLock(ctx, req.Sector.ID, storiface.FTUnsealed, storiface.FTNone); err != nil {
 		e.error(ctx, reqId, err)
 		return
@@ -154,6 +168,7 @@ func (e *MarketEvent) processSectorUnsealed(ctx context.Context, reqId uuid.UUID
 		e.error(ctx, reqId, err)
 		return
 	}
+	e.storageMgr.RecordUnsealedAccess(req.Sector)
 	e.val(ctx, reqId, nil)
 }
 
@@ -23,6 +23,30 @@ func (d MysqlRepo) LogRepo() repo.LogRepo {
 	panic("implement me")
 }
 
+func (d MysqlRepo) BatchRepo() repo.BatchRepo {
+	panic("implement me")
+}
+
+func (d MysqlRepo) UnreportedDealRepo() repo.UnreportedDealRepo {
+	panic("implement me")
+}
+
+func (d MysqlRepo) SectorHostRepo() repo.SectorHostRepo {
+	panic("implement me")
+}
+
+func (d MysqlRepo) TokenRepo() repo.TokenRepo {
+	panic("implement me")
+}
+
+func (d MysqlRepo) AuditRepo() repo.AuditRepo {
+	panic("implement me")
+}
+
+func (d MysqlRepo) IntegrityRepo() repo.IntegrityRepo {
+	panic("implement me")
+}
+
 func (d MysqlRepo) WorkerCallRepo() repo.WorkerCallRepo {
 	panic("implement me")
 }
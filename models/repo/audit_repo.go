@@ -0,0 +1,15 @@
+package repo
+
+import (
+	"time"
+
+	"github.com/filecoin-project/venus-sealer/types"
+)
+
+type AuditRepo interface {
+	Append(entry *types.AuditEntry) error
+	List(filter types.AuditFilter) ([]*types.AuditEntry, error)
+	// Prune removes the entries recorded before the time, and all but the
+	// keep newest entries when keep is positive, returning the number removed
+	Prune(before time.Time, keep int) (int64, error)
+}
@@ -0,0 +1,12 @@
+package repo
+
+import (
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/venus-sealer/types"
+)
+
+type BatchRepo interface {
+	Save(batcher string, entry *types.BatchEntry) error
+	Delete(batcher string, sectors []abi.SectorNumber) error
+	List(batcher string) ([]*types.BatchEntry, error)
+}
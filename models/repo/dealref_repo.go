@@ -1,6 +1,10 @@
 package repo
 
 import (
+	"github.com/ipfs/go-cid"
+
+	"github.com/filecoin-project/go-state-types/abi"
+
 	"github.com/filecoin-project/specs-actors/v2/actors/builtin/market"
 	"github.com/filecoin-project/venus-sealer/types"
 )
@@ -10,4 +14,7 @@ type DealRefRepo interface {
 	Save(dealId uint64, ref types.SealedRef, dealProposal *market.DealProposal) error
 	Has(dealId uint64) (bool, error)
 	List() (map[uint64][]types.SealedRef, error)
+	GetByPiece(pieceCid cid.Cid) (map[uint64][]types.SealedRef, error)
+	ListWithoutPiece() (map[uint64][]types.SealedRef, error)
+	SetPiece(dealId uint64, sector abi.SectorNumber, pieceCid cid.Cid) error
 }
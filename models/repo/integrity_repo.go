@@ -0,0 +1,12 @@
+package repo
+
+import (
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/venus-sealer/types"
+)
+
+type IntegrityRepo interface {
+	Save(result *types.SectorIntegrity) error
+	Get(sector abi.SectorNumber) (*types.SectorIntegrity, error)
+	List() ([]*types.SectorIntegrity, error)
+}
@@ -12,6 +12,12 @@ type Repo interface {
 	SectorInfoRepo() SectorInfoRepo
 	DealRefRepo() DealRefRepo
 	LogRepo() LogRepo
+	BatchRepo() BatchRepo
+	UnreportedDealRepo() UnreportedDealRepo
+	SectorHostRepo() SectorHostRepo
+	TokenRepo() TokenRepo
+	AuditRepo() AuditRepo
+	IntegrityRepo() IntegrityRepo
 	DbClose() error
 	AutoMigrate() error
 }
@@ -0,0 +1,12 @@
+package repo
+
+import (
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/venus-sealer/types"
+)
+
+type SectorHostRepo interface {
+	Save(host *types.SectorHost) error
+	Delete(sector abi.SectorID) error
+	List() ([]*types.SectorHost, error)
+}
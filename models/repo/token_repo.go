@@ -0,0 +1,14 @@
+package repo
+
+import (
+	"time"
+
+	"github.com/filecoin-project/venus-sealer/types"
+)
+
+type TokenRepo interface {
+	Revoke(token *types.RevokedToken) error
+	List() ([]*types.RevokedToken, error)
+	// DeleteExpired drops revocations of tokens expired before the time
+	DeleteExpired(before time.Time) error
+}
@@ -0,0 +1,12 @@
+package repo
+
+import (
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/venus-sealer/types"
+)
+
+type UnreportedDealRepo interface {
+	Save(deal *types.UnreportedDeal) error
+	Delete(deal abi.DealID) error
+	List() ([]*types.UnreportedDeal, error)
+}
@@ -0,0 +1,116 @@
+package sqlite
+
+import (
+	"time"
+
+	"github.com/filecoin-project/venus-sealer/models/repo"
+	"github.com/filecoin-project/venus-sealer/types"
+	"gorm.io/gorm"
+)
+
+type auditLog struct {
+	Id       int64  `gorm:"column:id;types:integer;primary_key;autoIncrement;" json:"id"`
+	Time     int64  `gorm:"column:time;type:bigint;index:audit_log_time" json:"time"` // unix nano
+	Caller   string `gorm:"column:caller;type:varchar(128);index:audit_log_caller" json:"caller"`
+	Remote   string `gorm:"column:remote;type:varchar(256);" json:"remote"`
+	Method   string `gorm:"column:method;type:varchar(128);index:audit_log_method" json:"method"`
+	Params   string `gorm:"column:params;type:text;" json:"params"`
+	Error    string `gorm:"column:error;type:text;" json:"error"`
+	Duration int64  `gorm:"column:duration;type:bigint;" json:"duration"` // nano seconds
+}
+
+func (a *auditLog) TableName() string {
+	return "audit_logs"
+}
+
+func (a *auditLog) AuditEntry() *types.AuditEntry {
+	return &types.AuditEntry{
+		ID:       a.Id,
+		Time:     time.Unix(0, a.Time),
+		Caller:   a.Caller,
+		Remote:   a.Remote,
+		Method:   a.Method,
+		Params:   a.Params,
+		Error:    a.Error,
+		Duration: time.Duration(a.Duration),
+	}
+}
+
+var _ repo.AuditRepo = (*auditRepo)(nil)
+
+type auditRepo struct {
+	*gorm.DB
+}
+
+func newAuditRepo(db *gorm.DB) *auditRepo {
+	return &auditRepo{DB: db}
+}
+
+func (a *auditRepo) Append(entry *types.AuditEntry) error {
+	return a.DB.Create(&auditLog{
+		Time:     entry.Time.UnixNano(),
+		Caller:   entry.Caller,
+		Remote:   entry.Remote,
+		Method:   entry.Method,
+		Params:   entry.Params,
+		Error:    entry.Error,
+		Duration: int64(entry.Duration),
+	}).Error
+}
+
+func (a *auditRepo) List(filter types.AuditFilter) ([]*types.AuditEntry, error) {
+	query := a.DB.Table("audit_logs")
+	if filter.Method != "" {
+		query = query.Where("method=?", filter.Method)
+	}
+	if filter.Caller != "" {
+		query = query.Where("caller=?", filter.Caller)
+	}
+	if !filter.Since.IsZero() {
+		query = query.Where("time>=?", filter.Since.UnixNano())
+	}
+	if !filter.Until.IsZero() {
+		query = query.Where("time<?", filter.Until.UnixNano())
+	}
+	if filter.FailedOnly {
+		query = query.Where("error!=''")
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+
+	var logs []*auditLog
+	if err := query.Order("id desc").Find(&logs).Error; err != nil {
+		return nil, err
+	}
+
+	out := make([]*types.AuditEntry, 0, len(logs))
+	for _, l := range logs {
+		out = append(out, l.AuditEntry())
+	}
+	return out, nil
+}
+
+func (a *auditRepo) Prune(before time.Time, keep int) (int64, error) {
+	res := a.DB.Where("time<?", before.UnixNano()).Delete(&auditLog{})
+	if res.Error != nil {
+		return 0, res.Error
+	}
+	removed := res.RowsAffected
+
+	if keep > 0 {
+		var ids []int64
+		if err := a.DB.Table("audit_logs").Order("id desc").Offset(keep).Limit(1).Pluck("id", &ids).Error; err != nil {
+			return removed, err
+		}
+		if len(ids) > 0 {
+			res := a.DB.Where("id<=?", ids[0]).Delete(&auditLog{})
+			if res.Error != nil {
+				return removed, res.Error
+			}
+			removed += res.RowsAffected
+		}
+	}
+
+	return removed, nil
+}
@@ -0,0 +1,109 @@
+package sqlite
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/filecoin-project/venus-sealer/types"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupAudit(suffix string, t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open("./audit_"+suffix), &gorm.Config{})
+	if err != nil {
+		t.Error(err)
+	}
+	err = db.AutoMigrate(&auditLog{})
+	if err != nil {
+		t.Error(err)
+	}
+	return db
+}
+
+func cleanAudit(suffix string, t *testing.T) {
+	os.Remove("./audit_" + suffix)
+}
+
+func Test_auditRepo(t *testing.T) {
+	db := setupAudit("logs", t)
+	defer cleanAudit("logs", t)
+	aRepo := newAuditRepo(db)
+
+	now := time.Now()
+	entries := []*types.AuditEntry{
+		{Time: now.Add(-2 * time.Hour), Caller: "id:a", Method: "SectorRemove", Params: "[1]"},
+		{Time: now.Add(-time.Hour), Caller: "id:b", Method: "SectorRemove", Params: "[2]", Error: "sector not found"},
+		{Time: now, Caller: "id:a", Method: "StorageAttach", Params: "[]", Duration: time.Second},
+	}
+	for _, e := range entries {
+		if err := aRepo.Append(e); err != nil {
+			t.Error(err)
+		}
+	}
+
+	list, err := aRepo.List(types.AuditFilter{})
+	if err != nil {
+		t.Error(err)
+	}
+	if len(list) != 3 {
+		t.Fatalf("expect %d entries, but got %d", 3, len(list))
+	}
+	if list[0].Method != "StorageAttach" || list[0].Duration != time.Second {
+		t.Errorf("expect newest entry first, but got %s", list[0].Method)
+	}
+
+	checkCount := func(filter types.AuditFilter, expect int) {
+		list, err := aRepo.List(filter)
+		if err != nil {
+			t.Error(err)
+		}
+		if len(list) != expect {
+			t.Errorf("expect %d entries for %+v, but got %d", expect, filter, len(list))
+		}
+	}
+	checkCount(types.AuditFilter{Method: "SectorRemove"}, 2)
+	checkCount(types.AuditFilter{Caller: "id:a"}, 2)
+	checkCount(types.AuditFilter{FailedOnly: true}, 1)
+	checkCount(types.AuditFilter{Since: now.Add(-90 * time.Minute)}, 2)
+	checkCount(types.AuditFilter{Until: now.Add(-90 * time.Minute)}, 1)
+	checkCount(types.AuditFilter{Limit: 1}, 1)
+}
+
+func Test_auditRepoPrune(t *testing.T) {
+	db := setupAudit("prune", t)
+	defer cleanAudit("prune", t)
+	aRepo := newAuditRepo(db)
+
+	now := time.Now()
+	for i := 0; i < 5; i++ {
+		if err := aRepo.Append(&types.AuditEntry{Time: now.Add(time.Duration(i-4) * time.Hour), Method: "SectorRemove"}); err != nil {
+			t.Error(err)
+		}
+	}
+
+	removed, err := aRepo.Prune(now.Add(-150*time.Minute), 0)
+	if err != nil {
+		t.Error(err)
+	}
+	if removed != 2 {
+		t.Errorf("expect %d entries removed by age, but got %d", 2, removed)
+	}
+
+	removed, err = aRepo.Prune(now.Add(-24*time.Hour), 2)
+	if err != nil {
+		t.Error(err)
+	}
+	if removed != 1 {
+		t.Errorf("expect %d entries removed by count, but got %d", 1, removed)
+	}
+
+	list, err := aRepo.List(types.AuditFilter{})
+	if err != nil {
+		t.Error(err)
+	}
+	if len(list) != 2 || !list[0].Time.Equal(time.Unix(0, now.UnixNano())) {
+		t.Errorf("expect the %d newest entries kept, but got %d", 2, len(list))
+	}
+}
@@ -0,0 +1,85 @@
+package sqlite
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/venus-sealer/models/repo"
+	"github.com/filecoin-project/venus-sealer/types"
+	"gorm.io/gorm"
+)
+
+type batchEntry struct {
+	Id           string `gorm:"column:id;type:varchar(64);primary_key;" json:"id"` // batcher/sector_number
+	Batcher      string `gorm:"column:batcher;type:varchar(32);index:batch_entry_batcher" json:"batcher"`
+	SectorNumber uint64 `gorm:"column:sector_number;type:unsigned bigint;" json:"sector_number"`
+	Cutoff       int64  `gorm:"column:cutoff;type:bigint;" json:"cutoff"` // unix nano, 0 without cutoff
+	Data         []byte `gorm:"column:data;type:blob;" json:"data"`
+}
+
+func (b *batchEntry) TableName() string {
+	return "batch_entries"
+}
+
+func batchEntryId(batcher string, sn abi.SectorNumber) string {
+	return fmt.Sprintf("%s/%d", batcher, sn)
+}
+
+var _ repo.BatchRepo = (*batchRepo)(nil)
+
+type batchRepo struct {
+	*gorm.DB
+}
+
+func newBatchRepo(db *gorm.DB) *batchRepo {
+	return &batchRepo{DB: db}
+}
+
+func (b *batchRepo) Save(batcher string, entry *types.BatchEntry) error {
+	var cutoff int64
+	if !entry.Cutoff.IsZero() {
+		cutoff = entry.Cutoff.UnixNano()
+	}
+
+	return b.DB.Save(&batchEntry{
+		Id:           batchEntryId(batcher, entry.SectorNumber),
+		Batcher:      batcher,
+		SectorNumber: uint64(entry.SectorNumber),
+		Cutoff:       cutoff,
+		Data:         entry.Data,
+	}).Error
+}
+
+func (b *batchRepo) Delete(batcher string, sectors []abi.SectorNumber) error {
+	if len(sectors) == 0 {
+		return nil
+	}
+
+	ids := make([]string, 0, len(sectors))
+	for _, sn := range sectors {
+		ids = append(ids, batchEntryId(batcher, sn))
+	}
+
+	return b.DB.Delete(&batchEntry{}, "id in ?", ids).Error
+}
+
+func (b *batchRepo) List(batcher string) ([]*types.BatchEntry, error) {
+	var entries []*batchEntry
+	if err := b.DB.Find(&entries, "batcher=?", batcher).Error; err != nil {
+		return nil, err
+	}
+
+	out := make([]*types.BatchEntry, 0, len(entries))
+	for _, e := range entries {
+		entry := &types.BatchEntry{
+			SectorNumber: abi.SectorNumber(e.SectorNumber),
+			Data:         e.Data,
+		}
+		if e.Cutoff != 0 {
+			entry.Cutoff = time.Unix(0, e.Cutoff)
+		}
+		out = append(out, entry)
+	}
+	return out, nil
+}
@@ -0,0 +1,89 @@
+package sqlite
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/venus-sealer/types"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupBatch(suffix string, t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open("./batch_"+suffix), &gorm.Config{})
+	if err != nil {
+		t.Error(err)
+	}
+	err = db.AutoMigrate(&batchEntry{})
+	if err != nil {
+		t.Error(err)
+	}
+	return db
+}
+
+func cleanBatch(suffix string, t *testing.T) {
+	os.Remove("./batch_" + suffix)
+}
+
+func Test_batchRepo(t *testing.T) {
+	db := setupBatch("entries", t)
+	defer cleanBatch("entries", t)
+	bRepo := newBatchRepo(db)
+
+	cutoff := time.Now().Add(time.Hour)
+	for _, sn := range []abi.SectorNumber{1, 2} {
+		err := bRepo.Save(types.BatcherCommit, &types.BatchEntry{SectorNumber: sn, Cutoff: cutoff, Data: []byte{byte(sn)}})
+		if err != nil {
+			t.Error(err)
+		}
+	}
+	err := bRepo.Save(types.BatcherTerminate, &types.BatchEntry{SectorNumber: 1, Data: []byte{3}})
+	if err != nil {
+		t.Error(err)
+	}
+
+	// saving again replaces the entry
+	err = bRepo.Save(types.BatcherCommit, &types.BatchEntry{SectorNumber: 2, Cutoff: cutoff, Data: []byte{4}})
+	if err != nil {
+		t.Error(err)
+	}
+
+	entries, err := bRepo.List(types.BatcherCommit)
+	if err != nil {
+		t.Error(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expect %d commit entries, but got %d", 2, len(entries))
+	}
+	for _, e := range entries {
+		if !e.Cutoff.Equal(cutoff) {
+			t.Errorf("expect cutoff %s, but got %s", cutoff, e.Cutoff)
+		}
+		if e.SectorNumber == 2 && e.Data[0] != 4 {
+			t.Errorf("expect updated data for sector 2, but got %v", e.Data)
+		}
+	}
+
+	err = bRepo.Delete(types.BatcherCommit, []abi.SectorNumber{1, 2})
+	if err != nil {
+		t.Error(err)
+	}
+
+	entries, err = bRepo.List(types.BatcherCommit)
+	if err != nil {
+		t.Error(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("expect no commit entries, but got %d", len(entries))
+	}
+
+	entries, err = bRepo.List(types.BatcherTerminate)
+	if err != nil {
+		t.Error(err)
+	}
+	if len(entries) != 1 || !entries[0].Cutoff.IsZero() {
+		t.Errorf("expect one terminate entry without cutoff, but got %v", entries)
+	}
+}
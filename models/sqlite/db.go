@@ -21,6 +21,30 @@ func (d SqlLiteRepo) LogRepo() repo.LogRepo {
 	return newLogRepo(d.GetDb())
 }
 
+func (d SqlLiteRepo) BatchRepo() repo.BatchRepo {
+	return newBatchRepo(d.GetDb())
+}
+
+func (d SqlLiteRepo) UnreportedDealRepo() repo.UnreportedDealRepo {
+	return newUnreportedDealRepo(d.GetDb())
+}
+
+func (d SqlLiteRepo) SectorHostRepo() repo.SectorHostRepo {
+	return newSectorHostRepo(d.GetDb())
+}
+
+func (d SqlLiteRepo) TokenRepo() repo.TokenRepo {
+	return newTokenRepo(d.GetDb())
+}
+
+func (d SqlLiteRepo) AuditRepo() repo.AuditRepo {
+	return newAuditRepo(d.GetDb())
+}
+
+func (d SqlLiteRepo) IntegrityRepo() repo.IntegrityRepo {
+	return newIntegrityRepo(d.GetDb())
+}
+
 func (d SqlLiteRepo) WorkerCallRepo() repo.WorkerCallRepo {
 	return newWorkerCallRepo(d.GetDb())
 }
@@ -68,6 +92,36 @@ func (d SqlLiteRepo) AutoMigrate() error {
 		return err
 	}
 
+	err = d.GetDb().AutoMigrate(&batchEntry{})
+	if err != nil {
+		return err
+	}
+
+	err = d.GetDb().AutoMigrate(&unreportedDeal{})
+	if err != nil {
+		return err
+	}
+
+	err = d.GetDb().AutoMigrate(&sectorHost{})
+	if err != nil {
+		return err
+	}
+
+	err = d.GetDb().AutoMigrate(&revokedToken{})
+	if err != nil {
+		return err
+	}
+
+	err = d.GetDb().AutoMigrate(&auditLog{})
+	if err != nil {
+		return err
+	}
+
+	err = d.GetDb().AutoMigrate(&sectorIntegrity{})
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
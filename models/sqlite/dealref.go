@@ -6,6 +6,7 @@ import (
 	"github.com/filecoin-project/venus-sealer/models/repo"
 	"github.com/filecoin-project/venus-sealer/types"
 	"github.com/google/uuid"
+	"github.com/ipfs/go-cid"
 	"gorm.io/gorm"
 )
 
@@ -15,6 +16,7 @@ type dealRef struct {
 	SectorId  uint64 `gorm:"column:sector_id;type:unsigned bigint;" json:"sector_id"`
 	PadOffset uint64 `gorm:"column:offset_pad;type:unsigned bigint;" json:"offset_pad"`
 	UnPadSize uint64 `gorm:"column:size_unpad;type:unsigned bigint;" json:"size_unpad"`
+	PieceCid  string `gorm:"column:piece_cid;type:varchar(256);index;" json:"piece_cid"`
 }
 
 func (dealRef *dealRef) TableName() string {
@@ -50,15 +52,61 @@ func (d *dealRefRepo) Get(dealId uint64) (types.SealedRefs, error) {
 }
 
 func (d *dealRefRepo) Save(dealId uint64, ref types.SealedRef, dealProposal *market.DealProposal) error {
+	var pieceCid string
+	if dealProposal != nil {
+		pieceCid = dealProposal.PieceCID.String()
+	}
 	return d.DB.Save(&dealRef{
 		Id:        uuid.New().String(),
 		DealId:    dealId,
 		SectorId:  uint64(ref.SectorID),
 		PadOffset: uint64(ref.Offset),
 		UnPadSize: uint64(ref.Size),
+		PieceCid:  pieceCid,
 	}).Error
 }
 
+// GetByPiece returns the refs of the deals for the piece, refs saved before the
+// piece cid was recorded are only found once SetPiece backfilled them
+func (d *dealRefRepo) GetByPiece(pieceCid cid.Cid) (map[uint64][]types.SealedRef, error) {
+	var dealRefs []*dealRef
+	if err := d.DB.Find(&dealRefs, "piece_cid=?", pieceCid.String()).Error; err != nil {
+		return nil, err
+	}
+
+	results := make(map[uint64][]types.SealedRef)
+	for _, ref := range dealRefs {
+		results[ref.DealId] = append(results[ref.DealId], types.SealedRef{
+			SectorID: abi.SectorNumber(ref.SectorId),
+			Offset:   abi.PaddedPieceSize(ref.PadOffset),
+			Size:     abi.UnpaddedPieceSize(ref.UnPadSize),
+		})
+	}
+	return results, nil
+}
+
+// ListWithoutPiece returns the refs saved before the piece cid was recorded with them
+func (d *dealRefRepo) ListWithoutPiece() (map[uint64][]types.SealedRef, error) {
+	var dealRefs []*dealRef
+	if err := d.DB.Find(&dealRefs, "piece_cid=? or piece_cid is null", "").Error; err != nil {
+		return nil, err
+	}
+
+	results := make(map[uint64][]types.SealedRef)
+	for _, ref := range dealRefs {
+		results[ref.DealId] = append(results[ref.DealId], types.SealedRef{
+			SectorID: abi.SectorNumber(ref.SectorId),
+			Offset:   abi.PaddedPieceSize(ref.PadOffset),
+			Size:     abi.UnpaddedPieceSize(ref.UnPadSize),
+		})
+	}
+	return results, nil
+}
+
+func (d *dealRefRepo) SetPiece(dealId uint64, sector abi.SectorNumber, pieceCid cid.Cid) error {
+	return d.DB.Model(&dealRef{}).Where("deal_id=? and sector_id=?", dealId, uint64(sector)).Update("piece_cid", pieceCid.String()).Error
+}
+
 func (d *dealRefRepo) Has(dealId uint64) (bool, error) {
 	var count int64
 	err := d.DB.Table("deal_refs").Where("deal_id=?", dealId).Count(&count).Error
@@ -1,8 +1,10 @@
 package sqlite
 
 import (
+	"github.com/filecoin-project/specs-actors/v2/actors/builtin/market"
 	"github.com/filecoin-project/venus-sealer/types"
 	"github.com/google/uuid"
+	"github.com/ipfs/go-cid"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 	"os"
@@ -192,3 +194,93 @@ func Test_dealRefRepo_Save(t *testing.T) {
 		t.Errorf("expect sector Size %d, but got %d", 222, refs[1][0].Size)
 	}
 }
+
+func Test_dealRefRepo_GetByPiece(t *testing.T) {
+	db := setupDealRef("piece_deal", t)
+	defer cleanDealRef("piece_deal", t)
+	dRepo := newDealRefRepo(db)
+
+	pieceCid, err := cid.Parse("baga6ea4seaqao7s73y24kcutaosvacpdjgfe5pw76ooefnyqw4ynr3d2y6x2mpq")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = dRepo.Save(1, types.SealedRef{
+		SectorID: 12,
+		Offset:   0,
+		Size:     1016,
+	}, &market.DealProposal{PieceCID: pieceCid})
+	if err != nil {
+		t.Error(err)
+	}
+	err = dRepo.Save(2, types.SealedRef{
+		SectorID: 13,
+		Offset:   0,
+		Size:     1016,
+	}, nil)
+	if err != nil {
+		t.Error(err)
+	}
+
+	refs, err := dRepo.GetByPiece(pieceCid)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if len(refs) != 1 || len(refs[1]) != 1 {
+		t.Fatalf("expect a ref of deal %d, but got %v", 1, refs)
+	}
+
+	if refs[1][0].SectorID != 12 {
+		t.Errorf("expect sector id %d, but got %d", 12, refs[1][0].SectorID)
+	}
+}
+
+func Test_dealRefRepo_SetPiece(t *testing.T) {
+	db := setupDealRef("set_piece", t)
+	defer cleanDealRef("set_piece", t)
+	dRepo := newDealRefRepo(db)
+
+	pieceCid, err := cid.Parse("baga6ea4seaqao7s73y24kcutaosvacpdjgfe5pw76ooefnyqw4ynr3d2y6x2mpq")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = dRepo.Save(1, types.SealedRef{SectorID: 12, Offset: 0, Size: 1016}, &market.DealProposal{PieceCID: pieceCid})
+	if err != nil {
+		t.Error(err)
+	}
+	err = dRepo.Save(2, types.SealedRef{SectorID: 13, Offset: 0, Size: 1016}, nil)
+	if err != nil {
+		t.Error(err)
+	}
+
+	refs, err := dRepo.ListWithoutPiece()
+	if err != nil {
+		t.Error(err)
+	}
+	if len(refs) != 1 || len(refs[2]) != 1 || refs[2][0].SectorID != 13 {
+		t.Fatalf("expect the ref of deal %d without piece cid, but got %v", 2, refs)
+	}
+
+	err = dRepo.SetPiece(2, 13, pieceCid)
+	if err != nil {
+		t.Error(err)
+	}
+
+	refs, err = dRepo.ListWithoutPiece()
+	if err != nil {
+		t.Error(err)
+	}
+	if len(refs) != 0 {
+		t.Errorf("expect no refs without piece cid, but got %v", refs)
+	}
+
+	refs, err = dRepo.GetByPiece(pieceCid)
+	if err != nil {
+		t.Error(err)
+	}
+	if len(refs) != 2 {
+		t.Errorf("expect refs of %d deals, but got %v", 2, refs)
+	}
+}
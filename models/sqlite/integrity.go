@@ -0,0 +1,91 @@
+package sqlite
+
+import (
+	"time"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/venus-sealer/models/repo"
+	"github.com/filecoin-project/venus-sealer/types"
+	"github.com/ipfs/go-cid"
+	"gorm.io/gorm"
+)
+
+type sectorIntegrity struct {
+	SectorNumber uint64 `gorm:"column:sector_number;type:unsigned bigint;primary_key;" json:"sector_number"`
+	CommR        string `gorm:"column:comm_r;type:varchar(256);" json:"comm_r"`
+	Checked      int64  `gorm:"column:checked;type:bigint;index:sector_integrity_checked" json:"checked"` // unix seconds
+	Error        string `gorm:"column:error;type:text;" json:"error"`
+}
+
+func (s *sectorIntegrity) TableName() string {
+	return "sector_integrity"
+}
+
+func (s *sectorIntegrity) toResult() (*types.SectorIntegrity, error) {
+	res := &types.SectorIntegrity{
+		SectorNumber: abi.SectorNumber(s.SectorNumber),
+		Checked:      time.Unix(s.Checked, 0),
+		Error:        s.Error,
+	}
+	if s.CommR != "" {
+		c, err := cid.Decode(s.CommR)
+		if err != nil {
+			return nil, err
+		}
+		res.CommR = c
+	}
+	return res, nil
+}
+
+var _ repo.IntegrityRepo = (*integrityRepo)(nil)
+
+type integrityRepo struct {
+	*gorm.DB
+}
+
+func newIntegrityRepo(db *gorm.DB) *integrityRepo {
+	return &integrityRepo{DB: db}
+}
+
+func (i *integrityRepo) Save(result *types.SectorIntegrity) error {
+	var commr string
+	if result.CommR != cid.Undef {
+		commr = result.CommR.String()
+	}
+
+	return i.DB.Save(&sectorIntegrity{
+		SectorNumber: uint64(result.SectorNumber),
+		CommR:        commr,
+		Checked:      result.Checked.Unix(),
+		Error:        result.Error,
+	}).Error
+}
+
+// Get returns nil if the sector was never checked
+func (i *integrityRepo) Get(sector abi.SectorNumber) (*types.SectorIntegrity, error) {
+	var rows []*sectorIntegrity
+	if err := i.DB.Find(&rows, "sector_number=?", uint64(sector)).Error; err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	return rows[0].toResult()
+}
+
+func (i *integrityRepo) List() ([]*types.SectorIntegrity, error) {
+	var rows []*sectorIntegrity
+	if err := i.DB.Order("sector_number").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	out := make([]*types.SectorIntegrity, 0, len(rows))
+	for _, row := range rows {
+		res, err := row.toResult()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, res)
+	}
+	return out, nil
+}
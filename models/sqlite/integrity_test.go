@@ -0,0 +1,81 @@
+package sqlite
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	commcid "github.com/filecoin-project/go-fil-commcid"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/venus-sealer/types"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupIntegrity(suffix string, t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open("./integrity_"+suffix), &gorm.Config{})
+	if err != nil {
+		t.Error(err)
+	}
+	err = db.AutoMigrate(&sectorIntegrity{})
+	if err != nil {
+		t.Error(err)
+	}
+	return db
+}
+
+func cleanIntegrity(suffix string, t *testing.T) {
+	os.Remove("./integrity_" + suffix)
+}
+
+func Test_integrityRepo(t *testing.T) {
+	db := setupIntegrity("results", t)
+	defer cleanIntegrity("results", t)
+	iRepo := newIntegrityRepo(db)
+
+	commr, err := commcid.ReplicaCommitmentV1ToCID(make([]byte, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := iRepo.Get(1)
+	if err != nil {
+		t.Error(err)
+	}
+	if res != nil {
+		t.Errorf("expect no result for an unchecked sector, but got %v", res)
+	}
+
+	checked := time.Now().Truncate(time.Second)
+	for _, sn := range []abi.SectorNumber{2, 1} {
+		err := iRepo.Save(&types.SectorIntegrity{SectorNumber: sn, CommR: commr, Checked: checked})
+		if err != nil {
+			t.Error(err)
+		}
+	}
+
+	// saving again replaces the result
+	err = iRepo.Save(&types.SectorIntegrity{SectorNumber: 2, CommR: commr, Checked: checked, Error: "generating vanilla proof: bad tree"})
+	if err != nil {
+		t.Error(err)
+	}
+
+	res, err = iRepo.Get(2)
+	if err != nil {
+		t.Error(err)
+	}
+	if res == nil || res.OK() || !res.CommR.Equals(commr) || !res.Checked.Equal(checked) {
+		t.Errorf("expect the failed result of sector 2, but got %v", res)
+	}
+
+	results, err := iRepo.List()
+	if err != nil {
+		t.Error(err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expect %d results, but got %d", 2, len(results))
+	}
+	if results[0].SectorNumber != 1 || !results[0].OK() {
+		t.Errorf("expect sector 1 to be listed first and ok, but got %v", results[0])
+	}
+}
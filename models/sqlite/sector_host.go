@@ -0,0 +1,62 @@
+package sqlite
+
+import (
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/venus-sealer/models/repo"
+	"github.com/filecoin-project/venus-sealer/types"
+	"gorm.io/gorm"
+)
+
+type sectorHost struct {
+	Miner    uint64 `gorm:"column:miner;type:unsigned bigint;primary_key;" json:"miner"`
+	SectorId uint64 `gorm:"column:sector_id;type:unsigned bigint;primary_key;" json:"sector_id"`
+	TaskType string `gorm:"column:task_type;type:varchar(64);primary_key;" json:"task_type"`
+	Hostname string `gorm:"column:hostname;type:varchar(256);" json:"hostname"`
+}
+
+func (s *sectorHost) TableName() string {
+	return "sector_hosts"
+}
+
+var _ repo.SectorHostRepo = (*sectorHostRepo)(nil)
+
+type sectorHostRepo struct {
+	*gorm.DB
+}
+
+func newSectorHostRepo(db *gorm.DB) *sectorHostRepo {
+	return &sectorHostRepo{DB: db}
+}
+
+func (s *sectorHostRepo) Save(host *types.SectorHost) error {
+	return s.DB.Save(&sectorHost{
+		Miner:    uint64(host.Sector.Miner),
+		SectorId: uint64(host.Sector.Number),
+		TaskType: string(host.Task),
+		Hostname: host.Hostname,
+	}).Error
+}
+
+func (s *sectorHostRepo) Delete(sector abi.SectorID) error {
+	return s.DB.Delete(&sectorHost{}, "miner=? and sector_id=?", uint64(sector.Miner), uint64(sector.Number)).Error
+}
+
+func (s *sectorHostRepo) List() ([]*types.SectorHost, error) {
+	var hosts []*sectorHost
+	if err := s.DB.Order("miner, sector_id").Find(&hosts).Error; err != nil {
+		return nil, err
+	}
+
+	out := make([]*types.SectorHost, 0, len(hosts))
+	for _, h := range hosts {
+		out = append(out, &types.SectorHost{
+			Sector: abi.SectorID{
+				Miner:  abi.ActorID(h.Miner),
+				Number: abi.SectorNumber(h.SectorId),
+			},
+			Task:     types.TaskType(h.TaskType),
+			Hostname: h.Hostname,
+		})
+	}
+	return out, nil
+}
@@ -0,0 +1,72 @@
+package sqlite
+
+import (
+	"os"
+	"testing"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/venus-sealer/types"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupSectorHost(suffix string, t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open("./sector_host_"+suffix), &gorm.Config{})
+	if err != nil {
+		t.Error(err)
+	}
+	err = db.AutoMigrate(&sectorHost{})
+	if err != nil {
+		t.Error(err)
+	}
+	return db
+}
+
+func cleanSectorHost(suffix string, t *testing.T) {
+	os.Remove("./sector_host_" + suffix)
+}
+
+func Test_sectorHostRepo(t *testing.T) {
+	db := setupSectorHost("hosts", t)
+	defer cleanSectorHost("hosts", t)
+	hRepo := newSectorHostRepo(db)
+
+	s1 := abi.SectorID{Miner: 1000, Number: 1}
+	s2 := abi.SectorID{Miner: 1000, Number: 2}
+	for _, h := range []*types.SectorHost{
+		{Sector: s1, Task: types.TTPreCommit1, Hostname: "a"},
+		{Sector: s1, Task: types.TTPreCommit2, Hostname: "b"},
+		{Sector: s2, Task: types.TTPreCommit1, Hostname: "c"},
+		// a task running again replaces the host
+		{Sector: s1, Task: types.TTPreCommit1, Hostname: "d"},
+	} {
+		if err := hRepo.Save(h); err != nil {
+			t.Error(err)
+		}
+	}
+
+	hosts, err := hRepo.List()
+	if err != nil {
+		t.Error(err)
+	}
+	if len(hosts) != 3 {
+		t.Fatalf("expect %d hosts, but got %d", 3, len(hosts))
+	}
+	for _, h := range hosts {
+		if h.Sector == s1 && h.Task == types.TTPreCommit1 && h.Hostname != "d" {
+			t.Errorf("expect updated host of PC1, but got %v", h)
+		}
+	}
+
+	if err := hRepo.Delete(s1); err != nil {
+		t.Error(err)
+	}
+
+	hosts, err = hRepo.List()
+	if err != nil {
+		t.Error(err)
+	}
+	if len(hosts) != 1 || hosts[0].Sector != s2 || hosts[0].Hostname != "c" {
+		t.Errorf("expect only the host of sector 2, but got %v", hosts)
+	}
+}
@@ -54,6 +54,9 @@ type sectorInfo struct {
 
 	// Recovery
 	Return string `gorm:"column:return;type:text;" json:"return"`
+	// map[SectorState]uint64
+	RecoveryAttempts  []byte `gorm:"column:recovery_attempts;type:blob;" json:"recovery_attempts"`
+	RecoveryEscalated bool   `gorm:"column:recovery_escalated;type:boolean;" json:"recovery_escalated"`
 
 	// Termination
 	TerminateMessage string `gorm:"column:terminate_message;type:text;" json:"terminate_message"`
@@ -102,6 +105,14 @@ func (sectorInfo *sectorInfo) SectorInfo() (*types.SectorInfo, error) {
 		}
 	}
 
+	sinfo.RecoveryEscalated = sectorInfo.RecoveryEscalated
+	if len(sectorInfo.RecoveryAttempts) > 0 {
+		err := json.Unmarshal(sectorInfo.RecoveryAttempts, &sinfo.RecoveryAttempts)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	if len(sectorInfo.CommD) > 0 {
 		commD, err := cid.Decode(sectorInfo.CommD)
 		if err != nil {
@@ -205,6 +216,15 @@ func FromSectorInfo(sector *types.SectorInfo) (*sectorInfo, error) {
 		sectorInfo.Pieces = pieces
 	}
 
+	sectorInfo.RecoveryEscalated = sector.RecoveryEscalated
+	if len(sector.RecoveryAttempts) > 0 {
+		attempts, err := json.Marshal(sector.RecoveryAttempts)
+		if err != nil {
+			return nil, err
+		}
+		sectorInfo.RecoveryAttempts = attempts
+	}
+
 	if sector.CommD != nil {
 		sectorInfo.CommD = sector.CommD.String()
 	}
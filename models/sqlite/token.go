@@ -0,0 +1,66 @@
+package sqlite
+
+import (
+	"time"
+
+	"github.com/filecoin-project/venus-sealer/models/repo"
+	"github.com/filecoin-project/venus-sealer/types"
+	"gorm.io/gorm"
+)
+
+type revokedToken struct {
+	Id      string `gorm:"column:id;type:varchar(64);primary_key;" json:"id"`
+	Revoked int64  `gorm:"column:revoked;type:bigint;" json:"revoked"`                            // unix seconds
+	Expires int64  `gorm:"column:expires;type:bigint;index:revoked_token_expires" json:"expires"` // unix seconds, 0 if the token doesn't expire
+}
+
+func (t *revokedToken) TableName() string {
+	return "revoked_tokens"
+}
+
+var _ repo.TokenRepo = (*tokenRepo)(nil)
+
+type tokenRepo struct {
+	*gorm.DB
+}
+
+func newTokenRepo(db *gorm.DB) *tokenRepo {
+	return &tokenRepo{DB: db}
+}
+
+func (t *tokenRepo) Revoke(token *types.RevokedToken) error {
+	var expires int64
+	if !token.Expires.IsZero() {
+		expires = token.Expires.Unix()
+	}
+
+	return t.DB.Save(&revokedToken{
+		Id:      token.ID,
+		Revoked: token.Revoked.Unix(),
+		Expires: expires,
+	}).Error
+}
+
+func (t *tokenRepo) List() ([]*types.RevokedToken, error) {
+	var tokens []*revokedToken
+	if err := t.DB.Order("revoked").Find(&tokens).Error; err != nil {
+		return nil, err
+	}
+
+	out := make([]*types.RevokedToken, 0, len(tokens))
+	for _, tk := range tokens {
+		rt := &types.RevokedToken{
+			ID:      tk.Id,
+			Revoked: time.Unix(tk.Revoked, 0),
+		}
+		if tk.Expires != 0 {
+			rt.Expires = time.Unix(tk.Expires, 0)
+		}
+		out = append(out, rt)
+	}
+	return out, nil
+}
+
+func (t *tokenRepo) DeleteExpired(before time.Time) error {
+	return t.DB.Delete(&revokedToken{}, "expires != 0 and expires < ?", before.Unix()).Error
+}
@@ -0,0 +1,73 @@
+package sqlite
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/filecoin-project/venus-sealer/types"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupToken(suffix string, t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open("./token_"+suffix), &gorm.Config{})
+	if err != nil {
+		t.Error(err)
+	}
+	err = db.AutoMigrate(&revokedToken{})
+	if err != nil {
+		t.Error(err)
+	}
+	return db
+}
+
+func cleanToken(suffix string, t *testing.T) {
+	os.Remove("./token_" + suffix)
+}
+
+func Test_tokenRepo(t *testing.T) {
+	db := setupToken("revoked", t)
+	defer cleanToken("revoked", t)
+	tRepo := newTokenRepo(db)
+
+	now := time.Now()
+	tokens := []*types.RevokedToken{
+		{ID: "expired", Revoked: now.Add(-2 * time.Hour), Expires: now.Add(-time.Hour)},
+		{ID: "valid", Revoked: now.Add(-time.Hour), Expires: now.Add(time.Hour)},
+		{ID: "forever", Revoked: now},
+	}
+	for _, tk := range tokens {
+		if err := tRepo.Revoke(tk); err != nil {
+			t.Error(err)
+		}
+	}
+
+	list, err := tRepo.List()
+	if err != nil {
+		t.Error(err)
+	}
+	if len(list) != 3 {
+		t.Fatalf("expect %d revoked tokens, but got %d", 3, len(list))
+	}
+	if !list[2].Expires.IsZero() {
+		t.Errorf("expect token %s not to expire", list[2].ID)
+	}
+
+	if err := tRepo.DeleteExpired(now); err != nil {
+		t.Error(err)
+	}
+
+	list, err = tRepo.List()
+	if err != nil {
+		t.Error(err)
+	}
+	if len(list) != 2 {
+		t.Fatalf("expect %d revoked tokens, but got %d", 2, len(list))
+	}
+	for _, tk := range list {
+		if tk.ID == "expired" {
+			t.Errorf("expect token %s to be dropped", tk.ID)
+		}
+	}
+}
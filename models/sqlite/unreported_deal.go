@@ -0,0 +1,87 @@
+package sqlite
+
+import (
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/venus-sealer/models/repo"
+	"github.com/filecoin-project/venus-sealer/types"
+	"github.com/ipfs/go-cid"
+	"golang.org/x/xerrors"
+	"gorm.io/gorm"
+)
+
+type unreportedDeal struct {
+	DealId    uint64 `gorm:"column:deal_id;type:unsigned bigint;primary_key;" json:"deal_id"`
+	SectorId  uint64 `gorm:"column:sector_id;type:unsigned bigint;" json:"sector_id"`
+	PieceCid  string `gorm:"column:piece_cid;type:varchar(256);" json:"piece_cid"`
+	PadOffset uint64 `gorm:"column:offset_pad;type:unsigned bigint;" json:"offset_pad"`
+	PadSize   uint64 `gorm:"column:size_pad;type:unsigned bigint;" json:"size_pad"`
+	Attempts  int    `gorm:"column:attempts;type:int;" json:"attempts"`
+	LastError string `gorm:"column:last_error;type:text;" json:"last_error"`
+}
+
+func (u *unreportedDeal) TableName() string {
+	return "unreported_deals"
+}
+
+var _ repo.UnreportedDealRepo = (*unreportedDealRepo)(nil)
+
+type unreportedDealRepo struct {
+	*gorm.DB
+}
+
+func newUnreportedDealRepo(db *gorm.DB) *unreportedDealRepo {
+	return &unreportedDealRepo{DB: db}
+}
+
+func (u *unreportedDealRepo) Save(deal *types.UnreportedDeal) error {
+	var pieceCid string
+	if deal.PieceCid.Defined() {
+		pieceCid = deal.PieceCid.String()
+	}
+
+	return u.DB.Save(&unreportedDeal{
+		DealId:    uint64(deal.DealId),
+		SectorId:  uint64(deal.SectorId),
+		PieceCid:  pieceCid,
+		PadOffset: uint64(deal.Offset),
+		PadSize:   uint64(deal.Size),
+		Attempts:  deal.Attempts,
+		LastError: deal.LastError,
+	}).Error
+}
+
+func (u *unreportedDealRepo) Delete(deal abi.DealID) error {
+	return u.DB.Delete(&unreportedDeal{}, "deal_id=?", uint64(deal)).Error
+}
+
+func (u *unreportedDealRepo) List() ([]*types.UnreportedDeal, error) {
+	var deals []*unreportedDeal
+	if err := u.DB.Order("deal_id").Find(&deals).Error; err != nil {
+		return nil, err
+	}
+
+	out := make([]*types.UnreportedDeal, 0, len(deals))
+	for _, d := range deals {
+		pieceCid := cid.Undef
+		if d.PieceCid != "" {
+			var err error
+			pieceCid, err = cid.Decode(d.PieceCid)
+			if err != nil {
+				return nil, xerrors.Errorf("decoding piece cid of deal %d: %w", d.DealId, err)
+			}
+		}
+
+		out = append(out, &types.UnreportedDeal{
+			DealAssign: types.DealAssign{
+				DealId:   abi.DealID(d.DealId),
+				SectorId: abi.SectorNumber(d.SectorId),
+				PieceCid: pieceCid,
+				Offset:   abi.PaddedPieceSize(d.PadOffset),
+				Size:     abi.PaddedPieceSize(d.PadSize),
+			},
+			Attempts:  d.Attempts,
+			LastError: d.LastError,
+		})
+	}
+	return out, nil
+}
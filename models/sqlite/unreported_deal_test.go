@@ -0,0 +1,86 @@
+package sqlite
+
+import (
+	"os"
+	"testing"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/venus-sealer/types"
+	"github.com/ipfs/go-cid"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupUnreportedDeal(suffix string, t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open("./unreported_"+suffix), &gorm.Config{})
+	if err != nil {
+		t.Error(err)
+	}
+	err = db.AutoMigrate(&unreportedDeal{})
+	if err != nil {
+		t.Error(err)
+	}
+	return db
+}
+
+func cleanUnreportedDeal(suffix string, t *testing.T) {
+	os.Remove("./unreported_" + suffix)
+}
+
+func Test_unreportedDealRepo(t *testing.T) {
+	db := setupUnreportedDeal("deals", t)
+	defer cleanUnreportedDeal("deals", t)
+	uRepo := newUnreportedDealRepo(db)
+
+	pieceCid, err := cid.Decode("baga6ea4seaqao7s73y24kcutaosvacpdjgfe5pw76ooefnyqw4ynr3d2y6x2mpq")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []abi.DealID{2, 1} {
+		err := uRepo.Save(&types.UnreportedDeal{
+			DealAssign: types.DealAssign{DealId: id, SectorId: 10, PieceCid: pieceCid, Offset: 2048, Size: 2048},
+			Attempts:   1,
+			LastError:  "market down",
+		})
+		if err != nil {
+			t.Error(err)
+		}
+	}
+
+	// saving again updates the attempts
+	err = uRepo.Save(&types.UnreportedDeal{
+		DealAssign: types.DealAssign{DealId: 2, SectorId: 10, PieceCid: pieceCid, Offset: 2048, Size: 2048},
+		Attempts:   2,
+		LastError:  "still down",
+	})
+	if err != nil {
+		t.Error(err)
+	}
+
+	deals, err := uRepo.List()
+	if err != nil {
+		t.Error(err)
+	}
+	if len(deals) != 2 {
+		t.Fatalf("expect %d deals, but got %d", 2, len(deals))
+	}
+	if deals[0].DealId != 1 || !deals[0].PieceCid.Equals(pieceCid) || deals[0].Offset != 2048 {
+		t.Errorf("unexpected deal %v", deals[0])
+	}
+	if deals[1].Attempts != 2 || deals[1].LastError != "still down" {
+		t.Errorf("expect updated attempts for deal 2, but got %v", deals[1])
+	}
+
+	err = uRepo.Delete(1)
+	if err != nil {
+		t.Error(err)
+	}
+
+	deals, err = uRepo.List()
+	if err != nil {
+		t.Error(err)
+	}
+	if len(deals) != 1 || deals[0].DealId != 2 {
+		t.Errorf("expect only deal 2, but got %v", deals)
+	}
+}
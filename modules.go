@@ -4,7 +4,6 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/hex"
-	"errors"
 	"math"
 	"net/http"
 	"time"
@@ -17,7 +16,6 @@ import (
 	"github.com/ipfs/go-datastore"
 	"github.com/mitchellh/go-homedir"
 	"go.uber.org/fx"
-	"go.uber.org/multierr"
 	"golang.org/x/xerrors"
 
 	"github.com/filecoin-project/go-address"
@@ -160,17 +158,21 @@ func RemoteStorage(lstor *stores.Local, si stores.SectorIndex, sa sectorstorage.
 	return stores.NewRemote(lstor, si, http.Header(sa), sc.ParallelFetchLimit, &stores.DefaultPartialFileHandler{})
 }
 
-func SectorStorage(mctx MetricsCtx, lc fx.Lifecycle, lstor *stores.Local, stor *stores.Remote, ls stores.LocalStorage, si stores.SectorIndex, sc sectorstorage.SealerConfig, repo repo.Repo) (*sectorstorage.Manager, error) {
+func SectorStorage(mctx MetricsCtx, lc fx.Lifecycle, lstor *stores.Local, stor *stores.Remote, ls stores.LocalStorage, si stores.SectorIndex, sc sectorstorage.SealerConfig, repo repo.Repo, live *config.Live) (*sectorstorage.Manager, error) {
 	ctx := LifecycleCtx(mctx, lc)
 
 	wsts := service.NewWorkCallService(repo, "sealer")
 	smsts := service.NewWorkStateService(repo)
 
-	sst, err := sectorstorage.New(ctx, lstor, stor, ls, si, sc, wsts, smsts)
+	sst, err := sectorstorage.New(ctx, lstor, stor, ls, si, sc, wsts, smsts, service.NewSectorHostService(repo))
 	if err != nil {
 		return nil, err
 	}
 
+	live.OnChange(func(cfg *config.StorageMiner) (func(), error) {
+		return sst.PrepareSchedConfig(cfg.Storage)
+	})
+
 	lc.Append(fx.Hook{
 		OnStop: sst.Close,
 	})
@@ -207,108 +209,159 @@ func StorageNetworkName(ctx MetricsCtx, a api.FullNode) (types2.NetworkName, err
 	return a.StateNetworkName(ctx)
 }
 
-func AddressSelector(addrConf *config.MinerAddressConfig) func() (*storage.AddressSelector, error) {
-	return func() (*storage.AddressSelector, error) {
+func AddressSelector(addrConf *config.MinerAddressConfig) func(live *config.Live) (*storage.AddressSelector, error) {
+	return func(live *config.Live) (*storage.AddressSelector, error) {
 		as := &storage.AddressSelector{}
 		if addrConf == nil {
 			return as, nil
 		}
 
 		log.Infof("miner address config: %v", *addrConf)
-		for _, s := range addrConf.PreCommitControl {
-			addr, err := address.NewFromString(s)
+		ac, err := ParseAddressConfig(*addrConf)
+		if err != nil {
+			return nil, err
+		}
+		as.SetConfig(ac)
+
+		live.OnChange(func(cfg *config.StorageMiner) (func(), error) {
+			ac, err := ParseAddressConfig(cfg.Addresses)
 			if err != nil {
-				return nil, xerrors.Errorf("parsing precommit control address: %w", err)
+				return nil, err
 			}
+			return func() {
+				as.SetConfig(ac)
+			}, nil
+		})
 
-			as.PreCommitControl = append(as.PreCommitControl, addr)
-		}
+		return as, nil
+	}
+}
+
+func ParseAddressConfig(addrConf config.MinerAddressConfig) (api.AddressConfig, error) {
+	var ac api.AddressConfig
 
-		for _, s := range addrConf.CommitControl {
+	parse := func(what string, addrs []string) ([]address.Address, error) {
+		var out []address.Address
+		for _, s := range addrs {
 			addr, err := address.NewFromString(s)
 			if err != nil {
-				return nil, xerrors.Errorf("parsing commit control address: %w", err)
+				return nil, xerrors.Errorf("parsing %s control address: %w", what, err)
 			}
 
-			as.CommitControl = append(as.CommitControl, addr)
+			out = append(out, addr)
 		}
+		return out, nil
+	}
 
-		as.DisableOwnerFallback = addrConf.DisableOwnerFallback
-		as.DisableWorkerFallback = addrConf.DisableWorkerFallback
-
-		return as, nil
+	var err error
+	if ac.PreCommitControl, err = parse("precommit", addrConf.PreCommitControl); err != nil {
+		return ac, err
+	}
+	if ac.CommitControl, err = parse("commit", addrConf.CommitControl); err != nil {
+		return ac, err
 	}
+	if ac.TerminateControl, err = parse("terminate", addrConf.TerminateControl); err != nil {
+		return ac, err
+	}
+
+	ac.DisableOwnerFallback = addrConf.DisableOwnerFallback
+	ac.DisableWorkerFallback = addrConf.DisableWorkerFallback
+
+	return ac, nil
 }
 
 type StorageMinerParams struct {
 	fx.In
 
-	Lifecycle          fx.Lifecycle
-	MetricsCtx         MetricsCtx
-	API                api.FullNode
-	Messager           api.IMessager
-	MarketClient       api2.MarketFullNode
-	MetadataService    *service.MetadataService
-	LogService         *service.LogService
-	SectorInfoService  *service.SectorInfoService
-	Sealer             sectorstorage.SectorManager
-	SectorIDCounter    types2.SectorIDCounter
-	Verifier           ffiwrapper.Verifier
-	Prover             ffiwrapper.Prover
-	GetSealingConfigFn types2.GetSealingConfigFunc
-	Journal            journal.Journal
-	AddrSel            *storage.AddressSelector
-	NetworkParams      *config.NetParamsConfig
+	Lifecycle             fx.Lifecycle
+	MetricsCtx            MetricsCtx
+	API                   api.FullNode
+	Messager              api.IMessager
+	MarketClient          api2.MarketFullNode
+	MetadataService       *service.MetadataService
+	LogService            *service.LogService
+	BatchService          *service.BatchService
+	UnreportedDealService *service.UnreportedDealService
+	SectorInfoService     *service.SectorInfoService
+	Sealer                sectorstorage.SectorManager
+	SectorIDCounter       types2.SectorIDCounter
+	Verifier              ffiwrapper.Verifier
+	Prover                ffiwrapper.Prover
+	GetSealingConfigFn    types2.GetSealingConfigFunc
+	GetMinerFeeConfigFn   config.GetMinerFeeConfigFunc
+	Journal               journal.Journal
+	AddrSel               *storage.AddressSelector
+	NetworkParams         *config.NetParamsConfig
 }
 
-func StorageMiner(fc config.MinerFeeConfig) func(params StorageMinerParams) (*storage.Miner, error) {
-	return func(params StorageMinerParams) (*storage.Miner, error) {
-		var (
-			metadataService   = params.MetadataService
-			sectorinfoService = params.SectorInfoService
-			logService        = params.LogService
-			mctx              = params.MetricsCtx
-			lc                = params.Lifecycle
-			api               = params.API
-			messager          = params.Messager
-			marketClient      = params.MarketClient
-			sealer            = params.Sealer
-			sc                = params.SectorIDCounter
-			verif             = params.Verifier
-			prover            = params.Prover
-			gsd               = params.GetSealingConfigFn
-			j                 = params.Journal
-			as                = params.AddrSel
-			np                = params.NetworkParams
-		)
-
-		maddr, err := metadataService.GetMinerAddress()
-		if err != nil {
-			return nil, err
-		}
+func StorageMiner(params StorageMinerParams) (*storage.Miner, error) {
+	var (
+		metadataService   = params.MetadataService
+		sectorinfoService = params.SectorInfoService
+		logService        = params.LogService
+		batchService      = params.BatchService
+		dealService       = params.UnreportedDealService
+		mctx              = params.MetricsCtx
+		lc                = params.Lifecycle
+		api               = params.API
+		messager          = params.Messager
+		marketClient      = params.MarketClient
+		sealer            = params.Sealer
+		sc                = params.SectorIDCounter
+		verif             = params.Verifier
+		prover            = params.Prover
+		gsd               = params.GetSealingConfigFn
+		fc                = params.GetMinerFeeConfigFn
+		j                 = params.Journal
+		as                = params.AddrSel
+		np                = params.NetworkParams
+	)
 
-		ctx := LifecycleCtx(mctx, lc)
+	maddr, err := metadataService.GetMinerAddress()
+	if err != nil {
+		return nil, err
+	}
 
-		fps, err := storage.NewWindowedPoStScheduler(api, messager, fc, as, sealer, verif, sealer, j, maddr, np)
-		if err != nil {
-			return nil, err
-		}
+	ctx := LifecycleCtx(mctx, lc)
 
-		sm, err := storage.NewMiner(api, messager, marketClient, maddr, metadataService, sectorinfoService, logService, sealer, sc, verif, prover, gsd, fc, j, as, np)
-		if err != nil {
-			return nil, err
-		}
+	fps, err := storage.NewWindowedPoStScheduler(api, messager, fc, as, sealer, verif, sealer, j, maddr, np)
+	if err != nil {
+		return nil, err
+	}
 
-		lc.Append(fx.Hook{
-			OnStart: func(context.Context) error {
-				go fps.Run(ctx)
-				return sm.Run(ctx)
-			},
-			OnStop: sm.Stop,
-		})
+	sm, err := storage.NewMiner(api, messager, marketClient, maddr, metadataService, sectorinfoService, logService, batchService, dealService, sealer, sc, verif, prover, gsd, fc, j, as, np)
+	if err != nil {
+		return nil, err
+	}
+
+	lc.Append(fx.Hook{
+		OnStart: func(context.Context) error {
+			go fps.Run(ctx)
+			return sm.Run(ctx)
+		},
+		OnStop: sm.Stop,
+	})
+
+	return sm, nil
+}
 
-		return sm, nil
+func IntegrityAuditor(mctx MetricsCtx, lc fx.Lifecycle, api api.FullNode, metadataService *service.MetadataService, checker sectorstorage.IntegrityChecker, results *service.IntegrityService, live *config.Live) (*storage.IntegrityAuditor, error) {
+	maddr, err := metadataService.GetMinerAddress()
+	if err != nil {
+		return nil, err
 	}
+
+	ctx := LifecycleCtx(mctx, lc)
+	ia := storage.NewIntegrityAuditor(api, maddr, checker, results, live)
+
+	lc.Append(fx.Hook{
+		OnStart: func(context.Context) error {
+			go ia.Run(ctx)
+			return nil
+		},
+	})
+
+	return ia, nil
 }
 
 func DoPoStWarmup(ctx MetricsCtx, api api.FullNode, metadataService *service.MetadataService, prover storage.WinningPoStProver) error {
@@ -376,16 +429,17 @@ out:
 	return nil
 }
 
-func NewSetSealConfigFunc(r *config.StorageMiner) (types2.SetSealingConfigFunc, error) {
+func NewSetSealConfigFunc(r *config.Live) (types2.SetSealingConfigFunc, error) {
 	return func(cfg sealiface.Config) (err error) {
 		err = mutateCfg(r, func(c *config.StorageMiner) {
 			c.Sealing = config.SealingConfig{
-				MaxWaitDealsSectors:       cfg.MaxWaitDealsSectors,
-				MaxSealingSectors:         cfg.MaxSealingSectors,
-				MaxSealingSectorsForDeals: cfg.MaxSealingSectorsForDeals,
-				WaitDealsDelay:            config.Duration(cfg.WaitDealsDelay),
-				AlwaysKeepUnsealedCopy:    cfg.AlwaysKeepUnsealedCopy,
-				FinalizeEarly:             cfg.FinalizeEarly,
+				MaxWaitDealsSectors:             cfg.MaxWaitDealsSectors,
+				MaxSealingSectors:               cfg.MaxSealingSectors,
+				MaxSealingSectorsForDeals:       cfg.MaxSealingSectorsForDeals,
+				WaitDealsDelay:                  config.Duration(cfg.WaitDealsDelay),
+				CommittedCapacitySectorLifetime: config.Duration(cfg.CommittedCapacitySectorLifetime),
+				AlwaysKeepUnsealedCopy:          cfg.AlwaysKeepUnsealedCopy,
+				FinalizeEarly:                   cfg.FinalizeEarly,
 
 				BatchPreCommits:     cfg.BatchPreCommits,
 				MaxPreCommitBatch:   cfg.MaxPreCommitBatch,
@@ -399,6 +453,7 @@ func NewSetSealConfigFunc(r *config.StorageMiner) (types2.SetSealingConfigFunc,
 				CommitBatchSlack:           config.Duration(cfg.CommitBatchSlack),
 				AggregateAboveBaseFee:      types.FIL(cfg.AggregateAboveBaseFee),
 				BatchPreCommitAboveBaseFee: types.FIL(cfg.BatchPreCommitAboveBaseFee),
+				BatchCostModel:             cfg.BatchCostModel,
 
 				CollateralFromMinerBalance: cfg.CollateralFromMinerBalance,
 				AvailableBalanceBuffer:     types.FIL(cfg.AvailableBalanceBuffer),
@@ -407,13 +462,21 @@ func NewSetSealConfigFunc(r *config.StorageMiner) (types2.SetSealingConfigFunc,
 				TerminateBatchMax:  cfg.TerminateBatchMax,
 				TerminateBatchMin:  cfg.TerminateBatchMin,
 				TerminateBatchWait: config.Duration(cfg.TerminateBatchWait),
+
+				AutoDealIngest:     cfg.AutoDealIngest,
+				DealIngestInterval: config.Duration(cfg.DealIngestInterval),
+				DealIngestBatch:    cfg.DealIngestBatch,
+
+				PlanDealPacking: cfg.PlanDealPacking,
+
+				RecoveryPlaybooks: cfg.RecoveryPlaybooks,
 			}
 		})
 		return
 	}, nil
 }
 
-func NewGetSealConfigFunc(r *config.StorageMiner) (types2.GetSealingConfigFunc, error) {
+func NewGetSealConfigFunc(r *config.Live) (types2.GetSealingConfigFunc, error) {
 	return func() (out sealiface.Config, err error) {
 		err = readCfg(r, func(cfg *config.StorageMiner) {
 			// log.Infof("max sealing sectors: %v", cfg.Sealing.MaxSealingSectors)
@@ -443,35 +506,47 @@ func NewGetSealConfigFunc(r *config.StorageMiner) (types2.GetSealingConfigFunc,
 				TerminateBatchWait: time.Duration(cfg.Sealing.TerminateBatchWait),
 
 				BatchPreCommitAboveBaseFee: types.BigInt(cfg.Sealing.BatchPreCommitAboveBaseFee),
+				BatchCostModel:             cfg.Sealing.BatchCostModel,
 				CollateralFromMinerBalance: cfg.Sealing.CollateralFromMinerBalance,
 				AvailableBalanceBuffer:     types.BigInt(cfg.Sealing.AvailableBalanceBuffer),
 				DisableCollateralFallback:  cfg.Sealing.DisableCollateralFallback,
 
 				StartEpochSealingBuffer: abi.ChainEpoch(cfg.Dealmaking.StartEpochSealingBuffer),
+
+				AutoDealIngest:     cfg.Sealing.AutoDealIngest,
+				DealIngestInterval: time.Duration(cfg.Sealing.DealIngestInterval),
+				DealIngestBatch:    cfg.Sealing.DealIngestBatch,
+
+				PlanDealPacking:      cfg.Sealing.PlanDealPacking,
+				ExpectedSealDuration: time.Duration(cfg.Dealmaking.ExpectedSealDuration),
+
+				RecoveryPlaybooks: cfg.Sealing.RecoveryPlaybooks,
 			}
 		})
 		return
 	}, nil
 }
 
-func readCfg(cfg *config.StorageMiner, accessor func(*config.StorageMiner)) error {
-	accessor(cfg)
-	return nil
+func NewGetMinerFeeConfigFunc(r *config.Live) config.GetMinerFeeConfigFunc {
+	return func() (out config.MinerFeeConfig) {
+		_ = readCfg(r, func(cfg *config.StorageMiner) {
+			out = cfg.Fees
+		})
+		return
+	}
 }
 
-func mutateCfg(cfg *config.StorageMiner, mutator func(*config.StorageMiner)) error {
-	var typeErr error
-	setConfigErr := cfg.LocalStorage().SetConfig(func(raw interface{}) {
-		cfg, ok := raw.(*config.StorageMiner)
-		if !ok {
-			typeErr = errors.New("expected miner config")
-			return
-		}
+func readCfg(r *config.Live, accessor func(*config.StorageMiner)) error {
+	r.Read(accessor)
+	return nil
+}
 
+func mutateCfg(r *config.Live, mutator func(*config.StorageMiner)) error {
+	_, err := r.Mutate(func(cfg *config.StorageMiner) error {
 		mutator(cfg)
+		return nil
 	})
-
-	return multierr.Combine(typeErr, setConfigErr)
+	return err
 }
 
 // MetricsCtx is a context wrapper with metrics
@@ -55,19 +55,27 @@ func ServeRPC(h http.Handler, id string, addr multiaddr.Multiaddr) (StopFunc, er
 func MinerHandler(mapi api.StorageMiner, permissioned bool) (http.Handler, error) {
 	m := mux.NewRouter()
 
+	sapi := mapi.(*impl.StorageMinerAPI)
+
 	rpcServer := jsonrpc.NewServer()
-	rpcServer.Register("Filecoin", mapi)
+	if permissioned {
+		// denied calls are recorded too
+		rpcServer.Register("Filecoin", api.AuditedStorMinerAPI(api.PermissionedStorMinerAPI(mapi), sapi.AuditService))
+	} else {
+		rpcServer.Register("Filecoin", api.AuditedStorMinerAPI(mapi, sapi.AuditService))
+	}
 
 	mux := mux.NewRouter()
 	mux.Handle("/rpc/v0", rpcServer)
-	mux.PathPrefix("/remote").HandlerFunc(mapi.(*impl.StorageMinerAPI).ServeRemote)
+	mux.PathPrefix("/remote").HandlerFunc(sapi.ServeRemote)
+	sapi.PieceHandler.Register(mux)
 
 	// debugging
 	// m.Handle("/debug/metrics", metrics.Exporter())
 	m.PathPrefix("/").Handler(http.DefaultServeMux) // pprof
 
 	if !permissioned {
-		return rpcServer, nil
+		return api.AuditHandler(rpcServer), nil
 	}
 
 	ah := &auth.Handler{
@@ -75,6 +83,6 @@ func MinerHandler(mapi api.StorageMiner, permissioned bool) (http.Handler, error
 		Next:   mux.ServeHTTP,
 	}
 
-	return ah, nil
+	return api.AuditHandler(ah), nil
 }
 
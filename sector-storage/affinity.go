@@ -0,0 +1,549 @@
+package sectorstorage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/specs-storage/storage"
+
+	"github.com/filecoin-project/venus-sealer/sector-storage/stores"
+	"github.com/filecoin-project/venus-sealer/sector-storage/storiface"
+	"github.com/filecoin-project/venus-sealer/types"
+)
+
+// AffinityCheckInterval is how often the scheduler re-evaluates waiting tasks
+// when there are affinity rules which can spill over
+var AffinityCheckInterval = 30 * time.Second
+
+// AffinityRule constrains the workers a task type can be scheduled on, e.g.
+//
+//	[[Storage.Affinity]]
+//	Task = "PC2"
+//	SameHostAs = "PC1"
+//
+// A rule with Weight 0 is hard and must always be satisfied. Rules with a Weight
+// are soft, workers satisfying them are preferred, higher weights first. A soft
+// rule with SpillAfterSecs is enforced until the task has been waiting that long.
+type AffinityRule struct {
+	// Task is the short name of the task type the rule applies to (AP, PC1, PC2, C1, C2, FIN, GET, UNS)
+	Task string
+
+	// SameHostAs is the short name of an earlier task type; the task has to run on
+	// the host which has run it for the same sector
+	SameHostAs string
+	// Holding lists sector file types (unsealed, sealed, cache) which have to be in
+	// the local storage of the worker
+	Holding []string
+	// RequireGPU only accepts workers with at least one GPU
+	RequireGPU bool
+
+	Weight         int
+	SpillAfterSecs uint64
+}
+
+type affinityRule struct {
+	task       types.TaskType
+	sameHostAs types.TaskType
+	holding    []storiface.SectorFileType
+	requireGPU bool
+
+	weight     int
+	spillAfter time.Duration
+}
+
+func parseAffinityRule(r AffinityRule) (*affinityRule, error) {
+	task, ok := types.TaskTypeFromShort(r.Task)
+	if !ok {
+		return nil, xerrors.Errorf("unknown task type '%s'", r.Task)
+	}
+
+	out := &affinityRule{
+		task:       task,
+		requireGPU: r.RequireGPU,
+		weight:     r.Weight,
+		spillAfter: time.Duration(r.SpillAfterSecs) * time.Second,
+	}
+
+	if r.SameHostAs != "" {
+		out.sameHostAs, ok = types.TaskTypeFromShort(r.SameHostAs)
+		if !ok {
+			return nil, xerrors.Errorf("unknown task type '%s' in SameHostAs", r.SameHostAs)
+		}
+	}
+
+	for _, h := range r.Holding {
+		var ft storiface.SectorFileType
+		for _, pt := range storiface.PathTypes {
+			if pt.String() == h {
+				ft = pt
+			}
+		}
+		if ft == storiface.FTNone {
+			return nil, xerrors.Errorf("unknown sector file type '%s' in Holding", h)
+		}
+		out.holding = append(out.holding, ft)
+	}
+
+	if out.sameHostAs == "" && len(out.holding) == 0 && !out.requireGPU {
+		return nil, xerrors.Errorf("affinity rule for %s has no conditions", r.Task)
+	}
+	if out.weight < 0 {
+		return nil, xerrors.Errorf("affinity rule for %s has negative weight", r.Task)
+	}
+	if out.weight == 0 && out.spillAfter > 0 {
+		return nil, xerrors.Errorf("affinity rule for %s: SpillAfterSecs requires a Weight", r.Task)
+	}
+
+	return out, nil
+}
+
+// mandatory returns whether the worker has to satisfy the rule for a request
+// which has been waiting since start
+func (r *affinityRule) mandatory(start time.Time) bool {
+	if r.weight == 0 {
+		return true
+	}
+	return r.spillAfter > 0 && time.Since(start) < r.spillAfter
+}
+
+func (r *affinityRule) String() string {
+	var conds []string
+	if r.sameHostAs != "" {
+		conds = append(conds, "same host as "+r.sameHostAs.Short())
+	}
+	if len(r.holding) > 0 {
+		var h []string
+		for _, ft := range r.holding {
+			h = append(h, ft.String())
+		}
+		conds = append(conds, "holding "+strings.Join(h, ","))
+	}
+	if r.requireGPU {
+		conds = append(conds, "with GPU")
+	}
+
+	kind := "hard"
+	if r.weight > 0 {
+		kind = fmt.Sprintf("weight %d", r.weight)
+		if r.spillAfter > 0 {
+			kind += fmt.Sprintf(", spill after %s", r.spillAfter)
+		}
+	}
+
+	return fmt.Sprintf("%s %s (%s)", r.task.Short(), strings.Join(conds, ", "), kind)
+}
+
+// affinity evaluates AffinityRules, and keeps track of the hosts which have
+// run tasks of sectors still being sealed, persisted in store when set
+type affinity struct {
+	index stores.SectorIndex
+
+	// rules can be replaced when the config changes
+	rulesLk sync.RWMutex
+	rules   map[types.TaskType][]*affinityRule
+
+	lk    sync.Mutex
+	hosts map[abi.SectorID]map[types.TaskType]string
+	store types.SectorHostStore
+}
+
+func newAffinity(rules []AffinityRule, index stores.SectorIndex) (*affinity, error) {
+	parsed, err := parseAffinityRules(rules)
+	if err != nil {
+		return nil, err
+	}
+
+	return &affinity{
+		index: index,
+		rules: parsed,
+		hosts: map[abi.SectorID]map[types.TaskType]string{},
+	}, nil
+}
+
+func parseAffinityRules(rules []AffinityRule) (map[types.TaskType][]*affinityRule, error) {
+	out := map[types.TaskType][]*affinityRule{}
+	for i, r := range rules {
+		ar, err := parseAffinityRule(r)
+		if err != nil {
+			return nil, xerrors.Errorf("affinity rule %d: %w", i, err)
+		}
+		out[ar.task] = append(out[ar.task], ar)
+	}
+	return out, nil
+}
+
+// setRules replaces the rules, requests already waiting keep the rules they were scheduled with
+func (a *affinity) setRules(rules map[types.TaskType][]*affinityRule) {
+	a.rulesLk.Lock()
+	defer a.rulesLk.Unlock()
+
+	a.rules = rules
+}
+
+func (a *affinity) describe() []string {
+	a.rulesLk.RLock()
+	defer a.rulesLk.RUnlock()
+
+	var out []string
+	for _, tt := range []types.TaskType{types.TTAddPiece, types.TTPreCommit1, types.TTPreCommit2, types.TTCommit1, types.TTCommit2, types.TTFinalize, types.TTFetch, types.TTUnseal} {
+		for _, r := range a.rules[tt] {
+			out = append(out, r.String())
+		}
+	}
+	return out
+}
+
+// load restores the hosts recorded before the last shutdown from store, and
+// records later hosts in it
+func (a *affinity) load(store types.SectorHostStore) error {
+	hosts, err := store.ListSectorHosts()
+	if err != nil {
+		return xerrors.Errorf("listing sector hosts: %w", err)
+	}
+
+	a.lk.Lock()
+	defer a.lk.Unlock()
+
+	a.store = store
+	for _, h := range hosts {
+		if a.hosts[h.Sector] == nil {
+			a.hosts[h.Sector] = map[types.TaskType]string{}
+		}
+		a.hosts[h.Sector][h.Task] = h.Hostname
+	}
+	return nil
+}
+
+// taskDone records the host which has run the task
+func (a *affinity) taskDone(sector abi.SectorID, task types.TaskType, hostname string) {
+	a.lk.Lock()
+	defer a.lk.Unlock()
+
+	if task == types.TTFinalize {
+		// the sector is sealed, later tasks don't depend on the sealing hosts
+		a.dropHosts(sector)
+		return
+	}
+
+	if a.hosts[sector] == nil {
+		a.hosts[sector] = map[types.TaskType]string{}
+	}
+	a.hosts[sector][task] = hostname
+
+	if a.store != nil {
+		if err := a.store.SaveSectorHost(&types.SectorHost{Sector: sector, Task: task, Hostname: hostname}); err != nil {
+			log.Errorw("persisting sector host", "sector", sector, "task", task, "host", hostname, "error", err)
+		}
+	}
+}
+
+// forget drops the hosts recorded for the sector, it was removed
+func (a *affinity) forget(sector abi.SectorID) {
+	a.lk.Lock()
+	defer a.lk.Unlock()
+
+	a.dropHosts(sector)
+}
+
+// call with a.lk
+func (a *affinity) dropHosts(sector abi.SectorID) {
+	if _, ok := a.hosts[sector]; !ok {
+		return
+	}
+	delete(a.hosts, sector)
+
+	if a.store != nil {
+		if err := a.store.DeleteSectorHosts(sector); err != nil {
+			log.Errorw("removing persisted sector hosts", "sector", sector, "error", err)
+		}
+	}
+}
+
+func (a *affinity) hostOf(sector abi.SectorID, task types.TaskType) (string, bool) {
+	a.lk.Lock()
+	defer a.lk.Unlock()
+
+	h, ok := a.hosts[sector][task]
+	return h, ok
+}
+
+// sectorLocations are the storage paths holding each sector file type
+type sectorLocations map[storiface.SectorFileType][]stores.ID
+
+// locate finds the storage paths holding the sector files the rules need
+func (a *affinity) locate(ctx context.Context, rules []*affinityRule, sector storage.SectorRef) (sectorLocations, error) {
+	locs := sectorLocations{}
+	for _, r := range rules {
+		for _, ft := range r.holding {
+			if _, ok := locs[ft]; ok {
+				continue
+			}
+
+			ssize, err := sector.ProofType.SectorSize()
+			if err != nil {
+				return nil, xerrors.Errorf("getting sector size: %w", err)
+			}
+
+			found, err := a.index.StorageFindSector(ctx, sector.ID, ft, ssize, false)
+			if err != nil {
+				return nil, xerrors.Errorf("finding sector %s: %w", ft, err)
+			}
+
+			locs[ft] = []stores.ID{}
+			for _, info := range found {
+				locs[ft] = append(locs[ft], info.ID)
+			}
+		}
+	}
+	return locs, nil
+}
+
+// workerPaths returns the local storage paths of the worker if any rule needs them
+func workerPaths(ctx context.Context, rules []*affinityRule, whnd *workerHandle) (map[stores.ID]struct{}, error) {
+	have := map[stores.ID]struct{}{}
+	for _, r := range rules {
+		if len(r.holding) == 0 {
+			continue
+		}
+
+		paths, err := whnd.workerRpc.Paths(ctx)
+		if err != nil {
+			return nil, xerrors.Errorf("getting worker paths: %w", err)
+		}
+		for _, path := range paths {
+			have[path.ID] = struct{}{}
+		}
+		break
+	}
+	return have, nil
+}
+
+func (a *affinity) match(ctx context.Context, r *affinityRule, sector storage.SectorRef, whnd *workerHandle) (bool, error) {
+	rules := []*affinityRule{r}
+
+	locs, err := a.locate(ctx, rules, sector)
+	if err != nil {
+		return false, err
+	}
+	have, err := workerPaths(ctx, rules, whnd)
+	if err != nil {
+		return false, err
+	}
+
+	return a.matchAt(r, sector, whnd, locs, have), nil
+}
+
+// matchAt checks the rule against the worker with the sector locations and
+// worker paths already looked up
+func (a *affinity) matchAt(r *affinityRule, sector storage.SectorRef, whnd *workerHandle, locs sectorLocations, have map[stores.ID]struct{}) bool {
+	if r.requireGPU && len(whnd.info.Resources.GPUs) == 0 {
+		return false
+	}
+
+	if r.sameHostAs != "" {
+		// hosts are unknown for tasks which ran before they were recorded
+		if host, ok := a.hostOf(sector.ID, r.sameHostAs); ok && host != whnd.info.Hostname {
+			return false
+		}
+	}
+
+	for _, ft := range r.holding {
+		var local bool
+		for _, id := range locs[ft] {
+			if _, ok := have[id]; ok {
+				local = true
+				break
+			}
+		}
+		if !local {
+			return false
+		}
+	}
+
+	return true
+}
+
+// selector wraps the selector of a request with the affinity rules of the task type
+func (a *affinity) selector(task types.TaskType, sector storage.SectorRef, sel WorkerSelector, start time.Time) WorkerSelector {
+	a.rulesLk.RLock()
+	rules := a.rules[task]
+	a.rulesLk.RUnlock()
+	if len(rules) == 0 {
+		return sel
+	}
+
+	return &affinitySelector{
+		WorkerSelector: sel,
+		affinity:       a,
+		rules:          rules,
+		sector:         sector,
+		start:          start,
+	}
+}
+
+type affinitySelector struct {
+	WorkerSelector
+
+	affinity *affinity
+	rules    []*affinityRule
+	sector   storage.SectorRef
+	start    time.Time
+
+	// scores of the candidate workers in the current scheduling pass, see prepare
+	lk     sync.Mutex
+	scores map[*workerHandle]int
+}
+
+func (s *affinitySelector) Ok(ctx context.Context, task types.TaskType, spt abi.RegisteredSealProof, sector storage.SectorRef, whnd *workerHandle) (bool, error) {
+	ok, err := s.WorkerSelector.Ok(ctx, task, spt, sector, whnd)
+	if err != nil || !ok {
+		return ok, err
+	}
+
+	for _, r := range s.rules {
+		if !r.mandatory(s.start) {
+			continue
+		}
+
+		ok, err := s.affinity.match(ctx, r, sector, whnd)
+		if err != nil {
+			return false, xerrors.Errorf("checking affinity rule %s: %w", r, err)
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// prepare scores the candidate workers of a scheduling pass once, so sorting
+// them doesn't look up the sector and the worker paths on every comparison
+func (s *affinitySelector) prepare(ctx context.Context, workers []*workerHandle) error {
+	s.lk.Lock()
+	s.scores = nil
+	s.lk.Unlock()
+
+	soft := s.softRules()
+
+	scores := make(map[*workerHandle]int, len(workers))
+	if len(soft) > 0 {
+		locs, err := s.affinity.locate(ctx, soft, s.sector)
+		if err != nil {
+			return err
+		}
+
+		for _, whnd := range workers {
+			if _, ok := scores[whnd]; ok {
+				continue
+			}
+
+			have, err := workerPaths(ctx, soft, whnd)
+			if err != nil {
+				return err
+			}
+			scores[whnd] = s.scoreAt(soft, whnd, locs, have)
+		}
+	}
+
+	s.lk.Lock()
+	s.scores = scores
+	s.lk.Unlock()
+
+	return nil
+}
+
+func (s *affinitySelector) Cmp(ctx context.Context, task types.TaskType, a, b *workerHandle) (bool, error) {
+	as, err := s.score(ctx, a)
+	if err != nil {
+		return false, err
+	}
+	bs, err := s.score(ctx, b)
+	if err != nil {
+		return false, err
+	}
+	if as != bs {
+		return as > bs, nil
+	}
+
+	return s.WorkerSelector.Cmp(ctx, task, a, b)
+}
+
+func (s *affinitySelector) softRules() []*affinityRule {
+	var soft []*affinityRule
+	for _, r := range s.rules {
+		if r.weight > 0 {
+			soft = append(soft, r)
+		}
+	}
+	return soft
+}
+
+// score sums the weights of the soft rules satisfied by the worker, workers
+// not scored by prepare are looked up now
+func (s *affinitySelector) score(ctx context.Context, whnd *workerHandle) (int, error) {
+	s.lk.Lock()
+	score, ok := s.scores[whnd]
+	s.lk.Unlock()
+	if ok {
+		return score, nil
+	}
+
+	soft := s.softRules()
+	locs, err := s.affinity.locate(ctx, soft, s.sector)
+	if err != nil {
+		return 0, xerrors.Errorf("checking affinity rules: %w", err)
+	}
+	have, err := workerPaths(ctx, soft, whnd)
+	if err != nil {
+		return 0, xerrors.Errorf("checking affinity rules: %w", err)
+	}
+
+	return s.scoreAt(soft, whnd, locs, have), nil
+}
+
+func (s *affinitySelector) scoreAt(soft []*affinityRule, whnd *workerHandle, locs sectorLocations, have map[stores.ID]struct{}) int {
+	var score int
+	for _, r := range soft {
+		if s.affinity.matchAt(r, s.sector, whnd, locs, have) {
+			score += r.weight
+		}
+	}
+	return score
+}
+
+// canSpill tells if a soft rule of the request still has to spill
+func (s *affinitySelector) canSpill() bool {
+	for _, r := range s.rules {
+		if r.weight > 0 && r.spillAfter > 0 && time.Since(s.start) < r.spillAfter+AffinityCheckInterval {
+			return true
+		}
+	}
+	return false
+}
+
+// state describes the rules of the request for sched-diag
+func (s *affinitySelector) state() []string {
+	var out []string
+	for _, r := range s.rules {
+		st := r.String()
+		if r.weight > 0 && r.spillAfter > 0 {
+			if left := r.spillAfter - time.Since(s.start); left > 0 {
+				st += fmt.Sprintf(": spills in %s", left.Truncate(time.Second))
+			} else {
+				st += ": spilled"
+			}
+		}
+		out = append(out, st)
+	}
+	return out
+}
+
+var _ WorkerSelector = &affinitySelector{}
+var _ workerPreparer = &affinitySelector{}
@@ -0,0 +1,232 @@
+package sectorstorage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/specs-storage/storage"
+
+	"github.com/filecoin-project/venus-sealer/sector-storage/fsutil"
+	"github.com/filecoin-project/venus-sealer/sector-storage/stores"
+	"github.com/filecoin-project/venus-sealer/sector-storage/storiface"
+	"github.com/filecoin-project/venus-sealer/types"
+)
+
+type anySelector struct{}
+
+func (anySelector) Ok(context.Context, types.TaskType, abi.RegisteredSealProof, storage.SectorRef, *workerHandle) (bool, error) {
+	return true, nil
+}
+
+func (anySelector) Cmp(context.Context, types.TaskType, *workerHandle, *workerHandle) (bool, error) {
+	return false, nil
+}
+
+func TestAffinityRuleParse(t *testing.T) {
+	_, err := newAffinity([]AffinityRule{{Task: "PC2", SameHostAs: "PC1"}, {Task: "FIN", Holding: []string{"cache"}}}, nil)
+	require.NoError(t, err)
+
+	for _, r := range []AffinityRule{
+		{Task: "XX", SameHostAs: "PC1"},
+		{Task: "PC2", SameHostAs: "XX"},
+		{Task: "FIN", Holding: []string{"foo"}},
+		{Task: "C2"},
+		{Task: "C2", RequireGPU: true, SpillAfterSecs: 10},
+	} {
+		_, err := newAffinity([]AffinityRule{r}, nil)
+		require.Error(t, err, "%+v", r)
+	}
+}
+
+func TestAffinitySelector(t *testing.T) {
+	ctx := context.Background()
+
+	aff, err := newAffinity([]AffinityRule{
+		{Task: "PC2", SameHostAs: "PC1"},
+		{Task: "C2", RequireGPU: true, Weight: 1, SpillAfterSecs: 60},
+	}, nil)
+	require.NoError(t, err)
+
+	sector := storage.SectorRef{
+		ID:        abi.SectorID{Miner: 1000, Number: 1},
+		ProofType: abi.RegisteredSealProof_StackedDrg2KiBV1,
+	}
+
+	hostA := &workerHandle{info: storiface.WorkerInfo{Hostname: "a"}}
+	hostB := &workerHandle{info: storiface.WorkerInfo{Hostname: "b", Resources: storiface.WorkerResources{GPUs: []string{"gpu"}}}}
+
+	// no record of PC1 yet, any host is fine
+	sel := aff.selector(types.TTPreCommit2, sector, anySelector{}, time.Now())
+	ok, err := sel.Ok(ctx, types.TTPreCommit2, sector.ProofType, sector, hostB)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	aff.taskDone(sector.ID, types.TTPreCommit1, "a")
+
+	ok, err = sel.Ok(ctx, types.TTPreCommit2, sector.ProofType, sector, hostB)
+	require.NoError(t, err)
+	require.False(t, ok)
+	ok, err = sel.Ok(ctx, types.TTPreCommit2, sector.ProofType, sector, hostA)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	// soft rule is enforced until it spills
+	sel = aff.selector(types.TTCommit2, sector, anySelector{}, time.Now())
+	ok, err = sel.Ok(ctx, types.TTCommit2, sector.ProofType, sector, hostA)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	sel = aff.selector(types.TTCommit2, sector, anySelector{}, time.Now().Add(-time.Minute))
+	ok, err = sel.Ok(ctx, types.TTCommit2, sector.ProofType, sector, hostA)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	// but workers matching it are still preferred
+	better, err := sel.Cmp(ctx, types.TTCommit2, hostB, hostA)
+	require.NoError(t, err)
+	require.True(t, better)
+	require.Contains(t, sel.(*affinitySelector).state()[0], "spilled")
+
+	// finalize forgets the sealing hosts
+	aff.taskDone(sector.ID, types.TTFinalize, "a")
+	_, ok = aff.hostOf(sector.ID, types.TTPreCommit1)
+	require.False(t, ok)
+
+	// and so does removing the sector
+	aff.taskDone(sector.ID, types.TTPreCommit1, "a")
+	aff.forget(sector.ID)
+	_, ok = aff.hostOf(sector.ID, types.TTPreCommit1)
+	require.False(t, ok)
+
+	// waiting tasks with rules which can still spill get rescheduled,
+	// even if the rules were replaced since
+	sh := &scheduler{schedQueue: &requestQueue{}}
+	sh.schedQueue.Push(&workerRequest{taskType: types.TTPreCommit2, sel: aff.selector(types.TTPreCommit2, sector, anySelector{}, time.Now())})
+	require.False(t, sh.spillPending())
+	sh.schedQueue.Push(&workerRequest{taskType: types.TTCommit2, sel: aff.selector(types.TTCommit2, sector, anySelector{}, time.Now())})
+	aff.setRules(map[types.TaskType][]*affinityRule{})
+	require.True(t, sh.spillPending())
+
+	// task types without rules keep their selector
+	_, ok = aff.selector(types.TTAddPiece, sector, anySelector{}, time.Now()).(anySelector)
+	require.True(t, ok)
+}
+
+type memSectorHosts map[abi.SectorID]map[types.TaskType]string
+
+func (m memSectorHosts) SaveSectorHost(host *types.SectorHost) error {
+	if m[host.Sector] == nil {
+		m[host.Sector] = map[types.TaskType]string{}
+	}
+	m[host.Sector][host.Task] = host.Hostname
+	return nil
+}
+
+func (m memSectorHosts) DeleteSectorHosts(sector abi.SectorID) error {
+	delete(m, sector)
+	return nil
+}
+
+func (m memSectorHosts) ListSectorHosts() ([]*types.SectorHost, error) {
+	var out []*types.SectorHost
+	for sector, hosts := range m {
+		for task, hostname := range hosts {
+			out = append(out, &types.SectorHost{Sector: sector, Task: task, Hostname: hostname})
+		}
+	}
+	return out, nil
+}
+
+func TestAffinityHostsRestored(t *testing.T) {
+	ctx := context.Background()
+	rules := []AffinityRule{{Task: "PC2", SameHostAs: "PC1"}}
+	sector := storage.SectorRef{
+		ID:        abi.SectorID{Miner: 1000, Number: 1},
+		ProofType: abi.RegisteredSealProof_StackedDrg2KiBV1,
+	}
+	store := memSectorHosts{}
+
+	aff, err := newAffinity(rules, nil)
+	require.NoError(t, err)
+	require.NoError(t, aff.load(store))
+	aff.taskDone(sector.ID, types.TTPreCommit1, "a")
+
+	// after a restart
+	aff, err = newAffinity(rules, nil)
+	require.NoError(t, err)
+	require.NoError(t, aff.load(store))
+
+	host, ok := aff.hostOf(sector.ID, types.TTPreCommit1)
+	require.True(t, ok)
+	require.Equal(t, "a", host)
+
+	sel := aff.selector(types.TTPreCommit2, sector, anySelector{}, time.Now())
+	ok, err = sel.Ok(ctx, types.TTPreCommit2, sector.ProofType, sector, &workerHandle{info: storiface.WorkerInfo{Hostname: "b"}})
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	aff.taskDone(sector.ID, types.TTFinalize, "a")
+	require.Empty(t, store)
+}
+
+type countingIndex struct {
+	*stores.Index
+	finds int
+}
+
+func (i *countingIndex) StorageFindSector(ctx context.Context, s abi.SectorID, ft storiface.SectorFileType, ssize abi.SectorSize, allowFetch bool) ([]stores.SectorStorageInfo, error) {
+	i.finds++
+	return i.Index.StorageFindSector(ctx, s, ft, ssize, allowFetch)
+}
+
+type countingPathsWorker struct {
+	*schedTestWorker
+	calls int
+}
+
+func (w *countingPathsWorker) Paths(ctx context.Context) ([]stores.StoragePath, error) {
+	w.calls++
+	return w.schedTestWorker.Paths(ctx)
+}
+
+func TestAffinitySelectorPrepare(t *testing.T) {
+	ctx := context.Background()
+
+	index := &countingIndex{Index: stores.NewIndex()}
+	require.NoError(t, index.StorageAttach(ctx, stores.StorageInfo{ID: "a", URLs: []string{"http://localhost/remote"}, CanSeal: true}, fsutil.FsStat{}))
+
+	sector := storage.SectorRef{
+		ID:        abi.SectorID{Miner: 1000, Number: 1},
+		ProofType: abi.RegisteredSealProof_StackedDrg2KiBV1,
+	}
+	require.NoError(t, index.StorageDeclareSector(ctx, "a", sector.ID, storiface.FTCache, true))
+
+	aff, err := newAffinity([]AffinityRule{{Task: "FIN", Holding: []string{"cache"}, Weight: 1}}, index)
+	require.NoError(t, err)
+
+	rpcA := &countingPathsWorker{schedTestWorker: &schedTestWorker{paths: []stores.StoragePath{{ID: "a"}}}}
+	rpcB := &countingPathsWorker{schedTestWorker: &schedTestWorker{paths: []stores.StoragePath{{ID: "b"}}}}
+	hostA := &workerHandle{workerRpc: rpcA, info: storiface.WorkerInfo{Hostname: "a"}}
+	hostB := &workerHandle{workerRpc: rpcB, info: storiface.WorkerInfo{Hostname: "b"}}
+
+	sel := aff.selector(types.TTFinalize, sector, anySelector{}, time.Now()).(*affinitySelector)
+	require.NoError(t, sel.prepare(ctx, []*workerHandle{hostA, hostB, hostA}))
+
+	for i := 0; i < 3; i++ {
+		better, err := sel.Cmp(ctx, types.TTFinalize, hostA, hostB)
+		require.NoError(t, err)
+		require.True(t, better)
+		better, err = sel.Cmp(ctx, types.TTFinalize, hostB, hostA)
+		require.NoError(t, err)
+		require.False(t, better)
+	}
+
+	// the sector and the worker paths are looked up once before sorting
+	require.Equal(t, 1, index.finds)
+	require.Equal(t, 1, rpcA.calls)
+	require.Equal(t, 1, rpcB.calls)
+}
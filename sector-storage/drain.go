@@ -0,0 +1,327 @@
+package sectorstorage
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/specs-storage/storage"
+
+	"github.com/filecoin-project/venus-sealer/sector-storage/stores"
+	"github.com/filecoin-project/venus-sealer/sector-storage/storiface"
+	"github.com/filecoin-project/venus-sealer/types"
+)
+
+// DrainCheckInterval is how often a drain checks whether the worker has finished its tasks
+var DrainCheckInterval = 5 * time.Second
+
+// SectorProofFunc returns the seal proof type of a sector, the scheduler only
+// knows sector IDs for files found in the index
+type SectorProofFunc func(ctx context.Context, sid abi.SectorID) (abi.RegisteredSealProof, error)
+
+type workerDrain struct {
+	status storiface.WorkerDrain
+	cancel context.CancelFunc
+}
+
+func (d *workerDrain) active() bool {
+	return d.status.State == storiface.DrainWaiting || d.status.State == storiface.DrainEvacuating
+}
+
+// DrainWorker disables the worker in the scheduler, waits for its tasks to
+// finish and then moves sector files which are only stored on the worker to
+// other workers. Progress is reported by DrainStatus.
+func (m *Manager) DrainWorker(ctx context.Context, wid uuid.UUID, proofType SectorProofFunc) error {
+	m.sched.workersLk.Lock()
+	whnd, ok := m.sched.workers[WorkerID(wid)]
+	if !ok {
+		m.sched.workersLk.Unlock()
+		return xerrors.Errorf("worker %s not found", wid)
+	}
+	whnd.draining = true
+	hostname := whnd.info.Hostname
+	m.sched.workersLk.Unlock()
+
+	m.drainLk.Lock()
+	defer m.drainLk.Unlock()
+
+	if d, ok := m.drains[WorkerID(wid)]; ok && d.active() {
+		return nil
+	}
+
+	dctx, cancel := context.WithCancel(context.TODO())
+	d := &workerDrain{
+		status: storiface.WorkerDrain{
+			Hostname: hostname,
+			State:    storiface.DrainWaiting,
+			Started:  time.Now(),
+		},
+		cancel: cancel,
+	}
+	m.drains[WorkerID(wid)] = d
+
+	go m.drainWorker(dctx, WorkerID(wid), d, proofType)
+
+	return nil
+}
+
+// CancelDrain stops draining the worker and enables it again
+func (m *Manager) CancelDrain(ctx context.Context, wid uuid.UUID) error {
+	m.sched.workersLk.Lock()
+	whnd, ok := m.sched.workers[WorkerID(wid)]
+	if ok {
+		whnd.draining = false
+	}
+	m.sched.workersLk.Unlock()
+
+	m.drainLk.Lock()
+	d, found := m.drains[WorkerID(wid)]
+	if found {
+		d.cancel()
+	}
+	m.drainLk.Unlock()
+
+	if !ok && !found {
+		return xerrors.Errorf("worker %s not found", wid)
+	}
+
+	return nil
+}
+
+func (m *Manager) DrainStatus() map[uuid.UUID]storiface.WorkerDrain {
+	m.drainLk.Lock()
+	defer m.drainLk.Unlock()
+
+	out := map[uuid.UUID]storiface.WorkerDrain{}
+	for wid, d := range m.drains {
+		st := d.status
+		if len(d.status.Failed) > 0 {
+			st.Failed = map[abi.SectorNumber]string{}
+			for n, e := range d.status.Failed {
+				st.Failed[n] = e
+			}
+		}
+		out[uuid.UUID(wid)] = st
+	}
+
+	return out
+}
+
+func (m *Manager) updateDrain(d *workerDrain, cb func(*storiface.WorkerDrain)) {
+	m.drainLk.Lock()
+	defer m.drainLk.Unlock()
+
+	cb(&d.status)
+}
+
+func (m *Manager) drainWorker(ctx context.Context, wid WorkerID, d *workerDrain, proofType SectorProofFunc) {
+	err := m.waitWorkerIdle(ctx, wid, d)
+	if err == nil {
+		m.updateDrain(d, func(st *storiface.WorkerDrain) {
+			st.State = storiface.DrainEvacuating
+		})
+
+		err = m.evacuateWorker(ctx, wid, d, proofType)
+	}
+
+	m.updateDrain(d, func(st *storiface.WorkerDrain) {
+		switch {
+		case ctx.Err() != nil:
+			st.State = storiface.DrainCancelled
+		case err != nil:
+			st.State = storiface.DrainFailed
+			st.Error = err.Error()
+		default:
+			st.State = storiface.DrainDone
+		}
+	})
+
+	if err != nil {
+		log.Errorw("draining worker", "worker", wid, "error", err)
+		return
+	}
+
+	log.Infow("worker drained", "worker", wid)
+}
+
+// waitWorkerIdle waits until the worker is disabled and has no tasks assigned
+func (m *Manager) waitWorkerIdle(ctx context.Context, wid WorkerID, d *workerDrain) error {
+	for {
+		running, idle, err := m.workerTasks(wid)
+		if err != nil {
+			return err
+		}
+
+		m.updateDrain(d, func(st *storiface.WorkerDrain) {
+			st.RunningTasks = running
+		})
+
+		if idle {
+			return nil
+		}
+
+		select {
+		case <-time.After(DrainCheckInterval):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (m *Manager) workerTasks(wid WorkerID) (running int, idle bool, err error) {
+	m.sched.workersLk.RLock()
+	defer m.sched.workersLk.RUnlock()
+
+	whnd, ok := m.sched.workers[wid]
+	if !ok {
+		return 0, false, xerrors.Errorf("worker %s disconnected", wid)
+	}
+
+	whnd.lk.Lock()
+	for _, n := range whnd.preparing.taskCounts {
+		running += int(n)
+	}
+	for _, n := range whnd.active.taskCounts {
+		running += int(n)
+	}
+	whnd.lk.Unlock()
+
+	whnd.wndLk.Lock()
+	var assigned int
+	for _, window := range whnd.activeWindows {
+		assigned += len(window.todo)
+	}
+	whnd.wndLk.Unlock()
+
+	// the worker returns assigned tasks to the scheduler once it sees it's draining
+	return running, !whnd.enabled && running == 0 && assigned == 0, nil
+}
+
+type drainSector struct {
+	ft    storiface.SectorFileType
+	ptype storiface.PathType
+}
+
+func (m *Manager) evacuateWorker(ctx context.Context, wid WorkerID, d *workerDrain, proofType SectorProofFunc) error {
+	m.sched.workersLk.RLock()
+	whnd, ok := m.sched.workers[wid]
+	m.sched.workersLk.RUnlock()
+	if !ok {
+		return xerrors.Errorf("worker %s disconnected", wid)
+	}
+
+	paths, err := whnd.workerRpc.Paths(ctx)
+	if err != nil {
+		return xerrors.Errorf("getting worker paths: %w", err)
+	}
+
+	canSeal := map[stores.ID]bool{}
+	for _, path := range paths {
+		canSeal[path.ID] = path.CanSeal
+	}
+
+	decls, err := m.index.StorageList(ctx)
+	if err != nil {
+		return xerrors.Errorf("listing sectors: %w", err)
+	}
+
+	toMove := map[abi.SectorID]*drainSector{}
+	for id, sectors := range decls {
+		seal, ok := canSeal[id]
+		if !ok {
+			continue
+		}
+
+		for _, decl := range sectors {
+			for _, ft := range storiface.PathTypes {
+				if !decl.SectorFileType.Has(ft) {
+					continue
+				}
+
+				found, err := m.index.StorageFindSector(ctx, decl.SectorID, ft, 0, false)
+				if err != nil {
+					return xerrors.Errorf("finding sector %d %s: %w", decl.Number, ft, err)
+				}
+
+				only := true
+				for _, info := range found {
+					if _, ok := canSeal[info.ID]; !ok {
+						only = false
+						break
+					}
+				}
+				if !only {
+					continue
+				}
+
+				ds, ok := toMove[decl.SectorID]
+				if !ok {
+					ds = &drainSector{ptype: storiface.PathStorage}
+					toMove[decl.SectorID] = ds
+				}
+				ds.ft |= ft
+				if seal {
+					// still being sealed, keep it in sealing storage
+					ds.ptype = storiface.PathSealing
+				}
+			}
+		}
+	}
+
+	m.updateDrain(d, func(st *storiface.WorkerDrain) {
+		st.Sectors = len(toMove)
+	})
+
+	for sid, ds := range toMove {
+		err := m.evacuateSector(ctx, sid, ds, proofType)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		m.updateDrain(d, func(st *storiface.WorkerDrain) {
+			if err != nil {
+				if st.Failed == nil {
+					st.Failed = map[abi.SectorNumber]string{}
+				}
+				st.Failed[sid.Number] = err.Error()
+				return
+			}
+			st.Evacuated++
+		})
+		if err != nil {
+			log.Errorw("evacuating sector", "worker", wid, "sector", sid, "error", err)
+		}
+	}
+
+	return nil
+}
+
+func (m *Manager) evacuateSector(ctx context.Context, sid abi.SectorID, ds *drainSector, proofType SectorProofFunc) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	spt, err := proofType(ctx, sid)
+	if err != nil {
+		return xerrors.Errorf("getting sector proof type: %w", err)
+	}
+	sector := storage.SectorRef{ID: sid, ProofType: spt}
+
+	if err := m.index.StorageLock(ctx, sid, storiface.FTNone, ds.ft); err != nil {
+		return xerrors.Errorf("acquiring sector lock: %w", err)
+	}
+
+	// the drained worker is disabled, so the files are moved to another worker
+	selector := newAllocSelector(m.index, ds.ft, ds.ptype)
+
+	err = m.sched.Schedule(ctx, sector, types.TTFetch, selector,
+		m.schedFetch(sector, ds.ft, ds.ptype, storiface.AcquireMove),
+		schedNop)
+	if err != nil {
+		return xerrors.Errorf("moving %s: %w", ds.ft, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,67 @@
+package sectorstorage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/venus-sealer/sector-storage/storiface"
+	"github.com/filecoin-project/venus-sealer/types"
+)
+
+func TestDrainWaitsForTasks(t *testing.T) {
+	DrainCheckInterval = 10 * time.Millisecond
+
+	ctx := context.Background()
+	m := &Manager{
+		sched:  newScheduler(),
+		drains: map[WorkerID]*workerDrain{},
+	}
+
+	wid := uuid.New()
+	whnd := &workerHandle{
+		info:      storiface.WorkerInfo{Hostname: "w"},
+		preparing: &activeResources{},
+		active:    &activeResources{taskCounts: map[types.TaskType]int64{types.TTPreCommit2: 1}},
+		enabled:   true,
+	}
+	m.sched.workers[WorkerID(wid)] = whnd
+
+	require.Error(t, m.DrainWorker(ctx, uuid.New(), nil))
+	require.NoError(t, m.DrainWorker(ctx, wid, func(context.Context, abi.SectorID) (abi.RegisteredSealProof, error) {
+		return abi.RegisteredSealProof_StackedDrg2KiBV1, nil
+	}))
+
+	m.sched.workersLk.RLock()
+	require.True(t, whnd.draining)
+	m.sched.workersLk.RUnlock()
+
+	require.Eventually(t, func() bool {
+		return m.DrainStatus()[wid].RunningTasks == 1
+	}, time.Second, 10*time.Millisecond)
+
+	// the worker still runs a task, so it isn't idle even once disabled
+	m.sched.workersLk.Lock()
+	whnd.enabled = false
+	m.sched.workersLk.Unlock()
+
+	running, idle, err := m.workerTasks(WorkerID(wid))
+	require.NoError(t, err)
+	require.Equal(t, 1, running)
+	require.False(t, idle)
+
+	require.NoError(t, m.CancelDrain(ctx, wid))
+	require.Eventually(t, func() bool {
+		return m.DrainStatus()[wid].State == storiface.DrainCancelled
+	}, time.Second, 10*time.Millisecond)
+
+	m.sched.workersLk.RLock()
+	require.False(t, whnd.draining)
+	m.sched.workersLk.RUnlock()
+	require.False(t, m.DrainStatus()[wid].SafeToPowerOff())
+}
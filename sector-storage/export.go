@@ -0,0 +1,530 @@
+package sectorstorage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/specs-storage/storage"
+
+	"github.com/filecoin-project/venus-sealer/sector-storage/stores"
+	"github.com/filecoin-project/venus-sealer/sector-storage/storiface"
+)
+
+const (
+	exportSealed = "sealed"
+	exportCache  = "cache"
+)
+
+// ExportSector copies the sealed file and the cache files of the sector from a
+// local storage path, with their checksums and the sector metadata, to a new
+// directory named after the sector in dir, or under the prefix named after the
+// sector in an object store when dir is an object store url, see
+// stores.ParseObjectStoreURL. The files have to be in a local storage path of
+// the sealer. The exported files are removed again when the export fails.
+func (m *Manager) ExportSector(ctx context.Context, sector storage.SectorRef, commr cid.Cid, info json.RawMessage, dir string) (*storiface.SectorExport, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	if err := m.index.StorageLock(ctx, sector.ID, storiface.FTSealed|storiface.FTCache, storiface.FTNone); err != nil {
+		return nil, xerrors.Errorf("acquiring sector lock: %w", err)
+	}
+
+	lp, _, err := m.localStore.AcquireSector(ctx, sector, storiface.FTSealed|storiface.FTCache, storiface.FTNone, storiface.PathStorage, storiface.AcquireMove)
+	if err != nil {
+		return nil, xerrors.Errorf("acquire sector failed: %w", err)
+	}
+	if lp.Sealed == "" || lp.Cache == "" {
+		return nil, xerrors.Errorf("sealed and cache files of sector %d aren't in a local storage path", sector.ID.Number)
+	}
+
+	var target exportTarget
+	if strings.Contains(dir, "://") {
+		target, err = newObjectExport(ctx, dir, storiface.SectorName(sector.ID))
+	} else {
+		target, err = newDirExport(filepath.Join(dir, storiface.SectorName(sector.ID)))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	exp := &storiface.SectorExport{
+		Sector:    sector.ID,
+		ProofType: sector.ProofType,
+		CommR:     commr,
+		Info:      info,
+	}
+
+	if err := exportSectorFiles(ctx, sector, lp, target, exp); err != nil {
+		target.abort()
+		return nil, err
+	}
+
+	return exp, nil
+}
+
+func exportSectorFiles(ctx context.Context, sector storage.SectorRef, lp storiface.SectorPaths, target exportTarget, exp *storiface.SectorExport) error {
+	f, err := target.put(ctx, lp.Sealed, exportSealed)
+	if err != nil {
+		return err
+	}
+	exp.Files = append(exp.Files, f)
+
+	cacheFiles, err := ioutil.ReadDir(lp.Cache)
+	if err != nil {
+		return xerrors.Errorf("reading cache directory: %w", err)
+	}
+	for _, fi := range cacheFiles {
+		if fi.IsDir() {
+			log.Warnw("not exporting directory in sector cache", "sector", sector.ID, "dir", fi.Name())
+			continue
+		}
+
+		f, err := target.put(ctx, filepath.Join(lp.Cache, fi.Name()), filepath.Join(exportCache, fi.Name()))
+		if err != nil {
+			return err
+		}
+		exp.Files = append(exp.Files, f)
+	}
+
+	exp.Exported = time.Now()
+	return target.finish(ctx, exp)
+}
+
+// exportTarget receives the files of an exported sector
+type exportTarget interface {
+	// put copies the file at src to name in the export
+	put(ctx context.Context, src, name string) (storiface.ExportedFile, error)
+	// finish writes the export metadata, which marks a complete export
+	finish(ctx context.Context, exp *storiface.SectorExport) error
+	// abort removes the files of a failed export
+	abort()
+}
+
+// dirExport exports a sector to a local directory of the sealer host
+type dirExport struct {
+	dir string
+}
+
+func newDirExport(dir string) (*dirExport, error) {
+	if err := os.Mkdir(dir, 0755); err != nil {
+		return nil, xerrors.Errorf("creating export directory: %w", err)
+	}
+
+	d := &dirExport{dir: dir}
+	if err := os.Mkdir(filepath.Join(dir, exportCache), 0755); err != nil {
+		d.abort()
+		return nil, xerrors.Errorf("creating export cache directory: %w", err)
+	}
+	return d, nil
+}
+
+func (d *dirExport) put(ctx context.Context, src, name string) (storiface.ExportedFile, error) {
+	return copySectorFile(ctx, src, d.dir, name)
+}
+
+func (d *dirExport) finish(ctx context.Context, exp *storiface.SectorExport) error {
+	return writeSectorExport(d.dir, exp)
+}
+
+func (d *dirExport) abort() {
+	if err := os.RemoveAll(d.dir); err != nil {
+		log.Errorw("removing failed sector export", "dir", d.dir, "error", err)
+	}
+}
+
+// objectExport exports a sector to the objects under a prefix in an object
+// store
+type objectExport struct {
+	objs   stores.ObjectStore
+	prefix string
+
+	// keys are the objects uploaded so far
+	keys []string
+}
+
+func newObjectExport(ctx context.Context, url string, name string) (*objectExport, error) {
+	cfg, err := stores.ParseObjectStoreURL(url)
+	if err != nil {
+		return nil, err
+	}
+	objs, err := stores.NewObjectStore(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	o := &objectExport{objs: objs, prefix: name}
+	_, err = objs.Stat(ctx, o.key(storiface.SectorExportMeta))
+	switch {
+	case err == nil:
+		return nil, xerrors.Errorf("sector is already exported to %s", url)
+	case !errors.Is(err, os.ErrNotExist):
+		return nil, xerrors.Errorf("checking for an existing export: %w", err)
+	}
+
+	return o, nil
+}
+
+func (o *objectExport) key(name string) string {
+	return path.Join(o.prefix, filepath.ToSlash(name))
+}
+
+// put checksums the local file before uploading it, the sector is locked so
+// it doesn't change in between
+func (o *objectExport) put(ctx context.Context, src, name string) (storiface.ExportedFile, error) {
+	f, err := hashSectorFile(ctx, src, name)
+	if err != nil {
+		return storiface.ExportedFile{}, err
+	}
+
+	key := o.key(name)
+	o.keys = append(o.keys, key)
+	if err := o.objs.Put(ctx, key, src); err != nil {
+		return storiface.ExportedFile{}, xerrors.Errorf("uploading %s: %w", src, err)
+	}
+
+	return f, nil
+}
+
+func (o *objectExport) finish(ctx context.Context, exp *storiface.SectorExport) error {
+	tmp, err := ioutil.TempDir("", "sector-export-")
+	if err != nil {
+		return xerrors.Errorf("creating temporary directory: %w", err)
+	}
+	defer os.RemoveAll(tmp) // nolint
+
+	if err := writeSectorExport(tmp, exp); err != nil {
+		return err
+	}
+
+	// uploaded last, its presence marks a complete export
+	key := o.key(storiface.SectorExportMeta)
+	o.keys = append(o.keys, key)
+	if err := o.objs.Put(ctx, key, filepath.Join(tmp, storiface.SectorExportMeta)); err != nil {
+		return xerrors.Errorf("uploading export metadata: %w", err)
+	}
+	return nil
+}
+
+// abort isn't bound to the context of the export, which may be why it failed
+func (o *objectExport) abort() {
+	for _, key := range o.keys {
+		if err := o.objs.Delete(context.Background(), key); err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Errorw("removing object of failed sector export", "key", key, "error", err)
+		}
+	}
+}
+
+// exportSource reads the files of an exported sector
+type exportSource interface {
+	// meta reads the export metadata
+	meta(ctx context.Context) (*storiface.SectorExport, error)
+	// get copies the file name of the export to dst, returning its size and
+	// checksum
+	get(ctx context.Context, name, dst string) (storiface.ExportedFile, error)
+}
+
+// openSectorExport opens the export of a sector in the directory from, or
+// under the prefix of the object store url from, see stores.ParseObjectStoreURL
+func openSectorExport(from string) (exportSource, error) {
+	if !strings.Contains(from, "://") {
+		return &dirSource{dir: from}, nil
+	}
+
+	cfg, err := stores.ParseObjectStoreURL(from)
+	if err != nil {
+		return nil, err
+	}
+	objs, err := stores.NewObjectStore(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &objectSource{objs: objs}, nil
+}
+
+// dirSource reads a sector exported to a local directory of the sealer host
+type dirSource struct {
+	dir string
+}
+
+func (d *dirSource) meta(ctx context.Context) (*storiface.SectorExport, error) {
+	b, err := ioutil.ReadFile(filepath.Join(d.dir, storiface.SectorExportMeta))
+	if err != nil {
+		return nil, xerrors.Errorf("reading export metadata: %w", err)
+	}
+	return decodeSectorExport(b)
+}
+
+func (d *dirSource) get(ctx context.Context, name, dst string) (storiface.ExportedFile, error) {
+	return copySectorFile(ctx, filepath.Join(d.dir, name), filepath.Dir(dst), filepath.Base(dst))
+}
+
+// objectSource reads a sector exported to an object store
+type objectSource struct {
+	objs   stores.ObjectStore
+	prefix string
+}
+
+func (o *objectSource) key(name string) string {
+	return path.Join(o.prefix, filepath.ToSlash(name))
+}
+
+func (o *objectSource) open(ctx context.Context, name string) (io.ReadCloser, error) {
+	key := o.key(name)
+	obj, err := o.objs.Stat(ctx, key)
+	if err != nil {
+		return nil, xerrors.Errorf("stat %s: %w", key, err)
+	}
+	r, err := o.objs.GetRange(ctx, key, 0, obj.Size)
+	if err != nil {
+		return nil, xerrors.Errorf("downloading %s: %w", key, err)
+	}
+	return r, nil
+}
+
+func (o *objectSource) meta(ctx context.Context) (*storiface.SectorExport, error) {
+	r, err := o.open(ctx, storiface.SectorExportMeta)
+	if err != nil {
+		return nil, xerrors.Errorf("reading export metadata: %w", err)
+	}
+	defer r.Close() // nolint
+
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, xerrors.Errorf("reading export metadata: %w", err)
+	}
+	return decodeSectorExport(b)
+}
+
+func (o *objectSource) get(ctx context.Context, name, dst string) (storiface.ExportedFile, error) {
+	r, err := o.open(ctx, name)
+	if err != nil {
+		return storiface.ExportedFile{}, err
+	}
+	defer r.Close() // nolint
+
+	return writeSectorFile(ctx, r, o.key(name), filepath.Dir(dst), filepath.Base(dst))
+}
+
+func decodeSectorExport(b []byte) (*storiface.SectorExport, error) {
+	var exp storiface.SectorExport
+	if err := json.Unmarshal(b, &exp); err != nil {
+		return nil, xerrors.Errorf("decoding export metadata: %w", err)
+	}
+	return &exp, nil
+}
+
+// ReadSectorExport reads the metadata of a sector exported to the directory,
+// or object store url, from
+func ReadSectorExport(ctx context.Context, from string) (*storiface.SectorExport, error) {
+	src, err := openSectorExport(from)
+	if err != nil {
+		return nil, err
+	}
+	return src.meta(ctx)
+}
+
+// ImportSector copies a sector exported to the directory, or object store url,
+// from into the best local storage path, checking the files against their
+// checksums, then declares it in the index. The sector is removed again if it
+// isn't provable.
+func (m *Manager) ImportSector(ctx context.Context, from string) (*storiface.SectorExport, error) {
+	src, err := openSectorExport(from)
+	if err != nil {
+		return nil, err
+	}
+	exp, err := src.meta(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sector := storage.SectorRef{
+		ID:        exp.Sector,
+		ProofType: exp.ProofType,
+	}
+
+	if err := m.importSectorFiles(ctx, sector, src, exp); err != nil {
+		return nil, err
+	}
+
+	wpp, err := sector.ProofType.RegisteredWindowPoStProof()
+	if err != nil {
+		return nil, err
+	}
+	bad, err := m.CheckProvable(ctx, wpp, []storage.SectorRef{sector}, func(ctx context.Context, id abi.SectorID) (cid.Cid, error) {
+		return exp.CommR, nil
+	})
+	if err != nil {
+		return nil, xerrors.Errorf("checking imported sector: %w", err)
+	}
+	if reason, ok := bad[sector.ID]; ok {
+		if err := m.Remove(ctx, sector); err != nil {
+			log.Errorw("removing unprovable imported sector", "sector", sector.ID, "error", err)
+		}
+		return nil, xerrors.Errorf("imported sector %d isn't provable: %s", sector.ID.Number, reason)
+	}
+
+	return exp, nil
+}
+
+func (m *Manager) importSectorFiles(ctx context.Context, sector storage.SectorRef, src exportSource, exp *storiface.SectorExport) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	if err := m.index.StorageLock(ctx, sector.ID, storiface.FTNone, storiface.FTSealed|storiface.FTCache); err != nil {
+		return xerrors.Errorf("acquiring sector lock: %w", err)
+	}
+
+	existing, err := m.index.StorageFindSector(ctx, sector.ID, storiface.FTSealed|storiface.FTCache, 0, false)
+	if err != nil {
+		return xerrors.Errorf("finding existing sector files: %w", err)
+	}
+	if len(existing) > 0 {
+		return xerrors.Errorf("sector %d is already stored in %s", sector.ID.Number, existing[0].ID)
+	}
+
+	lp, ids, err := m.localStore.AcquireSector(ctx, sector, storiface.FTNone, storiface.FTSealed|storiface.FTCache, storiface.PathStorage, storiface.AcquireMove)
+	if err != nil {
+		return xerrors.Errorf("allocating sector paths: %w", err)
+	}
+
+	err = func() error {
+		if err := os.MkdirAll(lp.Cache, 0755); err != nil {
+			return xerrors.Errorf("creating cache directory: %w", err)
+		}
+
+		for _, f := range exp.Files {
+			var dst string
+			switch {
+			case f.Path == exportSealed:
+				dst = lp.Sealed
+			case filepath.Dir(f.Path) == exportCache:
+				dst = filepath.Join(lp.Cache, filepath.Base(f.Path))
+			default:
+				return xerrors.Errorf("unexpected file %s in export", f.Path)
+			}
+
+			got, err := src.get(ctx, f.Path, dst)
+			if err != nil {
+				return err
+			}
+			if got.Size != f.Size || got.SHA256 != f.SHA256 {
+				return xerrors.Errorf("%s doesn't match its checksum: expected %d bytes with sha256 %s, got %d bytes with sha256 %s", f.Path, f.Size, f.SHA256, got.Size, got.SHA256)
+			}
+		}
+
+		return nil
+	}()
+	if err != nil {
+		_ = os.Remove(lp.Sealed)
+		_ = os.RemoveAll(lp.Cache)
+		return err
+	}
+
+	if err := m.localStore.DeclareSector(ctx, stores.ID(ids.Sealed), sector.ID, storiface.FTSealed, true); err != nil {
+		return xerrors.Errorf("declaring sealed file: %w", err)
+	}
+	if err := m.localStore.DeclareSector(ctx, stores.ID(ids.Cache), sector.ID, storiface.FTCache, true); err != nil {
+		return xerrors.Errorf("declaring cache files: %w", err)
+	}
+
+	return nil
+}
+
+// copySectorFile copies src to name in dir, returning its size and checksum
+func copySectorFile(ctx context.Context, src, dir, name string) (storiface.ExportedFile, error) {
+	if err := ctx.Err(); err != nil {
+		return storiface.ExportedFile{}, err
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return storiface.ExportedFile{}, xerrors.Errorf("opening %s: %w", src, err)
+	}
+	defer in.Close() // nolint
+
+	return writeSectorFile(ctx, in, src, dir, name)
+}
+
+// writeSectorFile writes in, read from src, to name in dir, returning its size
+// and checksum
+func writeSectorFile(ctx context.Context, in io.Reader, src, dir, name string) (storiface.ExportedFile, error) {
+	if err := ctx.Err(); err != nil {
+		return storiface.ExportedFile{}, err
+	}
+
+	dst := filepath.Join(dir, name)
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return storiface.ExportedFile{}, xerrors.Errorf("creating %s: %w", dst, err)
+	}
+	defer out.Close() // nolint
+
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(out, h), in)
+	if err != nil {
+		return storiface.ExportedFile{}, xerrors.Errorf("copying %s to %s: %w", src, dst, err)
+	}
+	if err := out.Sync(); err != nil {
+		return storiface.ExportedFile{}, xerrors.Errorf("syncing %s: %w", dst, err)
+	}
+
+	return storiface.ExportedFile{
+		Path:   name,
+		Size:   n,
+		SHA256: hex.EncodeToString(h.Sum(nil)),
+	}, nil
+}
+
+// hashSectorFile returns the size and checksum of the file at src, exported
+// as name
+func hashSectorFile(ctx context.Context, src, name string) (storiface.ExportedFile, error) {
+	if err := ctx.Err(); err != nil {
+		return storiface.ExportedFile{}, err
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return storiface.ExportedFile{}, xerrors.Errorf("opening %s: %w", src, err)
+	}
+	defer in.Close() // nolint
+
+	h := sha256.New()
+	n, err := io.Copy(h, in)
+	if err != nil {
+		return storiface.ExportedFile{}, xerrors.Errorf("reading %s: %w", src, err)
+	}
+
+	return storiface.ExportedFile{
+		Path:   name,
+		Size:   n,
+		SHA256: hex.EncodeToString(h.Sum(nil)),
+	}, nil
+}
+
+func writeSectorExport(dir string, exp *storiface.SectorExport) error {
+	b, err := json.MarshalIndent(exp, "", "  ")
+	if err != nil {
+		return xerrors.Errorf("encoding export metadata: %w", err)
+	}
+
+	// write the metadata last and atomically, its presence marks a complete export
+	tmp := filepath.Join(dir, storiface.SectorExportMeta+".tmp")
+	if err := ioutil.WriteFile(tmp, b, 0644); err != nil {
+		return xerrors.Errorf("writing export metadata: %w", err)
+	}
+	return os.Rename(tmp, filepath.Join(dir, storiface.SectorExportMeta))
+}
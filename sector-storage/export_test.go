@@ -0,0 +1,176 @@
+package sectorstorage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/specs-storage/storage"
+
+	"github.com/filecoin-project/venus-sealer/sector-storage/stores"
+	"github.com/filecoin-project/venus-sealer/sector-storage/storiface"
+)
+
+func TestSectorExportFiles(t *testing.T) {
+	ctx := context.Background()
+	src, dst := t.TempDir(), t.TempDir()
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(src, "p_aux"), []byte("p_aux data"), 0644))
+
+	f, err := copySectorFile(ctx, filepath.Join(src, "p_aux"), dst, "p_aux")
+	require.NoError(t, err)
+	require.Equal(t, int64(10), f.Size)
+	// sha256 of "p_aux data"
+	require.Equal(t, "0b0d660627523e4c333ec260fe6ac020e277729720a4833fe9b1e582ff019630", f.SHA256)
+
+	b, err := ioutil.ReadFile(filepath.Join(dst, "p_aux"))
+	require.NoError(t, err)
+	require.Equal(t, "p_aux data", string(b))
+
+	// existing files are never overwritten
+	_, err = copySectorFile(ctx, filepath.Join(src, "p_aux"), dst, "p_aux")
+	require.True(t, errors.Is(err, os.ErrExist))
+
+	exp := &storiface.SectorExport{
+		Sector:    abi.SectorID{Miner: 1000, Number: 1},
+		ProofType: abi.RegisteredSealProof_StackedDrg2KiBV1_1,
+		Info:      []byte(`{"SectorNumber":1}`),
+		Files:     []storiface.ExportedFile{f},
+	}
+	require.NoError(t, writeSectorExport(dst, exp))
+
+	read, err := ReadSectorExport(ctx, dst)
+	require.NoError(t, err)
+	require.Equal(t, exp.Sector, read.Sector)
+	require.Equal(t, exp.Files, read.Files)
+	require.JSONEq(t, string(exp.Info), string(read.Info))
+}
+
+// memObjects is an in-memory stores.ObjectStore
+type memObjects struct {
+	objects map[string][]byte
+	failPut string
+}
+
+func (m *memObjects) Put(ctx context.Context, key string, path string) error {
+	if key == m.failPut {
+		return xerrors.New("upload failed")
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	m.objects[key] = b
+	return nil
+}
+
+func (m *memObjects) GetRange(ctx context.Context, key string, offset, size int64) (io.ReadCloser, error) {
+	b, ok := m.objects[key]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	return ioutil.NopCloser(bytes.NewReader(b[offset : offset+size])), nil
+}
+
+func (m *memObjects) Stat(ctx context.Context, key string) (stores.ObjectInfo, error) {
+	b, ok := m.objects[key]
+	if !ok {
+		return stores.ObjectInfo{}, os.ErrNotExist
+	}
+	return stores.ObjectInfo{Key: key, Size: int64(len(b))}, nil
+}
+
+func (m *memObjects) List(ctx context.Context, prefix string) ([]stores.ObjectInfo, error) {
+	var out []stores.ObjectInfo
+	for key, b := range m.objects {
+		if strings.HasPrefix(key, prefix) {
+			out = append(out, stores.ObjectInfo{Key: key, Size: int64(len(b))})
+		}
+	}
+	return out, nil
+}
+
+func (m *memObjects) Delete(ctx context.Context, key string) error {
+	delete(m.objects, key)
+	return nil
+}
+
+func TestSectorExportTargets(t *testing.T) {
+	ctx := context.Background()
+	sector := storage.SectorRef{
+		ID:        abi.SectorID{Miner: 1000, Number: 1},
+		ProofType: abi.RegisteredSealProof_StackedDrg2KiBV1_1,
+	}
+
+	src := t.TempDir()
+	lp := storiface.SectorPaths{
+		Sealed: filepath.Join(src, "sealed"),
+		Cache:  filepath.Join(src, "cache"),
+	}
+	require.NoError(t, ioutil.WriteFile(lp.Sealed, []byte("sealed data"), 0644))
+	require.NoError(t, os.Mkdir(lp.Cache, 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(lp.Cache, "p_aux"), []byte("p_aux data"), 0644))
+
+	t.Run("objects", func(t *testing.T) {
+		objs := &memObjects{objects: map[string][]byte{}}
+		target := &objectExport{objs: objs, prefix: storiface.SectorName(sector.ID)}
+
+		exp := &storiface.SectorExport{Sector: sector.ID, ProofType: sector.ProofType}
+		require.NoError(t, exportSectorFiles(ctx, sector, lp, target, exp))
+		require.Len(t, exp.Files, 2)
+		require.Equal(t, "0b0d660627523e4c333ec260fe6ac020e277729720a4833fe9b1e582ff019630", exp.Files[1].SHA256)
+
+		require.Equal(t, []byte("sealed data"), objs.objects["s-t01000-1/sealed"])
+		require.Equal(t, []byte("p_aux data"), objs.objects["s-t01000-1/cache/p_aux"])
+		require.Contains(t, objs.objects, "s-t01000-1/"+storiface.SectorExportMeta)
+
+		// read back for an import
+		src := &objectSource{objs: objs, prefix: storiface.SectorName(sector.ID)}
+		read, err := src.meta(ctx)
+		require.NoError(t, err)
+		require.Equal(t, exp.Files, read.Files)
+
+		dst := filepath.Join(t.TempDir(), "p_aux")
+		got, err := src.get(ctx, read.Files[1].Path, dst)
+		require.NoError(t, err)
+		require.Equal(t, read.Files[1].SHA256, got.SHA256)
+		b, err := ioutil.ReadFile(dst)
+		require.NoError(t, err)
+		require.Equal(t, "p_aux data", string(b))
+	})
+
+	t.Run("failed objects", func(t *testing.T) {
+		objs := &memObjects{objects: map[string][]byte{}, failPut: "s-t01000-1/cache/p_aux"}
+		target := &objectExport{objs: objs, prefix: storiface.SectorName(sector.ID)}
+
+		exp := &storiface.SectorExport{Sector: sector.ID, ProofType: sector.ProofType}
+		require.Error(t, exportSectorFiles(ctx, sector, lp, target, exp))
+		target.abort()
+		require.Empty(t, objs.objects)
+	})
+
+	t.Run("failed dir", func(t *testing.T) {
+		dir := filepath.Join(t.TempDir(), storiface.SectorName(sector.ID))
+		target, err := newDirExport(dir)
+		require.NoError(t, err)
+
+		// a cache file already in the export fails it
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, exportCache, "p_aux"), nil, 0644))
+
+		exp := &storiface.SectorExport{Sector: sector.ID, ProofType: sector.ProofType}
+		require.Error(t, exportSectorFiles(ctx, sector, lp, target, exp))
+		target.abort()
+		_, err = os.Stat(dir)
+		require.True(t, os.IsNotExist(err))
+	})
+}
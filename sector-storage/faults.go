@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"golang.org/x/xerrors"
 
@@ -14,6 +15,7 @@ import (
 	"github.com/filecoin-project/specs-actors/actors/runtime/proof"
 	"github.com/filecoin-project/specs-storage/storage"
 
+	"github.com/filecoin-project/venus-sealer/sector-storage/stores"
 	"github.com/filecoin-project/venus-sealer/sector-storage/storiface"
 )
 
@@ -48,12 +50,46 @@ func (m *Manager) CheckProvable(ctx context.Context, pp abi.RegisteredPoStProof,
 				return nil
 			}
 
-			lp, _, err := m.localStore.AcquireSector(ctx, sector, storiface.FTSealed|storiface.FTCache, storiface.FTNone, storiface.PathStorage, storiface.AcquireMove)
+			// reading from failing paths would likely make the proof time out
+			if failing := m.failingStorage(ctx, sector.ID); failing != "" {
+				log.Warnw("CheckProvable Sector FAULT: stored in failing storage", "sector", sector, "reason", failing)
+				bad[sector.ID] = failing
+				return nil
+			}
+
+			wpp, err := sector.ProofType.RegisteredWindowPoStProof()
+			if err != nil {
+				return err
+			}
+
+			// challenges are only needed for the vanilla proof, without them
+			// nothing of sealed files in object stores is read
+			var challenges []uint64
+			if rg != nil {
+				var pr abi.PoStRandomness = make([]byte, abi.RandomnessLength)
+				_, _ = rand.Read(pr)
+				pr[31] &= 0x3f
+
+				ch, err := ffi.GeneratePoStFallbackSectorChallenges(wpp, sector.ID.Miner, pr, []abi.SectorNumber{
+					sector.ID.Number,
+				})
+				if err != nil {
+					log.Warnw("CheckProvable Sector FAULT: generating challenges", "sector", sector, "err", err)
+					bad[sector.ID] = fmt.Sprintf("generating fallback challenges: %s", err)
+					return nil
+				}
+				challenges = ch.Challenges[sector.ID.Number]
+			}
+
+			// sealed files in object stores are only read at the challenged leaves
+			lp, done, err := m.localStore.AcquireSectorForPoSt(ctx, sector, challenges)
 			if err != nil {
+				m.localStore.FileError(err)
 				log.Warnw("CheckProvable Sector FAULT: acquire sector in checkProvable", "sector", sector, "error", err)
 				bad[sector.ID] = fmt.Sprintf("acquire sector failed: %s", err)
 				return nil
 			}
+			defer done()
 
 			if lp.Sealed == "" || lp.Cache == "" {
 				log.Warnw("CheckProvable Sector FAULT: cache and/or sealed paths not found", "sector", sector, "sealed", lp.Sealed, "cache", lp.Cache)
@@ -71,6 +107,7 @@ func (m *Manager) CheckProvable(ctx context.Context, pp abi.RegisteredPoStProof,
 			for p, sz := range toCheck {
 				st, err := os.Stat(p)
 				if err != nil {
+					m.localStore.FileError(err)
 					log.Warnw("CheckProvable Sector FAULT: sector file stat error", "sector", sector, "sealed", lp.Sealed, "cache", lp.Cache, "file", p, "err", err)
 					bad[sector.ID] = fmt.Sprintf("%s", err)
 					return nil
@@ -86,24 +123,6 @@ func (m *Manager) CheckProvable(ctx context.Context, pp abi.RegisteredPoStProof,
 			}
 
 			if rg != nil {
-				wpp, err := sector.ProofType.RegisteredWindowPoStProof()
-				if err != nil {
-					return err
-				}
-
-				var pr abi.PoStRandomness = make([]byte, abi.RandomnessLength)
-				_, _ = rand.Read(pr)
-				pr[31] &= 0x3f
-
-				ch, err := ffi.GeneratePoStFallbackSectorChallenges(wpp, sector.ID.Miner, pr, []abi.SectorNumber{
-					sector.ID.Number,
-				})
-				if err != nil {
-					log.Warnw("CheckProvable Sector FAULT: generating challenges", "sector", sector, "sealed", lp.Sealed, "cache", lp.Cache, "err", err)
-					bad[sector.ID] = fmt.Sprintf("generating fallback challenges: %s", err)
-					return nil
-				}
-
 				commr, err := rg(ctx, sector.ID)
 				if err != nil {
 					log.Warnw("CheckProvable Sector FAULT: getting commR", "sector", sector, "sealed", lp.Sealed, "cache", lp.Cache, "err", err)
@@ -120,7 +139,7 @@ func (m *Manager) CheckProvable(ctx context.Context, pp abi.RegisteredPoStProof,
 					CacheDirPath:     lp.Cache,
 					PoStProofType:    wpp,
 					SealedSectorPath: lp.Sealed,
-				}, ch.Challenges[sector.ID.Number])
+				}, challenges)
 				if err != nil {
 					log.Warnw("CheckProvable Sector FAULT: generating vanilla proof", "sector", sector, "sealed", lp.Sealed, "cache", lp.Cache, "err", err)
 					bad[sector.ID] = fmt.Sprintf("generating vanilla proof: %s", err)
@@ -138,6 +157,78 @@ func (m *Manager) CheckProvable(ctx context.Context, pp abi.RegisteredPoStProof,
 	return bad, nil
 }
 
+// failingStorage returns why the sealed or cache files of the sector can only be
+// read from storage paths which failed FaultyFailingProbes probes in a row, or
+// an empty string when they can be read from another path. Sectors in paths
+// which failed fewer probes, or whose health is unknown, are checked by reading
+// them.
+func (m *Manager) failingStorage(ctx context.Context, sector abi.SectorID) string {
+	for _, ft := range []storiface.SectorFileType{storiface.FTSealed, storiface.FTCache} {
+		si, err := m.index.StorageFindSector(ctx, sector, ft, 0, false)
+		if err != nil {
+			log.Warnw("finding sector files to check storage health", "sector", sector, "type", ft, "error", err)
+			return ""
+		}
+		if len(si) == 0 {
+			// missing files are reported by the file checks
+			continue
+		}
+
+		var reasons []string
+		for _, info := range si {
+			h, err := m.index.StorageHealth(ctx, info.ID)
+			if err != nil {
+				// e.g. the path was just detached, its health is unknown
+				log.Warnw("getting storage health", "sector", sector, "type", ft, "storage", info.ID, "error", err)
+				reasons = nil
+				break
+			}
+
+			if h.Status == stores.HealthDegraded {
+				log.Warnw("sector stored in degraded storage", "sector", sector, "type", ft, "storage", info.ID, "reasons", h.Reasons)
+			}
+			if h.Status == stores.HealthFailing && h.FailingProbes < stores.FaultyFailingProbes {
+				log.Warnw("sector stored in storage failing its probes", "sector", sector, "type", ft, "storage", info.ID, "probes", h.FailingProbes, "reasons", h.Reasons)
+			}
+			if h.Status != stores.HealthFailing || h.FailingProbes < stores.FaultyFailingProbes {
+				reasons = nil
+				break
+			}
+			reasons = append(reasons, fmt.Sprintf("%s: %s", info.ID, strings.Join(h.Reasons, "; ")))
+		}
+
+		if len(reasons) > 0 {
+			return fmt.Sprintf("%s file only stored in failing storage (%s)", ft, strings.Join(reasons, ", "))
+		}
+	}
+
+	return ""
+}
+
+// CheckSectorFiles verifies that the sealed and cache files of a finalized sector exist
+// on disk and that the sealed file has the full sector size
+func CheckSectorFiles(sealed, cache string, ssize abi.SectorSize) error {
+	toCheck := map[string]int64{
+		sealed:                        1,
+		filepath.Join(cache, "p_aux"): 0,
+	}
+
+	addCachePathsForSectorSize(toCheck, cache, ssize)
+
+	for p, sz := range toCheck {
+		st, err := os.Stat(p)
+		if err != nil {
+			return xerrors.Errorf("stat %s: %w", p, err)
+		}
+
+		if sz != 0 && st.Size() != int64(ssize)*sz {
+			return xerrors.Errorf("%s is wrong size (got %d, expect %d)", p, st.Size(), int64(ssize)*sz)
+		}
+	}
+
+	return nil
+}
+
 func addCachePathsForSectorSize(chk map[string]int64, cacheDir string, ssize abi.SectorSize) {
 	switch ssize {
 	case 2 << 10:
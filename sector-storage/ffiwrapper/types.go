@@ -55,4 +55,12 @@ type SectorProvider interface {
 	AcquireSector(ctx context.Context, id storage.SectorRef, existing storiface.SectorFileType, allocate storiface.SectorFileType, ptype storiface.PathType) (storiface.SectorPaths, func(), error)
 }
 
+// PoStSectorProvider is implemented by sector providers which can provide
+// sectors for proving without reading the whole sealed file
+type PoStSectorProvider interface {
+	// AcquireSectorForPoSt returns the sealed and cache paths of the sector,
+	// where the sealed file only has to be readable at the challenged leaves
+	AcquireSectorForPoSt(ctx context.Context, id storage.SectorRef, challenges []uint64) (storiface.SectorPaths, func(), error)
+}
+
 var _ SectorProvider = &basicfs.Provider{}
\ No newline at end of file
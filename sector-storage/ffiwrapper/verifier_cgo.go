@@ -19,7 +19,7 @@ import (
 
 func (sb *Sealer) GenerateWinningPoSt(ctx context.Context, minerID abi.ActorID, sectorInfo []proof5.SectorInfo, randomness abi.PoStRandomness) ([]proof5.PoStProof, error) {
 	randomness[31] &= 0x3f
-	privsectors, skipped, done, err := sb.pubSectorToPriv(ctx, minerID, sectorInfo, nil, abi.RegisteredSealProof.RegisteredWinningPoStProof) // TODO: FAULTS?
+	privsectors, skipped, done, err := sb.pubSectorToPriv(ctx, minerID, sectorInfo, nil, randomness, abi.RegisteredSealProof.RegisteredWinningPoStProof) // TODO: FAULTS?
 	if err != nil {
 		return nil, err
 	}
@@ -33,7 +33,7 @@ func (sb *Sealer) GenerateWinningPoSt(ctx context.Context, minerID abi.ActorID,
 
 func (sb *Sealer) GenerateWindowPoSt(ctx context.Context, minerID abi.ActorID, sectorInfo []proof5.SectorInfo, randomness abi.PoStRandomness) ([]proof5.PoStProof, []abi.SectorID, error) {
 	randomness[31] &= 0x3f
-	privsectors, skipped, done, err := sb.pubSectorToPriv(ctx, minerID, sectorInfo, nil, abi.RegisteredSealProof.RegisteredWindowPoStProof)
+	privsectors, skipped, done, err := sb.pubSectorToPriv(ctx, minerID, sectorInfo, nil, randomness, abi.RegisteredSealProof.RegisteredWindowPoStProof)
 	if err != nil {
 		return nil, nil, xerrors.Errorf("gathering sector info: %w", err)
 	}
@@ -56,7 +56,7 @@ func (sb *Sealer) GenerateWindowPoSt(ctx context.Context, minerID abi.ActorID, s
 	return proof, faultyIDs, err
 }
 
-func (sb *Sealer) pubSectorToPriv(ctx context.Context, mid abi.ActorID, sectorInfo []proof5.SectorInfo, faults []abi.SectorNumber, rpt func(abi.RegisteredSealProof) (abi.RegisteredPoStProof, error)) (ffi.SortedPrivateSectorInfo, []abi.SectorID, func(), error) {
+func (sb *Sealer) pubSectorToPriv(ctx context.Context, mid abi.ActorID, sectorInfo []proof5.SectorInfo, faults []abi.SectorNumber, randomness abi.PoStRandomness, rpt func(abi.RegisteredSealProof) (abi.RegisteredPoStProof, error)) (ffi.SortedPrivateSectorInfo, []abi.SectorID, func(), error) {
 	fmap := map[abi.SectorNumber]struct{}{}
 	for _, fault := range faults {
 		fmap[fault] = struct{}{}
@@ -81,7 +81,13 @@ func (sb *Sealer) pubSectorToPriv(ctx context.Context, mid abi.ActorID, sectorIn
 			ProofType: s.SealProof,
 		}
 
-		paths, d, err := sb.sectors.AcquireSector(ctx, sid, storiface.FTCache|storiface.FTSealed, 0, storiface.PathStorage)
+		postProofType, err := rpt(s.SealProof)
+		if err != nil {
+			done()
+			return ffi.SortedPrivateSectorInfo{}, nil, nil, xerrors.Errorf("acquiring registered PoSt proof from sector info %+v: %w", s, err)
+		}
+
+		paths, d, err := sb.acquireSectorForPoSt(ctx, sid, postProofType, randomness)
 		if err != nil {
 			log.Warnw("failed to acquire sector, skipping", "sector", sid.ID, "error", err)
 			skipped = append(skipped, sid.ID)
@@ -89,12 +95,6 @@ func (sb *Sealer) pubSectorToPriv(ctx context.Context, mid abi.ActorID, sectorIn
 		}
 		doneFuncs = append(doneFuncs, d)
 
-		postProofType, err := rpt(s.SealProof)
-		if err != nil {
-			done()
-			return ffi.SortedPrivateSectorInfo{}, nil, nil, xerrors.Errorf("acquiring registered PoSt proof from sector info %+v: %w", s, err)
-		}
-
 		out = append(out, ffi.PrivateSectorInfo{
 			CacheDirPath:     paths.Cache,
 			PoStProofType:    postProofType,
@@ -106,6 +106,22 @@ func (sb *Sealer) pubSectorToPriv(ctx context.Context, mid abi.ActorID, sectorIn
 	return ffi.NewSortedPrivateSectorInfo(out...), skipped, done, nil
 }
 
+// acquireSectorForPoSt lets providers which support it read only the parts
+// of the sealed file challenged with the randomness
+func (sb *Sealer) acquireSectorForPoSt(ctx context.Context, sid storage.SectorRef, pp abi.RegisteredPoStProof, randomness abi.PoStRandomness) (storiface.SectorPaths, func(), error) {
+	psp, ok := sb.sectors.(PoStSectorProvider)
+	if !ok {
+		return sb.sectors.AcquireSector(ctx, sid, storiface.FTCache|storiface.FTSealed, 0, storiface.PathStorage)
+	}
+
+	ch, err := ffi.GeneratePoStFallbackSectorChallenges(pp, sid.ID.Miner, randomness, []abi.SectorNumber{sid.ID.Number})
+	if err != nil {
+		return storiface.SectorPaths{}, nil, xerrors.Errorf("generating fallback challenges: %w", err)
+	}
+
+	return psp.AcquireSectorForPoSt(ctx, sid, ch.Challenges[sid.ID.Number])
+}
+
 var _ Verifier = ProofVerifier
 
 type proofVerifier struct{}
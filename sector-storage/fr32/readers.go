@@ -31,6 +31,24 @@ func NewUnpadReader(src io.Reader, sz abi.PaddedPieceSize) (io.Reader, error) {
 	}, nil
 }
 
+// NewUnpadRangeReader unpads sz bytes of padded data read from the middle of a
+// piece, sz has to be a multiple of the 128 byte fr32 chunk but unlike with
+// NewUnpadReader doesn't have to be a valid piece size
+func NewUnpadRangeReader(src io.Reader, sz uint64) (io.Reader, error) {
+	if sz == 0 || sz%128 != 0 {
+		return nil, xerrors.Errorf("padded range size %d isn't a multiple of 128", sz)
+	}
+
+	buf := make([]byte, MTTresh*mtChunkCount(abi.PaddedPieceSize(sz)))
+
+	return &unpadReader{
+		src: src,
+
+		left: sz,
+		work: buf,
+	}, nil
+}
+
 func (r *unpadReader) Read(out []byte) (int, error) {
 	if r.left == 0 {
 		return 0, io.EOF
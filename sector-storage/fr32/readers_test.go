@@ -34,3 +34,27 @@ func TestUnpadReader(t *testing.T) {
 
 	require.Equal(t, raw, readered)
 }
+
+func TestUnpadRangeReader(t *testing.T) {
+	ps := abi.PaddedPieceSize(2048).Unpadded()
+
+	raw := make([]byte, ps)
+	for i := range raw {
+		raw[i] = byte(i)
+	}
+	raw[len(raw)-1] &= 0x3f // the last byte only holds 6 bits
+
+	padOut := make([]byte, ps.Padded())
+	fr32.Pad(raw, padOut)
+
+	// chunks 3 to 9 hold the unpadded bytes 381 to 1270
+	r, err := fr32.NewUnpadRangeReader(bytes.NewReader(padOut[3*128:10*128]), 7*128)
+	require.NoError(t, err)
+
+	readered, err := ioutil.ReadAll(bufio.NewReaderSize(r, 127))
+	require.NoError(t, err)
+	require.Equal(t, raw[3*127:10*127], readered)
+
+	_, err = fr32.NewUnpadRangeReader(bytes.NewReader(padOut), 100)
+	require.Error(t, err)
+}
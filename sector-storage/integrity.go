@@ -0,0 +1,104 @@
+package sectorstorage
+
+import (
+	"context"
+	"crypto/rand"
+
+	"github.com/ipfs/go-cid"
+	"golang.org/x/xerrors"
+
+	ffi "github.com/filecoin-project/filecoin-ffi"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/specs-actors/actors/runtime/proof"
+	"github.com/filecoin-project/specs-storage/storage"
+
+	"github.com/filecoin-project/venus-sealer/sector-storage/storiface"
+)
+
+// IntegrityChecker checks stored sealed replicas against the CommR they were
+// precommitted with
+type IntegrityChecker interface {
+	CheckSealedIntegrity(ctx context.Context, sector storage.SectorRef, commr cid.Cid, rounds int) error
+}
+
+var _ IntegrityChecker = &Manager{}
+
+// CheckSealedIntegrity re-derives the CommR of the sealed replica by generating
+// window post vanilla proofs for rounds of random challenges. Proving fails if
+// the tree-r-last roots in the cache don't hash to the CommR, or if a
+// challenged leaf of the replica doesn't match the trees. Unlike CheckProvable
+// it waits for the sector lock, so sectors being moved are checked afterwards.
+func (m *Manager) CheckSealedIntegrity(ctx context.Context, sector storage.SectorRef, commr cid.Cid, rounds int) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	ssize, err := sector.ProofType.SectorSize()
+	if err != nil {
+		return err
+	}
+	wpp, err := sector.ProofType.RegisteredWindowPoStProof()
+	if err != nil {
+		return err
+	}
+
+	if err := m.index.StorageLock(ctx, sector.ID, storiface.FTSealed|storiface.FTCache, storiface.FTNone); err != nil {
+		return xerrors.Errorf("acquiring sector lock: %w", err)
+	}
+
+	for i := 0; i < rounds; i++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		var pr abi.PoStRandomness = make([]byte, abi.RandomnessLength)
+		_, _ = rand.Read(pr)
+		pr[31] &= 0x3f
+
+		ch, err := ffi.GeneratePoStFallbackSectorChallenges(wpp, sector.ID.Miner, pr, []abi.SectorNumber{
+			sector.ID.Number,
+		})
+		if err != nil {
+			return xerrors.Errorf("generating fallback challenges: %w", err)
+		}
+
+		if err := m.proveChallenges(ctx, sector, commr, wpp, ssize, ch.Challenges[sector.ID.Number]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *Manager) proveChallenges(ctx context.Context, sector storage.SectorRef, commr cid.Cid, wpp abi.RegisteredPoStProof, ssize abi.SectorSize, challenges []uint64) error {
+	lp, done, err := m.localStore.AcquireSectorForPoSt(ctx, sector, challenges)
+	if err != nil {
+		m.localStore.FileError(err)
+		return xerrors.Errorf("acquire sector failed: %w", err)
+	}
+	defer done()
+
+	if lp.Sealed == "" || lp.Cache == "" {
+		return xerrors.Errorf("cache and/or sealed paths not found, cache %q, sealed %q", lp.Cache, lp.Sealed)
+	}
+
+	if err := CheckSectorFiles(lp.Sealed, lp.Cache, ssize); err != nil {
+		m.localStore.FileError(err)
+		return err
+	}
+
+	_, err = ffi.GenerateSingleVanillaProof(ffi.PrivateSectorInfo{
+		SectorInfo: proof.SectorInfo{
+			SealProof:    sector.ProofType,
+			SectorNumber: sector.ID.Number,
+			SealedCID:    commr,
+		},
+		CacheDirPath:     lp.Cache,
+		PoStProofType:    wpp,
+		SealedSectorPath: lp.Sealed,
+	}, challenges)
+	if err != nil {
+		return xerrors.Errorf("generating vanilla proof: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,64 @@
+package sectorstorage
+
+import (
+	"context"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/specs-storage/storage"
+
+	"github.com/filecoin-project/venus-sealer/types"
+)
+
+// labelTasks are the task types routed by sector labels, the remaining tasks
+// are scheduled on workers which already have the sector data
+var labelTasks = map[types.TaskType]struct{}{
+	types.TTAddPiece:   {},
+	types.TTPreCommit1: {},
+	types.TTPreCommit2: {},
+	types.TTCommit2:    {},
+}
+
+// matchLabels returns whether the worker labels contain all required labels
+func matchLabels(have, want map[string]string) bool {
+	for k, v := range want {
+		if hv, ok := have[k]; !ok || hv != v {
+			return false
+		}
+	}
+	return true
+}
+
+// labelSelector only accepts workers with the labels required for the sector class
+func (sh *scheduler) labelSelector(ctx context.Context, task types.TaskType, sel WorkerSelector) WorkerSelector {
+	if _, ok := labelTasks[task]; !ok {
+		return sel
+	}
+
+	sh.labelsLk.RLock()
+	labels := sh.sectorLabels[types.GetSectorClass(ctx)]
+	sh.labelsLk.RUnlock()
+	if len(labels) == 0 {
+		return sel
+	}
+
+	return &labelSelector{
+		WorkerSelector: sel,
+		labels:         labels,
+	}
+}
+
+type labelSelector struct {
+	WorkerSelector
+
+	labels map[string]string
+}
+
+func (s *labelSelector) Ok(ctx context.Context, task types.TaskType, spt abi.RegisteredSealProof, sector storage.SectorRef, whnd *workerHandle) (bool, error) {
+	if !matchLabels(whnd.info.Labels, s.labels) {
+		return false, nil
+	}
+
+	return s.WorkerSelector.Ok(ctx, task, spt, sector, whnd)
+}
+
+var _ WorkerSelector = &labelSelector{}
@@ -0,0 +1,46 @@
+package sectorstorage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/specs-storage/storage"
+
+	"github.com/filecoin-project/venus-sealer/sector-storage/storiface"
+	"github.com/filecoin-project/venus-sealer/types"
+)
+
+func TestLabelSelector(t *testing.T) {
+	sh := newScheduler()
+	sh.sectorLabels = map[string]map[string]string{
+		types.SectorClassDeal: {"tier": "deal"},
+	}
+
+	sector := storage.SectorRef{
+		ID:        abi.SectorID{Miner: 1000, Number: 1},
+		ProofType: abi.RegisteredSealProof_StackedDrg2KiBV1,
+	}
+
+	dealWorker := &workerHandle{info: storiface.WorkerInfo{Labels: map[string]string{"tier": "deal", "group": "rackA"}}}
+	bulkWorker := &workerHandle{info: storiface.WorkerInfo{}}
+
+	dealCtx := types.WithSectorClass(context.Background(), types.SectorClassDeal)
+
+	sel := sh.labelSelector(dealCtx, types.TTPreCommit1, anySelector{})
+	ok, err := sel.Ok(dealCtx, types.TTPreCommit1, sector.ProofType, sector, dealWorker)
+	require.NoError(t, err)
+	require.True(t, ok)
+	ok, err = sel.Ok(dealCtx, types.TTPreCommit1, sector.ProofType, sector, bulkWorker)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	// cc sectors and tasks which follow the data aren't routed
+	ccCtx := types.WithSectorClass(context.Background(), types.SectorClassCC)
+	_, ok = sh.labelSelector(ccCtx, types.TTPreCommit1, anySelector{}).(anySelector)
+	require.True(t, ok)
+	_, ok = sh.labelSelector(dealCtx, types.TTFinalize, anySelector{}).(anySelector)
+	require.True(t, ok)
+}
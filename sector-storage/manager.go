@@ -38,6 +38,9 @@ type Worker interface {
 
 	TaskNumbers(context.Context) (string, error)
 
+	// TaskLimits returns the per task type concurrency limits of the worker
+	TaskLimits(context.Context) (map[types.TaskType]int64, error)
+
 	SectorExists(context.Context, types.TaskType, storage.SectorRef) (bool, error)
 
 	// Returns paths accessible to the worker
@@ -55,6 +58,10 @@ type SectorManager interface {
 	storage.Prover
 	storiface.WorkerReturn
 	FaultTracker
+
+	// RefetchUnsealed drops the copies of the unsealed file in sealing paths, so
+	// the next task needing it fetches it again from a storage path
+	RefetchUnsealed(ctx context.Context, sector storage.SectorRef) error
 }
 
 type WorkerID uuid.UUID // worker session UUID
@@ -84,6 +91,16 @@ type Manager struct {
 
 	results map[types.WorkID]result
 	waitRes map[types.WorkID]chan struct{}
+
+	drainLk sync.Mutex
+	drains  map[WorkerID]*workerDrain
+
+	unsealed *unsealedCache
+	unseals  *unsealQueue
+
+	verified     *verifiedPieces
+	verifyLk     sync.Mutex
+	verifyPieces bool
 }
 
 type result struct {
@@ -119,6 +136,29 @@ type SealerConfig struct {
 	// to use when evaluating tasks against this worker. An empty value defaults
 	// to "hardware".
 	ResourceFiltering ResourceFilteringStrategy
+
+	// Affinity constrains which workers tasks are scheduled on, see AffinityRule
+	Affinity []AffinityRule
+
+	// SectorLabels maps sector classes ("deal", "cc") to the worker labels required
+	// for sealing them, e.g.
+	//
+	//	[Storage.SectorLabels.deal]
+	//	tier = "deal"
+	SectorLabels map[string]map[string]string
+
+	// UnsealedCache limits the space unsealed copies take in storage paths, see UnsealedCacheConfig
+	UnsealedCache UnsealedCacheConfig
+
+	// UnsealPriority is the scheduler priority of unseals for retrievals, a
+	// request with a higher priority in its context (e.g. a paid retrieval)
+	// raises the priority of the unseal it waits for
+	UnsealPriority int
+
+	// VerifyUnsealedPieces checks unsealed pieces against the piece cid of their
+	// deal before they are handed to the market, bad unsealed copies are removed
+	// and the sector is unsealed again
+	VerifyUnsealedPieces bool
 }
 
 type StorageAuth http.Header
@@ -126,12 +166,31 @@ type StorageAuth http.Header
 type WorkerStateStore statestore.StateStore
 type ManagerStateStore statestore.StateStore
 
-func New(ctx context.Context, lstor *stores.Local, stor *stores.Remote, ls stores.LocalStorage, si stores.SectorIndex, sc SealerConfig, wss WorkerStateStore, mss ManagerStateStore) (*Manager, error) {
+func New(ctx context.Context, lstor *stores.Local, stor *stores.Remote, ls stores.LocalStorage, si stores.SectorIndex, sc SealerConfig, wss WorkerStateStore, mss ManagerStateStore, hosts types.SectorHostStore) (*Manager, error) {
 	prover, err := ffiwrapper.New(&readonlyProvider{stor: lstor, index: si})
 	if err != nil {
 		return nil, xerrors.Errorf("creating prover instance: %w", err)
 	}
 
+	if err := checkSectorLabels(sc.SectorLabels); err != nil {
+		return nil, err
+	}
+
+	aff, err := newAffinity(sc.Affinity, si)
+	if err != nil {
+		return nil, xerrors.Errorf("parsing affinity rules: %w", err)
+	}
+	if hosts != nil {
+		if err := aff.load(hosts); err != nil {
+			return nil, xerrors.Errorf("loading sector hosts: %w", err)
+		}
+	}
+
+	ucfg, err := parseUnsealedCacheConfig(sc.UnsealedCache)
+	if err != nil {
+		return nil, xerrors.Errorf("parsing unsealed cache config: %w", err)
+	}
+
 	m := &Manager{
 		ls:         ls,
 		storage:    stor,
@@ -148,11 +207,29 @@ func New(ctx context.Context, lstor *stores.Local, stor *stores.Remote, ls store
 		callRes:    map[types.CallID]chan result{},
 		results:    map[types.WorkID]result{},
 		waitRes:    map[types.WorkID]chan struct{}{},
+
+		drains: map[WorkerID]*workerDrain{},
+
+		unsealed: newUnsealedCache(si, ucfg),
+		unseals:  newUnsealQueue(sc.UnsealPriority),
+
+		verified:     newVerifiedPieces(VerifiedPiecesCacheSize),
+		verifyPieces: sc.VerifyUnsealedPieces,
+	}
+
+	m.sched.affinity = aff
+	m.sched.sectorLabels = sc.SectorLabels
+
+	m.unsealed.allocated = stor.AllocatedRanges
+	m.unsealed.release = m.releaseUnsealed
+	m.unseals.setPriority = func(ctx context.Context, sector abi.SectorID, priority int) (bool, error) {
+		return m.sched.setPriority(ctx, sector, types.TTUnseal, priority)
 	}
 
 	m.setupWorkTracker()
 
 	go m.sched.runSched()
+	go m.unsealed.run(ctx)
 
 	localTasks := []types.TaskType{
 		types.TTCommit1, types.TTFinalize, types.TTFetch,
@@ -187,6 +264,65 @@ func New(ctx context.Context, lstor *stores.Local, stor *stores.Remote, ls store
 	return m, nil
 }
 
+func checkSectorLabels(labels map[string]map[string]string) error {
+	for class := range labels {
+		if class != types.SectorClassDeal && class != types.SectorClassCC {
+			return xerrors.Errorf("unknown sector class '%s' in SectorLabels", class)
+		}
+	}
+	return nil
+}
+
+// CheckSealerConfig checks the scheduler options of the config
+func CheckSealerConfig(sc SealerConfig) error {
+	if err := checkSectorLabels(sc.SectorLabels); err != nil {
+		return err
+	}
+	if _, err := parseAffinityRules(sc.Affinity); err != nil {
+		return xerrors.Errorf("parsing affinity rules: %w", err)
+	}
+	if _, err := parseUnsealedCacheConfig(sc.UnsealedCache); err != nil {
+		return xerrors.Errorf("parsing unsealed cache config: %w", err)
+	}
+	return nil
+}
+
+// PrepareSchedConfig checks the scheduler options of the config, the returned
+// function applies the affinity rules and sector labels to the scheduler, the
+// budgets to the unsealed cache, the unseal priority to new unseals and turns
+// piece verification on or off. The other options only apply to the local
+// worker, which is set up at startup.
+func (m *Manager) PrepareSchedConfig(sc SealerConfig) (func(), error) {
+	if err := checkSectorLabels(sc.SectorLabels); err != nil {
+		return nil, err
+	}
+
+	rules, err := parseAffinityRules(sc.Affinity)
+	if err != nil {
+		return nil, xerrors.Errorf("parsing affinity rules: %w", err)
+	}
+
+	ucfg, err := parseUnsealedCacheConfig(sc.UnsealedCache)
+	if err != nil {
+		return nil, xerrors.Errorf("parsing unsealed cache config: %w", err)
+	}
+
+	return func() {
+		m.sched.affinity.setRules(rules)
+
+		m.sched.labelsLk.Lock()
+		m.sched.sectorLabels = sc.SectorLabels
+		m.sched.labelsLk.Unlock()
+
+		m.unsealed.setConfig(ucfg)
+		m.unseals.setBasePriority(sc.UnsealPriority)
+
+		m.verifyLk.Lock()
+		m.verifyPieces = sc.VerifyUnsealedPieces
+		m.verifyLk.Unlock()
+	}, nil
+}
+
 func (m *Manager) AddLocalStorage(ctx context.Context, path string) error {
 	path, err := homedir.Expand(path)
 	if err != nil {
@@ -228,7 +364,14 @@ func (m *Manager) schedFetch(sector storage.SectorRef, ft storiface.SectorFileTy
 // It will schedule the Unsealing task on a worker that either already has the sealed sector files or has space in
 // one of it's sealing scratch spaces to store them after fetching them from another worker.
 // If the chosen worker already has the Unsealed sector file, we will NOT Unseal the sealed sector file again.
+// Concurrent calls for the same sector wait for a single unseal.
 func (m *Manager) SectorsUnsealPiece(ctx context.Context, sector storage.SectorRef, offset storiface.UnpaddedByteIndex, size abi.UnpaddedPieceSize, ticket abi.SealRandomness, unsealed *cid.Cid) error {
+	return m.unseals.do(ctx, sector.ID, func(ctx context.Context) error {
+		return m.unsealPiece(ctx, sector, offset, size, ticket, unsealed)
+	})
+}
+
+func (m *Manager) unsealPiece(ctx context.Context, sector storage.SectorRef, offset storiface.UnpaddedByteIndex, size abi.UnpaddedPieceSize, ticket abi.SealRandomness, unsealed *cid.Cid) error {
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
@@ -262,8 +405,10 @@ func (m *Manager) SectorsUnsealPiece(ctx context.Context, sector storage.SectorR
 	selector := newExistingSelector(m.index, sector.ID, storiface.FTSealed|storiface.FTCache, true)
 
 	log.Debugf("will schedule unseal for sector %d", sector.ID)
+	ctx = types.WithPriority(ctx, m.unseals.priorityOf(sector.ID))
 	err = m.sched.Schedule(ctx, sector, types.TTUnseal, selector, sealFetch, func(ctx context.Context, w Worker) error {
 		// TODO: make restartable
+		m.unseals.markStarted(sector.ID)
 
 		// NOTE: we're unsealing the whole sector here as with SDR we can't really
 		//  unseal the sector partially. Requesting the whole sector here can
@@ -589,7 +734,13 @@ func (m *Manager) FinalizeSector(ctx context.Context, sector storage.SectorRef,
 	return nil
 }
 
+// ReleaseUnsealed marks the ranges of the unsealed copies of the sector as safe
+// to free. Unsealing is expensive, so they are only freed once a storage path
+// holds more unsealed data than its budget, see UnsealedCacheConfig.
 func (m *Manager) ReleaseUnsealed(ctx context.Context, sector storage.SectorRef, safeToFree []storage.Range) error {
+	if m.unsealed != nil {
+		m.unsealed.markReleased(sector, safeToFree)
+	}
 	return nil
 }
 
@@ -612,10 +763,50 @@ func (m *Manager) Remove(ctx context.Context, sector storage.SectorRef) error {
 	if rerr := m.storage.Remove(ctx, sector.ID, storiface.FTUnsealed, true); rerr != nil {
 		err = multierror.Append(err, xerrors.Errorf("removing sector (unsealed): %w", rerr))
 	}
+	if m.verified != nil {
+		m.verified.forget(sector.ID)
+	}
+	m.sched.affinity.forget(sector.ID)
 
 	return err
 }
 
+func (m *Manager) RefetchUnsealed(ctx context.Context, sector storage.SectorRef) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	if err := m.index.StorageLock(ctx, sector.ID, storiface.FTNone, storiface.FTUnsealed); err != nil {
+		return xerrors.Errorf("acquiring sector lock: %w", err)
+	}
+
+	si, err := m.index.StorageFindSector(ctx, sector.ID, storiface.FTUnsealed, 0, false)
+	if err != nil {
+		return xerrors.Errorf("finding unsealed copies: %w", err)
+	}
+
+	var stored bool
+	for _, info := range si {
+		stored = stored || info.CanStore
+	}
+	if !stored {
+		return xerrors.Errorf("no copy of the unsealed file in a storage path to fetch from")
+	}
+
+	for _, info := range si {
+		if info.CanStore {
+			continue
+		}
+
+		// the file left in the path is replaced when the sector is fetched into it again
+		log.Infow("dropping unsealed copy", "sector", sector.ID, "storage", info.ID)
+		if err := m.index.StorageDropSector(ctx, info.ID, sector.ID, storiface.FTUnsealed); err != nil {
+			return xerrors.Errorf("dropping unsealed copy in %s: %w", info.ID, err)
+		}
+	}
+
+	return nil
+}
+
 func (m *Manager) ReturnAddPiece(ctx context.Context, callID types.CallID, pi abi.PieceInfo, err *storiface.CallError) error {
 	return m.returnResult(ctx, callID, pi, err)
 }
@@ -674,6 +865,18 @@ func (m *Manager) StorageLocal(ctx context.Context) (map[stores.ID]string, error
 	return out, nil
 }
 
+// RelayoutLocalStorage switches the local storage path to the layout and moves the
+// sector files into it
+func (m *Manager) RelayoutLocalStorage(ctx context.Context, id stores.ID, layout storiface.SectorLayout) (stores.RelayoutResult, error) {
+	return m.localStore.Relayout(ctx, id, layout)
+}
+
+// VerifyLocalManifest reconciles the manifest of the local storage path with the
+// sector files in it
+func (m *Manager) VerifyLocalManifest(ctx context.Context, id stores.ID) (stores.ManifestReport, error) {
+	return m.localStore.VerifyManifest(ctx, id)
+}
+
 func (m *Manager) FsStat(ctx context.Context, id stores.ID) (fsutil.FsStat, error) {
 	return m.storage.FsStat(ctx, id)
 }
@@ -21,9 +21,25 @@ type Unsealer interface {
 	SectorsUnsealPiece(ctx context.Context, sector storage.SectorRef, offset storiface.UnpaddedByteIndex, size abi.UnpaddedPieceSize, randomness abi.SealRandomness, commd *cid.Cid) error
 }
 
+// unsealedAccessRecorder is implemented by unsealers tracking reads of unsealed
+// copies, see Manager.RecordUnsealedAccess
+type unsealedAccessRecorder interface {
+	RecordUnsealedAccess(sector storage.SectorRef)
+}
+
+// pieceVerifier is implemented by unsealers checking unsealed data against the
+// piece cid, see Manager.VerifyUnsealedPiece
+type pieceVerifier interface {
+	VerifyingUnsealedPieces() bool
+	VerifyUnsealedPiece(ctx context.Context, sector storage.SectorRef, offset storiface.UnpaddedByteIndex, size abi.UnpaddedPieceSize, ticket abi.SealRandomness, commd *cid.Cid, pieceCid cid.Cid) error
+}
+
 type PieceProvider interface {
-	// ReadPiece is used to read an Unsealed piece at the given offset and of the given size from a Sector
-	ReadPiece(ctx context.Context, sector storage.SectorRef, offset storiface.UnpaddedByteIndex, size abi.UnpaddedPieceSize, ticket abi.SealRandomness, unsealed cid.Cid) (io.ReadCloser, bool, error)
+	// ReadPiece is used to read an Unsealed piece at the given offset and of the given size from a Sector,
+	// the data is checked against pieceCid first when VerifyUnsealedPieces is enabled
+	ReadPiece(ctx context.Context, sector storage.SectorRef, offset storiface.UnpaddedByteIndex, size abi.UnpaddedPieceSize, ticket abi.SealRandomness, unsealed cid.Cid, pieceCid cid.Cid) (io.ReadCloser, bool, error)
+	// ReadPieceRange is like ReadPiece, but only reads length bytes of the piece starting at start
+	ReadPieceRange(ctx context.Context, sector storage.SectorRef, offset storiface.UnpaddedByteIndex, size abi.UnpaddedPieceSize, start, length uint64, ticket abi.SealRandomness, unsealed cid.Cid, pieceCid cid.Cid) (io.ReadCloser, bool, error)
 	IsUnsealed(ctx context.Context, sector storage.SectorRef, offset storiface.UnpaddedByteIndex, size abi.UnpaddedPieceSize) (bool, error)
 }
 
@@ -63,11 +79,11 @@ func (p *pieceProvider) IsUnsealed(ctx context.Context, sector storage.SectorRef
 	return p.storage.CheckIsUnsealed(ctxLock, sector, abi.PaddedPieceSize(offset.Padded()), size.Padded())
 }
 
-// tryReadUnsealedPiece will try to read the unsealed piece from an existing unsealed sector file for the given sector from any worker that has it.
+// tryReadUnsealedPiece will try to read the padded range of an unsealed piece from an existing unsealed sector file for the given sector from any worker that has it.
 // It will NOT try to schedule an Unseal of a sealed sector file for the read.
 //
 // Returns a nil reader if the piece does NOT exist in any unsealed file or there is no unsealed file for the given sector on any of the workers.
-func (p *pieceProvider) tryReadUnsealedPiece(ctx context.Context, sector storage.SectorRef, offset storiface.UnpaddedByteIndex, size abi.UnpaddedPieceSize) (io.ReadCloser, context.CancelFunc, error) {
+func (p *pieceProvider) tryReadUnsealedPiece(ctx context.Context, sector storage.SectorRef, offset, size abi.PaddedPieceSize) (io.ReadCloser, context.CancelFunc, error) {
 	// acquire a lock purely for reading unsealed sectors
 	ctx, cancel := context.WithCancel(ctx)
 	if err := p.index.StorageLock(ctx, sector.ID, storiface.FTUnsealed, storiface.FTNone); err != nil {
@@ -78,7 +94,7 @@ func (p *pieceProvider) tryReadUnsealedPiece(ctx context.Context, sector storage
 	// Reader returns a reader for an unsealed piece at the given offset in the given sector.
 	// The returned reader will be nil if none of the workers has an unsealed sector file containing
 	// the unsealed piece.
-	r, err := p.storage.Reader(ctx, sector, abi.PaddedPieceSize(offset.Padded()), size.Padded())
+	r, err := p.storage.Reader(ctx, sector, offset, size)
 	if err != nil {
 		log.Debugf("did not get storage reader;sector=%+v, err:%s", sector.ID, err)
 		cancel()
@@ -97,15 +113,38 @@ func (p *pieceProvider) tryReadUnsealedPiece(ctx context.Context, sector storage
 // If we do NOT have an existing unsealed file  containing the given piece thus causing us to schedule an Unseal,
 // the returned boolean parameter will be set to true.
 // If we have an existing unsealed file containing the given piece, the returned boolean will be set to false.
-func (p *pieceProvider) ReadPiece(ctx context.Context, sector storage.SectorRef, offset storiface.UnpaddedByteIndex, size abi.UnpaddedPieceSize, ticket abi.SealRandomness, unsealed cid.Cid) (io.ReadCloser, bool, error) {
+// When VerifyUnsealedPieces is enabled and pieceCid is defined, the unsealed data is checked against it
+// before reading, bad unsealed copies are unsealed again.
+func (p *pieceProvider) ReadPiece(ctx context.Context, sector storage.SectorRef, offset storiface.UnpaddedByteIndex, size abi.UnpaddedPieceSize, ticket abi.SealRandomness, unsealed cid.Cid, pieceCid cid.Cid) (io.ReadCloser, bool, error) {
+	return p.ReadPieceRange(ctx, sector, offset, size, 0, uint64(size), ticket, unsealed, pieceCid)
+}
+
+// ReadPieceRange reads length bytes of the piece starting at the unpadded byte
+// start within the piece. Only the fr32 chunks covering the range are read from
+// the unsealed copy, the piece is still unsealed and verified as a whole.
+func (p *pieceProvider) ReadPieceRange(ctx context.Context, sector storage.SectorRef, offset storiface.UnpaddedByteIndex, size abi.UnpaddedPieceSize, start, length uint64, ticket abi.SealRandomness, unsealed cid.Cid, pieceCid cid.Cid) (io.ReadCloser, bool, error) {
 	if err := offset.Valid(); err != nil {
 		return nil, false, xerrors.Errorf("offset is not valid: %w", err)
 	}
 	if err := size.Validate(); err != nil {
 		return nil, false, xerrors.Errorf("size is not a valid piece size: %w", err)
 	}
+	if length == 0 || start+length > uint64(size) {
+		return nil, false, xerrors.Errorf("range %d+%d is out of the piece of size %d", start, length, size)
+	}
+
+	// every 127 unpadded bytes are stored in a 128 byte chunk
+	firstChunk := start / 127
+	endChunk := (start + length + 126) / 127
+	readOffset := abi.PaddedPieceSize(offset.Padded()) + abi.PaddedPieceSize(firstChunk*128)
+	readSize := abi.PaddedPieceSize((endChunk - firstChunk) * 128)
+
+	verifiedUns, err := p.verifyPiece(ctx, sector, offset, size, ticket, unsealed, pieceCid)
+	if err != nil {
+		return nil, verifiedUns, xerrors.Errorf("verifying piece: %w", err)
+	}
 
-	r, unlock, err := p.tryReadUnsealedPiece(ctx, sector, offset, size)
+	r, unlock, err := p.tryReadUnsealedPiece(ctx, sector, readOffset, readSize)
 
 	log.Debugf("result of first tryReadUnsealedPiece: r=%+v, err=%s", r, err)
 
@@ -118,7 +157,7 @@ func (p *pieceProvider) ReadPiece(ctx context.Context, sector storage.SectorRef,
 		return nil, false, err
 	}
 
-	var uns bool
+	uns := verifiedUns
 
 	if r == nil {
 		// a nil reader means that none of the workers has an unsealed sector file
@@ -136,7 +175,7 @@ func (p *pieceProvider) ReadPiece(ctx context.Context, sector storage.SectorRef,
 
 		log.Debugf("unsealed a sector file to read the piece, sector=%+v, offset=%d, size=%d", sector, offset, size)
 
-		r, unlock, err = p.tryReadUnsealedPiece(ctx, sector, offset, size)
+		r, unlock, err = p.tryReadUnsealedPiece(ctx, sector, readOffset, readSize)
 		if err != nil {
 			log.Errorf("failed to tryReadUnsealedPiece after SectorsUnsealPiece: %s", err)
 			return nil, true, xerrors.Errorf("read after unsealing: %w", err)
@@ -150,16 +189,28 @@ func (p *pieceProvider) ReadPiece(ctx context.Context, sector storage.SectorRef,
 		log.Debugf("unsealed piece already exists, no need to unseal, sector=%+v, offset=%d, size=%d", sector, offset, size)
 	}
 
-	upr, err := fr32.NewUnpadReader(r, size.Padded())
+	upr, err := fr32.NewUnpadRangeReader(r, uint64(readSize))
 	if err != nil {
+		_ = r.Close()
 		unlock()
 		return nil, uns, xerrors.Errorf("creating unpadded reader: %w", err)
 	}
 
+	br := bufio.NewReaderSize(upr, 127)
+	if _, err := br.Discard(int(start - firstChunk*127)); err != nil {
+		_ = r.Close()
+		unlock()
+		return nil, uns, xerrors.Errorf("skipping to range start: %w", err)
+	}
+
+	if rec, ok := p.uns.(unsealedAccessRecorder); ok {
+		rec.RecordUnsealedAccess(sector)
+	}
+
 	log.Debugf("returning reader to read unsealed piece, sector=%+v, offset=%d, size=%d", sector, offset, size)
 
 	return &funcCloser{
-		Reader: bufio.NewReaderSize(upr, 127),
+		Reader: io.LimitReader(br, int64(length)),
 		close: func() error {
 			err = r.Close()
 			unlock()
@@ -168,6 +219,28 @@ func (p *pieceProvider) ReadPiece(ctx context.Context, sector storage.SectorRef,
 	}, uns, nil
 }
 
+// verifyPiece checks the unsealed piece against its piece cid if the unsealer
+// verifies pieces and VerifyUnsealedPieces is enabled. Verifying unseals the
+// sector when no copy has the piece, the returned boolean tells if it had to.
+func (p *pieceProvider) verifyPiece(ctx context.Context, sector storage.SectorRef, offset storiface.UnpaddedByteIndex, size abi.UnpaddedPieceSize, ticket abi.SealRandomness, unsealed cid.Cid, pieceCid cid.Cid) (bool, error) {
+	v, ok := p.uns.(pieceVerifier)
+	if !ok || pieceCid == cid.Undef || !v.VerifyingUnsealedPieces() {
+		return false, nil
+	}
+
+	has, err := p.IsUnsealed(ctx, sector, offset, size)
+	if err != nil {
+		log.Warnw("checking for unsealed piece before verifying", "sector", sector.ID, "error", err)
+		has = true
+	}
+
+	commd := &unsealed
+	if unsealed == cid.Undef {
+		commd = nil
+	}
+	return !has, v.VerifyUnsealedPiece(ctx, sector, offset, size, ticket, commd, pieceCid)
+}
+
 type funcCloser struct {
 	io.Reader
 	close func() error
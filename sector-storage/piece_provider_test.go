@@ -51,6 +51,14 @@ func TestPieceProviderSimpleNoRemoteWorker(t *testing.T) {
 	ppt.readPiece(t, storiface.UnpaddedByteIndex(0), size,
 		false, pieceData)
 
+	// read a range not aligned to the fr32 chunks
+	rd, _, err := ppt.pp.ReadPieceRange(ppt.ctx, ppt.sector, 0, size, 1000, 5000, ppt.ticket, ppt.commD, cid.Undef)
+	require.NoError(t, err)
+	readData, err := ioutil.ReadAll(rd)
+	require.NoError(t, err)
+	require.NoError(t, rd.Close())
+	require.Equal(t, pieceData[1000:6000], readData)
+
 	// pre-commit 1
 	preCommit1 := ppt.preCommit1(t)
 
@@ -85,6 +93,44 @@ func TestPieceProviderSimpleNoRemoteWorker(t *testing.T) {
 		false, pieceData)
 
 }
+
+func TestPieceProviderVerifyingRead(t *testing.T) {
+	sealerCfg := SealerConfig{
+		ParallelFetchLimit:   10,
+		AllowAddPiece:        true,
+		AllowPreCommit1:      true,
+		AllowPreCommit2:      true,
+		AllowCommit:          true,
+		AllowUnseal:          true,
+		VerifyUnsealedPieces: true,
+	}
+
+	ppt := newPieceProviderTestHarness(t, sealerCfg, abi.RegisteredSealProof_StackedDrg8MiBV1)
+	defer ppt.shutdown(t)
+
+	pieceData := generatePieceData(8 * 127 * 1024 * 8)
+	size := abi.UnpaddedPieceSize(len(pieceData))
+	pi := ppt.addPiece(t, pieceData)
+
+	ppt.preCommit2(t, ppt.preCommit1(t))
+	ppt.finalizeSector(t, nil)
+
+	// verifying unseals the sector, the read reports it
+	rd, hadToUnseal, err := ppt.pp.ReadPiece(ppt.ctx, ppt.sector, 0, size, ppt.ticket, ppt.commD, pi.PieceCID)
+	require.NoError(t, err)
+	require.True(t, hadToUnseal)
+	readData, err := ioutil.ReadAll(rd)
+	require.NoError(t, err)
+	require.NoError(t, rd.Close())
+	require.Equal(t, pieceData, readData)
+
+	// data not matching the piece cid is never handed out
+	otherPiece, err := cid.Parse("baga6ea4seaqao7s73y24kcutaosvacpdjgfe5pw76ooefnyqw4ynr3d2y6x2mpq")
+	require.NoError(t, err)
+	_, _, err = ppt.pp.ReadPiece(ppt.ctx, ppt.sector, 0, size, ppt.ticket, ppt.commD, otherPiece)
+	require.Error(t, err)
+}
+
 func TestReadPieceRemoteWorkers(t *testing.T) {
 	logging.SetAllLoggers(logging.LevelDebug)
 
@@ -217,7 +263,7 @@ func newPieceProviderTestHarness(t *testing.T, mgrConfig SealerConfig, sectorPro
 	wsts := statestore.NewDsStateStore(namespace.Wrap(dstore, datastore.NewKey("/worker/calls")))
 	smsts := statestore.NewDsStateStore(namespace.Wrap(dstore, datastore.NewKey("/stmgr/calls")))
 
-	mgr, err := New(ctx, localStore, remoteStore, storage, index, mgrConfig, wsts, smsts)
+	mgr, err := New(ctx, localStore, remoteStore, storage, index, mgrConfig, wsts, smsts, nil)
 	require.NoError(t, err)
 
 	// start a http server on the manager to serve sector file requests.
@@ -338,7 +384,7 @@ func (p *pieceProviderTestHarness) isUnsealed(t *testing.T, offset storiface.Unp
 
 func (p *pieceProviderTestHarness) readPiece(t *testing.T, offset storiface.UnpaddedByteIndex, size abi.UnpaddedPieceSize,
 	expectedHadToUnseal bool, expectedBytes []byte) {
-	rd, isUnsealed, err := p.pp.ReadPiece(p.ctx, p.sector, offset, size, p.ticket, p.commD)
+	rd, isUnsealed, err := p.pp.ReadPiece(p.ctx, p.sector, offset, size, p.ticket, p.commD, cid.Undef)
 	require.NoError(t, err)
 	require.NotNil(t, rd)
 	require.Equal(t, expectedHadToUnseal, isUnsealed)
@@ -0,0 +1,216 @@
+package sectorstorage
+
+import (
+	"container/list"
+	"context"
+	"sync"
+
+	"github.com/ipfs/go-cid"
+	"golang.org/x/xerrors"
+
+	commpffi "github.com/filecoin-project/go-commp-utils/ffiwrapper"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/specs-storage/storage"
+
+	"github.com/filecoin-project/venus-sealer/sector-storage/fr32"
+	"github.com/filecoin-project/venus-sealer/sector-storage/storiface"
+)
+
+// VerifiedPiecesCacheSize is how many verified pieces are remembered
+var VerifiedPiecesCacheSize = 4096
+
+type verifiedPieceKey struct {
+	sector abi.SectorID
+	offset storiface.UnpaddedByteIndex
+	size   abi.UnpaddedPieceSize
+}
+
+type verifiedPiece struct {
+	key      verifiedPieceKey
+	pieceCid cid.Cid
+}
+
+// verifiedPieces remembers the pieces whose unsealed data matched their piece
+// cid, the least recently checked are forgotten first
+type verifiedPieces struct {
+	lk    sync.Mutex
+	size  int
+	order *list.List
+	items map[verifiedPieceKey]*list.Element
+}
+
+func newVerifiedPieces(size int) *verifiedPieces {
+	return &verifiedPieces{
+		size:  size,
+		order: list.New(),
+		items: map[verifiedPieceKey]*list.Element{},
+	}
+}
+
+func (v *verifiedPieces) has(key verifiedPieceKey, pieceCid cid.Cid) bool {
+	v.lk.Lock()
+	defer v.lk.Unlock()
+
+	e, ok := v.items[key]
+	if !ok || !e.Value.(*verifiedPiece).pieceCid.Equals(pieceCid) {
+		return false
+	}
+	v.order.MoveToFront(e)
+	return true
+}
+
+func (v *verifiedPieces) add(key verifiedPieceKey, pieceCid cid.Cid) {
+	v.lk.Lock()
+	defer v.lk.Unlock()
+
+	if e, ok := v.items[key]; ok {
+		e.Value.(*verifiedPiece).pieceCid = pieceCid
+		v.order.MoveToFront(e)
+		return
+	}
+
+	v.items[key] = v.order.PushFront(&verifiedPiece{key: key, pieceCid: pieceCid})
+	for v.order.Len() > v.size {
+		e := v.order.Back()
+		v.order.Remove(e)
+		delete(v.items, e.Value.(*verifiedPiece).key)
+	}
+}
+
+// forget drops the pieces of the sector, its unsealed data changed
+func (v *verifiedPieces) forget(sector abi.SectorID) {
+	v.lk.Lock()
+	defer v.lk.Unlock()
+
+	for key, e := range v.items {
+		if key.sector == sector {
+			v.order.Remove(e)
+			delete(v.items, key)
+		}
+	}
+}
+
+// VerifyingUnsealedPieces tells if unsealed pieces should be checked against
+// their piece cid before they are handed out, see SealerConfig.VerifyUnsealedPieces
+func (m *Manager) VerifyingUnsealedPieces() bool {
+	m.verifyLk.Lock()
+	defer m.verifyLk.Unlock()
+
+	return m.verifyPieces
+}
+
+// VerifyUnsealedPiece checks that the unsealed data of the piece hashes to its
+// piece cid, unsealing the sector if there is no unsealed copy yet. If the data
+// doesn't match, the unsealed copies of the sector are removed and the sector
+// is unsealed again from the sealed replica. Pieces found to match are
+// remembered, so checking them again is cheap.
+func (m *Manager) VerifyUnsealedPiece(ctx context.Context, sector storage.SectorRef, offset storiface.UnpaddedByteIndex, size abi.UnpaddedPieceSize, ticket abi.SealRandomness, commd *cid.Cid, pieceCid cid.Cid) error {
+	key := verifiedPieceKey{sector: sector.ID, offset: offset, size: size}
+	if m.verified.has(key, pieceCid) {
+		return nil
+	}
+
+	c, err := m.unsealedPieceCID(ctx, sector, offset, size, ticket, commd)
+	if err != nil {
+		return err
+	}
+
+	if !c.Equals(pieceCid) {
+		log.Warnw("unsealed piece doesn't match piece cid, unsealing again", "sector", sector.ID, "offset", offset, "size", size, "expected", pieceCid, "got", c)
+
+		if err := m.dropUnsealed(ctx, sector); err != nil {
+			return xerrors.Errorf("removing bad unsealed copy: %w", err)
+		}
+
+		c, err = m.unsealedPieceCID(ctx, sector, offset, size, ticket, commd)
+		if err != nil {
+			return err
+		}
+		if !c.Equals(pieceCid) {
+			return xerrors.Errorf("piece unsealed again from sector %d doesn't match piece cid: expected %s, got %s", sector.ID.Number, pieceCid, c)
+		}
+	}
+
+	m.verified.add(key, pieceCid)
+	return nil
+}
+
+// unsealedPieceCID computes the piece cid of the unsealed data of the piece,
+// unsealing the sector first if no copy has the piece unsealed
+func (m *Manager) unsealedPieceCID(ctx context.Context, sector storage.SectorRef, offset storiface.UnpaddedByteIndex, size abi.UnpaddedPieceSize, ticket abi.SealRandomness, commd *cid.Cid) (cid.Cid, error) {
+	c, err := m.readPieceCID(ctx, sector, offset, size)
+	if err != nil || c != cid.Undef {
+		return c, err
+	}
+
+	if err := m.SectorsUnsealPiece(ctx, sector, offset, size, ticket, commd); err != nil {
+		return cid.Undef, xerrors.Errorf("unsealing piece: %w", err)
+	}
+
+	c, err = m.readPieceCID(ctx, sector, offset, size)
+	if err != nil {
+		return cid.Undef, err
+	}
+	if c == cid.Undef {
+		return cid.Undef, xerrors.Errorf("no unsealed copy of the piece after unsealing")
+	}
+	return c, nil
+}
+
+// readPieceCID streams the piece from an unsealed copy to compute its piece cid,
+// cid.Undef is returned if no copy has the piece unsealed
+func (m *Manager) readPieceCID(ctx context.Context, sector storage.SectorRef, offset storiface.UnpaddedByteIndex, size abi.UnpaddedPieceSize) (cid.Cid, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	if err := m.index.StorageLock(ctx, sector.ID, storiface.FTUnsealed, storiface.FTNone); err != nil {
+		return cid.Undef, xerrors.Errorf("acquiring read sector lock: %w", err)
+	}
+
+	r, err := m.storage.Reader(ctx, sector, abi.PaddedPieceSize(offset.Padded()), size.Padded())
+	if xerrors.Is(err, storiface.ErrSectorNotFound) {
+		return cid.Undef, nil
+	}
+	if err != nil {
+		return cid.Undef, xerrors.Errorf("getting unsealed piece reader: %w", err)
+	}
+	if r == nil {
+		return cid.Undef, nil
+	}
+	defer r.Close() // nolint
+
+	upr, err := fr32.NewUnpadReader(r, size.Padded())
+	if err != nil {
+		return cid.Undef, xerrors.Errorf("creating unpadded reader: %w", err)
+	}
+
+	c, err := commpffi.GeneratePieceCIDFromFile(sector.ProofType, upr, size)
+	if err != nil {
+		return cid.Undef, xerrors.Errorf("computing piece cid: %w", err)
+	}
+
+	return c, nil
+}
+
+// dropUnsealed removes all unsealed copies of the sector, the sealed replica
+// has to exist to unseal it again
+func (m *Manager) dropUnsealed(ctx context.Context, sector storage.SectorRef) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	if err := m.index.StorageLock(ctx, sector.ID, storiface.FTSealed, storiface.FTUnsealed); err != nil {
+		return xerrors.Errorf("acquiring sector lock: %w", err)
+	}
+
+	sealed, err := m.index.StorageFindSector(ctx, sector.ID, storiface.FTSealed, 0, false)
+	if err != nil {
+		return xerrors.Errorf("finding sealed replica: %w", err)
+	}
+	if len(sealed) == 0 {
+		return xerrors.Errorf("sector %d has no sealed replica to unseal from", sector.ID.Number)
+	}
+
+	m.verified.forget(sector.ID)
+
+	return m.storage.Remove(ctx, sector.ID, storiface.FTUnsealed, true)
+}
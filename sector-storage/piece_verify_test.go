@@ -0,0 +1,41 @@
+package sectorstorage
+
+import (
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/venus-sealer/sector-storage/storiface"
+)
+
+func TestVerifiedPieces(t *testing.T) {
+	pieceA, err := cid.Parse("baga6ea4seaqao7s73y24kcutaosvacpdjgfe5pw76ooefnyqw4ynr3d2y6x2mpq")
+	require.NoError(t, err)
+	pieceB, err := cid.Parse("baga6ea4seaqomqafu276g53zko4k23xzh4h4uecjwicbmvhsuqi7o4bhthhm4aq")
+	require.NoError(t, err)
+
+	key := func(sector abi.SectorNumber, offset uint64) verifiedPieceKey {
+		return verifiedPieceKey{sector: abi.SectorID{Miner: 1000, Number: sector}, offset: storiface.UnpaddedByteIndex(offset), size: 1016}
+	}
+
+	v := newVerifiedPieces(2)
+	v.add(key(1, 0), pieceA)
+	v.add(key(1, 1016), pieceB)
+
+	require.True(t, v.has(key(1, 0), pieceA))
+	// a different piece cid for the same range isn't verified
+	require.False(t, v.has(key(1, 1016), pieceA))
+
+	// the least recently checked piece is forgotten first
+	v.add(key(2, 0), pieceA)
+	require.True(t, v.has(key(1, 0), pieceA))
+	require.False(t, v.has(key(1, 1016), pieceB))
+	require.True(t, v.has(key(2, 0), pieceA))
+
+	v.forget(abi.SectorID{Miner: 1000, Number: 1})
+	require.False(t, v.has(key(1, 0), pieceA))
+	require.True(t, v.has(key(2, 0), pieceA))
+}
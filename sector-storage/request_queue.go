@@ -1,6 +1,12 @@
 package sectorstorage
 
-import "sort"
+import (
+	"sort"
+
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/venus-sealer/types"
+)
 
 type requestQueue []*workerRequest
 
@@ -48,3 +54,20 @@ func (q *requestQueue) Remove(i int) *workerRequest {
 	sort.Sort(q)
 	return item
 }
+
+// SetPriority changes the priority of the requests of the task for the sector,
+// it returns false if there are none
+func (q *requestQueue) SetPriority(sector abi.SectorID, taskType types.TaskType, priority int) bool {
+	var found bool
+	for _, req := range *q {
+		if req.sector.ID == sector && req.taskType == taskType {
+			req.priority = priority
+			found = true
+		}
+	}
+
+	if found {
+		sort.Sort(q)
+	}
+	return found
+}
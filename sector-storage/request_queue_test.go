@@ -4,6 +4,9 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/specs-storage/storage"
+
 	"github.com/filecoin-project/venus-sealer/types"
 )
 
@@ -60,3 +63,30 @@ func TestRequestQueue(t *testing.T) {
 		t.Error("expected precommit1, got", pt.taskType)
 	}
 }
+
+func TestRequestQueueSetPriority(t *testing.T) {
+	rq := &requestQueue{}
+
+	sector := func(n abi.SectorNumber) storage.SectorRef {
+		return storage.SectorRef{ID: abi.SectorID{Miner: 1000, Number: n}}
+	}
+
+	rq.Push(&workerRequest{taskType: types.TTPreCommit1, sector: sector(1), priority: types.DealSectorPriority})
+	rq.Push(&workerRequest{taskType: types.TTUnseal, sector: sector(2)})
+	rq.Push(&workerRequest{taskType: types.TTAddPiece, sector: sector(3)})
+
+	if (*rq)[0].sector.ID.Number != 1 {
+		t.Error("expected the deal sector first, got", (*rq)[0].sector.ID.Number)
+	}
+
+	if rq.SetPriority(sector(2).ID, types.TTPreCommit1, 2048) {
+		t.Error("expected no request for another task type")
+	}
+	if !rq.SetPriority(sector(2).ID, types.TTUnseal, 2048) {
+		t.Error("expected the unseal request to be found")
+	}
+
+	if (*rq)[0].sector.ID.Number != 2 || (*rq)[0].index != 0 {
+		t.Error("expected the unseal first, got", (*rq)[0].sector.ID.Number)
+	}
+}
@@ -38,3 +38,29 @@ func (l *readonlyProvider) AcquireSector(ctx context.Context, id storage.SectorR
 
 	return p, cancel, err
 }
+
+func (l *readonlyProvider) AcquireSectorForPoSt(ctx context.Context, id storage.SectorRef, challenges []uint64) (storiface.SectorPaths, func(), error) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	// use TryLock to avoid blocking
+	locked, err := l.index.StorageTryLock(ctx, id.ID, storiface.FTSealed|storiface.FTCache, storiface.FTNone)
+	if err != nil {
+		cancel()
+		return storiface.SectorPaths{}, nil, xerrors.Errorf("acquiring sector lock: %w", err)
+	}
+	if !locked {
+		cancel()
+		return storiface.SectorPaths{}, nil, xerrors.Errorf("failed to acquire sector lock")
+	}
+
+	p, done, err := l.stor.AcquireSectorForPoSt(ctx, id, challenges)
+	if err != nil {
+		cancel()
+		return storiface.SectorPaths{}, nil, err
+	}
+
+	return p, func() {
+		done()
+		cancel()
+	}, nil
+}
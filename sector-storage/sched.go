@@ -52,6 +52,12 @@ type WorkerSelector interface {
 	Cmp(ctx context.Context, task types.TaskType, a, b *workerHandle) (bool, error) // true if a is preferred over b
 }
 
+// workerPreparer is implemented by selectors which look up the acceptable
+// workers once before they are sorted with Cmp
+type workerPreparer interface {
+	prepare(ctx context.Context, workers []*workerHandle) error
+}
+
 type scheduler struct {
 	workersLk sync.RWMutex
 	workers   map[WorkerID]*workerHandle
@@ -60,6 +66,7 @@ type scheduler struct {
 	windowRequests chan *schedWindowRequest
 	workerChange   chan struct{} // worker added / changed/freed resources
 	workerDisable  chan workerDisableReq
+	prioChange     chan prioChangeReq
 
 	// owned by the sh.runSched goroutine
 	schedQueue  *requestQueue
@@ -67,6 +74,11 @@ type scheduler struct {
 
 	workTracker *workTracker
 
+	affinity *affinity
+	// required worker labels by sector class, see SealerConfig.SectorLabels
+	labelsLk     sync.RWMutex
+	sectorLabels map[string]map[string]string
+
 	info chan func(interface{})
 
 	closing  chan struct{}
@@ -88,6 +100,8 @@ type workerHandle struct {
 	activeWindows []*schedWindow
 
 	enabled bool
+	// draining keeps the worker disabled, see Manager.DrainWorker
+	draining bool
 
 	// for sync manager goroutine closing
 	cleanupStarted bool
@@ -112,12 +126,21 @@ type workerDisableReq struct {
 	done          func()
 }
 
+type prioChangeReq struct {
+	sector   abi.SectorID
+	taskType types.TaskType
+	priority int
+	done     chan bool
+}
+
 type activeResources struct {
 	memUsedMin uint64
 	memUsedMax uint64
 	gpuUsed    bool
 	cpuUse     uint64
 
+	taskCounts map[types.TaskType]int64
+
 	cond *sync.Cond
 }
 
@@ -151,6 +174,7 @@ func newScheduler() *scheduler {
 		windowRequests: make(chan *schedWindowRequest, 20),
 		workerChange:   make(chan struct{}, 20),
 		workerDisable:  make(chan workerDisableReq),
+		prioChange:     make(chan prioChangeReq),
 
 		schedQueue: &requestQueue{},
 
@@ -159,6 +183,11 @@ func newScheduler() *scheduler {
 			running: map[types.CallID]trackedWork{},
 		},
 
+		affinity: &affinity{
+			rules: map[types.TaskType][]*affinityRule{},
+			hosts: map[abi.SectorID]map[types.TaskType]string{},
+		},
+
 		info: make(chan func(interface{})),
 
 		closing: make(chan struct{}),
@@ -168,18 +197,19 @@ func newScheduler() *scheduler {
 
 func (sh *scheduler) Schedule(ctx context.Context, sector storage.SectorRef, taskType types.TaskType, sel WorkerSelector, prepare WorkerAction, work WorkerAction) error {
 	ret := make(chan workerResponse)
+	start := time.Now()
 
 	select {
 	case sh.schedule <- &workerRequest{
 		sector:   sector,
 		taskType: taskType,
 		priority: types.GetPriority(ctx),
-		sel:      sel,
+		sel:      sh.affinity.selector(taskType, sector, sh.labelSelector(ctx, taskType, sel), start),
 
 		prepare: prepare,
 		work:    work,
 
-		start: time.Now(),
+		start: start,
 
 		ret: ret,
 		ctx: ctx,
@@ -212,11 +242,14 @@ type SchedDiagRequestInfo struct {
 	Sector   abi.SectorID
 	TaskType types.TaskType
 	Priority int
+	Affinity []string          `json:",omitempty"`
+	Labels   map[string]string `json:",omitempty"`
 }
 
 type SchedDiagInfo struct {
-	Requests    []SchedDiagRequestInfo
-	OpenWindows []string
+	Requests      []SchedDiagRequestInfo
+	OpenWindows   []string
+	AffinityRules []string `json:",omitempty"`
 }
 
 func (sh *scheduler) runSched() {
@@ -225,6 +258,12 @@ func (sh *scheduler) runSched() {
 	iw := time.After(InitWait)
 	var initialised bool
 
+	// soft affinity rules can stop applying while a task is waiting, so
+	// reschedule periodically when waiting tasks have any. Tasks keep the rules
+	// they were scheduled with, so they are checked on every tick
+	affinityCheck := time.NewTicker(AffinityCheckInterval)
+	defer affinityCheck.Stop()
+
 	for {
 		var doSched bool
 		var toDisable []workerDisableReq
@@ -245,8 +284,14 @@ func (sh *scheduler) runSched() {
 		case req := <-sh.windowRequests:
 			sh.openWindows = append(sh.openWindows, req)
 			doSched = true
+		case req := <-sh.prioChange:
+			found := sh.schedQueue.SetPriority(req.sector, req.taskType, req.priority)
+			req.done <- found
+			doSched = found
 		case ireq := <-sh.info:
 			ireq(sh.diag())
+		case <-affinityCheck.C:
+			doSched = sh.spillPending()
 
 		case <-iw:
 			initialised = true
@@ -312,13 +357,25 @@ func (sh *scheduler) diag() SchedDiagInfo {
 	for sqi := 0; sqi < sh.schedQueue.Len(); sqi++ {
 		task := (*sh.schedQueue)[sqi]
 
-		out.Requests = append(out.Requests, SchedDiagRequestInfo{
+		info := SchedDiagRequestInfo{
 			Sector:   task.sector.ID,
 			TaskType: task.taskType,
 			Priority: task.priority,
-		})
+		}
+		sel := task.sel
+		if as, ok := sel.(*affinitySelector); ok {
+			info.Affinity = as.state()
+			sel = as.WorkerSelector
+		}
+		if ls, ok := sel.(*labelSelector); ok {
+			info.Labels = ls.labels
+		}
+
+		out.Requests = append(out.Requests, info)
 	}
 
+	out.AffinityRules = sh.affinity.describe()
+
 	sh.workersLk.RLock()
 	defer sh.workersLk.RUnlock()
 
@@ -329,6 +386,16 @@ func (sh *scheduler) diag() SchedDiagInfo {
 	return out
 }
 
+// spillPending tells if a waiting task has soft affinity rules which can spill
+func (sh *scheduler) spillPending() bool {
+	for _, req := range *sh.schedQueue {
+		if as, ok := req.sel.(*affinitySelector); ok && as.canSpill() {
+			return true
+		}
+	}
+	return false
+}
+
 func (sh *scheduler) trySched() {
 	/*
 		This assigns tasks to workers based on:
@@ -394,7 +461,7 @@ func (sh *scheduler) trySched() {
 				}
 
 				// TODO: allow bigger windows
-				if !windows[wnd].allocated.canHandleRequest(needRes, windowRequest.worker, "schedAcceptable", worker.info) {
+				if !windows[wnd].allocated.canHandleRequest(task.taskType, needRes, windowRequest.worker, "schedAcceptable", worker.info) {
 					continue
 				}
 
@@ -417,6 +484,20 @@ func (sh *scheduler) trySched() {
 				return
 			}
 
+			if p, ok := task.sel.(workerPreparer); ok {
+				workers := make([]*workerHandle, 0, len(acceptableWindows[sqi]))
+				for _, wnd := range acceptableWindows[sqi] {
+					workers = append(workers, sh.workers[sh.openWindows[wnd].worker])
+				}
+
+				rpcCtx, cancel := context.WithTimeout(task.ctx, SelectorTimeout)
+				err := p.prepare(rpcCtx, workers)
+				cancel()
+				if err != nil {
+					log.Errorf("trySched(1) preparing worker selection: %+v", err)
+				}
+			}
+
 			// Pick best worker (shuffle in case some workers are equally as good)
 			rand.Shuffle(len(acceptableWindows[sqi]), func(i, j int) {
 				acceptableWindows[sqi][i], acceptableWindows[sqi][j] = acceptableWindows[sqi][j], acceptableWindows[sqi][i] // nolint:scopelint
@@ -466,13 +547,13 @@ func (sh *scheduler) trySched() {
 			log.Debugf("SCHED try assign sqi:%d sector %d to window %d", sqi, task.sector.ID.Number, wnd)
 
 			// TODO: allow bigger windows
-			if !windows[wnd].allocated.canHandleRequest(needRes, wid, "schedAssign", info) {
+			if !windows[wnd].allocated.canHandleRequest(task.taskType, needRes, wid, "schedAssign", info) {
 				continue
 			}
 
 			log.Debugf("SCHED ASSIGNED sqi:%d sector %d task %s to window %d", sqi, task.sector.ID.Number, task.taskType, wnd)
 
-			windows[wnd].allocated.add(info.Resources, needRes)
+			windows[wnd].allocated.add(task.taskType, info.Resources, needRes)
 			// TODO: We probably want to re-sort acceptableWindows here based on new
 			//  workerHandle.utilization + windows[wnd].allocated.utilization (workerHandle.utilization is used in all
 			//  task selectors, but not in the same way, so need to figure out how to do that in a non-O(n^2 way), and
@@ -561,6 +642,23 @@ func (sh *scheduler) Info(ctx context.Context) (interface{}, error) {
 	}
 }
 
+// setPriority changes the priority of the queued requests of the task for the
+// sector, it returns false if there are none. Requests already assigned to a
+// worker keep their priority.
+func (sh *scheduler) setPriority(ctx context.Context, sector abi.SectorID, taskType types.TaskType, priority int) (bool, error) {
+	done := make(chan bool, 1)
+
+	select {
+	case sh.prioChange <- prioChangeReq{sector: sector, taskType: taskType, priority: priority, done: done}:
+	case <-sh.closing:
+		return false, xerrors.New("closing")
+	case <-ctx.Done():
+		return false, ctx.Err()
+	}
+
+	return <-done, nil
+}
+
 func (sh *scheduler) Close(ctx context.Context) error {
 	close(sh.closing)
 	select {
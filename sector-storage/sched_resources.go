@@ -4,21 +4,22 @@ import (
 	"sync"
 
 	"github.com/filecoin-project/venus-sealer/sector-storage/storiface"
+	"github.com/filecoin-project/venus-sealer/types"
 )
 
-func (a *activeResources) withResources(id WorkerID, wr storiface.WorkerInfo, r Resources, locker sync.Locker, cb func() error) error {
-	for !a.canHandleRequest(r, id, "withResources", wr) {
+func (a *activeResources) withResources(tt types.TaskType, id WorkerID, wr storiface.WorkerInfo, r Resources, locker sync.Locker, cb func() error) error {
+	for !a.canHandleRequest(tt, r, id, "withResources", wr) {
 		if a.cond == nil {
 			a.cond = sync.NewCond(locker)
 		}
 		a.cond.Wait()
 	}
 
-	a.add(wr.Resources, r)
+	a.add(tt, wr.Resources, r)
 
 	err := cb()
 
-	a.free(wr.Resources, r)
+	a.free(tt, wr.Resources, r)
 	if a.cond != nil {
 		a.cond.Broadcast()
 	}
@@ -26,7 +27,11 @@ func (a *activeResources) withResources(id WorkerID, wr storiface.WorkerInfo, r
 	return err
 }
 
-func (a *activeResources) add(wr storiface.WorkerResources, r Resources) {
+func (a *activeResources) add(tt types.TaskType, wr storiface.WorkerResources, r Resources) {
+	if a.taskCounts == nil {
+		a.taskCounts = map[types.TaskType]int64{}
+	}
+	a.taskCounts[tt]++
 	if r.CanGPU {
 		a.gpuUsed = true
 	}
@@ -35,7 +40,10 @@ func (a *activeResources) add(wr storiface.WorkerResources, r Resources) {
 	a.memUsedMax += r.MaxMemory
 }
 
-func (a *activeResources) free(wr storiface.WorkerResources, r Resources) {
+func (a *activeResources) free(tt types.TaskType, wr storiface.WorkerResources, r Resources) {
+	if a.taskCounts[tt] > 0 {
+		a.taskCounts[tt]--
+	}
 	if r.CanGPU {
 		a.gpuUsed = false
 	}
@@ -46,7 +54,13 @@ func (a *activeResources) free(wr storiface.WorkerResources, r Resources) {
 
 // canHandleRequest evaluates if the worker has enough available resources to
 // handle the request.
-func (a *activeResources) canHandleRequest(needRes Resources, wid WorkerID, caller string, info storiface.WorkerInfo) bool {
+func (a *activeResources) canHandleRequest(tt types.TaskType, needRes Resources, wid WorkerID, caller string, info storiface.WorkerInfo) bool {
+	// task limits are set explicitly by the operator, so they apply even when resources are ignored
+	if limit, ok := info.TaskLimits[tt]; ok && limit > 0 && a.taskCounts[tt] >= limit {
+		log.Debugf("sched: not scheduling on worker %s for %s; %s task limit reached - running %d, limit %d", wid, caller, tt.Short(), a.taskCounts[tt], limit)
+		return false
+	}
+
 	if info.IgnoreResources {
 		// shortcircuit; if this worker is ignoring resources, it can always handle the request.
 		return true
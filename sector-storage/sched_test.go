@@ -124,6 +124,10 @@ func (t *schedTestWorker) TaskNumbers(ctx context.Context) (string, error) {
 	return "0-0", nil
 }
 
+func (t *schedTestWorker) TaskLimits(ctx context.Context) (map[types.TaskType]int64, error) {
+	return nil, nil
+}
+
 func (s *schedTestWorker) SectorExists(context.Context, types.TaskType, storage.SectorRef) (bool, error) {
 	return true, nil
 }
@@ -624,7 +628,7 @@ func TestWindowCompact(t *testing.T) {
 						taskType: task,
 						sector:   storage.SectorRef{ProofType: spt},
 					})
-					window.allocated.add(wh.info.Resources, ResourceTable[task][spt])
+					window.allocated.add(task, wh.info.Resources, ResourceTable[task][spt])
 				}
 
 				wh.activeWindows = append(wh.activeWindows, window)
@@ -643,7 +647,7 @@ func TestWindowCompact(t *testing.T) {
 
 				for ti, task := range tasks {
 					require.Equal(t, task, wh.activeWindows[wi].todo[ti].taskType, "%d, %d", wi, ti)
-					expectRes.add(wh.info.Resources, ResourceTable[task][spt])
+					expectRes.add(task, wh.info.Resources, ResourceTable[task][spt])
 				}
 
 				require.Equal(t, expectRes.cpuUse, wh.activeWindows[wi].allocated.cpuUse, "%d", wi)
@@ -680,3 +684,27 @@ func TestWindowCompact(t *testing.T) {
 		[][]types.TaskType{{types.TTPreCommit1, types.TTPreCommit1, types.TTAddPiece}, {types.TTPreCommit1, types.TTPreCommit2}}),
 	)
 }
+
+func TestTaskLimits(t *testing.T) {
+	spt := abi.RegisteredSealProof_StackedDrg2KiBV1
+	info := storiface.WorkerInfo{
+		Resources:  decentWorkerResources,
+		TaskLimits: map[types.TaskType]int64{types.TTAddPiece: 1},
+	}
+	apRes := ResourceTable[types.TTAddPiece][spt]
+	pc1Res := ResourceTable[types.TTPreCommit1][spt]
+
+	var a activeResources
+	require.True(t, a.canHandleRequest(types.TTAddPiece, apRes, WorkerID{}, "test", info))
+
+	a.add(types.TTAddPiece, info.Resources, apRes)
+	require.False(t, a.canHandleRequest(types.TTAddPiece, apRes, WorkerID{}, "test", info))
+	require.True(t, a.canHandleRequest(types.TTPreCommit1, pc1Res, WorkerID{}, "test", info))
+
+	// limits are set by the operator, so they apply even if resources are ignored
+	info.IgnoreResources = true
+	require.False(t, a.canHandleRequest(types.TTAddPiece, apRes, WorkerID{}, "test", info))
+
+	a.free(types.TTAddPiece, info.Resources, apRes)
+	require.True(t, a.canHandleRequest(types.TTAddPiece, apRes, WorkerID{}, "test", info))
+}
@@ -125,14 +125,27 @@ func (sw *schedWorker) handleWorker() {
 				return // invalid session / exiting
 			}
 
+			// task limits can be changed on the worker at runtime
+			sw.updateTaskLimits(ctx)
+
 			// session looks good
 			{
 				sched.workersLk.Lock()
 				enabled := worker.enabled
-				worker.enabled = true
+				draining := worker.draining
+				if !draining {
+					worker.enabled = true
+				}
 				sched.workersLk.Unlock()
 
-				if !enabled {
+				if draining {
+					if enabled {
+						// hand the tasks assigned to the worker back to the scheduler
+						if err := sw.disable(ctx); err != nil {
+							log.Warnw("failed to disable draining worker", "worker", sw.wid, "error", err)
+						}
+					}
+				} else if !enabled {
 					// go send window requests
 					break
 				}
@@ -156,6 +169,24 @@ func (sw *schedWorker) handleWorker() {
 			}
 		}
 
+		sched.workersLk.RLock()
+		draining := worker.draining
+		sched.workersLk.RUnlock()
+
+		if draining {
+			// windows which were in flight when the worker got disabled
+			worker.wndLk.Lock()
+			assigned := len(worker.activeWindows) > 0
+			worker.wndLk.Unlock()
+
+			if assigned {
+				if err := sw.disable(ctx); err != nil {
+					log.Warnw("failed to disable draining worker", "worker", sw.wid, "error", err)
+				}
+			}
+			continue
+		}
+
 		// process assigned windows (non-blocking)
 		sched.workersLk.RLock()
 		worker.wndLk.Lock()
@@ -249,6 +280,20 @@ func (sw *schedWorker) checkSession(ctx context.Context) bool {
 	}
 }
 
+func (sw *schedWorker) updateTaskLimits(ctx context.Context) {
+	limits, err := sw.worker.workerRpc.TaskLimits(ctx)
+	if err != nil {
+		log.Debugw("failed to get worker task limits", "worker", sw.wid, "error", err)
+		return
+	}
+
+	sw.sched.workersLk.Lock()
+	sw.worker.lk.Lock()
+	sw.worker.info.TaskLimits = limits
+	sw.worker.lk.Unlock()
+	sw.sched.workersLk.Unlock()
+}
+
 func (sw *schedWorker) requestWindows() bool {
 	for ; sw.windowsRequested < SchedWindows; sw.windowsRequested++ {
 		select {
@@ -296,14 +341,14 @@ func (sw *schedWorker) workerCompactWindows() {
 
 			for ti, todo := range window.todo {
 				needRes := ResourceTable[todo.taskType][todo.sector.ProofType]
-				if !lower.allocated.canHandleRequest(needRes, sw.wid, "compactWindows", worker.info) {
+				if !lower.allocated.canHandleRequest(todo.taskType, needRes, sw.wid, "compactWindows", worker.info) {
 					continue
 				}
 
 				moved = append(moved, ti)
 				lower.todo = append(lower.todo, todo)
-				lower.allocated.add(worker.info.Resources, needRes)
-				window.allocated.free(worker.info.Resources, needRes)
+				lower.allocated.add(todo.taskType, worker.info.Resources, needRes)
+				window.allocated.free(todo.taskType, worker.info.Resources, needRes)
 			}
 
 			if len(moved) > 0 {
@@ -352,7 +397,7 @@ assignLoop:
 			worker.lk.Lock()
 			for t, todo := range firstWindow.todo {
 				needRes := ResourceTable[todo.taskType][todo.sector.ProofType]
-				if worker.preparing.canHandleRequest(needRes, sw.wid, "startPreparing", worker.info) {
+				if worker.preparing.canHandleRequest(todo.taskType, needRes, sw.wid, "startPreparing", worker.info) {
 					tidx = t
 					break
 				}
@@ -393,7 +438,7 @@ func (sw *schedWorker) startProcessingTask(taskDone chan struct{}, req *workerRe
 	needRes := ResourceTable[req.taskType][req.sector.ProofType]
 
 	w.lk.Lock()
-	w.preparing.add(w.info.Resources, needRes)
+	w.preparing.add(req.taskType, w.info.Resources, needRes)
 	w.lk.Unlock()
 
 	go func() {
@@ -404,7 +449,7 @@ func (sw *schedWorker) startProcessingTask(taskDone chan struct{}, req *workerRe
 		w.lk.Lock()
 
 		if err != nil {
-			w.preparing.free(w.info.Resources, needRes)
+			w.preparing.free(req.taskType, w.info.Resources, needRes)
 			w.lk.Unlock()
 
 			select {
@@ -424,8 +469,8 @@ func (sw *schedWorker) startProcessingTask(taskDone chan struct{}, req *workerRe
 		}
 
 		// wait (if needed) for resources in the 'active' window
-		err = w.active.withResources(sw.wid, w.info, needRes, &w.lk, func() error {
-			w.preparing.free(w.info.Resources, needRes)
+		err = w.active.withResources(req.taskType, sw.wid, w.info, needRes, &w.lk, func() error {
+			w.preparing.free(req.taskType, w.info.Resources, needRes)
 			w.lk.Unlock()
 			defer w.lk.Lock() // we MUST return locked from this function
 
@@ -438,6 +483,9 @@ func (sw *schedWorker) startProcessingTask(taskDone chan struct{}, req *workerRe
 			log.Infof("Sector %d work for %s ...", req.sector.ID.Number, req.taskType)
 			err = req.work(req.ctx, sh.workTracker.worker(sw.wid, w.info, w.workerRpc))
 			log.Infof("Sector %d work for %s end ...", req.sector.ID.Number, req.taskType)
+			if err == nil {
+				sh.affinity.taskDone(req.sector.ID, req.taskType, w.info.Hostname)
+			}
 
 			select {
 			case req.ret <- workerResponse{err: err}:
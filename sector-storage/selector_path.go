@@ -0,0 +1,53 @@
+package sectorstorage
+
+import (
+	"context"
+
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/specs-storage/storage"
+
+	"github.com/filecoin-project/venus-sealer/sector-storage/stores"
+	"github.com/filecoin-project/venus-sealer/types"
+)
+
+// pathSelector selects workers with access to a storage path
+type pathSelector struct {
+	storage stores.ID
+}
+
+func newPathSelector(storage stores.ID) *pathSelector {
+	return &pathSelector{
+		storage: storage,
+	}
+}
+
+func (s *pathSelector) Ok(ctx context.Context, task types.TaskType, spt abi.RegisteredSealProof, sector storage.SectorRef, whnd *workerHandle) (bool, error) {
+	tasks, err := whnd.workerRpc.TaskTypes(ctx)
+	if err != nil {
+		return false, xerrors.Errorf("getting supported worker task types: %w", err)
+	}
+	if _, supported := tasks[task]; !supported {
+		return false, nil
+	}
+
+	paths, err := whnd.workerRpc.Paths(ctx)
+	if err != nil {
+		return false, xerrors.Errorf("getting worker paths: %w", err)
+	}
+
+	for _, path := range paths {
+		if path.ID == s.storage {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (s *pathSelector) Cmp(ctx context.Context, task types.TaskType, a, b *workerHandle) (bool, error) {
+	return a.utilization() < b.utilization(), nil
+}
+
+var _ WorkerSelector = &pathSelector{}
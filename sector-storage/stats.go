@@ -17,6 +17,11 @@ func (m *Manager) WorkerStats() map[uuid.UUID]storiface.WorkerStats {
 
 	for id, handle := range m.sched.workers {
 		handle.lk.Lock()
+		taskCounts := make(map[types.TaskType]int64, len(handle.active.taskCounts))
+		for tt, n := range handle.active.taskCounts {
+			taskCounts[tt] = n
+		}
+
 		out[uuid.UUID(id)] = storiface.WorkerStats{
 			Info:    handle.info,
 			Enabled: handle.enabled,
@@ -25,6 +30,7 @@ func (m *Manager) WorkerStats() map[uuid.UUID]storiface.WorkerStats {
 			MemUsedMax: handle.active.memUsedMax,
 			GpuUsed:    handle.active.gpuUsed,
 			CpuUse:     handle.active.cpuUse,
+			TaskCounts: taskCounts,
 		}
 		handle.lk.Unlock()
 	}
@@ -0,0 +1,146 @@
+package stores
+
+import (
+	"context"
+	"encoding/json"
+	"os/exec"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+// HealthStatus classifies the health of a storage path
+type HealthStatus string
+
+const (
+	HealthOK HealthStatus = "ok"
+	// HealthDegraded paths are slow or show errors, new sector files are only
+	// allocated in them when no healthy path has space
+	HealthDegraded HealthStatus = "degraded"
+	// HealthFailing paths don't get new sector files, and sectors only stored
+	// in them are reported faulty before proving
+	HealthFailing HealthStatus = "failing"
+)
+
+var (
+	// HealthProbeInterval is the interval of the probes of local paths
+	HealthProbeInterval = time.Minute
+
+	// latencies of a read probe, the slowest of the reads, marking the path
+	// degraded or failing
+	DegradedReadLatency = time.Second
+	FailingReadLatency  = 10 * time.Second
+
+	// latencies of a write probe, including syncing the written data
+	DegradedWriteLatency = 5 * time.Second
+	FailingWriteLatency  = 30 * time.Second
+
+	// FaultyFailingProbes is the number of consecutive probes which found a
+	// path failing after which the sectors only stored in it are reported
+	// faulty without reading them, a single slow probe isn't enough
+	FaultyFailingProbes = 3
+)
+
+// PathHealth is the result of the latest health probes of a storage path
+type PathHealth struct {
+	Status HealthStatus
+	// Reasons explain a status other than ok
+	Reasons []string `json:",omitempty"`
+
+	// ReadLatency is the slowest of the timed random reads of a sealed file, 0
+	// when the path has no local sealed files
+	ReadLatency time.Duration
+	// WriteLatency is the time taken to write and sync a probe file, only
+	// measured on sealing paths
+	WriteLatency time.Duration
+
+	// IOErrors counts the I/O errors seen in the path since the sealer started
+	IOErrors uint64
+
+	// FailingProbes counts the consecutive probes which found the path failing
+	FailingProbes int
+
+	SMART *SMARTInfo `json:",omitempty"`
+
+	Checked time.Time
+}
+
+func (h *PathHealth) worsen(s HealthStatus, reason string) {
+	if s == HealthFailing || h.Status != HealthFailing {
+		h.Status = s
+	}
+	h.Reasons = append(h.Reasons, reason)
+}
+
+// SMARTInfo is the SMART data of the block device of a path read with
+// smartctl, counters which the device doesn't report are 0
+type SMARTInfo struct {
+	Device string
+
+	Passed      bool
+	Temperature int
+
+	// ATA reallocated and pending sectors
+	ReallocatedSectors int64
+	PendingSectors     int64
+	// NVMe media and data integrity errors
+	MediaErrors int64
+}
+
+const (
+	smartAttrReallocated = 5
+	smartAttrPending     = 197
+)
+
+// readSMART reads the SMART health and attributes of the device with smartctl
+func readSMART(ctx context.Context, device string) (*SMARTInfo, error) {
+	out, err := exec.CommandContext(ctx, "smartctl", "--json", "-H", "-A", device).Output()
+	if err != nil {
+		// the exit status is a bitmask, only the lowest two bits mean the
+		// data couldn't be read, the others report the device health
+		var ee *exec.ExitError
+		if !xerrors.As(err, &ee) || ee.ExitCode()&0x3 != 0 {
+			return nil, xerrors.Errorf("running smartctl: %w", err)
+		}
+	}
+
+	var res struct {
+		SmartStatus struct {
+			Passed bool `json:"passed"`
+		} `json:"smart_status"`
+		Temperature struct {
+			Current int `json:"current"`
+		} `json:"temperature"`
+		AtaSmartAttributes struct {
+			Table []struct {
+				ID  int `json:"id"`
+				Raw struct {
+					Value int64 `json:"value"`
+				} `json:"raw"`
+			} `json:"table"`
+		} `json:"ata_smart_attributes"`
+		NvmeSmartHealthInformationLog struct {
+			MediaErrors int64 `json:"media_errors"`
+		} `json:"nvme_smart_health_information_log"`
+	}
+	if err := json.Unmarshal(out, &res); err != nil {
+		return nil, xerrors.Errorf("parsing smartctl output: %w", err)
+	}
+
+	si := &SMARTInfo{
+		Device:      device,
+		Passed:      res.SmartStatus.Passed,
+		Temperature: res.Temperature.Current,
+		MediaErrors: res.NvmeSmartHealthInformationLog.MediaErrors,
+	}
+	for _, attr := range res.AtaSmartAttributes.Table {
+		switch attr.ID {
+		case smartAttrReallocated:
+			si.ReallocatedSectors = attr.Raw.Value
+		case smartAttrPending:
+			si.PendingSectors = attr.Raw.Value
+		}
+	}
+
+	return si, nil
+}
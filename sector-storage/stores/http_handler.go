@@ -55,6 +55,7 @@ func (handler *FetchHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	mux.HandleFunc("/remote/stat/{id}", handler.remoteStatFs).Methods("GET")
 	mux.HandleFunc("/remote/{type}/{id}/{spt}/allocated/{offset}/{size}", handler.remoteGetAllocated).Methods("GET")
+	mux.HandleFunc("/remote/{type}/{id}/{spt}/allocated", handler.remoteGetAllocatedRanges).Methods("GET")
 	mux.HandleFunc("/remote/{type}/{id}", handler.remoteGetSector).Methods("GET")
 	mux.HandleFunc("/remote/{type}/{id}", handler.remoteDeleteSector).Methods("DELETE")
 
@@ -128,6 +129,7 @@ func (handler *FetchHandler) remoteGetSector(w http.ResponseWriter, r *http.Requ
 
 	stat, err := os.Stat(path)
 	if err != nil {
+		fileError(handler.Local, err)
 		log.Errorf("os.Stat: %+v", err)
 		w.WriteHeader(500)
 		return
@@ -154,9 +156,19 @@ func (handler *FetchHandler) remoteGetSector(w http.ResponseWriter, r *http.Requ
 			return
 		}
 	} else {
+		f, err := os.Open(path)
+		if err != nil {
+			fileError(handler.Local, err)
+			log.Errorf("opening sector file: %+v", err)
+			w.WriteHeader(500)
+			return
+		}
+		defer f.Close() // nolint
+
 		w.Header().Set("Content-Type", "application/octet-stream")
-		// will do a ranged read over the file at the given path if the caller has asked for a ranged read in the request headers.
-		http.ServeFile(w, r, path)
+		// will do a ranged read over the file if the caller has asked for a ranged read in the request headers,
+		// read errors are counted as I/O errors of the storage path.
+		http.ServeContent(w, r, stat.Name(), stat.ModTime(), &fileErrorReader{File: f, s: handler.Local})
 	}
 
 	log.Debugf("served sector file/dir, sectorID=%+v, fileType=%s, path=%s", id, ft, path)
@@ -294,6 +306,114 @@ func (handler *FetchHandler) remoteGetAllocated(w http.ResponseWriter, r *http.R
 	w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
 }
 
+// remoteGetAllocatedRanges returns the ranges of the Unsealed sector file which hold
+// unsealed data as json, read from the allocation trailer of the file.
+func (handler *FetchHandler) remoteGetAllocatedRanges(w http.ResponseWriter, r *http.Request) {
+	log.Debugf("SERVE Alloc ranges %s", r.URL)
+	vars := mux.Vars(r)
+
+	id, err := storiface.ParseSectorID(vars["id"])
+	if err != nil {
+		log.Errorf("parsing sectorID: %+v", err)
+		w.WriteHeader(500)
+		return
+	}
+
+	ft, err := ftFromString(vars["type"])
+	if err != nil {
+		log.Errorf("ftFromString: %+v", err)
+		w.WriteHeader(500)
+		return
+	}
+	if ft != storiface.FTUnsealed {
+		log.Errorf("/allocated only supports unsealed sector files")
+		w.WriteHeader(500)
+		return
+	}
+
+	spti, err := strconv.ParseInt(vars["spt"], 10, 64)
+	if err != nil {
+		log.Errorf("parsing spt: %+v", err)
+		w.WriteHeader(500)
+		return
+	}
+	ssize, err := abi.RegisteredSealProof(spti).SectorSize()
+	if err != nil {
+		log.Errorf("spt.SectorSize(): %+v", err)
+		w.WriteHeader(500)
+		return
+	}
+
+	// passing 0 spt because we don't allocate anything
+	si := storage.SectorRef{
+		ID:        id,
+		ProofType: 0,
+	}
+
+	paths, _, err := handler.Local.AcquireSector(r.Context(), si, ft, storiface.FTNone, storiface.PathStorage, storiface.AcquireMove)
+	if err != nil {
+		log.Errorf("AcquireSector: %+v", err)
+		w.WriteHeader(500)
+		return
+	}
+
+	path := storiface.PathByType(paths, ft)
+	if path == "" {
+		w.WriteHeader(404)
+		return
+	}
+
+	pf, err := handler.PfHandler.OpenPartialFile(abi.PaddedPieceSize(ssize), path)
+	if err != nil {
+		log.Error("opening partial file: ", err)
+		w.WriteHeader(500)
+		return
+	}
+	defer func() {
+		if err := pf.Close(); err != nil {
+			log.Error("closing partial file: ", err)
+		}
+	}()
+
+	ranges, err := allocatedRanges(pf)
+	if err != nil {
+		log.Error("allocated ranges: ", err)
+		w.WriteHeader(500)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(ranges); err != nil {
+		log.Warnf("error writing allocated ranges response: %+v", err)
+	}
+}
+
+// allocatedRanges returns the ranges of the partial file which hold unsealed data
+func allocatedRanges(pf *partialfile.PartialFile) ([]storage.Range, error) {
+	it, err := pf.Allocated()
+	if err != nil {
+		return nil, err
+	}
+
+	out := []storage.Range{}
+	var at uint64
+	for it.HasNext() {
+		r, err := it.NextRun()
+		if err != nil {
+			return nil, err
+		}
+
+		if r.Val {
+			out = append(out, storage.Range{
+				Offset: abi.PaddedPieceSize(at).Unpadded(),
+				Size:   abi.PaddedPieceSize(r.Len).Unpadded(),
+			})
+		}
+		at += r.Len
+	}
+
+	return out, nil
+}
+
 func ftFromString(t string) (storiface.SectorFileType, error) {
 	switch t {
 	case storiface.FTUnsealed.String():
@@ -34,11 +34,18 @@ type StorageInfo struct {
 
 	CanSeal  bool
 	CanStore bool
+
+	// Backend is the type of the object store holding the stored files of
+	// the path, empty for paths keeping all files in the local filesystem
+	Backend string
 }
 
 type HealthReport struct {
 	Stat fsutil.FsStat
 	Err  string
+
+	// Health is the result of the latest health probes of the path
+	Health PathHealth
 }
 
 type SectorStorageInfo struct {
@@ -48,6 +55,7 @@ type SectorStorageInfo struct {
 
 	CanSeal  bool
 	CanStore bool
+	Backend  string
 
 	Primary bool
 }
@@ -56,6 +64,7 @@ type SectorIndex interface { // part of storage-miner api
 	StorageAttach(context.Context, StorageInfo, fsutil.FsStat) error
 	StorageInfo(context.Context, ID) (StorageInfo, error)
 	StorageReportHealth(context.Context, ID, HealthReport) error
+	StorageHealth(context.Context, ID) (PathHealth, error)
 
 	StorageDeclareSector(ctx context.Context, storageID ID, s abi.SectorID, ft storiface.SectorFileType, primary bool) error
 	StorageDropSector(ctx context.Context, storageID ID, s abi.SectorID, ft storiface.SectorFileType) error
@@ -86,6 +95,7 @@ type storageEntry struct {
 
 	lastHeartbeat time.Time
 	heartbeatErr  error
+	health        PathHealth
 }
 
 type Index struct {
@@ -163,6 +173,7 @@ func (i *Index) StorageAttach(ctx context.Context, si StorageInfo, st fsutil.FsS
 		i.stores[si.ID].info.MaxStorage = si.MaxStorage
 		i.stores[si.ID].info.CanSeal = si.CanSeal
 		i.stores[si.ID].info.CanStore = si.CanStore
+		i.stores[si.ID].info.Backend = si.Backend
 
 		return nil
 	}
@@ -191,10 +202,43 @@ func (i *Index) StorageReportHealth(ctx context.Context, id ID, report HealthRep
 		ent.heartbeatErr = nil
 	}
 	ent.lastHeartbeat = time.Now()
+	ent.health = report.Health
 
 	return nil
 }
 
+// StorageHealth returns the health reported for the path, paths which don't
+// report their health are failing
+func (i *Index) StorageHealth(ctx context.Context, id ID) (PathHealth, error) {
+	i.lk.RLock()
+	defer i.lk.RUnlock()
+
+	ent, ok := i.stores[id]
+	if !ok {
+		return PathHealth{}, xerrors.Errorf("sector store not found")
+	}
+
+	return ent.pathHealth(), nil
+}
+
+func (ent *storageEntry) pathHealth() PathHealth {
+	h := ent.health
+	h.Reasons = append([]string(nil), h.Reasons...)
+	if h.Status == "" {
+		// not probed yet, or reported by an older worker
+		h.Status = HealthOK
+	}
+
+	if since := time.Since(ent.lastHeartbeat); since > SkippedHeartbeatThresh {
+		h.worsen(HealthFailing, fmt.Sprintf("no heartbeat for %s", since.Truncate(time.Second)))
+	}
+	if ent.heartbeatErr != nil {
+		h.worsen(HealthFailing, fmt.Sprintf("heartbeat error: %s", ent.heartbeatErr))
+	}
+
+	return h
+}
+
 func (i *Index) StorageDeclareSector(ctx context.Context, storageID ID, s abi.SectorID, ft storiface.SectorFileType, primary bool) error {
 	i.lk.Lock()
 	defer i.lk.Unlock()
@@ -306,6 +350,7 @@ func (i *Index) StorageFindSector(ctx context.Context, s abi.SectorID, ft storif
 
 			CanSeal:  st.info.CanSeal,
 			CanStore: st.info.CanStore,
+			Backend:  st.info.Backend,
 
 			Primary: isprimary[id],
 		})
@@ -359,6 +404,7 @@ func (i *Index) StorageFindSector(ctx context.Context, s abi.SectorID, ft storif
 
 				CanSeal:  st.info.CanSeal,
 				CanStore: st.info.CanStore,
+				Backend:  st.info.Backend,
 
 				Primary: false,
 			})
@@ -423,6 +469,11 @@ func (i *Index) StorageBestAlloc(ctx context.Context, allocate storiface.SectorF
 			continue
 		}
 
+		if p.health.Status == HealthFailing {
+			log.Debugf("not allocating on %s, path is failing: %v", p.info.ID, p.health.Reasons)
+			continue
+		}
+
 		candidates = append(candidates, *p)
 	}
 
@@ -431,6 +482,12 @@ func (i *Index) StorageBestAlloc(ctx context.Context, allocate storiface.SectorF
 	}
 
 	sort.Slice(candidates, func(i, j int) bool {
+		// degraded paths are only used when healthy paths are out of space
+		id, jd := candidates[i].health.Status == HealthDegraded, candidates[j].health.Status == HealthDegraded
+		if id != jd {
+			return jd
+		}
+
 		iw := big.Mul(big.NewInt(candidates[i].fsi.Available), big.NewInt(int64(candidates[i].info.Weight)))
 		jw := big.Mul(big.NewInt(candidates[j].fsi.Available), big.NewInt(int64(candidates[j].info.Weight)))
 
@@ -41,6 +41,10 @@ type Store interface {
 	// move sectors into storage
 	MoveStorage(ctx context.Context, s storage.SectorRef, types storiface.SectorFileType) error
 
+	// declare a sector file written to a path in the index, and in the manifest
+	// of the path when it's a local path
+	DeclareSector(ctx context.Context, id ID, s abi.SectorID, ft storiface.SectorFileType, primary bool) error
+
 	FsStat(ctx context.Context, id ID) (fsutil.FsStat, error)
 
 	Reserve(ctx context.Context, sid storage.SectorRef, ft storiface.SectorFileType, storageIDs storiface.SectorPaths, overheadTab map[storiface.SectorFileType]int) (func(), error)
@@ -9,6 +9,7 @@ import (
 	"os"
 	"path/filepath"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"golang.org/x/xerrors"
@@ -46,6 +47,19 @@ type LocalStorageMeta struct {
 	// MaxStorage specifies the maximum number of bytes to use for sector storage
 	// (0 = unlimited)
 	MaxStorage uint64
+
+	// Backend keeps the stored files in an object store instead of the path,
+	// which then only holds unsealed files and local copies. It can only be
+	// set for storage paths, and MaxStorage must be set to report capacity.
+	Backend *ObjectStoreConfig `json:",omitempty"`
+
+	// Layout of the sector files in the path, see 'storage relayout' for
+	// changing the layout of a path in use
+	Layout storiface.SectorLayout `json:",omitempty"`
+
+	// SmartDevice is the block device backing the path, its SMART data is
+	// read with smartctl in the health probes of the path when set
+	SmartDevice string `json:",omitempty"`
 }
 
 // StorageConfig .lotusstorage/storage.json
@@ -78,17 +92,48 @@ type Local struct {
 	paths map[ID]*path
 
 	localLk sync.RWMutex
+
+	// fetching has the running fetches of files from object stores, fetchLk
+	// guards it and the fetched copies of the paths
+	fetchLk  sync.Mutex
+	fetching map[Decl]chan struct{}
 }
 
 type path struct {
 	local      string // absolute local path
 	maxStorage uint64
+	layout     storiface.SectorLayout
+
+	// manifest lists the sector files in the local directory of the path
+	manifest *manifest
+
+	canSeal     bool
+	smartDevice string
+
+	// health is the result of the latest health probes, ioErrors counts the
+	// I/O errors seen in the path
+	health         PathHealth
+	probing        int32
+	ioErrors       uint64
+	probedIOErrors uint64
 
 	reserved     int64
 	reservations map[abi.SectorID]storiface.SectorFileType
+
+	// objects is the object store backend of the path, holding objectTypes
+	objects     ObjectStore
+	objectTypes storiface.SectorFileType
+	objectsUsed int64
+	// fetched has the time the local copies of object-backed files were
+	// last acquired
+	fetched map[Decl]time.Time
 }
 
 func (p *path) stat(ls LocalStorage) (fsutil.FsStat, error) {
+	if p.objects != nil {
+		return p.objectStat(ls)
+	}
+
 	stat, err := ls.Stat(p.local)
 	if err != nil {
 		return fsutil.FsStat{}, xerrors.Errorf("stat %s: %w", p.local, err)
@@ -160,7 +205,7 @@ func (p *path) stat(ls LocalStorage) (fsutil.FsStat, error) {
 }
 
 func (p *path) sectorPath(sid abi.SectorID, fileType storiface.SectorFileType) string {
-	return filepath.Join(p.local, fileType.String(), storiface.SectorName(sid))
+	return p.layout.SectorPath(p.local, sid, fileType)
 }
 
 func NewLocal(ctx context.Context, ls LocalStorage, index SectorIndex, urls []string) (*Local, error) {
@@ -169,33 +214,66 @@ func NewLocal(ctx context.Context, ls LocalStorage, index SectorIndex, urls []st
 		index:        index,
 		urls:         urls,
 
-		paths: map[ID]*path{},
+		paths:    map[ID]*path{},
+		fetching: map[Decl]chan struct{}{},
 	}
 	return l, l.open(ctx)
 }
 
-func (st *Local) OpenPath(ctx context.Context, p string) error {
-	st.localLk.Lock()
-	defer st.localLk.Unlock()
-
+// ReadStorageMeta reads the metadata of the storage path at p
+func ReadStorageMeta(p string) (LocalStorageMeta, error) {
 	mb, err := ioutil.ReadFile(filepath.Join(p, MetaFile))
 	if err != nil {
-		return xerrors.Errorf("reading storage metadata for %s: %w", p, err)
+		return LocalStorageMeta{}, xerrors.Errorf("reading storage metadata for %s: %w", p, err)
 	}
 
 	var meta LocalStorageMeta
 	if err := json.Unmarshal(mb, &meta); err != nil {
-		return xerrors.Errorf("unmarshalling storage metadata for %s: %w", p, err)
+		return LocalStorageMeta{}, xerrors.Errorf("unmarshalling storage metadata for %s: %w", p, err)
+	}
+
+	if _, err := storiface.ParseSectorLayout(string(meta.Layout)); err != nil {
+		return LocalStorageMeta{}, xerrors.Errorf("storage metadata for %s: %w", p, err)
+	}
+
+	return meta, nil
+}
+
+func (st *Local) OpenPath(ctx context.Context, p string) error {
+	st.localLk.Lock()
+	defer st.localLk.Unlock()
+
+	meta, err := ReadStorageMeta(p)
+	if err != nil {
+		return err
 	}
 
 	// TODO: Check existing / dedupe
 
 	out := &path{
-		local: p,
+		local:  p,
+		layout: meta.Layout,
+
+		canSeal:     meta.CanSeal,
+		smartDevice: meta.SmartDevice,
 
 		maxStorage:   meta.MaxStorage,
 		reserved:     0,
 		reservations: map[abi.SectorID]storiface.SectorFileType{},
+		fetched:      map[Decl]time.Time{},
+	}
+
+	var objects map[abi.SectorID]storiface.SectorFileType
+	if meta.Backend != nil {
+		out.objects, out.objectTypes, err = openObjects(meta)
+		if err != nil {
+			return xerrors.Errorf("opening object store for %s: %w", p, err)
+		}
+
+		objects, out.objectsUsed, err = out.listObjects(ctx)
+		if err != nil {
+			return xerrors.Errorf("listing objects of %s: %w", p, err)
+		}
 	}
 
 	fst, err := out.stat(st.localStorage)
@@ -210,17 +288,39 @@ func (st *Local) OpenPath(ctx context.Context, p string) error {
 		MaxStorage: meta.MaxStorage,
 		CanSeal:    meta.CanSeal,
 		CanStore:   meta.CanStore,
+		Backend:    backendType(meta),
 	}, fst)
 	if err != nil {
 		return xerrors.Errorf("declaring storage in index: %w", err)
 	}
 
-	if err := st.declareSectors(ctx, p, meta.ID, meta.CanStore); err != nil {
+	out.manifest, err = readManifest(p)
+	fromManifest := err == nil
+	if fromManifest {
+		if err := st.declareManifest(ctx, out, meta.ID, meta.CanStore); err != nil {
+			return err
+		}
+	} else {
+		if !xerrors.Is(err, os.ErrNotExist) {
+			log.Warnw("listing sector files of path, its manifest can't be used", "path", p, "error", err)
+		}
+
+		out.manifest = newManifest(p)
+		if err := st.declareSectors(ctx, out, meta.ID, meta.CanStore); err != nil {
+			return err
+		}
+	}
+	if err := st.declareObjects(ctx, meta.ID, objects, meta.CanStore); err != nil {
 		return err
 	}
 
 	st.paths[meta.ID] = out
 
+	if fromManifest {
+		// files may have changed while the sealer wasn't running
+		go st.verifyManifestBackground(meta.ID)
+	}
+
 	return nil
 }
 
@@ -238,28 +338,43 @@ func (st *Local) open(ctx context.Context) error {
 	}
 
 	go st.reportHealth(ctx)
+	go st.probeHealth(ctx)
+	go st.evictFetched(ctx)
 
 	return nil
 }
 
+// Redeclare declares the storage paths and the sector files in them again. The
+// manifests of the paths are verified before it returns, so files copied into
+// the paths are declared too.
 func (st *Local) Redeclare(ctx context.Context) error {
-	st.localLk.Lock()
-	defer st.localLk.Unlock()
+	ids, err := st.redeclarePaths(ctx)
+	if err != nil {
+		return err
+	}
 
-	for id, p := range st.paths {
-		mb, err := ioutil.ReadFile(filepath.Join(p.local, MetaFile))
+	for _, id := range ids {
+		res, err := st.VerifyManifest(ctx, id)
 		if err != nil {
-			return xerrors.Errorf("reading storage metadata for %s: %w", p.local, err)
+			return xerrors.Errorf("verifying sector manifest of %s: %w", id, err)
 		}
-
-		var meta LocalStorageMeta
-		if err := json.Unmarshal(mb, &meta); err != nil {
-			return xerrors.Errorf("unmarshalling storage metadata for %s: %w", p.local, err)
+		if len(res.Added)+len(res.Removed) > 0 {
+			log.Infow("redeclared sector files", "path", id, "added", res.Added, "removed", res.Removed)
 		}
+	}
 
-		fst, err := p.stat(st.localStorage)
+	return nil
+}
+
+func (st *Local) redeclarePaths(ctx context.Context) ([]ID, error) {
+	st.localLk.Lock()
+	defer st.localLk.Unlock()
+
+	var ids []ID
+	for id, p := range st.paths {
+		meta, err := ReadStorageMeta(p.local)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		if id != meta.ID {
@@ -267,6 +382,23 @@ func (st *Local) Redeclare(ctx context.Context) error {
 			continue
 		}
 
+		p.layout = meta.Layout
+		p.canSeal = meta.CanSeal
+		p.smartDevice = meta.SmartDevice
+
+		var objects map[abi.SectorID]storiface.SectorFileType
+		if p.objects != nil {
+			objects, p.objectsUsed, err = p.listObjects(ctx)
+			if err != nil {
+				return nil, xerrors.Errorf("listing objects of %s: %w", p.local, err)
+			}
+		}
+
+		fst, err := p.stat(st.localStorage)
+		if err != nil {
+			return nil, err
+		}
+
 		err = st.index.StorageAttach(ctx, StorageInfo{
 			ID:         id,
 			URLs:       st.urls,
@@ -274,49 +406,66 @@ func (st *Local) Redeclare(ctx context.Context) error {
 			MaxStorage: meta.MaxStorage,
 			CanSeal:    meta.CanSeal,
 			CanStore:   meta.CanStore,
+			Backend:    backendType(meta),
 		}, fst)
 		if err != nil {
-			return xerrors.Errorf("redeclaring storage in index: %w", err)
+			return nil, xerrors.Errorf("redeclaring storage in index: %w", err)
 		}
 
-		if err := st.declareSectors(ctx, p.local, meta.ID, meta.CanStore); err != nil {
-			return xerrors.Errorf("redeclaring sectors: %w", err)
+		if err := st.declareManifest(ctx, p, meta.ID, meta.CanStore); err != nil {
+			return nil, xerrors.Errorf("redeclaring sectors: %w", err)
+		}
+		if err := st.declareObjects(ctx, meta.ID, objects, meta.CanStore); err != nil {
+			return nil, xerrors.Errorf("redeclaring objects: %w", err)
 		}
+
+		ids = append(ids, id)
 	}
 
-	return nil
+	return ids, nil
 }
 
-func (st *Local) declareSectors(ctx context.Context, p string, id ID, primary bool) error {
+// declareSectors declares the sector files found in the path, and writes the
+// manifest of the path listing them
+func (st *Local) declareSectors(ctx context.Context, p *path, id ID, primary bool) error {
+	var entries []ManifestEntry
 	for _, t := range storiface.PathTypes {
-		ents, err := ioutil.ReadDir(filepath.Join(p, t.String()))
+		dir := filepath.Join(p.local, t.String())
+		ents, err := ioutil.ReadDir(dir)
 		if err != nil {
 			if os.IsNotExist(err) {
-				if err := os.MkdirAll(filepath.Join(p, t.String()), 0755); err != nil { // nolint
-					return xerrors.Errorf("openPath mkdir '%s': %w", filepath.Join(p, t.String()), err)
+				if err := os.MkdirAll(dir, 0755); err != nil { // nolint
+					return xerrors.Errorf("openPath mkdir '%s': %w", dir, err)
 				}
 
 				continue
 			}
-			return xerrors.Errorf("listing %s: %w", filepath.Join(p, t.String()), err)
+			return xerrors.Errorf("listing %s: %w", dir, err)
 		}
 
-		for _, ent := range ents {
-			if ent.Name() == FetchTempSubdir {
-				continue
-			}
-
-			sid, err := storiface.ParseSectorID(ent.Name())
-			if err != nil {
-				return xerrors.Errorf("parse sector id %s: %w", ent.Name(), err)
-			}
+		sectors, err := listSectorFiles(dir, ents)
+		if err != nil {
+			return err
+		}
 
+		for sid, spath := range sectors {
 			if err := st.index.StorageDeclareSector(ctx, id, sid, t, primary); err != nil {
 				return xerrors.Errorf("declare sector %d(t:%d) -> %s: %w", sid, t, id, err)
 			}
+
+			e, err := statSectorFile(sid, t, spath)
+			if err != nil {
+				return xerrors.Errorf("stat %s: %w", spath, err)
+			}
+			entries = append(entries, e)
 		}
 	}
 
+	if err := p.manifest.put(entries...); err != nil {
+		// eg. read-only paths, the files are listed again when the path is opened
+		log.Warnw("writing sector manifest", "path", id, "error", err)
+	}
+
 	return nil
 }
 
@@ -341,7 +490,8 @@ func (st *Local) reportStorage(ctx context.Context) {
 	toReport := map[ID]HealthReport{}
 	for id, p := range st.paths {
 		stat, err := p.stat(st.localStorage)
-		r := HealthReport{Stat: stat}
+		r := HealthReport{Stat: stat, Health: p.health}
+		r.Health.IOErrors = atomic.LoadUint64(&p.ioErrors)
 		if err != nil {
 			r.Err = err.Error()
 		}
@@ -420,13 +570,28 @@ func (st *Local) Reserve(ctx context.Context, sid storage.SectorRef, ft storifac
 }
 
 func (st *Local) AcquireSector(ctx context.Context, sid storage.SectorRef, existing storiface.SectorFileType, allocate storiface.SectorFileType, pathType storiface.PathType, op storiface.AcquireMode) (storiface.SectorPaths, storiface.SectorPaths, error) {
+	out, storageIDs, fetch, err := st.acquireSector(ctx, sid, existing, allocate, pathType, op)
+	if err != nil {
+		return storiface.SectorPaths{}, storiface.SectorPaths{}, err
+	}
+
+	if err := st.fetchObjects(ctx, fetch); err != nil {
+		return storiface.SectorPaths{}, storiface.SectorPaths{}, err
+	}
+
+	return out, storageIDs, nil
+}
+
+// acquireSector is AcquireSector without fetching existing files which are
+// only in object stores, those are returned to be fetched
+func (st *Local) acquireSector(ctx context.Context, sid storage.SectorRef, existing storiface.SectorFileType, allocate storiface.SectorFileType, pathType storiface.PathType, op storiface.AcquireMode) (storiface.SectorPaths, storiface.SectorPaths, []objectFetch, error) {
 	if existing|allocate != existing^allocate {
-		return storiface.SectorPaths{}, storiface.SectorPaths{}, xerrors.New("can't both find and allocate a sector")
+		return storiface.SectorPaths{}, storiface.SectorPaths{}, nil, xerrors.New("can't both find and allocate a sector")
 	}
 
 	ssize, err := sid.ProofType.SectorSize()
 	if err != nil {
-		return storiface.SectorPaths{}, storiface.SectorPaths{}, err
+		return storiface.SectorPaths{}, storiface.SectorPaths{}, nil, err
 	}
 
 	st.localLk.RLock()
@@ -434,6 +599,7 @@ func (st *Local) AcquireSector(ctx context.Context, sid storage.SectorRef, exist
 
 	var out storiface.SectorPaths
 	var storageIDs storiface.SectorPaths
+	var fetch []objectFetch
 
 	for _, fileType := range storiface.PathTypes {
 		if fileType&existing == 0 {
@@ -456,10 +622,18 @@ func (st *Local) AcquireSector(ctx context.Context, sid storage.SectorRef, exist
 				continue
 			}
 
-			spath := p.sectorPath(sid.ID, fileType)
+			spath := p.existingSectorPath(sid.ID, fileType)
 			storiface.SetPathByType(&out, fileType, spath)
 			storiface.SetPathByType(&storageIDs, fileType, string(info.ID))
 
+			if p.objects != nil && fileType&p.objectTypes != 0 {
+				if _, err := os.Stat(spath); os.IsNotExist(err) {
+					fetch = append(fetch, objectFetch{path: p, sid: sid.ID, fileType: fileType})
+				} else {
+					st.usedCopy(p, Decl{SectorID: sid.ID, SectorFileType: fileType})
+				}
+			}
+
 			existing ^= fileType
 			break
 		}
@@ -472,7 +646,7 @@ func (st *Local) AcquireSector(ctx context.Context, sid storage.SectorRef, exist
 
 		sis, err := st.index.StorageBestAlloc(ctx, fileType, ssize, pathType)
 		if err != nil {
-			return storiface.SectorPaths{}, storiface.SectorPaths{}, xerrors.Errorf("finding best storage for allocating : %w", err)
+			return storiface.SectorPaths{}, storiface.SectorPaths{}, nil, xerrors.Errorf("finding best storage for allocating : %w", err)
 		}
 
 		var best string
@@ -504,7 +678,11 @@ func (st *Local) AcquireSector(ctx context.Context, sid storage.SectorRef, exist
 		}
 
 		if best == "" {
-			return storiface.SectorPaths{}, storiface.SectorPaths{}, xerrors.Errorf("couldn't find a suitable path for a sector")
+			return storiface.SectorPaths{}, storiface.SectorPaths{}, nil, xerrors.Errorf("couldn't find a suitable path for a sector")
+		}
+
+		if err := os.MkdirAll(filepath.Dir(best), 0755); err != nil { // nolint
+			return storiface.SectorPaths{}, storiface.SectorPaths{}, nil, xerrors.Errorf("creating sector directory: %w", err)
 		}
 
 		storiface.SetPathByType(&out, fileType, best)
@@ -512,7 +690,7 @@ func (st *Local) AcquireSector(ctx context.Context, sid storage.SectorRef, exist
 		allocate ^= fileType
 	}
 
-	return out, storageIDs, nil
+	return out, storageIDs, fetch, nil
 }
 
 func (st *Local) Local(ctx context.Context) ([]StoragePath, error) {
@@ -611,15 +789,23 @@ func (st *Local) removeSector(ctx context.Context, sid abi.SectorID, typ storifa
 		return nil
 	}
 
-	if err := st.index.StorageDropSector(ctx, storage, sid, typ); err != nil {
+	if err := st.dropSector(ctx, p, storage, sid, typ); err != nil {
 		return xerrors.Errorf("dropping sector from index: %w", err)
 	}
 
-	spath := p.sectorPath(sid, typ)
-	log.Infof("remove %s", spath)
+	// a relayout may not have moved the files yet
+	for _, spath := range p.sectorPaths(sid, typ) {
+		log.Infof("remove %s", spath)
+
+		if err := os.RemoveAll(spath); err != nil {
+			log.Errorf("removing sector (%v) from %s: %+v", sid, spath, err)
+		}
+	}
 
-	if err := os.RemoveAll(spath); err != nil {
-		log.Errorf("removing sector (%v) from %s: %+v", sid, spath, err)
+	if p.objects != nil && typ&p.objectTypes != 0 {
+		if err := st.deleteObjects(ctx, p, sid, typ); err != nil {
+			log.Errorf("removing sector (%v) from object store of %s: %+v", sid, storage, err)
+		}
 	}
 
 	st.reportStorage(ctx) // report freed space
@@ -633,7 +819,8 @@ func (st *Local) MoveStorage(ctx context.Context, s storage.SectorRef, types sto
 		return xerrors.Errorf("acquire dest storage: %w", err)
 	}
 
-	src, srcIds, err := st.AcquireSector(ctx, s, types, storiface.FTNone, storiface.PathStorage, storiface.AcquireMove)
+	// the source files are moved as they are, nothing is fetched from object stores
+	src, srcIds, _, err := st.acquireSector(ctx, s, types, storiface.FTNone, storiface.PathStorage, storiface.AcquireMove)
 	if err != nil {
 		return xerrors.Errorf("acquire src storage: %w", err)
 	}
@@ -665,16 +852,34 @@ func (st *Local) MoveStorage(ctx context.Context, s storage.SectorRef, types sto
 
 		log.Debugf("moving %v(%d) to storage: %s(se:%t; st:%t) -> %s(se:%t; st:%t)", s, fileType, sst.ID, sst.CanSeal, sst.CanStore, dst.ID, dst.CanSeal, dst.CanStore)
 
-		if err := st.index.StorageDropSector(ctx, ID(storiface.PathByType(srcIds, fileType)), s.ID, fileType); err != nil {
+		st.localLk.RLock()
+		sp := st.paths[sst.ID]
+		st.localLk.RUnlock()
+
+		if err := st.dropSector(ctx, sp, sst.ID, s.ID, fileType); err != nil {
 			return xerrors.Errorf("dropping source sector from index: %w", err)
 		}
 
+		st.localLk.RLock()
+		p := st.paths[dst.ID]
+		st.localLk.RUnlock()
+
 		if err := move(storiface.PathByType(src, fileType), storiface.PathByType(dest, fileType)); err != nil {
+			if p != nil {
+				st.ioError(p, err)
+			}
 			// TODO: attempt some recovery (check if src is still there, re-declare)
 			return xerrors.Errorf("moving sector %v(%d): %w", s, fileType, err)
 		}
 
-		if err := st.index.StorageDeclareSector(ctx, ID(storiface.PathByType(destIds, fileType)), s.ID, fileType, true); err != nil {
+		if p != nil && p.objects != nil && fileType&p.objectTypes != 0 {
+			if err := st.uploadSector(ctx, p, s.ID, fileType, storiface.PathByType(dest, fileType)); err != nil {
+				// the files are still in the local directory of the path
+				log.Errorw("uploading sector to object store, keeping local copy", "sector", s.ID, "type", fileType, "path", dst.ID, "error", err)
+			}
+		}
+
+		if err := st.DeclareSector(ctx, ID(storiface.PathByType(destIds, fileType)), s.ID, fileType, true); err != nil {
 			return xerrors.Errorf("declare sector %d(t:%d) -> %s: %w", s, fileType, ID(storiface.PathByType(destIds, fileType)), err)
 		}
 	}
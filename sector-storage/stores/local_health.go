@@ -0,0 +1,270 @@
+package stores
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/venus-sealer/sector-storage/storiface"
+)
+
+const (
+	probeReads     = 4
+	probeReadSize  = 64 << 10
+	probeWriteSize = 4 << 20
+
+	probeFile = ".healthprobe"
+)
+
+// probeHealth probes the health of the local paths every HealthProbeInterval,
+// the results are sent to the index with the heartbeats
+func (st *Local) probeHealth(ctx context.Context) {
+	for {
+		st.localLk.RLock()
+		for _, p := range st.paths {
+			go st.probePath(ctx, p)
+		}
+		st.localLk.RUnlock()
+
+		select {
+		case <-time.After(HealthProbeInterval):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// probePath probes the path, unless the previous probe is still running, which
+// means the path doesn't complete I/O
+func (st *Local) probePath(ctx context.Context, p *path) {
+	if !atomic.CompareAndSwapInt32(&p.probing, 0, 1) {
+		st.localLk.Lock()
+		if p.health.Status != HealthFailing {
+			p.health.worsen(HealthFailing, fmt.Sprintf("health probe running since %s didn't finish", p.health.Checked.Format(time.RFC3339)))
+		}
+		p.health.FailingProbes++
+		st.localLk.Unlock()
+		return
+	}
+	defer atomic.StoreInt32(&p.probing, 0)
+
+	st.localLk.Lock()
+	p.health.Checked = time.Now()
+	canSeal, smartDevice := p.canSeal, p.smartDevice
+	st.localLk.Unlock()
+
+	h := st.checkPath(ctx, p, canSeal, smartDevice)
+
+	st.localLk.Lock()
+	if h.Status == HealthFailing {
+		h.FailingProbes = p.health.FailingProbes + 1
+	}
+	p.health = h
+	st.localLk.Unlock()
+
+	if h.Status != HealthOK {
+		log.Warnw("storage path unhealthy", "path", p.local, "status", h.Status, "reasons", h.Reasons)
+	}
+}
+
+func (st *Local) checkPath(ctx context.Context, p *path, canSeal bool, smartDevice string) PathHealth {
+	h := PathHealth{
+		Status:  HealthOK,
+		Checked: time.Now(),
+	}
+
+	lat, err := p.probeRead()
+	switch {
+	case err != nil:
+		st.ioError(p, err)
+		h.worsen(HealthFailing, fmt.Sprintf("read probe: %s", err))
+	case lat > FailingReadLatency:
+		h.worsen(HealthFailing, fmt.Sprintf("read latency %s", lat))
+	case lat > DegradedReadLatency:
+		h.worsen(HealthDegraded, fmt.Sprintf("read latency %s", lat))
+	}
+	h.ReadLatency = lat
+
+	if canSeal {
+		lat, err := p.probeWrite()
+		switch {
+		case err != nil:
+			st.ioError(p, err)
+			h.worsen(HealthFailing, fmt.Sprintf("write probe: %s", err))
+		case lat > FailingWriteLatency:
+			h.worsen(HealthFailing, fmt.Sprintf("write latency %s", lat))
+		case lat > DegradedWriteLatency:
+			h.worsen(HealthDegraded, fmt.Sprintf("write latency %s", lat))
+		}
+		h.WriteLatency = lat
+	}
+
+	if smartDevice != "" {
+		si, err := readSMART(ctx, smartDevice)
+		if err != nil {
+			// the device health is unknown, not bad
+			log.Warnw("reading SMART data", "path", p.local, "device", smartDevice, "error", err)
+		} else {
+			h.SMART = si
+			switch {
+			case !si.Passed:
+				h.worsen(HealthFailing, "SMART overall health check failed")
+			case si.PendingSectors > 0 || si.ReallocatedSectors > 0:
+				h.worsen(HealthDegraded, fmt.Sprintf("%d pending and %d reallocated disk sectors", si.PendingSectors, si.ReallocatedSectors))
+			case si.MediaErrors > 0:
+				h.worsen(HealthDegraded, fmt.Sprintf("%d media errors", si.MediaErrors))
+			}
+		}
+	}
+
+	// errors seen since the previous probe, including the ones of this probe
+	h.IOErrors = atomic.LoadUint64(&p.ioErrors)
+	if n := h.IOErrors - p.probedIOErrors; n > 0 {
+		h.worsen(HealthDegraded, fmt.Sprintf("%d I/O errors since the last probe", n))
+	}
+	p.probedIOErrors = h.IOErrors
+
+	return h
+}
+
+// probeRead times random reads of a sealed file in the path and returns the
+// latency of the slowest one, 0 when there are no local sealed files
+func (p *path) probeRead() (time.Duration, error) {
+	var sealed []ManifestEntry
+	for _, e := range p.manifest.list() {
+		if e.FileType == storiface.FTSealed && e.Size >= probeReadSize {
+			sealed = append(sealed, e)
+		}
+	}
+	if len(sealed) == 0 {
+		return 0, nil
+	}
+
+	e := sealed[rand.Intn(len(sealed))]
+	f, err := os.Open(p.existingSectorPath(e.Sector, e.FileType))
+	if err != nil {
+		if os.IsNotExist(err) {
+			// removed since it was listed
+			return 0, nil
+		}
+		return 0, err
+	}
+	defer f.Close() // nolint
+
+	buf := make([]byte, probeReadSize)
+	var slowest time.Duration
+	for i := 0; i < probeReads; i++ {
+		// page aligned, anywhere in the file so the reads are rarely cached
+		off := rand.Int63n(e.Size/4096-probeReadSize/4096+1) * 4096
+
+		start := time.Now()
+		if _, err := f.ReadAt(buf, off); err != nil {
+			return 0, xerrors.Errorf("reading %s at %d: %w", f.Name(), off, err)
+		}
+		if lat := time.Since(start); lat > slowest {
+			slowest = lat
+		}
+	}
+
+	return slowest, nil
+}
+
+// probeWrite times writing and syncing a probe file in the path
+func (p *path) probeWrite() (time.Duration, error) {
+	name := filepath.Join(p.local, probeFile)
+	defer os.Remove(name) // nolint
+
+	buf := make([]byte, probeWriteSize)
+	_, _ = rand.Read(buf)
+
+	start := time.Now()
+
+	f, err := os.OpenFile(name, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644) // nolint:gosec
+	if err != nil {
+		return 0, err
+	}
+	if _, err := f.Write(buf); err != nil {
+		_ = f.Close()
+		return 0, err
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return 0, err
+	}
+	if err := f.Close(); err != nil {
+		return 0, err
+	}
+
+	return time.Since(start), nil
+}
+
+// ioError counts an error of I/O in the path, missing files aren't counted
+func (st *Local) ioError(p *path, err error) {
+	if err == nil || xerrors.Is(err, os.ErrNotExist) {
+		return
+	}
+
+	atomic.AddUint64(&p.ioErrors, 1)
+}
+
+// FileError counts the error of reading or writing a sector file as an I/O
+// error of the local path the file is in, which degrades the health of the
+// path. Only errors wrapping an *os.PathError of a file in a local path are
+// counted, so the errors of any sector file access can be passed.
+func (st *Local) FileError(err error) {
+	var pe *os.PathError
+	if !xerrors.As(err, &pe) {
+		return
+	}
+
+	st.localLk.RLock()
+	var fp *path
+	for _, p := range st.paths {
+		rel, rerr := filepath.Rel(p.local, pe.Path)
+		if rerr == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			fp = p
+			break
+		}
+	}
+	st.localLk.RUnlock()
+
+	if fp != nil {
+		st.ioError(fp, err)
+	}
+}
+
+// fileErrorCounter is implemented by stores counting the I/O errors of sector
+// files, see Local.FileError
+type fileErrorCounter interface {
+	FileError(err error)
+}
+
+// fileError counts the error of a sector file access with the store, when it
+// counts them
+func fileError(s Store, err error) {
+	if c, ok := s.(fileErrorCounter); ok {
+		c.FileError(err)
+	}
+}
+
+// fileErrorReader counts the read errors of a sector file with the store
+type fileErrorReader struct {
+	*os.File
+	s Store
+}
+
+func (r *fileErrorReader) Read(b []byte) (int, error) {
+	n, err := r.File.Read(b)
+	if err != nil && err != io.EOF {
+		fileError(r.s, err)
+	}
+	return n, err
+}
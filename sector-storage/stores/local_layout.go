@@ -0,0 +1,210 @@
+package stores
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/venus-sealer/sector-storage/storiface"
+)
+
+// RelayoutResult reports the sector files moved into the new layout of a path
+type RelayoutResult struct {
+	Moved int
+
+	// Busy are the sector files which were locked by other tasks, running the
+	// relayout again moves them
+	Busy []Decl
+}
+
+// sectorPaths returns the paths the sector file can be at, the path in the
+// layout of the storage path first. Files stay in the previous layout of the
+// path until a relayout moves them.
+func (p *path) sectorPaths(sid abi.SectorID, fileType storiface.SectorFileType) []string {
+	out := []string{p.sectorPath(sid, fileType)}
+	for _, l := range []storiface.SectorLayout{storiface.LayoutFlat, storiface.LayoutSharded} {
+		if l != p.layout {
+			out = append(out, l.SectorPath(p.local, sid, fileType))
+		}
+	}
+	return out
+}
+
+// existingSectorPath returns the path of the sector file in any layout, or the
+// path in the layout of the storage path if there is none
+func (p *path) existingSectorPath(sid abi.SectorID, fileType storiface.SectorFileType) string {
+	paths := p.sectorPaths(sid, fileType)
+	if _, err := os.Stat(paths[0]); err == nil {
+		return paths[0]
+	}
+
+	for _, sp := range paths[1:] {
+		if _, err := os.Stat(sp); err == nil {
+			return sp
+		}
+	}
+	return paths[0]
+}
+
+// listSectorFiles returns the paths of the sector files in the directory of a
+// file type, ents are the entries of dir. Files in shard directories are
+// listed with the files directly in dir, so paths in any layout are listed.
+func listSectorFiles(dir string, ents []os.FileInfo) (map[abi.SectorID]string, error) {
+	out := map[abi.SectorID]string{}
+
+	for _, ent := range ents {
+		if ent.Name() == FetchTempSubdir {
+			continue
+		}
+
+		if ent.IsDir() && storiface.IsShardName(ent.Name()) {
+			shard := filepath.Join(dir, ent.Name())
+			sents, err := ioutil.ReadDir(shard)
+			if err != nil {
+				return nil, xerrors.Errorf("listing %s: %w", shard, err)
+			}
+
+			for _, sent := range sents {
+				if sent.Name() == FetchTempSubdir {
+					continue
+				}
+
+				sid, err := storiface.ParseSectorID(sent.Name())
+				if err != nil {
+					return nil, xerrors.Errorf("parse sector id %s: %w", sent.Name(), err)
+				}
+				if prev, ok := out[sid]; ok {
+					log.Warnw("sector file found in multiple layouts", "sector", sid, "path", prev)
+				}
+				out[sid] = filepath.Join(shard, sent.Name())
+			}
+			continue
+		}
+
+		sid, err := storiface.ParseSectorID(ent.Name())
+		if err != nil {
+			return nil, xerrors.Errorf("parse sector id %s: %w", ent.Name(), err)
+		}
+		if prev, ok := out[sid]; ok {
+			log.Warnw("sector file found in multiple layouts", "sector", sid, "path", prev)
+		}
+		out[sid] = filepath.Join(dir, ent.Name())
+	}
+
+	return out, nil
+}
+
+// Relayout switches the storage path to the layout and moves the sector files
+// into it. The path stays in use while the files are moved, as files are found
+// in any layout. Files of sectors locked by other tasks are skipped.
+func (st *Local) Relayout(ctx context.Context, id ID, layout storiface.SectorLayout) (RelayoutResult, error) {
+	if _, err := storiface.ParseSectorLayout(string(layout)); err != nil {
+		return RelayoutResult{}, err
+	}
+
+	st.localLk.Lock()
+	p, ok := st.paths[id]
+	if !ok {
+		st.localLk.Unlock()
+		return RelayoutResult{}, errPathNotFound
+	}
+
+	meta, err := ReadStorageMeta(p.local)
+	if err == nil {
+		meta.Layout = layout
+		err = writeStorageMeta(p.local, meta)
+	}
+	if err != nil {
+		st.localLk.Unlock()
+		return RelayoutResult{}, err
+	}
+	p.layout = layout
+	st.localLk.Unlock()
+
+	var res RelayoutResult
+	for _, t := range storiface.PathTypes {
+		dir := filepath.Join(p.local, t.String())
+		ents, err := ioutil.ReadDir(dir)
+		if err != nil {
+			return res, xerrors.Errorf("listing %s: %w", dir, err)
+		}
+
+		sectors, err := listSectorFiles(dir, ents)
+		if err != nil {
+			return res, err
+		}
+
+		for sid, src := range sectors {
+			dst := layout.SectorPath(p.local, sid, t)
+			if src == dst {
+				continue
+			}
+
+			moved, err := st.relayoutSector(ctx, sid, t, src, dst)
+			if err != nil {
+				return res, xerrors.Errorf("moving %s to %s: %w", src, dst, err)
+			}
+			if !moved {
+				res.Busy = append(res.Busy, Decl{SectorID: sid, SectorFileType: t})
+				continue
+			}
+			res.Moved++
+		}
+
+		if layout == storiface.LayoutFlat {
+			// new files aren't put in shards anymore, remove the emptied ones
+			for _, ent := range ents {
+				if ent.IsDir() && storiface.IsShardName(ent.Name()) {
+					_ = os.Remove(filepath.Join(dir, ent.Name()))
+				}
+			}
+		}
+	}
+
+	return res, nil
+}
+
+func (st *Local) relayoutSector(ctx context.Context, sid abi.SectorID, fileType storiface.SectorFileType, src, dst string) (bool, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	locked, err := st.index.StorageTryLock(ctx, sid, storiface.FTNone, fileType)
+	if err != nil {
+		return false, xerrors.Errorf("acquiring sector lock: %w", err)
+	}
+	if !locked {
+		return false, nil
+	}
+
+	if _, err := os.Stat(dst); err == nil {
+		return false, xerrors.Errorf("sector file already exists in the new layout")
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil { // nolint
+		return false, err
+	}
+	if err := os.Rename(src, dst); err != nil {
+		return false, err
+	}
+
+	log.Debugw("moved sector file to new layout", "sector", sid, "type", fileType, "from", src, "to", dst)
+	return true, nil
+}
+
+func writeStorageMeta(p string, meta LocalStorageMeta) error {
+	b, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return xerrors.Errorf("marshaling storage metadata: %w", err)
+	}
+
+	tmp := filepath.Join(p, MetaFile+".tmp")
+	if err := ioutil.WriteFile(tmp, b, 0644); err != nil {
+		return xerrors.Errorf("writing storage metadata: %w", err)
+	}
+	return os.Rename(tmp, filepath.Join(p, MetaFile))
+}
@@ -0,0 +1,504 @@
+package stores
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/venus-sealer/sector-storage/storiface"
+)
+
+// ManifestFile [path]/sectormanifest.json lists the sector files in the path,
+// paths are declared from it instead of listing all files when opened
+const ManifestFile = "sectormanifest.json"
+
+// ManifestJournal [path]/sectormanifest.journal records the changes made to
+// the manifest since ManifestFile was last written, one json record per line
+const ManifestJournal = "sectormanifest.journal"
+
+// manifestCompactRecords is the least number of journal records the manifest
+// is rewritten at, it's rewritten once the journal has more records than the
+// manifest has entries
+var manifestCompactRecords = 1024
+
+// ManifestEntry describes a sector file, or the cache directory of a sector,
+// in a storage path
+type ManifestEntry struct {
+	Sector   abi.SectorID
+	FileType storiface.SectorFileType
+
+	Size    int64
+	ModTime time.Time
+}
+
+// ManifestReport lists the differences found between the manifest of a path
+// and the sector files in it, the manifest and the index are updated to match
+// the files
+type ManifestReport struct {
+	Entries int
+
+	// Added are files which were missing from the manifest
+	Added []Decl
+	// Removed are manifest entries without files
+	Removed []Decl
+	// Changed are files with a different size or modification time
+	Changed []Decl
+}
+
+type manifest struct {
+	file    string
+	journal string
+
+	lk      sync.Mutex
+	entries map[Decl]ManifestEntry
+
+	// written is set once the manifest file exists
+	written bool
+	// records is the number of records in the journal
+	records int
+	// torn is set when the last journal record wasn't written completely, the
+	// journal is compacted before appending to it
+	torn bool
+}
+
+type manifestFile struct {
+	Entries []ManifestEntry
+}
+
+type journalRecord struct {
+	Entry ManifestEntry
+	// Drop removes the entry of the sector file instead of setting it
+	Drop bool `json:",omitempty"`
+}
+
+func newManifest(p string) *manifest {
+	return &manifest{
+		file:    filepath.Join(p, ManifestFile),
+		journal: filepath.Join(p, ManifestJournal),
+		entries: map[Decl]ManifestEntry{},
+	}
+}
+
+// readManifest reads the manifest of the path at p and applies its journal,
+// the error wraps os.ErrNotExist when the path has no manifest yet
+func readManifest(p string) (*manifest, error) {
+	m := newManifest(p)
+
+	b, err := ioutil.ReadFile(m.file)
+	if err != nil {
+		return nil, xerrors.Errorf("reading sector manifest: %w", err)
+	}
+
+	var mf manifestFile
+	if err := json.Unmarshal(b, &mf); err != nil {
+		return nil, xerrors.Errorf("unmarshalling sector manifest: %w", err)
+	}
+
+	for _, e := range mf.Entries {
+		m.entries[Decl{SectorID: e.Sector, SectorFileType: e.FileType}] = e
+	}
+	m.written = true
+
+	b, err = ioutil.ReadFile(m.journal)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return m, nil
+		}
+		return nil, xerrors.Errorf("reading sector manifest journal: %w", err)
+	}
+
+	for _, line := range bytes.Split(b, []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+
+		var r journalRecord
+		if err := json.Unmarshal(line, &r); err != nil {
+			// only the last record can be torn, by a crash while appending it
+			log.Warnw("skipping torn sector manifest journal record", "file", m.journal, "error", err)
+			m.torn = true
+			break
+		}
+
+		m.apply(r)
+		m.records++
+	}
+
+	return m, nil
+}
+
+func (m *manifest) get(d Decl) (ManifestEntry, bool) {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+
+	e, ok := m.entries[d]
+	return e, ok
+}
+
+func (m *manifest) list() []ManifestEntry {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+
+	out := make([]ManifestEntry, 0, len(m.entries))
+	for _, e := range m.entries {
+		out = append(out, e)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Sector != out[j].Sector {
+			if out[i].Sector.Miner != out[j].Sector.Miner {
+				return out[i].Sector.Miner < out[j].Sector.Miner
+			}
+			return out[i].Sector.Number < out[j].Sector.Number
+		}
+		return out[i].FileType < out[j].FileType
+	})
+	return out
+}
+
+func (m *manifest) put(entries ...ManifestEntry) error {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+
+	records := make([]journalRecord, len(entries))
+	for i, e := range entries {
+		records[i] = journalRecord{Entry: e}
+		m.apply(records[i])
+	}
+	return m.append(records)
+}
+
+func (m *manifest) drop(d Decl) error {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+
+	if _, ok := m.entries[d]; !ok {
+		return nil
+	}
+
+	r := journalRecord{Entry: ManifestEntry{Sector: d.SectorID, FileType: d.SectorFileType}, Drop: true}
+	m.apply(r)
+	return m.append([]journalRecord{r})
+}
+
+// apply applies the journal record to the entries, m.lk must be held
+func (m *manifest) apply(r journalRecord) {
+	d := Decl{SectorID: r.Entry.Sector, SectorFileType: r.Entry.FileType}
+	if r.Drop {
+		delete(m.entries, d)
+		return
+	}
+	m.entries[d] = r.Entry
+}
+
+// append appends the records to the journal, or rewrites the manifest when the
+// journal grew larger than it, m.lk must be held
+func (m *manifest) append(records []journalRecord) error {
+	compactAt := manifestCompactRecords
+	if len(m.entries) > compactAt {
+		compactAt = len(m.entries)
+	}
+	if !m.written || m.torn || m.records+len(records) > compactAt {
+		return m.write()
+	}
+
+	var buf bytes.Buffer
+	for _, r := range records {
+		b, err := json.Marshal(r)
+		if err != nil {
+			return xerrors.Errorf("marshaling sector manifest journal record: %w", err)
+		}
+		buf.Write(b)
+		buf.WriteByte('\n')
+	}
+
+	f, err := os.OpenFile(m.journal, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return xerrors.Errorf("opening sector manifest journal: %w", err)
+	}
+	if _, err := f.Write(buf.Bytes()); err != nil {
+		_ = f.Close()
+		m.torn = true
+		return xerrors.Errorf("writing sector manifest journal: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		m.torn = true
+		return xerrors.Errorf("syncing sector manifest journal: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return xerrors.Errorf("closing sector manifest journal: %w", err)
+	}
+
+	m.records += len(records)
+	return nil
+}
+
+// write replaces the manifest file and removes the journal, m.lk must be held
+func (m *manifest) write() error {
+	var mf manifestFile
+	for _, e := range m.entries {
+		mf.Entries = append(mf.Entries, e)
+	}
+
+	b, err := json.Marshal(mf)
+	if err != nil {
+		return xerrors.Errorf("marshaling sector manifest: %w", err)
+	}
+
+	tmp := m.file + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return xerrors.Errorf("creating sector manifest: %w", err)
+	}
+	if _, err := f.Write(b); err != nil {
+		_ = f.Close()
+		return xerrors.Errorf("writing sector manifest: %w", err)
+	}
+	// the rename must not replace the manifest with a file which isn't on disk yet
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return xerrors.Errorf("syncing sector manifest: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return xerrors.Errorf("closing sector manifest: %w", err)
+	}
+	if err := os.Rename(tmp, m.file); err != nil {
+		return xerrors.Errorf("replacing sector manifest: %w", err)
+	}
+	m.written = true
+
+	// the journal records are all in the manifest now, replaying them over it
+	// after a crash before the journal is removed changes nothing
+	if err := os.Remove(m.journal); err != nil && !os.IsNotExist(err) {
+		return xerrors.Errorf("removing sector manifest journal: %w", err)
+	}
+	m.records = 0
+	m.torn = false
+
+	return nil
+}
+
+// statSectorFile returns the manifest entry of the sector file at spath, the
+// size of a cache directory is the size of the files in it
+func statSectorFile(sid abi.SectorID, fileType storiface.SectorFileType, spath string) (ManifestEntry, error) {
+	fi, err := os.Stat(spath)
+	if err != nil {
+		return ManifestEntry{}, err
+	}
+
+	e := ManifestEntry{
+		Sector:   sid,
+		FileType: fileType,
+		Size:     fi.Size(),
+		ModTime:  fi.ModTime(),
+	}
+
+	if fi.IsDir() {
+		ents, err := ioutil.ReadDir(spath)
+		if err != nil {
+			return ManifestEntry{}, err
+		}
+
+		e.Size = 0
+		for _, ent := range ents {
+			e.Size += ent.Size()
+			if ent.ModTime().After(e.ModTime) {
+				e.ModTime = ent.ModTime()
+			}
+		}
+	}
+
+	return e, nil
+}
+
+// DeclareSector declares the sector file in the storage path in the index.
+// When the path is a local path the file is recorded in its manifest, or
+// dropped from it when the file isn't kept in the local directory.
+func (st *Local) DeclareSector(ctx context.Context, id ID, sid abi.SectorID, fileType storiface.SectorFileType, primary bool) error {
+	if err := st.index.StorageDeclareSector(ctx, id, sid, fileType, primary); err != nil {
+		return err
+	}
+
+	st.localLk.RLock()
+	p, ok := st.paths[id]
+	st.localLk.RUnlock()
+	if !ok {
+		return nil
+	}
+
+	e, err := statSectorFile(sid, fileType, p.existingSectorPath(sid, fileType))
+	switch {
+	case err == nil:
+		err = p.manifest.put(e)
+	case os.IsNotExist(err):
+		err = p.manifest.drop(Decl{SectorID: sid, SectorFileType: fileType})
+	}
+	if err != nil {
+		return xerrors.Errorf("updating manifest of %s: %w", id, err)
+	}
+
+	return nil
+}
+
+// dropSector drops the sector file in the storage path from the index and
+// the manifest of the path
+func (st *Local) dropSector(ctx context.Context, p *path, id ID, sid abi.SectorID, fileType storiface.SectorFileType) error {
+	if err := st.index.StorageDropSector(ctx, id, sid, fileType); err != nil {
+		return err
+	}
+
+	if p == nil {
+		return nil
+	}
+
+	if err := p.manifest.drop(Decl{SectorID: sid, SectorFileType: fileType}); err != nil {
+		return xerrors.Errorf("updating manifest of %s: %w", id, err)
+	}
+
+	return nil
+}
+
+// declareManifest declares the sector files listed in the manifest of the path
+func (st *Local) declareManifest(ctx context.Context, p *path, id ID, primary bool) error {
+	for _, e := range p.manifest.list() {
+		if err := st.index.StorageDeclareSector(ctx, id, e.Sector, e.FileType, primary); err != nil {
+			return xerrors.Errorf("declare sector %d(t:%d) -> %s: %w", e.Sector, e.FileType, id, err)
+		}
+	}
+
+	return nil
+}
+
+// verifyManifestBackground reconciles the manifest of a path declared from it
+// with the files in the path. It isn't bound to the context the path was
+// opened with, which may be the context of an API request.
+func (st *Local) verifyManifestBackground(id ID) {
+	start := time.Now()
+
+	res, err := st.VerifyManifest(context.Background(), id)
+	if err != nil {
+		log.Errorw("verifying sector manifest", "path", id, "error", err)
+		return
+	}
+
+	if len(res.Added)+len(res.Removed) > 0 {
+		log.Warnw("sector manifest was out of date", "path", id, "added", res.Added, "removed", res.Removed, "changed", len(res.Changed))
+		return
+	}
+	log.Infow("verified sector manifest", "path", id, "entries", res.Entries, "changed", len(res.Changed), "took", time.Since(start))
+}
+
+// VerifyManifest lists the sector files in the storage path and updates the
+// manifest of the path and the index where they differ. Files being written
+// by tasks in the path are skipped.
+func (st *Local) VerifyManifest(ctx context.Context, id ID) (ManifestReport, error) {
+	st.localLk.RLock()
+	p, ok := st.paths[id]
+	st.localLk.RUnlock()
+	if !ok {
+		return ManifestReport{}, errPathNotFound
+	}
+
+	meta, err := ReadStorageMeta(p.local)
+	if err != nil {
+		return ManifestReport{}, err
+	}
+
+	found := map[Decl]string{}
+	for _, t := range storiface.PathTypes {
+		dir := filepath.Join(p.local, t.String())
+		ents, err := ioutil.ReadDir(dir)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return ManifestReport{}, xerrors.Errorf("listing %s: %w", dir, err)
+		}
+
+		sectors, err := listSectorFiles(dir, ents)
+		if err != nil {
+			return ManifestReport{}, err
+		}
+		for sid, spath := range sectors {
+			found[Decl{SectorID: sid, SectorFileType: t}] = spath
+		}
+	}
+
+	var res ManifestReport
+	for d, spath := range found {
+		if st.reserved(p, d) {
+			continue
+		}
+
+		e, err := statSectorFile(d.SectorID, d.SectorFileType, spath)
+		if err != nil {
+			if os.IsNotExist(err) {
+				// removed, or moved by a relayout, since the directory was listed
+				continue
+			}
+			st.ioError(p, err)
+			return res, xerrors.Errorf("stat %s: %w", spath, err)
+		}
+
+		prev, ok := p.manifest.get(d)
+		switch {
+		case !ok:
+			if err := st.index.StorageDeclareSector(ctx, id, d.SectorID, d.SectorFileType, meta.CanStore); err != nil {
+				return res, xerrors.Errorf("declare sector %d(t:%d) -> %s: %w", d.SectorID, d.SectorFileType, id, err)
+			}
+			res.Added = append(res.Added, d)
+		case prev.Size != e.Size || !prev.ModTime.Equal(e.ModTime):
+			res.Changed = append(res.Changed, d)
+		default:
+			continue
+		}
+
+		if err := p.manifest.put(e); err != nil {
+			return res, xerrors.Errorf("updating manifest of %s: %w", id, err)
+		}
+	}
+
+	for _, e := range p.manifest.list() {
+		d := Decl{SectorID: e.Sector, SectorFileType: e.FileType}
+		if _, ok := found[d]; ok || st.reserved(p, d) {
+			continue
+		}
+
+		if _, err := os.Stat(p.existingSectorPath(e.Sector, e.FileType)); err == nil {
+			continue
+		}
+
+		if p.objects != nil && e.FileType&p.objectTypes != 0 {
+			// a local copy of a file in the object store, which stays declared
+			err = p.manifest.drop(d)
+		} else {
+			err = st.dropSector(ctx, p, id, e.Sector, e.FileType)
+		}
+		if err != nil {
+			return res, xerrors.Errorf("dropping sector %d(t:%d) from %s: %w", e.Sector, e.FileType, id, err)
+		}
+		res.Removed = append(res.Removed, d)
+	}
+
+	res.Entries = len(p.manifest.list())
+	return res, nil
+}
+
+// reserved returns whether space for the sector file is reserved in the path,
+// which means the file is being written
+func (st *Local) reserved(p *path, d Decl) bool {
+	st.localLk.RLock()
+	defer st.localLk.RUnlock()
+
+	return p.reservations[d.SectorID]&d.SectorFileType != 0
+}
@@ -0,0 +1,646 @@
+package stores
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/specs-storage/storage"
+
+	"github.com/filecoin-project/venus-sealer/sector-storage/fsutil"
+	"github.com/filecoin-project/venus-sealer/sector-storage/storiface"
+)
+
+// Storage paths with an object store backend keep sealed files, and cache
+// files if configured, in the object store. Files are moved into the local
+// directory of the path first and uploaded from there, the local directory
+// also keeps unsealed files, and copies of stored files fetched for reading.
+
+const (
+	// nodeSize is the size of the leaves of the sealed replica
+	nodeSize = 32
+
+	// postReadWindow is the aligned part of the sealed file read around a
+	// challenged leaf, which covers the subtree of the tree-r-last rows
+	// discarded from the cache files and rebuilt for proofs
+	postReadWindow = 1 << 20
+)
+
+// FetchedCopyTTL is how long local copies of object-backed files fetched for
+// reading are kept after they were last acquired
+var FetchedCopyTTL = time.Hour
+
+// objectFetch is an object-backed sector file missing in the local directory
+// of its path
+type objectFetch struct {
+	path     *path
+	sid      abi.SectorID
+	fileType storiface.SectorFileType
+}
+
+// objectKey returns the key of the object holding the sector file, for cache
+// files the key prefix of the files
+func objectKey(sid abi.SectorID, fileType storiface.SectorFileType) string {
+	return fileType.String() + "/" + storiface.SectorName(sid)
+}
+
+func openObjects(meta LocalStorageMeta) (ObjectStore, storiface.SectorFileType, error) {
+	if meta.CanSeal {
+		return nil, 0, xerrors.Errorf("paths with an object store backend can't be used for sealing")
+	}
+	if meta.MaxStorage == 0 {
+		return nil, 0, xerrors.Errorf("paths with an object store backend need MaxStorage set")
+	}
+
+	objs, err := NewObjectStore(*meta.Backend)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	types := storiface.FTSealed
+	if meta.Backend.UploadCache {
+		types |= storiface.FTCache
+	}
+	return objs, types, nil
+}
+
+func backendType(meta LocalStorageMeta) string {
+	if meta.Backend == nil {
+		return ""
+	}
+	return meta.Backend.Type
+}
+
+// listObjects returns the file types of the sectors in the object store of
+// the path and the size of all objects
+func (p *path) listObjects(ctx context.Context) (map[abi.SectorID]storiface.SectorFileType, int64, error) {
+	out := map[abi.SectorID]storiface.SectorFileType{}
+	var used int64
+
+	for _, t := range storiface.PathTypes {
+		if t&p.objectTypes == 0 {
+			continue
+		}
+
+		list, err := p.objects.List(ctx, t.String()+"/")
+		if err != nil {
+			return nil, 0, xerrors.Errorf("listing %s objects: %w", t, err)
+		}
+
+		for _, obj := range list {
+			name := strings.SplitN(strings.TrimPrefix(obj.Key, t.String()+"/"), "/", 2)[0]
+			sid, err := storiface.ParseSectorID(name)
+			if err != nil {
+				log.Warnw("unexpected object in sector storage", "key", obj.Key, "error", err)
+				continue
+			}
+
+			out[sid] |= t
+			used += obj.Size
+		}
+	}
+
+	return out, used, nil
+}
+
+// objectStat reports the space left for objects under MaxStorage, bounded by
+// the local filesystem which receives the files before they are uploaded
+func (p *path) objectStat(ls LocalStorage) (fsutil.FsStat, error) {
+	stat, err := ls.Stat(p.local)
+	if err != nil {
+		return fsutil.FsStat{}, xerrors.Errorf("stat %s: %w", p.local, err)
+	}
+
+	avail := int64(p.maxStorage) - p.objectsUsed - p.reserved
+	if avail < 0 {
+		avail = 0
+	}
+	if avail < stat.Available {
+		stat.Available = avail
+	}
+
+	stat.Capacity = int64(p.maxStorage)
+	stat.Reserved = p.reserved
+	stat.Max = int64(p.maxStorage)
+	stat.Used = p.objectsUsed
+
+	return stat, nil
+}
+
+func (st *Local) declareObjects(ctx context.Context, id ID, sectors map[abi.SectorID]storiface.SectorFileType, primary bool) error {
+	for sid, types := range sectors {
+		for _, t := range storiface.PathTypes {
+			if t&types == 0 {
+				continue
+			}
+
+			if err := st.index.StorageDeclareSector(ctx, id, sid, t, primary); err != nil {
+				return xerrors.Errorf("declare sector %d(t:%d) -> %s: %w", sid, t, id, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// uploadSector uploads the sector file at spath, or all cache files in it, to
+// the object store of the path and removes the local copy
+func (st *Local) uploadSector(ctx context.Context, p *path, sid abi.SectorID, fileType storiface.SectorFileType, spath string) error {
+	files := map[string]string{}
+	if fileType == storiface.FTCache {
+		ents, err := ioutil.ReadDir(spath)
+		if err != nil {
+			return xerrors.Errorf("listing cache files: %w", err)
+		}
+		for _, ent := range ents {
+			if ent.IsDir() {
+				log.Warnw("not uploading directory in sector cache", "sector", sid, "dir", ent.Name())
+				continue
+			}
+			files[objectKey(sid, fileType)+"/"+ent.Name()] = filepath.Join(spath, ent.Name())
+		}
+	} else {
+		files[objectKey(sid, fileType)] = spath
+	}
+
+	var size int64
+	for key, file := range files {
+		fi, err := os.Stat(file)
+		if err != nil {
+			return xerrors.Errorf("stat %s: %w", file, err)
+		}
+
+		log.Infow("uploading sector file", "sector", sid, "file", file, "key", key)
+		if err := p.objects.Put(ctx, key, file); err != nil {
+			return xerrors.Errorf("uploading %s: %w", file, err)
+		}
+		size += fi.Size()
+	}
+
+	st.localLk.Lock()
+	p.objectsUsed += size
+	st.localLk.Unlock()
+
+	if err := os.RemoveAll(spath); err != nil {
+		log.Errorf("removing uploaded sector (%v) from %s: %+v", sid, spath, err)
+	}
+
+	return nil
+}
+
+// deleteObjects deletes the sector file, or all cache files, from the object
+// store of the path
+func (st *Local) deleteObjects(ctx context.Context, p *path, sid abi.SectorID, fileType storiface.SectorFileType) error {
+	objs, err := sectorObjects(ctx, p.objects, sid, fileType)
+	if err != nil {
+		return err
+	}
+
+	for _, obj := range objs {
+		log.Infow("deleting sector object", "sector", sid, "key", obj.Key)
+		if err := p.objects.Delete(ctx, obj.Key); err != nil {
+			return xerrors.Errorf("deleting %s: %w", obj.Key, err)
+		}
+
+		st.localLk.Lock()
+		p.objectsUsed -= obj.Size
+		st.localLk.Unlock()
+	}
+
+	return nil
+}
+
+// sectorObjects returns the object holding the sector file, or the objects
+// of all cache files
+func sectorObjects(ctx context.Context, objs ObjectStore, sid abi.SectorID, fileType storiface.SectorFileType) ([]ObjectInfo, error) {
+	if fileType == storiface.FTCache {
+		return objs.List(ctx, objectKey(sid, fileType)+"/")
+	}
+
+	obj, err := objs.Stat(ctx, objectKey(sid, fileType))
+	if err != nil {
+		if xerrors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return []ObjectInfo{obj}, nil
+}
+
+// fetchObjects downloads the object-backed sector files to the local directory
+// of their path, so they can be read like any other stored file. Only fetches
+// of the same file wait for each other. The copies are removed once they
+// weren't acquired for FetchedCopyTTL, see evictFetched.
+func (st *Local) fetchObjects(ctx context.Context, fetch []objectFetch) error {
+	for _, f := range fetch {
+		if err := st.fetchObject(ctx, f); err != nil {
+			return xerrors.Errorf("fetching sector %v(%d) from object store: %w", f.sid, f.fileType, err)
+		}
+	}
+
+	return nil
+}
+
+func (st *Local) fetchObject(ctx context.Context, f objectFetch) error {
+	d := Decl{SectorID: f.sid, SectorFileType: f.fileType}
+
+	for {
+		st.fetchLk.Lock()
+
+		c, fetching := st.fetching[d]
+		if !fetching {
+			st.fetching[d] = make(chan struct{})
+			st.fetchLk.Unlock()
+			break
+		}
+
+		st.fetchLk.Unlock()
+
+		select {
+		case <-c:
+			continue
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	defer func() {
+		st.fetchLk.Lock()
+		close(st.fetching[d])
+		delete(st.fetching, d)
+		st.fetchLk.Unlock()
+	}()
+
+	defer st.usedCopy(f.path, d)
+
+	spath := f.path.sectorPath(f.sid, f.fileType)
+	if _, err := os.Stat(spath); err == nil {
+		return nil // fetched while waiting
+	}
+
+	list, err := sectorObjects(ctx, f.path.objects, f.sid, f.fileType)
+	if err != nil {
+		return err
+	}
+	if len(list) == 0 {
+		return xerrors.Errorf("no objects found: %w", storiface.ErrSectorNotFound)
+	}
+
+	release, err := st.reserveFetch(f.path, d, objectsSize(list))
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	return fetchSectorObjects(ctx, f.path.objects, list, f.fileType, spath)
+}
+
+// reserveFetch reserves space in the local directory of the path for a copy
+// of an object-backed file
+func (st *Local) reserveFetch(p *path, d Decl, size int64) (func(), error) {
+	st.localLk.Lock()
+	defer st.localLk.Unlock()
+
+	stat, err := st.localStorage.Stat(p.local)
+	if err != nil {
+		return nil, xerrors.Errorf("stat %s: %w", p.local, err)
+	}
+
+	if avail := stat.Available - p.reserved; avail < size {
+		return nil, storiface.Err(storiface.ErrTempAllocateSpace, xerrors.Errorf("can't reserve %d bytes in '%s' for a copy of sector %v(%d), only %d available", size, p.local, d.SectorID, d.SectorFileType, avail))
+	}
+
+	p.reserved += size
+	p.reservations[d.SectorID] |= d.SectorFileType
+
+	return func() {
+		st.localLk.Lock()
+		defer st.localLk.Unlock()
+
+		p.reserved -= size
+		p.reservations[d.SectorID] ^= d.SectorFileType
+		if p.reservations[d.SectorID] == storiface.FTNone {
+			delete(p.reservations, d.SectorID)
+		}
+	}, nil
+}
+
+// usedCopy records that the local copy of an object-backed file was acquired
+func (st *Local) usedCopy(p *path, d Decl) {
+	st.fetchLk.Lock()
+	defer st.fetchLk.Unlock()
+
+	p.fetched[d] = time.Now()
+}
+
+// evictFetched removes the local copies of object-backed files which weren't
+// acquired for FetchedCopyTTL
+func (st *Local) evictFetched(ctx context.Context) {
+	for {
+		select {
+		case <-time.After(FetchedCopyTTL / 4):
+		case <-ctx.Done():
+			return
+		}
+
+		st.localLk.RLock()
+		var paths []*path
+		for _, p := range st.paths {
+			if p.objects != nil {
+				paths = append(paths, p)
+			}
+		}
+		st.localLk.RUnlock()
+
+		for _, p := range paths {
+			if err := st.evictCopies(ctx, p); err != nil {
+				log.Warnw("evicting fetched sector copies", "path", p.local, "error", err)
+			}
+		}
+	}
+}
+
+func (st *Local) evictCopies(ctx context.Context, p *path) error {
+	found := map[Decl]string{}
+	for _, t := range storiface.PathTypes {
+		if t&p.objectTypes == 0 {
+			continue
+		}
+
+		dir := filepath.Join(p.local, t.String())
+		ents, err := ioutil.ReadDir(dir)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return xerrors.Errorf("listing %s: %w", dir, err)
+		}
+
+		sectors, err := listSectorFiles(dir, ents)
+		if err != nil {
+			return err
+		}
+		for sid, spath := range sectors {
+			found[Decl{SectorID: sid, SectorFileType: t}] = spath
+		}
+	}
+
+	st.fetchLk.Lock()
+	var evict []Decl
+	for d := range p.fetched {
+		if _, ok := found[d]; !ok {
+			delete(p.fetched, d) // removed with the sector
+		}
+	}
+	for d := range found {
+		used, ok := p.fetched[d]
+		if !ok {
+			// eg. fetched before a restart
+			p.fetched[d] = time.Now()
+			continue
+		}
+		if _, fetching := st.fetching[d]; !fetching && time.Since(used) > FetchedCopyTTL {
+			evict = append(evict, d)
+		}
+	}
+	st.fetchLk.Unlock()
+
+	for _, d := range evict {
+		if st.reserved(p, d) {
+			continue // being uploaded
+		}
+
+		if err := st.evictCopy(ctx, p, d, found[d]); err != nil {
+			log.Warnw("evicting fetched sector copy", "sector", d.SectorID, "type", d.SectorFileType, "error", err)
+		}
+	}
+
+	return nil
+}
+
+// evictCopy removes the local copy of the file unless the sector is locked,
+// or the copy isn't in the object store, eg. after a failed upload
+func (st *Local) evictCopy(ctx context.Context, p *path, d Decl, spath string) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel() // releases the lock
+
+	locked, err := st.index.StorageTryLock(ctx, d.SectorID, storiface.FTNone, d.SectorFileType)
+	if err != nil {
+		return xerrors.Errorf("locking sector: %w", err)
+	}
+	if !locked {
+		return nil // in use
+	}
+
+	list, err := sectorObjects(ctx, p.objects, d.SectorID, d.SectorFileType)
+	if err != nil {
+		return err
+	}
+	e, err := statSectorFile(d.SectorID, d.SectorFileType, spath)
+	if err != nil {
+		return err
+	}
+	if len(list) == 0 || objectsSize(list) != e.Size {
+		return xerrors.Errorf("local copy doesn't match the object store, keeping it")
+	}
+
+	log.Infow("evicting fetched sector copy", "sector", d.SectorID, "file", spath)
+	if err := os.RemoveAll(spath); err != nil {
+		st.ioError(p, err)
+		return err
+	}
+
+	st.fetchLk.Lock()
+	delete(p.fetched, d)
+	st.fetchLk.Unlock()
+
+	return nil
+}
+
+func objectsSize(list []ObjectInfo) int64 {
+	var size int64
+	for _, obj := range list {
+		size += obj.Size
+	}
+	return size
+}
+
+func fetchSectorObjects(ctx context.Context, objs ObjectStore, list []ObjectInfo, fileType storiface.SectorFileType, spath string) error {
+	tmp, err := tempFetchDest(spath, true)
+	if err != nil {
+		return err
+	}
+	if err := os.RemoveAll(tmp); err != nil {
+		return xerrors.Errorf("removing temp fetch dest: %w", err)
+	}
+
+	dst := func(ObjectInfo) string { return tmp }
+	if fileType == storiface.FTCache {
+		if err := os.Mkdir(tmp, 0755); err != nil { // nolint
+			return xerrors.Errorf("creating temp cache dir: %w", err)
+		}
+		dst = func(obj ObjectInfo) string {
+			return filepath.Join(tmp, filepath.Base(obj.Key))
+		}
+	}
+
+	for _, obj := range list {
+		if err := downloadObject(ctx, objs, obj, dst(obj)); err != nil {
+			_ = os.RemoveAll(tmp)
+			return err
+		}
+	}
+
+	return os.Rename(tmp, spath)
+}
+
+func downloadObject(ctx context.Context, objs ObjectStore, obj ObjectInfo, dst string) error {
+	f, err := os.OpenFile(dst, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return xerrors.Errorf("creating %s: %w", dst, err)
+	}
+	defer f.Close() // nolint
+
+	for off := int64(0); off < obj.Size; off += objectPartSize {
+		size := obj.Size - off
+		if size > objectPartSize {
+			size = objectPartSize
+		}
+
+		rd, err := objs.GetRange(ctx, obj.Key, off, size)
+		if err != nil {
+			return err
+		}
+		_, err = io.CopyN(f, rd, size)
+		_ = rd.Close()
+		if err != nil {
+			return xerrors.Errorf("downloading %s: %w", obj.Key, err)
+		}
+	}
+
+	return f.Close()
+}
+
+// AcquireSectorForPoSt returns the paths of the sealed and cache files of a
+// stored sector for proving the challenged leaves. Sealed files which are only
+// in an object store aren't fetched whole, a sparse copy with the parts around
+// the challenged leaves is read with ranged requests, and removed by done.
+func (st *Local) AcquireSectorForPoSt(ctx context.Context, sid storage.SectorRef, challenges []uint64) (storiface.SectorPaths, func(), error) {
+	out, _, fetch, err := st.acquireSector(ctx, sid, storiface.FTSealed|storiface.FTCache, storiface.FTNone, storiface.PathStorage, storiface.AcquireMove)
+	if err != nil {
+		return storiface.SectorPaths{}, nil, err
+	}
+
+	var sealed *objectFetch
+	var cache []objectFetch
+	for i := range fetch {
+		if fetch[i].fileType == storiface.FTSealed {
+			sealed = &fetch[i]
+			continue
+		}
+		cache = append(cache, fetch[i])
+	}
+
+	// cache files are small once the sector is finalized, fetch them whole
+	if err := st.fetchObjects(ctx, cache); err != nil {
+		return storiface.SectorPaths{}, nil, err
+	}
+
+	if sealed == nil {
+		return out, func() {}, nil
+	}
+
+	key := objectKey(sid.ID, storiface.FTSealed)
+	obj, err := sealed.path.objects.Stat(ctx, key)
+	if err != nil {
+		return storiface.SectorPaths{}, nil, xerrors.Errorf("stat sealed object: %w", err)
+	}
+
+	tmpdir := filepath.Join(sealed.path.local, storiface.FTSealed.String(), FetchTempSubdir)
+	if err := os.MkdirAll(tmpdir, 0755); err != nil { // nolint
+		return storiface.SectorPaths{}, nil, xerrors.Errorf("creating temp fetch dir: %w", err)
+	}
+	f, err := ioutil.TempFile(tmpdir, storiface.SectorName(sid.ID)+".post-")
+	if err != nil {
+		return storiface.SectorPaths{}, nil, xerrors.Errorf("creating sparse sealed file: %w", err)
+	}
+	done := func() {
+		if err := os.Remove(f.Name()); err != nil {
+			log.Warnw("removing sparse sealed file", "file", f.Name(), "error", err)
+		}
+	}
+
+	err = readChallenged(ctx, sealed.path.objects, key, f, obj.Size, challenges)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		done()
+		return storiface.SectorPaths{}, nil, xerrors.Errorf("reading challenged parts of sealed sector %v: %w", sid.ID, err)
+	}
+
+	out.Sealed = f.Name()
+	return out, done, nil
+}
+
+// readChallenged writes the windows around the challenged leaves of the sealed
+// object to f, which is sized like the object so size checks still apply
+func readChallenged(ctx context.Context, objs ObjectStore, key string, f *os.File, size int64, challenges []uint64) error {
+	if err := f.Truncate(size); err != nil {
+		return err
+	}
+
+	windows := map[int64]struct{}{}
+	for _, c := range challenges {
+		off := int64(c) * nodeSize / postReadWindow * postReadWindow
+		if off >= size {
+			return xerrors.Errorf("challenged leaf %d outside of the sector", c)
+		}
+		windows[off] = struct{}{}
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, len(windows))
+	throttle := make(chan struct{}, 8)
+	for off := range windows {
+		n := int64(postReadWindow)
+		if off+n > size {
+			n = size - off
+		}
+
+		wg.Add(1)
+		go func(off, n int64) {
+			defer wg.Done()
+			throttle <- struct{}{}
+			defer func() { <-throttle }()
+
+			rd, err := objs.GetRange(ctx, key, off, n)
+			if err != nil {
+				errs <- err
+				return
+			}
+			defer rd.Close() // nolint
+
+			buf := make([]byte, n)
+			if _, err := io.ReadFull(rd, buf); err != nil {
+				errs <- xerrors.Errorf("reading %s at %d: %w", key, off, err)
+				return
+			}
+			if _, err := f.WriteAt(buf, off); err != nil {
+				errs <- err
+			}
+		}(off, n)
+	}
+	wg.Wait()
+	close(errs)
+
+	return <-errs
+}
@@ -6,12 +6,20 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sync/atomic"
+	"syscall"
 	"testing"
+	"time"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/specs-storage/storage"
 
 	"github.com/filecoin-project/venus-sealer/sector-storage/fsutil"
+	"github.com/filecoin-project/venus-sealer/sector-storage/storiface"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/xerrors"
 )
 
 const pathSize = 16 << 20
@@ -43,6 +51,15 @@ func (t *TestingLocalStorage) Stat(path string) (fsutil.FsStat, error) {
 }
 
 func (t *TestingLocalStorage) init(subpath string) error {
+	return t.initMeta(subpath, &LocalStorageMeta{
+		ID:       ID(uuid.New().String()),
+		Weight:   1,
+		CanSeal:  true,
+		CanStore: true,
+	})
+}
+
+func (t *TestingLocalStorage) initMeta(subpath string, meta *LocalStorageMeta) error {
 	path := filepath.Join(t.root, subpath)
 	if err := os.Mkdir(path, 0755); err != nil {
 		return err
@@ -50,13 +67,6 @@ func (t *TestingLocalStorage) init(subpath string) error {
 
 	metaFile := filepath.Join(path, MetaFile)
 
-	meta := &LocalStorageMeta{
-		ID:       ID(uuid.New().String()),
-		Weight:   1,
-		CanSeal:  true,
-		CanStore: true,
-	}
-
 	mb, err := json.MarshalIndent(meta, "", "  ")
 	if err != nil {
 		return err
@@ -94,3 +104,504 @@ func TestLocalStorage(t *testing.T) {
 
 	// TODO: put more things here
 }
+
+func TestLocalObjectStorage(t *testing.T) {
+	ctx := context.TODO()
+
+	root, err := ioutil.TempDir("", "sector-storage-teststorage-")
+	require.NoError(t, err)
+	defer os.RemoveAll(root) // nolint
+
+	fake, srv := newFakeS3(t, "sectors")
+
+	tstor := &TestingLocalStorage{
+		root: root,
+	}
+
+	index := NewIndex()
+
+	st, err := NewLocal(ctx, tstor, index, nil)
+	require.NoError(t, err)
+
+	sealID := ID(uuid.New().String())
+	require.NoError(t, tstor.initMeta("seal", &LocalStorageMeta{
+		ID:      sealID,
+		Weight:  1,
+		CanSeal: true,
+	}))
+	storeID := ID(uuid.New().String())
+	require.NoError(t, tstor.initMeta("store", &LocalStorageMeta{
+		ID:         storeID,
+		Weight:     1,
+		CanStore:   true,
+		MaxStorage: pathSize,
+		Backend: &ObjectStoreConfig{
+			Type:            BackendS3,
+			Endpoint:        srv.URL,
+			Bucket:          "sectors",
+			CredentialsFile: testCredentials(t),
+			Profile:         "sealer",
+		},
+	}))
+
+	require.NoError(t, st.OpenPath(ctx, filepath.Join(root, "seal")))
+	require.NoError(t, st.OpenPath(ctx, filepath.Join(root, "store")))
+
+	si, err := index.StorageInfo(ctx, storeID)
+	require.NoError(t, err)
+	require.Equal(t, BackendS3, si.Backend)
+
+	sector := storage.SectorRef{
+		ID:        abi.SectorID{Miner: 1000, Number: 1},
+		ProofType: abi.RegisteredSealProof_StackedDrg2KiBV1,
+	}
+
+	// seal the sector into the sealing path
+	sealed := make([]byte, 2048)
+	for i := range sealed {
+		sealed[i] = byte(i)
+	}
+	lp, ids, err := st.AcquireSector(ctx, sector, storiface.FTNone, storiface.FTSealed|storiface.FTCache, storiface.PathSealing, storiface.AcquireMove)
+	require.NoError(t, err)
+	require.Equal(t, string(sealID), ids.Sealed)
+	require.NoError(t, ioutil.WriteFile(lp.Sealed, sealed, 0644))
+	require.NoError(t, os.Mkdir(lp.Cache, 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(lp.Cache, "p_aux"), []byte("p_aux data"), 0644))
+	require.NoError(t, index.StorageDeclareSector(ctx, sealID, sector.ID, storiface.FTSealed, true))
+	require.NoError(t, index.StorageDeclareSector(ctx, sealID, sector.ID, storiface.FTCache, true))
+
+	// moving to storage uploads the sealed file, the cache stays local
+	require.NoError(t, st.MoveStorage(ctx, sector, storiface.FTSealed|storiface.FTCache))
+	require.Equal(t, sealed, fake.objects["sealed/s-t01000-1"])
+	require.Len(t, fake.objects, 1)
+
+	storePath := filepath.Join(root, "store")
+	_, err = os.Stat(filepath.Join(storePath, "sealed", "s-t01000-1"))
+	require.True(t, os.IsNotExist(err))
+
+	fst, err := st.FsStat(ctx, storeID)
+	require.NoError(t, err)
+	require.Equal(t, int64(len(sealed)), fst.Used)
+	require.Equal(t, int64(pathSize-len(sealed)), fst.Available)
+
+	found, err := index.StorageFindSector(ctx, sector.ID, storiface.FTSealed, 0, false)
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	require.Equal(t, storeID, found[0].ID)
+	require.Equal(t, BackendS3, found[0].Backend)
+
+	// proving reads a sparse copy
+	lp, done, err := st.AcquireSectorForPoSt(ctx, sector, []uint64{3})
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(storePath, "cache", "s-t01000-1"), lp.Cache)
+	b, err := ioutil.ReadFile(lp.Sealed)
+	require.NoError(t, err)
+	require.Equal(t, sealed, b)
+	done()
+	_, err = os.Stat(lp.Sealed)
+	require.True(t, os.IsNotExist(err))
+
+	// reopening the path declares the uploaded sector
+	index2 := NewIndex()
+	st2, err := NewLocal(ctx, tstor, index2, nil)
+	require.NoError(t, err)
+	require.NoError(t, st2.OpenPath(ctx, storePath))
+	found, err = index2.StorageFindSector(ctx, sector.ID, storiface.FTSealed, 0, false)
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+
+	// acquiring the sector fetches the sealed file
+	lp, _, err = st.AcquireSector(ctx, sector, storiface.FTSealed, storiface.FTNone, storiface.PathStorage, storiface.AcquireMove)
+	require.NoError(t, err)
+	b, err = ioutil.ReadFile(lp.Sealed)
+	require.NoError(t, err)
+	require.Equal(t, sealed, b)
+
+	// the copy is kept while in use, and evicted once idle for FetchedCopyTTL
+	p := st.paths[storeID]
+	require.NoError(t, st.evictCopies(ctx, p))
+	_, err = os.Stat(lp.Sealed)
+	require.NoError(t, err)
+
+	d := Decl{SectorID: sector.ID, SectorFileType: storiface.FTSealed}
+	p.fetched[d] = time.Now().Add(-FetchedCopyTTL - time.Minute)
+	require.NoError(t, st.evictCopies(ctx, p))
+	_, err = os.Stat(lp.Sealed)
+	require.True(t, os.IsNotExist(err))
+	require.Equal(t, sealed, fake.objects["sealed/s-t01000-1"])
+
+	lp, _, err = st.AcquireSector(ctx, sector, storiface.FTSealed, storiface.FTNone, storiface.PathStorage, storiface.AcquireMove)
+	require.NoError(t, err)
+	b, err = ioutil.ReadFile(lp.Sealed)
+	require.NoError(t, err)
+	require.Equal(t, sealed, b)
+
+	require.NoError(t, st.Remove(ctx, sector.ID, storiface.FTSealed, false))
+	require.Empty(t, fake.objects)
+	_, err = os.Stat(lp.Sealed)
+	require.True(t, os.IsNotExist(err))
+}
+
+func TestLocalRelayout(t *testing.T) {
+	ctx := context.TODO()
+
+	root, err := ioutil.TempDir("", "sector-storage-teststorage-")
+	require.NoError(t, err)
+	defer os.RemoveAll(root) // nolint
+
+	tstor := &TestingLocalStorage{
+		root: root,
+	}
+
+	index := NewIndex()
+
+	st, err := NewLocal(ctx, tstor, index, nil)
+	require.NoError(t, err)
+
+	id := ID(uuid.New().String())
+	require.NoError(t, tstor.initMeta("1", &LocalStorageMeta{
+		ID:       id,
+		Weight:   1,
+		CanSeal:  true,
+		CanStore: true,
+	}))
+	p := filepath.Join(root, "1")
+	require.NoError(t, st.OpenPath(ctx, p))
+
+	s1 := abi.SectorID{Miner: 1000, Number: 1}
+	s2 := abi.SectorID{Miner: 1000, Number: 1234}
+	for _, sid := range []abi.SectorID{s1, s2} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(p, "sealed", storiface.SectorName(sid)), []byte("sealed"), 0644))
+		require.NoError(t, os.Mkdir(filepath.Join(p, "cache", storiface.SectorName(sid)), 0755))
+		require.NoError(t, st.DeclareSector(ctx, id, sid, storiface.FTSealed, true))
+		require.NoError(t, st.DeclareSector(ctx, id, sid, storiface.FTCache, true))
+	}
+
+	// the sealed file of s2 is being read
+	lkctx, unlock := context.WithCancel(ctx)
+	require.NoError(t, index.StorageLock(lkctx, s2, storiface.FTSealed, storiface.FTNone))
+
+	res, err := st.Relayout(ctx, id, storiface.LayoutSharded)
+	require.NoError(t, err)
+	require.Equal(t, 3, res.Moved)
+	require.Equal(t, []Decl{{SectorID: s2, SectorFileType: storiface.FTSealed}}, res.Busy)
+	unlock()
+
+	meta, err := ReadStorageMeta(p)
+	require.NoError(t, err)
+	require.Equal(t, storiface.LayoutSharded, meta.Layout)
+
+	// files left in the flat layout are still found
+	ref := storage.SectorRef{ID: s2, ProofType: abi.RegisteredSealProof_StackedDrg2KiBV1}
+	lp, _, err := st.AcquireSector(ctx, ref, storiface.FTSealed|storiface.FTCache, storiface.FTNone, storiface.PathStorage, storiface.AcquireMove)
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(p, "sealed", "s-t01000-1234"), lp.Sealed)
+	require.Equal(t, filepath.Join(p, "cache", "1", "s-t01000-1234"), lp.Cache)
+
+	// locks are released in the background once their context is done
+	require.Eventually(t, func() bool {
+		res, err = st.Relayout(ctx, id, storiface.LayoutSharded)
+		require.NoError(t, err)
+		return len(res.Busy) == 0
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Equal(t, 1, res.Moved)
+
+	lp, _, err = st.AcquireSector(ctx, ref, storiface.FTSealed, storiface.FTNone, storiface.PathStorage, storiface.AcquireMove)
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(p, "sealed", "1", "s-t01000-1234"), lp.Sealed)
+
+	// new files are allocated in the sharded layout
+	ref3 := storage.SectorRef{ID: abi.SectorID{Miner: 1000, Number: 2001}, ProofType: abi.RegisteredSealProof_StackedDrg2KiBV1}
+	lp, _, err = st.AcquireSector(ctx, ref3, storiface.FTNone, storiface.FTUnsealed, storiface.PathSealing, storiface.AcquireMove)
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(p, "unsealed", "2", "s-t01000-2001"), lp.Unsealed)
+	_, err = os.Stat(filepath.Dir(lp.Unsealed))
+	require.NoError(t, err)
+
+	// reopening the path declares the sharded files
+	index2 := NewIndex()
+	st2, err := NewLocal(ctx, tstor, index2, nil)
+	require.NoError(t, err)
+	require.NoError(t, st2.OpenPath(ctx, p))
+	for _, sid := range []abi.SectorID{s1, s2} {
+		found, err := index2.StorageFindSector(ctx, sid, storiface.FTSealed|storiface.FTCache, 0, false)
+		require.NoError(t, err)
+		require.Len(t, found, 1)
+	}
+
+	// back to flat, the emptied shards are removed
+	res, err = st.Relayout(ctx, id, storiface.LayoutFlat)
+	require.NoError(t, err)
+	require.Equal(t, 4, res.Moved)
+	for _, sid := range []abi.SectorID{s1, s2} {
+		_, err = os.Stat(filepath.Join(p, "sealed", storiface.SectorName(sid)))
+		require.NoError(t, err)
+	}
+	for _, shard := range []string{"0", "1"} {
+		_, err = os.Stat(filepath.Join(p, "sealed", shard))
+		require.True(t, os.IsNotExist(err))
+	}
+}
+
+func TestLocalManifest(t *testing.T) {
+	ctx := context.TODO()
+
+	root, err := ioutil.TempDir("", "sector-storage-teststorage-")
+	require.NoError(t, err)
+	defer os.RemoveAll(root) // nolint
+
+	tstor := &TestingLocalStorage{
+		root: root,
+	}
+
+	index := NewIndex()
+
+	st, err := NewLocal(ctx, tstor, index, nil)
+	require.NoError(t, err)
+
+	id := ID(uuid.New().String())
+	require.NoError(t, tstor.initMeta("1", &LocalStorageMeta{
+		ID:       id,
+		Weight:   1,
+		CanSeal:  true,
+		CanStore: true,
+	}))
+	p := filepath.Join(root, "1")
+	require.NoError(t, st.OpenPath(ctx, p))
+
+	mf, err := readManifest(p)
+	require.NoError(t, err)
+	require.Empty(t, mf.list())
+
+	s1 := abi.SectorID{Miner: 1000, Number: 1}
+	s2 := abi.SectorID{Miner: 1000, Number: 2}
+	sealed1 := filepath.Join(p, "sealed", storiface.SectorName(s1))
+	require.NoError(t, ioutil.WriteFile(sealed1, []byte("sealed"), 0644))
+	require.NoError(t, os.Mkdir(filepath.Join(p, "cache", storiface.SectorName(s1)), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(p, "cache", storiface.SectorName(s1), "p_aux"), []byte("p_aux data"), 0644))
+	require.NoError(t, st.DeclareSector(ctx, id, s1, storiface.FTSealed, true))
+	require.NoError(t, st.DeclareSector(ctx, id, s1, storiface.FTCache, true))
+
+	mf, err = readManifest(p)
+	require.NoError(t, err)
+	entries := mf.list()
+	require.Len(t, entries, 2)
+	require.Equal(t, storiface.FTSealed, entries[0].FileType)
+	require.Equal(t, int64(6), entries[0].Size)
+	require.Equal(t, storiface.FTCache, entries[1].FileType)
+	require.Equal(t, int64(10), entries[1].Size)
+
+	require.NoError(t, st.Remove(ctx, s1, storiface.FTCache, false))
+	mf, err = readManifest(p)
+	require.NoError(t, err)
+	require.Len(t, mf.list(), 1)
+
+	// files changed behind the back of the sealer
+	require.NoError(t, os.Remove(sealed1))
+	sealed2 := filepath.Join(p, "sealed", storiface.SectorName(s2))
+	require.NoError(t, ioutil.WriteFile(sealed2, []byte("sealed"), 0644))
+
+	// files being written aren't reconciled
+	s3 := storage.SectorRef{ID: abi.SectorID{Miner: 1000, Number: 3}, ProofType: abi.RegisteredSealProof_StackedDrg2KiBV1}
+	release, err := st.Reserve(ctx, s3, storiface.FTUnsealed, storiface.SectorPaths{Unsealed: string(id)}, storiface.FSOverheadSeal)
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(p, "unsealed", storiface.SectorName(s3.ID)), []byte("unsealed"), 0644))
+
+	res, err := st.VerifyManifest(ctx, id)
+	require.NoError(t, err)
+	require.Equal(t, ManifestReport{
+		Entries: 1,
+		Added:   []Decl{{SectorID: s2, SectorFileType: storiface.FTSealed}},
+		Removed: []Decl{{SectorID: s1, SectorFileType: storiface.FTSealed}},
+	}, res)
+
+	found, err := index.StorageFindSector(ctx, s1, storiface.FTSealed, 0, false)
+	require.NoError(t, err)
+	require.Empty(t, found)
+	found, err = index.StorageFindSector(ctx, s2, storiface.FTSealed, 0, false)
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+
+	release()
+	require.NoError(t, ioutil.WriteFile(sealed2, []byte("sealed, longer"), 0644))
+
+	res, err = st.VerifyManifest(ctx, id)
+	require.NoError(t, err)
+	require.Equal(t, ManifestReport{
+		Entries: 2,
+		Added:   []Decl{{SectorID: s3.ID, SectorFileType: storiface.FTUnsealed}},
+		Changed: []Decl{{SectorID: s2, SectorFileType: storiface.FTSealed}},
+	}, res)
+
+	// reopening the path declares the sectors in the manifest
+	index2 := NewIndex()
+	st2, err := NewLocal(ctx, tstor, index2, nil)
+	require.NoError(t, err)
+	require.NoError(t, st2.OpenPath(ctx, p))
+	found, err = index2.StorageFindSector(ctx, s2, storiface.FTSealed, 0, false)
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	found, err = index2.StorageFindSector(ctx, s3.ID, storiface.FTUnsealed, 0, false)
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+
+	// files copied into the path are declared once Redeclare returns
+	s4 := abi.SectorID{Miner: 1000, Number: 4}
+	require.NoError(t, ioutil.WriteFile(filepath.Join(p, "sealed", storiface.SectorName(s4)), []byte("sealed"), 0644))
+	require.NoError(t, st2.Redeclare(ctx))
+	found, err = index2.StorageFindSector(ctx, s4, storiface.FTSealed, 0, false)
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+}
+
+func TestManifestJournal(t *testing.T) {
+	defer func(n int) {
+		manifestCompactRecords = n
+	}(manifestCompactRecords)
+	manifestCompactRecords = 3
+
+	p := t.TempDir()
+	entry := func(n abi.SectorNumber) ManifestEntry {
+		return ManifestEntry{Sector: abi.SectorID{Miner: 1000, Number: n}, FileType: storiface.FTSealed, Size: int64(n)}
+	}
+	journalExists := func() bool {
+		_, err := os.Stat(filepath.Join(p, ManifestJournal))
+		return err == nil
+	}
+
+	// the first change writes the manifest, later ones are journaled
+	m := newManifest(p)
+	require.NoError(t, m.put(entry(1)))
+	require.False(t, journalExists())
+	require.NoError(t, m.put(entry(2)))
+	require.NoError(t, m.drop(Decl{SectorID: entry(1).Sector, SectorFileType: storiface.FTSealed}))
+	require.True(t, journalExists())
+
+	read, err := readManifest(p)
+	require.NoError(t, err)
+	require.Equal(t, []ManifestEntry{entry(2)}, read.list())
+	require.Equal(t, 2, read.records)
+
+	// the journal is compacted into the manifest once it has more records
+	require.NoError(t, m.put(entry(3), entry(4)))
+	require.False(t, journalExists())
+	read, err = readManifest(p)
+	require.NoError(t, err)
+	require.Equal(t, []ManifestEntry{entry(2), entry(3), entry(4)}, read.list())
+
+	// a torn record is skipped, and the manifest rewritten on the next change
+	require.NoError(t, m.put(entry(5)))
+	f, err := os.OpenFile(filepath.Join(p, ManifestJournal), os.O_APPEND|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	_, err = f.Write([]byte(`{"Entry":{"Sec`))
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	read, err = readManifest(p)
+	require.NoError(t, err)
+	require.True(t, read.torn)
+	require.Len(t, read.list(), 4)
+
+	require.NoError(t, read.put(entry(6)))
+	require.False(t, journalExists())
+	read, err = readManifest(p)
+	require.NoError(t, err)
+	require.Len(t, read.list(), 5)
+}
+
+func TestLocalHealth(t *testing.T) {
+	ctx := context.TODO()
+
+	root, err := ioutil.TempDir("", "sector-storage-teststorage-")
+	require.NoError(t, err)
+	defer os.RemoveAll(root) // nolint
+
+	tstor := &TestingLocalStorage{
+		root: root,
+	}
+
+	index := NewIndex()
+
+	st, err := NewLocal(ctx, tstor, index, nil)
+	require.NoError(t, err)
+
+	var ids []ID
+	for _, sub := range []string{"1", "2"} {
+		id := ID(uuid.New().String())
+		require.NoError(t, tstor.initMeta(sub, &LocalStorageMeta{
+			ID:       id,
+			Weight:   1,
+			CanSeal:  true,
+			CanStore: true,
+		}))
+		require.NoError(t, st.OpenPath(ctx, filepath.Join(root, sub)))
+		ids = append(ids, id)
+	}
+
+	sid := abi.SectorID{Miner: 1000, Number: 1}
+	require.NoError(t, ioutil.WriteFile(filepath.Join(root, "1", "sealed", storiface.SectorName(sid)), make([]byte, 1<<20), 0644))
+	require.NoError(t, st.DeclareSector(ctx, ids[0], sid, storiface.FTSealed, true))
+
+	p := st.paths[ids[0]]
+	st.probePath(ctx, p)
+	require.Equal(t, HealthOK, p.health.Status, p.health.Reasons)
+	require.NotZero(t, p.health.ReadLatency)
+	require.NotZero(t, p.health.WriteLatency)
+	_, err = os.Stat(filepath.Join(root, "1", probeFile))
+	require.True(t, os.IsNotExist(err))
+
+	defer func(d, f time.Duration) {
+		DegradedReadLatency, FailingReadLatency = d, f
+	}(DegradedReadLatency, FailingReadLatency)
+
+	// degraded paths are only used when healthy ones are full
+	DegradedReadLatency = 0
+	st.probePath(ctx, p)
+	st.reportStorage(ctx)
+
+	h, err := index.StorageHealth(ctx, ids[0])
+	require.NoError(t, err)
+	require.Equal(t, HealthDegraded, h.Status)
+	require.Len(t, h.Reasons, 1)
+
+	best, err := index.StorageBestAlloc(ctx, storiface.FTUnsealed, 2048, storiface.PathSealing)
+	require.NoError(t, err)
+	require.Len(t, best, 2)
+	require.Equal(t, ids[1], best[0].ID)
+
+	// failing paths aren't used
+	FailingReadLatency = 0
+	st.probePath(ctx, p)
+	st.reportStorage(ctx)
+
+	h, err = index.StorageHealth(ctx, ids[0])
+	require.NoError(t, err)
+	require.Equal(t, HealthFailing, h.Status)
+	require.Equal(t, 1, h.FailingProbes)
+	st.probePath(ctx, p)
+	require.Equal(t, 2, p.health.FailingProbes)
+
+	best, err = index.StorageBestAlloc(ctx, storiface.FTUnsealed, 2048, storiface.PathSealing)
+	require.NoError(t, err)
+	require.Len(t, best, 1)
+	require.Equal(t, ids[1], best[0].ID)
+
+	// I/O errors degrade the path until the next probe
+	FailingReadLatency, DegradedReadLatency = time.Hour, time.Hour
+	st.ioError(p, xerrors.New("input/output error"))
+	st.probePath(ctx, p)
+	require.Equal(t, HealthDegraded, p.health.Status)
+	require.Equal(t, uint64(1), p.health.IOErrors)
+	require.Zero(t, p.health.FailingProbes)
+	st.probePath(ctx, p)
+	require.Equal(t, HealthOK, p.health.Status)
+
+	// errors of sector file accesses count for the path of the file
+	sealed := filepath.Join(root, "1", "sealed", storiface.SectorName(sid))
+	st.FileError(xerrors.Errorf("reading: %w", &os.PathError{Op: "read", Path: sealed, Err: syscall.EIO}))
+	st.FileError(&os.PathError{Op: "open", Path: sealed, Err: syscall.ENOENT})
+	st.FileError(&os.PathError{Op: "read", Path: filepath.Join(root, "elsewhere"), Err: syscall.EIO})
+	st.FileError(xerrors.New("generating vanilla proof"))
+	require.Equal(t, uint64(2), atomic.LoadUint64(&p.ioErrors))
+	require.Zero(t, atomic.LoadUint64(&st.paths[ids[1]].ioErrors))
+}
@@ -110,6 +110,21 @@ func (mr *MockSectorIndexMockRecorder) StorageFindSector(ctx, sector, ft, ssize,
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StorageFindSector", reflect.TypeOf((*MockSectorIndex)(nil).StorageFindSector), ctx, sector, ft, ssize, allowFetch)
 }
 
+// StorageHealth mocks base method.
+func (m *MockSectorIndex) StorageHealth(arg0 context.Context, arg1 stores.ID) (stores.PathHealth, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StorageHealth", arg0, arg1)
+	ret0, _ := ret[0].(stores.PathHealth)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// StorageHealth indicates an expected call of StorageHealth.
+func (mr *MockSectorIndexMockRecorder) StorageHealth(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StorageHealth", reflect.TypeOf((*MockSectorIndex)(nil).StorageHealth), arg0, arg1)
+}
+
 // StorageInfo mocks base method.
 func (m *MockSectorIndex) StorageInfo(arg0 context.Context, arg1 stores.ID) (stores.StorageInfo, error) {
 	m.ctrl.T.Helper()
@@ -139,6 +139,20 @@ func (mr *MockStoreMockRecorder) AcquireSector(ctx, s, existing, allocate, seali
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AcquireSector", reflect.TypeOf((*MockStore)(nil).AcquireSector), ctx, s, existing, allocate, sealing, op)
 }
 
+// DeclareSector mocks base method.
+func (m *MockStore) DeclareSector(ctx context.Context, id stores.ID, s abi.SectorID, ft storiface.SectorFileType, primary bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeclareSector", ctx, id, s, ft, primary)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeclareSector indicates an expected call of DeclareSector.
+func (mr *MockStoreMockRecorder) DeclareSector(ctx, id, s, ft, primary interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeclareSector", reflect.TypeOf((*MockStore)(nil).DeclareSector), ctx, id, s, ft, primary)
+}
+
 // FsStat mocks base method.
 func (m *MockStore) FsStat(ctx context.Context, id stores.ID) (fsutil.FsStat, error) {
 	m.ctrl.T.Helper()
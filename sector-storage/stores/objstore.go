@@ -0,0 +1,557 @@
+package stores
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+const (
+	// BackendS3 stores files through an S3-compatible API
+	BackendS3 = "s3"
+
+	unsignedPayload = "UNSIGNED-PAYLOAD"
+)
+
+// objectPartSize is the size of the parts large files are uploaded in, which
+// allows 64GiB sectors in 128 parts
+var objectPartSize int64 = 512 << 20
+
+// ObjectStoreConfig configures the object store holding the stored files of a
+// storage path, see LocalStorageMeta.Backend
+type ObjectStoreConfig struct {
+	// Type of the object store, only "s3" is supported
+	Type string
+
+	// Endpoint of the API, eg. https://s3.us-east-1.amazonaws.com or
+	// http://127.0.0.1:9000 for a local MinIO. Buckets are addressed in the path.
+	Endpoint string
+	Region   string
+	Bucket   string
+
+	// Prefix of the object keys, allows sharing a bucket between paths
+	Prefix string
+
+	// Credentials are only referenced, the keys are never stored in the path
+	// metadata. They are read from the AWS shared credentials file at
+	// CredentialsFile, using Profile or "default", when set, otherwise from the
+	// environment variables named by AccessKeyEnv and SecretKeyEnv, which
+	// default to AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY.
+	CredentialsFile string `json:",omitempty"`
+	Profile         string `json:",omitempty"`
+	AccessKeyEnv    string `json:",omitempty"`
+	SecretKeyEnv    string `json:",omitempty"`
+
+	// UploadCache uploads the cache files too, otherwise they stay in the
+	// local directory of the path
+	UploadCache bool
+}
+
+// ObjectInfo describes a stored object
+type ObjectInfo struct {
+	Key  string
+	Size int64
+}
+
+// ObjectStore is the subset of an object storage API used for storing sector
+// files. Keys are relative to the configured prefix. Missing objects are
+// reported with errors wrapping os.ErrNotExist.
+type ObjectStore interface {
+	// Put uploads the file at path as key
+	Put(ctx context.Context, key string, path string) error
+
+	// GetRange reads size bytes of the object starting at offset
+	GetRange(ctx context.Context, key string, offset, size int64) (io.ReadCloser, error)
+
+	Stat(ctx context.Context, key string) (ObjectInfo, error)
+	List(ctx context.Context, prefix string) ([]ObjectInfo, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// NewObjectStore returns a client for the configured object store
+func NewObjectStore(cfg ObjectStoreConfig) (ObjectStore, error) {
+	switch cfg.Type {
+	case BackendS3:
+		return NewS3Store(cfg)
+	default:
+		return nil, xerrors.Errorf("unknown object store type %q", cfg.Type)
+	}
+}
+
+// ParseObjectStoreURL parses the location of objects in an object store, eg.
+// s3://bucket/prefix?endpoint=http://127.0.0.1:9000&region=us-east-1. The endpoint defaults to the AWS endpoint of the region. The credentials are
+// read from the environment unless the credentials and profile parameters
+// name a credentials file.
+func ParseObjectStoreURL(s string) (ObjectStoreConfig, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return ObjectStoreConfig{}, xerrors.Errorf("parsing object store url: %w", err)
+	}
+	if u.Scheme != BackendS3 {
+		return ObjectStoreConfig{}, xerrors.Errorf("unknown object store type %q", u.Scheme)
+	}
+	if u.Host == "" {
+		return ObjectStoreConfig{}, xerrors.Errorf("object store url %s has no bucket", s)
+	}
+
+	q := u.Query()
+	cfg := ObjectStoreConfig{
+		Type:     BackendS3,
+		Endpoint: q.Get("endpoint"),
+		Region:   q.Get("region"),
+		Bucket:   u.Host,
+		Prefix:   strings.Trim(u.Path, "/"),
+
+		CredentialsFile: q.Get("credentials"),
+		Profile:         q.Get("profile"),
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = "https://s3." + cfg.Region + ".amazonaws.com"
+	}
+
+	return cfg, nil
+}
+
+// S3Store is an ObjectStore talking to an S3-compatible API, signing requests
+// with AWS signature version 4
+type S3Store struct {
+	endpoint *url.URL
+	cfg      ObjectStoreConfig
+	client   *http.Client
+
+	accessKey, secretKey string
+}
+
+func NewS3Store(cfg ObjectStoreConfig) (*S3Store, error) {
+	if cfg.Endpoint == "" || cfg.Bucket == "" {
+		return nil, xerrors.Errorf("s3 endpoint and bucket must be set")
+	}
+
+	u, err := url.Parse(cfg.Endpoint)
+	if err != nil {
+		return nil, xerrors.Errorf("parsing s3 endpoint: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, xerrors.Errorf("s3 endpoint must be a http or https url, got %q", cfg.Endpoint)
+	}
+
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	cfg.Prefix = strings.Trim(cfg.Prefix, "/")
+
+	ak, sk, err := cfg.credentials()
+	if err != nil {
+		return nil, err
+	}
+
+	return &S3Store{
+		endpoint:  u,
+		cfg:       cfg,
+		client:    &http.Client{},
+		accessKey: ak,
+		secretKey: sk,
+	}, nil
+}
+
+// credentials reads the keys referenced by the config
+func (cfg ObjectStoreConfig) credentials() (string, string, error) {
+	if cfg.CredentialsFile != "" {
+		return readCredentialsFile(cfg.CredentialsFile, cfg.Profile)
+	}
+
+	akEnv, skEnv := cfg.AccessKeyEnv, cfg.SecretKeyEnv
+	if akEnv == "" {
+		akEnv = "AWS_ACCESS_KEY_ID"
+	}
+	if skEnv == "" {
+		skEnv = "AWS_SECRET_ACCESS_KEY"
+	}
+	return os.Getenv(akEnv), os.Getenv(skEnv), nil
+}
+
+// readCredentialsFile reads the keys of the profile from an AWS shared
+// credentials file
+func readCredentialsFile(file string, profile string) (string, string, error) {
+	if profile == "" {
+		profile = "default"
+	}
+
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return "", "", xerrors.Errorf("reading s3 credentials: %w", err)
+	}
+
+	var section, ak, sk string
+	for _, line := range strings.Split(string(b), "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";"):
+		case strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]"):
+			section = strings.TrimSpace(line[1 : len(line)-1])
+		case section == profile:
+			kv := strings.SplitN(line, "=", 2)
+			if len(kv) != 2 {
+				continue
+			}
+			switch strings.TrimSpace(kv[0]) {
+			case "aws_access_key_id":
+				ak = strings.TrimSpace(kv[1])
+			case "aws_secret_access_key":
+				sk = strings.TrimSpace(kv[1])
+			}
+		}
+	}
+
+	if ak == "" || sk == "" {
+		return "", "", xerrors.Errorf("no s3 credentials for profile %q in %s", profile, file)
+	}
+	return ak, sk, nil
+}
+
+func (s *S3Store) Put(ctx context.Context, key string, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return xerrors.Errorf("opening %s: %w", path, err)
+	}
+	defer f.Close() // nolint
+
+	st, err := f.Stat()
+	if err != nil {
+		return xerrors.Errorf("stat %s: %w", path, err)
+	}
+
+	if st.Size() <= objectPartSize {
+		resp, err := s.do(ctx, http.MethodPut, key, nil, nil, io.NewSectionReader(f, 0, st.Size()), st.Size())
+		if err != nil {
+			return err
+		}
+		return resp.Body.Close()
+	}
+
+	return s.putMultipart(ctx, key, f, st.Size())
+}
+
+type s3InitiateMultipart struct {
+	UploadID string `xml:"UploadId"`
+}
+
+type s3CompletePart struct {
+	PartNumber int
+	ETag       string
+}
+
+type s3CompleteMultipart struct {
+	XMLName xml.Name         `xml:"CompleteMultipartUpload"`
+	Parts   []s3CompletePart `xml:"Part"`
+}
+
+func (s *S3Store) putMultipart(ctx context.Context, key string, f *os.File, size int64) error {
+	resp, err := s.do(ctx, http.MethodPost, key, url.Values{"uploads": {""}}, nil, nil, 0)
+	if err != nil {
+		return xerrors.Errorf("initiating multipart upload: %w", err)
+	}
+	var init s3InitiateMultipart
+	err = xml.NewDecoder(resp.Body).Decode(&init)
+	_ = resp.Body.Close()
+	if err != nil {
+		return xerrors.Errorf("decoding multipart upload: %w", err)
+	}
+
+	err = func() error {
+		var complete s3CompleteMultipart
+		for off, n := int64(0), 1; off < size; off, n = off+objectPartSize, n+1 {
+			psize := size - off
+			if psize > objectPartSize {
+				psize = objectPartSize
+			}
+
+			q := url.Values{
+				"partNumber": {strconv.Itoa(n)},
+				"uploadId":   {init.UploadID},
+			}
+			resp, err := s.do(ctx, http.MethodPut, key, q, nil, io.NewSectionReader(f, off, psize), psize)
+			if err != nil {
+				return xerrors.Errorf("uploading part %d: %w", n, err)
+			}
+			_ = resp.Body.Close()
+
+			complete.Parts = append(complete.Parts, s3CompletePart{
+				PartNumber: n,
+				ETag:       resp.Header.Get("ETag"),
+			})
+		}
+
+		body, err := xml.Marshal(complete)
+		if err != nil {
+			return err
+		}
+		resp, err := s.do(ctx, http.MethodPost, key, url.Values{"uploadId": {init.UploadID}}, nil, bytes.NewReader(body), int64(len(body)))
+		if err != nil {
+			return xerrors.Errorf("completing multipart upload: %w", err)
+		}
+		return resp.Body.Close()
+	}()
+	if err != nil {
+		resp, aerr := s.do(context.Background(), http.MethodDelete, key, url.Values{"uploadId": {init.UploadID}}, nil, nil, 0)
+		if aerr != nil {
+			log.Warnw("aborting multipart upload", "key", key, "error", aerr)
+		} else {
+			_ = resp.Body.Close()
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (s *S3Store) GetRange(ctx context.Context, key string, offset, size int64) (io.ReadCloser, error) {
+	h := http.Header{}
+	h.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, offset+size-1))
+
+	resp, err := s.do(ctx, http.MethodGet, key, nil, h, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusPartialContent {
+		_ = resp.Body.Close()
+		return nil, xerrors.Errorf("s3 GET %s: expected a partial response, got %s", key, resp.Status)
+	}
+
+	return resp.Body, nil
+}
+
+func (s *S3Store) Stat(ctx context.Context, key string) (ObjectInfo, error) {
+	resp, err := s.do(ctx, http.MethodHead, key, nil, nil, nil, 0)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	_ = resp.Body.Close()
+
+	return ObjectInfo{
+		Key:  key,
+		Size: resp.ContentLength,
+	}, nil
+}
+
+type s3ListResult struct {
+	Contents []struct {
+		Key  string
+		Size int64
+	}
+	IsTruncated           bool
+	NextContinuationToken string
+}
+
+func (s *S3Store) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	full := s.key(prefix)
+
+	var out []ObjectInfo
+	var token string
+	for {
+		q := url.Values{
+			"list-type": {"2"},
+			"prefix":    {full},
+		}
+		if token != "" {
+			q.Set("continuation-token", token)
+		}
+
+		resp, err := s.do(ctx, http.MethodGet, "", q, nil, nil, 0)
+		if err != nil {
+			return nil, xerrors.Errorf("listing %s: %w", prefix, err)
+		}
+		var res s3ListResult
+		err = xml.NewDecoder(resp.Body).Decode(&res)
+		_ = resp.Body.Close()
+		if err != nil {
+			return nil, xerrors.Errorf("decoding list of %s: %w", prefix, err)
+		}
+
+		for _, c := range res.Contents {
+			out = append(out, ObjectInfo{
+				Key:  strings.TrimPrefix(strings.TrimPrefix(c.Key, s.cfg.Prefix), "/"),
+				Size: c.Size,
+			})
+		}
+
+		if !res.IsTruncated {
+			return out, nil
+		}
+		token = res.NextContinuationToken
+	}
+}
+
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+	resp, err := s.do(ctx, http.MethodDelete, key, nil, nil, nil, 0)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+func (s *S3Store) key(key string) string {
+	if s.cfg.Prefix == "" {
+		return key
+	}
+	return s.cfg.Prefix + "/" + key
+}
+
+// do sends a signed request for the object at key, or for the bucket if key
+// is empty. Responses other than 2xx are returned as errors.
+func (s *S3Store) do(ctx context.Context, method, key string, query url.Values, header http.Header, body io.Reader, size int64) (*http.Response, error) {
+	u := *s.endpoint
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + s.cfg.Bucket
+	if key != "" {
+		u.Path += "/" + s.key(key)
+	}
+	u.RawQuery = s3Query(query)
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, xerrors.Errorf("creating request: %w", err)
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if body != nil {
+		req.ContentLength = size
+	}
+
+	signV4(req, s.accessKey, s.secretKey, s.cfg.Region, unsignedPayload, time.Now())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, xerrors.Errorf("s3 %s %s: %w", method, key, err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		_ = resp.Body.Close()
+
+		if resp.StatusCode == http.StatusNotFound && key != "" {
+			return nil, xerrors.Errorf("s3 %s %s: %w", method, key, os.ErrNotExist)
+		}
+		return nil, xerrors.Errorf("s3 %s %s: %s: %s", method, key, resp.Status, string(msg))
+	}
+
+	return resp, nil
+}
+
+// s3Query encodes the query the way it's signed, url.Values.Encode encodes
+// spaces as '+' which S3 doesn't accept in signatures
+func s3Query(q url.Values) string {
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var parts []string
+	for _, k := range keys {
+		for _, v := range q[k] {
+			parts = append(parts, s3Escape(k, true)+"="+s3Escape(v, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// s3Escape percent-encodes everything but the unreserved characters, and '/'
+// unless escapeSlash is set
+func s3Escape(s string, escapeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !escapeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// signV4 adds the AWS signature version 4 headers to the request. The host,
+// the range and all x-amz-* headers are signed.
+func signV4(req *http.Request, accessKey, secretKey, region, payloadHash string, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{
+		"host": req.URL.Host,
+	}
+	for k, v := range req.Header {
+		lk := strings.ToLower(k)
+		if lk == "range" || strings.HasPrefix(lk, "x-amz-") {
+			headers[lk] = strings.TrimSpace(strings.Join(v, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	var canonHeaders strings.Builder
+	for _, k := range names {
+		canonHeaders.WriteString(k + ":" + headers[k] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonQuery := s3Query(req.URL.Query())
+
+	canonRequest := strings.Join([]string{
+		req.Method,
+		s3Escape(req.URL.Path, false),
+		canonQuery,
+		canonHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + region + "/s3/aws4_request"
+	reqHash := sha256.Sum256([]byte(canonRequest))
+	toSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(reqHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+secretKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	sig := hex.EncodeToString(hmacSHA256(key, toSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", accessKey, scope, signedHeaders, sig))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	_, _ = h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+var _ ObjectStore = &S3Store{}
@@ -143,13 +143,14 @@ func (r *Remote) AcquireSector(ctx context.Context, s storage.SectorRef, existin
 
 		url, err := r.acquireFromRemote(ctx, s.ID, fileType, dest)
 		if err != nil {
+			fileError(r.local, err)
 			return storiface.SectorPaths{}, storiface.SectorPaths{}, err
 		}
 
 		storiface.SetPathByType(&paths, fileType, dest)
 		storiface.SetPathByType(&stores, fileType, storageID)
 
-		if err := r.index.StorageDeclareSector(ctx, ID(storageID), s.ID, fileType, op == storiface.AcquireMove); err != nil {
+		if err := r.local.DeclareSector(ctx, ID(storageID), s.ID, fileType, op == storiface.AcquireMove); err != nil {
 			log.Warnf("declaring sector %v in %s failed: %+v", s, storageID, err)
 			continue
 		}
@@ -333,6 +334,10 @@ func (r *Remote) MoveStorage(ctx context.Context, s storage.SectorRef, types sto
 	return r.local.MoveStorage(ctx, s, types)
 }
 
+func (r *Remote) DeclareSector(ctx context.Context, id ID, s abi.SectorID, ft storiface.SectorFileType, primary bool) error {
+	return r.local.DeclareSector(ctx, id, s, ft, primary)
+}
+
 func (r *Remote) Remove(ctx context.Context, sid abi.SectorID, typ storiface.SectorFileType, force bool) error {
 	if bits.OnesCount(uint(typ)) != 1 {
 		return xerrors.New("delete expects one file type")
@@ -566,6 +571,87 @@ func (r *Remote) CheckIsUnsealed(ctx context.Context, s storage.SectorRef, offse
 	return false, nil
 }
 
+// AllocatedRanges returns the ranges of the unsealed sector file in the storage
+// path which hold unsealed data, read from the allocation trailer of the file.
+func (r *Remote) AllocatedRanges(ctx context.Context, s storage.SectorRef, id ID) ([]storage.Range, error) {
+	ft := storiface.FTUnsealed
+
+	paths, storageIDs, err := r.local.AcquireSector(ctx, s, ft, storiface.FTNone, storiface.PathStorage, storiface.AcquireMove)
+	if err != nil {
+		return nil, xerrors.Errorf("acquire local: %w", err)
+	}
+
+	if path := storiface.PathByType(paths, ft); path != "" && ID(storiface.PathByType(storageIDs, ft)) == id {
+		ssize, err := s.ProofType.SectorSize()
+		if err != nil {
+			return nil, err
+		}
+
+		pf, err := r.pfHandler.OpenPartialFile(abi.PaddedPieceSize(ssize), path)
+		if err != nil {
+			fileError(r.local, err)
+			return nil, xerrors.Errorf("opening partial file: %w", err)
+		}
+		defer r.pfHandler.Close(pf) // nolint
+
+		return allocatedRanges(pf)
+	}
+
+	si, err := r.index.StorageFindSector(ctx, s.ID, ft, 0, false)
+	if err != nil {
+		return nil, xerrors.Errorf("StorageFindSector: %w", err)
+	}
+
+	var merr error
+	for _, info := range si {
+		if info.ID != id {
+			continue
+		}
+
+		for _, url := range info.URLs {
+			out, err := r.getAllocatedRanges(ctx, url, s.ProofType)
+			if err != nil {
+				merr = multierror.Append(merr, xerrors.Errorf("get allocated ranges from %s: %w", url, err))
+				continue
+			}
+
+			return out, nil
+		}
+	}
+
+	if merr == nil {
+		return nil, xerrors.Errorf("no unsealed copy of sector %d in storage %s: %w", s.ID, id, storiface.ErrSectorNotFound)
+	}
+	return nil, merr
+}
+
+func (r *Remote) getAllocatedRanges(ctx context.Context, url string, spt abi.RegisteredSealProof) ([]storage.Range, error) {
+	url = fmt.Sprintf("%s/%d/allocated", url, spt)
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, xerrors.Errorf("request: %w", err)
+	}
+	req.Header = r.auth.Clone()
+	req = req.WithContext(ctx)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, xerrors.Errorf("do request: %w", err)
+	}
+	defer resp.Body.Close() // nolint
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, xerrors.Errorf("unexpected http response: %d", resp.StatusCode)
+	}
+
+	var out []storage.Range
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, xerrors.Errorf("decoding allocated ranges: %w", err)
+	}
+
+	return out, nil
+}
+
 // Reader returns a reader for an unsealed piece at the given offset in the given sector.
 // If the Miner has the unsealed piece locally, it will return a reader that reads from the local copy.
 // If the Miner does NOT have the unsealed piece locally, it will query all workers that have the unsealed sector file
@@ -599,6 +685,7 @@ func (r *Remote) Reader(ctx context.Context, s storage.SectorRef, offset, size a
 		// open the unsealed sector file for the given sector size located at the given path.
 		pf, err := r.pfHandler.OpenPartialFile(abi.PaddedPieceSize(ssize), path)
 		if err != nil {
+			fileError(r.local, err)
 			return nil, xerrors.Errorf("opening partial file: %w", err)
 		}
 		log.Debugf("local partial file opened %s (+%d,%d)", path, offset, size)
@@ -607,13 +694,19 @@ func (r *Remote) Reader(ctx context.Context, s storage.SectorRef, offset, size a
 		// in the unsealed sector file. That is what `HasAllocated` checks for.
 		has, err := r.pfHandler.HasAllocated(pf, storiface.UnpaddedByteIndex(offset.Unpadded()), size.Unpadded())
 		if err != nil {
+			fileError(r.local, err)
 			return nil, xerrors.Errorf("has allocated: %w", err)
 		}
 		log.Debugf("check if partial file is allocated %s (+%d,%d)", path, offset, size)
 
 		if has {
 			log.Infof("returning piece reader for local unsealed piece sector=%+v, (offset=%d, size=%d)", s.ID, offset, size)
-			return r.pfHandler.Reader(pf, storiface.PaddedByteIndex(offset), size)
+			f, err := r.pfHandler.Reader(pf, storiface.PaddedByteIndex(offset), size)
+			if err != nil {
+				fileError(r.local, err)
+				return nil, err
+			}
+			return &fileErrorReader{File: f, s: r.local}, nil
 		}
 
 		log.Debugf("miner has unsealed file but not unseal piece, %s (+%d,%d)", path, offset, size)
@@ -0,0 +1,36 @@
+package storiface
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/ipfs/go-cid"
+
+	"github.com/filecoin-project/go-state-types/abi"
+)
+
+// SectorExportMeta is the name of the metadata file of an exported sector, it
+// is written after the sector files so a partial export can't be imported
+const SectorExportMeta = "sector.json"
+
+// SectorExport describes a sector exported for backups, the sealed file and
+// the cache files are stored next to it
+type SectorExport struct {
+	Sector    abi.SectorID
+	ProofType abi.RegisteredSealProof
+	CommR     cid.Cid
+	Exported  time.Time
+
+	// Info is the sector metadata of the sealing pipeline
+	Info json.RawMessage
+
+	Files []ExportedFile
+}
+
+// ExportedFile is a file of an exported sector, Path is relative to the export
+// directory
+type ExportedFile struct {
+	Path   string
+	Size   int64
+	SHA256 string
+}
@@ -15,14 +15,19 @@ type SectorRedoParams struct {
 	SectorNumber abi.SectorNumber
 	SealPath     string
 	StorePath    string
+
+	// layouts of the sector files in the paths, filled in from the
+	// sectorstore.json of the paths
+	SealLayout  SectorLayout
+	StoreLayout SectorLayout
 }
 
 func (s *SectorRedoParams) SectorSealPath(sid abi.SectorID, fileType SectorFileType) string {
-	return filepath.Join(s.SealPath, fileType.String(), SectorName(sid))
+	return s.SealLayout.SectorPath(s.SealPath, sid, fileType)
 }
 
 func (s *SectorRedoParams) SectorStorePath(sid abi.SectorID, fileType SectorFileType) string {
-	return filepath.Join(s.StorePath, fileType.String(), SectorName(sid))
+	return s.StoreLayout.SectorPath(s.StorePath, sid, fileType)
 }
 
 
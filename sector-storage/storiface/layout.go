@@ -0,0 +1,61 @@
+package storiface
+
+import (
+	"path/filepath"
+	"strconv"
+
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-state-types/abi"
+)
+
+// SectorLayout is the directory layout of the sector files in a storage path
+type SectorLayout string
+
+const (
+	// LayoutFlat keeps the files of a type in one directory, eg. sealed/s-t01000-1234
+	LayoutFlat SectorLayout = ""
+
+	// LayoutSharded groups the files of a type in directories of
+	// SectorsPerShard sector numbers, eg. sealed/1/s-t01000-1234
+	LayoutSharded SectorLayout = "sharded"
+
+	SectorsPerShard = 1000
+)
+
+func ParseSectorLayout(s string) (SectorLayout, error) {
+	switch s {
+	case "", "flat":
+		return LayoutFlat, nil
+	case string(LayoutSharded):
+		return LayoutSharded, nil
+	default:
+		return "", xerrors.Errorf("unknown sector layout %q, expected flat or sharded", s)
+	}
+}
+
+func (l SectorLayout) String() string {
+	if l == LayoutFlat {
+		return "flat"
+	}
+	return string(l)
+}
+
+// SectorPath returns the path of the sector file in the storage path at root
+func (l SectorLayout) SectorPath(root string, sid abi.SectorID, fileType SectorFileType) string {
+	if l == LayoutSharded {
+		return filepath.Join(root, fileType.String(), ShardName(sid), SectorName(sid))
+	}
+	return filepath.Join(root, fileType.String(), SectorName(sid))
+}
+
+// ShardName returns the name of the shard directory of the sector
+func ShardName(sid abi.SectorID) string {
+	return strconv.FormatUint(uint64(sid.Number)/SectorsPerShard, 10)
+}
+
+// IsShardName returns whether name is the name of a shard directory
+func IsShardName(name string) bool {
+	_, err := strconv.ParseUint(name, 10, 64)
+	return err == nil
+}
@@ -1,5 +1,11 @@
 package storiface
 
+import (
+	"time"
+
+	"github.com/filecoin-project/go-state-types/abi"
+)
+
 type PathType string
 
 const (
@@ -13,3 +19,25 @@ const (
 	AcquireMove AcquireMode = "move"
 	AcquireCopy AcquireMode = "copy"
 )
+
+// UnsealedPath is the space tracked unsealed copies take in a storage path
+type UnsealedPath struct {
+	ID   string
+	Used uint64
+	// Budget is 0 when copies in the path aren't evicted
+	Budget uint64
+
+	// Copies are in eviction order
+	Copies []UnsealedCopy
+}
+
+type UnsealedCopy struct {
+	Sector    abi.SectorID
+	Allocated uint64
+	// Releasable is the part of the copy which can be evicted, the rest is
+	// pinned by deals kept unsealed, or the sector isn't proving yet
+	Releasable uint64
+
+	LastAccess time.Time
+	Reads      uint64
+}
@@ -23,6 +23,13 @@ type WorkerInfo struct {
 	// Default should be false (zero value, i.e. resources taken into account).
 	IgnoreResources bool
 	Resources       WorkerResources
+
+	// TaskLimits caps the number of tasks of a type running on the worker at
+	// the same time. Task types without a limit are only bound by resources.
+	TaskLimits map[types.TaskType]int64
+
+	// Labels are set by the operator to route sectors to groups of workers
+	Labels map[string]string
 }
 
 type WorkerResources struct {
@@ -43,6 +50,8 @@ type WorkerStats struct {
 	MemUsedMax uint64
 	GpuUsed    bool   // nolint
 	CpuUse     uint64 // nolint
+
+	TaskCounts map[types.TaskType]int64
 }
 
 const (
@@ -67,6 +76,48 @@ type WorkerJob struct {
 	Hostname string `json:",omitempty"` // optional, set for ret-wait jobs
 }
 
+type DrainState string
+
+const (
+	DrainWaiting    DrainState = "waiting"    // waiting for running tasks to finish
+	DrainEvacuating DrainState = "evacuating" // moving sector files only stored on the worker
+	DrainDone       DrainState = "done"
+	DrainFailed     DrainState = "failed"
+	DrainCancelled  DrainState = "cancelled"
+)
+
+type WorkerDrain struct {
+	Hostname string
+	State    DrainState
+	Started  time.Time
+
+	RunningTasks int
+
+	// sectors with files only stored on the worker
+	Sectors   int
+	Evacuated int
+	Failed    map[abi.SectorNumber]string `json:",omitempty"`
+
+	Error string `json:",omitempty"`
+}
+
+// SafeToPowerOff returns whether the worker doesn't hold anything the sealer still needs
+func (d WorkerDrain) SafeToPowerOff() bool {
+	return d.State == DrainDone && len(d.Failed) == 0
+}
+
+// UnsealJob is an unseal of a sector for retrievals, concurrent requests for
+// the sector wait for the same unseal
+type UnsealJob struct {
+	Sector   abi.SectorID
+	Priority int
+	Waiters  int
+
+	Queued time.Time
+	// Started is zero while the unseal waits for a worker
+	Started time.Time
+}
+
 type WorkerCalls interface {
 	AddPiece(ctx context.Context, sector storage.SectorRef, pieceSizes []abi.UnpaddedPieceSize, newPieceSize abi.UnpaddedPieceSize, pieceData storage.Data) (types.CallID, error)
 	SealPreCommit1(ctx context.Context, sector storage.SectorRef, ticket abi.SealRandomness, pieces []abi.PieceInfo) (types.CallID, error)
@@ -102,6 +102,10 @@ func (t *testWorker) TaskNumbers(ctx context.Context) (string, error) {
 	return "0-0", nil
 }
 
+func (t *testWorker) TaskLimits(ctx context.Context) (map[types.TaskType]int64, error) {
+	return nil, nil
+}
+
 func (t *testWorker) SectorExists(context.Context, types.TaskType, storage.SectorRef) (bool, error) {
 	return true, nil
 }
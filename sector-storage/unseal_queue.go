@@ -0,0 +1,195 @@
+package sectorstorage
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/venus-sealer/sector-storage/storiface"
+	"github.com/filecoin-project/venus-sealer/types"
+)
+
+type unsealJob struct {
+	priority int
+	waiters  int
+	queued   time.Time
+	started  time.Time
+
+	cancel context.CancelFunc
+	done   chan struct{}
+	err    error
+}
+
+// unsealQueue collapses concurrent unseals of a sector into one. Unsealing
+// always produces the whole sector, so one unseal serves requests for any of
+// its ranges.
+type unsealQueue struct {
+	// setPriority changes the priority of the unseal in the scheduler
+	setPriority func(ctx context.Context, sector abi.SectorID, priority int) (bool, error)
+
+	lk       sync.Mutex
+	priority int
+	jobs     map[abi.SectorID]*unsealJob
+}
+
+func newUnsealQueue(priority int) *unsealQueue {
+	return &unsealQueue{
+		priority: priority,
+		jobs:     map[abi.SectorID]*unsealJob{},
+	}
+}
+
+func (q *unsealQueue) setBasePriority(priority int) {
+	q.lk.Lock()
+	q.priority = priority
+	q.lk.Unlock()
+}
+
+// do runs unseal unless the sector is being unsealed already, then it waits for
+// that unseal. The unseal is cancelled once nobody waits for it anymore.
+func (q *unsealQueue) do(ctx context.Context, sector abi.SectorID, unseal func(ctx context.Context) error) error {
+	q.lk.Lock()
+	prio := q.priority
+	if p := types.GetPriority(ctx); p > prio {
+		prio = p
+	}
+
+	job, ok := q.jobs[sector]
+	if !ok {
+		jctx, cancel := context.WithCancel(context.Background())
+		job = &unsealJob{
+			priority: prio,
+			queued:   time.Now(),
+			cancel:   cancel,
+			done:     make(chan struct{}),
+		}
+		q.jobs[sector] = job
+
+		go q.run(jctx, sector, job, unseal)
+	} else {
+		log.Debugw("waiting for running unseal", "sector", sector, "waiters", job.waiters)
+	}
+	job.waiters++
+	q.lk.Unlock()
+
+	if ok {
+		if err := q.boost(ctx, sector, prio); err != nil {
+			log.Warnw("raising unseal priority", "sector", sector, "error", err)
+		}
+	}
+
+	select {
+	case <-job.done:
+		return job.err
+	case <-ctx.Done():
+		q.lk.Lock()
+		job.waiters--
+		if job.waiters == 0 {
+			job.cancel()
+			// later requests start a new unseal instead of waiting for the cancelled one
+			if q.jobs[sector] == job {
+				delete(q.jobs, sector)
+			}
+		}
+		q.lk.Unlock()
+		return ctx.Err()
+	}
+}
+
+func (q *unsealQueue) run(ctx context.Context, sector abi.SectorID, job *unsealJob, unseal func(ctx context.Context) error) {
+	err := unseal(ctx)
+	job.cancel()
+
+	q.lk.Lock()
+	if q.jobs[sector] == job {
+		delete(q.jobs, sector)
+	}
+	job.err = err
+	q.lk.Unlock()
+
+	close(job.done)
+}
+
+// priorityOf returns the priority to schedule the unseal of the sector with
+func (q *unsealQueue) priorityOf(sector abi.SectorID) int {
+	q.lk.Lock()
+	defer q.lk.Unlock()
+
+	if job, ok := q.jobs[sector]; ok {
+		return job.priority
+	}
+	return q.priority
+}
+
+func (q *unsealQueue) markStarted(sector abi.SectorID) {
+	q.lk.Lock()
+	defer q.lk.Unlock()
+
+	if job, ok := q.jobs[sector]; ok {
+		job.started = time.Now()
+	}
+}
+
+// boost raises the priority of the queued unseal of the sector
+func (q *unsealQueue) boost(ctx context.Context, sector abi.SectorID, priority int) error {
+	q.lk.Lock()
+	job, ok := q.jobs[sector]
+	if !ok {
+		q.lk.Unlock()
+		return xerrors.Errorf("sector %d isn't being unsealed", sector.Number)
+	}
+	if priority <= job.priority || !job.started.IsZero() {
+		q.lk.Unlock()
+		return nil
+	}
+	job.priority = priority
+	q.lk.Unlock()
+
+	// the unseal may not have reached the scheduler yet, it reads the new priority then
+	_, err := q.setPriority(ctx, sector, priority)
+	return err
+}
+
+// status returns the unseals, those waiting for a worker first by priority
+func (q *unsealQueue) status() []storiface.UnsealJob {
+	q.lk.Lock()
+	out := make([]storiface.UnsealJob, 0, len(q.jobs))
+	for sector, job := range q.jobs {
+		out = append(out, storiface.UnsealJob{
+			Sector:   sector,
+			Priority: job.priority,
+			Waiters:  job.waiters,
+			Queued:   job.queued,
+			Started:  job.started,
+		})
+	}
+	q.lk.Unlock()
+
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Started.IsZero() != out[j].Started.IsZero() {
+			return out[i].Started.IsZero()
+		}
+		if out[i].Priority != out[j].Priority {
+			return out[i].Priority > out[j].Priority
+		}
+		return out[i].Queued.Before(out[j].Queued)
+	})
+
+	return out
+}
+
+// UnsealQueue returns the running unseals for retrievals
+func (m *Manager) UnsealQueue() []storiface.UnsealJob {
+	return m.unseals.status()
+}
+
+// BoostUnseal raises the priority of the unseal of the sector while it waits
+// for a worker, e.g. for paid retrievals
+func (m *Manager) BoostUnseal(ctx context.Context, sector abi.SectorID, priority int) error {
+	return m.unseals.boost(ctx, sector, priority)
+}
@@ -0,0 +1,123 @@
+package sectorstorage
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/venus-sealer/types"
+)
+
+func TestUnsealQueueDedup(t *testing.T) {
+	ctx := context.Background()
+	sector := abi.SectorID{Miner: 1000, Number: 1}
+
+	q := newUnsealQueue(0)
+	var lk sync.Mutex
+	var boosted []int
+	q.setPriority = func(ctx context.Context, s abi.SectorID, priority int) (bool, error) {
+		lk.Lock()
+		defer lk.Unlock()
+		boosted = append(boosted, priority)
+		return s == sector, nil
+	}
+
+	unsealing := make(chan struct{})
+	release := make(chan struct{})
+	calls := 0
+	unseal := func(ctx context.Context) error {
+		calls++
+		close(unsealing)
+		<-release
+		return xerrors.New("unseal failed")
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, 3)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		errs[0] = q.do(ctx, sector, unseal)
+	}()
+	<-unsealing
+
+	// later requests with a higher priority raise the priority of the unseal
+	for i := 1; i < 3; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = q.do(types.WithPriority(ctx, 10*i), sector, unseal)
+		}(i)
+
+		require.Eventually(t, func() bool {
+			status := q.status()
+			return len(status) == 1 && status[0].Waiters == i+1 && status[0].Priority == 10*i
+		}, time.Second, 10*time.Millisecond)
+	}
+	require.Equal(t, 20, q.priorityOf(sector))
+
+	// the priority doesn't change anymore once a worker runs the unseal
+	q.markStarted(sector)
+	require.NoError(t, q.boost(ctx, sector, 100))
+	require.Equal(t, 20, q.status()[0].Priority)
+
+	close(release)
+	wg.Wait()
+
+	require.Equal(t, 1, calls)
+	for _, err := range errs {
+		require.EqualError(t, err, "unseal failed")
+	}
+	require.ElementsMatch(t, []int{10, 20}, boosted)
+	require.Empty(t, q.status())
+	require.Error(t, q.boost(ctx, sector, 100))
+}
+
+func TestUnsealQueueCancel(t *testing.T) {
+	sector := abi.SectorID{Miner: 1000, Number: 1}
+	q := newUnsealQueue(0)
+
+	cancelled := make(chan struct{})
+	release := make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan error)
+	go func() {
+		done <- q.do(ctx, sector, func(ctx context.Context) error {
+			<-ctx.Done()
+			close(cancelled)
+			<-release
+			return ctx.Err()
+		})
+	}()
+
+	require.Eventually(t, func() bool {
+		return len(q.status()) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	// the unseal stops when the last waiter leaves
+	cancel()
+	require.ErrorIs(t, <-done, context.Canceled)
+
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("unseal wasn't cancelled")
+	}
+
+	// a new request doesn't join the cancelled unseal, which is still stopping
+	require.NoError(t, q.do(context.Background(), sector, func(ctx context.Context) error {
+		return nil
+	}))
+
+	close(release)
+	require.Eventually(t, func() bool {
+		return len(q.status()) == 0
+	}, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,483 @@
+package sectorstorage
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/docker/go-units"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/specs-storage/storage"
+
+	"github.com/filecoin-project/venus-sealer/sector-storage/stores"
+	"github.com/filecoin-project/venus-sealer/sector-storage/storiface"
+	"github.com/filecoin-project/venus-sealer/types"
+)
+
+// UnsealedCacheCheckInterval is how often the unsealed copies of all tracked
+// sectors are listed and the space they take in storage paths is checked
+// against the budgets. Reads and releases only refresh the copies of the
+// sectors they touched.
+var UnsealedCacheCheckInterval = 5 * time.Minute
+
+// Eviction policies of the unsealed cache
+const (
+	UnsealedEvictLRU = "lru"
+	UnsealedEvictLFU = "lfu"
+)
+
+// UnsealedCacheConfig limits the space unsealed copies take in storage paths, e.g.
+//
+//	[Storage.UnsealedCache]
+//	Budget = "2TiB"
+//	Policy = "lfu"
+//	[Storage.UnsealedCache.PathBudgets]
+//	"3f9e5d4a-5e6c-4a39-a1b1-4b0c9b7f6f2e" = "500GiB"
+//
+// Once the copies in a path take more than its budget, the ranges the sealing
+// pipeline marked as safe to free are released, starting with the least recently
+// (lru) or least frequently (lfu) read copies. Ranges of deals with KeepUnsealed
+// set, or all deals with Sealing.AlwaysKeepUnsealedCopy, are never released.
+// Copies are tracked once the sector is proving or has been read.
+type UnsealedCacheConfig struct {
+	// Budget applies to every storage path, empty disables eviction
+	Budget string
+	// PathBudgets overrides Budget for single storage paths by storage ID
+	PathBudgets map[string]string
+	// Policy is lru (default) or lfu
+	Policy string
+}
+
+type unsealedCacheConfig struct {
+	budget      uint64
+	pathBudgets map[stores.ID]uint64
+	policy      string
+}
+
+func parseUnsealedCacheConfig(c UnsealedCacheConfig) (unsealedCacheConfig, error) {
+	out := unsealedCacheConfig{
+		pathBudgets: map[stores.ID]uint64{},
+		policy:      c.Policy,
+	}
+
+	switch c.Policy {
+	case "":
+		out.policy = UnsealedEvictLRU
+	case UnsealedEvictLRU, UnsealedEvictLFU:
+	default:
+		return unsealedCacheConfig{}, xerrors.Errorf("unknown eviction policy '%s', expected %s or %s", c.Policy, UnsealedEvictLRU, UnsealedEvictLFU)
+	}
+
+	parse := func(s string) (uint64, error) {
+		if s == "" {
+			return 0, nil
+		}
+		b, err := units.RAMInBytes(s)
+		if err != nil {
+			return 0, err
+		}
+		if b < 0 {
+			return 0, xerrors.Errorf("negative size %s", s)
+		}
+		return uint64(b), nil
+	}
+
+	var err error
+	if out.budget, err = parse(c.Budget); err != nil {
+		return unsealedCacheConfig{}, xerrors.Errorf("parsing Budget: %w", err)
+	}
+	for id, s := range c.PathBudgets {
+		if out.pathBudgets[stores.ID(id)], err = parse(s); err != nil {
+			return unsealedCacheConfig{}, xerrors.Errorf("parsing budget of storage %s: %w", id, err)
+		}
+	}
+
+	return out, nil
+}
+
+func (c unsealedCacheConfig) budgetOf(id stores.ID) uint64 {
+	if b, ok := c.pathBudgets[id]; ok {
+		return b
+	}
+	return c.budget
+}
+
+func (c unsealedCacheConfig) enabled() bool {
+	if c.budget > 0 {
+		return true
+	}
+	for _, b := range c.pathBudgets {
+		if b > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+type unsealedSector struct {
+	ref storage.SectorRef
+
+	// released is set once the sealing pipeline marked ranges of the sector as
+	// safe to free, until then the whole sector is pinned
+	released   bool
+	safeToFree []storage.Range
+
+	lastAccess time.Time
+	reads      uint64
+
+	// unsealed ranges of the copies by storage path, as of the last check
+	copies map[stores.ID][]storage.Range
+}
+
+// unsealedCache tracks the unsealed copies of sectors and releases them when
+// storage paths hold more than their budget
+type unsealedCache struct {
+	index stores.SectorIndex
+
+	// allocated returns the unsealed ranges of the copy in the storage path
+	allocated func(ctx context.Context, sector storage.SectorRef, id stores.ID) ([]storage.Range, error)
+	// release frees the ranges of the copy in the storage path
+	release func(ctx context.Context, sector storage.SectorRef, id stores.ID, ranges []storage.Range) error
+
+	lk      sync.Mutex
+	cfg     unsealedCacheConfig
+	sectors map[abi.SectorID]*unsealedSector
+
+	// dirty are the sectors read, released or evicted since the last check,
+	// the next check refreshes only their copies unless full is set
+	dirty map[abi.SectorID]struct{}
+	full  bool
+
+	kick chan struct{}
+}
+
+func newUnsealedCache(index stores.SectorIndex, cfg unsealedCacheConfig) *unsealedCache {
+	return &unsealedCache{
+		index:   index,
+		cfg:     cfg,
+		sectors: map[abi.SectorID]*unsealedSector{},
+		dirty:   map[abi.SectorID]struct{}{},
+		full:    true,
+		kick:    make(chan struct{}, 1),
+	}
+}
+
+func (c *unsealedCache) setConfig(cfg unsealedCacheConfig) {
+	c.lk.Lock()
+	c.cfg = cfg
+	// copies aren't listed while the cache is disabled
+	c.full = true
+	c.lk.Unlock()
+
+	c.poke()
+}
+
+func (c *unsealedCache) poke() {
+	select {
+	case c.kick <- struct{}{}:
+	default:
+	}
+}
+
+// must be called with lk held
+func (c *unsealedCache) sector(ref storage.SectorRef) *unsealedSector {
+	s, ok := c.sectors[ref.ID]
+	if !ok {
+		s = &unsealedSector{ref: ref}
+		c.sectors[ref.ID] = s
+	}
+	return s
+}
+
+func (c *unsealedCache) markReleased(ref storage.SectorRef, safeToFree []storage.Range) {
+	c.lk.Lock()
+	s := c.sector(ref)
+	s.released = true
+	s.safeToFree = safeToFree
+	c.dirty[ref.ID] = struct{}{}
+	c.lk.Unlock()
+
+	c.poke()
+}
+
+func (c *unsealedCache) recordAccess(ref storage.SectorRef) {
+	c.lk.Lock()
+	s := c.sector(ref)
+	s.lastAccess = time.Now()
+	s.reads++
+	c.dirty[ref.ID] = struct{}{}
+	c.lk.Unlock()
+
+	// reads may unseal sectors again
+	c.poke()
+}
+
+func (c *unsealedCache) run(ctx context.Context) {
+	t := time.NewTicker(UnsealedCacheCheckInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-t.C:
+			c.lk.Lock()
+			c.full = true
+			c.lk.Unlock()
+		case <-c.kick:
+		case <-ctx.Done():
+			return
+		}
+
+		c.check(ctx)
+	}
+}
+
+func (c *unsealedCache) check(ctx context.Context) {
+	c.lk.Lock()
+	cfg := c.cfg
+	var refs []storage.SectorRef
+	if c.full {
+		for _, s := range c.sectors {
+			refs = append(refs, s.ref)
+		}
+	} else {
+		for sid := range c.dirty {
+			refs = append(refs, c.sectors[sid].ref)
+		}
+	}
+	c.full = false
+	c.dirty = map[abi.SectorID]struct{}{}
+	c.lk.Unlock()
+
+	if !cfg.enabled() {
+		return
+	}
+
+	copies := map[abi.SectorID]map[stores.ID][]storage.Range{}
+	for _, ref := range refs {
+		infos, err := c.index.StorageFindSector(ctx, ref.ID, storiface.FTUnsealed, 0, false)
+		if err != nil {
+			log.Warnw("finding unsealed copies", "sector", ref.ID, "error", err)
+			continue
+		}
+
+		copies[ref.ID] = map[stores.ID][]storage.Range{}
+		for _, info := range infos {
+			ranges, err := c.allocated(ctx, ref, info.ID)
+			if err != nil {
+				log.Warnw("reading unsealed ranges", "sector", ref.ID, "storage", info.ID, "error", err)
+				continue
+			}
+			copies[ref.ID][info.ID] = ranges
+		}
+	}
+
+	c.lk.Lock()
+	for sid, cp := range copies {
+		if s, ok := c.sectors[sid]; ok {
+			s.copies = cp
+		}
+	}
+	c.lk.Unlock()
+
+	for _, p := range c.status() {
+		if p.Budget == 0 || p.Used <= p.Budget {
+			continue
+		}
+
+		c.evict(ctx, p)
+	}
+}
+
+func (c *unsealedCache) evict(ctx context.Context, p storiface.UnsealedPath) {
+	id := stores.ID(p.ID)
+	used := p.Used
+
+	for _, cp := range p.Copies {
+		if used <= p.Budget {
+			break
+		}
+		if cp.Releasable == 0 {
+			continue
+		}
+
+		c.lk.Lock()
+		s, ok := c.sectors[cp.Sector]
+		if !ok {
+			c.lk.Unlock()
+			continue
+		}
+		ref := s.ref
+		ranges := intersectRanges(s.copies[id], s.safeToFree)
+		c.lk.Unlock()
+
+		if err := c.release(ctx, ref, id, ranges); err != nil {
+			log.Warnw("releasing unsealed copy", "sector", ref.ID, "storage", id, "error", err)
+			continue
+		}
+
+		freed := rangesSize(ranges)
+		if freed > used {
+			freed = used
+		}
+		used -= freed
+
+		log.Infow("released unsealed copy", "sector", ref.ID, "storage", id, "freed", freed, "lastAccess", cp.LastAccess, "reads", cp.Reads)
+
+		c.lk.Lock()
+		if s, ok := c.sectors[ref.ID]; ok {
+			delete(s.copies, id) // read again in the next check
+			c.dirty[ref.ID] = struct{}{}
+		}
+		c.lk.Unlock()
+	}
+
+	if used > p.Budget {
+		log.Warnw("unsealed copies still take more than the budget of the storage path, the rest is pinned", "storage", id, "used", used, "budget", p.Budget)
+	}
+}
+
+// status returns the tracked copies by storage path, in eviction order
+func (c *unsealedCache) status() []storiface.UnsealedPath {
+	c.lk.Lock()
+	defer c.lk.Unlock()
+
+	paths := map[stores.ID]*storiface.UnsealedPath{}
+	for _, s := range c.sectors {
+		for id, allocated := range s.copies {
+			p, ok := paths[id]
+			if !ok {
+				p = &storiface.UnsealedPath{ID: string(id), Budget: c.cfg.budgetOf(id)}
+				paths[id] = p
+			}
+
+			cp := storiface.UnsealedCopy{
+				Sector:     s.ref.ID,
+				Allocated:  rangesSize(allocated),
+				LastAccess: s.lastAccess,
+				Reads:      s.reads,
+			}
+			if s.released {
+				cp.Releasable = rangesSize(intersectRanges(allocated, s.safeToFree))
+			}
+
+			p.Used += cp.Allocated
+			p.Copies = append(p.Copies, cp)
+		}
+	}
+
+	out := make([]storiface.UnsealedPath, 0, len(paths))
+	for _, p := range paths {
+		sortEvictionOrder(p.Copies, c.cfg.policy)
+		out = append(out, *p)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].ID < out[j].ID
+	})
+
+	return out
+}
+
+func sortEvictionOrder(copies []storiface.UnsealedCopy, policy string) {
+	sort.SliceStable(copies, func(i, j int) bool {
+		a, b := copies[i], copies[j]
+		if policy == UnsealedEvictLFU && a.Reads != b.Reads {
+			return a.Reads < b.Reads
+		}
+		if !a.LastAccess.Equal(b.LastAccess) {
+			return a.LastAccess.Before(b.LastAccess)
+		}
+		if a.Sector.Miner != b.Sector.Miner {
+			return a.Sector.Miner < b.Sector.Miner
+		}
+		return a.Sector.Number < b.Sector.Number
+	})
+}
+
+// intersectRanges returns the parts of the ranges in a which are also in b
+func intersectRanges(a, b []storage.Range) []storage.Range {
+	a, b = sortedRanges(a), sortedRanges(b)
+
+	var out []storage.Range
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		aend, bend := a[i].Offset+a[i].Size, b[j].Offset+b[j].Size
+
+		start, end := a[i].Offset, aend
+		if b[j].Offset > start {
+			start = b[j].Offset
+		}
+		if bend < end {
+			end = bend
+		}
+		if start < end {
+			out = append(out, storage.Range{Offset: start, Size: end - start})
+		}
+
+		if aend < bend {
+			i++
+		} else {
+			j++
+		}
+	}
+
+	return out
+}
+
+func sortedRanges(rs []storage.Range) []storage.Range {
+	out := append([]storage.Range(nil), rs...)
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Offset < out[j].Offset
+	})
+	return out
+}
+
+// rangesSize returns the space the ranges take in an unsealed file
+func rangesSize(rs []storage.Range) uint64 {
+	var out uint64
+	for _, r := range rs {
+		out += uint64(r.Size.Padded())
+	}
+	return out
+}
+
+// RecordUnsealedAccess records a read from the unsealed copy of the sector,
+// the unsealed cache evicts copies which are read rarely first
+func (m *Manager) RecordUnsealedAccess(sector storage.SectorRef) {
+	if m.unsealed == nil {
+		return
+	}
+	m.unsealed.recordAccess(sector)
+}
+
+// UnsealedCacheStatus returns the tracked unsealed copies by storage path, in eviction order
+func (m *Manager) UnsealedCacheStatus() []storiface.UnsealedPath {
+	if m.unsealed == nil {
+		return nil
+	}
+	return m.unsealed.status()
+}
+
+// releaseUnsealed frees the ranges of the unsealed copy in the storage path on a
+// worker with access to the path
+func (m *Manager) releaseUnsealed(ctx context.Context, sector storage.SectorRef, id stores.ID, ranges []storage.Range) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// copies being read are released in a later check
+	locked, err := m.index.StorageTryLock(ctx, sector.ID, storiface.FTNone, storiface.FTUnsealed)
+	if err != nil {
+		return xerrors.Errorf("acquiring sector lock: %w", err)
+	}
+	if !locked {
+		return xerrors.Errorf("the unsealed copy is in use")
+	}
+
+	m.verified.forget(sector.ID)
+
+	return m.sched.Schedule(ctx, sector, types.TTFinalize, newPathSelector(id), schedNop, func(ctx context.Context, w Worker) error {
+		_, err := m.waitSimpleCall(ctx)(w.ReleaseUnsealed(ctx, sector, ranges))
+		return err
+	})
+}
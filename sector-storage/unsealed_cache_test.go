@@ -0,0 +1,132 @@
+package sectorstorage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/specs-storage/storage"
+
+	"github.com/filecoin-project/venus-sealer/sector-storage/fsutil"
+	"github.com/filecoin-project/venus-sealer/sector-storage/stores"
+	"github.com/filecoin-project/venus-sealer/sector-storage/storiface"
+)
+
+func TestIntersectRanges(t *testing.T) {
+	r := func(off, size abi.UnpaddedPieceSize) storage.Range {
+		return storage.Range{Offset: off, Size: size}
+	}
+
+	require.Nil(t, intersectRanges(nil, []storage.Range{r(0, 127)}))
+	require.Equal(t, []storage.Range{r(127, 127)}, intersectRanges([]storage.Range{r(0, 508)}, []storage.Range{r(127, 127)}))
+	require.Equal(t,
+		[]storage.Range{r(0, 127), r(254, 127)},
+		intersectRanges([]storage.Range{r(254, 254), r(0, 127)}, []storage.Range{r(0, 381)}))
+	require.Nil(t, intersectRanges([]storage.Range{r(0, 127)}, []storage.Range{r(127, 127)}))
+}
+
+func TestUnsealedCacheEviction(t *testing.T) {
+	// sector 1 was read often a while ago, sector 2 once just now and sector 3
+	// isn't proving yet; releasing one sector brings the path under the budget
+	for policy, expect := range map[string]abi.SectorNumber{
+		UnsealedEvictLRU: 1,
+		UnsealedEvictLFU: 2,
+	} {
+		t.Run(policy, func(t *testing.T) {
+			ctx := context.Background()
+			spt := abi.RegisteredSealProof_StackedDrg2KiBV1
+			ssize, err := spt.SectorSize()
+			require.NoError(t, err)
+			full := storage.Range{Size: abi.PaddedPieceSize(ssize).Unpadded()}
+
+			index := stores.NewIndex()
+			require.NoError(t, index.StorageAttach(ctx, stores.StorageInfo{ID: "st", URLs: []string{"http://localhost/remote"}, CanStore: true}, fsutil.FsStat{}))
+
+			cfg, err := parseUnsealedCacheConfig(UnsealedCacheConfig{Budget: "5KiB", Policy: policy})
+			require.NoError(t, err)
+			c := newUnsealedCache(index, cfg)
+
+			allocated := map[abi.SectorNumber][]storage.Range{}
+			var released []abi.SectorNumber
+			c.allocated = func(ctx context.Context, sector storage.SectorRef, id stores.ID) ([]storage.Range, error) {
+				require.Equal(t, stores.ID("st"), id)
+				return allocated[sector.ID.Number], nil
+			}
+			c.release = func(ctx context.Context, sector storage.SectorRef, id stores.ID, ranges []storage.Range) error {
+				released = append(released, sector.ID.Number)
+				allocated[sector.ID.Number] = []storage.Range{{Offset: full.Size / 2, Size: full.Size / 2}}
+				return nil
+			}
+
+			// the first half of each sector is safe to free, the second half is kept unsealed
+			safeToFree := []storage.Range{{Size: full.Size / 2}}
+			for n := abi.SectorNumber(1); n <= 3; n++ {
+				ref := storage.SectorRef{ID: abi.SectorID{Miner: 1000, Number: n}, ProofType: spt}
+				require.NoError(t, index.StorageDeclareSector(ctx, "st", ref.ID, storiface.FTUnsealed, true))
+				allocated[n] = []storage.Range{full}
+
+				if n != 3 {
+					c.markReleased(ref, safeToFree)
+				}
+			}
+			c.sectors[abi.SectorID{Miner: 1000, Number: 1}].lastAccess = time.Now().Add(-time.Hour)
+			c.sectors[abi.SectorID{Miner: 1000, Number: 1}].reads = 5
+			c.recordAccess(storage.SectorRef{ID: abi.SectorID{Miner: 1000, Number: 2}, ProofType: spt})
+
+			c.check(ctx)
+			require.Equal(t, []abi.SectorNumber{expect}, released)
+
+			// the released half isn't counted anymore, the pinned rest stays
+			c.check(ctx)
+			require.Equal(t, []abi.SectorNumber{expect}, released)
+
+			status := c.status()
+			require.Len(t, status, 1)
+			require.Equal(t, uint64(5<<10), status[0].Used)
+			require.Equal(t, uint64(5<<10), status[0].Budget)
+			for _, cp := range status[0].Copies {
+				switch cp.Sector.Number {
+				case expect:
+					require.Equal(t, uint64(ssize/2), cp.Allocated)
+					require.Zero(t, cp.Releasable)
+				case 3:
+					require.Equal(t, uint64(ssize), cp.Allocated)
+					require.Zero(t, cp.Releasable)
+				default:
+					require.Equal(t, uint64(ssize/2), cp.Releasable)
+				}
+			}
+
+			// reads only refresh the copies of the read sector
+			var listed []abi.SectorNumber
+			c.allocated = func(ctx context.Context, sector storage.SectorRef, id stores.ID) ([]storage.Range, error) {
+				listed = append(listed, sector.ID.Number)
+				return allocated[sector.ID.Number], nil
+			}
+			c.recordAccess(storage.SectorRef{ID: abi.SectorID{Miner: 1000, Number: 2}, ProofType: spt})
+			c.check(ctx)
+			require.Equal(t, []abi.SectorNumber{2}, listed)
+		})
+	}
+}
+
+func TestUnsealedCacheConfig(t *testing.T) {
+	cfg, err := parseUnsealedCacheConfig(UnsealedCacheConfig{})
+	require.NoError(t, err)
+	require.False(t, cfg.enabled())
+	require.Equal(t, UnsealedEvictLRU, cfg.policy)
+
+	cfg, err = parseUnsealedCacheConfig(UnsealedCacheConfig{PathBudgets: map[string]string{"st": "1GiB"}})
+	require.NoError(t, err)
+	require.True(t, cfg.enabled())
+	require.Equal(t, uint64(1<<30), cfg.budgetOf("st"))
+	require.Zero(t, cfg.budgetOf("other"))
+
+	_, err = parseUnsealedCacheConfig(UnsealedCacheConfig{Policy: "fifo"})
+	require.Error(t, err)
+	_, err = parseUnsealedCacheConfig(UnsealedCacheConfig{Budget: "lots"})
+	require.Error(t, err)
+}
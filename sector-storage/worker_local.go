@@ -19,11 +19,13 @@ import (
 	"golang.org/x/xerrors"
 
 	ffi "github.com/filecoin-project/filecoin-ffi"
+	rlepluslazy "github.com/filecoin-project/go-bitfield/rle"
 	"github.com/filecoin-project/go-state-types/abi"
 	"github.com/filecoin-project/go-statestore"
 	"github.com/filecoin-project/specs-storage/storage"
 
 	"github.com/filecoin-project/venus-sealer/sector-storage/ffiwrapper"
+	"github.com/filecoin-project/venus-sealer/sector-storage/partialfile"
 	"github.com/filecoin-project/venus-sealer/sector-storage/stores"
 	"github.com/filecoin-project/venus-sealer/sector-storage/storiface"
 	"github.com/filecoin-project/venus-sealer/types"
@@ -40,8 +42,17 @@ type WorkerConfig struct {
 	// with the local worker.
 	IgnoreResourceFiltering bool
 
-	TaskTotal  int64
+	TaskTotal int64
+	// IsBindP1P2 keeps PC2 on the worker which has run PC1, the sealer side
+	// affinity rule {Task = "PC2", SameHostAs = "PC1"} does the same across workers
 	IsBindP1P2 bool
+
+	// TaskLimits caps the number of concurrently running tasks per task type,
+	// task types without a limit are only bound by TaskTotal.
+	TaskLimits map[types.TaskType]int64
+
+	// Labels are reported in the worker info, see SealerConfig.SectorLabels
+	Labels map[string]string
 }
 
 // used do provide custom proofs impl (mostly used in testing)
@@ -64,9 +75,13 @@ type LocalWorker struct {
 	taskLk      sync.Mutex
 	taskNumber  int64
 	taskTotal   int64
+	taskLimits  map[types.TaskType]int64
+	taskRunning map[types.TaskType]int64
 
 	isBindP1P2 bool
 
+	labels map[string]string
+
 	session     uuid.UUID
 	testDisable int64
 	closing     chan struct{}
@@ -78,6 +93,13 @@ func newLocalWorker(executor ExecutorFunc, wcfg WorkerConfig, store stores.Store
 		acceptTasks[taskType] = struct{}{}
 	}
 
+	taskLimits := map[types.TaskType]int64{}
+	for taskType, limit := range wcfg.TaskLimits {
+		if limit > 0 {
+			taskLimits[taskType] = limit
+		}
+	}
+
 	w := &LocalWorker{
 		storage:    store,
 		localStore: local,
@@ -89,12 +111,15 @@ func newLocalWorker(executor ExecutorFunc, wcfg WorkerConfig, store stores.Store
 		},
 		acceptTasks:     acceptTasks,
 		taskTotal:       wcfg.TaskTotal,
+		taskLimits:      taskLimits,
+		taskRunning:     map[types.TaskType]int64{},
 		executor:        executor,
 		noSwap:          wcfg.NoSwap,
 		ignoreResources: wcfg.IgnoreResourceFiltering,
 		session:         uuid.New(),
 		closing:         make(chan struct{}),
 		isBindP1P2:      wcfg.IsBindP1P2,
+		labels:          wcfg.Labels,
 	}
 
 	if w.executor == nil {
@@ -156,7 +181,7 @@ func (l *localWorkerPathProvider) AcquireSector(ctx context.Context, sector stor
 
 			sid := storiface.PathByType(storageIDs, fileType)
 
-			if err := l.w.sindex.StorageDeclareSector(ctx, stores.ID(sid), sector.ID, fileType, l.op == storiface.AcquireMove); err != nil {
+			if err := l.w.localStore.DeclareSector(ctx, stores.ID(sid), sector.ID, fileType, l.op == storiface.AcquireMove); err != nil {
 				log.Errorf("declare sector error: %+v", err)
 			}
 		}
@@ -214,12 +239,52 @@ var returnFunc = map[types.ReturnType]func(context.Context, types.CallID, storif
 	types.ReturnFetch:           rfunc(storiface.WorkerReturn.ReturnFetch),
 }
 
-func (l *LocalWorker) asyncCall(ctx context.Context, sector storage.SectorRef, rt types.ReturnType, work func(ctx context.Context, ci types.CallID) (interface{}, error)) (types.CallID, error) {
+// task types the worker calls are accounted as for TaskLimits
+var returnTaskType = map[types.ReturnType]types.TaskType{
+	types.ReturnAddPiece:        types.TTAddPiece,
+	types.ReturnSealPreCommit1:  types.TTPreCommit1,
+	types.ReturnSealPreCommit2:  types.TTPreCommit2,
+	types.ReturnSealCommit1:     types.TTCommit1,
+	types.ReturnSealCommit2:     types.TTCommit2,
+	types.ReturnFinalizeSector:  types.TTFinalize,
+	types.ReturnReleaseUnsealed: types.TTFinalize,
+	types.ReturnMoveStorage:     types.TTFetch,
+	types.ReturnUnsealPiece:     types.TTUnseal,
+	types.ReturnFetch:           types.TTFetch,
+}
+
+func (l *LocalWorker) acquireTaskSlot(tt types.TaskType) error {
+	l.taskLk.Lock()
+	defer l.taskLk.Unlock()
+
+	if limit, ok := l.taskLimits[tt]; ok && l.taskRunning[tt] >= limit {
+		log.Errorf("task number of %s [%d-%d]", tt.Short(), l.taskRunning[tt], limit)
+		return xerrors.Errorf("The number of %s tasks has reached the upper limit [%d-%d] ", tt.Short(), l.taskRunning[tt], limit)
+	}
+
 	taskNumber := atomic.AddInt64(&l.taskNumber, 1)
 	if l.taskTotal >= 0 && taskNumber > l.taskTotal {
 		atomic.AddInt64(&l.taskNumber, -1)
 		log.Errorf("task number [%d-%d]", taskNumber-1, l.taskTotal)
-		return types.CallID{}, xerrors.Errorf("The number of tasks has reached the upper limit [%d-%d] ", taskNumber-1, l.taskTotal)
+		return xerrors.Errorf("The number of tasks has reached the upper limit [%d-%d] ", taskNumber-1, l.taskTotal)
+	}
+
+	l.taskRunning[tt]++
+	return nil
+}
+
+func (l *LocalWorker) releaseTaskSlot(tt types.TaskType) {
+	l.taskLk.Lock()
+	defer l.taskLk.Unlock()
+
+	atomic.AddInt64(&l.taskNumber, -1)
+	l.taskRunning[tt]--
+}
+
+func (l *LocalWorker) asyncCall(ctx context.Context, sector storage.SectorRef, rt types.ReturnType, work func(ctx context.Context, ci types.CallID) (interface{}, error)) (types.CallID, error) {
+	tt := returnTaskType[rt]
+	if err := l.acquireTaskSlot(tt); err != nil {
+		return types.CallID{}, err
 	}
 
 	ci := types.CallID{
@@ -236,7 +301,7 @@ func (l *LocalWorker) asyncCall(ctx context.Context, sector storage.SectorRef, r
 	go func() {
 		defer func() {
 			log.Infof("task [%s] complete for sector %d", rt, sector.ID.Number)
-			atomic.AddInt64(&l.taskNumber, -1)
+			l.releaseTaskSlot(tt)
 			l.running.Done()
 		}()
 
@@ -410,7 +475,73 @@ func (l *LocalWorker) FinalizeSector(ctx context.Context, sector storage.SectorR
 }
 
 func (l *LocalWorker) ReleaseUnsealed(ctx context.Context, sector storage.SectorRef, safeToFree []storage.Range) (types.CallID, error) {
-	return types.UndefCall, xerrors.Errorf("implement me")
+	return l.asyncCall(ctx, sector, types.ReturnReleaseUnsealed, func(ctx context.Context, ci types.CallID) (interface{}, error) {
+		return nil, l.releaseUnsealed(ctx, sector, safeToFree)
+	})
+}
+
+// releaseUnsealed frees the ranges in the local unsealed file of the sector and
+// removes the file once it holds no unsealed data anymore
+func (l *LocalWorker) releaseUnsealed(ctx context.Context, sector storage.SectorRef, safeToFree []storage.Range) error {
+	ssize, err := sector.ProofType.SectorSize()
+	if err != nil {
+		return err
+	}
+	maxPieceSize := abi.PaddedPieceSize(ssize)
+
+	paths, _, err := l.localStore.AcquireSector(ctx, sector, storiface.FTUnsealed, storiface.FTNone, storiface.PathStorage, storiface.AcquireMove)
+	if err != nil {
+		return xerrors.Errorf("acquiring unsealed sector path: %w", err)
+	}
+	if paths.Unsealed == "" {
+		return nil // released already
+	}
+
+	// Free doesn't update the allocation the file was opened with, so the file
+	// is opened again for each range
+	for _, r := range safeToFree {
+		pf, err := partialfile.OpenPartialFile(maxPieceSize, paths.Unsealed)
+		if err != nil {
+			return xerrors.Errorf("opening partial file: %w", err)
+		}
+
+		if err := pf.Free(storiface.PaddedByteIndex(r.Offset.Padded()), r.Size.Padded()); err != nil {
+			_ = pf.Close()
+			return xerrors.Errorf("free partial file range: %w", err)
+		}
+
+		if err := pf.Close(); err != nil {
+			return err
+		}
+	}
+
+	pf, err := partialfile.OpenPartialFile(maxPieceSize, paths.Unsealed)
+	if err != nil {
+		return xerrors.Errorf("opening partial file: %w", err)
+	}
+
+	allocated, err := pf.Allocated()
+	if err != nil {
+		_ = pf.Close()
+		return xerrors.Errorf("getting allocated ranges: %w", err)
+	}
+	left, err := rlepluslazy.Count(allocated)
+	if err != nil {
+		_ = pf.Close()
+		return xerrors.Errorf("counting allocated bytes: %w", err)
+	}
+
+	if err := pf.Close(); err != nil {
+		return err
+	}
+
+	if left == 0 {
+		if err := l.localStore.Remove(ctx, sector.ID, storiface.FTUnsealed, true); err != nil {
+			return xerrors.Errorf("removing unsealed data: %w", err)
+		}
+	}
+
+	return nil
 }
 
 func (l *LocalWorker) Remove(ctx context.Context, sector abi.SectorID) error {
@@ -444,6 +575,7 @@ func (l *LocalWorker) UnsealPiece(ctx context.Context, sector storage.SectorRef,
 	return l.asyncCall(ctx, sector, types.ReturnUnsealPiece, func(ctx context.Context, ci types.CallID) (interface{}, error) {
 		log.Debugf("worker will unseal piece now, sector=%+v", sector.ID)
 		if err = sb.UnsealPiece(ctx, sector, index, size, randomness, cid); err != nil {
+			l.localStore.FileError(err)
 			return nil, xerrors.Errorf("unsealing sector: %w", err)
 		}
 
@@ -489,12 +621,54 @@ func (l *LocalWorker) Paths(ctx context.Context) ([]stores.StoragePath, error) {
 }
 
 func (l *LocalWorker) TaskNumbers(context.Context) (string, error) {
-	str := fmt.Sprintf("%d-%d", l.taskNumber, l.taskTotal)
+	str := fmt.Sprintf("%d-%d", atomic.LoadInt64(&l.taskNumber), l.taskTotal)
 	return str, nil
 }
 
+func (l *LocalWorker) TaskLimits(context.Context) (map[types.TaskType]int64, error) {
+	l.taskLk.Lock()
+	defer l.taskLk.Unlock()
+
+	out := make(map[types.TaskType]int64, len(l.taskLimits))
+	for tt, limit := range l.taskLimits {
+		out[tt] = limit
+	}
+	return out, nil
+}
+
+// SetTaskLimit changes the number of tasks of the given type allowed to run at
+// the same time, limit <= 0 removes the limit
+func (l *LocalWorker) SetTaskLimit(ctx context.Context, tt types.TaskType, limit int64) error {
+	l.taskLk.Lock()
+	defer l.taskLk.Unlock()
+
+	if limit <= 0 {
+		delete(l.taskLimits, tt)
+		return nil
+	}
+
+	l.taskLimits[tt] = limit
+	return nil
+}
+
+func (l *LocalWorker) BindP1P2(context.Context) (bool, error) {
+	l.taskLk.Lock()
+	defer l.taskLk.Unlock()
+
+	return l.isBindP1P2, nil
+}
+
+func (l *LocalWorker) SetBindP1P2(ctx context.Context, bind bool) error {
+	l.taskLk.Lock()
+	defer l.taskLk.Unlock()
+
+	l.isBindP1P2 = bind
+	return nil
+}
+
 func (l *LocalWorker) SectorExists(ctx context.Context, task types.TaskType, sector storage.SectorRef) (bool, error) {
-	if l.isBindP1P2 && task == types.TTPreCommit2 {
+	bindP1P2, _ := l.BindP1P2(ctx)
+	if bindP1P2 && task == types.TTPreCommit2 {
 		paths, _, err := l.storage.AcquireSector(ctx, sector, 0, storiface.FTSealed, storiface.PathSealing, storiface.AcquireMode(""))
 		if err != nil {
 			log.Errorf("try to find sector paths err: %s", err.Error())
@@ -516,7 +690,7 @@ func (l *LocalWorker) SectorExists(ctx context.Context, task types.TaskType, sec
 	return true, nil
 }
 
-func (l *LocalWorker) Info(context.Context) (storiface.WorkerInfo, error) {
+func (l *LocalWorker) Info(ctx context.Context) (storiface.WorkerInfo, error) {
 	hostname, err := os.Hostname() // TODO: allow overriding from config
 	if err != nil {
 		panic(err)
@@ -542,9 +716,13 @@ func (l *LocalWorker) Info(context.Context) (storiface.WorkerInfo, error) {
 		memSwap = 0
 	}
 
+	taskLimits, _ := l.TaskLimits(ctx)
+
 	return storiface.WorkerInfo{
 		Hostname:        hostname,
 		IgnoreResources: l.ignoreResources,
+		TaskLimits:      taskLimits,
+		Labels:          l.labels,
 		Resources: storiface.WorkerResources{
 			MemPhysical: mem.Total,
 			MemSwap:     memSwap,
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	logging "github.com/ipfs/go-log/v2"
+	"go.uber.org/fx"
+
+	"github.com/filecoin-project/venus-sealer/models/repo"
+	"github.com/filecoin-project/venus-sealer/types"
+)
+
+var log = logging.Logger("service")
+
+var (
+	// AuditQueueSize is the number of entries waiting to be written, entries
+	// recorded while the queue is full are written synchronously
+	AuditQueueSize = 1024
+
+	// AuditRetention is how long entries are kept, AuditMaxEntries is the
+	// number of newest entries kept
+	AuditRetention  = 90 * 24 * time.Hour
+	AuditMaxEntries = 1000000
+
+	AuditPruneInterval = time.Hour
+)
+
+// AuditService writes audit entries in the background, so recording doesn't
+// slow down the api calls, and prunes old entries
+type AuditService struct {
+	repo.AuditRepo
+
+	entries chan *types.AuditEntry
+
+	// stopped is set under lk once run no longer takes new entries
+	lk      sync.RWMutex
+	stopped bool
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func NewAuditService(lc fx.Lifecycle, repo repo.Repo) *AuditService {
+	a := &AuditService{
+		AuditRepo: repo.AuditRepo(),
+		entries:   make(chan *types.AuditEntry, AuditQueueSize),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+
+	lc.Append(fx.Hook{
+		OnStart: func(context.Context) error {
+			go a.run()
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			a.lk.Lock()
+			a.stopped = true
+			a.lk.Unlock()
+
+			close(a.stop)
+			select {
+			case <-a.done:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		},
+	})
+
+	return a
+}
+
+// RecordAudit queues the entry to be written, it is written before returning
+// when the queue is full or the service is stopping, entries are never dropped
+func (a *AuditService) RecordAudit(entry *types.AuditEntry) error {
+	a.lk.RLock()
+	defer a.lk.RUnlock()
+
+	if !a.stopped {
+		select {
+		case a.entries <- entry:
+			return nil
+		default:
+		}
+	}
+
+	return a.AuditRepo.Append(entry)
+}
+
+func (a *AuditService) run() {
+	defer close(a.done)
+
+	a.prune()
+
+	prune := time.NewTicker(AuditPruneInterval)
+	defer prune.Stop()
+
+	for {
+		select {
+		case entry := <-a.entries:
+			a.write(entry)
+		case <-prune.C:
+			a.prune()
+		case <-a.stop:
+			// write what was recorded before stopping
+			for {
+				select {
+				case entry := <-a.entries:
+					a.write(entry)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (a *AuditService) write(entry *types.AuditEntry) {
+	if err := a.AuditRepo.Append(entry); err != nil {
+		log.Errorw("recording audit entry", "method", entry.Method, "error", err)
+	}
+}
+
+func (a *AuditService) prune() {
+	n, err := a.AuditRepo.Prune(time.Now().Add(-AuditRetention), AuditMaxEntries)
+	if err != nil {
+		log.Errorw("pruning audit entries", "error", err)
+		return
+	}
+	if n > 0 {
+		log.Infow("pruned audit entries", "removed", n)
+	}
+}
@@ -0,0 +1,29 @@
+package service
+
+import (
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/venus-sealer/models/repo"
+	"github.com/filecoin-project/venus-sealer/types"
+)
+
+var _ types.BatchStore = (*BatchService)(nil)
+
+type BatchService struct {
+	repo.BatchRepo
+}
+
+func NewBatchService(repo repo.Repo) *BatchService {
+	return &BatchService{BatchRepo: repo.BatchRepo()}
+}
+
+func (b *BatchService) SaveBatchEntry(batcher string, entry *types.BatchEntry) error {
+	return b.BatchRepo.Save(batcher, entry)
+}
+
+func (b *BatchService) DeleteBatchEntries(batcher string, sectors []abi.SectorNumber) error {
+	return b.BatchRepo.Delete(batcher, sectors)
+}
+
+func (b *BatchService) ListBatchEntries(batcher string) ([]*types.BatchEntry, error) {
+	return b.BatchRepo.List(batcher)
+}
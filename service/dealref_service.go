@@ -1,6 +1,10 @@
 package service
 
 import (
+	"github.com/ipfs/go-cid"
+
+	"github.com/filecoin-project/go-state-types/abi"
+
 	"github.com/filecoin-project/specs-actors/v2/actors/builtin/market"
 	"github.com/filecoin-project/venus-sealer/models/repo"
 	"github.com/filecoin-project/venus-sealer/types"
@@ -31,3 +35,15 @@ func (d *DealRefService) Has(dealId uint64) (bool, error) {
 func (d *DealRefService) List() (map[uint64][]types.SealedRef, error) {
 	return d.DealRefRepo.List()
 }
+
+func (d *DealRefService) GetByPiece(pieceCid cid.Cid) (map[uint64][]types.SealedRef, error) {
+	return d.DealRefRepo.GetByPiece(pieceCid)
+}
+
+func (d *DealRefService) ListWithoutPiece() (map[uint64][]types.SealedRef, error) {
+	return d.DealRefRepo.ListWithoutPiece()
+}
+
+func (d *DealRefService) SetPiece(dealId uint64, sector abi.SectorNumber, pieceCid cid.Cid) error {
+	return d.DealRefRepo.SetPiece(dealId, sector, pieceCid)
+}
@@ -0,0 +1,13 @@
+package service
+
+import (
+	"github.com/filecoin-project/venus-sealer/models/repo"
+)
+
+type IntegrityService struct {
+	repo.IntegrityRepo
+}
+
+func NewIntegrityService(repo repo.Repo) *IntegrityService {
+	return &IntegrityService{IntegrityRepo: repo.IntegrityRepo()}
+}
@@ -0,0 +1,29 @@
+package service
+
+import (
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/venus-sealer/models/repo"
+	"github.com/filecoin-project/venus-sealer/types"
+)
+
+var _ types.SectorHostStore = (*SectorHostService)(nil)
+
+type SectorHostService struct {
+	repo.SectorHostRepo
+}
+
+func NewSectorHostService(repo repo.Repo) *SectorHostService {
+	return &SectorHostService{SectorHostRepo: repo.SectorHostRepo()}
+}
+
+func (s *SectorHostService) SaveSectorHost(host *types.SectorHost) error {
+	return s.SectorHostRepo.Save(host)
+}
+
+func (s *SectorHostService) DeleteSectorHosts(sector abi.SectorID) error {
+	return s.SectorHostRepo.Delete(sector)
+}
+
+func (s *SectorHostService) ListSectorHosts() ([]*types.SectorHost, error) {
+	return s.SectorHostRepo.List()
+}
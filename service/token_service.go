@@ -0,0 +1,65 @@
+package service
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/venus-sealer/models/repo"
+	"github.com/filecoin-project/venus-sealer/types"
+)
+
+// TokenService keeps the revocation list of scoped API tokens, it's checked
+// on every authenticated request so revoked IDs are cached in memory
+type TokenService struct {
+	repo.TokenRepo
+
+	lk      sync.RWMutex
+	revoked map[string]struct{}
+}
+
+func NewTokenService(repo repo.Repo) (*TokenService, error) {
+	ts := &TokenService{TokenRepo: repo.TokenRepo()}
+
+	if err := ts.TokenRepo.DeleteExpired(time.Now()); err != nil {
+		return nil, xerrors.Errorf("dropping revocations of expired tokens: %w", err)
+	}
+
+	tokens, err := ts.TokenRepo.List()
+	if err != nil {
+		return nil, xerrors.Errorf("loading revoked tokens: %w", err)
+	}
+
+	ts.revoked = make(map[string]struct{}, len(tokens))
+	for _, t := range tokens {
+		ts.revoked[t.ID] = struct{}{}
+	}
+
+	return ts, nil
+}
+
+func (ts *TokenService) Revoke(id string, expires time.Time) error {
+	err := ts.TokenRepo.Revoke(&types.RevokedToken{
+		ID:      id,
+		Revoked: time.Now(),
+		Expires: expires,
+	})
+	if err != nil {
+		return err
+	}
+
+	ts.lk.Lock()
+	ts.revoked[id] = struct{}{}
+	ts.lk.Unlock()
+
+	return nil
+}
+
+func (ts *TokenService) IsRevoked(id string) bool {
+	ts.lk.RLock()
+	defer ts.lk.RUnlock()
+
+	_, ok := ts.revoked[id]
+	return ok
+}
@@ -0,0 +1,29 @@
+package service
+
+import (
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/venus-sealer/models/repo"
+	"github.com/filecoin-project/venus-sealer/types"
+)
+
+var _ types.UnreportedDealStore = (*UnreportedDealService)(nil)
+
+type UnreportedDealService struct {
+	repo.UnreportedDealRepo
+}
+
+func NewUnreportedDealService(repo repo.Repo) *UnreportedDealService {
+	return &UnreportedDealService{UnreportedDealRepo: repo.UnreportedDealRepo()}
+}
+
+func (u *UnreportedDealService) SaveUnreportedDeal(deal *types.UnreportedDeal) error {
+	return u.UnreportedDealRepo.Save(deal)
+}
+
+func (u *UnreportedDealService) DeleteUnreportedDeal(deal abi.DealID) error {
+	return u.UnreportedDealRepo.Delete(deal)
+}
+
+func (u *UnreportedDealService) ListUnreportedDeals() ([]*types.UnreportedDeal, error) {
+	return u.UnreportedDealRepo.List()
+}
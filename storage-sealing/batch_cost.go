@@ -0,0 +1,158 @@
+package sealing
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+
+	types2 "github.com/filecoin-project/venus/pkg/types"
+
+	"github.com/filecoin-project/venus-sealer/types"
+)
+
+var (
+	// Gas used by sealing messages, estimated from mainnet messages. The batch cost model
+	// estimates the gas of the messages of the queued sectors, these are only used when
+	// estimating fails. Aggregate proofs can't be estimated before they are computed, so
+	// the ProveCommitAggregate gas always comes from here.
+	PreCommitSectorGas         int64 = 25_000_000
+	PreCommitBatchGasBase      int64 = 15_000_000
+	PreCommitBatchGasPerSector int64 = 8_000_000
+
+	ProveCommitSectorGas             int64 = 50_000_000
+	ProveCommitAggregateGasBase      int64 = 80_000_000
+	ProveCommitAggregateGasPerSector int64 = 12_000_000
+)
+
+// batchCostModel compares the cost of one batch message against individual
+// messages at a base fee
+type batchCostModel struct {
+	singleGas         int64
+	batchGasBase      int64
+	batchGasPerSector int64
+
+	// minBatch is the smallest batch the actor accepts
+	minBatch int
+	// slack is how long before the earliest cutoff the sectors are sent at the latest
+	slack time.Duration
+	// networkFee is the batch fee burned for a batch of n sectors
+	networkFee func(n int) (abi.TokenAmount, error)
+}
+
+func (c *batchCostModel) option(baseFee abi.TokenAmount, aggregated, individual int) (types.BatchOption, error) {
+	gas := int64(individual) * c.singleGas
+	netFee := big.Zero()
+
+	if aggregated > 0 {
+		gas += c.batchGasBase + int64(aggregated)*c.batchGasPerSector
+
+		var err error
+		netFee, err = c.networkFee(aggregated)
+		if err != nil {
+			return types.BatchOption{}, xerrors.Errorf("getting batch network fee for %d sectors: %w", aggregated, err)
+		}
+	}
+
+	gasCost := big.Mul(baseFee, big.NewInt(gas))
+
+	return types.BatchOption{
+		Aggregated: aggregated,
+		Individual: individual,
+		GasCost:    gasCost,
+		NetworkFee: netFee,
+		Total:      big.Add(gasCost, netFee),
+	}, nil
+}
+
+// decide picks the cheapest split of n sectors between one batch message and
+// individual messages. Sectors too few for a batch wait for more sectors until
+// the earliest cutoff is near, unless the batch is forced.
+func (c *batchCostModel) decide(n int, baseFee abi.TokenAmount, earliestCutoff time.Time, force bool) (*types.BatchDecision, error) {
+	d := &types.BatchDecision{
+		Time:           time.Now(),
+		BaseFee:        baseFee,
+		Sectors:        n,
+		EarliestCutoff: earliestCutoff,
+	}
+
+	individual, err := c.option(baseFee, 0, n)
+	if err != nil {
+		return nil, err
+	}
+	d.Options = append(d.Options, individual)
+
+	if n < c.minBatch {
+		if flushAt := earliestCutoff.Add(-c.slack); !force && !earliestCutoff.IsZero() && d.Time.Before(flushAt) {
+			d.Wait = true
+			d.Reason = fmt.Sprintf("waiting for more sectors, %d sector(s) are below the minimum batch size of %d, they are sent individually in %s at the latest",
+				n, c.minBatch, flushAt.Sub(d.Time).Truncate(time.Second))
+			return d, nil
+		}
+
+		d.Chosen = individual
+		d.Reason = fmt.Sprintf("%d sector(s) are below the minimum batch size of %d", n, c.minBatch)
+		return d, nil
+	}
+
+	best := individual
+	var aggregated types.BatchOption
+	for k := c.minBatch; k <= n; k++ {
+		o, err := c.option(baseFee, k, n-k)
+		if err != nil {
+			return nil, err
+		}
+		if k == n {
+			aggregated = o
+		}
+		if o.Total.LessThan(best.Total) {
+			best = o
+		}
+	}
+	d.Options = append(d.Options, aggregated)
+	if best.Aggregated != 0 && best.Individual != 0 {
+		d.Options = append(d.Options, best)
+	}
+	d.Chosen = best
+
+	switch {
+	case best.Aggregated == 0:
+		d.Reason = fmt.Sprintf("sending %d sector(s) individually for %s is cheaper than one batch for %s",
+			n, types2.FIL(individual.Total).Short(), types2.FIL(aggregated.Total).Short())
+	case best.Individual == 0:
+		d.Reason = fmt.Sprintf("one batch of %d sector(s) for %s is cheaper than individual messages for %s",
+			n, types2.FIL(aggregated.Total).Short(), types2.FIL(individual.Total).Short())
+	default:
+		d.Reason = fmt.Sprintf("a batch of %d sector(s) and %d individual message(s) for %s is the cheapest split, one batch costs %s, individual messages %s",
+			best.Aggregated, best.Individual, types2.FIL(best.Total).Short(), types2.FIL(aggregated.Total).Short(), types2.FIL(individual.Total).Short())
+	}
+
+	return d, nil
+}
+
+// linearGas fits base + perSector*n to the gas of batches of one and of n sectors
+func linearGas(one, all int64, n int) (base, perSector int64) {
+	if n > 1 && all > one {
+		perSector = (all - one) / int64(n-1)
+	}
+	return one - perSector, perSector
+}
+
+// sectorsByCutoff returns the sectors ordered by cutoff, sectors without a cutoff last
+func sectorsByCutoff(sectors []abi.SectorNumber, cutoffs map[abi.SectorNumber]time.Time) []abi.SectorNumber {
+	sort.Slice(sectors, func(i, j int) bool {
+		ci, cj := cutoffs[sectors[i]], cutoffs[sectors[j]]
+		switch {
+		case ci.IsZero() != cj.IsZero():
+			return !ci.IsZero()
+		case !ci.Equal(cj):
+			return ci.Before(cj)
+		}
+		return sectors[i] < sectors[j]
+	})
+	return sectors
+}
@@ -0,0 +1,110 @@
+package sealing
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+)
+
+func testCostModel(feePerSector int64) *batchCostModel {
+	return &batchCostModel{
+		singleGas:         100,
+		batchGasBase:      200,
+		batchGasPerSector: 20,
+		minBatch:          4,
+		slack:             time.Hour,
+		networkFee: func(n int) (abi.TokenAmount, error) {
+			return big.NewInt(int64(n) * feePerSector), nil
+		},
+	}
+}
+
+func TestBatchCostModelDecide(t *testing.T) {
+	baseFee := big.NewInt(1)
+
+	t.Run("below min batch", func(t *testing.T) {
+		d, err := testCostModel(0).decide(3, baseFee, time.Time{}, false)
+		require.NoError(t, err)
+		require.Equal(t, 0, d.Chosen.Aggregated)
+		require.Equal(t, 3, d.Chosen.Individual)
+		require.Len(t, d.Options, 1)
+	})
+
+	t.Run("cheap batch", func(t *testing.T) {
+		d, err := testCostModel(0).decide(10, baseFee, time.Time{}, false)
+		require.NoError(t, err)
+		require.Equal(t, 10, d.Chosen.Aggregated)
+		require.Equal(t, 0, d.Chosen.Individual)
+		// 200 + 10*20
+		require.Equal(t, big.NewInt(400), d.Chosen.Total)
+	})
+
+	t.Run("expensive batch", func(t *testing.T) {
+		d, err := testCostModel(1000).decide(10, baseFee, time.Time{}, false)
+		require.NoError(t, err)
+		require.Equal(t, 0, d.Chosen.Aggregated)
+		require.Equal(t, 10, d.Chosen.Individual)
+		require.Equal(t, big.NewInt(1000), d.Chosen.Total)
+	})
+
+	t.Run("split", func(t *testing.T) {
+		m := testCostModel(0)
+		// the network fee grows quickly past 6 sectors
+		m.networkFee = func(n int) (abi.TokenAmount, error) {
+			if n > 6 {
+				return big.NewInt(int64(n-6) * 1000), nil
+			}
+			return big.Zero(), nil
+		}
+
+		d, err := m.decide(10, baseFee, time.Time{}, false)
+		require.NoError(t, err)
+		require.Equal(t, 6, d.Chosen.Aggregated)
+		require.Equal(t, 4, d.Chosen.Individual)
+		require.Len(t, d.Options, 3)
+	})
+
+	t.Run("wait for cutoff", func(t *testing.T) {
+		d, err := testCostModel(0).decide(3, baseFee, time.Now().Add(2*time.Hour), false)
+		require.NoError(t, err)
+		require.True(t, d.Wait)
+		require.Equal(t, 0, d.Chosen.Individual)
+
+		// within the slack of the cutoff
+		d, err = testCostModel(0).decide(3, baseFee, time.Now().Add(30*time.Minute), false)
+		require.NoError(t, err)
+		require.False(t, d.Wait)
+		require.Equal(t, 3, d.Chosen.Individual)
+
+		d, err = testCostModel(0).decide(3, baseFee, time.Now().Add(2*time.Hour), true)
+		require.NoError(t, err)
+		require.False(t, d.Wait)
+		require.Equal(t, 3, d.Chosen.Individual)
+	})
+}
+
+func TestLinearGas(t *testing.T) {
+	base, perSector := linearGas(120, 300, 10)
+	require.Equal(t, int64(100), base)
+	require.Equal(t, int64(20), perSector)
+
+	base, perSector = linearGas(120, 120, 1)
+	require.Equal(t, int64(120), base)
+	require.Equal(t, int64(0), perSector)
+}
+
+func TestSectorsByCutoff(t *testing.T) {
+	now := time.Now()
+	cutoffs := map[abi.SectorNumber]time.Time{
+		1: now.Add(time.Hour),
+		2: now,
+		4: now,
+	}
+
+	sorted := sectorsByCutoff([]abi.SectorNumber{1, 3, 4, 2}, cutoffs)
+	require.Equal(t, []abi.SectorNumber{2, 4, 1, 3}, sorted)
+}
@@ -0,0 +1,37 @@
+package sealing
+
+import (
+	"sort"
+	"time"
+
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/venus-sealer/types"
+)
+
+func batchQueue(sectors []abi.SectorNumber, cutoffs map[abi.SectorNumber]time.Time, restored map[abi.SectorNumber]struct{}, slack time.Duration) types.BatchQueue {
+	q := types.BatchQueue{
+		Sectors: make([]types.BatchedSector, 0, len(sectors)),
+		Slack:   slack,
+	}
+
+	for _, sn := range sectors {
+		_, r := restored[sn]
+		bs := types.BatchedSector{
+			Sector:   sn,
+			Cutoff:   cutoffs[sn],
+			Restored: r,
+		}
+		q.Sectors = append(q.Sectors, bs)
+
+		if !bs.Cutoff.IsZero() && (q.Cutoff.IsZero() || bs.Cutoff.Before(q.Cutoff)) {
+			q.Cutoff = bs.Cutoff
+		}
+	}
+
+	sort.Slice(q.Sectors, func(i, j int) bool {
+		return q.Sectors[i].Sector < q.Sectors[j].Sector
+	})
+
+	return q
+}
@@ -0,0 +1,166 @@
+package sealing
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	miner0 "github.com/filecoin-project/specs-actors/actors/builtin/miner"
+
+	"github.com/filecoin-project/venus-sealer/config"
+	"github.com/filecoin-project/venus-sealer/storage-sealing/sealiface"
+	"github.com/filecoin-project/venus-sealer/types"
+)
+
+type memBatchStore map[string]map[abi.SectorNumber]*types.BatchEntry
+
+func (s memBatchStore) SaveBatchEntry(batcher string, entry *types.BatchEntry) error {
+	if s[batcher] == nil {
+		s[batcher] = map[abi.SectorNumber]*types.BatchEntry{}
+	}
+	s[batcher][entry.SectorNumber] = entry
+	return nil
+}
+
+func (s memBatchStore) DeleteBatchEntries(batcher string, sectors []abi.SectorNumber) error {
+	for _, sn := range sectors {
+		delete(s[batcher], sn)
+	}
+	return nil
+}
+
+func (s memBatchStore) ListBatchEntries(batcher string) ([]*types.BatchEntry, error) {
+	var out []*types.BatchEntry
+	for _, e := range s[batcher] {
+		out = append(out, e)
+	}
+	return out, nil
+}
+
+func TestPreCommitBatcherRestore(t *testing.T) {
+	maddr, err := address.NewIDAddress(1000)
+	require.NoError(t, err)
+
+	cutoff := time.Now().Add(10 * time.Hour).Truncate(time.Second)
+	data, err := json.Marshal(&preCommitBatchData{
+		Deposit: big.NewInt(10),
+		Info:    &miner0.SectorPreCommitInfo{SectorNumber: 5},
+	})
+	require.NoError(t, err)
+
+	store := memBatchStore{}
+	require.NoError(t, store.SaveBatchEntry(types.BatcherPreCommit, &types.BatchEntry{SectorNumber: 5, Cutoff: cutoff, Data: data}))
+
+	getCfg := func() (sealiface.Config, error) {
+		return sealiface.Config{
+			MaxPreCommitBatch:   10,
+			PreCommitBatchWait:  24 * time.Hour,
+			PreCommitBatchSlack: time.Hour,
+		}, nil
+	}
+
+	b := NewPreCommitBatcher(context.Background(), &config.NetParamsConfig{BlockDelaySecs: 30}, maddr, nil, nil, func() config.MinerFeeConfig { return config.MinerFeeConfig{} }, getCfg, store)
+	defer b.Stop(context.Background()) //nolint:errcheck
+
+	q, err := b.Queue()
+	require.NoError(t, err)
+	require.Len(t, q.Sectors, 1)
+	require.Equal(t, abi.SectorNumber(5), q.Sectors[0].Sector)
+	require.True(t, q.Sectors[0].Restored)
+	require.True(t, q.Cutoff.Equal(cutoff))
+	require.Equal(t, time.Hour, q.Slack)
+
+	b.lk.Lock()
+	require.True(t, b.todo[5].deposit.Equals(big.NewInt(10)))
+	b.lk.Unlock()
+
+	require.NoError(t, b.persistDone([]abi.SectorNumber{5}))
+	require.Len(t, store[types.BatcherPreCommit], 0)
+}
+
+func TestPreCommitBatcherPrune(t *testing.T) {
+	maddr, err := address.NewIDAddress(1000)
+	require.NoError(t, err)
+
+	store := memBatchStore{}
+	for _, sn := range []abi.SectorNumber{5, 6} {
+		data, err := json.Marshal(&preCommitBatchData{
+			Deposit: big.NewInt(10),
+			Info:    &miner0.SectorPreCommitInfo{SectorNumber: sn},
+		})
+		require.NoError(t, err)
+		require.NoError(t, store.SaveBatchEntry(types.BatcherPreCommit, &types.BatchEntry{SectorNumber: sn, Data: data}))
+	}
+
+	getCfg := func() (sealiface.Config, error) {
+		return sealiface.Config{
+			MaxPreCommitBatch:  10,
+			PreCommitBatchWait: 24 * time.Hour,
+		}, nil
+	}
+
+	b := NewPreCommitBatcher(context.Background(), &config.NetParamsConfig{BlockDelaySecs: 30}, maddr, nil, nil, func() config.MinerFeeConfig { return config.MinerFeeConfig{} }, getCfg, store)
+	defer b.Stop(context.Background()) //nolint:errcheck
+
+	b.lk.Lock()
+	b.results[7] = sealiface.PreCommitBatchRes{Sectors: []abi.SectorNumber{7}}
+	b.results[8] = sealiface.PreCommitBatchRes{Sectors: []abi.SectorNumber{8}}
+	b.lk.Unlock()
+
+	select {
+	case <-b.pruned:
+		t.Fatal("restored entries must not be sent before pruning")
+	default:
+	}
+
+	b.prune(map[abi.SectorNumber]struct{}{5: {}, 8: {}})
+
+	select {
+	case <-b.pruned:
+	default:
+		t.Fatal("batcher not released after pruning")
+	}
+
+	q, err := b.Queue()
+	require.NoError(t, err)
+	require.Len(t, q.Sectors, 1)
+	require.Equal(t, abi.SectorNumber(5), q.Sectors[0].Sector)
+	require.Len(t, store[types.BatcherPreCommit], 1)
+	require.NotNil(t, store[types.BatcherPreCommit][5])
+
+	b.lk.Lock()
+	require.Len(t, b.results, 1)
+	require.Contains(t, b.results, abi.SectorNumber(8))
+	b.lk.Unlock()
+
+	// sectors couldn't be listed, keep the persisted entry for the next start
+	b.lk.Lock()
+	b.restored[5] = struct{}{}
+	b.lk.Unlock()
+	b.prune(nil)
+
+	q, err = b.Queue()
+	require.NoError(t, err)
+	require.Len(t, q.Sectors, 0)
+	require.NotNil(t, store[types.BatcherPreCommit][5])
+}
+
+func TestBatchQueue(t *testing.T) {
+	now := time.Now()
+	q := batchQueue([]abi.SectorNumber{3, 1, 2}, map[abi.SectorNumber]time.Time{
+		1: now.Add(2 * time.Hour),
+		3: now.Add(time.Hour),
+	}, map[abi.SectorNumber]struct{}{2: {}}, time.Minute)
+
+	require.Len(t, q.Sectors, 3)
+	require.Equal(t, abi.SectorNumber(1), q.Sectors[0].Sector)
+	require.True(t, q.Sectors[1].Cutoff.IsZero())
+	require.True(t, q.Sectors[1].Restored)
+	require.True(t, q.Cutoff.Equal(now.Add(time.Hour)))
+}
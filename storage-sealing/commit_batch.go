@@ -3,6 +3,7 @@ package sealing
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	types2 "github.com/filecoin-project/venus/pkg/types"
 	"sort"
 	"sync"
@@ -48,6 +49,8 @@ type CommitBatcherApi interface {
 	StateMinerInitialPledgeCollateral(context.Context, address.Address, miner.SectorPreCommitInfo, types.TipSetToken) (big.Int, error)
 	StateNetworkVersion(ctx context.Context, tok types.TipSetToken) (network.Version, error)
 	StateMinerAvailableBalance(context.Context, address.Address, types.TipSetToken) (big.Int, error)
+
+	GasEstimateGasLimit(ctx context.Context, from, to address.Address, method abi.MethodNum, value abi.TokenAmount, params []byte, tok types.TipSetToken) (int64, error)
 }
 
 type AggregateInput struct {
@@ -61,7 +64,7 @@ type CommitBatcher struct {
 	maddr     address.Address
 	mctx      context.Context
 	addrSel   AddrSel
-	feeCfg    config.MinerFeeConfig
+	feeCfg    config.GetMinerFeeConfigFunc
 	getConfig types.GetSealingConfigFunc
 	prover    ffiwrapper.Prover
 
@@ -69,6 +72,18 @@ type CommitBatcher struct {
 	todo    map[abi.SectorNumber]AggregateInput
 	waiting map[abi.SectorNumber][]chan sealiface.CommitBatchRes
 
+	// results of restored sectors sent before the state machine added them again
+	store    types.BatchStore
+	restored map[abi.SectorNumber]struct{}
+	results  map[abi.SectorNumber]sealiface.CommitBatchRes
+
+	lastDecision *types.BatchDecision
+
+	// pruned is closed once the restored entries were checked against the
+	// sectors, restored entries aren't sent before that
+	pruned    chan struct{}
+	pruneOnce sync.Once
+
 	notify, stop, stopped chan struct{}
 	force                 chan chan []sealiface.CommitBatchRes
 	lk                    sync.Mutex
@@ -76,7 +91,7 @@ type CommitBatcher struct {
 	networkParams *config.NetParamsConfig
 }
 
-func NewCommitBatcher(mctx context.Context, networkParams *config.NetParamsConfig, maddr address.Address, api CommitBatcherApi, addrSel AddrSel, feeCfg config.MinerFeeConfig, getConfig types.GetSealingConfigFunc, prov ffiwrapper.Prover) *CommitBatcher {
+func NewCommitBatcher(mctx context.Context, networkParams *config.NetParamsConfig, maddr address.Address, api CommitBatcherApi, addrSel AddrSel, feeCfg config.GetMinerFeeConfigFunc, getConfig types.GetSealingConfigFunc, prov ffiwrapper.Prover, store types.BatchStore) *CommitBatcher {
 	b := &CommitBatcher{
 		api:       api,
 		maddr:     maddr,
@@ -90,20 +105,39 @@ func NewCommitBatcher(mctx context.Context, networkParams *config.NetParamsConfi
 		todo:    map[abi.SectorNumber]AggregateInput{},
 		waiting: map[abi.SectorNumber][]chan sealiface.CommitBatchRes{},
 
+		store:    store,
+		restored: map[abi.SectorNumber]struct{}{},
+		results:  map[abi.SectorNumber]sealiface.CommitBatchRes{},
+
 		notify:  make(chan struct{}, 1),
 		force:   make(chan chan []sealiface.CommitBatchRes),
 		stop:    make(chan struct{}),
+		pruned:  make(chan struct{}),
 		stopped: make(chan struct{}),
 
 		networkParams: networkParams,
 	}
 
+	if err := b.restore(); err != nil {
+		log.Errorw("restoring commit batch queue", "error", err)
+	}
+	if len(b.restored) == 0 {
+		b.release()
+	}
+
 	go b.run()
 
 	return b
 }
 
 func (b *CommitBatcher) run() {
+	select {
+	case <-b.pruned:
+	case <-b.stop:
+		close(b.stopped)
+		return
+	}
+
 	var forceRes chan []sealiface.CommitBatchRes
 	var lastMsg []sealiface.CommitBatchRes
 
@@ -135,7 +169,7 @@ func (b *CommitBatcher) run() {
 		}
 
 		var err error
-		lastMsg, err = b.maybeStartBatch(sendAboveMax)
+		lastMsg, err = b.maybeStartBatch(sendAboveMax, forceRes != nil)
 		if err != nil {
 			log.Warnw("CommitBatcher processBatch error", "error", err)
 		}
@@ -192,7 +226,7 @@ func (b *CommitBatcher) batchWait(maxWait, slack time.Duration) time.Duration {
 	return wait
 }
 
-func (b *CommitBatcher) maybeStartBatch(notif bool) ([]sealiface.CommitBatchRes, error) {
+func (b *CommitBatcher) maybeStartBatch(notif, force bool) ([]sealiface.CommitBatchRes, error) {
 	b.lk.Lock()
 	defer b.lk.Unlock()
 
@@ -210,46 +244,94 @@ func (b *CommitBatcher) maybeStartBatch(notif bool) ([]sealiface.CommitBatchRes,
 		return nil, nil
 	}
 
-	var res []sealiface.CommitBatchRes
+	sectors := make([]abi.SectorNumber, 0, total)
+	for sn := range b.todo {
+		sectors = append(sectors, sn)
+	}
+	sectors = sectorsByCutoff(sectors, b.cutoffs)
 
-	individual := (total < cfg.MinCommitBatch) || (total < miner5.MinAggregatedSectors)
+	var aggregate, individual []abi.SectorNumber
 
-	if !individual && !cfg.AggregateAboveBaseFee.Equals(big.Zero()) {
-		tok, _, err := b.api.ChainHead(b.mctx)
-		if err != nil {
-			return nil, err
+	if cfg.BatchCostModel {
+		if len(sectors) > cfg.MaxCommitBatch {
+			sectors = sectors[:cfg.MaxCommitBatch] // the rest waits for the next batch
 		}
 
-		bf, err := b.api.ChainBaseFee(b.mctx, tok)
+		d, err := b.decide(cfg, sectors, force)
 		if err != nil {
-			return nil, xerrors.Errorf("couldn't get base fee: %w", err)
+			return nil, xerrors.Errorf("deciding commit batch split: %w", err)
+		}
+		if d.Wait {
+			log.Infow("CommitBatcher waiting for more sectors", "sectors", d.Sectors, "reason", d.Reason)
+			return nil, nil
 		}
+		b.lastDecision = d
+		log.Infow("CommitBatcher batch decision", "sectors", d.Sectors, "aggregate", d.Chosen.Aggregated, "individual", d.Chosen.Individual, "reason", d.Reason)
+
+		aggregate, individual = sectors[:d.Chosen.Aggregated], sectors[d.Chosen.Aggregated:]
+	} else {
+		aggregate = sectors
+		if (total < cfg.MinCommitBatch) || (total < miner5.MinAggregatedSectors) {
+			aggregate, individual = nil, sectors
+		}
+
+		if len(aggregate) > 0 && !cfg.AggregateAboveBaseFee.Equals(big.Zero()) {
+			tok, _, err := b.api.ChainHead(b.mctx)
+			if err != nil {
+				return nil, err
+			}
 
-		if bf.LessThan(cfg.AggregateAboveBaseFee) {
-			individual = true
+			bf, err := b.api.ChainBaseFee(b.mctx, tok)
+			if err != nil {
+				return nil, xerrors.Errorf("couldn't get base fee: %w", err)
+			}
+
+			if bf.LessThan(cfg.AggregateAboveBaseFee) {
+				aggregate, individual = nil, sectors
+			}
 		}
 	}
 
-	if individual {
-		res, err = b.processIndividually(cfg)
-	} else {
-		res, err = b.processBatch(cfg)
+	var res []sealiface.CommitBatchRes
+	var lastErr error
+
+	if len(aggregate) > 0 {
+		ares, err := b.processBatch(cfg, aggregate)
+		if err != nil {
+			log.Warnf("CommitBatcher maybeStartBatch processBatch %v", err)
+			lastErr = err
+		}
+		res = append(res, b.sent(ares, err)...)
 	}
 
-	if err != nil {
-		log.Warnf("CommitBatcher maybeStartBatch individual:%v processBatch %v", individual, err)
+	if len(individual) > 0 {
+		ires, err := b.processIndividually(cfg, individual)
+		if err != nil {
+			log.Warnf("CommitBatcher maybeStartBatch processIndividually %v", err)
+			lastErr = err
+		}
+		res = append(res, b.sent(ires, err)...)
 	}
 
-	if err != nil && len(res) == 0 {
-		return nil, err
+	if lastErr != nil && len(res) == 0 {
+		return nil, lastErr
 	}
 
+	return res, nil
+}
+
+// sent hands the results of sent messages to the sectors waiting for them, and removes the sectors from the queue
+func (b *CommitBatcher) sent(res []sealiface.CommitBatchRes, err error) []sealiface.CommitBatchRes {
 	for _, r := range res {
 		if err != nil {
 			r.Error = err.Error()
 		}
 
 		for _, sn := range r.Sectors {
+			if len(b.waiting[sn]) == 0 {
+				// restored sector, the state machine picks the result up when it adds the sector again
+				b.results[sn] = r
+			}
 			for _, ch := range b.waiting[sn] {
 				ch <- r // buffered
 			}
@@ -257,13 +339,226 @@ func (b *CommitBatcher) maybeStartBatch(notif bool) ([]sealiface.CommitBatchRes,
 			delete(b.waiting, sn)
 			delete(b.todo, sn)
 			delete(b.cutoffs, sn)
+			delete(b.restored, sn)
+		}
+
+		if err := b.persistDone(r.Sectors); err != nil {
+			log.Errorw("removing sectors from persisted commit batch queue", "sectors", r.Sectors, "error", err)
 		}
 	}
 
-	return res, nil
+	return res
 }
 
-func (b *CommitBatcher) processBatch(cfg sealiface.Config) ([]sealiface.CommitBatchRes, error) {
+// decide runs the batch cost model for the sectors at the current base fee
+func (b *CommitBatcher) decide(cfg sealiface.Config, sectors []abi.SectorNumber, force bool) (*types.BatchDecision, error) {
+	tok, _, err := b.api.ChainHead(b.mctx)
+	if err != nil {
+		return nil, err
+	}
+
+	bf, err := b.api.ChainBaseFee(b.mctx, tok)
+	if err != nil {
+		return nil, xerrors.Errorf("couldn't get base fee: %w", err)
+	}
+
+	nv, err := b.api.StateNetworkVersion(b.mctx, tok)
+	if err != nil {
+		return nil, xerrors.Errorf("couldn't get network version: %w", err)
+	}
+
+	minBatch := cfg.MinCommitBatch
+	if minBatch < miner5.MinAggregatedSectors {
+		minBatch = miner5.MinAggregatedSectors
+	}
+
+	model := &batchCostModel{
+		singleGas:         ProveCommitSectorGas,
+		batchGasBase:      ProveCommitAggregateGasBase,
+		batchGasPerSector: ProveCommitAggregateGasPerSector,
+		minBatch:          minBatch,
+		slack:             cfg.CommitBatchSlack,
+		networkFee: func(n int) (abi.TokenAmount, error) {
+			return policy.AggregateProveCommitNetworkFee(nv, n, bf)
+		},
+	}
+
+	var earliest time.Time
+	if len(sectors) > 0 {
+		earliest = b.cutoffs[sectors[0]]
+
+		if err := b.estimateGas(model, sectors[0], tok); err != nil {
+			log.Warnw("estimating ProveCommitSector gas, using the default", "gas", model.singleGas, "error", err)
+		}
+	}
+
+	return model.decide(len(sectors), bf, earliest, force)
+}
+
+// estimateGas sets the gas of individual messages in the cost model from an estimate of the
+// ProveCommitSector message of the sector
+func (b *CommitBatcher) estimateGas(model *batchCostModel, sn abi.SectorNumber, tok types.TipSetToken) error {
+	mi, err := b.api.StateMinerInfo(b.mctx, b.maddr, tok)
+	if err != nil {
+		return xerrors.Errorf("couldn't get miner info: %w", err)
+	}
+
+	enc := new(bytes.Buffer)
+	params := &miner.ProveCommitSectorParams{
+		SectorNumber: sn,
+		Proof:        b.todo[sn].Proof,
+	}
+	if err := params.MarshalCBOR(enc); err != nil {
+		return xerrors.Errorf("marshaling commit params: %w", err)
+	}
+
+	collateral, err := b.getSectorCollateral(sn, tok)
+	if err != nil {
+		return err
+	}
+
+	gas, err := b.api.GasEstimateGasLimit(b.mctx, mi.Worker, b.maddr, miner.Methods.ProveCommitSector, collateral, enc.Bytes(), tok)
+	if err != nil {
+		return err
+	}
+
+	model.singleGas = gas
+	return nil
+}
+
+// Explain returns the last decision of the batch cost model, and what it would decide for the queue now
+func (b *CommitBatcher) Explain() (types.BatchExplain, error) {
+	cfg, err := b.getConfig()
+	if err != nil {
+		return types.BatchExplain{}, xerrors.Errorf("getting config: %w", err)
+	}
+
+	b.lk.Lock()
+	defer b.lk.Unlock()
+
+	out := types.BatchExplain{Last: b.lastDecision}
+	if len(b.todo) == 0 {
+		return out, nil
+	}
+
+	sectors := make([]abi.SectorNumber, 0, len(b.todo))
+	for sn := range b.todo {
+		sectors = append(sectors, sn)
+	}
+	sectors = sectorsByCutoff(sectors, b.cutoffs)
+	if len(sectors) > cfg.MaxCommitBatch {
+		sectors = sectors[:cfg.MaxCommitBatch]
+	}
+
+	out.Next, err = b.decide(cfg, sectors, false)
+	if err != nil {
+		return types.BatchExplain{}, xerrors.Errorf("deciding commit batch split: %w", err)
+	}
+
+	return out, nil
+}
+
+// restore loads the batch queue persisted before the last shutdown
+func (b *CommitBatcher) restore() error {
+	if b.store == nil {
+		return nil
+	}
+
+	entries, err := b.store.ListBatchEntries(types.BatcherCommit)
+	if err != nil {
+		return xerrors.Errorf("listing batch entries: %w", err)
+	}
+
+	for _, e := range entries {
+		var in AggregateInput
+		if err := json.Unmarshal(e.Data, &in); err != nil {
+			log.Errorw("decoding persisted commit batch entry", "sector", e.SectorNumber, "error", err)
+			continue
+		}
+
+		b.todo[e.SectorNumber] = in
+		if !e.Cutoff.IsZero() {
+			b.cutoffs[e.SectorNumber] = e.Cutoff
+		}
+		b.restored[e.SectorNumber] = struct{}{}
+	}
+
+	if len(entries) > 0 {
+		log.Infow("restored commit batch queue", "sectors", len(b.todo))
+	}
+
+	return nil
+}
+
+// prune drops the restored sectors the state machine won't add again, and the
+// results nobody is going to pick up, live are the sectors still waiting for a batch. A nil
+// live means the sectors couldn't be listed, the restored entries are then only
+// dropped from memory and restored again on the next start
+func (b *CommitBatcher) prune(live map[abi.SectorNumber]struct{}) {
+	b.lk.Lock()
+	defer b.lk.Unlock()
+	defer b.release()
+
+	var drop []abi.SectorNumber
+	for sn := range b.restored {
+		if _, ok := live[sn]; ok {
+			continue
+		}
+		delete(b.todo, sn)
+		delete(b.cutoffs, sn)
+		delete(b.restored, sn)
+		drop = append(drop, sn)
+	}
+	for sn := range b.results {
+		if _, ok := live[sn]; !ok {
+			delete(b.results, sn)
+		}
+	}
+
+	if len(drop) == 0 || live == nil {
+		return
+	}
+	log.Infow("dropping restored commit batch entries of sectors no longer waiting for a batch", "sectors", drop)
+	if err := b.persistDone(drop); err != nil {
+		log.Errorw("removing sectors from persisted commit batch queue", "sectors", drop, "error", err)
+	}
+}
+
+// release lets run send the queued sectors
+func (b *CommitBatcher) release() {
+	b.pruneOnce.Do(func() {
+		close(b.pruned)
+	})
+}
+
+// call with b.lk
+func (b *CommitBatcher) persist(sn abi.SectorNumber) error {
+	if b.store == nil {
+		return nil
+	}
+
+	in := b.todo[sn]
+	data, err := json.Marshal(&in)
+	if err != nil {
+		return xerrors.Errorf("encoding batch entry: %w", err)
+	}
+
+	return b.store.SaveBatchEntry(types.BatcherCommit, &types.BatchEntry{
+		SectorNumber: sn,
+		Cutoff:       b.cutoffs[sn],
+		Data:         data,
+	})
+}
+
+func (b *CommitBatcher) persistDone(sectors []abi.SectorNumber) error {
+	if b.store == nil {
+		return nil
+	}
+
+	return b.store.DeleteBatchEntries(types.BatcherCommit, sectors)
+}
+
+func (b *CommitBatcher) processBatch(cfg sealiface.Config, sectors []abi.SectorNumber) ([]sealiface.CommitBatchRes, error) {
 	tok, _, err := b.api.ChainHead(b.mctx)
 	if err != nil {
 		return nil, err
@@ -283,7 +578,8 @@ func (b *CommitBatcher) processBatch(cfg sealiface.Config) ([]sealiface.CommitBa
 	infos := make([]proof5.AggregateSealVerifyInfo, 0, total)
 	collateral := big.Zero()
 
-	for id, p := range b.todo {
+	for _, id := range sectors {
+		p := b.todo[id]
 		if len(infos) >= cfg.MaxCommitBatch {
 			log.Infow("commit batch full")
 			break
@@ -340,7 +636,7 @@ func (b *CommitBatcher) processBatch(cfg sealiface.Config) ([]sealiface.CommitBa
 		return []sealiface.CommitBatchRes{res}, xerrors.Errorf("couldn't get miner info: %w", err)
 	}
 
-	maxFee := b.feeCfg.MaxCommitBatchGasFee.FeeForSectors(len(infos))
+	maxFee := b.feeCfg().MaxCommitBatchGasFee.FeeForSectors(len(infos))
 
 	bf, err := b.api.ChainBaseFee(b.mctx, tok)
 	if err != nil {
@@ -386,7 +682,7 @@ func (b *CommitBatcher) processBatch(cfg sealiface.Config) ([]sealiface.CommitBa
 	return []sealiface.CommitBatchRes{res}, nil
 }
 
-func (b *CommitBatcher) processIndividually(cfg sealiface.Config) ([]sealiface.CommitBatchRes, error) {
+func (b *CommitBatcher) processIndividually(cfg sealiface.Config, sectors []abi.SectorNumber) ([]sealiface.CommitBatchRes, error) {
 	mi, err := b.api.StateMinerInfo(b.mctx, b.maddr, nil)
 	if err != nil {
 		return nil, xerrors.Errorf("couldn't get miner info: %w", err)
@@ -413,7 +709,8 @@ func (b *CommitBatcher) processIndividually(cfg sealiface.Config) ([]sealiface.C
 
 	var res []sealiface.CommitBatchRes
 
-	for sn, info := range b.todo {
+	for _, sn := range sectors {
+		info := b.todo[sn]
 		r := sealiface.CommitBatchRes{
 			Sectors:       []abi.SectorNumber{sn},
 			FailedSectors: map[abi.SectorNumber]string{},
@@ -462,14 +759,14 @@ func (b *CommitBatcher) processSingle(cfg sealiface.Config, mi miner.MinerInfo,
 		}
 	}
 
-	goodFunds := big.Add(collateral, big.Int(b.feeCfg.MaxCommitGasFee))
+	goodFunds := big.Add(collateral, big.Int(b.feeCfg().MaxCommitGasFee))
 
 	from, _, err := b.addrSel(b.mctx, mi, api.CommitAddr, goodFunds, collateral)
 	if err != nil {
 		return "", xerrors.Errorf("no good address to send commit message from: %w", err)
 	}
 
-	uid, err := b.api.MessagerSendMsg(b.mctx, from, b.maddr, miner.Methods.ProveCommitSector, collateral, big.Int(b.feeCfg.MaxCommitGasFee), enc.Bytes())
+	uid, err := b.api.MessagerSendMsg(b.mctx, from, b.maddr, miner.Methods.ProveCommitSector, collateral, big.Int(b.feeCfg().MaxCommitGasFee), enc.Bytes())
 	if err != nil {
 		return "", xerrors.Errorf("pushing message to mpool: %w", err)
 	}
@@ -487,8 +784,24 @@ func (b *CommitBatcher) AddCommit(ctx context.Context, s types.SectorInfo, in Ag
 	}
 
 	b.lk.Lock()
+	if r, ok := b.results[sn]; ok {
+		// sent in a batch restored after a restart
+		delete(b.results, sn)
+		b.lk.Unlock()
+		return r, nil
+	}
+
+	if _, ok := b.restored[sn]; ok {
+		delete(b.restored, sn)
+		if restored := b.cutoffs[sn]; !restored.IsZero() && restored.Before(cu) {
+			cu = restored
+		}
+	}
 	b.cutoffs[sn] = cu
 	b.todo[sn] = in
+	if err := b.persist(sn); err != nil {
+		log.Errorw("persisting commit batch entry", "sector", sn, "error", err)
+	}
 
 	sent := make(chan sealiface.CommitBatchRes, 1)
 	b.waiting[sn] = append(b.waiting[sn], sent)
@@ -550,6 +863,24 @@ func (b *CommitBatcher) Pending(ctx context.Context) ([]abi.SectorID, error) {
 	return res, nil
 }
 
+// Queue returns the sectors in the batch queue with their cutoffs
+func (b *CommitBatcher) Queue() (types.BatchQueue, error) {
+	cfg, err := b.getConfig()
+	if err != nil {
+		return types.BatchQueue{}, xerrors.Errorf("getting config: %w", err)
+	}
+
+	b.lk.Lock()
+	defer b.lk.Unlock()
+
+	sectors := make([]abi.SectorNumber, 0, len(b.todo))
+	for sn := range b.todo {
+		sectors = append(sectors, sn)
+	}
+
+	return batchQueue(sectors, b.cutoffs, b.restored, cfg.CommitBatchSlack), nil
+}
+
 func (b *CommitBatcher) Stop(ctx context.Context) error {
 	close(b.stop)
 
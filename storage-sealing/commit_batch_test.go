@@ -363,7 +363,7 @@ func TestCommitBatcher(t *testing.T) {
 				UpgradeIgnitionHeight: 94000,
 				ForkLengthThreshold:   policy.ChainFinality,
 				BlockDelaySecs:        30,
-			}, t0123, pcapi, as, fc, cfg, &fakeProver{})
+			}, t0123, pcapi, as, feeCfg, cfg, &fakeProver{}, nil)
 
 			var promises []promise
 
@@ -2,24 +2,51 @@ package sealing
 
 import (
 	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-state-types/abi"
 	"github.com/filecoin-project/venus-market/piece"
+
+	"github.com/filecoin-project/venus-sealer/storage-sealing/sealiface"
 	"github.com/filecoin-project/venus-sealer/types"
 )
 
+var (
+	// how often the ingester checks the config while automatic ingestion is disabled
+	DealIngestIdleInterval = time.Minute
+
+	DefaultDealIngestBatch = 50
+)
+
 func (m *Sealing) DealSector(ctx context.Context) ([]types.DealAssign, error) {
+	return m.addDeals(ctx, DefaultDealIngestBatch)
+}
+
+func (m *Sealing) DealIngestStatus() types.DealIngestStatus {
+	return m.ingester.Status()
+}
+
+// addDeals pulls up to max unpacked deals from venus-market and adds them to sectors
+func (m *Sealing) addDeals(ctx context.Context, max int) ([]types.DealAssign, error) {
 	m.startupWait.Wait()
 
-	deals, err := m.api.GetUnPackedDeals(ctx, m.maddr, &piece.GetDealSpec{MaxPiece: 50})
+	deals, err := m.api.GetUnPackedDeals(ctx, m.maddr, &piece.GetDealSpec{MaxPiece: max})
 	if err != nil {
-		return nil, err
+		return nil, xerrors.Errorf("getting unpacked deals: %w", err)
 	}
 	log.Infof("got %d deals from venus-market", len(deals))
-	//read from file
+
 	var assigned []types.DealAssign
 	for _, deal := range deals {
+		//read from file
 		r, err := piece.Read(deal.PieceStorage)
 		if err != nil {
 			log.Errorf("read piece from piece storage %v", err)
+			m.ingester.dealFailed()
 			continue
 		}
 
@@ -33,22 +60,273 @@ func (m *Sealing) DealSector(ctx context.Context) ([]types.DealAssign, error) {
 		_ = r.Close()
 		if err != nil {
 			log.Errorf("add piece to sector %v", err)
+			m.ingester.dealFailed()
 			continue
 		}
 
-		err = m.api.UpdateDealOnPacking(ctx, m.maddr, deal.DealProposal.PieceCID, deal.DealID, so.Sector, so.Offset)
-		if err != nil {
-			log.Errorf("update deal status on chain ", err)
-			//if error how to fix this problems
-			continue
-		}
-		assigned = append(assigned, types.DealAssign{
+		da := types.DealAssign{
 			DealId:   deal.DealID,
 			SectorId: so.Sector,
 			PieceCid: deal.PieceCID,
 			Offset:   so.Offset,
 			Size:     deal.PieceSize,
+		}
+		assigned = append(assigned, da)
+		m.ingester.dealAdded()
+
+		err = m.api.UpdateDealOnPacking(ctx, m.maddr, deal.DealProposal.PieceCID, deal.DealID, so.Sector, so.Offset)
+		if err != nil {
+			// the piece is in the sector already, keep telling venus-market until it knows
+			log.Errorw("reporting deal packing to venus-market, will retry", "deal", deal.DealID, "sector", so.Sector, "error", err)
+			m.ingester.reportFailed(da, err)
+		}
+	}
+
+	return assigned, nil
+}
+
+func (m *Sealing) dealCapacity(cfg sealiface.Config) string {
+	if cfg.MaxSealingSectorsForDeals > 0 && m.stats.CurSealing() >= cfg.MaxSealingSectorsForDeals {
+		return "MaxSealingSectorsForDeals reached"
+	}
+
+	if cfg.MaxWaitDealsSectors > 0 && m.stats.CurStaging() >= cfg.MaxWaitDealsSectors {
+		m.inputLk.Lock()
+		open := len(m.openSectors)
+		m.inputLk.Unlock()
+
+		// deals can still go to sectors accepting pieces
+		if open == 0 {
+			return "MaxWaitDealsSectors reached"
+		}
+	}
+
+	return ""
+}
+
+// DealIngester pulls unpacked deals from venus-market into sectors when
+// AutoDealIngest is enabled, and retries reporting deals venus-market failed
+// to accept as packed. Unreported deals are persisted in the store so the
+// reports are still retried after a restart
+type DealIngester struct {
+	m     *Sealing
+	store types.UnreportedDealStore
+
+	lk         sync.Mutex
+	status     types.DealIngestStatus
+	unreported map[abi.DealID]*types.UnreportedDeal
+
+	notify, stop, stopped chan struct{}
+}
+
+func newDealIngester(mctx context.Context, m *Sealing, store types.UnreportedDealStore) *DealIngester {
+	d := &DealIngester{
+		m:          m,
+		store:      store,
+		unreported: map[abi.DealID]*types.UnreportedDeal{},
+
+		notify:  make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+
+	if err := d.load(); err != nil {
+		log.Errorf("loading unreported deals: %+v", err)
+	}
+
+	go d.run(mctx)
+
+	return d
+}
+
+// load restores the deals which weren't reported before the sealer stopped
+func (d *DealIngester) load() error {
+	if d.store == nil {
+		return nil
+	}
+
+	deals, err := d.store.ListUnreportedDeals()
+	if err != nil {
+		return err
+	}
+
+	d.lk.Lock()
+	defer d.lk.Unlock()
+
+	for _, ud := range deals {
+		d.unreported[ud.DealId] = ud
+	}
+	if len(deals) > 0 {
+		log.Infof("restored %d deals to report to venus-market", len(deals))
+	}
+
+	return nil
+}
+
+func (d *DealIngester) run(ctx context.Context) {
+	m := d.m
+	m.startupWait.Wait()
+
+	for {
+		wait := d.ingest(ctx)
+
+		select {
+		case <-d.stop:
+			close(d.stopped)
+			return
+		case <-d.notify:
+		case <-time.After(wait):
+		}
+	}
+}
+
+// ingest runs one round of ingestion, returns how long to wait for the next one
+func (d *DealIngester) ingest(ctx context.Context) time.Duration {
+	d.retryReports(ctx)
+
+	cfg, err := d.m.getConfig()
+	if err != nil {
+		d.update(func(st *types.DealIngestStatus) {
+			st.LastError = xerrors.Errorf("getting sealing config: %w", err).Error()
+		})
+		return DealIngestIdleInterval
+	}
+
+	if !cfg.AutoDealIngest {
+		d.update(func(st *types.DealIngestStatus) {
+			st.Enabled = false
+			st.Paused = ""
 		})
+		return DealIngestIdleInterval
+	}
+
+	wait := cfg.DealIngestInterval
+	if wait <= 0 {
+		wait = DealIngestIdleInterval
+	}
+	batch := cfg.DealIngestBatch
+	if batch <= 0 {
+		batch = DefaultDealIngestBatch
+	}
+
+	paused := d.m.dealCapacity(cfg)
+	d.update(func(st *types.DealIngestStatus) {
+		st.Enabled = true
+		st.Paused = paused
+	})
+	if paused != "" {
+		return wait
+	}
+
+	_, err = d.m.addDeals(ctx, batch)
+	d.update(func(st *types.DealIngestStatus) {
+		st.LastPoll = time.Now()
+		st.LastError = ""
+		if err != nil {
+			st.LastError = err.Error()
+		}
+	})
+
+	return wait
+}
+
+func (d *DealIngester) retryReports(ctx context.Context) {
+	d.lk.Lock()
+	pending := make([]types.UnreportedDeal, 0, len(d.unreported))
+	for _, ud := range d.unreported {
+		pending = append(pending, *ud)
+	}
+	d.lk.Unlock()
+
+	for _, ud := range pending {
+		err := d.m.api.UpdateDealOnPacking(ctx, d.m.maddr, ud.PieceCid, ud.DealId, ud.SectorId, ud.Offset)
+		if err != nil {
+			log.Warnw("reporting deal packing to venus-market", "deal", ud.DealId, "sector", ud.SectorId, "attempts", ud.Attempts+1, "error", err)
+			d.reportFailed(ud.DealAssign, err)
+			continue
+		}
+
+		log.Infow("reported deal packing to venus-market", "deal", ud.DealId, "sector", ud.SectorId)
+		d.lk.Lock()
+		delete(d.unreported, ud.DealId)
+		if d.store != nil {
+			if err := d.store.DeleteUnreportedDeal(ud.DealId); err != nil {
+				log.Errorw("deleting reported deal", "deal", ud.DealId, "error", err)
+			}
+		}
+		d.lk.Unlock()
+	}
+}
+
+func (d *DealIngester) reportFailed(da types.DealAssign, err error) {
+	d.lk.Lock()
+	defer d.lk.Unlock()
+
+	ud, ok := d.unreported[da.DealId]
+	if !ok {
+		ud = &types.UnreportedDeal{DealAssign: da}
+		d.unreported[da.DealId] = ud
+	}
+	ud.Attempts++
+	ud.LastError = err.Error()
+
+	if d.store != nil {
+		if err := d.store.SaveUnreportedDeal(ud); err != nil {
+			log.Errorw("persisting unreported deal", "deal", da.DealId, "error", err)
+		}
+	}
+}
+
+func (d *DealIngester) dealAdded() {
+	d.update(func(st *types.DealIngestStatus) {
+		st.Ingested++
+	})
+}
+
+func (d *DealIngester) dealFailed() {
+	d.update(func(st *types.DealIngestStatus) {
+		st.Failed++
+	})
+}
+
+func (d *DealIngester) update(cb func(*types.DealIngestStatus)) {
+	d.lk.Lock()
+	defer d.lk.Unlock()
+
+	cb(&d.status)
+}
+
+// Poke runs an ingestion round now
+func (d *DealIngester) Poke() {
+	select {
+	case d.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (d *DealIngester) Status() types.DealIngestStatus {
+	d.lk.Lock()
+	defer d.lk.Unlock()
+
+	st := d.status
+	st.Unreported = make([]types.UnreportedDeal, 0, len(d.unreported))
+	for _, ud := range d.unreported {
+		st.Unreported = append(st.Unreported, *ud)
+	}
+	sort.Slice(st.Unreported, func(i, j int) bool {
+		return st.Unreported[i].DealId < st.Unreported[j].DealId
+	})
+
+	return st
+}
+
+func (d *DealIngester) Stop(ctx context.Context) error {
+	close(d.stop)
+
+	select {
+	case <-d.stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
 	}
-	return assigned, err
 }
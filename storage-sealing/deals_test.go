@@ -0,0 +1,88 @@
+package sealing
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/venus-sealer/storage-sealing/sealiface"
+	"github.com/filecoin-project/venus-sealer/types"
+)
+
+func TestDealCapacity(t *testing.T) {
+	m := &Sealing{
+		openSectors: map[abi.SectorID]*openSector{},
+		stats: types.SectorStats{
+			BySector: map[abi.SectorID]types.StatSectorState{},
+		},
+	}
+	cfg := sealiface.Config{MaxWaitDealsSectors: 1, MaxSealingSectorsForDeals: 2}
+
+	require.Equal(t, "", m.dealCapacity(cfg))
+
+	m.stats.UpdateSector(cfg, abi.SectorID{Number: 1}, types.WaitDeals)
+	require.Equal(t, "MaxWaitDealsSectors reached", m.dealCapacity(cfg))
+
+	// the waiting sector can still take deals
+	m.openSectors[abi.SectorID{Number: 1}] = &openSector{}
+	require.Equal(t, "", m.dealCapacity(cfg))
+
+	m.stats.UpdateSector(cfg, abi.SectorID{Number: 2}, types.PreCommit1)
+	require.Equal(t, "MaxSealingSectorsForDeals reached", m.dealCapacity(cfg))
+}
+
+func TestDealIngesterUnreported(t *testing.T) {
+	d := &DealIngester{unreported: map[abi.DealID]*types.UnreportedDeal{}}
+
+	d.reportFailed(types.DealAssign{DealId: 2, SectorId: 10}, xerrors.New("market down"))
+	d.reportFailed(types.DealAssign{DealId: 1, SectorId: 10}, xerrors.New("market down"))
+	d.reportFailed(types.DealAssign{DealId: 2, SectorId: 10}, xerrors.New("still down"))
+
+	st := d.Status()
+	require.Len(t, st.Unreported, 2)
+	require.Equal(t, abi.DealID(1), st.Unreported[0].DealId)
+	require.Equal(t, 2, st.Unreported[1].Attempts)
+	require.Equal(t, "still down", st.Unreported[1].LastError)
+}
+
+type memUnreportedDeals map[abi.DealID]types.UnreportedDeal
+
+func (m memUnreportedDeals) SaveUnreportedDeal(deal *types.UnreportedDeal) error {
+	m[deal.DealId] = *deal
+	return nil
+}
+
+func (m memUnreportedDeals) DeleteUnreportedDeal(deal abi.DealID) error {
+	delete(m, deal)
+	return nil
+}
+
+func (m memUnreportedDeals) ListUnreportedDeals() ([]*types.UnreportedDeal, error) {
+	out := make([]*types.UnreportedDeal, 0, len(m))
+	for _, ud := range m {
+		ud := ud
+		out = append(out, &ud)
+	}
+	return out, nil
+}
+
+func TestDealIngesterReload(t *testing.T) {
+	store := memUnreportedDeals{}
+	d := &DealIngester{store: store, unreported: map[abi.DealID]*types.UnreportedDeal{}}
+
+	d.reportFailed(types.DealAssign{DealId: 1, SectorId: 10}, xerrors.New("market down"))
+	d.reportFailed(types.DealAssign{DealId: 1, SectorId: 10}, xerrors.New("still down"))
+	require.Equal(t, 2, store[1].Attempts)
+
+	// a restarted ingester keeps retrying the report
+	d = &DealIngester{store: store, unreported: map[abi.DealID]*types.UnreportedDeal{}}
+	require.NoError(t, d.load())
+
+	st := d.Status()
+	require.Len(t, st.Unreported, 1)
+	require.Equal(t, 2, st.Unreported[0].Attempts)
+	require.Equal(t, "still down", st.Unreported[0].LastError)
+}
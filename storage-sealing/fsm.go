@@ -144,10 +144,16 @@ var fsmPlanners = map[types.SectorState]func(events []statemachine.Event, state
 	),
 	types.SealPreCommit1Failed: planOne(
 		on(SectorRetrySealPreCommit1{}, types.PreCommit1),
+		on(SectorRestartPreCommit1{}, types.PreCommit1),
+		on(SectorRestartGetTicket{}, types.GetTicket),
+		apply(SectorRecoveryEscalated{}),
 	),
 	types.SealPreCommit2Failed: planOne(
 		on(SectorRetrySealPreCommit1{}, types.PreCommit1),
 		on(SectorRetrySealPreCommit2{}, types.PreCommit2),
+		on(SectorRestartPreCommit1{}, types.PreCommit1),
+		on(SectorRestartGetTicket{}, types.GetTicket),
+		apply(SectorRecoveryEscalated{}),
 	),
 	types.PreCommitFailed: planOne(
 		on(SectorRetryPreCommit{}, types.PreCommitting),
@@ -157,10 +163,16 @@ var fsmPlanners = map[types.SectorState]func(events []statemachine.Event, state
 		on(SectorPreCommitLanded{}, types.WaitSeed),
 		on(SectorDealsExpired{}, types.DealsExpired),
 		on(SectorInvalidDealIDs{}, types.RecoverDealIDs),
+		on(SectorRestartPreCommit1{}, types.PreCommit1),
+		on(SectorRestartGetTicket{}, types.GetTicket),
+		apply(SectorRecoveryEscalated{}),
 	),
 	types.ComputeProofFailed: planOne(
 		on(SectorRetryComputeProof{}, types.Committing),
 		on(SectorSealPreCommit1Failed{}, types.SealPreCommit1Failed),
+		on(SectorRestartPreCommit1{}, types.PreCommit1),
+		on(SectorRestartGetTicket{}, types.GetTicket),
+		apply(SectorRecoveryEscalated{}),
 	),
 	types.CommitFinalizeFailed: planOne(
 		on(SectorRetryFinalize{}, types.CommitFinalize),
@@ -178,6 +190,9 @@ var fsmPlanners = map[types.SectorState]func(events []statemachine.Event, state
 		on(SectorDealsExpired{}, types.DealsExpired),
 		on(SectorInvalidDealIDs{}, types.RecoverDealIDs),
 		on(SectorTicketExpired{}, types.Removing),
+		on(SectorRestartPreCommit1{}, types.PreCommit1),
+		on(SectorRestartGetTicket{}, types.GetTicket),
+		apply(SectorRecoveryEscalated{}),
 	),
 	types.FinalizeFailed: planOne(
 		on(SectorRetryFinalize{}, types.FinalizeSector),
@@ -313,10 +328,14 @@ func (m *Sealing) plan(events []statemachine.Event, state *types.SectorInfo) (fu
 		}
 	}
 
+	prev := state.State
 	processed, err := p(events, state)
 	if err != nil {
 		return nil, 0, xerrors.Errorf("running planner for state %s failed: %w", state.State, err)
 	}
+	if state.State != prev {
+		countRecoveryAttempt(state)
+	}
 
 	/////
 	// Now decide what to do next
@@ -509,6 +528,11 @@ func (m *Sealing) onUpdateSector(ctx context.Context, state *types.SectorInfo) e
 				log.Errorf("%+v", err)
 			}
 		}()
+
+		if m.ingester != nil && cfg.AutoDealIngest {
+			// there may be room for more deals now
+			m.ingester.Poke()
+		}
 	}
 
 	return nil
@@ -559,6 +583,7 @@ func (m *Sealing) restartSectors(ctx context.Context) error {
 	if err != nil {
 		log.Errorf("loading sector list: %+v", err)
 	}
+	m.pruneBatchers(trackedSectors, err == nil)
 
 	for _, sector := range trackedSectors {
 		if err := m.sectors.Send(uint64(sector.SectorNumber), SectorRestart{}); err != nil {
@@ -571,6 +596,46 @@ func (m *Sealing) restartSectors(ctx context.Context) error {
 	return nil
 }
 
+// RestoreSector starts the state machine of a sector whose info was written
+// to the sector store while the sealer was running, e.g. imported from a
+// backup, it continues in the saved state like after a restart
+func (m *Sealing) RestoreSector(ctx context.Context, sid abi.SectorNumber) error {
+	m.startupWait.Wait()
+
+	return m.sectors.Send(uint64(sid), SectorRestart{})
+}
+
+// pruneBatchers drops the restored batch entries of sectors which aren't in the
+// state adding them to the batcher anymore, the batchers don't send anything
+// before. When the sectors couldn't be listed no restored entry is sent.
+func (m *Sealing) pruneBatchers(sectors []types.SectorInfo, listed bool) {
+	if !listed {
+		m.precommiter.prune(nil)
+		m.commiter.prune(nil)
+		m.terminator.prune(nil)
+		return
+	}
+
+	precommit := map[abi.SectorNumber]struct{}{}
+	commit := map[abi.SectorNumber]struct{}{}
+	terminate := map[abi.SectorNumber]struct{}{}
+
+	for _, sector := range sectors {
+		switch sector.State {
+		case types.SubmitPreCommitBatch:
+			precommit[sector.SectorNumber] = struct{}{}
+		case types.SubmitCommitAggregate:
+			commit[sector.SectorNumber] = struct{}{}
+		case types.Terminating:
+			terminate[sector.SectorNumber] = struct{}{}
+		}
+	}
+
+	m.precommiter.prune(precommit)
+	m.commiter.prune(commit)
+	m.terminator.prune(terminate)
+}
+
 func (m *Sealing) ForceSectorState(ctx context.Context, id abi.SectorNumber, state types.SectorState) error {
 	m.startupWait.Wait()
 	return m.sectors.Send(id, SectorForceState{state})
@@ -635,7 +700,7 @@ func planOne(ts ...func() (mut mutator, next func(*types.SectorInfo) (more bool,
 					continue
 				}
 
-				if err, iserr := event.User.(error); iserr {
+				if err, iserr := event.User.(error); iserr && !isProbeEvent(event.User) {
 					log.Warnf("sector %d got error event %T: %+v", state.SectorNumber, event.User, err)
 				}
 
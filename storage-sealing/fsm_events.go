@@ -54,6 +54,8 @@ type SectorForceState struct {
 
 func (evt SectorForceState) applyGlobal(state *types.SectorInfo) bool {
 	state.State = evt.State
+	// the sector was recovered by hand, give the recovery playbooks a fresh start
+	state.RecoveryAttempts = nil
 	return true
 }
 
@@ -353,6 +355,35 @@ func (evt SectorUpdateDealIDs) apply(state *types.SectorInfo) {
 	}
 }
 
+// Recovery playbooks
+
+type SectorRestartPreCommit1 struct{}
+
+func (evt SectorRestartPreCommit1) apply(state *types.SectorInfo) {
+	state.PreCommit2Fails = 0
+	state.InvalidProofs = 0
+}
+
+type SectorRestartGetTicket struct{}
+
+func (evt SectorRestartGetTicket) apply(state *types.SectorInfo) {
+	state.TicketValue = nil
+	state.TicketEpoch = 0
+	state.PreCommit2Fails = 0
+	state.InvalidProofs = 0
+}
+
+// SectorRecoveryEscalated is sent when the recovery playbook of the failure
+// state is used up, the sector waits for manual recovery
+type SectorRecoveryEscalated struct {
+	Cause    string
+	Attempts uint64
+}
+
+func (evt SectorRecoveryEscalated) apply(state *types.SectorInfo) {
+	state.RecoveryEscalated = true
+}
+
 // Faults
 
 type SectorFaulty struct{}
@@ -0,0 +1,215 @@
+package sealing
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+
+	"golang.org/x/xerrors"
+
+	statemachine "github.com/filecoin-project/go-statemachine"
+
+	"github.com/filecoin-project/venus-sealer/types"
+)
+
+var errFSMProbe = xerrors.New("fsm probe")
+
+// fsmEvents are all events handled by the planners, TestFSMEventList checks
+// that it lists every event declared in fsm_events.go
+var fsmEvents = []mutator{
+	SectorStart{},
+	SectorStartCC{},
+	SectorAddPiece{},
+	SectorPieceAdded{},
+	SectorAddPieceFailed{errFSMProbe},
+	SectorRetryWaitDeals{},
+	SectorStartPacking{},
+	SectorPacked{},
+	SectorTicket{},
+	SectorOldTicket{},
+	SectorPreCommit1{},
+	SectorPreCommit2{},
+	SectorPreCommitBatch{},
+	SectorPreCommitBatchSent{},
+	SectorPreCommitLanded{},
+	SectorSealPreCommit1Failed{errFSMProbe},
+	SectorSealPreCommit2Failed{errFSMProbe},
+	SectorChainPreCommitFailed{errFSMProbe},
+	SectorPreCommitted{},
+	SectorSeedReady{},
+	SectorComputeProofFailed{errFSMProbe},
+	SectorCommitFailed{errFSMProbe},
+	SectorRetrySubmitCommit{},
+	SectorDealsExpired{errFSMProbe},
+	SectorTicketExpired{errFSMProbe},
+	SectorCommitted{},
+	SectorProofReady{},
+	SectorSubmitCommitAggregate{},
+	SectorCommitSubmitted{},
+	SectorCommitAggregateSent{},
+	SectorProving{},
+	SectorFinalized{},
+	SectorRetryFinalize{},
+	SectorFinalizeFailed{errFSMProbe},
+	SectorRetrySealPreCommit1{},
+	SectorRetrySealPreCommit2{},
+	SectorRetryPreCommit{},
+	SectorRetryWaitSeed{},
+	SectorRetryPreCommitWait{},
+	SectorRetryComputeProof{},
+	SectorRetryInvalidProof{},
+	SectorRetryCommitWait{},
+	SectorInvalidDealIDs{},
+	SectorUpdateDealIDs{},
+	SectorRestartPreCommit1{},
+	SectorRestartGetTicket{},
+	SectorRecoveryEscalated{},
+	SectorFaulty{},
+	SectorFaultReported{},
+	SectorTerminating{},
+	SectorTerminated{},
+	SectorTerminateFailed{errFSMProbe},
+	SectorRemoved{},
+	SectorRemoveFailed{errFSMProbe},
+}
+
+// fsmGlobalEvents are the events accepted in every state
+var fsmGlobalEvents = []globalMutator{
+	SectorRestart{},
+	SectorFatalError{errFSMProbe},
+	SectorForceState{},
+	SectorTerminate{},
+	SectorRemove{},
+}
+
+// returns to the state in SectorInfo.Return, see onReturning
+const fsmReturnState = types.SectorState("<return>")
+
+func eventName(evt interface{}) string {
+	return reflect.TypeOf(evt).Name()
+}
+
+// FSMGraph returns the transitions of the sector state machine, found by
+// sending every event to every planner. Events which don't change the state
+// aren't included, transitions possible in every state start at FSMAnyState.
+func FSMGraph() []types.FSMEdge {
+	var edges []types.FSMEdge
+
+	for from, planner := range fsmPlanners {
+		for _, evt := range fsmEvents {
+			to, ok := probePlanner(planner, from, evt)
+			if !ok || to == from {
+				continue
+			}
+			edges = append(edges, types.FSMEdge{From: from, To: to, Event: eventName(evt)})
+		}
+	}
+
+	// states entered through onReturning go back to where they were entered from
+	var out []types.FSMEdge
+	for _, e := range edges {
+		if e.To != fsmReturnState {
+			out = append(out, e)
+			continue
+		}
+		for _, in := range edges {
+			if in.To == e.From && in.From != e.From {
+				out = append(out, types.FSMEdge{From: e.From, To: in.From, Event: e.Event})
+			}
+		}
+	}
+
+	for _, evt := range fsmGlobalEvents {
+		state := &types.SectorInfo{State: fsmReturnState}
+		evt.applyGlobal(state)
+		if state.State != fsmReturnState && state.State != "" {
+			out = append(out, types.FSMEdge{From: types.FSMAnyState, To: state.State, Event: eventName(evt)})
+		}
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].From != out[j].From {
+			return out[i].From < out[j].From
+		}
+		if out[i].To != out[j].To {
+			return out[i].To < out[j].To
+		}
+		return out[i].Event < out[j].Event
+	})
+
+	return dedupEdges(out)
+}
+
+// probePlanner returns the state the planner moves a sector in the state to on the event
+func probePlanner(planner func([]statemachine.Event, *types.SectorInfo) (uint64, error), from types.SectorState, evt mutator) (to types.SectorState, ok bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			ok = false
+		}
+	}()
+
+	state := &types.SectorInfo{State: from, Return: types.ReturnState(fsmReturnState)}
+	if _, err := planner([]statemachine.Event{{User: evt}}, state); err != nil {
+		return "", false
+	}
+	return state.State, true
+}
+
+func dedupEdges(edges []types.FSMEdge) []types.FSMEdge {
+	var out []types.FSMEdge
+	for i, e := range edges {
+		if i > 0 && e == edges[i-1] {
+			continue
+		}
+		out = append(out, e)
+	}
+	return out
+}
+
+// WriteFSMGraph renders the transitions in the dot or mermaid format
+func WriteFSMGraph(w io.Writer, edges []types.FSMEdge, format string) error {
+	switch format {
+	case "dot":
+		if _, err := fmt.Fprintln(w, "digraph sectors {"); err != nil {
+			return err
+		}
+		for _, e := range edges {
+			if _, err := fmt.Fprintf(w, "  %q -> %q [label=%q];\n", graphState(e.From), graphState(e.To), e.Event); err != nil {
+				return err
+			}
+		}
+		_, err := fmt.Fprintln(w, "}")
+		return err
+	case "mermaid":
+		if _, err := fmt.Fprintln(w, "stateDiagram-v2"); err != nil {
+			return err
+		}
+		for _, e := range edges {
+			if _, err := fmt.Fprintf(w, "  %s --> %s: %s\n", mermaidState(e.From), mermaidState(e.To), e.Event); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return xerrors.Errorf("unknown graph format %s, expected dot or mermaid", format)
+	}
+}
+
+func graphState(s types.SectorState) string {
+	switch s {
+	case types.UndefinedSectorState:
+		return "Start"
+	case types.FSMAnyState:
+		return "AnyState"
+	}
+	return string(s)
+}
+
+func mermaidState(s types.SectorState) string {
+	if s == types.UndefinedSectorState {
+		return "[*]"
+	}
+	return strings.ReplaceAll(graphState(s), " ", "_")
+}
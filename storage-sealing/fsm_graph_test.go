@@ -0,0 +1,87 @@
+package sealing
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/venus-sealer/types"
+)
+
+func TestFSMGraph(t *testing.T) {
+	edges := FSMGraph()
+
+	for _, e := range []types.FSMEdge{
+		{From: types.UndefinedSectorState, To: types.WaitDeals, Event: "SectorStart"},
+		{From: types.PreCommit1, To: types.PreCommit2, Event: "SectorPreCommit1"},
+		{From: types.RecoverDealIDs, To: types.PreCommit1, Event: "SectorUpdateDealIDs"},
+		{From: types.FSMAnyState, To: types.Removing, Event: "SectorRemove"},
+	} {
+		require.Contains(t, edges, e)
+	}
+
+	for _, e := range edges {
+		require.NotEqual(t, fsmReturnState, e.To)
+		require.NotEqual(t, e.From, e.To)
+	}
+}
+
+func TestWriteFSMGraph(t *testing.T) {
+	edges := []types.FSMEdge{
+		{From: types.UndefinedSectorState, To: types.WaitDeals, Event: "SectorStart"},
+		{From: types.FSMAnyState, To: types.Removing, Event: "SectorRemove"},
+	}
+
+	var dot bytes.Buffer
+	require.NoError(t, WriteFSMGraph(&dot, edges, "dot"))
+	require.Equal(t, `digraph sectors {
+  "Start" -> "WaitDeals" [label="SectorStart"];
+  "AnyState" -> "Removing" [label="SectorRemove"];
+}
+`, dot.String())
+
+	var mermaid bytes.Buffer
+	require.NoError(t, WriteFSMGraph(&mermaid, edges, "mermaid"))
+	require.Equal(t, `stateDiagram-v2
+  [*] --> WaitDeals: SectorStart
+  AnyState --> Removing: SectorRemove
+`, mermaid.String())
+
+	require.Error(t, WriteFSMGraph(&dot, edges, "svg"))
+}
+
+// TestFSMEventList makes sure the graph isn't missing events added later
+func TestFSMEventList(t *testing.T) {
+	f, err := parser.ParseFile(token.NewFileSet(), "fsm_events.go", nil, 0)
+	require.NoError(t, err)
+
+	listed := map[string]bool{}
+	for _, evt := range fsmEvents {
+		listed[eventName(evt)] = true
+	}
+	for _, evt := range fsmGlobalEvents {
+		listed[eventName(evt)] = true
+	}
+
+	for _, decl := range f.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Recv == nil || (fn.Name.Name != "apply" && fn.Name.Name != "applyGlobal") {
+			continue
+		}
+
+		recv := fn.Recv.List[0].Type
+		if star, ok := recv.(*ast.StarExpr); ok {
+			recv = star.X
+		}
+		name := recv.(*ast.Ident).Name
+		if !strings.HasPrefix(name, "Sector") {
+			continue
+		}
+		require.True(t, listed[name], "%s is missing from fsmEvents", name)
+	}
+}
@@ -0,0 +1,157 @@
+package sealing
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	statemachine "github.com/filecoin-project/go-statemachine"
+
+	"github.com/filecoin-project/venus-sealer/types"
+)
+
+// fsmEventsByKind maps the Kind of logged events, see logEvents, to the event
+var fsmEventsByKind = func() map[string]interface{} {
+	out := map[string]interface{}{}
+	for _, evt := range fsmEvents {
+		out[fmt.Sprintf("event;%T", evt)] = evt
+	}
+	for _, evt := range fsmGlobalEvents {
+		out[fmt.Sprintf("event;%T", evt)] = evt
+	}
+	return out
+}()
+
+// isProbeEvent returns whether the event was sent by FSMGraph or the history
+// replay, errors of these events are not logged
+func isProbeEvent(evt interface{}) bool {
+	e, ok := evt.(xerrors.Formatter)
+	return ok && e.FormatError(nil) == errFSMProbe
+}
+
+// SectorHistory returns the states the sector went through, rebuilt by
+// replaying its event log through the planners
+func (m *Sealing) SectorHistory(sid abi.SectorNumber) (types.SectorHistory, error) {
+	info, err := m.GetSectorInfo(sid)
+	if err != nil {
+		return types.SectorHistory{}, xerrors.Errorf("getting sector info: %w", err)
+	}
+
+	logs, err := m.logService.List(sid)
+	if err != nil {
+		return types.SectorHistory{}, xerrors.Errorf("getting sector log: %w", err)
+	}
+
+	return replaySectorHistory(sid, logs, info.State, time.Now()), nil
+}
+
+func replaySectorHistory(sid abi.SectorNumber, logs []*types.Log, current types.SectorState, now time.Time) types.SectorHistory {
+	out := types.SectorHistory{Sector: sid}
+
+	sort.SliceStable(logs, func(i, j int) bool {
+		return logs[i].Timestamp < logs[j].Timestamp
+	})
+
+	leave := func(at time.Time, event string) {
+		if len(out.Spans) == 0 {
+			return
+		}
+		last := &out.Spans[len(out.Spans)-1]
+		last.Left = at
+		last.Duration = at.Sub(last.Entered)
+		last.Event = event
+	}
+	enter := func(state types.SectorState, at time.Time) {
+		out.Spans = append(out.Spans, types.SectorStateSpan{State: state, Entered: at})
+	}
+
+	state := &types.SectorInfo{SectorNumber: sid}
+	var lastAt time.Time
+	for _, l := range logs {
+		if !strings.HasPrefix(l.Kind, "event;") {
+			continue
+		}
+
+		evt, ok := decodeLoggedEvent(l)
+		if !ok {
+			out.Incomplete = true
+			continue
+		}
+
+		at := time.Unix(int64(l.Timestamp), 0)
+		lastAt = at
+
+		before := *state
+		if !replayEvent(state, evt) {
+			*state = before
+			out.Incomplete = true
+			continue
+		}
+
+		if state.State != before.State {
+			leave(at, eventName(evt))
+			enter(state.State, at)
+		}
+	}
+
+	if state.State != current {
+		out.Incomplete = true
+		leave(lastAt, "")
+		enter(current, lastAt)
+	}
+
+	if len(out.Spans) > 0 {
+		last := &out.Spans[len(out.Spans)-1]
+		last.Duration = now.Sub(last.Entered)
+	}
+
+	return out
+}
+
+func decodeLoggedEvent(l *types.Log) (interface{}, bool) {
+	proto, ok := fsmEventsByKind[l.Kind]
+	if !ok {
+		return nil, false
+	}
+
+	// start from the prototype to keep the probe error of error events
+	v := reflect.New(reflect.TypeOf(proto))
+	v.Elem().Set(reflect.ValueOf(proto))
+
+	var logged struct {
+		User json.RawMessage
+	}
+	if err := json.Unmarshal([]byte(l.Message), &logged); err == nil && len(logged.User) > 0 {
+		// truncated messages don't decode, the event is replayed without its fields then
+		_ = json.Unmarshal(logged.User, v.Interface())
+	}
+
+	return v.Elem().Interface(), true
+}
+
+// replayEvent applies the event like plan does, it returns false if the
+// planner of the current state doesn't accept it
+func replayEvent(state *types.SectorInfo, evt interface{}) (ok bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			ok = false
+		}
+	}()
+
+	p := fsmPlanners[state.State]
+	if p == nil {
+		if _, global := evt.(globalMutator); !global {
+			return false
+		}
+		p = planOne()
+	}
+
+	_, err := p([]statemachine.Event{{User: evt}}, state)
+	return err == nil
+}
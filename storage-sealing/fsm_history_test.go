@@ -0,0 +1,57 @@
+package sealing
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	statemachine "github.com/filecoin-project/go-statemachine"
+
+	"github.com/filecoin-project/venus-sealer/types"
+)
+
+func eventLog(t *testing.T, at uint64, evt interface{}) *types.Log {
+	msg, err := json.Marshal(statemachine.Event{User: evt})
+	require.NoError(t, err)
+	return &types.Log{
+		SectorNumber: 1,
+		Timestamp:    at,
+		Message:      string(msg),
+		Kind:         fmt.Sprintf("event;%T", evt),
+	}
+}
+
+func TestReplaySectorHistory(t *testing.T) {
+	logs := []*types.Log{
+		eventLog(t, 130, SectorPacked{}),
+		eventLog(t, 100, SectorStart{ID: 1}),
+		{SectorNumber: 1, Timestamp: 110, Kind: "seal_failed", Message: "not an event"},
+		eventLog(t, 120, SectorStartPacking{}),
+	}
+
+	h := replaySectorHistory(1, logs, types.GetTicket, time.Unix(200, 0))
+	require.False(t, h.Incomplete)
+	require.Equal(t, []types.SectorStateSpan{
+		{State: types.WaitDeals, Entered: time.Unix(100, 0), Left: time.Unix(120, 0), Duration: 20 * time.Second, Event: "SectorStartPacking"},
+		{State: types.Packing, Entered: time.Unix(120, 0), Left: time.Unix(130, 0), Duration: 10 * time.Second, Event: "SectorPacked"},
+		{State: types.GetTicket, Entered: time.Unix(130, 0), Duration: 70 * time.Second},
+	}, h.Spans)
+}
+
+func TestReplaySectorHistoryIncomplete(t *testing.T) {
+	logs := []*types.Log{
+		eventLog(t, 100, SectorStart{ID: 1}),
+		eventLog(t, 120, SectorStartPacking{}),
+	}
+
+	// the log is missing SectorPacked and everything after it
+	h := replaySectorHistory(1, logs, types.PreCommit1, time.Unix(200, 0))
+	require.True(t, h.Incomplete)
+	require.Len(t, h.Spans, 3)
+	require.Equal(t, types.Packing, h.Spans[1].State)
+	require.Equal(t, types.PreCommit1, h.Spans[2].State)
+	require.Equal(t, 80*time.Second, h.Spans[2].Duration)
+}
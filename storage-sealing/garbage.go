@@ -100,17 +100,17 @@ func (m *Sealing) RedoSector(ctx context.Context, rsi storiface.SectorRedoParams
 		}
 
 		log.Infof("sector %d sealPaths: %v", si.SectorNumber, paths)
-		err = os.MkdirAll(filepath.Join(rsi.SealPath, storiface.FTSealed.String()), 0755)
+		err = os.MkdirAll(filepath.Dir(paths.Sealed), 0755)
 		if err != nil {
-			log.Errorf("sector %d mkdir %s err: %s", si.SectorNumber, filepath.Join(rsi.SealPath, storiface.FTSealed.String()), err)
+			log.Errorf("sector %d mkdir %s err: %s", si.SectorNumber, filepath.Dir(paths.Sealed), err)
 		}
-		err = os.MkdirAll(filepath.Join(rsi.SealPath, storiface.FTCache.String()), 0755)
+		err = os.MkdirAll(filepath.Dir(paths.Cache), 0755)
 		if err != nil {
-			log.Errorf("sector %d mkdir %s err: %s", si.SectorNumber, filepath.Join(rsi.SealPath, storiface.FTCache.String()), err)
+			log.Errorf("sector %d mkdir %s err: %s", si.SectorNumber, filepath.Dir(paths.Cache), err)
 		}
-		err = os.MkdirAll(filepath.Join(rsi.SealPath, storiface.FTUnsealed.String()), 0755)
+		err = os.MkdirAll(filepath.Dir(paths.Unsealed), 0755)
 		if err != nil {
-			log.Errorf("sector %d mkdir %s err: %s", si.SectorNumber, filepath.Join(rsi.SealPath, storiface.FTUnsealed.String()), err)
+			log.Errorf("sector %d mkdir %s err: %s", si.SectorNumber, filepath.Dir(paths.Unsealed), err)
 		}
 
 		uf, err := os.OpenFile(paths.Unsealed, os.O_RDWR|os.O_CREATE, 0644) // nolint:gosec
@@ -231,13 +231,13 @@ func (m *Sealing) RedoSector(ctx context.Context, rsi storiface.SectorRedoParams
 			Cache: rsi.SectorStorePath(sid, storiface.FTCache),
 		}
 
-		err = os.MkdirAll(filepath.Join(rsi.StorePath, storiface.FTSealed.String()), 0755)
+		err = os.MkdirAll(filepath.Dir(storePaths.Sealed), 0755)
 		if err != nil {
-			log.Errorf("sector %d mkdir %s err: %s", si.SectorNumber, filepath.Join(rsi.StorePath, storiface.FTSealed.String()), err)
+			log.Errorf("sector %d mkdir %s err: %s", si.SectorNumber, filepath.Dir(storePaths.Sealed), err)
 		}
-		err = os.MkdirAll(filepath.Join(rsi.StorePath, storiface.FTCache.String()), 0755)
+		err = os.MkdirAll(filepath.Dir(storePaths.Cache), 0755)
 		if err != nil {
-			log.Errorf("sector %d mkdir %s err: %s", si.SectorNumber, filepath.Join(rsi.StorePath, storiface.FTCache.String()), err)
+			log.Errorf("sector %d mkdir %s err: %s", si.SectorNumber, filepath.Dir(storePaths.Cache), err)
 		}
 
 		log.Infof("sector %d storePaths: %v", si.SectorNumber, storePaths)
@@ -52,15 +52,21 @@ func (m *Sealing) handleWaitDeals(ctx statemachine.Context, sector types.SectorI
 	}
 
 	if _, has := m.openSectors[sid]; !has {
-		m.openSectors[sid] = &openSector{
+		open := &openSector{
 			used: used,
 			maybeAccept: func(cid cid.Cid) error {
-				// todo check deal start deadline (configurable)
+				// deal start deadlines are checked by the packing planner, see PlanDealPacking
 				m.assignedPieces[sid] = append(m.assignedPieces[sid], cid)
 
 				return ctx.Send(SectorAddPiece{})
 			},
 		}
+		for _, piece := range sector.Pieces {
+			if piece.DealInfo != nil {
+				open.addDeal(piece.DealInfo.DealSchedule.EndEpoch)
+			}
+		}
+		m.openSectors[sid] = open
 	}
 
 	go func() {
@@ -112,9 +118,19 @@ func (m *Sealing) maybeStartSealing(ctx statemachine.Context, sector types.Secto
 			return false, xerrors.Errorf("getting storage config: %w", err)
 		}
 
-		// todo check deal age, start sealing if any deal has less than X (configurable) to start deadline
 		sealTime := time.Unix(sector.CreationTime, 0).Add(cfg.WaitDealsDelay)
 
+		if cfg.PlanDealPacking {
+			// start sealing early enough for the deal starting first
+			latest, ok, err := m.latestSealStart(ctx.Context(), sector, cfg, now)
+			if err != nil {
+				return false, xerrors.Errorf("getting deal start deadline: %w", err)
+			}
+			if ok && latest.Before(sealTime) {
+				sealTime = latest
+			}
+		}
+
 		if now.After(sealTime) {
 			log.Infow("starting to seal deal sector", "sector", sector.SectorNumber, "trigger", "wait-timeout")
 			return true, ctx.Send(SectorStartPacking{})
@@ -132,6 +148,33 @@ func (m *Sealing) maybeStartSealing(ctx statemachine.Context, sector types.Secto
 	return false, nil
 }
 
+// latestSealStart returns the time the sector has to start sealing by to be
+// sealed before the earliest start epoch of its deals
+func (m *Sealing) latestSealStart(ctx context.Context, sector types.SectorInfo, cfg sealiface.Config, now time.Time) (time.Time, bool, error) {
+	var minStart abi.ChainEpoch
+	var found bool
+	for _, piece := range sector.Pieces {
+		if piece.DealInfo == nil {
+			continue
+		}
+		if !found || piece.DealInfo.DealSchedule.StartEpoch < minStart {
+			minStart = piece.DealInfo.DealSchedule.StartEpoch
+			found = true
+		}
+	}
+	if !found {
+		return time.Time{}, false, nil
+	}
+
+	_, head, err := m.api.ChainHead(ctx)
+	if err != nil {
+		return time.Time{}, false, xerrors.Errorf("getting chain head: %w", err)
+	}
+
+	left := minStart - m.sealedByEpoch(head, cfg)
+	return now.Add(time.Duration(left) * time.Duration(m.networkParams.BlockDelaySecs) * time.Second), true, nil
+}
+
 func (m *Sealing) handleAddPiece(ctx statemachine.Context, sector types.SectorInfo) error {
 	ssize, err := sector.SectorType.SectorSize()
 	if err != nil {
@@ -191,7 +234,7 @@ func (m *Sealing) handleAddPiece(ctx statemachine.Context, sector types.SectorIn
 		for _, p := range pads {
 			expectCid := zerocomm.ZeroPieceCommitment(p.Unpadded())
 
-			ppi, err := m.sealer.AddPiece(sectorstorage.WithPriority(ctx.Context(), types.DealSectorPriority),
+			ppi, err := m.sealer.AddPiece(types.WithSectorClass(sectorstorage.WithPriority(ctx.Context(), types.DealSectorPriority), types.SectorClassDeal),
 				m.minerSector(sector.SectorType, sector.SectorNumber),
 				pieceSizes,
 				p.Unpadded(),
@@ -213,7 +256,7 @@ func (m *Sealing) handleAddPiece(ctx statemachine.Context, sector types.SectorIn
 			})
 		}
 
-		ppi, err := m.sealer.AddPiece(sectorstorage.WithPriority(ctx.Context(), types.DealSectorPriority),
+		ppi, err := m.sealer.AddPiece(types.WithSectorClass(sectorstorage.WithPriority(ctx.Context(), types.DealSectorPriority), types.SectorClassDeal),
 			m.minerSector(sector.SectorType, sector.SectorNumber),
 			pieceSizes,
 			deal.size,
@@ -329,6 +372,14 @@ func (m *Sealing) SectorAddPieceToAny(ctx context.Context, size abi.UnpaddedPiec
 
 // called with m.inputLk
 func (m *Sealing) updateInput(ctx context.Context, sp abi.RegisteredSealProof) error {
+	cfg, err := m.getConfig()
+	if err != nil {
+		return xerrors.Errorf("getting storage config: %w", err)
+	}
+	if cfg.PlanDealPacking {
+		return m.updateInputPlanned(ctx, sp, cfg)
+	}
+
 	ssize, err := sp.SectorSize()
 	if err != nil {
 		return err
@@ -403,6 +454,7 @@ func (m *Sealing) updateInput(ctx context.Context, sp abi.RegisteredSealProof) e
 		}
 
 		m.openSectors[mt.sector].used += mt.padding + mt.size
+		m.openSectors[mt.sector].addDeal(m.pendingPieces[mt.deal].deal.DealSchedule.EndEpoch)
 
 		m.pendingPieces[mt.deal].assigned = true
 		delete(toAssign, mt.deal)
@@ -72,6 +72,21 @@ func (mr *MockCommitBatcherApiMockRecorder) ChainHead(arg0 interface{}) *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChainHead", reflect.TypeOf((*MockCommitBatcherApi)(nil).ChainHead), arg0)
 }
 
+// GasEstimateGasLimit mocks base method.
+func (m *MockCommitBatcherApi) GasEstimateGasLimit(arg0 context.Context, arg1, arg2 address.Address, arg3 abi.MethodNum, arg4 big.Int, arg5 []byte, arg6 types.TipSetToken) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GasEstimateGasLimit", arg0, arg1, arg2, arg3, arg4, arg5, arg6)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GasEstimateGasLimit indicates an expected call of GasEstimateGasLimit.
+func (mr *MockCommitBatcherApiMockRecorder) GasEstimateGasLimit(arg0, arg1, arg2, arg3, arg4, arg5, arg6 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GasEstimateGasLimit", reflect.TypeOf((*MockCommitBatcherApi)(nil).GasEstimateGasLimit), arg0, arg1, arg2, arg3, arg4, arg5, arg6)
+}
+
 // MessagerSendMsg mocks base method.
 func (m *MockCommitBatcherApi) MessagerSendMsg(arg0 context.Context, arg1, arg2 address.Address, arg3 abi.MethodNum, arg4, arg5 big.Int, arg6 []byte) (string, error) {
 	m.ctrl.T.Helper()
@@ -71,6 +71,21 @@ func (mr *MockPreCommitBatcherApiMockRecorder) ChainHead(arg0 interface{}) *gomo
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChainHead", reflect.TypeOf((*MockPreCommitBatcherApi)(nil).ChainHead), arg0)
 }
 
+// GasEstimateGasLimit mocks base method.
+func (m *MockPreCommitBatcherApi) GasEstimateGasLimit(arg0 context.Context, arg1, arg2 address.Address, arg3 abi.MethodNum, arg4 big.Int, arg5 []byte, arg6 types.TipSetToken) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GasEstimateGasLimit", arg0, arg1, arg2, arg3, arg4, arg5, arg6)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GasEstimateGasLimit indicates an expected call of GasEstimateGasLimit.
+func (mr *MockPreCommitBatcherApiMockRecorder) GasEstimateGasLimit(arg0, arg1, arg2, arg3, arg4, arg5, arg6 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GasEstimateGasLimit", reflect.TypeOf((*MockPreCommitBatcherApi)(nil).GasEstimateGasLimit), arg0, arg1, arg2, arg3, arg4, arg5, arg6)
+}
+
 // MessagerSendMsg mocks base method.
 func (m *MockPreCommitBatcherApi) MessagerSendMsg(arg0 context.Context, arg1, arg2 address.Address, arg3 abi.MethodNum, arg4, arg5 big.Int, arg6 []byte) (string, error) {
 	m.ctrl.T.Helper()
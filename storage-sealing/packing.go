@@ -0,0 +1,358 @@
+package sealing
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/venus-sealer/storage-sealing/sealiface"
+	"github.com/filecoin-project/venus-sealer/types"
+)
+
+// packingPiece is a deal piece waiting to be assigned to a sector
+type packingPiece struct {
+	key   cid.Cid
+	deal  abi.DealID
+	piece cid.Cid
+	size  abi.UnpaddedPieceSize
+	start abi.ChainEpoch
+	end   abi.ChainEpoch
+}
+
+// packingSector is an open sector, or a sector which would have to be created
+type packingSector struct {
+	id  abi.SectorID
+	new bool
+
+	used   abi.UnpaddedPieceSize
+	deals  int
+	minEnd abi.ChainEpoch // end epochs of deals in the sector, 0 without deals
+	maxEnd abi.ChainEpoch
+
+	pieces  []packingPiece
+	padding abi.PaddedPieceSize
+}
+
+// fit returns the padding needed to add a piece of the size to the sector
+func (s *packingSector) fit(ssize abi.SectorSize, maxDeals int, size abi.UnpaddedPieceSize) (abi.PaddedPieceSize, bool) {
+	if s.deals >= maxDeals {
+		return 0, false
+	}
+
+	padding := piecePadding(s.used, size)
+	if s.used.Padded()+padding+size.Padded() > abi.PaddedPieceSize(ssize) {
+		return 0, false
+	}
+
+	return padding, true
+}
+
+// piecePadding returns the filler padding needed before a piece of the size, pieces are aligned to their size
+func piecePadding(used abi.UnpaddedPieceSize, size abi.UnpaddedPieceSize) abi.PaddedPieceSize {
+	offset := used.Padded()
+	return (size.Padded() - offset%size.Padded()) % size.Padded()
+}
+
+// endDistance is how far the end epoch is from the end epochs of deals in the sector
+func (s *packingSector) endDistance(end abi.ChainEpoch) abi.ChainEpoch {
+	switch {
+	case s.deals == 0:
+		return 0
+	case end < s.minEnd:
+		return s.minEnd - end
+	case end > s.maxEnd:
+		return end - s.maxEnd
+	}
+	return 0
+}
+
+func (s *packingSector) add(p packingPiece, padding abi.PaddedPieceSize) {
+	s.used += padding.Unpadded() + p.size
+	s.padding += padding
+	s.pieces = append(s.pieces, p)
+
+	if s.deals == 0 || p.end < s.minEnd {
+		s.minEnd = p.end
+	}
+	if s.deals == 0 || p.end > s.maxEnd {
+		s.maxEnd = p.end
+	}
+	s.deals++
+}
+
+type rejectedPiece struct {
+	packingPiece
+	reason string
+}
+
+type packingPlan struct {
+	sealedBy abi.ChainEpoch
+
+	// sectors which got pieces assigned, open sectors first
+	sectors  []*packingSector
+	rejected []rejectedPiece
+}
+
+// planPacking assigns pieces to sectors with first fit decreasing bin packing.
+// Pieces go to the sector needing the least padding, and then to the sector with
+// deals ending closest to the deal of the piece. Pieces with a start epoch before
+// sealedBy, the earliest epoch a sector can be sealed by, are rejected.
+func planPacking(ssize abi.SectorSize, maxDeals int, open []*packingSector, pieces []packingPiece, sealedBy abi.ChainEpoch) *packingPlan {
+	plan := &packingPlan{
+		sealedBy: sealedBy,
+	}
+
+	var todo []packingPiece
+	for _, p := range pieces {
+		if p.start < sealedBy {
+			plan.rejected = append(plan.rejected, rejectedPiece{
+				packingPiece: p,
+				reason:       fmt.Sprintf("deal start epoch %d is before %d, the earliest epoch the sector can be sealed by", p.start, sealedBy),
+			})
+			continue
+		}
+		todo = append(todo, p)
+	}
+
+	sort.Slice(todo, func(i, j int) bool {
+		if todo[i].size != todo[j].size {
+			return todo[i].size > todo[j].size
+		}
+		if todo[i].end != todo[j].end {
+			return todo[i].end < todo[j].end
+		}
+		return todo[i].deal < todo[j].deal
+	})
+
+	sectors := make([]*packingSector, 0, len(open))
+	for _, s := range open {
+		sc := *s
+		sc.pieces = nil
+		sc.padding = 0
+		sectors = append(sectors, &sc)
+	}
+	sort.Slice(sectors, func(i, j int) bool {
+		return sectors[i].id.Number < sectors[j].id.Number // prefer older sectors
+	})
+
+	for _, p := range todo {
+		var best *packingSector
+		var bestPad abi.PaddedPieceSize
+		var bestDist abi.ChainEpoch
+
+		for _, s := range sectors {
+			pad, ok := s.fit(ssize, maxDeals, p.size)
+			if !ok {
+				continue
+			}
+			dist := s.endDistance(p.end)
+
+			if best == nil || pad < bestPad || (pad == bestPad && dist < bestDist) {
+				best, bestPad, bestDist = s, pad, dist
+			}
+		}
+
+		if best == nil {
+			best = &packingSector{new: true}
+			sectors = append(sectors, best)
+			bestPad = 0
+		}
+
+		best.add(p, bestPad)
+	}
+
+	for _, s := range sectors {
+		if len(s.pieces) > 0 {
+			plan.sectors = append(plan.sectors, s)
+		}
+	}
+
+	return plan
+}
+
+// sealedByEpoch returns the earliest epoch a sector starting to seal now will be sealed by
+func (m *Sealing) sealedByEpoch(head abi.ChainEpoch, cfg sealiface.Config) abi.ChainEpoch {
+	blockDelay := time.Duration(m.networkParams.BlockDelaySecs) * time.Second
+	if blockDelay == 0 {
+		return head
+	}
+
+	return head + abi.ChainEpoch((cfg.ExpectedSealDuration+blockDelay-1)/blockDelay)
+}
+
+// call with m.inputLk
+func (m *Sealing) pendingPackingPieces() []packingPiece {
+	var out []packingPiece
+	for key, piece := range m.pendingPieces {
+		if piece.assigned {
+			continue
+		}
+
+		out = append(out, packingPiece{
+			key:   key,
+			deal:  piece.deal.DealID,
+			piece: piece.deal.DealProposal.PieceCID,
+			size:  piece.size,
+			start: piece.deal.DealSchedule.StartEpoch,
+			end:   piece.deal.DealSchedule.EndEpoch,
+		})
+	}
+	return out
+}
+
+// call with m.inputLk
+func (m *Sealing) openPackingSectors() []*packingSector {
+	out := make([]*packingSector, 0, len(m.openSectors))
+	for id, sector := range m.openSectors {
+		out = append(out, &packingSector{
+			id:     id,
+			used:   sector.used,
+			deals:  sector.deals,
+			minEnd: sector.minEnd,
+			maxEnd: sector.maxEnd,
+		})
+	}
+	return out
+}
+
+// call with m.inputLk
+func (m *Sealing) planInput(ctx context.Context, sp abi.RegisteredSealProof, cfg sealiface.Config) (*packingPlan, error) {
+	ssize, err := sp.SectorSize()
+	if err != nil {
+		return nil, err
+	}
+
+	maxDeals, err := getDealPerSectorLimit(ssize)
+	if err != nil {
+		return nil, xerrors.Errorf("getting per-sector deal limit: %w", err)
+	}
+
+	_, head, err := m.api.ChainHead(ctx)
+	if err != nil {
+		return nil, xerrors.Errorf("getting chain head: %w", err)
+	}
+
+	return planPacking(ssize, maxDeals, m.openPackingSectors(), m.pendingPackingPieces(), m.sealedByEpoch(head, cfg)), nil
+}
+
+// call with m.inputLk
+func (m *Sealing) updateInputPlanned(ctx context.Context, sp abi.RegisteredSealProof, cfg sealiface.Config) error {
+	plan, err := m.planInput(ctx, sp, cfg)
+	if err != nil {
+		return xerrors.Errorf("planning deal packing: %w", err)
+	}
+
+	for _, r := range plan.rejected {
+		log.Warnw("rejecting deal", "deal", r.deal, "reason", r.reason)
+
+		piece := m.pendingPieces[r.key]
+		delete(m.pendingPieces, r.key)
+		piece.accepted(0, 0, xerrors.Errorf("rejecting deal %d: %s", r.deal, r.reason))
+	}
+
+	var toCreate bool
+	for _, planned := range plan.sectors {
+		if planned.new {
+			toCreate = true
+			continue
+		}
+
+		sector, ok := m.openSectors[planned.id]
+		if !ok {
+			continue
+		}
+
+		for _, p := range planned.pieces {
+			err := sector.maybeAccept(p.key)
+			if err != nil {
+				m.pendingPieces[p.key].accepted(planned.id.Number, 0, err) // non-error case in handleAddPiece
+			}
+
+			sector.used += piecePadding(sector.used, p.size).Unpadded() + p.size
+			sector.addDeal(p.end)
+
+			m.pendingPieces[p.key].assigned = true
+
+			if err != nil {
+				log.Errorf("sector %d rejected deal %s: %+v", planned.id, p.key, err)
+			}
+		}
+	}
+
+	if toCreate {
+		if err := m.tryCreateDealSector(ctx, sp); err != nil {
+			log.Errorw("Failed to create a new sector for deals", "error", err)
+		}
+	}
+
+	return nil
+}
+
+// PackingPlan returns how deals waiting for sectors would be packed now, without assigning them
+func (m *Sealing) PackingPlan(ctx context.Context) (types.PackingPlan, error) {
+	cfg, err := m.getConfig()
+	if err != nil {
+		return types.PackingPlan{}, xerrors.Errorf("getting storage config: %w", err)
+	}
+
+	sp, err := m.currentSealProof(ctx)
+	if err != nil {
+		return types.PackingPlan{}, xerrors.Errorf("getting current seal proof type: %w", err)
+	}
+
+	ssize, err := sp.SectorSize()
+	if err != nil {
+		return types.PackingPlan{}, err
+	}
+
+	m.inputLk.Lock()
+	plan, err := m.planInput(ctx, sp, cfg)
+	m.inputLk.Unlock()
+	if err != nil {
+		return types.PackingPlan{}, xerrors.Errorf("planning deal packing: %w", err)
+	}
+
+	out := types.PackingPlan{
+		SealedBy: plan.sealedBy,
+	}
+
+	for _, s := range plan.sectors {
+		ps := types.PlannedSector{
+			Sector:  s.id.Number,
+			New:     s.new,
+			Used:    s.used.Padded(),
+			Free:    abi.PaddedPieceSize(ssize) - s.used.Padded(),
+			Padding: s.padding,
+		}
+		for _, p := range s.pieces {
+			ps.Pieces = append(ps.Pieces, p.planned(""))
+		}
+		out.Sectors = append(out.Sectors, ps)
+	}
+
+	for _, r := range plan.rejected {
+		out.Rejected = append(out.Rejected, r.planned(r.reason))
+	}
+	sort.Slice(out.Rejected, func(i, j int) bool {
+		return out.Rejected[i].DealID < out.Rejected[j].DealID
+	})
+
+	return out, nil
+}
+
+func (p packingPiece) planned(reason string) types.PlannedPiece {
+	return types.PlannedPiece{
+		DealID:     p.deal,
+		PieceCID:   p.piece,
+		Size:       p.size,
+		StartEpoch: p.start,
+		EndEpoch:   p.end,
+		Reason:     reason,
+	}
+}
@@ -0,0 +1,106 @@
+package sealing
+
+import (
+	"testing"
+
+	commcid "github.com/filecoin-project/go-fil-commcid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-state-types/abi"
+)
+
+func testPackingPiece(t *testing.T, deal abi.DealID, size abi.UnpaddedPieceSize, start, end abi.ChainEpoch) packingPiece {
+	comm := [32]byte{byte(deal)}
+	key, err := commcid.DataCommitmentV1ToCID(comm[:])
+	require.NoError(t, err)
+
+	return packingPiece{key: key, deal: deal, piece: key, size: size, start: start, end: end}
+}
+
+func TestPiecePadding(t *testing.T) {
+	require.Equal(t, abi.PaddedPieceSize(0), piecePadding(0, 254))
+	require.Equal(t, abi.PaddedPieceSize(128), piecePadding(127, 254))
+	require.Equal(t, abi.PaddedPieceSize(0), piecePadding(254, 254))
+	require.Equal(t, abi.PaddedPieceSize(768), piecePadding(254, 1016))
+}
+
+func TestPlanPacking(t *testing.T) {
+	ssize := abi.SectorSize(2048)
+
+	t.Run("rejects late deals", func(t *testing.T) {
+		plan := planPacking(ssize, 10, nil, []packingPiece{
+			testPackingPiece(t, 1, 254, 50, 1000),
+			testPackingPiece(t, 2, 254, 200, 1000),
+		}, 100)
+
+		require.Len(t, plan.rejected, 1)
+		require.Equal(t, abi.DealID(1), plan.rejected[0].deal)
+
+		require.Len(t, plan.sectors, 1)
+		require.True(t, plan.sectors[0].new)
+		require.Equal(t, abi.DealID(2), plan.sectors[0].pieces[0].deal)
+	})
+
+	t.Run("least padding", func(t *testing.T) {
+		open := []*packingSector{
+			{id: abi.SectorID{Number: 1}, used: 127, deals: 1, minEnd: 1000, maxEnd: 1000},
+			{id: abi.SectorID{Number: 2}, used: 254, deals: 1, minEnd: 1000, maxEnd: 1000},
+		}
+
+		plan := planPacking(ssize, 10, open, []packingPiece{testPackingPiece(t, 1, 254, 200, 1000)}, 100)
+
+		require.Len(t, plan.sectors, 1)
+		require.Equal(t, abi.SectorNumber(2), plan.sectors[0].id.Number)
+		require.Equal(t, abi.PaddedPieceSize(0), plan.sectors[0].padding)
+		require.Equal(t, abi.UnpaddedPieceSize(508), plan.sectors[0].used)
+
+		// the open sectors are not modified
+		require.Equal(t, abi.UnpaddedPieceSize(254), open[1].used)
+	})
+
+	t.Run("groups end epochs", func(t *testing.T) {
+		open := []*packingSector{
+			{id: abi.SectorID{Number: 1}, used: 254, deals: 1, minEnd: 1000, maxEnd: 1000},
+			{id: abi.SectorID{Number: 2}, used: 254, deals: 1, minEnd: 5000, maxEnd: 5000},
+		}
+
+		plan := planPacking(ssize, 10, open, []packingPiece{
+			testPackingPiece(t, 1, 254, 200, 4900),
+			testPackingPiece(t, 2, 254, 200, 1100),
+		}, 100)
+
+		require.Len(t, plan.sectors, 2)
+		for _, s := range plan.sectors {
+			require.Len(t, s.pieces, 1)
+			switch s.id.Number {
+			case 1:
+				require.Equal(t, abi.DealID(2), s.pieces[0].deal)
+				require.Equal(t, abi.ChainEpoch(1100), s.maxEnd)
+			case 2:
+				require.Equal(t, abi.DealID(1), s.pieces[0].deal)
+				require.Equal(t, abi.ChainEpoch(4900), s.minEnd)
+			}
+		}
+	})
+
+	t.Run("new sectors", func(t *testing.T) {
+		open := []*packingSector{
+			{id: abi.SectorID{Number: 1}, used: 2032, deals: 1, minEnd: 1000, maxEnd: 1000},
+			{id: abi.SectorID{Number: 2}, used: 254, deals: 2, minEnd: 1000, maxEnd: 1000},
+		}
+
+		// sector 1 is full, sector 2 has the maximum number of deals
+		plan := planPacking(ssize, 2, open, []packingPiece{
+			testPackingPiece(t, 1, 1016, 200, 1000),
+			testPackingPiece(t, 2, 1016, 200, 1000),
+			testPackingPiece(t, 3, 508, 200, 1000),
+		}, 100)
+
+		require.Len(t, plan.sectors, 2)
+		require.True(t, plan.sectors[0].new)
+		require.True(t, plan.sectors[1].new)
+		require.Len(t, plan.sectors[0].pieces, 2)
+		require.Len(t, plan.sectors[1].pieces, 1)
+		require.Equal(t, abi.DealID(3), plan.sectors[1].pieces[0].deal)
+	})
+}
@@ -3,6 +3,7 @@ package sealing
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"github.com/filecoin-project/go-address"
 	"github.com/filecoin-project/go-state-types/abi"
 	"github.com/filecoin-project/go-state-types/big"
@@ -34,6 +35,8 @@ type PreCommitBatcherApi interface {
 	ChainHead(ctx context.Context) (types.TipSetToken, abi.ChainEpoch, error)
 	ChainBaseFee(context.Context, types.TipSetToken) (abi.TokenAmount, error)
 	StateNetworkVersion(ctx context.Context, tok types.TipSetToken) (network.Version, error)
+
+	GasEstimateGasLimit(ctx context.Context, from, to address.Address, method abi.MethodNum, value abi.TokenAmount, params []byte, tok types.TipSetToken) (int64, error)
 }
 
 type preCommitEntry struct {
@@ -41,18 +44,36 @@ type preCommitEntry struct {
 	pci     *miner0.SectorPreCommitInfo
 }
 
+// persisted form of preCommitEntry
+type preCommitBatchData struct {
+	Deposit abi.TokenAmount
+	Info    *miner0.SectorPreCommitInfo
+}
+
 type PreCommitBatcher struct {
 	api       PreCommitBatcherApi
 	maddr     address.Address
 	mctx      context.Context
 	addrSel   AddrSel
-	feeCfg    config.MinerFeeConfig
+	feeCfg    config.GetMinerFeeConfigFunc
 	getConfig types.GetSealingConfigFunc
 
 	cutoffs map[abi.SectorNumber]time.Time
 	todo    map[abi.SectorNumber]*preCommitEntry
 	waiting map[abi.SectorNumber][]chan sealiface.PreCommitBatchRes
 
+	// results of restored sectors sent before the state machine added them again
+	store    types.BatchStore
+	restored map[abi.SectorNumber]struct{}
+	results  map[abi.SectorNumber]sealiface.PreCommitBatchRes
+
+	lastDecision *types.BatchDecision
+
+	// pruned is closed once the restored entries were checked against the
+	// sectors, restored entries aren't sent before that
+	pruned    chan struct{}
+	pruneOnce sync.Once
+
 	notify, stop, stopped chan struct{}
 	force                 chan chan []sealiface.PreCommitBatchRes
 	lk                    sync.Mutex
@@ -60,7 +81,7 @@ type PreCommitBatcher struct {
 	networkParams *config.NetParamsConfig
 }
 
-func NewPreCommitBatcher(mctx context.Context, networkParams *config.NetParamsConfig, maddr address.Address, api PreCommitBatcherApi, addrSel AddrSel, feeCfg config.MinerFeeConfig, getConfig types.GetSealingConfigFunc) *PreCommitBatcher {
+func NewPreCommitBatcher(mctx context.Context, networkParams *config.NetParamsConfig, maddr address.Address, api PreCommitBatcherApi, addrSel AddrSel, feeCfg config.GetMinerFeeConfigFunc, getConfig types.GetSealingConfigFunc, store types.BatchStore) *PreCommitBatcher {
 	b := &PreCommitBatcher{
 		api:           api,
 		maddr:         maddr,
@@ -73,18 +94,37 @@ func NewPreCommitBatcher(mctx context.Context, networkParams *config.NetParamsCo
 		todo:          map[abi.SectorNumber]*preCommitEntry{},
 		waiting:       map[abi.SectorNumber][]chan sealiface.PreCommitBatchRes{},
 
+		store:    store,
+		restored: map[abi.SectorNumber]struct{}{},
+		results:  map[abi.SectorNumber]sealiface.PreCommitBatchRes{},
+
 		notify:  make(chan struct{}, 1),
 		force:   make(chan chan []sealiface.PreCommitBatchRes),
 		stop:    make(chan struct{}),
+		pruned:  make(chan struct{}),
 		stopped: make(chan struct{}),
 	}
 
+	if err := b.restore(); err != nil {
+		log.Errorw("restoring precommit batch queue", "error", err)
+	}
+	if len(b.restored) == 0 {
+		b.release()
+	}
+
 	go b.run()
 
 	return b
 }
 
 func (b *PreCommitBatcher) run() {
+	select {
+	case <-b.pruned:
+	case <-b.stop:
+		close(b.stopped)
+		return
+	}
+
 	var forceRes chan []sealiface.PreCommitBatchRes
 	var lastRes []sealiface.PreCommitBatchRes
 
@@ -206,28 +246,73 @@ func (b *PreCommitBatcher) maybeStartBatch(notif bool) ([]sealiface.PreCommitBat
 		return nil, xerrors.Errorf("couldn't get network version: %w", err)
 	}
 
-	individual := false
-	if !cfg.BatchPreCommitAboveBaseFee.Equals(big.Zero()) && bf.LessThan(cfg.BatchPreCommitAboveBaseFee) && nv >= network.Version14 {
-		individual = true
+	sectors := make([]abi.SectorNumber, 0, total)
+	for sn := range b.todo {
+		sectors = append(sectors, sn)
+	}
+	sectors = sectorsByCutoff(sectors, b.cutoffs)
+
+	var batch, individual []abi.SectorNumber
+
+	if cfg.BatchCostModel {
+		if len(sectors) > cfg.MaxPreCommitBatch {
+			sectors = sectors[:cfg.MaxPreCommitBatch] // the rest waits for the next batch
+		}
+
+		d, err := b.decide(cfg, sectors, tok, bf, nv)
+		if err != nil {
+			return nil, xerrors.Errorf("deciding precommit batch split: %w", err)
+		}
+		b.lastDecision = d
+		log.Infow("PreCommitBatcher batch decision", "sectors", d.Sectors, "batch", d.Chosen.Aggregated, "individual", d.Chosen.Individual, "reason", d.Reason)
+
+		batch, individual = sectors[:d.Chosen.Aggregated], sectors[d.Chosen.Aggregated:]
+	} else {
+		batch = sectors
+		if !cfg.BatchPreCommitAboveBaseFee.Equals(big.Zero()) && bf.LessThan(cfg.BatchPreCommitAboveBaseFee) && nv >= network.Version14 {
+			batch, individual = nil, sectors
+		}
 	}
 
 	// todo support multiple batches
 	var res []sealiface.PreCommitBatchRes
-	if !individual {
-		res, err = b.processBatch(cfg, tok, bf, nv)
-	} else {
-		res, err = b.processIndividually(cfg)
+	var lastErr error
+
+	if len(batch) > 0 {
+		bres, err := b.processBatch(cfg, batch, tok, bf, nv)
+		if err != nil {
+			lastErr = err
+		}
+		res = append(res, b.sent(bres, err)...)
 	}
-	if err != nil && len(res) == 0 {
-		return nil, err
+
+	if len(individual) > 0 {
+		ires, err := b.processIndividually(cfg, individual)
+		if err != nil {
+			lastErr = err
+		}
+		res = append(res, b.sent(ires, err)...)
 	}
 
+	if lastErr != nil && len(res) == 0 {
+		return nil, lastErr
+	}
+
+	return res, nil
+}
+
+// sent hands the results of sent messages to the sectors waiting for them, and removes the sectors from the queue
+func (b *PreCommitBatcher) sent(res []sealiface.PreCommitBatchRes, err error) []sealiface.PreCommitBatchRes {
 	for _, r := range res {
 		if err != nil {
 			r.Error = err.Error()
 		}
 
 		for _, sn := range r.Sectors {
+			if len(b.waiting[sn]) == 0 {
+				// restored sector, the state machine picks the result up when it adds the sector again
+				b.results[sn] = r
+			}
 			for _, ch := range b.waiting[sn] {
 				ch <- r // buffered
 			}
@@ -235,13 +320,258 @@ func (b *PreCommitBatcher) maybeStartBatch(notif bool) ([]sealiface.PreCommitBat
 			delete(b.waiting, sn)
 			delete(b.todo, sn)
 			delete(b.cutoffs, sn)
+			delete(b.restored, sn)
+		}
+
+		if err := b.persistDone(r.Sectors); err != nil {
+			log.Errorw("removing sectors from persisted precommit batch queue", "sectors", r.Sectors, "error", err)
 		}
 	}
 
-	return res, nil
+	return res
+}
+
+// decide runs the batch cost model for the sectors at the base fee
+func (b *PreCommitBatcher) decide(cfg sealiface.Config, sectors []abi.SectorNumber, tok types.TipSetToken, bf abi.TokenAmount, nv network.Version) (*types.BatchDecision, error) {
+	model := &batchCostModel{
+		singleGas:         PreCommitSectorGas,
+		batchGasBase:      PreCommitBatchGasBase,
+		batchGasPerSector: PreCommitBatchGasPerSector,
+		minBatch:          1,
+		slack:             cfg.PreCommitBatchSlack,
+		networkFee: func(n int) (abi.TokenAmount, error) {
+			return policy.AggregatePreCommitNetworkFee(nv, n, bf)
+		},
+	}
+
+	var earliest time.Time
+	if len(sectors) > 0 {
+		earliest = b.cutoffs[sectors[0]]
+
+		if err := b.estimateGas(model, sectors, tok); err != nil {
+			log.Warnw("estimating precommit gas, using the defaults", "error", err)
+		}
+	}
+
+	// a batch of one sector is valid, so precommits never wait for more sectors
+	return model.decide(len(sectors), bf, earliest, false)
+}
+
+// estimateGas sets the gas of the cost model from estimates of a PreCommitSector message of
+// the first sector, and of PreCommitSectorBatch messages of the first and of all the sectors
+func (b *PreCommitBatcher) estimateGas(model *batchCostModel, sectors []abi.SectorNumber, tok types.TipSetToken) error {
+	mi, err := b.api.StateMinerInfo(b.mctx, b.maddr, tok)
+	if err != nil {
+		return xerrors.Errorf("couldn't get miner info: %w", err)
+	}
+
+	first := b.todo[sectors[0]]
+	enc := new(bytes.Buffer)
+	if err := first.pci.MarshalCBOR(enc); err != nil {
+		return xerrors.Errorf("marshaling precommit params: %w", err)
+	}
+
+	single, err := b.api.GasEstimateGasLimit(b.mctx, mi.Worker, b.maddr, miner.Methods.PreCommitSector, first.deposit, enc.Bytes(), tok)
+	if err != nil {
+		return xerrors.Errorf("estimating PreCommitSector gas: %w", err)
+	}
+
+	batchGas := func(n int) (int64, error) {
+		params := miner5.PreCommitSectorBatchParams{}
+		deposit := big.Zero()
+		for _, sn := range sectors[:n] {
+			p := b.todo[sn]
+			params.Sectors = append(params.Sectors, *p.pci)
+			deposit = big.Add(deposit, p.deposit)
+		}
+
+		enc := new(bytes.Buffer)
+		if err := params.MarshalCBOR(enc); err != nil {
+			return 0, xerrors.Errorf("marshaling precommit batch params: %w", err)
+		}
+
+		fee, err := model.networkFee(n)
+		if err != nil {
+			return 0, xerrors.Errorf("getting batch network fee for %d sectors: %w", n, err)
+		}
+
+		gas, err := b.api.GasEstimateGasLimit(b.mctx, mi.Worker, b.maddr, miner.Methods.PreCommitSectorBatch, big.Add(deposit, fee), enc.Bytes(), tok)
+		if err != nil {
+			return 0, xerrors.Errorf("estimating PreCommitSectorBatch gas for %d sectors: %w", n, err)
+		}
+		return gas, nil
+	}
+
+	one, err := batchGas(1)
+	if err != nil {
+		return err
+	}
+	all := one
+	if len(sectors) > 1 {
+		if all, err = batchGas(len(sectors)); err != nil {
+			return err
+		}
+	}
+
+	model.singleGas = single
+	model.batchGasBase, model.batchGasPerSector = linearGas(one, all, len(sectors))
+	return nil
+}
+
+// Explain returns the last decision of the batch cost model, and what it would decide for the queue now
+func (b *PreCommitBatcher) Explain() (types.BatchExplain, error) {
+	cfg, err := b.getConfig()
+	if err != nil {
+		return types.BatchExplain{}, xerrors.Errorf("getting config: %w", err)
+	}
+
+	b.lk.Lock()
+	defer b.lk.Unlock()
+
+	out := types.BatchExplain{Last: b.lastDecision}
+	if len(b.todo) == 0 {
+		return out, nil
+	}
+
+	tok, _, err := b.api.ChainHead(b.mctx)
+	if err != nil {
+		return types.BatchExplain{}, err
+	}
+
+	bf, err := b.api.ChainBaseFee(b.mctx, tok)
+	if err != nil {
+		return types.BatchExplain{}, xerrors.Errorf("couldn't get base fee: %w", err)
+	}
+
+	nv, err := b.api.StateNetworkVersion(b.mctx, tok)
+	if err != nil {
+		return types.BatchExplain{}, xerrors.Errorf("couldn't get network version: %w", err)
+	}
+
+	sectors := make([]abi.SectorNumber, 0, len(b.todo))
+	for sn := range b.todo {
+		sectors = append(sectors, sn)
+	}
+	sectors = sectorsByCutoff(sectors, b.cutoffs)
+	if len(sectors) > cfg.MaxPreCommitBatch {
+		sectors = sectors[:cfg.MaxPreCommitBatch]
+	}
+
+	out.Next, err = b.decide(cfg, sectors, tok, bf, nv)
+	if err != nil {
+		return types.BatchExplain{}, xerrors.Errorf("deciding precommit batch split: %w", err)
+	}
+
+	return out, nil
 }
 
-func (b *PreCommitBatcher) processIndividually(cfg sealiface.Config) ([]sealiface.PreCommitBatchRes, error) {
+// restore loads the batch queue persisted before the last shutdown
+func (b *PreCommitBatcher) restore() error {
+	if b.store == nil {
+		return nil
+	}
+
+	entries, err := b.store.ListBatchEntries(types.BatcherPreCommit)
+	if err != nil {
+		return xerrors.Errorf("listing batch entries: %w", err)
+	}
+
+	for _, e := range entries {
+		var data preCommitBatchData
+		if err := json.Unmarshal(e.Data, &data); err != nil {
+			log.Errorw("decoding persisted precommit batch entry", "sector", e.SectorNumber, "error", err)
+			continue
+		}
+
+		b.todo[e.SectorNumber] = &preCommitEntry{
+			deposit: data.Deposit,
+			pci:     data.Info,
+		}
+		if !e.Cutoff.IsZero() {
+			b.cutoffs[e.SectorNumber] = e.Cutoff
+		}
+		b.restored[e.SectorNumber] = struct{}{}
+	}
+
+	if len(entries) > 0 {
+		log.Infow("restored precommit batch queue", "sectors", len(b.todo))
+	}
+
+	return nil
+}
+
+// prune drops the restored sectors the state machine won't add again, and the
+// results nobody is going to pick up, live are the sectors still waiting for a batch. A nil
+// live means the sectors couldn't be listed, the restored entries are then only
+// dropped from memory and restored again on the next start
+func (b *PreCommitBatcher) prune(live map[abi.SectorNumber]struct{}) {
+	b.lk.Lock()
+	defer b.lk.Unlock()
+	defer b.release()
+
+	var drop []abi.SectorNumber
+	for sn := range b.restored {
+		if _, ok := live[sn]; ok {
+			continue
+		}
+		delete(b.todo, sn)
+		delete(b.cutoffs, sn)
+		delete(b.restored, sn)
+		drop = append(drop, sn)
+	}
+	for sn := range b.results {
+		if _, ok := live[sn]; !ok {
+			delete(b.results, sn)
+		}
+	}
+
+	if len(drop) == 0 || live == nil {
+		return
+	}
+	log.Infow("dropping restored precommit batch entries of sectors no longer waiting for a batch", "sectors", drop)
+	if err := b.persistDone(drop); err != nil {
+		log.Errorw("removing sectors from persisted precommit batch queue", "sectors", drop, "error", err)
+	}
+}
+
+// release lets run send the queued sectors
+func (b *PreCommitBatcher) release() {
+	b.pruneOnce.Do(func() {
+		close(b.pruned)
+	})
+}
+
+// call with b.lk
+func (b *PreCommitBatcher) persist(sn abi.SectorNumber) error {
+	if b.store == nil {
+		return nil
+	}
+
+	entry := b.todo[sn]
+	data, err := json.Marshal(&preCommitBatchData{
+		Deposit: entry.deposit,
+		Info:    entry.pci,
+	})
+	if err != nil {
+		return xerrors.Errorf("encoding batch entry: %w", err)
+	}
+
+	return b.store.SaveBatchEntry(types.BatcherPreCommit, &types.BatchEntry{
+		SectorNumber: sn,
+		Cutoff:       b.cutoffs[sn],
+		Data:         data,
+	})
+}
+
+func (b *PreCommitBatcher) persistDone(sectors []abi.SectorNumber) error {
+	if b.store == nil {
+		return nil
+	}
+
+	return b.store.DeleteBatchEntries(types.BatcherPreCommit, sectors)
+}
+
+func (b *PreCommitBatcher) processIndividually(cfg sealiface.Config, sectors []abi.SectorNumber) ([]sealiface.PreCommitBatchRes, error) {
 	mi, err := b.api.StateMinerInfo(b.mctx, b.maddr, nil)
 	if err != nil {
 		return nil, xerrors.Errorf("couldn't get miner info: %w", err)
@@ -263,7 +593,8 @@ func (b *PreCommitBatcher) processIndividually(cfg sealiface.Config) ([]sealifac
 
 	var res []sealiface.PreCommitBatchRes
 
-	for sn, info := range b.todo {
+	for _, sn := range sectors {
+		info := b.todo[sn]
 		r := sealiface.PreCommitBatchRes{
 			Sectors: []abi.SectorNumber{sn},
 		}
@@ -302,14 +633,14 @@ func (b *PreCommitBatcher) processSingle(cfg sealiface.Config, mi miner.MinerInf
 		}
 	}
 
-	goodFunds := big.Add(deposit, big.Int(b.feeCfg.MaxPreCommitGasFee))
+	goodFunds := big.Add(deposit, big.Int(b.feeCfg().MaxPreCommitGasFee))
 
 	from, _, err := b.addrSel(b.mctx, mi, api.PreCommitAddr, goodFunds, deposit)
 	if err != nil {
 		return "", xerrors.Errorf("no good address to send precommit message from: %w", err)
 	}
 
-	mcid, err := b.api.MessagerSendMsg(b.mctx, from, b.maddr, miner.Methods.PreCommitSector, deposit, big.Int(b.feeCfg.MaxPreCommitGasFee), enc.Bytes())
+	mcid, err := b.api.MessagerSendMsg(b.mctx, from, b.maddr, miner.Methods.PreCommitSector, deposit, big.Int(b.feeCfg().MaxPreCommitGasFee), enc.Bytes())
 	if err != nil {
 		return "", xerrors.Errorf("pushing message to mpool: %w", err)
 	}
@@ -317,12 +648,13 @@ func (b *PreCommitBatcher) processSingle(cfg sealiface.Config, mi miner.MinerInf
 	return mcid, nil
 }
 
-func (b *PreCommitBatcher) processBatch(cfg sealiface.Config, tok types.TipSetToken, bf abi.TokenAmount, nv network.Version) ([]sealiface.PreCommitBatchRes, error) {
+func (b *PreCommitBatcher) processBatch(cfg sealiface.Config, sectors []abi.SectorNumber, tok types.TipSetToken, bf abi.TokenAmount, nv network.Version) ([]sealiface.PreCommitBatchRes, error) {
 	params := miner5.PreCommitSectorBatchParams{}
 	deposit := big.Zero()
 	var res sealiface.PreCommitBatchRes
 
-	for _, p := range b.todo {
+	for _, sn := range sectors {
+		p := b.todo[sn]
 		if len(params.Sectors) >= cfg.MaxPreCommitBatch {
 			log.Infow("precommit batch full")
 			break
@@ -343,7 +675,7 @@ func (b *PreCommitBatcher) processBatch(cfg sealiface.Config, tok types.TipSetTo
 		return []sealiface.PreCommitBatchRes{res}, xerrors.Errorf("couldn't get miner info: %w", err)
 	}
 
-	maxFee := b.feeCfg.MaxPreCommitBatchGasFee.FeeForSectors(len(params.Sectors))
+	maxFee := b.feeCfg().MaxPreCommitBatchGasFee.FeeForSectors(len(params.Sectors))
 
 	aggFeeRaw, err := policy.AggregatePreCommitNetworkFee(nv, len(params.Sectors), bf)
 	if err != nil {
@@ -389,11 +721,28 @@ func (b *PreCommitBatcher) AddPreCommit(ctx context.Context, s types.SectorInfo,
 	sn := s.SectorNumber
 
 	b.lk.Lock()
-	b.cutoffs[sn] = getPreCommitCutoff(curEpoch, s, abi.ChainEpoch(b.networkParams.BlockDelaySecs))
+	if r, ok := b.results[sn]; ok {
+		// sent in a batch restored after a restart
+		delete(b.results, sn)
+		b.lk.Unlock()
+		return r, nil
+	}
+
+	cutoff := getPreCommitCutoff(curEpoch, s, abi.ChainEpoch(b.networkParams.BlockDelaySecs))
+	if _, ok := b.restored[sn]; ok {
+		delete(b.restored, sn)
+		if restored := b.cutoffs[sn]; !restored.IsZero() && restored.Before(cutoff) {
+			cutoff = restored
+		}
+	}
+	b.cutoffs[sn] = cutoff
 	b.todo[sn] = &preCommitEntry{
 		deposit: deposit,
 		pci:     in,
 	}
+	if err := b.persist(sn); err != nil {
+		log.Errorw("persisting precommit batch entry", "sector", sn, "error", err)
+	}
 
 	sent := make(chan sealiface.PreCommitBatchRes, 1)
 	b.waiting[sn] = append(b.waiting[sn], sent)
@@ -455,6 +804,24 @@ func (b *PreCommitBatcher) Pending(ctx context.Context) ([]abi.SectorID, error)
 	return res, nil
 }
 
+// Queue returns the sectors in the batch queue with their cutoffs
+func (b *PreCommitBatcher) Queue() (types.BatchQueue, error) {
+	cfg, err := b.getConfig()
+	if err != nil {
+		return types.BatchQueue{}, xerrors.Errorf("getting config: %w", err)
+	}
+
+	b.lk.Lock()
+	defer b.lk.Unlock()
+
+	sectors := make([]abi.SectorNumber, 0, len(b.todo))
+	for sn := range b.todo {
+		sectors = append(sectors, sn)
+	}
+
+	return batchQueue(sectors, b.cutoffs, b.restored, cfg.PreCommitBatchSlack), nil
+}
+
 func (b *PreCommitBatcher) Stop(ctx context.Context) error {
 	close(b.stop)
 
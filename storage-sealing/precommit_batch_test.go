@@ -40,6 +40,10 @@ var fc = config.MinerFeeConfig{
 	MaxCommitBatchGasFee:    config.BatchFeeConfig{Base: types.FIL(types.FromFil(3)), PerSector: types.FIL(types.FromFil(1))},
 }
 
+func feeCfg() config.MinerFeeConfig {
+	return fc
+}
+
 func TestPrecommitBatcher(t *testing.T) {
 	t0123, err := address.NewFromString("t0123")
 	require.NoError(t, err)
@@ -267,7 +271,7 @@ func TestPrecommitBatcher(t *testing.T) {
 				UpgradeIgnitionHeight: 94000,
 				ForkLengthThreshold:   policy.ChainFinality,
 				BlockDelaySecs:        30,
-			}, t0123, pcapi, as, fc, cfg)
+			}, t0123, pcapi, as, feeCfg, cfg, nil)
 
 			var promises []promise
 
@@ -0,0 +1,162 @@
+package sealing
+
+import (
+	"context"
+
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-statemachine"
+
+	"github.com/filecoin-project/venus-sealer/storage-sealing/sealiface"
+	"github.com/filecoin-project/venus-sealer/types"
+)
+
+// RepackDealStatus is the venus-market deal status the RepackDeals recovery
+// action resets deals to, deals in it are handed out by GetUnPackedDeals again
+var RepackDealStatus = "Undefine"
+
+// failureCause names the check error for matching recovery playbooks, see sealiface.RecoveryStates
+func failureCause(err error) string {
+	switch err.(type) {
+	case *ErrBadCommD:
+		return "BadCommD"
+	case *ErrExpiredTicket:
+		return "ExpiredTicket"
+	case *ErrBadTicket:
+		return "BadTicket"
+	case *ErrInvalidDeals:
+		return "InvalidDeals"
+	case *ErrExpiredDeals:
+		return "ExpiredDeals"
+	case *ErrNoPrecommit:
+		return "NoPrecommit"
+	case *ErrBadSeed:
+		return "BadSeed"
+	case *ErrInvalidProof:
+		return "InvalidProof"
+	case *ErrCommitWaitFailed:
+		return "CommitWaitFailed"
+	default:
+		return ""
+	}
+}
+
+// countRecoveryAttempt is called when the sector enters a new state
+func countRecoveryAttempt(state *types.SectorInfo) {
+	state.RecoveryEscalated = false
+
+	if _, ok := sealiface.RecoveryStates[string(state.State)]; !ok {
+		return
+	}
+
+	if state.RecoveryAttempts == nil {
+		state.RecoveryAttempts = map[types.SectorState]uint64{}
+	}
+	state.RecoveryAttempts[state.State]++
+}
+
+// runRecoveryPlaybook handles the failure of the sector with the playbook of
+// its state. It returns false if the built-in handling of the state should run.
+func (m *Sealing) runRecoveryPlaybook(ctx statemachine.Context, sector types.SectorInfo, cause string) (bool, error) {
+	if m.getConfig == nil {
+		return false, nil // tests
+	}
+
+	cfg, err := m.getConfig()
+	if err != nil {
+		log.Errorw("getting sealing config, skipping recovery playbooks", "sector", sector.SectorNumber, "error", err)
+		return false, nil
+	}
+
+	playbook := sealiface.FindRecoveryPlaybook(cfg.RecoveryPlaybooks, string(sector.State), cause)
+	if playbook == nil {
+		return false, nil
+	}
+
+	attempt := sector.RecoveryAttempts[sector.State]
+	if attempt == 0 {
+		attempt = 1 // entered the state before attempts were counted
+	}
+
+	step, ok := playbook.Step(attempt)
+	if !ok {
+		if sector.RecoveryEscalated {
+			log.Warnw("recovery playbook used up, waiting for manual recovery", "sector", sector.SectorNumber, "state", sector.State)
+			return true, nil
+		}
+
+		log.Errorw("recovery playbook used up, the sector needs manual recovery", "sector", sector.SectorNumber, "state", sector.State, "cause", cause, "attempts", attempt)
+		return true, ctx.Send(SectorRecoveryEscalated{Cause: cause, Attempts: attempt})
+	}
+
+	log.Infow("running recovery playbook", "sector", sector.SectorNumber, "state", sector.State, "cause", cause, "attempt", attempt, "action", step.Action)
+
+	switch step.Action {
+	case sealiface.RecoveryRetry:
+		return false, nil
+	case sealiface.RecoveryRemove:
+		return true, ctx.Send(SectorRemove{})
+	case sealiface.RecoveryRepackDeals:
+		if err := m.repackDeals(ctx.Context(), sector, cfg); err != nil {
+			return true, xerrors.Errorf("repacking deals: %w", err)
+		}
+		return true, ctx.Send(SectorRemove{})
+	}
+
+	if err := m.failedCooldown(ctx, sector); err != nil {
+		return true, err
+	}
+
+	switch step.Action {
+	case sealiface.RecoveryRefetchUnsealed:
+		if err := m.sealer.RefetchUnsealed(ctx.Context(), m.minerSector(sector.SectorType, sector.SectorNumber)); err != nil {
+			log.Warnw("can't refetch the unsealed file, restarting PreCommit1 with the current copy", "sector", sector.SectorNumber, "error", err)
+		}
+		return true, ctx.Send(SectorRestartPreCommit1{})
+	case sealiface.RecoveryRestartPreCommit1:
+		return true, ctx.Send(SectorRestartPreCommit1{})
+	case sealiface.RecoveryRestartGetTicket:
+		return true, ctx.Send(SectorRestartGetTicket{})
+	default:
+		log.Errorw("unknown recovery action, running the built-in handling", "sector", sector.SectorNumber, "action", step.Action)
+		return false, nil
+	}
+}
+
+// repackDeals hands the deals of the sector which can still be sealed before
+// their start epoch back to venus-market, deal ingestion puts them into new sectors
+func (m *Sealing) repackDeals(ctx context.Context, sector types.SectorInfo, cfg sealiface.Config) error {
+	tok, height, err := m.api.ChainHead(ctx)
+	if err != nil {
+		return xerrors.Errorf("getting chain head: %w", err)
+	}
+	sealedBy := m.sealedByEpoch(height, cfg)
+
+	for _, p := range sector.Pieces {
+		if p.DealInfo == nil {
+			continue
+		}
+
+		proposal, err := m.api.StateMarketStorageDealProposal(ctx, p.DealInfo.DealID, tok)
+		if err != nil {
+			log.Warnw("not repacking deal, getting proposal failed", "sector", sector.SectorNumber, "deal", p.DealInfo.DealID, "error", err)
+			continue
+		}
+
+		if proposal.StartEpoch <= sealedBy {
+			log.Warnw("not repacking deal which can't be sealed before its start epoch", "sector", sector.SectorNumber, "deal", p.DealInfo.DealID, "start", proposal.StartEpoch, "sealedBy", sealedBy)
+			continue
+		}
+
+		if err := m.api.UpdateDealStatus(ctx, m.maddr, p.DealInfo.DealID, RepackDealStatus); err != nil {
+			return xerrors.Errorf("resetting status of deal %d: %w", p.DealInfo.DealID, err)
+		}
+		log.Infow("repacking deal", "sector", sector.SectorNumber, "deal", p.DealInfo.DealID)
+	}
+
+	if m.ingester != nil && cfg.AutoDealIngest {
+		m.ingester.Poke()
+	}
+
+	return nil
+}
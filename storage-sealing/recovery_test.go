@@ -0,0 +1,111 @@
+package sealing
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+
+	"github.com/filecoin-project/venus-sealer/storage-sealing/sealiface"
+	"github.com/filecoin-project/venus-sealer/types"
+)
+
+func TestRecoveryPlaybook(t *testing.T) {
+	playbooks := []sealiface.RecoveryPlaybook{
+		{State: "CommitFailed", Steps: []sealiface.RecoveryStep{{Action: sealiface.RecoveryRetry, Attempts: 2}}},
+		{State: "CommitFailed", Cause: "ExpiredTicket", Steps: []sealiface.RecoveryStep{{Action: sealiface.RecoveryRestartGetTicket, Attempts: 1}}},
+		{State: "SealPreCommit1Failed", Steps: []sealiface.RecoveryStep{
+			{Action: sealiface.RecoveryRetry, Attempts: 3},
+			{Action: sealiface.RecoveryRefetchUnsealed, Attempts: 1},
+		}},
+	}
+	for _, p := range playbooks {
+		require.NoError(t, p.Validate())
+	}
+
+	require.Equal(t, &playbooks[1], sealiface.FindRecoveryPlaybook(playbooks, "CommitFailed", "ExpiredTicket"))
+	require.Equal(t, &playbooks[0], sealiface.FindRecoveryPlaybook(playbooks, "CommitFailed", "InvalidProof"))
+	require.Nil(t, sealiface.FindRecoveryPlaybook(playbooks, "PreCommitFailed", ""))
+
+	pc1 := playbooks[2]
+	require.Equal(t, uint64(4), pc1.Attempts())
+	for attempt, action := range map[uint64]string{1: sealiface.RecoveryRetry, 3: sealiface.RecoveryRetry, 4: sealiface.RecoveryRefetchUnsealed} {
+		step, ok := pc1.Step(attempt)
+		require.True(t, ok)
+		require.Equal(t, action, step.Action, attempt)
+	}
+	_, ok := pc1.Step(5)
+	require.False(t, ok)
+
+	for _, p := range []sealiface.RecoveryPlaybook{
+		{State: "Proving", Steps: pc1.Steps},
+		{State: "SealPreCommit1Failed", Cause: "ExpiredDeals", Steps: pc1.Steps},
+		{State: "PreCommitFailed", Cause: "BadSeed", Steps: pc1.Steps},
+		{State: "PreCommitFailed"},
+		{State: "PreCommitFailed", Steps: []sealiface.RecoveryStep{{Action: "Pray", Attempts: 1}}},
+		{State: "PreCommitFailed", Steps: []sealiface.RecoveryStep{{Action: sealiface.RecoveryRemove}}},
+	} {
+		require.Error(t, p.Validate(), p)
+	}
+}
+
+func TestRecoveryEvents(t *testing.T) {
+	for state := range sealiface.RecoveryStates {
+		from := types.SectorState(state)
+		planner := fsmPlanners[from]
+		require.NotNil(t, planner, state)
+
+		to, ok := probePlanner(planner, from, SectorRestartPreCommit1{})
+		require.True(t, ok, state)
+		require.Equal(t, types.PreCommit1, to)
+
+		to, ok = probePlanner(planner, from, SectorRestartGetTicket{})
+		require.True(t, ok, state)
+		require.Equal(t, types.GetTicket, to)
+
+		to, ok = probePlanner(planner, from, SectorRecoveryEscalated{})
+		require.True(t, ok, state)
+		require.Equal(t, from, to)
+	}
+}
+
+func TestRecoveryAttempts(t *testing.T) {
+	ma, _ := address.NewIDAddress(55151)
+	m := test{
+		s: &Sealing{
+			maddr: ma,
+			stats: types.SectorStats{
+				BySector: map[abi.SectorID]types.StatSectorState{},
+			},
+			logService: newLogService(t),
+		},
+		t:     t,
+		state: &types.SectorInfo{State: types.PreCommit1},
+	}
+
+	m.planSingle(SectorSealPreCommit1Failed{xerrors.New("pc1 failed")})
+	require.Equal(t, uint64(1), m.state.RecoveryAttempts[types.SealPreCommit1Failed])
+
+	m.planSingle(SectorRestartPreCommit1{})
+	require.Equal(t, types.PreCommit1, m.state.State)
+
+	m.planSingle(SectorSealPreCommit1Failed{xerrors.New("pc1 failed")})
+	require.Equal(t, uint64(2), m.state.RecoveryAttempts[types.SealPreCommit1Failed])
+
+	m.planSingle(SectorRecoveryEscalated{Attempts: 2})
+	require.Equal(t, types.SealPreCommit1Failed, m.state.State)
+	require.True(t, m.state.RecoveryEscalated)
+
+	// restarting the fsm doesn't count as a new attempt
+	m.planSingle(SectorRestart{})
+	require.Equal(t, uint64(2), m.state.RecoveryAttempts[types.SealPreCommit1Failed])
+	require.True(t, m.state.RecoveryEscalated)
+
+	// manual recovery resets the playbooks
+	m.planSingle(SectorForceState{State: types.PreCommit1})
+	require.False(t, m.state.RecoveryEscalated)
+	require.Empty(t, m.state.RecoveryAttempts)
+}
@@ -51,7 +51,18 @@ type Config struct {
 	// sending precommit messages to the chain individually
 	BatchPreCommitAboveBaseFee abi.TokenAmount
 
+	BatchCostModel bool
+
 	TerminateBatchMax  uint64
 	TerminateBatchMin  uint64
 	TerminateBatchWait time.Duration
+
+	AutoDealIngest     bool
+	DealIngestInterval time.Duration
+	DealIngestBatch    int
+
+	PlanDealPacking      bool
+	ExpectedSealDuration time.Duration
+
+	RecoveryPlaybooks []RecoveryPlaybook
 }
@@ -0,0 +1,149 @@
+package sealiface
+
+import (
+	"golang.org/x/xerrors"
+)
+
+// Recovery actions
+const (
+	// RecoveryRetry runs the built-in handling of the failure state
+	RecoveryRetry = "Retry"
+	// RecoveryRestartPreCommit1 seals the sector again starting with PreCommit1
+	RecoveryRestartPreCommit1 = "RestartPreCommit1"
+	// RecoveryRefetchUnsealed drops the copies of the unsealed file in sealing
+	// paths, so PreCommit1 fetches it again from a storage path, and restarts PreCommit1
+	RecoveryRefetchUnsealed = "RefetchUnsealed"
+	// RecoveryRestartGetTicket seals the sector again with a new ticket
+	RecoveryRestartGetTicket = "RestartGetTicket"
+	// RecoveryRepackDeals hands deals which can still be sealed back to venus-market,
+	// so they are packed into new sectors, and removes the sector
+	RecoveryRepackDeals = "RepackDeals"
+	// RecoveryRemove removes the sector
+	RecoveryRemove = "Remove"
+)
+
+var recoveryActions = []string{
+	RecoveryRetry,
+	RecoveryRestartPreCommit1,
+	RecoveryRefetchUnsealed,
+	RecoveryRestartGetTicket,
+	RecoveryRepackDeals,
+	RecoveryRemove,
+}
+
+// RecoveryStates lists the failure states which can have a playbook, with the
+// causes the handler of the state tells apart
+var RecoveryStates = map[string][]string{
+	"SealPreCommit1Failed": nil,
+	"SealPreCommit2Failed": nil,
+	"ComputeProofFailed":   nil,
+	"PreCommitFailed":      {"BadCommD", "ExpiredTicket", "BadTicket", "InvalidDeals", "ExpiredDeals", "NoPrecommit"},
+	"CommitFailed":         {"BadSeed", "InvalidProof", "NoPrecommit", "ExpiredTicket", "InvalidDeals", "ExpiredDeals", "CommitWaitFailed"},
+}
+
+// RecoveryPlaybook replaces the built-in retry logic of a failure state, eg.
+//
+//	[[Sealing.RecoveryPlaybooks]]
+//	State = "SealPreCommit1Failed"
+//	Steps = [
+//	  { Action = "Retry", Attempts = 3 },
+//	  { Action = "RefetchUnsealed", Attempts = 1 },
+//	]
+//
+// The sector counts how often it entered each failure state. The first
+// Attempts of the first step are handled by that step, the following by the
+// next step and so on. Once all steps are used up the sector stays in the
+// failure state and the failure is escalated until it is moved to another
+// state by hand, which also resets the counters.
+type RecoveryPlaybook struct {
+	// State is the failure state the playbook handles, see RecoveryStates
+	State string
+	// Cause restricts the playbook to failures with this cause, eg. ExpiredDeals.
+	// A playbook for the cause is preferred over one without a cause.
+	Cause string
+	Steps []RecoveryStep
+}
+
+type RecoveryStep struct {
+	// Action is one of Retry, RestartPreCommit1, RefetchUnsealed, RestartGetTicket, RepackDeals, Remove
+	Action   string
+	Attempts uint64
+}
+
+// Step returns the step handling the n-th attempt, counting from 1, and false
+// when all steps are used up
+func (p RecoveryPlaybook) Step(attempt uint64) (RecoveryStep, bool) {
+	for _, s := range p.Steps {
+		if attempt <= s.Attempts {
+			return s, true
+		}
+		attempt -= s.Attempts
+	}
+	return RecoveryStep{}, false
+}
+
+// Attempts is the number of attempts before the failure is escalated
+func (p RecoveryPlaybook) Attempts() uint64 {
+	var out uint64
+	for _, s := range p.Steps {
+		out += s.Attempts
+	}
+	return out
+}
+
+func (p RecoveryPlaybook) Validate() error {
+	causes, ok := RecoveryStates[p.State]
+	if !ok {
+		return xerrors.Errorf("state %s can't have a recovery playbook", p.State)
+	}
+
+	if p.Cause != "" && !contains(causes, p.Cause) {
+		if len(causes) == 0 {
+			return xerrors.Errorf("state %s doesn't tell causes apart", p.State)
+		}
+		return xerrors.Errorf("unknown cause %s of %s, expected one of %v", p.Cause, p.State, causes)
+	}
+
+	if len(p.Steps) == 0 {
+		return xerrors.Errorf("no steps")
+	}
+
+	for i, s := range p.Steps {
+		if !contains(recoveryActions, s.Action) {
+			return xerrors.Errorf("step %d: unknown action %s, expected one of %v", i, s.Action, recoveryActions)
+		}
+		if s.Attempts == 0 {
+			return xerrors.Errorf("step %d: Attempts must be at least 1", i)
+		}
+	}
+
+	return nil
+}
+
+// FindRecoveryPlaybook returns the playbook for the failure, nil if there is none
+func FindRecoveryPlaybook(playbooks []RecoveryPlaybook, state, cause string) *RecoveryPlaybook {
+	var fallback *RecoveryPlaybook
+	for i := range playbooks {
+		p := &playbooks[i]
+		if p.State != state {
+			continue
+		}
+
+		if p.Cause == "" && fallback == nil {
+			fallback = p
+		}
+		if cause != "" && p.Cause == cause {
+			return p
+		}
+	}
+	return fallback
+}
+
+func contains(l []string, s string) bool {
+	for _, e := range l {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
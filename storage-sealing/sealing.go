@@ -73,6 +73,8 @@ type SealingAPI interface {
 	StateGetRandomnessFromBeacon(ctx context.Context, personalization crypto.DomainSeparationTag, randEpoch abi.ChainEpoch, entropy []byte, tok types2.TipSetToken) (abi.Randomness, error)
 	StateGetRandomnessFromTickets(ctx context.Context,personalization crypto.DomainSeparationTag, randEpoch abi.ChainEpoch, entropy []byte, tok types2.TipSetToken) (abi.Randomness, error)
 	ChainReadObj(context.Context, cid.Cid) ([]byte, error)
+	// GasEstimateGasLimit estimates the gas used by the message at the tipset
+	GasEstimateGasLimit(ctx context.Context, from, to address.Address, method abi.MethodNum, value abi.TokenAmount, params []byte, tok types2.TipSetToken) (int64, error)
 	
 	//for messager
 	MessagerWaitMsg(context.Context, string) (types2.MsgLookup, error)
@@ -83,6 +85,7 @@ type SealingAPI interface {
 	GetUnPackedDeals(ctx context.Context, miner address.Address, spec *piece.GetDealSpec) ([]*piece.DealInfoIncludePath, error)                                       //perm:read
 	MarkDealsAsPacking(ctx context.Context, miner address.Address, deals []abi.DealID) error                                                                          //perm:write
 	UpdateDealOnPacking(ctx context.Context, miner address.Address, pieceCID cid.Cid, dealId abi.DealID, sectorid abi.SectorNumber, offset abi.PaddedPieceSize) error //perm:write
+	UpdateDealStatus(ctx context.Context, miner address.Address, dealId abi.DealID, status string) error                                                              //perm:write
 }
 
 type SectorStateNotifee func(before, after types2.SectorInfo)
@@ -93,7 +96,7 @@ type Sealing struct {
 	api    SealingAPI
 	DealInfo *CurrentDealInfoManager
 
-	feeCfg config.MinerFeeConfig
+	feeCfg config.GetMinerFeeConfigFunc
 	events Events
 
 	startupWait sync.WaitGroup
@@ -125,6 +128,7 @@ type Sealing struct {
 	terminator  *TerminateBatcher
 	precommiter *PreCommitBatcher
 	commiter    *CommitBatcher
+	ingester    *DealIngester
 
 	getConfig types2.GetSealingConfigFunc
 
@@ -135,9 +139,23 @@ type Sealing struct {
 type openSector struct {
 	used abi.UnpaddedPieceSize // change to bitfield/rle when AddPiece gains offset support to better fill sectors
 
+	// deals in the sector, used by the packing planner
+	deals          int
+	minEnd, maxEnd abi.ChainEpoch
+
 	maybeAccept func(cid.Cid) error // called with inputLk
 }
 
+func (s *openSector) addDeal(end abi.ChainEpoch) {
+	if s.deals == 0 || end < s.minEnd {
+		s.minEnd = end
+	}
+	if s.deals == 0 || end > s.maxEnd {
+		s.maxEnd = end
+	}
+	s.deals++
+}
+
 type pendingPiece struct {
 	size abi.UnpaddedPieceSize
 	deal types2.PieceDealInfo
@@ -148,7 +166,7 @@ type pendingPiece struct {
 	accepted func(abi.SectorNumber, abi.UnpaddedPieceSize, error)
 }
 
-func New(mctx context.Context, api SealingAPI, fc config.MinerFeeConfig, events Events, maddr address.Address, metaDataService *service.MetadataService, sectorInfoService *service.SectorInfoService, logService *service.LogService, sealer sectorstorage.SectorManager, sc types2.SectorIDCounter, verif ffiwrapper.Verifier, prov ffiwrapper.Prover, pcp PreCommitPolicy, gc types2.GetSealingConfigFunc, notifee SectorStateNotifee, as AddrSel, networkParams *config.NetParamsConfig) *Sealing {
+func New(mctx context.Context, api SealingAPI, fc config.GetMinerFeeConfigFunc, events Events, maddr address.Address, metaDataService *service.MetadataService, sectorInfoService *service.SectorInfoService, logService *service.LogService, batchStore types2.BatchStore, dealStore types2.UnreportedDealStore, sealer sectorstorage.SectorManager, sc types2.SectorIDCounter, verif ffiwrapper.Verifier, prov ffiwrapper.Prover, pcp PreCommitPolicy, gc types2.GetSealingConfigFunc, notifee SectorStateNotifee, as AddrSel, networkParams *config.NetParamsConfig) *Sealing {
 	s := &Sealing{
 		api:    api,
 		DealInfo: &CurrentDealInfoManager{api},
@@ -172,9 +190,9 @@ func New(mctx context.Context, api SealingAPI, fc config.MinerFeeConfig, events
 		notifee: notifee,
 		addrSel: as,
 
-		terminator:  NewTerminationBatcher(mctx, maddr, api, as, fc),
-		precommiter: NewPreCommitBatcher(mctx, networkParams, maddr, api, as, fc, gc),
-		commiter:    NewCommitBatcher(mctx, networkParams, maddr, api, as, fc, gc, prov),
+		terminator:  NewTerminationBatcher(mctx, maddr, api, as, fc, batchStore),
+		precommiter: NewPreCommitBatcher(mctx, networkParams, maddr, api, as, fc, gc, batchStore),
+		commiter:    NewCommitBatcher(mctx, networkParams, maddr, api, as, fc, gc, prov, batchStore),
 
 		getConfig: gc,
 
@@ -183,6 +201,7 @@ func New(mctx context.Context, api SealingAPI, fc config.MinerFeeConfig, events
 		},
 	}
 	s.startupWait.Add(1)
+	s.ingester = newDealIngester(mctx, s, dealStore)
 
 	s.sectors = statemachine.New(sectorInfoService, s, types2.SectorInfo{})
 
@@ -203,6 +222,10 @@ func (m *Sealing) Stop(ctx context.Context) error {
 		return err
 	}
 
+	if err := m.ingester.Stop(ctx); err != nil {
+		return err
+	}
+
 	if err := m.sectors.Stop(ctx); err != nil {
 		return err
 	}
@@ -245,6 +268,37 @@ func (m *Sealing) CommitPending(ctx context.Context) ([]abi.SectorID, error) {
 	return m.commiter.Pending(ctx)
 }
 
+func (m *Sealing) BatchQueues(ctx context.Context) (types2.BatchQueues, error) {
+	var out types2.BatchQueues
+	var err error
+
+	if out.PreCommit, err = m.precommiter.Queue(); err != nil {
+		return types2.BatchQueues{}, xerrors.Errorf("getting precommit batch queue: %w", err)
+	}
+	if out.Commit, err = m.commiter.Queue(); err != nil {
+		return types2.BatchQueues{}, xerrors.Errorf("getting commit batch queue: %w", err)
+	}
+	if out.Terminate, err = m.terminator.Queue(); err != nil {
+		return types2.BatchQueues{}, xerrors.Errorf("getting terminate batch queue: %w", err)
+	}
+
+	return out, nil
+}
+
+func (m *Sealing) BatchExplain(ctx context.Context) (types2.BatchExplains, error) {
+	var out types2.BatchExplains
+	var err error
+
+	if out.PreCommit, err = m.precommiter.Explain(); err != nil {
+		return types2.BatchExplains{}, xerrors.Errorf("explaining precommit batch: %w", err)
+	}
+	if out.Commit, err = m.commiter.Explain(); err != nil {
+		return types2.BatchExplains{}, xerrors.Errorf("explaining commit batch: %w", err)
+	}
+
+	return out, nil
+}
+
 func (m *Sealing) currentSealProof(ctx context.Context) (abi.RegisteredSealProof, error) {
 	mi, err := m.api.StateMinerInfo(ctx, m.maddr, nil)
 	if err != nil {
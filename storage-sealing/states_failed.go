@@ -9,6 +9,7 @@ import (
 
 	"github.com/filecoin-project/venus/pkg/types/specactors/builtin/market"
 	"github.com/filecoin-project/venus/pkg/types/specactors/builtin/miner"
+	"github.com/filecoin-project/venus/pkg/types/specactors/policy"
 
 	"github.com/filecoin-project/go-commp-utils/zerocomm"
 	"github.com/filecoin-project/go-state-types/abi"
@@ -57,6 +58,10 @@ func (m *Sealing) checkPreCommitted(ctx statemachine.Context, sector types.Secto
 }
 
 func (m *Sealing) handleSealPrecommit1Failed(ctx statemachine.Context, sector types.SectorInfo) error {
+	if handled, err := m.runRecoveryPlaybook(ctx, sector, ""); handled {
+		return err
+	}
+
 	if err := m.failedCooldown(ctx, sector); err != nil {
 		return err
 	}
@@ -65,6 +70,10 @@ func (m *Sealing) handleSealPrecommit1Failed(ctx statemachine.Context, sector ty
 }
 
 func (m *Sealing) handleSealPrecommit2Failed(ctx statemachine.Context, sector types.SectorInfo) error {
+	if handled, err := m.runRecoveryPlaybook(ctx, sector, ""); handled {
+		return err
+	}
+
 	if err := m.failedCooldown(ctx, sector); err != nil {
 		return err
 	}
@@ -112,6 +121,12 @@ func (m *Sealing) handlePreCommitFailed(ctx statemachine.Context, sector types.S
 	}
 
 	if err := checkPrecommit(ctx.Context(), m.Address(), sector, tok, height, m.api); err != nil {
+		if cause := failureCause(err); cause != "" {
+			if handled, perr := m.runRecoveryPlaybook(ctx, sector, cause); handled {
+				return perr
+			}
+		}
+
 		switch err.(type) {
 		case *ErrApi:
 			log.Errorf("handlePreCommitFailed: api error, not proceeding: %+v", err)
@@ -162,6 +177,10 @@ func (m *Sealing) handlePreCommitFailed(ctx statemachine.Context, sector types.S
 		return ctx.Send(SectorRetryWaitSeed{})
 	}
 
+	if handled, err := m.runRecoveryPlaybook(ctx, sector, ""); handled {
+		return err
+	}
+
 	if len(sector.PreCommitMessage) > 0 {
 		log.Warn("retrying precommit even though the message failed to apply")
 	}
@@ -176,6 +195,10 @@ func (m *Sealing) handlePreCommitFailed(ctx statemachine.Context, sector types.S
 func (m *Sealing) handleComputeProofFailed(ctx statemachine.Context, sector types.SectorInfo) error {
 	// TODO: Check sector files
 
+	if handled, err := m.runRecoveryPlaybook(ctx, sector, ""); handled {
+		return err
+	}
+
 	if err := m.failedCooldown(ctx, sector); err != nil {
 		return err
 	}
@@ -188,7 +211,7 @@ func (m *Sealing) handleComputeProofFailed(ctx statemachine.Context, sector type
 }
 
 func (m *Sealing) handleCommitFailed(ctx statemachine.Context, sector types.SectorInfo) error {
-	tok, _, err := m.api.ChainHead(ctx.Context())
+	tok, height, err := m.api.ChainHead(ctx.Context())
 	if err != nil {
 		log.Errorf("handleCommitting: api error, not proceeding: %+v", err)
 		return nil
@@ -223,6 +246,17 @@ func (m *Sealing) handleCommitFailed(ctx statemachine.Context, sector types.Sect
 	}
 
 	if err := m.checkCommit(ctx.Context(), sector, sector.Proof, tok); err != nil {
+		cause := failureCause(err)
+		if _, ok := err.(*ErrNoPrecommit); ok && sector.TicketEpoch < height-policy.MaxPreCommitRandomnessLookback {
+			// the precommit expired together with the ticket
+			cause = "ExpiredTicket"
+		}
+		if cause != "" {
+			if handled, perr := m.runRecoveryPlaybook(ctx, sector, cause); handled {
+				return perr
+			}
+		}
+
 		switch err.(type) {
 		case *ErrApi:
 			log.Errorf("handleCommitFailed: api error, not proceeding: %+v", err)
@@ -263,6 +297,10 @@ func (m *Sealing) handleCommitFailed(ctx statemachine.Context, sector types.Sect
 
 	// TODO: Check sector files
 
+	if handled, err := m.runRecoveryPlaybook(ctx, sector, ""); handled {
+		return err
+	}
+
 	if err := m.failedCooldown(ctx, sector); err != nil {
 		return err
 	}
@@ -454,7 +454,7 @@ func (m *Sealing) handlePreCommitting(ctx statemachine.Context, sector types.Sec
 		return nil
 	}
 
-	goodFunds := big.Add(deposit, big.Int(m.feeCfg.MaxPreCommitGasFee))
+	goodFunds := big.Add(deposit, big.Int(m.feeCfg().MaxPreCommitGasFee))
 
 	from, _, err := m.addrSel(ctx.Context(), mi, api.PreCommitAddr, goodFunds, deposit)
 	if err != nil {
@@ -462,7 +462,7 @@ func (m *Sealing) handlePreCommitting(ctx statemachine.Context, sector types.Sec
 	}
 
 	log.Infof("submitting precommit for sector %d (deposit: %s): ", sector.SectorNumber, deposit)
-	uid, err := m.api.MessagerSendMsg(ctx.Context(), from, m.maddr, miner.Methods.PreCommitSector, deposit, big.Int(m.feeCfg.MaxPreCommitGasFee), enc.Bytes())
+	uid, err := m.api.MessagerSendMsg(ctx.Context(), from, m.maddr, miner.Methods.PreCommitSector, deposit, big.Int(m.feeCfg().MaxPreCommitGasFee), enc.Bytes())
 	if err != nil {
 		if params.ReplaceCapacity {
 			m.remarkForUpgrade(params.ReplaceSectorNumber)
@@ -760,7 +760,7 @@ func (m *Sealing) handleSubmitCommit(ctx statemachine.Context, sector types.Sect
 		return err
 	}
 
-	goodFunds := big.Add(collateral, big.Int(m.feeCfg.MaxCommitGasFee))
+	goodFunds := big.Add(collateral, big.Int(m.feeCfg().MaxCommitGasFee))
 
 	from, _, err := m.addrSel(ctx.Context(), mi, api.CommitAddr, goodFunds, collateral)
 	if err != nil {
@@ -768,7 +768,7 @@ func (m *Sealing) handleSubmitCommit(ctx statemachine.Context, sector types.Sect
 	}
 
 	// TODO: check seed / ticket / deals are up to date
-	uid, err := m.api.MessagerSendMsg(ctx.Context(), from, m.maddr, miner.Methods.ProveCommitSector, collateral, big.Int(m.feeCfg.MaxCommitGasFee), enc.Bytes())
+	uid, err := m.api.MessagerSendMsg(ctx.Context(), from, m.maddr, miner.Methods.ProveCommitSector, collateral, big.Int(m.feeCfg().MaxCommitGasFee), enc.Bytes())
 	if err != nil {
 		return ctx.Send(SectorCommitFailed{xerrors.Errorf("pushing message to mpool: %w", err)})
 	}
@@ -3,6 +3,7 @@ package sealing
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"sort"
 	"sync"
 	"time"
@@ -45,18 +46,28 @@ type TerminateBatcher struct {
 	maddr   address.Address
 	mctx    context.Context
 	addrSel AddrSel
-	feeCfg  config.MinerFeeConfig
+	feeCfg  config.GetMinerFeeConfigFunc
 
 	todo map[SectorLocation]*bitfield.BitField // MinerSectorLocation -> BitField
 
 	waiting map[abi.SectorNumber][]chan string
 
+	// messages of restored sectors sent before the state machine added them again
+	store    types.BatchStore
+	restored map[abi.SectorNumber]struct{}
+	results  map[abi.SectorNumber]string
+
+	// pruned is closed once the restored entries were checked against the
+	// sectors, restored entries aren't sent before that
+	pruned    chan struct{}
+	pruneOnce sync.Once
+
 	notify, stop, stopped chan struct{}
 	force                 chan chan string
 	lk                    sync.Mutex
 }
 
-func NewTerminationBatcher(mctx context.Context, maddr address.Address, api TerminateBatcherApi, addrSel AddrSel, feeCfg config.MinerFeeConfig) *TerminateBatcher {
+func NewTerminationBatcher(mctx context.Context, maddr address.Address, api TerminateBatcherApi, addrSel AddrSel, feeCfg config.GetMinerFeeConfigFunc, store types.BatchStore) *TerminateBatcher {
 	b := &TerminateBatcher{
 		api:     api,
 		maddr:   maddr,
@@ -67,18 +78,37 @@ func NewTerminationBatcher(mctx context.Context, maddr address.Address, api Term
 		todo:    map[SectorLocation]*bitfield.BitField{},
 		waiting: map[abi.SectorNumber][]chan string{},
 
+		store:    store,
+		restored: map[abi.SectorNumber]struct{}{},
+		results:  map[abi.SectorNumber]string{},
+
 		notify:  make(chan struct{}, 1),
 		force:   make(chan chan string),
 		stop:    make(chan struct{}),
+		pruned:  make(chan struct{}),
 		stopped: make(chan struct{}),
 	}
 
+	if err := b.restore(); err != nil {
+		log.Errorw("restoring terminate batch queue", "error", err)
+	}
+	if len(b.restored) == 0 {
+		b.release()
+	}
+
 	go b.run()
 
 	return b
 }
 
 func (b *TerminateBatcher) run() {
+	select {
+	case <-b.pruned:
+	case <-b.stop:
+		close(b.stopped)
+		return
+	}
+
 	var forceRes chan string
 	var lastMsg string
 
@@ -214,12 +244,12 @@ func (b *TerminateBatcher) processBatch(notif, after bool) (string, error) {
 		return "", xerrors.Errorf("couldn't get miner info: %w", err)
 	}
 
-	from, _, err := b.addrSel(b.mctx, mi, api.TerminateSectorsAddr, big.Int(b.feeCfg.MaxTerminateGasFee), big.Int(b.feeCfg.MaxTerminateGasFee))
+	from, _, err := b.addrSel(b.mctx, mi, api.TerminateSectorsAddr, big.Int(b.feeCfg().MaxTerminateGasFee), big.Int(b.feeCfg().MaxTerminateGasFee))
 	if err != nil {
 		return "", xerrors.Errorf("no good address found: %w", err)
 	}
 
-	mcid, err := b.api.MessagerSendMsg(b.mctx, from, b.maddr, miner.Methods.TerminateSectors, big.Zero(), big.Int(b.feeCfg.MaxTerminateGasFee), enc.Bytes())
+	mcid, err := b.api.MessagerSendMsg(b.mctx, from, b.maddr, miner.Methods.TerminateSectors, big.Zero(), big.Int(b.feeCfg().MaxTerminateGasFee), enc.Bytes())
 	if err != nil {
 		return "", xerrors.Errorf("sending message failed: %w", err)
 	}
@@ -231,22 +261,137 @@ func (b *TerminateBatcher) processBatch(notif, after bool) (string, error) {
 			Partition: t.Partition,
 		})
 
+		var done []abi.SectorNumber
 		err := t.Sectors.ForEach(func(sn uint64) error {
+			if len(b.waiting[abi.SectorNumber(sn)]) == 0 {
+				// restored sector, the state machine picks the message up when it adds the sector again
+				b.results[abi.SectorNumber(sn)] = mcid
+			}
 			for _, ch := range b.waiting[abi.SectorNumber(sn)] {
 				ch <- mcid // buffered
 			}
 			delete(b.waiting, abi.SectorNumber(sn))
+			delete(b.restored, abi.SectorNumber(sn))
+			done = append(done, abi.SectorNumber(sn))
 
 			return nil
 		})
 		if err != nil {
 			return "", xerrors.Errorf("sectors foreach: %w", err)
 		}
+
+		if err := b.persistDone(done); err != nil {
+			log.Errorw("removing sectors from persisted terminate batch queue", "sectors", done, "error", err)
+		}
 	}
 
 	return mcid, nil
 }
 
+// restore loads the batch queue persisted before the last shutdown
+func (b *TerminateBatcher) restore() error {
+	if b.store == nil {
+		return nil
+	}
+
+	entries, err := b.store.ListBatchEntries(types.BatcherTerminate)
+	if err != nil {
+		return xerrors.Errorf("listing batch entries: %w", err)
+	}
+
+	for _, e := range entries {
+		var loc SectorLocation
+		if err := json.Unmarshal(e.Data, &loc); err != nil {
+			log.Errorw("decoding persisted terminate batch entry", "sector", e.SectorNumber, "error", err)
+			continue
+		}
+
+		bf, ok := b.todo[loc]
+		if !ok {
+			n := bitfield.New()
+			bf = &n
+			b.todo[loc] = bf
+		}
+		bf.Set(uint64(e.SectorNumber))
+		b.restored[e.SectorNumber] = struct{}{}
+	}
+
+	if len(entries) > 0 {
+		log.Infow("restored terminate batch queue", "sectors", len(b.restored))
+	}
+
+	return nil
+}
+
+// prune drops the restored sectors the state machine won't add again, and the
+// results nobody is going to pick up, live are the sectors still being terminated. A nil
+// live means the sectors couldn't be listed, the restored entries are then only
+// dropped from memory and restored again on the next start
+func (b *TerminateBatcher) prune(live map[abi.SectorNumber]struct{}) {
+	b.lk.Lock()
+	defer b.lk.Unlock()
+	defer b.release()
+
+	var drop []abi.SectorNumber
+	for sn := range b.restored {
+		if _, ok := live[sn]; ok {
+			continue
+		}
+		for loc, bf := range b.todo {
+			bf.Unset(uint64(sn))
+			if n, err := bf.Count(); err == nil && n == 0 {
+				delete(b.todo, loc)
+			}
+		}
+		delete(b.restored, sn)
+		drop = append(drop, sn)
+	}
+	for sn := range b.results {
+		if _, ok := live[sn]; !ok {
+			delete(b.results, sn)
+		}
+	}
+
+	if len(drop) == 0 || live == nil {
+		return
+	}
+	log.Infow("dropping restored terminate batch entries of sectors no longer terminating", "sectors", drop)
+	if err := b.persistDone(drop); err != nil {
+		log.Errorw("removing sectors from persisted terminate batch queue", "sectors", drop, "error", err)
+	}
+}
+
+// release lets run send the queued sectors
+func (b *TerminateBatcher) release() {
+	b.pruneOnce.Do(func() {
+		close(b.pruned)
+	})
+}
+
+func (b *TerminateBatcher) persist(sn abi.SectorNumber, loc SectorLocation) error {
+	if b.store == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(&loc)
+	if err != nil {
+		return xerrors.Errorf("encoding batch entry: %w", err)
+	}
+
+	return b.store.SaveBatchEntry(types.BatcherTerminate, &types.BatchEntry{
+		SectorNumber: sn,
+		Data:         data,
+	})
+}
+
+func (b *TerminateBatcher) persistDone(sectors []abi.SectorNumber) error {
+	if b.store == nil {
+		return nil
+	}
+
+	return b.store.DeleteBatchEntries(types.BatcherTerminate, sectors)
+}
+
 // register termination, wait for batch message, return message CID
 // can return cid.Undef,true if the sector is already terminated on-chain
 func (b *TerminateBatcher) AddTermination(ctx context.Context, s abi.SectorID) (mcid string, terminated bool, err error) {
@@ -275,11 +420,22 @@ func (b *TerminateBatcher) AddTermination(ctx context.Context, s abi.SectorID) (
 		}
 		if !live {
 			// already terminated
+			b.lk.Lock()
+			delete(b.results, s.Number)
+			b.lk.Unlock()
 			return "", true, nil
 		}
 	}
 
 	b.lk.Lock()
+	if mcid, ok := b.results[s.Number]; ok {
+		// sent in a batch restored after a restart
+		delete(b.results, s.Number)
+		b.lk.Unlock()
+		return mcid, false, nil
+	}
+	delete(b.restored, s.Number)
+
 	bf, ok := b.todo[*loc]
 	if !ok {
 		n := bitfield.New()
@@ -287,6 +443,9 @@ func (b *TerminateBatcher) AddTermination(ctx context.Context, s abi.SectorID) (
 		b.todo[*loc] = bf
 	}
 	bf.Set(uint64(s.Number))
+	if err := b.persist(s.Number, *loc); err != nil {
+		log.Errorw("persisting terminate batch entry", "sector", s.Number, "error", err)
+	}
 
 	sent := make(chan string, 1)
 	b.waiting[s.Number] = append(b.waiting[s.Number], sent)
@@ -354,6 +513,25 @@ func (b *TerminateBatcher) Pending(ctx context.Context) ([]abi.SectorID, error)
 	return res, nil
 }
 
+// Queue returns the sectors in the batch queue, terminations have no cutoffs
+func (b *TerminateBatcher) Queue() (types.BatchQueue, error) {
+	b.lk.Lock()
+	defer b.lk.Unlock()
+
+	var sectors []abi.SectorNumber
+	for _, bf := range b.todo {
+		err := bf.ForEach(func(id uint64) error {
+			sectors = append(sectors, abi.SectorNumber(id))
+			return nil
+		})
+		if err != nil {
+			return types.BatchQueue{}, err
+		}
+	}
+
+	return batchQueue(sectors, nil, b.restored, 0), nil
+}
+
 func (b *TerminateBatcher) Stop(ctx context.Context) error {
 	close(b.stop)
 
@@ -392,6 +392,26 @@ func (s SealingAPIAdapter) ChainBaseFee(ctx context.Context, tok types2.TipSetTo
 	return ts.Blocks()[0].ParentBaseFee, nil
 }
 
+func (s SealingAPIAdapter) GasEstimateGasLimit(ctx context.Context, from, to address.Address, method abi.MethodNum, value abi.TokenAmount, params []byte, tok types2.TipSetToken) (int64, error) {
+	tsk, err := types.TipSetKeyFromBytes(tok)
+	if err != nil {
+		return 0, xerrors.Errorf("failed to unmarshal TipSetToken to TipSetKey: %w", err)
+	}
+
+	msg, err := s.delegate.GasEstimateMessageGas(ctx, &types.Message{
+		From:   from,
+		To:     to,
+		Method: method,
+		Value:  value,
+		Params: params,
+	}, nil, tsk)
+	if err != nil {
+		return 0, err
+	}
+
+	return msg.GasLimit, nil
+}
+
 func (s SealingAPIAdapter) ChainGetMessage(ctx context.Context, mc cid.Cid) (*types.Message, error) {
 	return s.delegate.ChainGetMessage(ctx, mc)
 }
@@ -480,6 +500,10 @@ func (s SealingAPIAdapter) MarkDealsAsPacking(ctx context.Context, miner address
 	return s.marketAPI.MarkDealsAsPacking(ctx, miner, deals)
 }
 
+func (s SealingAPIAdapter) UpdateDealStatus(ctx context.Context, miner address.Address, dealId abi.DealID, status string) error {
+	return s.marketAPI.UpdateDealStatus(ctx, miner, dealId, status)
+}
+
 func (s SealingAPIAdapter) UpdateDealOnPacking(ctx context.Context, miner address.Address, pieceCID cid.Cid, dealId abi.DealID, sectorid abi.SectorNumber, offset abi.PaddedPieceSize) error {
 	return s.marketAPI.UpdateDealOnPacking(ctx, miner, pieceCID, dealId, sectorid, offset)
 }
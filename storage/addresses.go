@@ -2,6 +2,7 @@ package storage
 
 import (
 	"context"
+	"sync"
 
 	"github.com/filecoin-project/go-address"
 	"github.com/filecoin-project/go-state-types/abi"
@@ -23,26 +24,44 @@ type addrSelectApi interface {
 }
 
 type AddressSelector struct {
-	api.AddressConfig
+	lk  sync.RWMutex
+	cfg api.AddressConfig
+}
+
+func (as *AddressSelector) Config() api.AddressConfig {
+	as.lk.RLock()
+	defer as.lk.RUnlock()
+
+	return as.cfg
+}
+
+// SetConfig replaces the address config, messages being sent keep the addresses already picked
+func (as *AddressSelector) SetConfig(cfg api.AddressConfig) {
+	as.lk.Lock()
+	defer as.lk.Unlock()
+
+	as.cfg = cfg
 }
 
 func (as *AddressSelector) AddressFor(ctx context.Context, a addrSelectApi, am addrMessager, mi miner.MinerInfo, use api.AddrUse, goodFunds, minFunds abi.TokenAmount) (address.Address, abi.TokenAmount, error) {
+	cfg := as.Config()
+
 	var addrs []address.Address
 	switch use {
 	case api.PreCommitAddr:
-		for _, addr := range as.PreCommitControl {
+		for _, addr := range cfg.PreCommitControl {
 			if addr.String() != mi.Worker.String() && addr.String() != mi.Owner.String() {
 				addrs = append(addrs, addr)
 			}
 		}
 	case api.CommitAddr:
-		for _, addr := range as.CommitControl {
+		for _, addr := range cfg.CommitControl {
 			if addr.String() != mi.Worker.String() && addr.String() != mi.Owner.String() {
 				addrs = append(addrs, addr)
 			}
 		}
 	case api.TerminateSectorsAddr:
-		for _, addr := range as.TerminateControl {
+		for _, addr := range cfg.TerminateControl {
 			if addr.String() != mi.Worker.String() && addr.String() != mi.Owner.String() {
 				addrs = append(addrs, addr)
 			}
@@ -55,9 +74,9 @@ func (as *AddressSelector) AddressFor(ctx context.Context, a addrSelectApi, am a
 		delete(defaultCtl, mi.Owner)
 		delete(defaultCtl, mi.Worker)
 
-		configCtl := append([]address.Address{}, as.PreCommitControl...)
-		configCtl = append(configCtl, as.CommitControl...)
-		configCtl = append(configCtl, as.TerminateControl...)
+		configCtl := append([]address.Address{}, cfg.PreCommitControl...)
+		configCtl = append(configCtl, cfg.CommitControl...)
+		configCtl = append(configCtl, cfg.TerminateControl...)
 
 		for _, addr := range configCtl {
 			if addr.Protocol() != address.ID {
@@ -76,10 +95,10 @@ func (as *AddressSelector) AddressFor(ctx context.Context, a addrSelectApi, am a
 			addrs = append(addrs, a)
 		}
 	}
-	if !as.DisableOwnerFallback {
+	if !cfg.DisableOwnerFallback {
 		addrs = append(addrs, mi.Owner)
 	}
-	if !as.DisableOwnerFallback {
+	if !cfg.DisableOwnerFallback {
 		addrs = append(addrs, mi.Worker)
 	}
 	log.Infof("pick address: %v", addrs)
@@ -0,0 +1,282 @@
+package storage
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/specs-storage/storage"
+
+	"github.com/filecoin-project/venus/pkg/types"
+	"github.com/filecoin-project/venus/pkg/types/specactors/builtin/miner"
+
+	"github.com/filecoin-project/venus-sealer/config"
+	sectorstorage "github.com/filecoin-project/venus-sealer/sector-storage"
+	"github.com/filecoin-project/venus-sealer/service"
+	types2 "github.com/filecoin-project/venus-sealer/types"
+)
+
+type integrityAuditAPI interface {
+	StateMinerActiveSectors(context.Context, address.Address, types.TipSetKey) ([]*miner.SectorOnChainInfo, error)
+	StateSectorGetInfo(context.Context, address.Address, abi.SectorNumber, types.TipSetKey) (*miner.SectorOnChainInfo, error)
+	StateSectorPreCommitInfo(context.Context, address.Address, abi.SectorNumber, types.TipSetKey) (miner.SectorPreCommitOnChainInfo, error)
+}
+
+// IntegrityAuditor checks sealed replicas against the CommR they were
+// precommitted with and records the results. Sectors are checked on request,
+// or in the background one at a time, pausing IntegrityAuditConfig.Interval
+// between two checks.
+type IntegrityAuditor struct {
+	api     integrityAuditAPI
+	maddr   address.Address
+	checker sectorstorage.IntegrityChecker
+	results *service.IntegrityService
+	live    *config.Live
+
+	// checkLk allows a single check at a time, so requested checks don't add
+	// to the reads of the background ones
+	checkLk sync.Mutex
+
+	lk     sync.Mutex
+	queue  []abi.SectorNumber
+	queued map[abi.SectorNumber]struct{}
+	wake   chan struct{}
+}
+
+func NewIntegrityAuditor(api integrityAuditAPI, maddr address.Address, checker sectorstorage.IntegrityChecker, results *service.IntegrityService, live *config.Live) *IntegrityAuditor {
+	return &IntegrityAuditor{
+		api:     api,
+		maddr:   maddr,
+		checker: checker,
+		results: results,
+		live:    live,
+
+		queued: map[abi.SectorNumber]struct{}{},
+		wake:   make(chan struct{}, 1),
+	}
+}
+
+// Verify checks the sectors right away, all active sectors if none are given.
+// Rounds defaults to IntegrityAuditConfig.Rounds.
+func (a *IntegrityAuditor) Verify(ctx context.Context, sectors []abi.SectorNumber, rounds int) ([]types2.SectorIntegrity, error) {
+	if rounds <= 0 {
+		rounds = a.config().Rounds
+	}
+
+	sectors, err := a.sectorsOrActive(ctx, sectors)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]types2.SectorIntegrity, 0, len(sectors))
+	for _, sn := range sectors {
+		res, err := a.check(ctx, sn, rounds)
+		if err != nil {
+			return nil, xerrors.Errorf("checking sector %d: %w", sn, err)
+		}
+		out = append(out, *res)
+	}
+
+	return out, nil
+}
+
+// Enqueue queues the sectors for the background checks, all active sectors
+// if none are given. It returns how many sectors weren't queued yet.
+func (a *IntegrityAuditor) Enqueue(ctx context.Context, sectors []abi.SectorNumber) (int, error) {
+	sectors, err := a.sectorsOrActive(ctx, sectors)
+	if err != nil {
+		return 0, err
+	}
+
+	a.lk.Lock()
+	defer a.lk.Unlock()
+
+	var added int
+	for _, sn := range sectors {
+		if _, ok := a.queued[sn]; ok {
+			continue
+		}
+		a.queued[sn] = struct{}{}
+		a.queue = append(a.queue, sn)
+		added++
+	}
+
+	select {
+	case a.wake <- struct{}{}:
+	default:
+	}
+
+	return added, nil
+}
+
+// Results returns the last check of every checked sector
+func (a *IntegrityAuditor) Results() ([]*types2.SectorIntegrity, error) {
+	return a.results.List()
+}
+
+func (a *IntegrityAuditor) Run(ctx context.Context) {
+	for {
+		cfg := a.config()
+
+		sn, found, err := a.next(ctx, cfg.Enable)
+		if err != nil {
+			log.Warnw("picking sector for integrity check", "error", err)
+		}
+		if found {
+			if _, err := a.check(ctx, sn, cfg.Rounds); err != nil {
+				log.Warnw("checking sector integrity", "sector", sn, "error", err)
+			}
+		}
+
+		// keep the pace after a check, queueing sectors only wakes an idle auditor
+		wake := a.wake
+		if found {
+			wake = nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-wake:
+		case <-time.After(time.Duration(cfg.Interval)):
+		}
+	}
+}
+
+func (a *IntegrityAuditor) config() config.IntegrityAuditConfig {
+	var cfg config.IntegrityAuditConfig
+	a.live.Read(func(c *config.StorageMiner) {
+		cfg = c.IntegrityAudit
+	})
+	return cfg
+}
+
+// next picks the next queued sector, or when sweeping the active sector
+// checked the longest time ago
+func (a *IntegrityAuditor) next(ctx context.Context, sweep bool) (abi.SectorNumber, bool, error) {
+	a.lk.Lock()
+	if len(a.queue) > 0 {
+		sn := a.queue[0]
+		a.queue = a.queue[1:]
+		delete(a.queued, sn)
+		a.lk.Unlock()
+		return sn, true, nil
+	}
+	a.lk.Unlock()
+
+	if !sweep {
+		return 0, false, nil
+	}
+
+	active, err := a.sectorsOrActive(ctx, nil)
+	if err != nil {
+		return 0, false, err
+	}
+	if len(active) == 0 {
+		return 0, false, nil
+	}
+
+	results, err := a.results.List()
+	if err != nil {
+		return 0, false, xerrors.Errorf("listing integrity results: %w", err)
+	}
+	checked := make(map[abi.SectorNumber]time.Time, len(results))
+	for _, res := range results {
+		checked[res.SectorNumber] = res.Checked
+	}
+
+	oldest := active[0]
+	for _, sn := range active[1:] {
+		if checked[sn].Before(checked[oldest]) {
+			oldest = sn
+		}
+	}
+
+	return oldest, true, nil
+}
+
+func (a *IntegrityAuditor) sectorsOrActive(ctx context.Context, sectors []abi.SectorNumber) ([]abi.SectorNumber, error) {
+	if len(sectors) > 0 {
+		return sectors, nil
+	}
+
+	active, err := a.api.StateMinerActiveSectors(ctx, a.maddr, types.EmptyTSK)
+	if err != nil {
+		return nil, xerrors.Errorf("getting active sectors: %w", err)
+	}
+
+	out := make([]abi.SectorNumber, 0, len(active))
+	for _, info := range active {
+		out = append(out, info.SectorNumber)
+	}
+	return out, nil
+}
+
+// check checks the sealed replica of the sector and records the result. An
+// error is returned only if the check couldn't run, a replica that doesn't
+// match is recorded as a failed check.
+func (a *IntegrityAuditor) check(ctx context.Context, sn abi.SectorNumber, rounds int) (*types2.SectorIntegrity, error) {
+	a.checkLk.Lock()
+	defer a.checkLk.Unlock()
+
+	sector, commr, err := a.sealedCID(ctx, sn)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &types2.SectorIntegrity{
+		SectorNumber: sn,
+		CommR:        commr,
+	}
+
+	if err := a.checker.CheckSealedIntegrity(ctx, sector, commr, rounds); err != nil {
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		log.Errorw("sealed replica doesn't match its CommR", "sector", sn, "commR", commr, "error", err)
+		res.Error = err.Error()
+	}
+	res.Checked = time.Now()
+
+	if err := a.results.Save(res); err != nil {
+		return nil, xerrors.Errorf("saving integrity result: %w", err)
+	}
+
+	return res, nil
+}
+
+// sealedCID returns the CommR the sector was precommitted with
+func (a *IntegrityAuditor) sealedCID(ctx context.Context, sn abi.SectorNumber) (storage.SectorRef, cid.Cid, error) {
+	mid, err := address.IDFromAddress(a.maddr)
+	if err != nil {
+		return storage.SectorRef{}, cid.Undef, err
+	}
+
+	sector := storage.SectorRef{
+		ID: abi.SectorID{
+			Miner:  abi.ActorID(mid),
+			Number: sn,
+		},
+	}
+
+	si, err := a.api.StateSectorGetInfo(ctx, a.maddr, sn, types.EmptyTSK)
+	if err != nil {
+		return storage.SectorRef{}, cid.Undef, xerrors.Errorf("getting sector info: %w", err)
+	}
+	if si != nil {
+		sector.ProofType = si.SealProof
+		return sector, si.SealedCID, nil
+	}
+
+	pci, err := a.api.StateSectorPreCommitInfo(ctx, a.maddr, sn, types.EmptyTSK)
+	if err != nil {
+		return storage.SectorRef{}, cid.Undef, xerrors.Errorf("sector %d isn't active or precommitted: %w", sn, err)
+	}
+	sector.ProofType = pci.Info.SealProof
+	return sector, pci.Info.SealedCID, nil
+}
@@ -0,0 +1,170 @@
+package storage
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/xerrors"
+
+	"github.com/ipfs/go-cid"
+
+	"github.com/filecoin-project/go-address"
+	commcid "github.com/filecoin-project/go-fil-commcid"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/specs-storage/storage"
+
+	"github.com/filecoin-project/venus/pkg/types"
+	"github.com/filecoin-project/venus/pkg/types/specactors/builtin/miner"
+
+	"github.com/filecoin-project/venus-sealer/config"
+	"github.com/filecoin-project/venus-sealer/service"
+	types2 "github.com/filecoin-project/venus-sealer/types"
+)
+
+type mockIntegrityAPI struct {
+	active []*miner.SectorOnChainInfo
+}
+
+func (m *mockIntegrityAPI) StateMinerActiveSectors(context.Context, address.Address, types.TipSetKey) ([]*miner.SectorOnChainInfo, error) {
+	return m.active, nil
+}
+
+func (m *mockIntegrityAPI) StateSectorGetInfo(ctx context.Context, maddr address.Address, sn abi.SectorNumber, tsk types.TipSetKey) (*miner.SectorOnChainInfo, error) {
+	for _, si := range m.active {
+		if si.SectorNumber == sn {
+			return si, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *mockIntegrityAPI) StateSectorPreCommitInfo(context.Context, address.Address, abi.SectorNumber, types.TipSetKey) (miner.SectorPreCommitOnChainInfo, error) {
+	return miner.SectorPreCommitOnChainInfo{}, xerrors.Errorf("precommit not found")
+}
+
+type mockIntegrityChecker struct {
+	bad map[abi.SectorNumber]bool
+}
+
+func (m *mockIntegrityChecker) CheckSealedIntegrity(ctx context.Context, sector storage.SectorRef, commr cid.Cid, rounds int) error {
+	if m.bad[sector.ID.Number] {
+		return xerrors.Errorf("generating vanilla proof: bad tree")
+	}
+	return nil
+}
+
+type mockIntegrityRepo struct {
+	lk      sync.Mutex
+	results map[abi.SectorNumber]*types2.SectorIntegrity
+}
+
+func (m *mockIntegrityRepo) Save(result *types2.SectorIntegrity) error {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+	m.results[result.SectorNumber] = result
+	return nil
+}
+
+func (m *mockIntegrityRepo) Get(sector abi.SectorNumber) (*types2.SectorIntegrity, error) {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+	return m.results[sector], nil
+}
+
+func (m *mockIntegrityRepo) List() ([]*types2.SectorIntegrity, error) {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+	var out []*types2.SectorIntegrity
+	for _, res := range m.results {
+		out = append(out, res)
+	}
+	return out, nil
+}
+
+func newTestIntegrityAuditor(t *testing.T, bad map[abi.SectorNumber]bool, sectors ...abi.SectorNumber) (*IntegrityAuditor, *mockIntegrityRepo) {
+	maddr, err := address.NewIDAddress(1000)
+	require.NoError(t, err)
+
+	commr, err := commcid.ReplicaCommitmentV1ToCID(make([]byte, 32))
+	require.NoError(t, err)
+
+	api := &mockIntegrityAPI{}
+	for _, sn := range sectors {
+		api.active = append(api.active, &miner.SectorOnChainInfo{
+			SectorNumber: sn,
+			SealProof:    abi.RegisteredSealProof_StackedDrg2KiBV1_1,
+			SealedCID:    commr,
+		})
+	}
+
+	repo := &mockIntegrityRepo{results: map[abi.SectorNumber]*types2.SectorIntegrity{}}
+	live := config.NewLive(config.DefaultMainnetStorageMiner())
+
+	return NewIntegrityAuditor(api, maddr, &mockIntegrityChecker{bad: bad}, &service.IntegrityService{IntegrityRepo: repo}, live), repo
+}
+
+func TestIntegrityAuditorVerify(t *testing.T) {
+	ctx := context.Background()
+	ia, repo := newTestIntegrityAuditor(t, map[abi.SectorNumber]bool{2: true}, 1, 2, 3)
+
+	// no sectors checks all active sectors
+	results, err := ia.Verify(ctx, nil, 0)
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+	for _, res := range results {
+		require.Equal(t, res.SectorNumber != 2, res.OK(), "sector %d", res.SectorNumber)
+	}
+
+	res, err := repo.Get(2)
+	require.NoError(t, err)
+	require.False(t, res.OK())
+
+	// sectors which aren't precommitted can't be checked
+	_, err = ia.Verify(ctx, []abi.SectorNumber{4}, 1)
+	require.Error(t, err)
+}
+
+func TestIntegrityAuditorNext(t *testing.T) {
+	ctx := context.Background()
+	ia, repo := newTestIntegrityAuditor(t, nil, 1, 2, 3)
+
+	// nothing to check without queued sectors or the sweep
+	_, found, err := ia.next(ctx, false)
+	require.NoError(t, err)
+	require.False(t, found)
+
+	added, err := ia.Enqueue(ctx, []abi.SectorNumber{3, 1, 3})
+	require.NoError(t, err)
+	require.Equal(t, 2, added)
+
+	added, err = ia.Enqueue(ctx, []abi.SectorNumber{1})
+	require.NoError(t, err)
+	require.Equal(t, 0, added)
+
+	for _, expect := range []abi.SectorNumber{3, 1} {
+		sn, found, err := ia.next(ctx, false)
+		require.NoError(t, err)
+		require.True(t, found)
+		require.Equal(t, expect, sn)
+	}
+
+	// the sweep picks never checked sectors, then the least recently checked
+	now := time.Now()
+	require.NoError(t, repo.Save(&types2.SectorIntegrity{SectorNumber: 1, Checked: now.Add(-time.Hour)}))
+	require.NoError(t, repo.Save(&types2.SectorIntegrity{SectorNumber: 3, Checked: now.Add(-2 * time.Hour)}))
+
+	sn, found, err := ia.next(ctx, true)
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Equal(t, abi.SectorNumber(2), sn)
+
+	require.NoError(t, repo.Save(&types2.SectorIntegrity{SectorNumber: 2, Checked: now}))
+
+	sn, found, err = ia.next(ctx, true)
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Equal(t, abi.SectorNumber(3), sn)
+}
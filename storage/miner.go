@@ -56,10 +56,12 @@ type Miner struct {
 	metadataService   *service.MetadataService
 	sectorInfoService *service.SectorInfoService
 	logService        *service.LogService
+	batchService      *service.BatchService
+	dealService       *service.UnreportedDealService
 	networkParams     *config.NetParamsConfig
 
 	api    fullNodeFilteredAPI
-	feeCfg config.MinerFeeConfig
+	feeCfg config.GetMinerFeeConfigFunc
 	sealer sectorstorage.SectorManager
 
 	sc      types2.SectorIDCounter
@@ -72,7 +74,8 @@ type Miner struct {
 	getSealConfig types2.GetSealingConfigFunc
 	sealing       *sealing.Sealing
 
-	sealingEvtType journal.EventType
+	sealingEvtType  journal.EventType
+	recoveryEvtType journal.EventType
 
 	journal journal.Journal
 }
@@ -86,6 +89,15 @@ type SealingStateEvt struct {
 	Error        string
 }
 
+// RecoveryEscalatedEvt is a journal event that records a failed sector which
+// used up its recovery playbook and needs manual recovery.
+type RecoveryEscalatedEvt struct {
+	SectorNumber abi.SectorNumber
+	State        types2.SectorState
+	Attempts     uint64
+	Error        string
+}
+
 // fullNodeFilteredAPI is the subset of the full node API the Miner needs from
 // a Lotus full node.
 type fullNodeFilteredAPI interface {
@@ -146,12 +158,14 @@ func NewMiner(api fullNodeFilteredAPI,
 	metaService *service.MetadataService,
 	sectorInfoService *service.SectorInfoService,
 	logService *service.LogService,
+	batchService *service.BatchService,
+	dealService *service.UnreportedDealService,
 	sealer sectorstorage.SectorManager,
 	sc types2.SectorIDCounter,
 	verif ffiwrapper.Verifier,
 	prover ffiwrapper.Prover,
 	gsd types2.GetSealingConfigFunc,
-	feeCfg config.MinerFeeConfig,
+	feeCfg config.GetMinerFeeConfigFunc,
 	journal journal.Journal,
 	as *AddressSelector,
 	networkParams *config.NetParamsConfig) (*Miner, error) {
@@ -172,7 +186,10 @@ func NewMiner(api fullNodeFilteredAPI,
 		getSealConfig:     gsd,
 		journal:           journal,
 		logService:        logService,
+		batchService:      batchService,
+		dealService:       dealService,
 		sealingEvtType:    journal.RegisterEventType("storage", "sealing_states"),
+		recoveryEvtType:   journal.RegisterEventType("storage", "recovery_escalated"),
 	}
 
 	return m, nil
@@ -222,7 +239,7 @@ func (m *Miner) Run(ctx context.Context) error {
 	cfg := types2.GetSealingConfigFunc(m.getSealConfig)
 
 	// Instantiate the sealing FSM.
-	m.sealing = sealing.New(ctx, adaptedAPI, m.feeCfg, evtsAdapter, m.maddr, m.metadataService, m.sectorInfoService, m.logService, m.sealer, m.sc, m.verif, m.prover,
+	m.sealing = sealing.New(ctx, adaptedAPI, m.feeCfg, evtsAdapter, m.maddr, m.metadataService, m.sectorInfoService, m.logService, m.batchService, m.dealService, m.sealer, m.sc, m.verif, m.prover,
 		&pcp, cfg, m.handleSealingNotifications, as, m.networkParams)
 
 	// Run the sealing FSM.
@@ -241,6 +258,17 @@ func (m *Miner) handleSealingNotifications(before, after types2.SectorInfo) {
 			Error:        after.LastErr,
 		}
 	})
+
+	if after.RecoveryEscalated && !before.RecoveryEscalated {
+		m.journal.RecordEvent(m.recoveryEvtType, func() interface{} {
+			return RecoveryEscalatedEvt{
+				SectorNumber: after.SectorNumber,
+				State:        after.State,
+				Attempts:     after.RecoveryAttempts[after.State],
+				Error:        after.LastErr,
+			}
+		})
+	}
 }
 
 func (m *Miner) Stop(ctx context.Context) error {
@@ -48,6 +48,14 @@ func (m *Miner) DealSector(ctx context.Context) ([]types.DealAssign, error) {
 	return m.sealing.DealSector(ctx)
 }
 
+func (m *Miner) DealIngestStatus() types.DealIngestStatus {
+	return m.sealing.DealIngestStatus()
+}
+
+func (m *Miner) DealPackingPlan(ctx context.Context) (types.PackingPlan, error) {
+	return m.sealing.PackingPlan(ctx)
+}
+
 func (m *Miner) RedoSector(ctx context.Context, rsi storiface.SectorRedoParams) error  {
 	return  m.sealing.RedoSector(ctx, rsi)
 }
@@ -56,6 +64,10 @@ func (m *Miner) ForceSectorState(ctx context.Context, id abi.SectorNumber, state
 	return m.sealing.ForceSectorState(ctx, id, state)
 }
 
+func (m *Miner) RestoreSector(ctx context.Context, id abi.SectorNumber) error {
+	return m.sealing.RestoreSector(ctx, id)
+}
+
 func (m *Miner) RemoveSector(ctx context.Context, id abi.SectorNumber) error {
 	return m.sealing.Remove(ctx, id)
 }
@@ -88,6 +100,18 @@ func (m *Miner) CommitPending(ctx context.Context) ([]abi.SectorID, error) {
 	return m.sealing.CommitPending(ctx)
 }
 
+func (m *Miner) BatchQueues(ctx context.Context) (types.BatchQueues, error) {
+	return m.sealing.BatchQueues(ctx)
+}
+
+func (m *Miner) BatchExplain(ctx context.Context) (types.BatchExplains, error) {
+	return m.sealing.BatchExplain(ctx)
+}
+
+func (m *Miner) SectorHistory(sid abi.SectorNumber) (types.SectorHistory, error) {
+	return m.sealing.SectorHistory(sid)
+}
+
 func (m *Miner) MarkForUpgrade(id abi.SectorNumber) error {
 	return m.sealing.MarkForUpgrade(id)
 }
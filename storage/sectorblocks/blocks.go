@@ -7,8 +7,10 @@ import (
 	"io"
 	"sync"
 
+	"github.com/ipfs/go-cid"
 	"github.com/ipfs/go-datastore"
 	dshelp "github.com/ipfs/go-ipfs-ds-help"
+	"go.uber.org/fx"
 	"golang.org/x/xerrors"
 
 	"github.com/filecoin-project/go-state-types/abi"
@@ -48,15 +50,62 @@ type SectorBlocks struct {
 	keyLk sync.Mutex
 }
 
-func NewSectorBlocks(miner *storage.Miner, ds *service.DealRefService) *SectorBlocks {
+func NewSectorBlocks(lc fx.Lifecycle, miner *storage.Miner, ds *service.DealRefService) *SectorBlocks {
 	sbc := &SectorBlocks{
 		Miner: miner,
 		keys:  ds,
 	}
 
+	lc.Append(fx.Hook{
+		OnStart: func(context.Context) error {
+			// the sectors are only known once the miner started
+			if err := sbc.backfillPieces(); err != nil {
+				log.Errorf("backfilling piece cids of deal refs: %+v", err)
+			}
+			return nil
+		},
+	})
+
 	return sbc
 }
 
+// backfillPieces records the piece cids of refs saved before they were stored
+// with the ref, taking them from the pieces of their sectors
+func (st *SectorBlocks) backfillPieces() error {
+	refs, err := st.keys.ListWithoutPiece()
+	if err != nil {
+		return xerrors.Errorf("listing refs without piece cid: %w", err)
+	}
+	if len(refs) == 0 {
+		return nil
+	}
+
+	var filled int
+	for dealID, dealRefs := range refs {
+		for _, ref := range dealRefs {
+			si, err := st.Miner.GetSectorInfo(ref.SectorID)
+			if err != nil {
+				// the sector may have been removed
+				continue
+			}
+
+			for _, p := range si.Pieces {
+				if p.DealInfo == nil || p.DealInfo.DealID != abi.DealID(dealID) {
+					continue
+				}
+				if err := st.keys.SetPiece(dealID, ref.SectorID, p.Piece.PieceCID); err != nil {
+					return xerrors.Errorf("setting piece cid of deal %d: %w", dealID, err)
+				}
+				filled++
+				break
+			}
+		}
+	}
+
+	log.Infow("backfilled piece cids of deal refs", "filled", filled, "refs", len(refs))
+	return nil
+}
+
 func (st *SectorBlocks) AddPiece(ctx context.Context, size abi.UnpaddedPieceSize, r io.Reader, d types.PieceDealInfo) (abi.SectorNumber, abi.PaddedPieceSize, error) {
 	so, err := st.Miner.SectorAddPieceToAny(ctx, size, r, d)
 	if err != nil {
@@ -106,3 +155,19 @@ func (st *SectorBlocks) Has(dealID abi.DealID) (bool, error) {
 	// TODO: ensure sector is still there
 	return st.keys.Has(uint64(dealID))
 }
+
+// GetRefsByPiece returns where the piece is stored by deal. Refs saved before
+// the piece cid was recorded with them are backfilled when the sealer starts.
+func (st *SectorBlocks) GetRefsByPiece(pieceCid cid.Cid) (map[abi.DealID][]types.SealedRef, error) {
+	refs, err := st.keys.GetByPiece(pieceCid)
+	if err != nil {
+		return nil, xerrors.Errorf("getting refs by piece: %w", err)
+	}
+
+	out := map[abi.DealID][]types.SealedRef{}
+	for dealID, dealRefs := range refs {
+		out[abi.DealID(dealID)] = dealRefs
+	}
+
+	return out, nil
+}
@@ -0,0 +1,296 @@
+package sectorblocks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/ipfs/go-cid"
+	logging "github.com/ipfs/go-log/v2"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-jsonrpc/auth"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/specs-storage/storage"
+
+	"github.com/filecoin-project/venus-sealer/api"
+	sectorstorage "github.com/filecoin-project/venus-sealer/sector-storage"
+	"github.com/filecoin-project/venus-sealer/sector-storage/storiface"
+	"github.com/filecoin-project/venus-sealer/types"
+)
+
+var log = logging.Logger("sectorblocks")
+
+// PieceHandler serves unsealed piece data over HTTP, unsealing sectors on demand:
+//
+//	GET /piece/{pieceCid}
+//	GET /sector/{id}/unsealed?offset={unpadded offset}&size={unpadded size}
+//
+// Both support single byte ranges. With VerifyUnsealedPieces enabled the piece
+// cid is checked against the data the first time a piece is served from a sector.
+type PieceHandler struct {
+	blocks *SectorBlocks
+	pieces sectorstorage.PieceProvider
+}
+
+func NewPieceHandler(blocks *SectorBlocks, pieces sectorstorage.PieceProvider) *PieceHandler {
+	return &PieceHandler{
+		blocks: blocks,
+		pieces: pieces,
+	}
+}
+
+func (h *PieceHandler) Register(r *mux.Router) {
+	r.HandleFunc("/piece/{cid}", h.servePiece).Methods("GET", "HEAD")
+	r.HandleFunc("/sector/{id}/unsealed", h.serveUnsealed).Methods("GET", "HEAD")
+}
+
+func (h *PieceHandler) servePiece(w http.ResponseWriter, r *http.Request) {
+	if !h.authorized(w, r) {
+		return
+	}
+
+	pieceCid, err := cid.Parse(mux.Vars(r)["cid"])
+	if err != nil {
+		httpError(w, http.StatusBadRequest, xerrors.Errorf("parsing piece cid: %w", err))
+		return
+	}
+
+	refs, err := h.blocks.GetRefsByPiece(pieceCid)
+	if err != nil {
+		httpError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	sector, info, ref, err := h.pickRef(r.Context(), refs)
+	if err != nil {
+		httpError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if info == nil {
+		httpError(w, http.StatusNotFound, xerrors.Errorf("piece %s not found", pieceCid))
+		return
+	}
+
+	h.serve(w, r, sector, info, storiface.UnpaddedByteIndex(ref.Offset.Unpadded()), ref.Size, pieceCid)
+}
+
+func (h *PieceHandler) serveUnsealed(w http.ResponseWriter, r *http.Request) {
+	if !h.authorized(w, r) {
+		return
+	}
+
+	num, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		httpError(w, http.StatusBadRequest, xerrors.Errorf("parsing sector number: %w", err))
+		return
+	}
+	offset, err := strconv.ParseUint(r.URL.Query().Get("offset"), 10, 64)
+	if err != nil {
+		httpError(w, http.StatusBadRequest, xerrors.Errorf("parsing offset: %w", err))
+		return
+	}
+	size, err := strconv.ParseUint(r.URL.Query().Get("size"), 10, 64)
+	if err != nil {
+		httpError(w, http.StatusBadRequest, xerrors.Errorf("parsing size: %w", err))
+		return
+	}
+	if err := storiface.UnpaddedByteIndex(offset).Valid(); err != nil {
+		httpError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := abi.UnpaddedPieceSize(size).Validate(); err != nil {
+		httpError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	info, err := h.blocks.GetSectorInfo(abi.SectorNumber(num))
+	if err != nil {
+		httpError(w, http.StatusNotFound, xerrors.Errorf("getting sector info: %w", err))
+		return
+	}
+	sector, err := h.sectorRef(info)
+	if err != nil {
+		httpError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// ranges matching a piece are checked against its piece cid
+	pieceCid := cid.Undef
+	if pi, ok := info.PieceAt(abi.UnpaddedPieceSize(offset).Padded()); ok && pi.Size == abi.UnpaddedPieceSize(size).Padded() {
+		pieceCid = pi.PieceCID
+	}
+
+	h.serve(w, r, sector, &info, storiface.UnpaddedByteIndex(offset), abi.UnpaddedPieceSize(size), pieceCid)
+}
+
+func (h *PieceHandler) authorized(w http.ResponseWriter, r *http.Request) bool {
+	if !auth.HasPerm(r.Context(), nil, api.PermPiecesRead) {
+		w.WriteHeader(401)
+		_ = json.NewEncoder(w).Encode(struct{ Error string }{"unauthorized: missing pieces:read permission"})
+		return false
+	}
+	return true
+}
+
+// pickRef picks a ref of the piece, preferring sectors with an unsealed copy.
+// The returned sector info is nil if no ref can be read from.
+func (h *PieceHandler) pickRef(ctx context.Context, refs map[abi.DealID][]types.SealedRef) (storage.SectorRef, *types.SectorInfo, types.SealedRef, error) {
+	var (
+		fallback     *types.SectorInfo
+		fallbackRef  types.SealedRef
+		fallbackSect storage.SectorRef
+	)
+
+	for _, dealRefs := range refs {
+		for _, ref := range dealRefs {
+			info, err := h.blocks.GetSectorInfo(ref.SectorID)
+			if err != nil {
+				log.Warnw("getting sector info of piece ref", "sector", ref.SectorID, "error", err)
+				continue
+			}
+			if info.CommD == nil {
+				continue
+			}
+			sector, err := h.sectorRef(info)
+			if err != nil {
+				return storage.SectorRef{}, nil, types.SealedRef{}, err
+			}
+
+			unsealed, err := h.pieces.IsUnsealed(ctx, sector, storiface.UnpaddedByteIndex(ref.Offset.Unpadded()), ref.Size)
+			if err != nil {
+				log.Warnw("checking for unsealed piece", "sector", ref.SectorID, "error", err)
+			}
+			if unsealed {
+				return sector, &info, ref, nil
+			}
+			if fallback == nil {
+				info := info
+				fallback, fallbackRef, fallbackSect = &info, ref, sector
+			}
+		}
+	}
+
+	return fallbackSect, fallback, fallbackRef, nil
+}
+
+func (h *PieceHandler) sectorRef(info types.SectorInfo) (storage.SectorRef, error) {
+	mid, err := address.IDFromAddress(h.blocks.Address())
+	if err != nil {
+		return storage.SectorRef{}, xerrors.Errorf("getting miner id: %w", err)
+	}
+
+	return storage.SectorRef{
+		ID: abi.SectorID{
+			Miner:  abi.ActorID(mid),
+			Number: info.SectorNumber,
+		},
+		ProofType: info.SectorType,
+	}, nil
+}
+
+func (h *PieceHandler) serve(w http.ResponseWriter, r *http.Request, sector storage.SectorRef, info *types.SectorInfo, offset storiface.UnpaddedByteIndex, size abi.UnpaddedPieceSize, pieceCid cid.Cid) {
+	start, length, err := parseRange(r.Header.Get("Range"), int64(size))
+	if err != nil {
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", size))
+		httpError(w, http.StatusRequestedRangeNotSatisfiable, err)
+		return
+	}
+
+	status := http.StatusOK
+	setHeaders := func() {
+		w.Header().Set("Accept-Ranges", "bytes")
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Length", strconv.FormatInt(length, 10))
+		if length != int64(size) {
+			status = http.StatusPartialContent
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, start+length-1, size))
+		}
+	}
+
+	if r.Method == http.MethodHead {
+		setHeaders()
+		w.WriteHeader(status)
+		return
+	}
+
+	if info.CommD == nil {
+		httpError(w, http.StatusNotFound, xerrors.Errorf("sector %d has no unsealed cid", sector.ID.Number))
+		return
+	}
+
+	rd, _, err := h.pieces.ReadPieceRange(r.Context(), sector, offset, size, uint64(start), uint64(length), info.TicketValue, *info.CommD, pieceCid)
+	if err != nil {
+		httpError(w, http.StatusInternalServerError, xerrors.Errorf("reading piece: %w", err))
+		return
+	}
+	defer rd.Close() // nolint
+
+	setHeaders()
+	w.WriteHeader(status)
+	if _, err := io.CopyN(w, rd, length); err != nil {
+		log.Warnw("serving piece data", "sector", sector.ID, "offset", offset, "error", err)
+	}
+}
+
+// parseRange parses a Range header with a single byte range, requests for
+// multiple ranges get the whole content
+func parseRange(header string, size int64) (start, length int64, err error) {
+	if header == "" {
+		return 0, size, nil
+	}
+	if !strings.HasPrefix(header, "bytes=") {
+		return 0, 0, xerrors.Errorf("invalid range %q", header)
+	}
+	spec := strings.TrimSpace(strings.TrimPrefix(header, "bytes="))
+	if strings.Contains(spec, ",") {
+		return 0, size, nil
+	}
+
+	i := strings.Index(spec, "-")
+	if i < 0 {
+		return 0, 0, xerrors.Errorf("invalid range %q", header)
+	}
+	first, last := strings.TrimSpace(spec[:i]), strings.TrimSpace(spec[i+1:])
+
+	if first == "" {
+		// suffix range, the last n bytes
+		n, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || n <= 0 {
+			return 0, 0, xerrors.Errorf("invalid range %q", header)
+		}
+		if n > size {
+			n = size
+		}
+		return size - n, n, nil
+	}
+
+	start, err = strconv.ParseInt(first, 10, 64)
+	if err != nil || start < 0 || start >= size {
+		return 0, 0, xerrors.Errorf("invalid range %q", header)
+	}
+	end := size - 1
+	if last != "" {
+		end, err = strconv.ParseInt(last, 10, 64)
+		if err != nil || end < start {
+			return 0, 0, xerrors.Errorf("invalid range %q", header)
+		}
+		if end >= size {
+			end = size - 1
+		}
+	}
+
+	return start, end - start + 1, nil
+}
+
+func httpError(w http.ResponseWriter, status int, err error) {
+	log.Warnw("piece http request failed", "status", status, "error", err)
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(struct{ Error string }{err.Error()})
+}
@@ -0,0 +1,28 @@
+package sectorblocks
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRange(t *testing.T) {
+	for header, expect := range map[string][2]int64{
+		"":              {0, 1016},
+		"bytes=0-99":    {0, 100},
+		"bytes=100-":    {100, 916},
+		"bytes=-16":     {1000, 16},
+		"bytes=-2000":   {0, 1016},
+		"bytes=10-5000": {10, 1006},
+		"bytes=0-1,5-9": {0, 1016},
+	} {
+		start, length, err := parseRange(header, 1016)
+		require.NoError(t, err, header)
+		require.Equal(t, expect, [2]int64{start, length}, header)
+	}
+
+	for _, header := range []string{"items=0-1", "bytes=1016-", "bytes=5-1", "bytes=-0", "bytes=a-b"} {
+		_, _, err := parseRange(header, 1016)
+		require.Error(t, err, header)
+	}
+}
@@ -321,12 +321,12 @@ func (s *WindowPoStScheduler) declareRecoveries(ctx context.Context, dlIdx uint6
 		Params: enc,
 		Value:  types.NewInt(0),
 	}
-	spec := &types.MessageSendSpec{MaxFee: abi.TokenAmount(s.feeCfg.MaxWindowPoStGasFee)}
+	spec := &types.MessageSendSpec{MaxFee: abi.TokenAmount(s.feeCfg().MaxWindowPoStGasFee)}
 	if err := s.prepareMessage(ctx, msg, spec); err != nil {
 		return recoveries, nil, err
 	}
 
-	uid, err := s.Messager.PushMessage(ctx, msg, &types3.MsgMeta{MaxFee: abi.TokenAmount(s.feeCfg.MaxWindowPoStGasFee)})
+	uid, err := s.Messager.PushMessage(ctx, msg, &types3.MsgMeta{MaxFee: abi.TokenAmount(s.feeCfg().MaxWindowPoStGasFee)})
 	if err != nil {
 		return recoveries, nil, xerrors.Errorf("pushing message to mpool: %w", err)
 	}
@@ -423,7 +423,7 @@ func (s *WindowPoStScheduler) declareFaults(ctx context.Context, dlIdx uint64, p
 		Params: enc,
 		Value:  types.NewInt(0), // TODO: Is there a fee?
 	}
-	spec := &types.MessageSendSpec{MaxFee: abi.TokenAmount(s.feeCfg.MaxWindowPoStGasFee)}
+	spec := &types.MessageSendSpec{MaxFee: abi.TokenAmount(s.feeCfg().MaxWindowPoStGasFee)}
 	if err := s.prepareMessage(ctx, msg, spec); err != nil {
 		return faults, nil, err
 	}
@@ -835,7 +835,7 @@ func (s *WindowPoStScheduler) submitPoStMessage(ctx context.Context, proof *mine
 		Params: enc,
 		Value:  types.NewInt(0),
 	}
-	spec := &types.MessageSendSpec{MaxFee: abi.TokenAmount(s.feeCfg.MaxWindowPoStGasFee)}
+	spec := &types.MessageSendSpec{MaxFee: abi.TokenAmount(s.feeCfg().MaxWindowPoStGasFee)}
 
 	var (
 		uid string
@@ -237,6 +237,7 @@ func TestWDPostDoPost(t *testing.T) {
 		actor:        postAct,
 		journal:      journal.NilJournal(),
 		addrSel:      &AddressSelector{},
+		feeCfg:       func() config.MinerFeeConfig { return config.MinerFeeConfig{} },
 	}
 
 	di := &dline.Info{
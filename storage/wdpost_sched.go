@@ -32,7 +32,7 @@ type WindowPoStScheduler struct {
 	networkParams *config.NetParamsConfig
 
 	api              fullNodeFilteredAPI
-	feeCfg           config.MinerFeeConfig
+	feeCfg           config.GetMinerFeeConfigFunc
 	addrSel          *AddressSelector
 	prover           storage.Prover
 	verifier         ffiwrapper.Verifier
@@ -53,7 +53,7 @@ type WindowPoStScheduler struct {
 // NewWindowedPoStScheduler creates a new WindowPoStScheduler scheduler.
 func NewWindowedPoStScheduler(api fullNodeFilteredAPI,
 	messager api.IMessager,
-	fc config.MinerFeeConfig,
+	fc config.GetMinerFeeConfigFunc,
 	as *AddressSelector,
 	sp storage.Prover,
 	verif ffiwrapper.Verifier,
@@ -0,0 +1,27 @@
+package types
+
+import "time"
+
+// AuditEntry records a call of a mutating API method
+type AuditEntry struct {
+	ID     int64
+	Time   time.Time
+	Caller string // ID of a scoped token, or the fingerprint of other tokens
+	Remote string
+	Method string
+	// Params are the JSON encoded call arguments with sensitive fields redacted
+	Params   string
+	Error    string // empty if the call succeeded
+	Duration time.Duration
+}
+
+type AuditFilter struct {
+	Method string
+	Caller string
+	Since  time.Time
+	Until  time.Time
+	// FailedOnly only returns calls which returned an error
+	FailedOnly bool
+	// Limit is the maximum number of entries returned, newest first. 0 for no limit
+	Limit int
+}
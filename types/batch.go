@@ -0,0 +1,89 @@
+package types
+
+import (
+	"time"
+
+	"github.com/filecoin-project/go-state-types/abi"
+)
+
+// Batcher names used to persist batch queues
+const (
+	BatcherPreCommit = "precommit"
+	BatcherCommit    = "commit"
+	BatcherTerminate = "terminate"
+)
+
+// BatchEntry is a sector waiting in a batcher, Data is the batcher specific
+// message parameters of the sector
+type BatchEntry struct {
+	SectorNumber abi.SectorNumber
+	Cutoff       time.Time
+	Data         []byte
+}
+
+// BatchStore persists the batch queues so they survive restarts
+type BatchStore interface {
+	SaveBatchEntry(batcher string, entry *BatchEntry) error
+	DeleteBatchEntries(batcher string, sectors []abi.SectorNumber) error
+	ListBatchEntries(batcher string) ([]*BatchEntry, error)
+}
+
+type BatchedSector struct {
+	Sector abi.SectorNumber
+	// Cutoff is zero when the batcher has no deadline for the sector
+	Cutoff time.Time
+	// Restored sectors were loaded from the repo and haven't been added by the sealing state machine again yet
+	Restored bool
+}
+
+type BatchQueue struct {
+	Sectors []BatchedSector
+	// Cutoff is the earliest cutoff of the sectors, the batch is sent Slack before it at the latest
+	Cutoff time.Time
+	Slack  time.Duration
+}
+
+type BatchQueues struct {
+	PreCommit BatchQueue
+	Commit    BatchQueue
+	Terminate BatchQueue
+}
+
+// BatchOption is the estimated cost of sending Aggregated sectors in one batch
+// message and Individual sectors in one message each
+type BatchOption struct {
+	Aggregated int
+	Individual int
+
+	GasCost    abi.TokenAmount
+	NetworkFee abi.TokenAmount
+	Total      abi.TokenAmount
+}
+
+// BatchDecision records how the batch cost model split a batch
+type BatchDecision struct {
+	Time    time.Time
+	BaseFee abi.TokenAmount
+	Sectors int
+	// EarliestCutoff of the sectors in the batch, zero if none has one
+	EarliestCutoff time.Time
+
+	// Options considered: all individual, all aggregated and the cheapest split
+	Options []BatchOption
+	Chosen  BatchOption
+	// Wait is set when no sectors are sent yet, waiting for more sectors to batch
+	Wait bool
+
+	Reason string
+}
+
+type BatchExplain struct {
+	// Last is the decision for the last batch sent, Next is what would be done with the queue now
+	Last *BatchDecision
+	Next *BatchDecision
+}
+
+type BatchExplains struct {
+	PreCommit BatchExplain
+	Commit    BatchExplain
+}
@@ -0,0 +1,36 @@
+package types
+
+import (
+	"time"
+
+	"github.com/filecoin-project/go-state-types/abi"
+)
+
+// FSMAnyState is the source of transitions which are possible in every state
+const FSMAnyState = SectorState("*")
+
+// FSMEdge is a transition of the sector state machine
+type FSMEdge struct {
+	From  SectorState
+	To    SectorState
+	Event string
+}
+
+// SectorStateSpan is a period a sector spent in one state
+type SectorStateSpan struct {
+	State   SectorState
+	Entered time.Time
+	// Left is zero for the current state
+	Left     time.Time
+	Duration time.Duration
+	// Event moved the sector out of the state
+	Event string
+}
+
+type SectorHistory struct {
+	Sector abi.SectorNumber
+	Spans  []SectorStateSpan
+	// Incomplete is set when the event log doesn't lead to the current state,
+	// eg. because it was truncated
+	Incomplete bool
+}
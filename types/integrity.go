@@ -0,0 +1,22 @@
+package types
+
+import (
+	"time"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/ipfs/go-cid"
+)
+
+// SectorIntegrity is the result of the last check of a sealed replica against
+// the CommR the sector was precommitted with
+type SectorIntegrity struct {
+	SectorNumber abi.SectorNumber
+	CommR        cid.Cid
+	Checked      time.Time
+	// Error is empty if the replica matched the CommR
+	Error string
+}
+
+func (s *SectorIntegrity) OK() bool {
+	return s.Error == ""
+}
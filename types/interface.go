@@ -1,6 +1,10 @@
 package types
 
 import (
+	"github.com/ipfs/go-cid"
+
+	"github.com/filecoin-project/go-state-types/abi"
+
 	"github.com/filecoin-project/specs-actors/v2/actors/builtin/market"
 )
 
@@ -9,4 +13,7 @@ type DealRef interface {
 	Save(uint64, SealedRef, *market.DealProposal) error
 	Has(uint64) (bool, error)
 	List() (map[uint64][]SealedRef, error)
+	GetByPiece(cid.Cid) (map[uint64][]SealedRef, error)
+	ListWithoutPiece() (map[uint64][]SealedRef, error)
+	SetPiece(uint64, abi.SectorNumber, cid.Cid) error
 }
@@ -0,0 +1,30 @@
+package types
+
+import (
+	"context"
+)
+
+// Sector classes passed to the scheduler as routing hints
+const (
+	SectorClassDeal = "deal"
+	SectorClassCC   = "cc"
+)
+
+type schedSectorClassCtxKey int
+
+var SchedSectorClassKey schedSectorClassCtxKey
+
+// GetSectorClass returns the routing hint of the sector the request is for,
+// an empty string if there is none
+func GetSectorClass(ctx context.Context) string {
+	sc := ctx.Value(SchedSectorClassKey)
+	if c, ok := sc.(string); ok {
+		return c
+	}
+
+	return ""
+}
+
+func WithSectorClass(ctx context.Context, class string) context.Context {
+	return context.WithValue(ctx, SchedSectorClassKey, class)
+}
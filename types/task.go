@@ -58,3 +58,14 @@ func (a TaskType) Short() string {
 
 	return n
 }
+
+// TaskTypeFromShort looks up a task type by its short name, e.g. PC1
+func TaskTypeFromShort(short string) (TaskType, bool) {
+	for tt, n := range shortNames {
+		if n == short {
+			return tt, true
+		}
+	}
+
+	return "", false
+}
@@ -0,0 +1,22 @@
+package types
+
+import (
+	"time"
+
+	"github.com/filecoin-project/go-jsonrpc/auth"
+	"github.com/gbrlsnchs/jwt/v3"
+)
+
+// JWTPayload is the payload of API tokens. Scoped tokens carry an ID, which
+// can be revoked, and usually an expiration time.
+type JWTPayload struct {
+	jwt.Payload
+	Allow []auth.Permission
+}
+
+type RevokedToken struct {
+	ID      string
+	Revoked time.Time
+	// Expires is when the token expires, the revocation can be dropped after it
+	Expires time.Time
+}
@@ -3,6 +3,7 @@ package types
 import (
 	"bytes"
 	"context"
+	"time"
 
 	"github.com/ipfs/go-cid"
 
@@ -117,6 +118,12 @@ type SectorInfo struct {
 
 	// Recovery
 	Return ReturnState
+	// RecoveryAttempts counts how often the sector entered each failure state,
+	// the counters pick the step of the recovery playbook
+	RecoveryAttempts map[SectorState]uint64
+	// RecoveryEscalated is set when the recovery playbook of the current
+	// failure state is used up
+	RecoveryEscalated bool
 
 	// Termination
 	TerminateMessage string
@@ -153,6 +160,18 @@ func (t *SectorInfo) ExistingPieceSizes() []abi.UnpaddedPieceSize {
 	return out
 }
 
+// PieceAt returns the piece starting at the offset in the sector
+func (t *SectorInfo) PieceAt(offset abi.PaddedPieceSize) (abi.PieceInfo, bool) {
+	var at abi.PaddedPieceSize
+	for _, p := range t.Pieces {
+		if at == offset {
+			return p.Piece, true
+		}
+		at += p.Piece.Size
+	}
+	return abi.PieceInfo{}, false
+}
+
 func (t *SectorInfo) HasDeals() bool {
 	for _, piece := range t.Pieces {
 		if piece.DealInfo != nil {
@@ -168,14 +187,15 @@ func (t *SectorInfo) SealingCtx(ctx context.Context) context.Context {
 	//  we need sealed sooner
 
 	if t.HasDeals() {
-		return WithPriority(ctx, DealSectorPriority)
+		return WithSectorClass(WithPriority(ctx, DealSectorPriority), SectorClassDeal)
 	}
 
-	return ctx
+	return WithSectorClass(ctx, SectorClassCC)
 }
 
 // Returns list of offset/length tuples of sector data ranges which clients
-// requested to keep unsealed
+// requested to keep unsealed. Inverted it lists the ranges which are safe to
+// free, including filler pieces.
 func (t *SectorInfo) KeepUnsealedRanges(invert, alwaysKeep bool) []storage.Range {
 	var out []storage.Range
 
@@ -184,11 +204,11 @@ func (t *SectorInfo) KeepUnsealedRanges(invert, alwaysKeep bool) []storage.Range
 		psize := piece.Piece.Size.Unpadded()
 		at += psize
 
-		if piece.DealInfo == nil {
+		if piece.DealInfo == nil && !invert {
 			continue
 		}
 
-		keep := piece.DealInfo.KeepUnsealed || alwaysKeep
+		keep := piece.DealInfo != nil && (piece.DealInfo.KeepUnsealed || alwaysKeep)
 
 		if keep == invert {
 			continue
@@ -235,3 +255,79 @@ type DealAssign struct {
 	Offset   abi.PaddedPieceSize
 	Size     abi.PaddedPieceSize
 }
+
+// UnreportedDeal is a deal added to a sector which venus-market doesn't know about yet
+type UnreportedDeal struct {
+	DealAssign
+	Attempts  int
+	LastError string
+}
+
+// UnreportedDealStore persists the unreported deals so they are still reported
+// after a restart
+type UnreportedDealStore interface {
+	SaveUnreportedDeal(deal *UnreportedDeal) error
+	DeleteUnreportedDeal(deal abi.DealID) error
+	ListUnreportedDeals() ([]*UnreportedDeal, error)
+}
+
+// SectorHost is the host which has run a task of a sector still being sealed
+type SectorHost struct {
+	Sector   abi.SectorID
+	Task     TaskType
+	Hostname string
+}
+
+// SectorHostStore persists the sector hosts so SameHostAs affinity rules
+// still match after a restart
+type SectorHostStore interface {
+	SaveSectorHost(host *SectorHost) error
+	DeleteSectorHosts(sector abi.SectorID) error
+	ListSectorHosts() ([]*SectorHost, error)
+}
+
+type DealIngestStatus struct {
+	Enabled bool
+	// Paused is why no deals are pulled from venus-market right now
+	Paused    string `json:",omitempty"`
+	LastPoll  time.Time
+	LastError string `json:",omitempty"`
+
+	// deals added to sectors / failed to add since the sealer started
+	Ingested uint64
+	Failed   uint64
+
+	Unreported []UnreportedDeal
+}
+
+// PackingPlan is how the packing planner would assign the deals waiting for sectors now
+type PackingPlan struct {
+	// SealedBy is the earliest epoch a sector starting to seal now can be sealed by
+	SealedBy abi.ChainEpoch
+
+	Sectors  []PlannedSector
+	Rejected []PlannedPiece
+}
+
+type PlannedSector struct {
+	// Sector is 0 for sectors which would have to be created
+	Sector abi.SectorNumber
+	New    bool
+
+	// Used and Free include the planned pieces, Padding is the filler they need
+	Used    abi.PaddedPieceSize
+	Free    abi.PaddedPieceSize
+	Padding abi.PaddedPieceSize
+
+	Pieces []PlannedPiece
+}
+
+type PlannedPiece struct {
+	DealID     abi.DealID
+	PieceCID   cid.Cid
+	Size       abi.UnpaddedPieceSize
+	StartEpoch abi.ChainEpoch
+	EndEpoch   abi.ChainEpoch
+
+	Reason string `json:",omitempty"`
+}